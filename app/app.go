@@ -1,12 +1,16 @@
 package app
 
 import (
+	"log"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"testlake/middleware"
 	"testlake/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/joho/godotenv"
 )
 
 // @title TestLake API
@@ -28,12 +32,24 @@ import (
 // @name Authorization
 
 func ServeApplication() {
+	if err := utils.InitJWTKeys(); err != nil {
+		log.Fatal("Failed to load JWT keys: ", err)
+	}
+	if err := utils.CheckLinkSigningSecret(); err != nil {
+		log.Fatal(err)
+	}
+	watchJWTKeyReload()
+	startJobs()
+	startUsageMetering()
+
 	router := gin.Default()
 
 	router.Use(middleware.DefaultAuthMiddleware())
 
 	Swagger(router)
 
+	WellKnownRoutes(router.Group(""))
+
 	baseRoute := router.Group("/api/v1")
 
 	publicRoutes := baseRoute.Group("")
@@ -41,6 +57,8 @@ func ServeApplication() {
 
 	privateRoutes := baseRoute.Group("")
 	privateRoutes.Use(middleware.JWTAuthMiddleware())
+	privateRoutes.Use(middleware.APIUsageMetering())
+	privateRoutes.Use(middleware.SuspendedOrganizationReadOnly())
 	PrivateRoutes(privateRoutes)
 
 	router.NoRoute(utils.HandleNoRoute())
@@ -49,3 +67,24 @@ func ServeApplication() {
 	port := os.Getenv("PORT")
 	router.Run(ip + ":" + port)
 }
+
+// watchJWTKeyReload reloads the JWT keys on SIGHUP so that a rotated key
+// configuration takes effect without restarting the server.
+func watchJWTKeyReload() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for range signals {
+			// Pick up key settings changed in .env since start-up
+			if err := godotenv.Overload(); err != nil {
+				log.Printf("Failed to reload .env file: %v", err)
+			}
+			if err := utils.ReloadJWTKeys(); err != nil {
+				log.Printf("Failed to reload JWT keys, keeping previous keys: %v", err)
+				continue
+			}
+			log.Printf("JWT keys reloaded")
+		}
+	}()
+}
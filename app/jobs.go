@@ -0,0 +1,27 @@
+package app
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"testlake/job"
+	"testlake/utils"
+)
+
+// startJobs starts the background jobs unless JOBS_ENABLED=false, e.g. on
+// instances that should only serve requests.
+func startJobs() {
+	if os.Getenv("JOBS_ENABLED") == "false" {
+		log.Println("JOBS_ENABLED is false, background jobs are not started")
+		return
+	}
+	job.Start(context.Background(), job.BillingJob(), job.UsageReconciliationJob(), job.UsageAlertJob())
+}
+
+// startUsageMetering flushes the API requests metered by this instance
+// periodically. Unlike the jobs it runs on every instance, since each one only
+// holds the requests it served.
+func startUsageMetering() {
+	go utils.APIUsageMeter().Run(context.Background(), utils.LoadUsageFlushInterval())
+}
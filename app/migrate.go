@@ -0,0 +1,82 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"testlake/dao"
+	"testlake/migrations"
+)
+
+const migrateUsage = `usage: main migrate <command>
+
+commands:
+  up             apply pending migrations
+  status         list migrations and when they were applied
+  create <name>  add an empty migration to the migrations directory`
+
+// RunMigrateCommand runs "migrate up|status|create" from the command line.
+func RunMigrateCommand(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	switch args[0] {
+	case "up":
+		dao.Open()
+		sqlDB, err := dao.Database.DB()
+		if err != nil {
+			log.Fatal("Failed to get database connection: ", err)
+		}
+		applied, err := migrations.Up(context.Background(), sqlDB)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+
+	case "status":
+		dao.Open()
+		sqlDB, err := dao.Database.DB()
+		if err != nil {
+			log.Fatal("Failed to get database connection: ", err)
+		}
+		statuses, err := migrations.Status(context.Background(), sqlDB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != nil {
+				state = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if status.Modified {
+				state += " (modified since applied)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+		}
+
+	case "create":
+		if len(args) < 2 {
+			log.Fatal(migrateUsage)
+		}
+		dir := os.Getenv("MIGRATIONS_DIR")
+		if dir == "" {
+			dir = "migrations"
+		}
+		path, err := migrations.Create(dir, args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("created", path)
+
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
@@ -2,11 +2,22 @@ package app
 
 import (
 	"testlake/controller"
+	"testlake/middleware"
 	"testlake/service"
 
 	"github.com/gin-gonic/gin"
 )
 
+func WellKnownRoutes(r *gin.RouterGroup) {
+	// Discovery documents served outside the versioned API
+	wellKnownService := service.AuthService{
+		Route:      ".well-known",
+		Controller: controller.AuthController{},
+	}
+
+	wellKnownService.GetJWKS(r, "jwks.json")
+}
+
 func PublicRoutes(r *gin.RouterGroup) {
 	// Create public sub-group
 	// Authentication endpoints (public)
@@ -21,7 +32,19 @@ func PublicRoutes(r *gin.RouterGroup) {
 	authService.ForgotPassword(r, "forgot-password")
 	authService.ResetPassword(r, "reset-password")
 	authService.VerifyEmail(r, "verify-email")
+	authService.ConfirmEmailChange(r, "confirm-email-change")
 	authService.ResendEmailConfirmation(r, "resend-email-confirmation")
+	authService.UnlockAccount(r, "unlock-account")
+
+	// Single sign-on endpoints (public)
+	ssoService := service.SSOService{
+		Route:      "auth/sso",
+		Controller: controller.SSOController{},
+	}
+
+	ssoService.Discover(r, "discover")
+	ssoService.Login(r, "login")
+	ssoService.Callback(r, "callback")
 
 	// Plan endpoints (public)
 	planService := service.PlanService{
@@ -32,6 +55,14 @@ func PublicRoutes(r *gin.RouterGroup) {
 	planService.GetAllPlans(r, "")
 	planService.GetPlan(r, "")
 	planService.ComparePlans(r, "compare")
+
+	// Payment provider webhooks (public, verified by signature)
+	payPalService := service.PayPalService{
+		Route:      "payments/paypal",
+		Controller: controller.PayPalWebhookController{},
+	}
+
+	payPalService.HandleWebhook(r, "webhooks")
 }
 
 func PrivateRoutes(r *gin.RouterGroup) {
@@ -53,6 +84,10 @@ func PrivateRoutes(r *gin.RouterGroup) {
 
 	userService.GetProfile(r, "profile")
 	userService.UpdateProfile(r, "profile")
+	userService.RequestEmailChange(r, "email/change")
+	userService.GetSessions(r, "sessions")
+	userService.RevokeSession(r, "sessions")
+	userService.GetLoginHistory(r, "login-history")
 	userService.DeleteAccount(r, "account")
 	userService.GetDashboard(r, "dashboard")
 	userService.GetNotifications(r, "notifications")
@@ -73,10 +108,21 @@ func PrivateRoutes(r *gin.RouterGroup) {
 	organizationService.UpdateOrganization(r)
 	organizationService.DeleteOrganization(r)
 	organizationService.GetOrganizationMembers(r)
+	organizationService.GetMembersActivity(r)
 	organizationService.InviteMember(r)
 	organizationService.GetPendingInvites(r)
 	organizationService.RemoveMember(r)
 	organizationService.UpdateMemberRole(r)
+	organizationService.GetSSOConfig(r)
+	organizationService.UpdateSSOConfig(r)
+	organizationService.VerifySSODomain(r)
+	organizationService.DeleteSSOConfig(r)
+	organizationService.CheckLimits(r)
+	organizationService.GetBillingDetails(r)
+	organizationService.UpdateBillingDetails(r)
+	organizationService.GetBillingContacts(r)
+	organizationService.AddBillingContact(r)
+	organizationService.RemoveBillingContact(r)
 
 	// Payment Method endpoints
 	paymentMethodService := service.PaymentMethodService{
@@ -86,6 +132,7 @@ func PrivateRoutes(r *gin.RouterGroup) {
 
 	paymentMethodService.GetPaymentMethods(r, "")
 	paymentMethodService.CreatePaymentMethod(r, "")
+	paymentMethodService.ConfirmPaymentMethod(r, "")
 	paymentMethodService.UpdatePaymentMethod(r, "")
 	paymentMethodService.DeletePaymentMethod(r, "")
 	paymentMethodService.SetDefaultPaymentMethod(r, "")
@@ -99,9 +146,20 @@ func PrivateRoutes(r *gin.RouterGroup) {
 	subscriptionService.GetSubscription(r, "")
 	subscriptionService.CreateSubscription(r, "create")
 	subscriptionService.ChangePlan(r, "change-plan")
+	subscriptionService.CancelScheduledChange(r, "scheduled-change")
 	subscriptionService.CancelSubscription(r, "cancel")
 	subscriptionService.ReactivateSubscription(r, "reactivate")
 	subscriptionService.GetSubscriptionUsage(r, "usage")
+	subscriptionService.GetCouponRedemptions(r, "coupons")
+
+	// Usage endpoints
+	usageService := service.UsageService{
+		Route:      "organizations/:id/usage",
+		Controller: controller.UsageController{},
+	}
+
+	usageService.GetCurrentUsage(r, "current")
+	usageService.GetUsageHistory(r, "history")
 
 	// Billing endpoints
 	billingService := service.BillingService{
@@ -111,6 +169,8 @@ func PrivateRoutes(r *gin.RouterGroup) {
 
 	billingService.GetBillingOverview(r, "overview")
 	billingService.GetBillingHistory(r, "history")
+	billingService.GetBillingEvents(r, "events")
+	billingService.GetBillingForecast(r, "forecast")
 
 	// Invoice endpoints
 	invoiceService := service.BillingService{
@@ -120,6 +180,13 @@ func PrivateRoutes(r *gin.RouterGroup) {
 
 	invoiceService.GetInvoices(r, "")
 
+	creditNoteService := service.BillingService{
+		Route:      "organizations/:id/credit-notes",
+		Controller: controller.BillingController{},
+	}
+
+	creditNoteService.GetCreditNotes(r, "")
+
 	// Global invoice endpoints (not organization-specific routes)
 	globalInvoiceService := service.BillingService{
 		Route:      "invoices",
@@ -129,4 +196,37 @@ func PrivateRoutes(r *gin.RouterGroup) {
 	globalInvoiceService.GetInvoice(r, "")
 	globalInvoiceService.DownloadInvoice(r, "")
 	globalInvoiceService.PayInvoice(r, "")
+
+	globalCreditNoteService := service.BillingService{
+		Route:      "credit-notes",
+		Controller: controller.BillingController{},
+	}
+
+	globalCreditNoteService.DownloadCreditNote(r, "")
+
+	// Platform admin endpoints
+	adminRoutes := r.Group("")
+	adminRoutes.Use(middleware.PlatformAdminMiddleware())
+
+	adminService := service.AdminService{
+		Route:      "admin",
+		Controller: controller.AdminController{},
+	}
+
+	adminService.GetEndingTrials(adminRoutes, "trials/ending")
+	adminService.RefundPayment(adminRoutes, "payments")
+	adminService.CreateCoupon(adminRoutes, "coupons")
+	adminService.GetCoupons(adminRoutes, "coupons")
+	adminService.GetCouponRedemptions(adminRoutes, "coupons")
+	adminService.DeactivateCoupon(adminRoutes, "coupons")
+	adminService.CreatePlan(adminRoutes, "plans")
+	adminService.GetCataloguePlans(adminRoutes, "plans")
+	adminService.GetCataloguePlan(adminRoutes, "plans")
+	adminService.UpdatePlan(adminRoutes, "plans")
+	adminService.CreatePlanVersion(adminRoutes, "plans")
+	adminService.RetirePlan(adminRoutes, "plans")
+	adminService.SyncPlan(adminRoutes, "plans")
+	adminService.GetRevenueReport(adminRoutes, "revenue")
+	adminService.GetInvoiceDeliveries(adminRoutes, "invoices")
+	adminService.GetBillingEvents(adminRoutes, "billing-events")
 }
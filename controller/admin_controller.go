@@ -0,0 +1,864 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"testlake/dao"
+	"testlake/inout"
+	"testlake/inout/admin"
+	"testlake/inout/billing"
+	"testlake/inout/coupon"
+	"testlake/inout/plan"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AdminController serves the platform admin endpoints, which work across organizations.
+type AdminController struct{}
+
+// GetEndingTrials reports the trials ending within the next days, soonest first
+func (controller AdminController) GetEndingTrials(context *gin.Context) {
+	days, err := strconv.Atoi(context.DefaultQuery("days", "7"))
+	if err != nil || days < 1 || days > 90 {
+		utils.ReportBadRequest(context, "Invalid days parameter, expected 1 to 90")
+		return
+	}
+
+	page, err := strconv.Atoi(context.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		utils.ReportBadRequest(context, "Invalid page parameter")
+		return
+	}
+
+	now := time.Now()
+	subscriptionDao := dao.NewSubscriptionDao()
+	trials, total, err := subscriptionDao.GetTrialsEnding(now, now.AddDate(0, 0, days), page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	paymentMethodDao := dao.NewPaymentMethodDao()
+	list := make([]admin.EndingTrial, 0, len(trials))
+	for i := range trials {
+		paymentMethod, err := paymentMethodDao.GetDefaultByOrganizationID(trials[i].OrganizationID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportInternalServerError(context, "Database error")
+			return
+		}
+		list = append(list, admin.FromEndingTrialModel(&trials[i], paymentMethod != nil && paymentMethod.IsChargeable(), now))
+	}
+
+	totalPages := int(total) / subscriptionDao.Limit
+	if int(total)%subscriptionDao.Limit > 0 {
+		totalPages++
+	}
+
+	response := admin.EndingTrialListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: list,
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      subscriptionDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// RefundPayment refunds a payment in full or in part through the payment
+// provider and returns the credit note documenting the refund
+func (controller AdminController) RefundPayment(context *gin.Context) {
+	paymentID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid payment ID")
+		return
+	}
+
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	var request admin.RefundPaymentRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.ReportBadRequest(context, "Invalid request body")
+		return
+	}
+
+	var balanceErr *payments.RefundBalanceError
+	creditNote, err := payments.RefundPayment(context.Request.Context(), payments.RefundRequest{
+		PaymentID:   paymentID,
+		Amount:      request.Amount,
+		Reason:      request.Reason,
+		RequestedBy: userID,
+	}, time.Now())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.ReportNotFound(context, "Payment not found")
+		return
+	case errors.Is(err, payments.ErrRefundNotAllowed):
+		utils.ReportBadRequest(context, "Payment cannot be refunded")
+		return
+	case errors.As(err, &balanceErr):
+		utils.ReportBadRequest(context, fmt.Sprintf("Refund exceeds the refundable balance of %s %s", model.FormatMinorUnits(balanceErr.Refundable, balanceErr.Currency), balanceErr.Currency))
+		return
+	case errors.Is(err, payments.ErrRefundDeclined):
+		log.Printf("Refund of payment %s was declined: %v", paymentID, err)
+		utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider declined the refund")
+		return
+	case err != nil:
+		log.Printf("Failed to refund payment %s: %v", paymentID, err)
+		utils.ReportInternalServerError(context, "Failed to refund payment")
+		return
+	}
+
+	if loaded, err := dao.NewCreditNoteDao().GetByID(creditNote.ID); err == nil {
+		creditNote = loaded
+	}
+
+	status, description := http.StatusOK, "Payment refunded"
+	if creditNote.Status == model.CreditNoteStatusPending {
+		status, description = http.StatusAccepted, "Refund is pending at the payment provider"
+	}
+	response := billing.CreditNoteOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: description,
+		},
+		Data: billing.FromCreditNoteModel(creditNote),
+	}
+
+	context.JSON(status, response)
+}
+
+// CreateCoupon creates a discount code organizations can redeem when they
+// subscribe or change plan
+func (controller AdminController) CreateCoupon(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	var request coupon.CreateCouponRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.ReportBadRequest(context, "Invalid request body")
+		return
+	}
+
+	switch {
+	case request.DiscountType == model.CouponDiscountTypePercent && (request.PercentOff == nil || request.AmountOff != nil):
+		utils.ReportBadRequest(context, "Percent coupons need percent_off and no amount_off")
+		return
+	case request.DiscountType == model.CouponDiscountTypeFixed && (request.AmountOff == nil || request.PercentOff != nil):
+		utils.ReportBadRequest(context, "Fixed coupons need amount_off and no percent_off")
+		return
+	case request.Duration == model.CouponDurationRepeating && request.DurationInCycles == nil:
+		utils.ReportBadRequest(context, "Repeating coupons need duration_in_cycles")
+		return
+	case request.Duration != model.CouponDurationRepeating && request.DurationInCycles != nil:
+		utils.ReportBadRequest(context, "Only repeating coupons take duration_in_cycles")
+		return
+	case request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()):
+		utils.ReportBadRequest(context, "expires_at must be in the future")
+		return
+	}
+
+	planDao := dao.NewPlanDao()
+	for _, planID := range request.PlanIDs {
+		if _, err := planDao.GetByID(planID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				utils.ReportBadRequest(context, fmt.Sprintf("Plan %s not found", planID))
+				return
+			}
+			utils.ReportInternalServerError(context, "Database error")
+			return
+		}
+	}
+
+	couponDao := dao.NewCouponDao()
+	if _, err := couponDao.GetByCode(request.Code); err == nil {
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Coupon code already exists")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	planIDs := request.PlanIDs
+	if planIDs == nil {
+		planIDs = []uuid.UUID{}
+	}
+	planIDsJSON, err := json.Marshal(planIDs)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to encode plan IDs")
+		return
+	}
+
+	currency := strings.ToUpper(request.Currency)
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	if !model.IsSupportedCurrency(currency) {
+		utils.ReportBadRequest(context, "Unsupported currency")
+		return
+	}
+	var amountOff *int64
+	if request.AmountOff != nil {
+		minorUnits := model.ToMinorUnits(*request.AmountOff, currency)
+		amountOff = &minorUnits
+	}
+
+	newCoupon := model.Coupon{
+		Code:             model.NormalizeCouponCode(request.Code),
+		Name:             request.Name,
+		DiscountType:     request.DiscountType,
+		PercentOff:       request.PercentOff,
+		AmountOff:        amountOff,
+		Currency:         currency,
+		Duration:         request.Duration,
+		DurationInCycles: request.DurationInCycles,
+		MaxRedemptions:   request.MaxRedemptions,
+		ExpiresAt:        request.ExpiresAt,
+		PlanIDs:          string(planIDsJSON),
+		IsActive:         true,
+		CreatedBy:        &userID,
+	}
+	if err := couponDao.Create(&newCoupon); err != nil {
+		utils.ReportInternalServerError(context, "Failed to create coupon")
+		return
+	}
+
+	response := coupon.CouponOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Coupon created",
+		},
+		Data: coupon.FromCouponModel(&newCoupon),
+	}
+
+	context.JSON(http.StatusCreated, response)
+}
+
+// GetCoupons lists the coupons, newest first
+func (controller AdminController) GetCoupons(context *gin.Context) {
+	page, err := strconv.Atoi(context.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		utils.ReportBadRequest(context, "Invalid page parameter")
+		return
+	}
+
+	couponDao := dao.NewCouponDao()
+	coupons, total, err := couponDao.GetAll(page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	totalPages := int(total) / couponDao.Limit
+	if int(total)%couponDao.Limit > 0 {
+		totalPages++
+	}
+
+	response := coupon.CouponListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: coupon.FromCouponModelList(coupons),
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      couponDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetCouponRedemptions lists the organizations that redeemed a coupon
+func (controller AdminController) GetCouponRedemptions(context *gin.Context) {
+	couponID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid coupon ID")
+		return
+	}
+
+	page, err := strconv.Atoi(context.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		utils.ReportBadRequest(context, "Invalid page parameter")
+		return
+	}
+
+	couponDao := dao.NewCouponDao()
+	if _, err := couponDao.GetByID(couponID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Coupon not found")
+			return
+		}
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	redemptions, total, err := couponDao.GetRedemptionsByCouponID(couponID, page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	totalPages := int(total) / couponDao.Limit
+	if int(total)%couponDao.Limit > 0 {
+		totalPages++
+	}
+
+	response := coupon.RedemptionListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: coupon.FromRedemptionModelList(redemptions),
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      couponDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// DeactivateCoupon stops a coupon from being redeemed. Discounts it already
+// gave keep running for their duration
+func (controller AdminController) DeactivateCoupon(context *gin.Context) {
+	couponID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid coupon ID")
+		return
+	}
+
+	couponDao := dao.NewCouponDao()
+	existing, err := couponDao.GetByID(couponID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Coupon not found")
+			return
+		}
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	existing.IsActive = false
+	if err := couponDao.Update(existing); err != nil {
+		utils.ReportInternalServerError(context, "Failed to deactivate coupon")
+		return
+	}
+
+	response := coupon.CouponOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Coupon deactivated",
+		},
+		Data: coupon.FromCouponModel(existing),
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// CreatePlan adds a plan to the catalogue and creates its PayPal product and
+// billing plans
+func (controller AdminController) CreatePlan(context *gin.Context) {
+	var request plan.CreatePlanRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.ReportBadRequest(context, "Invalid request body")
+		return
+	}
+
+	prices, err := planPrices(request.Prices)
+	if err != nil {
+		utils.ReportBadRequest(context, err.Error())
+		return
+	}
+
+	newPlan := &model.Plan{
+		Name:                    request.Name,
+		Slug:                    strings.ToLower(strings.TrimSpace(request.Slug)),
+		Description:             request.Description,
+		PriceMonthly:            model.ToMinorUnits(request.PriceMonthly, model.DefaultCurrency),
+		PriceYearly:             model.ToMinorUnits(request.PriceYearly, model.DefaultCurrency),
+		MaxUsers:                request.MaxUsers,
+		MaxProjects:             request.MaxProjects,
+		MaxEnvironments:         request.MaxEnvironments,
+		MaxSchemas:              request.MaxSchemas,
+		MaxTestRecordsPerSchema: request.MaxTestRecordsPerSchema,
+		Prices:                  prices,
+	}
+	if err := applyPlanUpdate(newPlan, plan.UpdatePlanRequest{Features: request.Features, UsageAlertThresholds: request.UsageAlertThresholds}); err != nil {
+		utils.ReportInternalServerError(context, "Failed to encode plan")
+		return
+	}
+	if newPlan.Features == "" {
+		newPlan.Features = "[]"
+	}
+	if newPlan.UsageAlertThresholds == "" {
+		thresholds, _ := json.Marshal(model.DefaultUsageAlertThresholds)
+		newPlan.UsageAlertThresholds = string(thresholds)
+	}
+
+	created, err := payments.CreatePlan(context.Request.Context(), newPlan)
+	description := "Plan created"
+	switch {
+	case errors.Is(err, payments.ErrPlanSlugTaken):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Plan slug already exists")
+		return
+	case errors.Is(err, payments.ErrGatewaySyncFailed):
+		description = "Plan created, but syncing it with PayPal failed; retry with the sync endpoint"
+	case err != nil:
+		utils.ReportInternalServerError(context, "Failed to create plan")
+		return
+	}
+
+	response := plan.CataloguePlanOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: description,
+		},
+		Data: plan.FromCataloguePlanModel(created),
+	}
+
+	context.JSON(http.StatusCreated, response)
+}
+
+// GetCataloguePlans lists every version of every plan, retired ones included
+func (controller AdminController) GetCataloguePlans(context *gin.Context) {
+	page, err := strconv.Atoi(context.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		utils.ReportBadRequest(context, "Invalid page parameter")
+		return
+	}
+
+	planDao := dao.NewPlanDao()
+	plans, total, err := planDao.GetCatalogue(page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	totalPages := int(total) / planDao.Limit
+	if int(total)%planDao.Limit > 0 {
+		totalPages++
+	}
+
+	response := plan.CataloguePlanListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: plan.FromCataloguePlanModelList(plans),
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      planDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetCataloguePlan returns a plan version and how many subscriptions are on it
+func (controller AdminController) GetCataloguePlan(context *gin.Context) {
+	existing, ok := controller.catalogueParamPlan(context)
+	if !ok {
+		return
+	}
+	controller.respondCataloguePlan(context, existing, "Success")
+}
+
+// UpdatePlan edits a plan's name, description, limits and features in place,
+// for its subscribers too. Prices change with CreatePlanVersion
+func (controller AdminController) UpdatePlan(context *gin.Context) {
+	existing, ok := controller.catalogueParamPlan(context)
+	if !ok {
+		return
+	}
+
+	var request plan.UpdatePlanRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.ReportBadRequest(context, "Invalid request body")
+		return
+	}
+
+	if err := applyPlanUpdate(existing, request); err != nil {
+		utils.ReportInternalServerError(context, "Failed to encode plan")
+		return
+	}
+	if err := dao.NewPlanDao().Update(existing); err != nil {
+		utils.ReportInternalServerError(context, "Failed to update plan")
+		return
+	}
+
+	controller.respondCataloguePlan(context, existing, "Plan updated")
+}
+
+// CreatePlanVersion replaces a plan with a new version at new prices. The
+// current version is retired and its subscribers keep their price
+func (controller AdminController) CreatePlanVersion(context *gin.Context) {
+	current, ok := controller.catalogueParamPlan(context)
+	if !ok {
+		return
+	}
+
+	var request plan.CreatePlanVersionRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.ReportBadRequest(context, "Invalid request body")
+		return
+	}
+
+	next := *current
+	next.Prices = make([]model.PlanPrice, len(current.Prices))
+	for i, price := range current.Prices {
+		next.Prices[i] = model.PlanPrice{Currency: price.Currency, BillingCycle: price.BillingCycle, Amount: price.Amount}
+	}
+	if request.Prices != nil {
+		prices, err := planPrices(request.Prices)
+		if err != nil {
+			utils.ReportBadRequest(context, err.Error())
+			return
+		}
+		next.Prices = prices
+	}
+	if request.PriceMonthly != nil {
+		next.PriceMonthly = model.ToMinorUnits(*request.PriceMonthly, model.DefaultCurrency)
+	}
+	if request.PriceYearly != nil {
+		next.PriceYearly = model.ToMinorUnits(*request.PriceYearly, model.DefaultCurrency)
+	}
+	if err := applyPlanUpdate(&next, request.UpdatePlanRequest); err != nil {
+		utils.ReportInternalServerError(context, "Failed to encode plan")
+		return
+	}
+
+	created, err := payments.VersionPlan(context.Request.Context(), current.ID, &next, time.Now())
+	description := "Plan version created"
+	switch {
+	case errors.Is(err, payments.ErrPlanRetired):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Only the current version of a plan can be versioned")
+		return
+	case errors.Is(err, payments.ErrGatewaySyncFailed):
+		description = "Plan version created, but syncing it with PayPal failed; retry with the sync endpoint"
+	case err != nil:
+		utils.ReportInternalServerError(context, "Failed to create plan version")
+		return
+	}
+
+	response := plan.CataloguePlanOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: description,
+		},
+		Data: plan.FromCataloguePlanModel(created),
+	}
+
+	context.JSON(http.StatusCreated, response)
+}
+
+// RetirePlan takes a plan off the catalogue. Its subscribers keep being
+// billed at its price until they change plan
+func (controller AdminController) RetirePlan(context *gin.Context) {
+	existing, ok := controller.catalogueParamPlan(context)
+	if !ok {
+		return
+	}
+
+	retired, err := payments.RetirePlan(context.Request.Context(), existing.ID, time.Now())
+	switch {
+	case errors.Is(err, payments.ErrPlanRetired):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Plan is already retired")
+		return
+	case errors.Is(err, payments.ErrFreePlanRetire):
+		utils.ReportBadRequest(context, "The free plan cannot be retired")
+		return
+	case err != nil:
+		utils.ReportInternalServerError(context, "Failed to retire plan")
+		return
+	}
+
+	controller.respondCataloguePlan(context, retired, "Plan retired")
+}
+
+// SyncPlan creates the PayPal product and billing plans a plan is missing,
+// e.g. after a sync failed or a currency was added
+func (controller AdminController) SyncPlan(context *gin.Context) {
+	existing, ok := controller.catalogueParamPlan(context)
+	if !ok {
+		return
+	}
+
+	synced, err := payments.SyncGatewayPlan(context.Request.Context(), existing.ID)
+	switch {
+	case errors.Is(err, payments.ErrPlanRetired):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Retired plans are not synced")
+		return
+	case errors.Is(err, payments.ErrGatewaySyncFailed):
+		utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Failed to sync plan with PayPal")
+		return
+	case err != nil:
+		utils.ReportInternalServerError(context, "Failed to sync plan")
+		return
+	}
+
+	controller.respondCataloguePlan(context, synced, "Plan synced")
+}
+
+// GetRevenueReport returns the monthly revenue metrics, MRR, churn and recognized revenue, per currency
+func (controller AdminController) GetRevenueReport(context *gin.Context) {
+	now := time.Now()
+	current := time.Date(now.UTC().Year(), now.UTC().Month(), 1, 0, 0, 0, 0, time.UTC)
+	from, to := current.AddDate(0, -11, 0), current
+	if param := context.Query("from"); param != "" {
+		month, err := time.Parse("2006-01", param)
+		if err != nil {
+			utils.ReportBadRequest(context, "Invalid from parameter, expected YYYY-MM")
+			return
+		}
+		from = month
+	}
+	if param := context.Query("to"); param != "" {
+		month, err := time.Parse("2006-01", param)
+		if err != nil {
+			utils.ReportBadRequest(context, "Invalid to parameter, expected YYYY-MM")
+			return
+		}
+		to = month
+	}
+	if to.Before(from) || to.After(from.AddDate(5, 0, 0)) {
+		utils.ReportBadRequest(context, "The report covers 1 month to 5 years, from before to")
+		return
+	}
+
+	report, err := payments.RevenueReport(from, to, now)
+	if err != nil {
+		log.Printf("Failed to compute revenue report: %v", err)
+		utils.ReportInternalServerError(context, "Failed to compute revenue report")
+		return
+	}
+
+	if utils.WantsCSV(context) {
+		utils.ServeCSV(context, "revenue-"+from.Format("2006-01")+"-"+to.Format("2006-01")+".csv", admin.RevenueReportCSV(report))
+		return
+	}
+
+	response := admin.RevenueReportOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: admin.FromRevenueMonthList(report),
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetInvoiceDeliveries lists every attempt at emailing an invoice, newest first
+func (controller AdminController) GetInvoiceDeliveries(context *gin.Context) {
+	invoiceID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid invoice ID")
+		return
+	}
+
+	invoiceDao := dao.NewInvoiceDao()
+	if _, err := invoiceDao.GetByID(invoiceID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Invoice not found")
+			return
+		}
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	deliveries, err := invoiceDao.GetDeliveries(invoiceID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	response := admin.InvoiceDeliveryListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: admin.FromInvoiceDeliveryModelList(deliveries),
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetBillingEvents returns the billing event log of every organization, or of
+// one, newest first, filtered by type, date and actor
+func (controller AdminController) GetBillingEvents(context *gin.Context) {
+	filter, page, ok := billingEventFilter(context)
+	if !ok {
+		return
+	}
+	if param := context.Query("organization_id"); param != "" {
+		organizationID, err := uuid.Parse(param)
+		if err != nil {
+			utils.ReportBadRequest(context, "Invalid organization_id parameter")
+			return
+		}
+		filter.OrganizationID = &organizationID
+	}
+
+	eventDao := dao.NewBillingEventDao()
+	events, total, err := eventDao.Search(filter, page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	totalPages := int(total) / eventDao.Limit
+	if int(total)%eventDao.Limit > 0 {
+		totalPages++
+	}
+
+	response := admin.BillingEventListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: admin.FromBillingEventModelList(events),
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      eventDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// catalogueParamPlan loads the plan of the id path parameter, or reports why it cannot.
+func (controller AdminController) catalogueParamPlan(context *gin.Context) (*model.Plan, bool) {
+	planID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid plan ID")
+		return nil, false
+	}
+
+	existing, err := dao.NewPlanDao().GetByID(planID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Plan not found")
+			return nil, false
+		}
+		utils.ReportInternalServerError(context, "Database error")
+		return nil, false
+	}
+	return existing, true
+}
+
+func (controller AdminController) respondCataloguePlan(context *gin.Context, p *model.Plan, description string) {
+	subscribers, err := dao.NewSubscriptionDao().CountActiveByPlanID(p.ID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	data := plan.FromCataloguePlanModel(p)
+	data.Subscribers = &subscribers
+	response := plan.CataloguePlanOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: description,
+		},
+		Data: data,
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// planPrices converts the non-USD prices of a plan request to price points,
+// both billing cycles of each currency.
+func planPrices(requests []plan.PlanPriceRequest) ([]model.PlanPrice, error) {
+	prices := []model.PlanPrice{}
+	seen := map[string]bool{}
+	for _, request := range requests {
+		currency := strings.ToUpper(request.Currency)
+		switch {
+		case !model.IsSupportedCurrency(currency):
+			return nil, fmt.Errorf("Unsupported currency %s", request.Currency)
+		case currency == model.DefaultCurrency:
+			return nil, errors.New("USD prices are price_monthly and price_yearly")
+		case seen[currency]:
+			return nil, fmt.Errorf("Currency %s is priced twice", currency)
+		}
+		seen[currency] = true
+		prices = append(prices,
+			model.PlanPrice{Currency: currency, BillingCycle: model.BillingCycleMonthly, Amount: model.ToMinorUnits(request.PriceMonthly, currency)},
+			model.PlanPrice{Currency: currency, BillingCycle: model.BillingCycleYearly, Amount: model.ToMinorUnits(request.PriceYearly, currency)},
+		)
+	}
+	return prices, nil
+}
+
+// applyPlanUpdate copies the fields set in request to p.
+func applyPlanUpdate(p *model.Plan, request plan.UpdatePlanRequest) error {
+	if request.Name != nil {
+		p.Name = *request.Name
+	}
+	if request.Description != nil {
+		p.Description = request.Description
+	}
+	if request.MaxUsers != nil {
+		p.MaxUsers = *request.MaxUsers
+	}
+	if request.MaxProjects != nil {
+		p.MaxProjects = *request.MaxProjects
+	}
+	if request.MaxEnvironments != nil {
+		p.MaxEnvironments = *request.MaxEnvironments
+	}
+	if request.MaxSchemas != nil {
+		p.MaxSchemas = *request.MaxSchemas
+	}
+	if request.MaxTestRecordsPerSchema != nil {
+		p.MaxTestRecordsPerSchema = *request.MaxTestRecordsPerSchema
+	}
+	if request.Features != nil {
+		features, err := json.Marshal(request.Features)
+		if err != nil {
+			return err
+		}
+		p.Features = string(features)
+	}
+	if request.UsageAlertThresholds != nil {
+		thresholds, err := json.Marshal(request.UsageAlertThresholds)
+		if err != nil {
+			return err
+		}
+		p.UsageAlertThresholds = string(thresholds)
+	}
+	return nil
+}
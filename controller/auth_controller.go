@@ -3,7 +3,11 @@ package controller
 import (
 	"errors"
 	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"testlake/dao"
 	"testlake/inout"
@@ -12,6 +16,7 @@ import (
 	"testlake/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
@@ -80,8 +85,8 @@ func (controller AuthController) SignUp(context *gin.Context) {
 		// log.Printf("Failed to send confirmation email: %v", err)
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(newUser.ID, newUser.Email, newUser.Username)
+	// Start a session and generate JWT token
+	token, err := controller.startSession(context, newUser, model.AuthMethodSignUp)
 	if err != nil {
 		utils.ReportInternalServerError(context, "Failed to generate token")
 		return
@@ -109,11 +114,21 @@ func (controller AuthController) SignIn(context *gin.Context) {
 		return
 	}
 
+	throttleConfig := utils.LoadLoginThrottleConfig()
+	throttleDao := dao.NewLoginThrottleDao()
+	accountKey := utils.NormalizeLoginKey(request.Email)
+	clientIP := context.ClientIP()
+
+	// Reject attempts while the account or the client IP is backing off or locked
+	if controller.rejectThrottledSignIn(context, throttleDao, throttleConfig, accountKey, clientIP) {
+		return
+	}
+
 	userDao := dao.NewUserDao()
 	foundUser, err := userDao.GetByEmail(request.Email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.ReportUnauthorized(context, "Invalid credentials")
+			controller.registerFailedSignIn(context, throttleDao, throttleConfig, accountKey, clientIP, nil)
 		} else {
 			utils.ReportInternalServerError(context, "Database error")
 		}
@@ -122,18 +137,37 @@ func (controller AuthController) SignIn(context *gin.Context) {
 
 	// Check password
 	if foundUser.PasswordHash == nil || !utils.CheckPasswordHash(request.Password, *foundUser.PasswordHash) {
-		utils.ReportUnauthorized(context, "Invalid credentials")
+		controller.registerFailedSignIn(context, throttleDao, throttleConfig, accountKey, clientIP, foundUser)
 		return
 	}
 
 	// Check user status
 	if foundUser.Status != model.UserStatusActive {
+		controller.recordLoginEvent(context, accountKey, &foundUser.ID, model.AuthMethodPassword, model.LoginOutcomeInactiveAccount, nil)
 		utils.ReportForbidden(context, "Account is not active")
 		return
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(foundUser.ID, foundUser.Email, foundUser.Username)
+	// Organizations may require their members to sign in through their identity provider
+	ssoEnforced, err := dao.NewOrganizationSSODao().IsEnforcedForUser(foundUser.ID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+	if ssoEnforced {
+		controller.recordLoginEvent(context, accountKey, &foundUser.ID, model.AuthMethodPassword, model.LoginOutcomeSSORequired, nil)
+		utils.ReportForbidden(context, "Your organization requires single sign-on, use POST /api/v1/auth/sso/discover to sign in")
+		return
+	}
+
+	// Successful sign-in clears the account failures; IP failures decay with the window
+	if err := throttleDao.Reset(model.LoginThrottleScopeAccount, accountKey); err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	// Start a session and generate JWT token
+	token, err := controller.startSession(context, foundUser, model.AuthMethodPassword)
 	if err != nil {
 		utils.ReportInternalServerError(context, "Failed to generate token")
 		return
@@ -156,10 +190,205 @@ func (controller AuthController) SignIn(context *gin.Context) {
 	context.JSON(http.StatusOK, response)
 }
 
+// startSession records the sign-in, creates a session and issues a token bound to it
+func (controller AuthController) startSession(context *gin.Context, foundUser *model.User, method model.AuthMethod) (string, error) {
+	now := time.Now()
+	session := &model.UserSession{
+		UserID:     foundUser.ID,
+		IPAddress:  context.ClientIP(),
+		UserAgent:  utils.TruncateString(context.Request.UserAgent(), 500),
+		AuthMethod: method,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(utils.TokenTTL()),
+	}
+
+	if err := dao.NewUserSessionDao().Create(session); err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateJWT(foundUser.ID, foundUser.Email, foundUser.Username, session.ID)
+	if err != nil {
+		return "", err
+	}
+
+	controller.recordLoginEvent(context, utils.NormalizeLoginKey(foundUser.Email), &foundUser.ID, method, model.LoginOutcomeSuccess, &session.ID)
+	return token, nil
+}
+
+// recordLoginEvent stores a sign-in attempt for the login history. Failures are
+// logged only, as the history must never prevent a user from signing in.
+func (controller AuthController) recordLoginEvent(context *gin.Context, email string, userID *uuid.UUID, method model.AuthMethod, outcome model.LoginOutcome, sessionID *uuid.UUID) {
+	event := &model.LoginEvent{
+		UserID:     userID,
+		Email:      email,
+		IPAddress:  context.ClientIP(),
+		UserAgent:  utils.TruncateString(context.Request.UserAgent(), 500),
+		AuthMethod: method,
+		Outcome:    outcome,
+		SessionID:  sessionID,
+	}
+
+	if err := dao.NewLoginEventDao().Create(event); err != nil {
+		log.Printf("Failed to record login event: %v", err)
+	}
+}
+
+// rejectThrottledSignIn responds with 429 when the account or IP must wait before trying again
+func (controller AuthController) rejectThrottledSignIn(context *gin.Context, throttleDao *dao.LoginThrottleDao, config utils.LoginThrottleConfig, accountKey, clientIP string) bool {
+	accountThrottle, err := throttleDao.Get(model.LoginThrottleScopeAccount, accountKey)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return true
+	}
+
+	ipThrottle, err := throttleDao.Get(model.LoginThrottleScopeIP, clientIP)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return true
+	}
+
+	now := time.Now()
+	retryAt := time.Time{}
+	for _, throttle := range []*model.LoginThrottle{accountThrottle, ipThrottle} {
+		var next time.Time
+		if throttle.IsLocked(now) {
+			next = *throttle.LockedUntil
+		} else if !throttle.IsWindowExpired(now, config.Window) {
+			next = throttle.NextAttemptAt(config.BaseDelay, config.MaxDelay)
+		}
+		if next.After(retryAt) {
+			retryAt = next
+		}
+	}
+
+	if !retryAt.After(now) {
+		return false
+	}
+
+	controller.recordLoginEvent(context, accountKey, nil, model.AuthMethodPassword, model.LoginOutcomeThrottled, nil)
+
+	message := "Too many failed sign-in attempts. Please try again later"
+	if accountThrottle.IsLocked(now) || ipThrottle.IsLocked(now) {
+		message = "Sign-in temporarily locked due to too many failed attempts"
+	}
+
+	controller.reportTooManyAttempts(context, retryAt.Sub(now), message)
+	return true
+}
+
+// registerFailedSignIn records a failure against the account and the IP, and
+// notifies the user by email when the account gets locked
+func (controller AuthController) registerFailedSignIn(context *gin.Context, throttleDao *dao.LoginThrottleDao, config utils.LoginThrottleConfig, accountKey, clientIP string, foundUser *model.User) {
+	accountThrottle, accountLocked, err := throttleDao.RecordFailure(model.LoginThrottleScopeAccount, accountKey,
+		config.AccountThreshold, config.Window, config.LockoutDuration)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	_, ipLocked, err := throttleDao.RecordFailure(model.LoginThrottleScopeIP, clientIP,
+		config.IPThreshold, config.Window, config.LockoutDuration)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	var userID *uuid.UUID
+	outcome := model.LoginOutcomeInvalidCredentials
+	if foundUser != nil {
+		userID = &foundUser.ID
+	}
+	if accountLocked || ipLocked {
+		outcome = model.LoginOutcomeLocked
+	}
+	controller.recordLoginEvent(context, accountKey, userID, model.AuthMethodPassword, outcome, nil)
+
+	if accountLocked && foundUser != nil {
+		lockedUntil := *accountThrottle.LockedUntil
+		unlockToken := utils.SignLinkToken(utils.LinkPurposeAccountUnlock, foundUser.ID,
+			strconv.FormatInt(lockedUntil.Unix(), 10), config.LockoutDuration)
+
+		// Sent in the background so response timing does not reveal whether the account exists
+		go func(email, username string) {
+			if err := utils.SendAccountLocked(email, username, unlockToken, lockedUntil); err != nil {
+				log.Printf("Failed to send account locked email: %v", err)
+			}
+		}(foundUser.Email, foundUser.Username)
+	}
+
+	if accountLocked || ipLocked {
+		controller.reportTooManyAttempts(context, config.LockoutDuration, "Sign-in temporarily locked due to too many failed attempts")
+		return
+	}
+
+	utils.ReportUnauthorized(context, "Invalid credentials")
+}
+
+func (controller AuthController) reportTooManyAttempts(context *gin.Context, retryAfter time.Duration, message string) {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	context.Header("Retry-After", strconv.Itoa(seconds))
+	utils.ReportCustomError(context, http.StatusTooManyRequests, http.StatusTooManyRequests, message)
+}
+
+// UnlockAccount lifts a sign-in lockout using the signed link from the lockout email
+func (controller AuthController) UnlockAccount(context *gin.Context) {
+	userID, nonce, err := utils.VerifyLinkToken(utils.LinkPurposeAccountUnlock, context.Param("token"))
+	if err != nil {
+		controller.renderTemplateError(context, http.StatusBadRequest, "Invalid Link", "Invalid unlock link", "The unlock link is invalid or has expired.")
+		return
+	}
+
+	userDao := dao.NewUserDao()
+	foundUser, err := userDao.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			controller.renderTemplateError(context, http.StatusNotFound, "Invalid Link", "Invalid unlock link", "User account not found.")
+			return
+		}
+		controller.renderTemplateError(context, http.StatusInternalServerError, "Unlock Failed", "Unlock failed", "An error occurred while processing your request.")
+		return
+	}
+
+	throttleDao := dao.NewLoginThrottleDao()
+	accountKey := utils.NormalizeLoginKey(foundUser.Email)
+	throttle, err := throttleDao.Get(model.LoginThrottleScopeAccount, accountKey)
+	if err != nil {
+		controller.renderTemplateError(context, http.StatusInternalServerError, "Unlock Failed", "Unlock failed", "An error occurred while processing your request.")
+		return
+	}
+
+	if !throttle.IsLocked(time.Now()) {
+		controller.renderSuccess(context, "Account Unlocked", "Account already unlocked", "Your account is not locked. You can sign in to TestLake.")
+		return
+	}
+
+	// Links from an earlier lockout must not lift the current one
+	if nonce != strconv.FormatInt(throttle.LockedUntil.Unix(), 10) {
+		controller.renderTemplateError(context, http.StatusBadRequest, "Invalid Link", "Invalid unlock link", "This unlock link is no longer valid. Please use the link from the most recent email.")
+		return
+	}
+
+	if err := throttleDao.Reset(model.LoginThrottleScopeAccount, accountKey); err != nil {
+		controller.renderTemplateError(context, http.StatusInternalServerError, "Unlock Failed", "Unlock failed", "Failed to unlock your account.")
+		return
+	}
+
+	controller.renderSuccess(context, "Account Unlocked", "Account unlocked", "Your account has been unlocked. You can now sign in to TestLake.")
+}
+
 // SignOut invalidates the current JWT token
 func (controller AuthController) SignOut(context *gin.Context) {
-	// For JWT-based auth, client-side token removal is sufficient
-	// In a more sophisticated setup, we could maintain a token blacklist
+	// Revoke the session behind the token so it cannot be used again.
+	// Session-less or already invalid tokens only need client-side removal.
+	if err := utils.ValidateJWT(context); err == nil {
+		if sessionID := utils.ExtractSessionID(context); sessionID != uuid.Nil {
+			if err := dao.NewUserSessionDao().Revoke(sessionID); err != nil {
+				utils.ReportInternalServerError(context, "Failed to revoke session")
+				return
+			}
+		}
+	}
+
 	response := inout.BaseResponse{
 		ErrorCode:        0,
 		ErrorDescription: "Signed out successfully",
@@ -199,13 +428,22 @@ func (controller AuthController) RefreshToken(context *gin.Context) {
 		return
 	}
 
-	// Generate new JWT token
-	newToken, err := utils.GenerateJWT(foundUser.ID, foundUser.Email, foundUser.Username)
+	// Generate new JWT token for the same session and extend the session to match
+	sessionID := utils.ExtractSessionID(context)
+	newToken, err := utils.GenerateJWT(foundUser.ID, foundUser.Email, foundUser.Username, sessionID)
 	if err != nil {
 		utils.ReportInternalServerError(context, "Failed to generate token")
 		return
 	}
 
+	if sessionID != uuid.Nil {
+		expiresAt := time.Now().Add(utils.TokenTTL())
+		if err := dao.NewUserSessionDao().Touch(sessionID, &expiresAt); err != nil {
+			utils.ReportInternalServerError(context, "Database error")
+			return
+		}
+	}
+
 	response := auth.RefreshTokenOut{
 		BaseResponse: inout.BaseResponse{
 			ErrorCode:        0,
@@ -219,6 +457,18 @@ func (controller AuthController) RefreshToken(context *gin.Context) {
 	context.JSON(http.StatusOK, response)
 }
 
+// GetJWKS publishes the public keys used to verify TestLake tokens
+func (controller AuthController) GetJWKS(context *gin.Context) {
+	keys, err := utils.PublicJWKs()
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to load signing keys")
+		return
+	}
+
+	context.Header("Cache-Control", "public, max-age=300")
+	context.JSON(http.StatusOK, auth.JWKSOut{Keys: keys})
+}
+
 // Helper method to render template-based error pages
 func (controller AuthController) renderTemplateError(context *gin.Context, statusCode int, title, heading, message string) {
 	htmlContent, err := utils.RenderEmailVerificationError(title, heading, message)
@@ -232,6 +482,18 @@ func (controller AuthController) renderTemplateError(context *gin.Context, statu
 	context.String(statusCode, htmlContent)
 }
 
+// Helper method to render template-based success pages
+func (controller AuthController) renderSuccess(context *gin.Context, title, heading, message string) {
+	htmlContent, err := utils.RenderActionSuccess(title, heading, message)
+	if err != nil {
+		context.String(http.StatusOK, fmt.Sprintf("%s: %s", heading, message))
+		return
+	}
+
+	context.Header("Content-Type", "text/html; charset=utf-8")
+	context.String(http.StatusOK, htmlContent)
+}
+
 // ForgotPassword initiates password reset process
 func (controller AuthController) ForgotPassword(context *gin.Context) {
 	var request auth.ForgotPasswordRequest
@@ -297,7 +559,7 @@ func (controller AuthController) VerifyEmail(context *gin.Context) {
 
 	// Get token from database
 	tokenDao := dao.NewEmailVerificationDao()
-	token, err := tokenDao.GetByToken(tokenStr)
+	token, err := tokenDao.GetByToken(tokenStr, model.EmailTokenPurposeVerifyEmail)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			controller.renderTemplateError(context, http.StatusNotFound, "Invalid Link", "Invalid verification link", "The verification token is invalid or has expired.")
@@ -358,6 +620,50 @@ func (controller AuthController) VerifyEmail(context *gin.Context) {
 	context.String(http.StatusOK, successHTML)
 }
 
+// ConfirmEmailChange applies a pending email change once the new address is confirmed
+func (controller AuthController) ConfirmEmailChange(context *gin.Context) {
+	tokenStr := context.Param("token")
+	if tokenStr == "" {
+		controller.renderTemplateError(context, http.StatusBadRequest, "Invalid Link", "Invalid confirmation link", "The confirmation token is missing.")
+		return
+	}
+
+	tokenDao := dao.NewEmailVerificationDao()
+	token, err := tokenDao.GetByToken(tokenStr, model.EmailTokenPurposeChangeEmail)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			controller.renderTemplateError(context, http.StatusNotFound, "Invalid Link", "Invalid confirmation link", "The confirmation token is invalid or has expired.")
+			return
+		}
+		controller.renderTemplateError(context, http.StatusInternalServerError, "Confirmation Failed", "Confirmation failed", "An error occurred while processing your confirmation.")
+		return
+	}
+
+	if !token.IsValid() {
+		if token.IsUsed {
+			controller.renderTemplateError(context, http.StatusBadRequest, "Already Confirmed", "Email change already confirmed", "This email change has already been confirmed.")
+			return
+		}
+		controller.renderTemplateError(context, http.StatusBadRequest, "Link Expired", "Confirmation link expired", "This confirmation link has expired. Please request the email change again.")
+		return
+	}
+
+	if err := tokenDao.ApplyEmailChange(token); err != nil {
+		if errors.Is(err, dao.ErrTokenAlreadyUsed) {
+			controller.renderTemplateError(context, http.StatusBadRequest, "Already Confirmed", "Email change already confirmed", "This email change has already been confirmed.")
+			return
+		}
+		if errors.Is(err, dao.ErrEmailAlreadyInUse) {
+			controller.renderTemplateError(context, http.StatusConflict, "Email Unavailable", "Email address unavailable", "This email address is now used by another account. Please request the change with a different address.")
+			return
+		}
+		controller.renderTemplateError(context, http.StatusInternalServerError, "Confirmation Failed", "Confirmation failed", "Failed to update your email address.")
+		return
+	}
+
+	controller.renderSuccess(context, "Email Changed", "Email address changed", "Your TestLake account now uses "+*token.NewEmail+". Use it the next time you sign in.")
+}
+
 // ResendEmailConfirmation resends email confirmation to user
 func (controller AuthController) ResendEmailConfirmation(context *gin.Context) {
 	var request auth.ResendEmailConfirmationRequest
@@ -2,8 +2,10 @@ package controller
 
 import (
 	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"testlake/dao"
 	"testlake/inout"
 	"testlake/inout/billing"
@@ -11,7 +13,9 @@ import (
 	"testlake/inout/plan"
 	"testlake/inout/subscription"
 	"testlake/model"
+	"testlake/payments"
 	"testlake/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -22,21 +26,14 @@ type BillingController struct{}
 
 // GetBillingOverview returns billing overview for an organization
 func (controller BillingController) GetBillingOverview(context *gin.Context) {
-	orgIDParam := context.Param("orgId")
+	orgIDParam := context.Param("id")
 	organizationID, err := uuid.Parse(orgIDParam)
 	if err != nil {
 		utils.ReportBadRequest(context, "Invalid organization ID")
 		return
 	}
 
-	userID, err := utils.ExtractUserID(context)
-	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
-		return
-	}
-
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForMember(context, organizationID); !ok {
 		return
 	}
 
@@ -58,15 +55,20 @@ func (controller BillingController) GetBillingOverview(context *gin.Context) {
 		planDao := dao.NewPlanDao()
 		currentPlan, err := planDao.GetByID(*org.PlanID)
 		if err == nil {
-			planData := plan.FromPlanModel(currentPlan)
+			currency := org.Currency
+			if currentSub != nil {
+				currency = currentSub.Currency
+			}
+			planData := plan.FromPlanModel(currentPlan, currency)
 			overview.CurrentPlan = &planData
 
 			// Calculate next billing amount
-			if org.BillingCycle == model.BillingCycleMonthly {
-				overview.NextBillingAmount = currentPlan.PriceMonthly
-			} else {
-				overview.NextBillingAmount = currentPlan.PriceYearly
+			nextAmount, _ := currentPlan.PriceIn(currency, org.BillingCycle)
+			if currentSub != nil && currentSub.PlanID == currentPlan.ID {
+				nextAmount -= payments.UpcomingDiscount(currentSub, nextAmount, currency)
 			}
+			overview.NextBillingAmount = model.FromMinorUnits(nextAmount, currency)
+			overview.Currency = currency
 		}
 	}
 
@@ -122,21 +124,14 @@ func (controller BillingController) GetBillingOverview(context *gin.Context) {
 
 // GetInvoices returns invoices for an organization
 func (controller BillingController) GetInvoices(context *gin.Context) {
-	orgIDParam := context.Param("orgId")
+	orgIDParam := context.Param("id")
 	organizationID, err := uuid.Parse(orgIDParam)
 	if err != nil {
 		utils.ReportBadRequest(context, "Invalid organization ID")
 		return
 	}
 
-	userID, err := utils.ExtractUserID(context)
-	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
-		return
-	}
-
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForMember(context, organizationID); !ok {
 		return
 	}
 
@@ -185,12 +180,6 @@ func (controller BillingController) GetInvoice(context *gin.Context) {
 		return
 	}
 
-	userID, err := utils.ExtractUserID(context)
-	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
-		return
-	}
-
 	invoiceDao := dao.NewInvoiceDao()
 	invoice, err := invoiceDao.GetByID(invoiceID)
 	if err != nil {
@@ -203,8 +192,7 @@ func (controller BillingController) GetInvoice(context *gin.Context) {
 	}
 
 	// Verify user has access to the organization
-	if !controller.verifyOrganizationAccess(userID, invoice.OrganizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForMember(context, invoice.OrganizationID); !ok {
 		return
 	}
 
@@ -219,7 +207,7 @@ func (controller BillingController) GetInvoice(context *gin.Context) {
 	context.JSON(http.StatusOK, response)
 }
 
-// DownloadInvoice provides a download link for an invoice
+// DownloadInvoice streams the PDF of an invoice
 func (controller BillingController) DownloadInvoice(context *gin.Context) {
 	idParam := context.Param("id")
 	invoiceID, err := uuid.Parse(idParam)
@@ -228,17 +216,104 @@ func (controller BillingController) DownloadInvoice(context *gin.Context) {
 		return
 	}
 
-	userID, err := utils.ExtractUserID(context)
+	invoiceDao := dao.NewInvoiceDao()
+	invoice, err := invoiceDao.GetByID(invoiceID)
 	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Invoice not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
 		return
 	}
 
-	invoiceDao := dao.NewInvoiceDao()
-	invoice, err := invoiceDao.GetByID(invoiceID)
+	// Verify user has access to the organization
+	if _, ok := organizationForMember(context, invoice.OrganizationID); !ok {
+		return
+	}
+
+	if invoice.Status == model.InvoiceStatusDraft {
+		utils.ReportNotFound(context, "Invoice has not been issued yet")
+		return
+	}
+
+	document, err := utils.InvoiceDocument(invoice)
+	if err != nil {
+		log.Printf("Failed to render invoice %s: %v", invoice.InvoiceNumber, err)
+		utils.ReportInternalServerError(context, "Failed to render invoice")
+		return
+	}
+
+	etag := `"` + document.Checksum + `"`
+	context.Header("ETag", etag)
+	context.Header("Cache-Control", "private, no-cache")
+	if context.GetHeader("If-None-Match") == etag {
+		context.Status(http.StatusNotModified)
+		return
+	}
+	context.Header("Content-Disposition", `attachment; filename="`+document.Filename+`"`)
+	context.Data(http.StatusOK, "application/pdf", document.Content)
+}
+
+// GetCreditNotes returns the credit notes of an organization, newest first
+func (controller BillingController) GetCreditNotes(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	if _, ok := organizationForMember(context, organizationID); !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(context.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		utils.ReportBadRequest(context, "Invalid page parameter")
+		return
+	}
+
+	creditNoteDao := dao.NewCreditNoteDao()
+	creditNotes, total, err := creditNoteDao.GetByOrganizationID(organizationID, page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	totalPages := int(total) / creditNoteDao.Limit
+	if int(total)%creditNoteDao.Limit > 0 {
+		totalPages++
+	}
+
+	response := billing.CreditNoteListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: billing.FromCreditNoteModelList(creditNotes),
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      creditNoteDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// DownloadCreditNote streams the PDF of an issued credit note
+func (controller BillingController) DownloadCreditNote(context *gin.Context) {
+	creditNoteID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid credit note ID")
+		return
+	}
+
+	creditNote, err := dao.NewCreditNoteDao().GetByID(creditNoteID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.ReportNotFound(context, "Invoice not found")
+			utils.ReportNotFound(context, "Credit note not found")
 		} else {
 			utils.ReportInternalServerError(context, "Database error")
 		}
@@ -246,17 +321,31 @@ func (controller BillingController) DownloadInvoice(context *gin.Context) {
 	}
 
 	// Verify user has access to the organization
-	if !controller.verifyOrganizationAccess(userID, invoice.OrganizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForMember(context, creditNote.OrganizationID); !ok {
+		return
+	}
+
+	if creditNote.Status != model.CreditNoteStatusIssued {
+		utils.ReportNotFound(context, "Credit note has not been issued")
+		return
+	}
+
+	content, checksum, err := utils.CreditNoteDocument(creditNote)
+	if err != nil {
+		log.Printf("Failed to render credit note %s: %v", creditNote.CreditNoteNumber, err)
+		utils.ReportInternalServerError(context, "Failed to render credit note")
 		return
 	}
 
-	// In a real implementation, this would generate and return a PDF or redirect to a PDF URL
-	if invoice.InvoiceURL != nil {
-		context.Redirect(http.StatusFound, *invoice.InvoiceURL)
-	} else {
-		utils.ReportNotFound(context, "Invoice download not available")
+	etag := `"` + checksum + `"`
+	context.Header("ETag", etag)
+	context.Header("Cache-Control", "private, no-cache")
+	if context.GetHeader("If-None-Match") == etag {
+		context.Status(http.StatusNotModified)
+		return
 	}
+	context.Header("Content-Disposition", `attachment; filename="`+creditNote.PDFFilename()+`"`)
+	context.Data(http.StatusOK, "application/pdf", content)
 }
 
 // PayInvoice processes payment for an invoice
@@ -292,50 +381,154 @@ func (controller BillingController) PayInvoice(context *gin.Context) {
 	}
 
 	// Verify user has access to the organization
-	if !controller.verifyOrganizationAccess(userID, invoice.OrganizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, invoice.OrganizationID, "Only organization owners and admins can pay invoices"); !ok {
 		return
 	}
 
 	// Check if invoice is already paid
-	if invoice.Status == model.InvoiceStatusPaid {
+	if invoice.IsPaid() {
 		utils.ReportBadRequest(context, "Invoice is already paid")
 		return
 	}
 
-	// In a real implementation, this would process payment through PayPal
-	// For now, we'll simulate a successful payment
-
-	// Create payment record
 	paymentDao := dao.NewPaymentDao()
-	newPayment := &model.Payment{
-		OrganizationID: invoice.OrganizationID,
-		InvoiceID:      &invoice.ID,
-		Amount:         invoice.TotalAmount,
-		Currency:       invoice.Currency,
-		PaymentMethod:  model.PaymentMethodEnumPayPal,
-		Status:         model.PaymentStatusCompleted,
+	gateway := utils.GetPaymentGateway()
+
+	// First step: open a checkout order and send the payer to approve it
+	if request.OrderID == nil {
+		returnURL, cancelURL := utils.PaymentReturnURLs()
+		order, err := gateway.CreateOrder(context.Request.Context(), utils.GatewayOrderRequest{
+			ReferenceID:   invoice.ID.String(),
+			CustomID:      invoice.ID.String(),
+			InvoiceNumber: invoice.InvoiceNumber,
+			Description:   "Invoice " + invoice.InvoiceNumber,
+			Amount:        invoice.TotalAmount,
+			Currency:      invoice.Currency,
+			ReturnURL:     returnURL,
+			CancelURL:     cancelURL,
+		})
+		if err != nil {
+			log.Printf("Failed to create gateway order for invoice %s: %v", invoice.ID, err)
+			utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider error")
+			return
+		}
+
+		newPayment := &model.Payment{
+			OrganizationID:  invoice.OrganizationID,
+			InvoiceID:       &invoice.ID,
+			SubscriptionID:  invoice.SubscriptionID,
+			PayPalPaymentID: &order.ID,
+			Amount:          invoice.TotalAmount,
+			Currency:        invoice.Currency,
+			PaymentMethod:   model.PaymentMethodEnumPayPal,
+			Status:          model.PaymentStatusPending,
+		}
+		err = paymentDao.Create(newPayment)
+		if err != nil {
+			utils.ReportInternalServerError(context, "Failed to create payment record")
+			return
+		}
+
+		data := payment.FromPaymentModel(newPayment)
+		data.ApprovalURL = order.ApprovalURL
+		response := billing.PaymentOut{
+			BaseResponse: inout.BaseResponse{
+				ErrorCode:        0,
+				ErrorDescription: "Payment approval required",
+			},
+			Data: data,
+		}
+		context.JSON(http.StatusAccepted, response)
+		return
 	}
 
-	err = paymentDao.Create(newPayment)
+	// Second step: the payer approved the order, capture the funds
+	pendingPayment, err := paymentDao.GetByPayPalPaymentID(*request.OrderID)
 	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to create payment record")
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Order not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+	if pendingPayment.InvoiceID == nil || *pendingPayment.InvoiceID != invoice.ID || pendingPayment.Status != model.PaymentStatusPending {
+		utils.ReportBadRequest(context, "Order does not belong to this invoice")
 		return
 	}
 
-	// Update invoice status
-	err = invoiceDao.UpdateStatus(invoice.ID, model.InvoiceStatusPaid)
+	capture, err := gateway.CaptureOrder(context.Request.Context(), *request.OrderID, pendingPayment.ID.String())
+	if err != nil {
+		log.Printf("Failed to capture gateway order %s: %v", *request.OrderID, err)
+		reason := err.Error()
+		pendingPayment.Status = model.PaymentStatusFailed
+		pendingPayment.FailureReason = &reason
+		updateErr := dao.Transaction(func(tx *gorm.DB) error {
+			if err := paymentDao.WithTx(tx).Update(pendingPayment); err != nil {
+				return err
+			}
+			return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(invoice.OrganizationID, model.BillingEventTypePaymentFailed, map[string]interface{}{
+				"invoice_id": invoice.ID,
+				"payment_id": pendingPayment.ID,
+				"order_id":   *request.OrderID,
+				"reason":     reason,
+			})
+		})
+		if updateErr != nil {
+			log.Printf("Failed to record payment failure %s: %v", pendingPayment.ID, updateErr)
+		}
+		utils.ReportCustomError(context, http.StatusPaymentRequired, http.StatusPaymentRequired, "Payment could not be captured")
+		return
+	}
+
+	// Never trust the approval alone, the captured funds must match the invoice
+	if capture.Status != "COMPLETED" || capture.CustomID != invoice.ID.String() ||
+		capture.Currency != invoice.Currency || capture.Amount != invoice.TotalAmount {
+		log.Printf("Captured order %s does not match invoice %s: %+v", *request.OrderID, invoice.ID, capture)
+		utils.ReportCustomError(context, http.StatusPaymentRequired, http.StatusPaymentRequired, "Captured payment does not match the invoice")
+		return
+	}
+
+	now := time.Now()
+	pendingPayment.PayPalPaymentID = &capture.ID
+	if capture.PayerID != "" {
+		pendingPayment.PayPalPayerID = &capture.PayerID
+	}
+	pendingPayment.Status = model.PaymentStatusCompleted
+	pendingPayment.ProcessedAt = &now
+	err = paymentDao.Update(pendingPayment)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to update payment record")
+		return
+	}
+
+	// Settling the invoice ends its dunning and lifts a suspension it caused
+	var notice *utils.BillingNotice
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		var err error
+		notice, err = payments.SettleInvoice(tx, invoice, now, model.UserActor(userID))
+		if err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(invoice.OrganizationID, model.BillingEventTypePaymentSucceeded, map[string]interface{}{
+			"invoice_id": invoice.ID,
+			"payment_id": pendingPayment.ID,
+			"capture_id": capture.ID,
+			"amount":     capture.Amount,
+		})
+	})
 	if err != nil {
 		utils.ReportInternalServerError(context, "Failed to update invoice status")
 		return
 	}
+	payments.SendBillingNotice(invoice.OrganizationID, notice)
 
 	response := billing.PaymentOut{
 		BaseResponse: inout.BaseResponse{
 			ErrorCode:        0,
 			ErrorDescription: "Payment processed successfully",
 		},
-		Data: payment.FromPaymentModel(newPayment),
+		Data: payment.FromPaymentModel(pendingPayment),
 	}
 
 	context.JSON(http.StatusOK, response)
@@ -343,21 +536,14 @@ func (controller BillingController) PayInvoice(context *gin.Context) {
 
 // GetBillingHistory returns billing history for an organization
 func (controller BillingController) GetBillingHistory(context *gin.Context) {
-	orgIDParam := context.Param("orgId")
+	orgIDParam := context.Param("id")
 	organizationID, err := uuid.Parse(orgIDParam)
 	if err != nil {
 		utils.ReportBadRequest(context, "Invalid organization ID")
 		return
 	}
 
-	userID, err := utils.ExtractUserID(context)
-	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
-		return
-	}
-
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForMember(context, organizationID); !ok {
 		return
 	}
 
@@ -379,7 +565,7 @@ func (controller BillingController) GetBillingHistory(context *gin.Context) {
 			historyItems = append(historyItems, billing.BillingHistoryItem{
 				ID:          invoice.ID,
 				Type:        "invoice",
-				Amount:      invoice.TotalAmount,
+				Amount:      model.FromMinorUnits(invoice.TotalAmount, invoice.Currency),
 				Currency:    invoice.Currency,
 				Status:      string(invoice.Status),
 				Description: "Invoice " + invoice.InvoiceNumber,
@@ -390,13 +576,13 @@ func (controller BillingController) GetBillingHistory(context *gin.Context) {
 
 	// Get payments
 	paymentDao := dao.NewPaymentDao()
-	payments, _, err := paymentDao.GetByOrganizationID(organizationID, 0) // Get all for history
+	organizationPayments, _, err := paymentDao.GetByOrganizationID(organizationID, 0) // Get all for history
 	if err == nil {
-		for _, payment := range payments {
+		for _, payment := range organizationPayments {
 			historyItems = append(historyItems, billing.BillingHistoryItem{
 				ID:          payment.ID,
 				Type:        "payment",
-				Amount:      payment.Amount,
+				Amount:      model.FromMinorUnits(payment.Amount, payment.Currency),
 				Currency:    payment.Currency,
 				Status:      string(payment.Status),
 				Description: "Payment",
@@ -405,6 +591,22 @@ func (controller BillingController) GetBillingHistory(context *gin.Context) {
 		}
 	}
 
+	// Get credit notes
+	creditNotes, _, err := dao.NewCreditNoteDao().GetByOrganizationID(organizationID, 0)
+	if err == nil {
+		for _, creditNote := range creditNotes {
+			historyItems = append(historyItems, billing.BillingHistoryItem{
+				ID:          creditNote.ID,
+				Type:        "credit_note",
+				Amount:      model.FromMinorUnits(-creditNote.Amount, creditNote.Currency),
+				Currency:    creditNote.Currency,
+				Status:      string(creditNote.Status),
+				Description: "Credit note " + creditNote.CreditNoteNumber + " for invoice " + creditNote.Invoice.InvoiceNumber,
+				Date:        creditNote.CreatedAt,
+			})
+		}
+	}
+
 	// Pagination
 	limit := 50
 	total := int64(len(historyItems))
@@ -440,9 +642,167 @@ func (controller BillingController) GetBillingHistory(context *gin.Context) {
 	context.JSON(http.StatusOK, response)
 }
 
-// Helper method to verify organization access
-func (controller BillingController) verifyOrganizationAccess(userID, organizationID uuid.UUID) bool {
-	orgDao := dao.NewOrganizationDao()
-	_, err := orgDao.GetByID(organizationID)
-	return err == nil
+// GetBillingEvents returns the billing event log of an organization, newest first, filtered by type, date and actor
+func (controller BillingController) GetBillingEvents(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can view billing events"); !ok {
+		return
+	}
+
+	filter, page, ok := billingEventFilter(context)
+	if !ok {
+		return
+	}
+	filter.OrganizationID = &organizationID
+
+	eventDao := dao.NewBillingEventDao()
+	events, total, err := eventDao.Search(filter, page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	totalPages := int(total) / eventDao.Limit
+	if int(total)%eventDao.Limit > 0 {
+		totalPages++
+	}
+
+	response := billing.BillingEventListOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: billing.FromBillingEventModelList(events),
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      eventDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// billingEventFilter reads the filters of the billing event log from the query:
+// type (comma separated), from and to (a date, to inclusive, or an RFC 3339
+// time, to exclusive), actor_type, actor_id and page. It reports a bad request
+// when one is invalid.
+func billingEventFilter(context *gin.Context) (dao.BillingEventFilter, int, bool) {
+	var filter dao.BillingEventFilter
+
+	page, err := strconv.Atoi(context.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		utils.ReportBadRequest(context, "Invalid page parameter")
+		return filter, 0, false
+	}
+
+	for _, eventType := range strings.Split(context.Query("type"), ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			filter.EventTypes = append(filter.EventTypes, model.BillingEventType(eventType))
+		}
+	}
+
+	if filter.From, err = billingEventTime(context.Query("from"), false); err != nil {
+		utils.ReportBadRequest(context, "Invalid from parameter, expected YYYY-MM-DD or an RFC 3339 time")
+		return filter, 0, false
+	}
+	if filter.To, err = billingEventTime(context.Query("to"), true); err != nil {
+		utils.ReportBadRequest(context, "Invalid to parameter, expected YYYY-MM-DD or an RFC 3339 time")
+		return filter, 0, false
+	}
+	if filter.From != nil && filter.To != nil && !filter.To.After(*filter.From) {
+		utils.ReportBadRequest(context, "to must be after from")
+		return filter, 0, false
+	}
+
+	if actorType := model.BillingActorType(context.Query("actor_type")); actorType != "" {
+		switch actorType {
+		case model.BillingActorTypeUser, model.BillingActorTypeAdmin, model.BillingActorTypePayPal, model.BillingActorTypeSystem:
+			filter.ActorType = actorType
+		default:
+			utils.ReportBadRequest(context, "Invalid actor_type parameter, expected user, admin, paypal or system")
+			return filter, 0, false
+		}
+	}
+	if param := context.Query("actor_id"); param != "" {
+		actorID, err := uuid.Parse(param)
+		if err != nil {
+			utils.ReportBadRequest(context, "Invalid actor_id parameter")
+			return filter, 0, false
+		}
+		filter.ActorID = &actorID
+	}
+
+	return filter, page, true
+}
+
+// billingEventTime parses a time filter: an RFC 3339 time, or a date that a to
+// filter includes the whole of. It returns nil when param is empty.
+func billingEventTime(param string, to bool) (*time.Time, error) {
+	if param == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, param); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", param)
+	if err != nil {
+		return nil, err
+	}
+	if to {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
+// GetBillingForecast returns the charges an organization is expected to be billed over the next months
+func (controller BillingController) GetBillingForecast(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	if _, ok := organizationForMember(context, organizationID); !ok {
+		return
+	}
+
+	months, err := strconv.Atoi(context.DefaultQuery("months", "3"))
+	if err != nil || months < 1 || months > 24 {
+		utils.ReportBadRequest(context, "months must be between 1 and 24")
+		return
+	}
+
+	now := time.Now()
+	forecast, err := payments.ForecastBilling(organizationID, now, now.AddDate(0, months, 0))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Organization not found")
+		} else {
+			log.Printf("Failed to forecast billing of organization %s: %v", organizationID, err)
+			utils.ReportInternalServerError(context, "Failed to forecast billing")
+		}
+		return
+	}
+
+	if utils.WantsCSV(context) {
+		utils.ServeCSV(context, "billing-forecast-"+now.Format("2006-01-02")+".csv", billing.BillingForecastCSV(forecast))
+		return
+	}
+
+	response := billing.BillingForecastOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: billing.FromBillingForecast(forecast, now),
+	}
+
+	context.JSON(http.StatusOK, response)
 }
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"errors"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// organizationForMember loads the organization and checks that the caller
+// created it or is one of its members. On failure the error response is sent.
+func organizationForMember(context *gin.Context, organizationID uuid.UUID) (*model.Organization, bool) {
+	org, userID, ok := organizationForCaller(context, organizationID)
+	if !ok || org.CreatedBy == userID {
+		return org, ok
+	}
+
+	isMember, err := dao.NewOrganizationMemberDao().IsUserMember(organizationID, userID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return nil, false
+	}
+	if !isMember {
+		utils.ReportForbidden(context, "Access denied")
+		return nil, false
+	}
+	return org, true
+}
+
+// organizationForAdmin loads the organization and checks that the caller
+// created it or is one of its owners or admins; deniedMessage tells other
+// members why they are refused. On failure the error response is sent.
+func organizationForAdmin(context *gin.Context, organizationID uuid.UUID, deniedMessage string) (*model.Organization, bool) {
+	org, userID, ok := organizationForCaller(context, organizationID)
+	if !ok || org.CreatedBy == userID {
+		return org, ok
+	}
+
+	role, err := dao.NewOrganizationMemberDao().GetUserRole(organizationID, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportForbidden(context, "Access denied")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return nil, false
+	}
+	if role != model.OrganizationMemberRoleOwner && role != model.OrganizationMemberRoleAdmin {
+		utils.ReportForbidden(context, deniedMessage)
+		return nil, false
+	}
+	return org, true
+}
+
+// organizationForCaller authenticates the caller and loads the organization.
+func organizationForCaller(context *gin.Context, organizationID uuid.UUID) (*model.Organization, uuid.UUID, bool) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return nil, uuid.Nil, false
+	}
+
+	org, err := dao.NewOrganizationDao().GetByID(organizationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Organization not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return nil, uuid.Nil, false
+	}
+	return org, userID, true
+}
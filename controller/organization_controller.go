@@ -2,15 +2,19 @@ package controller
 
 import (
 	"errors"
+	"log"
 	"math"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 
 	"testlake/dao"
 	"testlake/inout"
 	"testlake/inout/organization"
 	"testlake/model"
+	"testlake/payments"
 	"testlake/utils"
 
 	"github.com/gin-gonic/gin"
@@ -425,6 +429,93 @@ func (controller OrganizationController) GetOrganizationMembers(context *gin.Con
 	context.JSON(http.StatusOK, response)
 }
 
+// GetMembersActivity returns each member's last sign-in and activity for offboarding reviews
+func (controller OrganizationController) GetMembersActivity(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	idParam := context.Param("id")
+	orgID, err := uuid.Parse(idParam)
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	orgDao := dao.NewOrganizationDao()
+	org, err := orgDao.GetByID(orgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Organization not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	// Check if user has access to view member activity (creator or admin)
+	memberDao := dao.NewOrganizationMemberDao()
+	if org.CreatedBy != userID {
+		role, err := memberDao.GetUserRole(orgID, userID)
+		if err != nil {
+			utils.ReportForbidden(context, "Access denied")
+			return
+		}
+		if role != model.OrganizationMemberRoleAdmin {
+			utils.ReportForbidden(context, "Only admins can view member activity")
+			return
+		}
+	}
+
+	orgMembers, err := orgDao.GetMembers(orgID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	userIDs := make([]uuid.UUID, len(orgMembers))
+	for i, member := range orgMembers {
+		userIDs[i] = member.UserID
+	}
+
+	activity := map[uuid.UUID]dao.MemberActivity{}
+	if len(userIDs) > 0 {
+		activity, err = dao.NewUserSessionDao().GetMemberActivity(userIDs)
+		if err != nil {
+			utils.ReportInternalServerError(context, "Database error")
+			return
+		}
+	}
+
+	members := make([]organization.MemberActivity, len(orgMembers))
+	for i, member := range orgMembers {
+		members[i] = organization.MemberActivity{
+			Member:      organization.FromUserModel(&member.User, string(member.Role), member.InvitedAt),
+			Status:      member.Status,
+			LastLoginAt: member.User.LastLoginAt,
+		}
+		if member.JoinedAt != nil {
+			members[i].JoinedAt = *member.JoinedAt
+		}
+		if memberActivity, ok := activity[member.UserID]; ok {
+			members[i].LastSeenAt = memberActivity.LastSeenAt
+			members[i].ActiveSessions = memberActivity.ActiveSessions
+		}
+	}
+
+	response := organization.MembersActivityOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: members,
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
 // InviteMember invites a user to the organization
 func (controller OrganizationController) InviteMember(context *gin.Context) {
 	userID, err := utils.ExtractUserID(context)
@@ -495,6 +586,11 @@ func (controller OrganizationController) InviteMember(context *gin.Context) {
 		return
 	}
 
+	// The invitation holds a seat until it is accepted or expires
+	if !utils.EnforceLimit(context, orgID, model.LimitResourceUsers, 1) {
+		return
+	}
+
 	// Create invitation token
 	token, err := utils.GenerateSecureToken(32)
 	if err != nil {
@@ -722,3 +818,620 @@ func (controller OrganizationController) UpdateMemberRole(context *gin.Context)
 
 	context.JSON(http.StatusOK, response)
 }
+
+// GetSSOConfig returns the organization's identity provider configuration (creator or admin only)
+func (controller OrganizationController) GetSSOConfig(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage single sign-on")
+	if !ok {
+		return
+	}
+
+	config, err := dao.NewOrganizationSSODao().GetByOrganizationID(org.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Single sign-on is not configured")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	controller.respondSSOConfig(context, org, config)
+}
+
+// UpdateSSOConfig registers or replaces the organization's OIDC provider. The
+// issuer is checked through discovery before the configuration is saved.
+func (controller OrganizationController) UpdateSSOConfig(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage single sign-on")
+	if !ok {
+		return
+	}
+
+	var req organization.SSOConfigRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		utils.ReportBadRequest(context, "Invalid request data: "+err.Error())
+		return
+	}
+
+	// SSO is an enterprise feature
+	hasFeature, err := controller.hasPlanFeature(org, "sso")
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+	if !hasFeature {
+		utils.ReportForbidden(context, "Single sign-on is not included in the organization's plan")
+		return
+	}
+
+	ssoDao := dao.NewOrganizationSSODao()
+	config, err := ssoDao.GetByOrganizationID(org.ID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportInternalServerError(context, "Database error")
+			return
+		}
+		config = &model.OrganizationSSOConfig{OrganizationID: org.ID, CreatedBy: userID}
+	}
+
+	// An email domain can only be routed to the organization that verified it
+	domains := make([]string, len(req.AllowedDomains))
+	for i, domain := range req.AllowedDomains {
+		domains[i] = strings.ToLower(strings.TrimSpace(domain))
+		owner, err := ssoDao.GetVerifiedDomain(domains[i])
+		if err == nil && owner.OrganizationID != org.ID {
+			utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Domain "+domains[i]+" is already used by another organization")
+			return
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportInternalServerError(context, "Database error")
+			return
+		}
+	}
+
+	config.Issuer = strings.TrimRight(req.Issuer, "/")
+	config.ClientID = req.ClientID
+	if req.ClientSecret != nil {
+		config.ClientSecret = *req.ClientSecret
+	}
+	config.AllowedDomains = strings.Join(domains, ",")
+	config.DefaultRole = model.OrganizationMemberRoleMember
+	if req.DefaultRole != "" {
+		config.DefaultRole = model.OrganizationMemberRole(req.DefaultRole)
+	}
+	config.EnforceSSO = req.EnforceSSO
+	config.IsEnabled = true
+	if req.IsEnabled != nil {
+		config.IsEnabled = *req.IsEnabled
+	}
+
+	client := utils.NewOIDCClient(config.Issuer, config.ClientID, config.ClientSecret, utils.SSORedirectURL())
+	if _, err := client.Discover(); err != nil {
+		utils.ReportBadRequest(context, "Failed to load the provider configuration: "+err.Error())
+		return
+	}
+
+	if err := ssoDao.Save(config); err != nil {
+		utils.ReportInternalServerError(context, "Failed to save single sign-on configuration")
+		return
+	}
+
+	controller.respondSSOConfig(context, org, config)
+}
+
+// VerifySSODomain checks the domain's DNS TXT record and, when it holds the
+// verification token, starts routing the domain's logins to the organization
+func (controller OrganizationController) VerifySSODomain(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage single sign-on")
+	if !ok {
+		return
+	}
+
+	ssoDao := dao.NewOrganizationSSODao()
+	config, err := ssoDao.GetByOrganizationID(org.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Single sign-on is not configured")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	ssoDomain, err := ssoDao.GetDomain(org.ID, context.Param("domain"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Domain is not allowed for single sign-on")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	if !ssoDomain.IsVerified() {
+		found, err := utils.HasDomainVerificationRecord(ssoDomain)
+		if err != nil {
+			utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Failed to look up the domain's DNS records")
+			return
+		}
+		if !found {
+			utils.ReportBadRequest(context, "TXT record "+ssoDomain.TXTRecordName()+" does not contain "+ssoDomain.TXTRecordValue())
+			return
+		}
+
+		if err := ssoDao.MarkDomainVerified(ssoDomain, time.Now()); err != nil {
+			if errors.Is(err, dao.ErrSSODomainTaken) {
+				utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Domain "+ssoDomain.Domain+" is already used by another organization")
+			} else {
+				utils.ReportInternalServerError(context, "Failed to verify domain")
+			}
+			return
+		}
+	}
+
+	controller.respondSSOConfig(context, org, config)
+}
+
+// DeleteSSOConfig removes the identity provider; members fall back to password sign-in
+func (controller OrganizationController) DeleteSSOConfig(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage single sign-on")
+	if !ok {
+		return
+	}
+
+	if err := dao.NewOrganizationSSODao().DeleteByOrganizationID(org.ID); err != nil {
+		utils.ReportInternalServerError(context, "Failed to delete single sign-on configuration")
+		return
+	}
+
+	response := inout.BaseResponse{
+		ErrorCode:        0,
+		ErrorDescription: "Single sign-on configuration deleted",
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// hasPlanFeature checks the features of the organization's plan, falling back to
+// the plan type for organizations that are not linked to a plan record
+func (controller OrganizationController) hasPlanFeature(org *model.Organization, feature string) (bool, error) {
+	if org.PlanID == nil {
+		return org.PlanType == model.PlanTypeEnterprise, nil
+	}
+
+	plan, err := dao.NewPlanDao().GetByID(*org.PlanID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return org.PlanType == model.PlanTypeEnterprise, nil
+		}
+		return false, err
+	}
+	return plan.HasFeature(feature), nil
+}
+
+func (controller OrganizationController) respondSSOConfig(context *gin.Context, org *model.Organization, config *model.OrganizationSSOConfig) {
+	domains, err := dao.NewOrganizationSSODao().GetDomains(org.ID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	response := organization.SSOConfigOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: organization.FromSSOConfigModel(config, domains, utils.SSORedirectURL(), utils.GetBaseURL()+"/api/v1/auth/sso/"+org.Slug+"/login"),
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// CheckLimits checks the organization's live usage against its plan. With a
+// resource, it checks whether count more of it fit and lists the plans that
+// would allow them when they do not.
+func (controller OrganizationController) CheckLimits(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	orgID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, err := dao.NewOrganizationDao().GetByID(orgID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Organization not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+	if org.CreatedBy != userID {
+		isMember, err := dao.NewOrganizationMemberDao().IsUserMember(orgID, userID)
+		if err != nil || !isMember {
+			utils.ReportForbidden(context, "Access denied")
+			return
+		}
+	}
+
+	resource := context.Query("resource")
+	count, err := strconv.Atoi(context.DefaultQuery("count", "1"))
+	if err != nil || count < 1 {
+		utils.ReportBadRequest(context, "Invalid count parameter")
+		return
+	}
+	if resource != "" && !slices.Contains(model.LimitResources, resource) {
+		utils.ReportBadRequest(context, "Unknown resource, expected one of: "+strings.Join(model.LimitResources, ", "))
+		return
+	}
+
+	limits, err := utils.LoadOrganizationLimits(orgID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to check plan limits")
+		return
+	}
+
+	data := organization.LimitsCheck{
+		PlanName: limits.Plan.Name,
+		Usage:    *limits.Usage,
+		Checks:   limits.CheckAll(),
+	}
+	if limits.Plan.ID != uuid.Nil {
+		data.PlanID = &limits.Plan.ID
+	}
+	if resource != "" {
+		check := limits.Check(resource, count)
+		data.Checks = []utils.LimitCheck{check}
+		if !check.Allowed {
+			data.UpgradeOptions, err = limits.UpgradeOptions(check)
+			if err != nil {
+				utils.ReportInternalServerError(context, "Failed to load upgrade options")
+				return
+			}
+		}
+	}
+
+	response := organization.LimitsCheckOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: data,
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetBillingDetails returns the billing address and tax ID invoices are
+// addressed to, with the tax they are charged
+func (controller OrganizationController) GetBillingDetails(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage billing details")
+	if !ok {
+		return
+	}
+
+	tax, err := payments.CalculateTax(org, 0, "USD")
+	if err != nil {
+		log.Printf("Failed to calculate the tax of organization %s: %v", org.ID, err)
+		utils.ReportInternalServerError(context, "Failed to calculate tax")
+		return
+	}
+
+	context.JSON(http.StatusOK, organization.BillingDetailsOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: organization.FromBillingDetailsModel(org, tax),
+	})
+}
+
+// UpdateBillingDetails replaces the billing address and tax ID. Invoices issued
+// from now on are taxed accordingly, including the charges of the provider
+// subscription
+func (controller OrganizationController) UpdateBillingDetails(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage billing details")
+	if !ok {
+		return
+	}
+
+	var req organization.UpdateBillingDetailsRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		utils.ReportBadRequest(context, "Invalid request data: "+err.Error())
+		return
+	}
+
+	previousTax, err := payments.CalculateTax(org, 0, "USD")
+	if err != nil {
+		log.Printf("Failed to calculate the tax of organization %s: %v", org.ID, err)
+		utils.ReportInternalServerError(context, "Failed to calculate tax")
+		return
+	}
+
+	org.BillingEmail = trimmedOrNil(req.BillingEmail)
+	org.BillingName = trimmedOrNil(req.Name)
+	org.BillingAddressLine1 = trimmedOrNil(req.AddressLine1)
+	org.BillingAddressLine2 = trimmedOrNil(req.AddressLine2)
+	org.BillingCity = trimmedOrNil(req.City)
+	org.BillingPostalCode = trimmedOrNil(req.PostalCode)
+	org.BillingState = trimmedOrNil(req.State)
+	org.BillingCountry = trimmedOrNil(req.Country)
+	org.TaxID = nil
+	if taxID := trimmedOrNil(req.TaxID); taxID != nil {
+		normalized := utils.NormalizeTaxID(*taxID)
+		org.TaxID = &normalized
+	}
+
+	tax, err := payments.CalculateTax(org, 0, "USD")
+	if err != nil {
+		log.Printf("Failed to calculate the tax of organization %s: %v", org.ID, err)
+		utils.ReportInternalServerError(context, "Failed to calculate tax")
+		return
+	}
+
+	// The provider adds the tax to what it charges: keep it in line with the invoices
+	if tax.Rate != previousTax.Rate {
+		activeSub, err := dao.NewSubscriptionDao().GetActiveByOrganizationID(org.ID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportInternalServerError(context, "Database error")
+			return
+		}
+		if err == nil && activeSub.IsGatewayBilled() {
+			if err := utils.GetPaymentGateway().UpdateSubscriptionTax(context.Request.Context(), *activeSub.PayPalSubscriptionID, tax.Rate); err != nil {
+				log.Printf("Failed to update the tax of gateway subscription %s: %v", *activeSub.PayPalSubscriptionID, err)
+				utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider error")
+				return
+			}
+		}
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := dao.NewOrganizationDao().WithTx(tx).Update(org); err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(org.ID, model.BillingEventTypeBillingDetailsUpdated, map[string]interface{}{
+			"updated_by":      userID,
+			"billing_country": org.BillingCountry,
+			"tax_id":          org.TaxID,
+			"tax_rate":        tax.Rate,
+			"reverse_charge":  tax.ReverseCharge,
+		})
+	})
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to update billing details")
+		return
+	}
+
+	context.JSON(http.StatusOK, organization.BillingDetailsOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Billing details updated",
+		},
+		Data: organization.FromBillingDetailsModel(org, tax),
+	})
+}
+
+// GetBillingContacts lists the addresses the billing emails go to besides the billing email
+func (controller OrganizationController) GetBillingContacts(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage billing contacts")
+	if !ok {
+		return
+	}
+
+	contacts, err := dao.NewBillingContactDao().GetByOrganizationID(org.ID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	data := make([]organization.BillingContact, len(contacts))
+	for i := range contacts {
+		data[i] = organization.FromBillingContactModel(&contacts[i])
+	}
+
+	context.JSON(http.StatusOK, organization.BillingContactsOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: data,
+	})
+}
+
+// AddBillingContact adds an address invoices, receipts and payment failures are emailed to
+func (controller OrganizationController) AddBillingContact(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage billing contacts")
+	if !ok {
+		return
+	}
+
+	var req organization.AddBillingContactRequest
+	if err := context.ShouldBindJSON(&req); err != nil {
+		utils.ReportBadRequest(context, "Invalid request data: "+err.Error())
+		return
+	}
+
+	contact := &model.BillingContact{
+		OrganizationID: org.ID,
+		Email:          strings.ToLower(strings.TrimSpace(req.Email)),
+		Name:           trimmedOrNil(req.Name),
+		CreatedBy:      userID,
+	}
+
+	contactDao := dao.NewBillingContactDao()
+	exists, err := contactDao.ExistsByEmail(org.ID, contact.Email)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+	if exists {
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Billing contact already exists")
+		return
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := contactDao.WithTx(tx).Create(contact); err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(org.ID, model.BillingEventTypeBillingContactAdded, map[string]interface{}{
+			"billing_contact_id": contact.ID,
+			"email":              contact.Email,
+			"added_by":           userID,
+		})
+	})
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to add billing contact")
+		return
+	}
+
+	context.JSON(http.StatusCreated, organization.BillingContactOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Billing contact added",
+		},
+		Data: organization.FromBillingContactModel(contact),
+	})
+}
+
+// RemoveBillingContact stops sending the billing emails to a billing contact
+func (controller OrganizationController) RemoveBillingContact(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	org, ok := organizationForAdmin(context, organizationID, "Only admins can manage billing contacts")
+	if !ok {
+		return
+	}
+
+	contactID, err := uuid.Parse(context.Param("contactId"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid billing contact ID")
+		return
+	}
+
+	contactDao := dao.NewBillingContactDao()
+	contact, err := contactDao.GetByID(contactID)
+	if err != nil || contact.OrganizationID != org.ID {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Billing contact not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := contactDao.WithTx(tx).Delete(contact.ID); err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(org.ID, model.BillingEventTypeBillingContactRemoved, map[string]interface{}{
+			"billing_contact_id": contact.ID,
+			"email":              contact.Email,
+			"removed_by":         userID,
+		})
+	})
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to remove billing contact")
+		return
+	}
+
+	response := inout.BaseResponse{
+		ErrorCode:        0,
+		ErrorDescription: "Billing contact removed",
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// trimmedOrNil clears optional text fields sent empty
+func trimmedOrNil(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*value)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
+}
@@ -2,11 +2,13 @@ package controller
 
 import (
 	"errors"
+	"log"
 	"net/http"
 	"testlake/dao"
 	"testlake/inout"
 	"testlake/inout/payment"
 	"testlake/model"
+	"testlake/payments"
 	"testlake/utils"
 
 	"github.com/gin-gonic/gin"
@@ -25,15 +27,7 @@ func (controller PaymentMethodController) GetPaymentMethods(context *gin.Context
 		return
 	}
 
-	// Verify user has access to organization
-	userID, err := utils.ExtractUserID(context)
-	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
-		return
-	}
-
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForMember(context, organizationID); !ok {
 		return
 	}
 
@@ -70,41 +64,80 @@ func (controller PaymentMethodController) CreatePaymentMethod(context *gin.Conte
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage payment methods"); !ok {
 		return
 	}
 
-	var request payment.CreatePaymentMethodRequest
-	if err := context.ShouldBindJSON(&request); err != nil {
-		utils.ReportBadRequest(context, "Invalid request body")
+	paymentMethod, approvalURL, err := payments.StartPaymentMethodSetup(context.Request.Context(), organizationID, userID)
+	if err != nil {
+		log.Printf("Failed to start payment method setup for organization %s: %v", organizationID, err)
+		utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider error")
 		return
 	}
 
-	paymentMethod := &model.PaymentMethod{
-		OrganizationID:    organizationID,
-		PayPalEmail:       &request.PayPalEmail,
-		PayPalPayerID:     request.PayPalPayerID,
-		PaymentMethodType: request.PaymentMethodType,
-		IsDefault:         request.IsDefault,
-		IsActive:          true,
-		CreatedBy:         userID,
+	data := payment.FromPaymentMethodModel(paymentMethod)
+	data.ApprovalURL = approvalURL
+	response := payment.PaymentMethodOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: data,
 	}
 
-	paymentMethodDao := dao.NewPaymentMethodDao()
+	context.JSON(http.StatusCreated, response)
+}
+
+// ConfirmPaymentMethod saves a payment method the payer approved
+func (controller PaymentMethodController) ConfirmPaymentMethod(context *gin.Context) {
+	orgIDParam := context.Param("id")
+	organizationID, err := uuid.Parse(orgIDParam)
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	idParam := context.Param("pmId")
+	paymentMethodID, err := uuid.Parse(idParam)
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid payment method ID")
+		return
+	}
 
-	// If this is set as default, make sure no other payment method is default
-	if request.IsDefault {
-		err = paymentMethodDao.SetDefault(organizationID, paymentMethod.ID)
-		if err != nil {
-			utils.ReportInternalServerError(context, "Failed to update default payment method")
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage payment methods"); !ok {
+		return
+	}
+
+	var request payment.ConfirmPaymentMethodRequest
+	if context.Request.ContentLength != 0 {
+		if err := context.ShouldBindJSON(&request); err != nil {
+			utils.ReportBadRequest(context, "Invalid request body")
 			return
 		}
 	}
 
-	err = paymentMethodDao.Create(paymentMethod)
-	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to create payment method")
+	if _, ok := controller.getOrganizationPaymentMethod(context, organizationID, paymentMethodID); !ok {
+		return
+	}
+
+	paymentMethod, err := payments.ConfirmPaymentMethod(context.Request.Context(), paymentMethodID, request.IsDefault, model.UserActor(userID))
+	switch {
+	case errors.Is(err, payments.ErrPaymentMethodNotPending):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Payment method was already confirmed")
+		return
+	case errors.Is(err, payments.ErrPaymentMethodNotApproved):
+		log.Printf("Payment method %s was not saved: %v", paymentMethodID, err)
+		utils.ReportBadRequest(context, "Payment method was not approved at PayPal")
+		return
+	case err != nil:
+		log.Printf("Failed to confirm payment method %s: %v", paymentMethodID, err)
+		utils.ReportInternalServerError(context, "Failed to confirm payment method")
 		return
 	}
 
@@ -116,7 +149,7 @@ func (controller PaymentMethodController) CreatePaymentMethod(context *gin.Conte
 		Data: payment.FromPaymentMethodModel(paymentMethod),
 	}
 
-	context.JSON(http.StatusCreated, response)
+	context.JSON(http.StatusOK, response)
 }
 
 // UpdatePaymentMethod updates an existing payment method
@@ -141,8 +174,7 @@ func (controller PaymentMethodController) UpdatePaymentMethod(context *gin.Conte
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage payment methods"); !ok {
 		return
 	}
 
@@ -152,44 +184,18 @@ func (controller PaymentMethodController) UpdatePaymentMethod(context *gin.Conte
 		return
 	}
 
-	paymentMethodDao := dao.NewPaymentMethodDao()
-	paymentMethod, err := paymentMethodDao.GetByID(paymentMethodID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.ReportNotFound(context, "Payment method not found")
-		} else {
-			utils.ReportInternalServerError(context, "Database error")
-		}
+	paymentMethod, ok := controller.getOrganizationPaymentMethod(context, organizationID, paymentMethodID)
+	if !ok {
 		return
 	}
 
-	// Verify the payment method belongs to the organization
-	if paymentMethod.OrganizationID != organizationID {
-		utils.ReportForbidden(context, "Access denied")
-		return
-	}
-
-	// Update fields
-	if request.PayPalEmail != nil {
-		paymentMethod.PayPalEmail = request.PayPalEmail
-	}
-	if request.PayPalPayerID != nil {
-		paymentMethod.PayPalPayerID = request.PayPalPayerID
-	}
 	if request.IsDefault != nil && *request.IsDefault {
-		err = paymentMethodDao.SetDefault(organizationID, paymentMethodID)
-		if err != nil {
-			utils.ReportInternalServerError(context, "Failed to update default payment method")
+		paymentMethod, ok = controller.setDefault(context, paymentMethodID, userID)
+		if !ok {
 			return
 		}
 	}
 
-	err = paymentMethodDao.Update(paymentMethod)
-	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to update payment method")
-		return
-	}
-
 	response := payment.PaymentMethodOut{
 		BaseResponse: inout.BaseResponse{
 			ErrorCode:        0,
@@ -223,30 +229,24 @@ func (controller PaymentMethodController) DeletePaymentMethod(context *gin.Conte
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage payment methods"); !ok {
 		return
 	}
 
-	paymentMethodDao := dao.NewPaymentMethodDao()
-	paymentMethod, err := paymentMethodDao.GetByID(paymentMethodID)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			utils.ReportNotFound(context, "Payment method not found")
-		} else {
-			utils.ReportInternalServerError(context, "Database error")
-		}
+	if _, ok := controller.getOrganizationPaymentMethod(context, organizationID, paymentMethodID); !ok {
 		return
 	}
 
-	// Verify the payment method belongs to the organization
-	if paymentMethod.OrganizationID != organizationID {
-		utils.ReportForbidden(context, "Access denied")
+	err = payments.DeletePaymentMethod(context.Request.Context(), paymentMethodID, model.UserActor(userID))
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		utils.ReportNotFound(context, "Payment method not found")
 		return
-	}
-
-	err = paymentMethodDao.SetActive(paymentMethodID, false)
-	if err != nil {
+	case errors.Is(err, payments.ErrLastPaymentMethod):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Add another payment method before deleting the only one of an active subscription")
+		return
+	case err != nil:
+		log.Printf("Failed to delete payment method %s: %v", paymentMethodID, err)
 		utils.ReportInternalServerError(context, "Failed to delete payment method")
 		return
 	}
@@ -281,47 +281,57 @@ func (controller PaymentMethodController) SetDefaultPaymentMethod(context *gin.C
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage payment methods"); !ok {
 		return
 	}
 
-	paymentMethodDao := dao.NewPaymentMethodDao()
-	paymentMethod, err := paymentMethodDao.GetByID(paymentMethodID)
+	if _, ok := controller.getOrganizationPaymentMethod(context, organizationID, paymentMethodID); !ok {
+		return
+	}
+
+	if _, ok := controller.setDefault(context, paymentMethodID, userID); !ok {
+		return
+	}
+
+	response := inout.BaseResponse{
+		ErrorCode:        0,
+		ErrorDescription: "Payment method set as default successfully",
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// getOrganizationPaymentMethod loads a payment method of the organization, or
+// reports why it cannot
+func (controller PaymentMethodController) getOrganizationPaymentMethod(context *gin.Context, organizationID, paymentMethodID uuid.UUID) (*model.PaymentMethod, bool) {
+	paymentMethod, err := dao.NewPaymentMethodDao().GetByID(paymentMethodID)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			utils.ReportNotFound(context, "Payment method not found")
 		} else {
 			utils.ReportInternalServerError(context, "Database error")
 		}
-		return
+		return nil, false
 	}
 
 	// Verify the payment method belongs to the organization
 	if paymentMethod.OrganizationID != organizationID {
 		utils.ReportForbidden(context, "Access denied")
-		return
+		return nil, false
 	}
+	return paymentMethod, true
+}
 
-	err = paymentMethodDao.SetDefault(organizationID, paymentMethodID)
-	if err != nil {
+// setDefault makes a verified payment method the default one, or reports why it cannot
+func (controller PaymentMethodController) setDefault(context *gin.Context, paymentMethodID, userID uuid.UUID) (*model.PaymentMethod, bool) {
+	paymentMethod, err := payments.SetDefaultPaymentMethod(paymentMethodID, model.UserActor(userID))
+	switch {
+	case errors.Is(err, payments.ErrPaymentMethodNotVerified):
+		utils.ReportBadRequest(context, "Only a verified payment method can be the default")
+		return nil, false
+	case err != nil:
 		utils.ReportInternalServerError(context, "Failed to set default payment method")
-		return
-	}
-
-	response := inout.BaseResponse{
-		ErrorCode:        0,
-		ErrorDescription: "Payment method set as default successfully",
+		return nil, false
 	}
-
-	context.JSON(http.StatusOK, response)
-}
-
-// Helper method to verify organization access
-func (controller PaymentMethodController) verifyOrganizationAccess(userID, organizationID uuid.UUID) bool {
-	// This should check if the user has access to the organization
-	// For now, implementing a simple check - in production, this would verify membership
-	orgDao := dao.NewOrganizationDao()
-	_, err := orgDao.GetByID(organizationID)
-	return err == nil
+	return paymentMethod, true
 }
@@ -0,0 +1,466 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"testlake/dao"
+	"testlake/inout"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PayPalWebhookController struct{}
+
+// errWebhookUnmatched means the event refers to nothing this service created,
+// e.g. a subscription that was already replaced.
+var errWebhookUnmatched = errors.New("webhook resource does not match any record")
+
+// payPalWebhookEventTypes are the PayPal events acted upon and the billing event
+// each one is recorded as.
+var payPalWebhookEventTypes = map[string]model.BillingEventType{
+	"BILLING.SUBSCRIPTION.ACTIVATED":      model.BillingEventTypeSubscriptionActivated,
+	"BILLING.SUBSCRIPTION.CANCELLED":      model.BillingEventTypeSubscriptionCancelled,
+	"BILLING.SUBSCRIPTION.SUSPENDED":      model.BillingEventTypeSubscriptionSuspended,
+	"BILLING.SUBSCRIPTION.PAYMENT.FAILED": model.BillingEventTypePaymentFailed,
+	"PAYMENT.SALE.COMPLETED":              model.BillingEventTypePaymentSucceeded,
+	"PAYMENT.SALE.DENIED":                 model.BillingEventTypePaymentFailed,
+	"INVOICING.INVOICE.PAID":              model.BillingEventTypeInvoicePaid,
+	"INVOICING.INVOICE.CANCELLED":         model.BillingEventTypeInvoiceCancelled,
+	"VAULT.PAYMENT-TOKEN.DELETED":         model.BillingEventTypePaymentMethodInvalidated,
+}
+
+// HandleWebhook receives PayPal webhooks. The signature is verified with PayPal,
+// events are processed once per event ID and every state change of an event is
+// committed in one transaction together with its BillingEvent.
+func (controller PayPalWebhookController) HandleWebhook(context *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(context.Request.Body, 1<<20))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid request body")
+		return
+	}
+
+	err = utils.GetPaymentGateway().VerifyWebhook(context.Request.Context(), utils.GatewayWebhook{
+		TransmissionID:   context.GetHeader("PAYPAL-TRANSMISSION-ID"),
+		TransmissionTime: context.GetHeader("PAYPAL-TRANSMISSION-TIME"),
+		TransmissionSig:  context.GetHeader("PAYPAL-TRANSMISSION-SIG"),
+		CertURL:          context.GetHeader("PAYPAL-CERT-URL"),
+		AuthAlgo:         context.GetHeader("PAYPAL-AUTH-ALGO"),
+		Body:             body,
+	})
+	if err != nil {
+		if errors.Is(err, utils.ErrWebhookSignatureInvalid) {
+			utils.ReportBadRequest(context, "Invalid webhook signature")
+			return
+		}
+		// Let PayPal retry the delivery once verification is possible again
+		log.Printf("Failed to verify PayPal webhook: %v", err)
+		utils.ReportCustomError(context, http.StatusServiceUnavailable, http.StatusServiceUnavailable, "Webhook verification unavailable")
+		return
+	}
+
+	var event utils.PayPalWebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.ID == "" {
+		utils.ReportBadRequest(context, "Invalid webhook event")
+		return
+	}
+
+	eventType, handled := payPalWebhookEventTypes[event.EventType]
+	if !handled {
+		controller.acknowledge(context, "Event ignored")
+		return
+	}
+
+	billingEventDao := dao.NewBillingEventDao()
+	exists, err := billingEventDao.EventExists(event.ID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+	if exists {
+		controller.acknowledge(context, "Event already processed")
+		return
+	}
+
+	var resource utils.PayPalWebhookResource
+	if err := json.Unmarshal(event.Resource, &resource); err != nil {
+		utils.ReportBadRequest(context, "Invalid webhook resource")
+		return
+	}
+
+	// Emailed once the transaction is committed, so that they show its changes
+	var notices webhookNotices
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		notices = nil
+		organizationID, err := controller.apply(tx, event.EventType, resource, &notices)
+		if errors.Is(err, errWebhookUnmatched) {
+			organizationID, err = controller.organizationFromCustomValue(tx, resource)
+		}
+		if err != nil {
+			return err
+		}
+
+		eventData := string(body)
+		return billingEventDao.WithTx(tx).Create(&model.BillingEvent{
+			OrganizationID: organizationID,
+			EventType:      eventType,
+			EventData:      &eventData,
+			PayPalEventID:  &event.ID,
+			ProcessedAt:    time.Now(),
+			ActorType:      model.BillingActorTypePayPal,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, errWebhookUnmatched) {
+			log.Printf("PayPal webhook %s (%s) does not match any organization", event.ID, event.EventType)
+			controller.acknowledge(context, "Event ignored")
+			return
+		}
+		// A concurrent delivery of the same event won the race for the unique event ID
+		if exists, _ := billingEventDao.EventExists(event.ID); exists {
+			controller.acknowledge(context, "Event already processed")
+			return
+		}
+		log.Printf("Failed to process PayPal webhook %s (%s): %v", event.ID, event.EventType, err)
+		utils.ReportInternalServerError(context, "Failed to process webhook")
+		return
+	}
+
+	for _, notice := range notices {
+		payments.SendBillingNotice(notice.organizationID, notice.notice)
+	}
+	controller.acknowledge(context, "Event processed")
+}
+
+// webhookNotices collects the billing notices an event causes.
+type webhookNotices []webhookNotice
+
+type webhookNotice struct {
+	organizationID uuid.UUID
+	notice         *utils.BillingNotice
+}
+
+func (n *webhookNotices) add(organizationID uuid.UUID, notice *utils.BillingNotice) {
+	if notice != nil {
+		*n = append(*n, webhookNotice{organizationID: organizationID, notice: notice})
+	}
+}
+
+func (controller PayPalWebhookController) acknowledge(context *gin.Context, description string) {
+	context.JSON(http.StatusOK, inout.BaseResponse{
+		ErrorCode:        0,
+		ErrorDescription: description,
+	})
+}
+
+// apply updates the records an event refers to and returns their organization.
+func (controller PayPalWebhookController) apply(tx *gorm.DB, eventType string, resource utils.PayPalWebhookResource, notices *webhookNotices) (uuid.UUID, error) {
+	switch eventType {
+	case "BILLING.SUBSCRIPTION.ACTIVATED":
+		return controller.subscriptionActivated(tx, resource)
+	case "BILLING.SUBSCRIPTION.CANCELLED":
+		return controller.subscriptionCancelled(tx, resource)
+	case "BILLING.SUBSCRIPTION.SUSPENDED":
+		return controller.subscriptionSuspended(tx, resource)
+	case "BILLING.SUBSCRIPTION.PAYMENT.FAILED":
+		return controller.subscriptionPaymentFailed(tx, resource)
+	case "PAYMENT.SALE.COMPLETED":
+		return controller.saleCompleted(tx, resource, notices)
+	case "PAYMENT.SALE.DENIED":
+		return controller.saleDenied(tx, resource)
+	case "INVOICING.INVOICE.PAID":
+		return controller.invoiceStatusChanged(tx, resource, model.InvoiceStatusPaid, notices)
+	case "INVOICING.INVOICE.CANCELLED":
+		return controller.invoiceStatusChanged(tx, resource, model.InvoiceStatusCancelled, notices)
+	case "VAULT.PAYMENT-TOKEN.DELETED":
+		return controller.paymentTokenDeleted(tx, resource)
+	}
+	return uuid.Nil, errWebhookUnmatched
+}
+
+func (controller PayPalWebhookController) subscriptionActivated(tx *gorm.DB, resource utils.PayPalWebhookResource) (uuid.UUID, error) {
+	subscriptionDao := dao.NewSubscriptionDao().WithTx(tx)
+	sub, err := controller.findSubscription(subscriptionDao, resource.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	sub.Status = model.SubscriptionStatusActive
+	sub.CancelAtPeriodEnd = false
+	if resource.BillingInfo.NextBillingTime != nil {
+		sub.CurrentPeriodEnd = *resource.BillingInfo.NextBillingTime
+	}
+	if err := subscriptionDao.Update(sub); err != nil {
+		return uuid.Nil, err
+	}
+	// The new subscription replaces whatever the organization had before
+	if err := subscriptionDao.CancelOthers(sub.OrganizationID, sub.ID); err != nil {
+		return uuid.Nil, err
+	}
+
+	return sub.OrganizationID, controller.updateOrganization(tx, sub.OrganizationID, func(org *model.Organization) {
+		org.PlanID = &sub.PlanID
+		org.BillingCycle = sub.BillingCycle
+		org.Currency = sub.Currency
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusActive
+		org.NextBillingDate = &sub.CurrentPeriodEnd
+	})
+}
+
+func (controller PayPalWebhookController) subscriptionCancelled(tx *gorm.DB, resource utils.PayPalWebhookResource) (uuid.UUID, error) {
+	subscriptionDao := dao.NewSubscriptionDao().WithTx(tx)
+	sub, err := controller.findSubscription(subscriptionDao, resource.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	now := time.Now()
+	sub.Status = model.SubscriptionStatusCancelled
+	sub.CancelledAt = &now
+	if err := subscriptionDao.Update(sub); err != nil {
+		return uuid.Nil, err
+	}
+
+	return sub.OrganizationID, controller.updateOrganization(tx, sub.OrganizationID, func(org *model.Organization) {
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusCancelled
+		org.NextBillingDate = nil
+	})
+}
+
+func (controller PayPalWebhookController) subscriptionSuspended(tx *gorm.DB, resource utils.PayPalWebhookResource) (uuid.UUID, error) {
+	subscriptionDao := dao.NewSubscriptionDao().WithTx(tx)
+	sub, err := controller.findSubscription(subscriptionDao, resource.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Cancelling at period end suspends the subscription at PayPal, the
+	// organization keeps its plan until the period is over
+	if sub.CancelAtPeriodEnd {
+		return sub.OrganizationID, nil
+	}
+
+	sub.Status = model.SubscriptionStatusSuspended
+	if err := subscriptionDao.Update(sub); err != nil {
+		return uuid.Nil, err
+	}
+
+	return sub.OrganizationID, controller.updateOrganization(tx, sub.OrganizationID, func(org *model.Organization) {
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusSuspended
+	})
+}
+
+func (controller PayPalWebhookController) subscriptionPaymentFailed(tx *gorm.DB, resource utils.PayPalWebhookResource) (uuid.UUID, error) {
+	sub, err := controller.findSubscription(dao.NewSubscriptionDao().WithTx(tx), resource.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	amount, currency, reason := resource.FailedPayment()
+	if reason == "" {
+		reason = "Subscription payment failed"
+	}
+	failedPayment := &model.Payment{
+		OrganizationID: sub.OrganizationID,
+		SubscriptionID: &sub.ID,
+		Amount:         amount,
+		Currency:       currency,
+		PaymentMethod:  model.PaymentMethodEnumPayPal,
+		Status:         model.PaymentStatusFailed,
+		FailureReason:  &reason,
+	}
+	if failedPayment.Currency == "" {
+		failedPayment.Currency = sub.Currency
+	}
+	if err := dao.NewPaymentDao().WithTx(tx).Create(failedPayment); err != nil {
+		return uuid.Nil, err
+	}
+
+	return sub.OrganizationID, controller.updateOrganization(tx, sub.OrganizationID, func(org *model.Organization) {
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusPastDue
+	})
+}
+
+func (controller PayPalWebhookController) saleCompleted(tx *gorm.DB, resource utils.PayPalWebhookResource, notices *webhookNotices) (uuid.UUID, error) {
+	sub, err := controller.findSubscription(dao.NewSubscriptionDao().WithTx(tx), resource.BillingAgreementID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	amount, currency := resource.SaleAmount()
+	now := time.Now()
+	sale, err := controller.saleToPayment(tx, sub, resource, model.PaymentStatusCompleted, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Settle the oldest open invoice of the subscription for the same amount;
+	// proration invoices are charged to the vaulted payment method instead
+	invoiceDao := dao.NewInvoiceDao().WithTx(tx)
+	openInvoices, err := invoiceDao.GetOpenBySubscriptionID(sub.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	for _, invoice := range openInvoices {
+		if invoice.IsProration || invoice.Currency != currency || invoice.TotalAmount != amount {
+			continue
+		}
+		notice, err := payments.SettleInvoice(tx, &invoice, now, model.PayPalActor)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		notices.add(sub.OrganizationID, notice)
+		sale.InvoiceID = &invoice.ID
+		if err := dao.NewPaymentDao().WithTx(tx).Update(sale); err != nil {
+			return uuid.Nil, err
+		}
+		break
+	}
+
+	// A payment ends the past due state unless invoices are still in dunning
+	_, err = dao.NewOrganizationDao().WithTx(tx).ReactivateIfSettled(sub.OrganizationID)
+	return sub.OrganizationID, err
+}
+
+func (controller PayPalWebhookController) saleDenied(tx *gorm.DB, resource utils.PayPalWebhookResource) (uuid.UUID, error) {
+	sub, err := controller.findSubscription(dao.NewSubscriptionDao().WithTx(tx), resource.BillingAgreementID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	reason := "Payment denied"
+	if _, err := controller.saleToPayment(tx, sub, resource, model.PaymentStatusFailed, &reason); err != nil {
+		return uuid.Nil, err
+	}
+
+	return sub.OrganizationID, controller.updateOrganization(tx, sub.OrganizationID, func(org *model.Organization) {
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusPastDue
+	})
+}
+
+// saleToPayment records a sale as a Payment, updating the one a previous event
+// about the same sale created.
+func (controller PayPalWebhookController) saleToPayment(tx *gorm.DB, sub *model.Subscription, resource utils.PayPalWebhookResource, status model.PaymentStatus, reason *string) (*model.Payment, error) {
+	paymentDao := dao.NewPaymentDao().WithTx(tx)
+	amount, currency := resource.SaleAmount()
+	now := time.Now()
+
+	sale, err := paymentDao.GetByPayPalPaymentID(resource.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if sale == nil {
+		sale = &model.Payment{
+			OrganizationID:  sub.OrganizationID,
+			SubscriptionID:  &sub.ID,
+			PayPalPaymentID: &resource.ID,
+			Amount:          amount,
+			Currency:        currency,
+			PaymentMethod:   model.PaymentMethodEnumPayPal,
+		}
+	}
+	sale.Status = status
+	sale.FailureReason = reason
+	sale.ProcessedAt = &now
+
+	if sale.ID == uuid.Nil {
+		err = paymentDao.Create(sale)
+	} else {
+		err = paymentDao.Update(sale)
+	}
+	return sale, err
+}
+
+func (controller PayPalWebhookController) invoiceStatusChanged(tx *gorm.DB, resource utils.PayPalWebhookResource, status model.InvoiceStatus, notices *webhookNotices) (uuid.UUID, error) {
+	invoiceDao := dao.NewInvoiceDao().WithTx(tx)
+	invoice, err := invoiceDao.GetByPayPalInvoiceID(resource.InvoiceID())
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, errWebhookUnmatched
+		}
+		return uuid.Nil, err
+	}
+
+	// A paid invoice is never reopened by a late cancellation
+	if invoice.IsPaid() {
+		return invoice.OrganizationID, nil
+	}
+	if status == model.InvoiceStatusPaid {
+		notice, err := payments.SettleInvoice(tx, invoice, time.Now(), model.PayPalActor)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		notices.add(invoice.OrganizationID, notice)
+		return invoice.OrganizationID, nil
+	}
+	return invoice.OrganizationID, invoiceDao.UpdateStatus(invoice.ID, status)
+}
+
+// paymentTokenDeleted invalidates the payment method of a vault token removed
+// at PayPal, e.g. by the payer from their PayPal account.
+func (controller PayPalWebhookController) paymentTokenDeleted(tx *gorm.DB, resource utils.PayPalWebhookResource) (uuid.UUID, error) {
+	if resource.ID == "" {
+		return uuid.Nil, errWebhookUnmatched
+	}
+	paymentMethod, err := dao.NewPaymentMethodDao().WithTx(tx).GetByPayPalVaultID(resource.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, errWebhookUnmatched
+		}
+		return uuid.Nil, err
+	}
+
+	// Deleting a payment method removes its token too
+	if !paymentMethod.IsActive || paymentMethod.Status == model.PaymentMethodStatusInvalid {
+		return paymentMethod.OrganizationID, nil
+	}
+	return paymentMethod.OrganizationID, payments.InvalidatePaymentMethod(tx, paymentMethod, "Removed at PayPal", time.Now())
+}
+
+func (controller PayPalWebhookController) findSubscription(subscriptionDao *dao.SubscriptionDao, paypalSubscriptionID string) (*model.Subscription, error) {
+	if paypalSubscriptionID == "" {
+		return nil, errWebhookUnmatched
+	}
+	sub, err := subscriptionDao.GetByPayPalSubscriptionID(paypalSubscriptionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errWebhookUnmatched
+		}
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (controller PayPalWebhookController) updateOrganization(tx *gorm.DB, organizationID uuid.UUID, change func(org *model.Organization)) error {
+	orgDao := dao.NewOrganizationDao().WithTx(tx)
+	org, err := orgDao.GetByID(organizationID)
+	if err != nil {
+		return err
+	}
+	change(org)
+	return orgDao.Update(org)
+}
+
+// organizationFromCustomValue records events about records that no longer exist
+// against the organization set as custom id when the resource was created.
+func (controller PayPalWebhookController) organizationFromCustomValue(tx *gorm.DB, resource utils.PayPalWebhookResource) (uuid.UUID, error) {
+	organizationID, err := uuid.Parse(resource.CustomValue())
+	if err != nil {
+		return uuid.Nil, errWebhookUnmatched
+	}
+	if _, err := dao.NewOrganizationDao().WithTx(tx).GetByID(organizationID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return uuid.Nil, errWebhookUnmatched
+		}
+		return uuid.Nil, err
+	}
+	return organizationID, nil
+}
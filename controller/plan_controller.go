@@ -4,9 +4,11 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"testlake/dao"
 	"testlake/inout"
 	"testlake/inout/plan"
+	"testlake/model"
 	"testlake/utils"
 
 	"github.com/gin-gonic/gin"
@@ -16,8 +18,71 @@ import (
 
 type PlanController struct{}
 
+// currency returns the currency plans are priced in for the caller: the
+// currency query parameter, else the currency of the organization_id query
+// parameter's organization, which the caller must belong to, else USD. When
+// it fails the error response is sent and false is returned.
+func (controller PlanController) currency(context *gin.Context) (string, bool) {
+	if currency := strings.ToUpper(context.Query("currency")); currency != "" {
+		if !model.IsSupportedCurrency(currency) {
+			utils.ReportBadRequest(context, "Unsupported currency")
+			return "", false
+		}
+		return currency, true
+	}
+
+	orgIDParam := context.Query("organization_id")
+	if orgIDParam == "" {
+		return model.DefaultCurrency, true
+	}
+	organizationID, err := uuid.Parse(orgIDParam)
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return "", false
+	}
+	if err := utils.ValidateJWT(context); err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return "", false
+	}
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return "", false
+	}
+
+	org, err := dao.NewOrganizationDao().GetByID(organizationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Organization not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return "", false
+	}
+	if org.CreatedBy != userID {
+		isMember, err := dao.NewOrganizationMemberDao().IsUserMember(organizationID, userID)
+		if err != nil {
+			utils.ReportInternalServerError(context, "Database error")
+			return "", false
+		}
+		if !isMember {
+			utils.ReportForbidden(context, "Access denied")
+			return "", false
+		}
+	}
+	if org.Currency == "" {
+		return model.DefaultCurrency, true
+	}
+	return org.Currency, true
+}
+
 // GetAllPlans returns all active plans
 func (controller PlanController) GetAllPlans(context *gin.Context) {
+	currency, ok := controller.currency(context)
+	if !ok {
+		return
+	}
+
 	planDao := dao.NewPlanDao()
 	plans, err := planDao.GetAll()
 	if err != nil {
@@ -30,7 +95,7 @@ func (controller PlanController) GetAllPlans(context *gin.Context) {
 			ErrorCode:        0,
 			ErrorDescription: "Success",
 		},
-		List: plan.FromPlanModelList(plans),
+		List: plan.FromPlanModelList(plans, currency),
 	}
 
 	context.JSON(http.StatusOK, response)
@@ -44,6 +109,10 @@ func (controller PlanController) GetPlan(context *gin.Context) {
 		utils.ReportBadRequest(context, "Invalid plan ID")
 		return
 	}
+	currency, ok := controller.currency(context)
+	if !ok {
+		return
+	}
 
 	planDao := dao.NewPlanDao()
 	foundPlan, err := planDao.GetByID(planID)
@@ -61,7 +130,7 @@ func (controller PlanController) GetPlan(context *gin.Context) {
 			ErrorCode:        0,
 			ErrorDescription: "Success",
 		},
-		Data: plan.FromPlanModel(foundPlan),
+		Data: plan.FromPlanModel(foundPlan, currency),
 	}
 
 	context.JSON(http.StatusOK, response)
@@ -69,6 +138,11 @@ func (controller PlanController) GetPlan(context *gin.Context) {
 
 // ComparePlans returns all active plans for comparison
 func (controller PlanController) ComparePlans(context *gin.Context) {
+	currency, ok := controller.currency(context)
+	if !ok {
+		return
+	}
+
 	planDao := dao.NewPlanDao()
 	plans, err := planDao.GetAll()
 	if err != nil {
@@ -77,7 +151,7 @@ func (controller PlanController) ComparePlans(context *gin.Context) {
 	}
 
 	comparison := plan.PlanComparison{
-		Plans: plan.FromPlanModelList(plans),
+		Plans: plan.FromPlanModelList(plans, currency),
 	}
 
 	response := plan.PlanComparisonOut{
@@ -99,6 +173,10 @@ func (controller PlanController) GetPlansWithPagination(context *gin.Context) {
 		utils.ReportBadRequest(context, "Invalid page parameter")
 		return
 	}
+	currency, ok := controller.currency(context)
+	if !ok {
+		return
+	}
 
 	planDao := dao.NewPlanDao()
 	plans, total, err := planDao.GetAllWithPagination(page)
@@ -121,7 +199,7 @@ func (controller PlanController) GetPlansWithPagination(context *gin.Context) {
 			ErrorCode:        0,
 			ErrorDescription: "Success",
 		},
-		List: plan.FromPlanModelList(plans),
+		List: plan.FromPlanModelList(plans, currency),
 		Meta: inout.PaginationMeta{
 			Page:       page,
 			Limit:      planDao.Limit,
@@ -0,0 +1,369 @@
+package controller
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"testlake/dao"
+	"testlake/inout"
+	"testlake/inout/auth"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ssoStateTTL bounds how long a user may take to sign in at the identity provider
+const ssoStateTTL = 10 * time.Minute
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+
+type SSOController struct{}
+
+// Discover finds the organization that handles SSO for an email address and
+// returns the identity provider URL to send the browser to
+func (controller SSOController) Discover(context *gin.Context) {
+	var request auth.SSODiscoverRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.ReportBadRequest(context, "Invalid request data")
+		return
+	}
+
+	email := utils.NormalizeLoginKey(request.Email)
+	config, err := dao.NewOrganizationSSODao().GetByEmailDomain(email)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Single sign-on is not configured for this email domain")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	org, err := dao.NewOrganizationDao().GetByID(config.OrganizationID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	authorizationURL, err := controller.beginLogin(config, email)
+	if err != nil {
+		log.Printf("SSO discovery failed for organization %s: %v", org.ID, err)
+		utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	response := auth.SSODiscoverOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: auth.SSODiscoverData{
+			OrganizationID:   org.ID,
+			OrganizationName: org.Name,
+			OrganizationSlug: org.Slug,
+			AuthorizationURL: authorizationURL,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// Login redirects the browser to the identity provider of an organization
+func (controller SSOController) Login(context *gin.Context) {
+	org, err := dao.NewOrganizationDao().GetBySlug(context.Param("slug"))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Organization not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	config, err := dao.NewOrganizationSSODao().GetByOrganizationID(org.ID)
+	if err != nil || !config.IsEnabled {
+		if err == nil || errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Single sign-on is not configured for this organization")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	authorizationURL, err := controller.beginLogin(config, utils.NormalizeLoginKey(context.Query("login_hint")))
+	if err != nil {
+		log.Printf("SSO discovery failed for organization %s: %v", org.ID, err)
+		utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	context.Redirect(http.StatusFound, authorizationURL)
+}
+
+// beginLogin stores a fresh state, nonce and PKCE verifier and builds the authorization URL
+func (controller SSOController) beginLogin(config *model.OrganizationSSOConfig, loginHint string) (string, error) {
+	client := utils.NewOIDCClient(config.Issuer, config.ClientID, config.ClientSecret, utils.SSORedirectURL())
+	metadata, err := client.Discover()
+	if err != nil {
+		return "", err
+	}
+
+	state, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+	codeVerifier, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	loginState := &model.SSOLoginState{
+		State:          state,
+		Nonce:          nonce,
+		CodeVerifier:   codeVerifier,
+		OrganizationID: config.OrganizationID,
+		ExpiresAt:      time.Now().Add(ssoStateTTL),
+	}
+	if err := dao.NewSSOLoginStateDao().Create(loginState); err != nil {
+		return "", err
+	}
+
+	return client.AuthorizationURL(metadata, state, nonce, codeVerifier, loginHint), nil
+}
+
+// Callback completes the authorization code flow, provisions the user into the
+// organization on first sign-in and starts a TestLake session
+func (controller SSOController) Callback(context *gin.Context) {
+	if providerError := context.Query("error"); providerError != "" {
+		controller.fail(context, http.StatusUnauthorized, "Identity provider rejected the sign-in: "+providerError)
+		return
+	}
+
+	code := context.Query("code")
+	state := context.Query("state")
+	if code == "" || state == "" {
+		controller.fail(context, http.StatusBadRequest, "Missing code or state")
+		return
+	}
+
+	loginState, err := dao.NewSSOLoginStateDao().Consume(state)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			controller.fail(context, http.StatusBadRequest, "Sign-in request is invalid or has expired")
+		} else {
+			controller.fail(context, http.StatusInternalServerError, "Database error")
+		}
+		return
+	}
+
+	ssoDao := dao.NewOrganizationSSODao()
+	config, err := ssoDao.GetByOrganizationID(loginState.OrganizationID)
+	if err != nil || !config.IsEnabled {
+		controller.fail(context, http.StatusForbidden, "Single sign-on is not configured for this organization")
+		return
+	}
+
+	client := utils.NewOIDCClient(config.Issuer, config.ClientID, config.ClientSecret, utils.SSORedirectURL())
+	metadata, err := client.Discover()
+	if err != nil {
+		log.Printf("SSO discovery failed for organization %s: %v", config.OrganizationID, err)
+		controller.fail(context, http.StatusBadGateway, "Identity provider is unavailable")
+		return
+	}
+
+	tokens, err := client.ExchangeCode(metadata, code, loginState.CodeVerifier)
+	if err != nil {
+		log.Printf("SSO code exchange failed for organization %s: %v", config.OrganizationID, err)
+		controller.fail(context, http.StatusUnauthorized, "Failed to complete sign-in with the identity provider")
+		return
+	}
+
+	claims, err := client.VerifyIDToken(metadata, tokens.IDToken, loginState.Nonce)
+	if err != nil {
+		log.Printf("SSO ID token rejected for organization %s: %v", config.OrganizationID, err)
+		controller.fail(context, http.StatusUnauthorized, "Identity provider returned an invalid token")
+		return
+	}
+
+	authController := AuthController{}
+	email := utils.NormalizeLoginKey(claims.Email)
+	domainVerified, err := ssoDao.IsEmailDomainVerified(config.OrganizationID, email)
+	if err != nil {
+		controller.fail(context, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if email == "" || !config.AllowsEmail(email) || !domainVerified || claims.IsEmailUnverified() {
+		authController.recordLoginEvent(context, email, nil, model.AuthMethodSSO, model.LoginOutcomeSSOFailed, nil)
+		controller.fail(context, http.StatusForbidden, "Your email address is not allowed to sign in to this organization")
+		return
+	}
+
+	subject := config.Issuer + "|" + claims.Subject
+	userDao := dao.NewUserDao()
+	foundUser, err := userDao.GetByEmail(email)
+	isNewUser := false
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			controller.fail(context, http.StatusInternalServerError, "Database error")
+			return
+		}
+		foundUser, err = controller.newUser(claims, email, subject)
+		if err != nil {
+			controller.fail(context, http.StatusInternalServerError, "Failed to create user")
+			return
+		}
+		isNewUser = true
+	} else if !controller.mayLinkExistingUser(foundUser, config, subject) {
+		// The provider is controlled by the organization, so it must not be able to
+		// take over an account that has no relationship with the organization yet
+		authController.recordLoginEvent(context, email, &foundUser.ID, model.AuthMethodSSO, model.LoginOutcomeSSOFailed, nil)
+		controller.fail(context, http.StatusConflict, "An account with this email already exists. Sign in with your password and accept an invitation to the organization first")
+		return
+	}
+
+	if foundUser.Status != model.UserStatusActive {
+		authController.recordLoginEvent(context, email, &foundUser.ID, model.AuthMethodSSO, model.LoginOutcomeInactiveAccount, nil)
+		controller.fail(context, http.StatusForbidden, "Account is not active")
+		return
+	}
+
+	// Invited members already hold their seat, anyone else takes a new one
+	if !controller.holdsSeat(config, foundUser, isNewUser) {
+		limits, err := utils.LoadOrganizationLimits(config.OrganizationID)
+		if err != nil {
+			controller.fail(context, http.StatusInternalServerError, "Failed to check plan limits")
+			return
+		}
+		if !limits.Check(model.LimitResourceUsers, 1).Allowed {
+			authController.recordLoginEvent(context, email, nil, model.AuthMethodSSO, model.LoginOutcomeSSOFailed, nil)
+			controller.fail(context, http.StatusPaymentRequired, "This organization has no seats left on its plan. Ask an administrator to upgrade it")
+			return
+		}
+	}
+
+	if err := ssoDao.ProvisionMember(config, foundUser, isNewUser); err != nil {
+		controller.fail(context, http.StatusInternalServerError, "Failed to provision organization membership")
+		return
+	}
+
+	token, err := authController.startSession(context, foundUser, model.AuthMethodSSO)
+	if err != nil {
+		controller.fail(context, http.StatusInternalServerError, "Failed to generate token")
+		return
+	}
+	userDao.UpdateLastLogin(foundUser.ID)
+
+	// Browser flows hand the token to the frontend in the fragment so it never reaches server logs
+	if frontendURL := os.Getenv("SSO_FRONTEND_CALLBACK_URL"); frontendURL != "" {
+		context.Redirect(http.StatusFound, frontendURL+"#"+url.Values{"token": {token}}.Encode())
+		return
+	}
+
+	response := auth.SignInOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: auth.AuthData{
+			Token: token,
+			User:  auth.UserFromModel(foundUser),
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// mayLinkExistingUser allows SSO for accounts created by the same provider or
+// already invited to, or member of, the organization
+func (controller SSOController) mayLinkExistingUser(user *model.User, config *model.OrganizationSSOConfig, subject string) bool {
+	if user.AuthProvider == model.AuthProviderOIDC && user.AuthProviderID != nil && *user.AuthProviderID == subject {
+		return true
+	}
+
+	member, err := dao.NewOrganizationMemberDao().GetMemberByUserID(config.OrganizationID, user.ID)
+	if err != nil {
+		return false
+	}
+	return member.Status == "joined" || member.Status == "invited"
+}
+
+// holdsSeat reports whether the user is already counted against the
+// organization's user limit: the creator and invited or joined members are
+func (controller SSOController) holdsSeat(config *model.OrganizationSSOConfig, user *model.User, isNewUser bool) bool {
+	if isNewUser {
+		return false
+	}
+	member, err := dao.NewOrganizationMemberDao().GetMemberByUserID(config.OrganizationID, user.ID)
+	if err == nil && (member.Status == "joined" || member.Status == "invited") {
+		return true
+	}
+	org, err := dao.NewOrganizationDao().GetByID(config.OrganizationID)
+	return err == nil && org.CreatedBy == user.ID
+}
+
+// newUser builds a verified account from the ID token claims with a unique username
+func (controller SSOController) newUser(claims *utils.OIDCIDTokenClaims, email, subject string) (*model.User, error) {
+	base := usernameInvalidChars.ReplaceAllString(strings.ToLower(strings.SplitN(email, "@", 2)[0]), "")
+	for len(base) < 3 {
+		base += "0"
+	}
+	base = utils.TruncateString(base, 90)
+
+	userDao := dao.NewUserDao()
+	username := base
+	for attempt := 0; ; attempt++ {
+		exists, err := userDao.UsernameExists(username)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			break
+		}
+		if attempt >= 5 {
+			return nil, errors.New("could not generate a unique username")
+		}
+		suffix, err := utils.GenerateSecureToken(3)
+		if err != nil {
+			return nil, err
+		}
+		username = base + "-" + suffix
+	}
+
+	newUser := &model.User{
+		Email:           email,
+		Username:        username,
+		AuthProvider:    model.AuthProviderOIDC,
+		AuthProviderID:  &subject,
+		Status:          model.UserStatusActive,
+		IsEmailVerified: true,
+	}
+	if claims.GivenName != "" {
+		newUser.FirstName = &claims.GivenName
+	}
+	if claims.FamilyName != "" {
+		newUser.LastName = &claims.FamilyName
+	}
+	return newUser, nil
+}
+
+// fail reports a callback error, either to the frontend when one is configured or as JSON
+func (controller SSOController) fail(context *gin.Context, statusCode int, message string) {
+	if frontendURL := os.Getenv("SSO_FRONTEND_CALLBACK_URL"); frontendURL != "" {
+		context.Redirect(http.StatusFound, frontendURL+"#"+url.Values{"error": {message}}.Encode())
+		return
+	}
+
+	utils.ReportCustomError(context, statusCode, statusCode, message)
+}
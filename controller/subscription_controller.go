@@ -2,11 +2,15 @@ package controller
 
 import (
 	"errors"
+	"log"
 	"net/http"
+	"strings"
 	"testlake/dao"
 	"testlake/inout"
+	"testlake/inout/coupon"
 	"testlake/inout/subscription"
 	"testlake/model"
+	"testlake/payments"
 	"testlake/utils"
 	"time"
 
@@ -19,21 +23,14 @@ type SubscriptionController struct{}
 
 // GetSubscription returns the current subscription for an organization
 func (controller SubscriptionController) GetSubscription(context *gin.Context) {
-	orgIDParam := context.Param("orgId")
+	orgIDParam := context.Param("id")
 	organizationID, err := uuid.Parse(orgIDParam)
 	if err != nil {
 		utils.ReportBadRequest(context, "Invalid organization ID")
 		return
 	}
 
-	userID, err := utils.ExtractUserID(context)
-	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
-		return
-	}
-
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForMember(context, organizationID); !ok {
 		return
 	}
 
@@ -48,12 +45,18 @@ func (controller SubscriptionController) GetSubscription(context *gin.Context) {
 		return
 	}
 
+	data := subscription.FromSubscriptionModel(sub)
+	if redemption, err := dao.NewCouponDao().GetActiveRedemption(sub.ID); err == nil {
+		discount := coupon.FromRedemptionModel(redemption)
+		data.Discount = &discount
+	}
+
 	response := subscription.SubscriptionOut{
 		BaseResponse: inout.BaseResponse{
 			ErrorCode:        0,
 			ErrorDescription: "Success",
 		},
-		Data: subscription.FromSubscriptionModel(sub),
+		Data: data,
 	}
 
 	context.JSON(http.StatusOK, response)
@@ -74,8 +77,7 @@ func (controller SubscriptionController) CreateSubscription(context *gin.Context
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage the subscription"); !ok {
 		return
 	}
 
@@ -96,17 +98,68 @@ func (controller SubscriptionController) CreateSubscription(context *gin.Context
 		}
 		return
 	}
+	if !plan.IsActive {
+		utils.ReportBadRequest(context, "Plan is no longer available")
+		return
+	}
 
 	// Check if organization already has an active subscription
 	subscriptionDao := dao.NewSubscriptionDao()
+	// A subscription billed without the gateway (free plan) may be replaced by a paid
+	// one, it is cancelled once the provider activates the new subscription
 	existingSub, err := subscriptionDao.GetActiveByOrganizationID(organizationID)
-	if err == nil && existingSub != nil {
+	if err == nil && existingSub != nil && (existingSub.IsGatewayBilled() || plan.PriceFor(request.BillingCycle) == 0) {
 		utils.ReportBadRequest(context, "Organization already has an active subscription")
 		return
 	}
 
-	// Create subscription - in a real implementation, this would involve PayPal API calls
+	orgDao := dao.NewOrganizationDao()
+	org, err := orgDao.GetByID(organizationID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to get organization")
+		return
+	}
+
+	currency := strings.ToUpper(request.Currency)
+	if currency == "" {
+		currency = org.Currency
+	}
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	if !model.IsSupportedCurrency(currency) {
+		utils.ReportBadRequest(context, "Unsupported currency")
+		return
+	}
+	price, found := plan.PriceIn(currency, request.BillingCycle)
+	if !found {
+		utils.ReportBadRequest(context, "Plan is not available in "+currency)
+		return
+	}
+
 	now := time.Now()
+	var discounted *int64
+	if request.CouponCode != "" {
+		if price == 0 {
+			utils.ReportBadRequest(context, "Coupons only apply to paid plans")
+			return
+		}
+		redeemable, err := payments.CheckCoupon(request.CouponCode, organizationID, plan.ID, now)
+		if err != nil {
+			if !controller.reportCouponError(context, err) {
+				utils.ReportInternalServerError(context, "Database error")
+			}
+			return
+		}
+		discountedPrice := price - redeemable.Discount(price, currency)
+		discounted = &discountedPrice
+	}
+
+	if request.StartTrial {
+		controller.startTrial(context, org, plan, request.BillingCycle, currency, request.CouponCode, userID)
+		return
+	}
+
 	var periodEnd time.Time
 	if request.BillingCycle == model.BillingCycleMonthly {
 		periodEnd = now.AddDate(0, 1, 0)
@@ -115,44 +168,201 @@ func (controller SubscriptionController) CreateSubscription(context *gin.Context
 	}
 
 	newSubscription := &model.Subscription{
-		OrganizationID:       organizationID,
-		PlanID:               request.PlanID,
-		PayPalSubscriptionID: "TEMP_" + uuid.New().String(), // This would be replaced with actual PayPal subscription ID
-		Status:               model.SubscriptionStatusPending,
-		BillingCycle:         request.BillingCycle,
-		CurrentPeriodStart:   now,
-		CurrentPeriodEnd:     periodEnd,
-		CreatedBy:            userID,
+		OrganizationID:     organizationID,
+		PlanID:             request.PlanID,
+		Status:             model.SubscriptionStatusActive,
+		BillingCycle:       request.BillingCycle,
+		Currency:           currency,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   periodEnd,
+		CreatedBy:          userID,
+	}
+
+	// Paid plans are billed by the payment provider; the subscription stays pending
+	// until the payer approves it and the provider reports it as active
+	approvalURL := ""
+	if price > 0 {
+		gatewayPlanID := plan.PayPalPlanIDIn(currency, request.BillingCycle)
+		if gatewayPlanID == nil {
+			utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Plan is not available for purchase yet")
+			return
+		}
+
+		subscriberEmail := ""
+		if org.BillingEmail != nil {
+			subscriberEmail = *org.BillingEmail
+		}
+		tax, err := payments.CalculateTax(org, price, currency)
+		if err != nil {
+			log.Printf("Failed to calculate the tax of organization %s: %v", organizationID, err)
+			utils.ReportInternalServerError(context, "Failed to calculate tax")
+			return
+		}
+		var taxPercentage *float64
+		if tax.Rate > 0 {
+			taxPercentage = &tax.Rate
+		}
+
+		returnURL, cancelURL := utils.PaymentReturnURLs()
+		// A discount is priced into the provider subscription from its first
+		// cycle, the provider adds the tax on top so that its charges match the invoices
+		gatewaySubscription, err := utils.GetPaymentGateway().CreateSubscription(context.Request.Context(), utils.GatewaySubscriptionRequest{
+			PlanID:          *gatewayPlanID,
+			CustomID:        organizationID.String(),
+			SubscriberEmail: subscriberEmail,
+			ReturnURL:       returnURL,
+			CancelURL:       cancelURL,
+			Price:           discounted,
+			Currency:        currency,
+			TaxPercentage:   taxPercentage,
+		})
+		if err != nil {
+			log.Printf("Failed to create gateway subscription for organization %s: %v", organizationID, err)
+			utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider error")
+			return
+		}
+
+		newSubscription.PayPalSubscriptionID = &gatewaySubscription.ID
+		newSubscription.Status = model.SubscriptionStatusPending
+		approvalURL = gatewaySubscription.ApprovalURL
 	}
 
-	err = subscriptionDao.Create(newSubscription)
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := subscriptionDao.WithTx(tx).Create(newSubscription); err != nil {
+			return err
+		}
+		err := dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(organizationID, model.BillingEventTypeSubscriptionCreated, map[string]interface{}{
+			"subscription_id":        newSubscription.ID,
+			"plan_id":                plan.ID,
+			"billing_cycle":          newSubscription.BillingCycle,
+			"currency":               currency,
+			"status":                 newSubscription.Status,
+			"paypal_subscription_id": newSubscription.PayPalSubscriptionID,
+		})
+		if err != nil {
+			return err
+		}
+		if request.CouponCode == "" {
+			return nil
+		}
+		if _, err := payments.RedeemCoupon(tx, request.CouponCode, newSubscription, plan.ID, userID, now); err != nil {
+			return err
+		}
+		// The provider charges the first period at the discounted price
+		if _, err := payments.DiscountLine(tx, newSubscription, newSubscription.CurrentPeriodStart, price, 1, currency); err != nil {
+			return err
+		}
+		if newSubscription.IsGatewayBilled() {
+			return dao.NewCouponDao().WithTx(tx).SetGatewayPrice(newSubscription.ID, *discounted)
+		}
+		return nil
+	})
 	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to create subscription")
+		if !controller.reportCouponError(context, err) {
+			utils.ReportInternalServerError(context, "Failed to create subscription")
+		}
 		return
 	}
 
-	// Update organization plan information
-	orgDao := dao.NewOrganizationDao()
-	org, err := orgDao.GetByID(organizationID)
-	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to get organization")
+	// Update organization plan information once nothing is left to approve
+	if newSubscription.Status == model.SubscriptionStatusActive {
+		org.PlanID = &plan.ID
+		org.BillingCycle = request.BillingCycle
+		org.Currency = currency
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusActive
+		org.NextBillingDate = &periodEnd
+		err = orgDao.Update(org)
+		if err != nil {
+			utils.ReportInternalServerError(context, "Failed to update organization")
+			return
+		}
+	}
+
+	data := subscription.FromSubscriptionModel(newSubscription)
+	data.ApprovalURL = approvalURL
+
+	response := subscription.SubscriptionOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Subscription created successfully",
+		},
+		Data: data,
+	}
+
+	context.JSON(http.StatusCreated, response)
+}
+
+// startTrial subscribes the organization to a paid plan for a free trial. Nothing
+// is charged: when the trial ends the billing run converts it to a paid period
+// if the organization has a payment method, or moves it to the free plan. A
+// coupon redeemed with the trial discounts the paid periods that follow.
+func (controller SubscriptionController) startTrial(context *gin.Context, org *model.Organization, plan *model.Plan, cycle model.BillingCycle, currency, couponCode string, userID uuid.UUID) {
+	if plan.PriceFor(cycle) == 0 {
+		utils.ReportBadRequest(context, "Trials are only available for paid plans")
+		return
+	}
+	if org.TrialEndsAt != nil {
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Organization has already used its trial")
 		return
 	}
 
-	org.PlanID = &plan.ID
-	org.BillingCycle = request.BillingCycle
-	org.SubscriptionStatus = model.OrganizationSubscriptionStatusActive
-	org.NextBillingDate = &periodEnd
-	err = orgDao.Update(org)
+	now := time.Now()
+	trialEnd := now.Add(utils.LoadTrialConfig().Length)
+	newSubscription := &model.Subscription{
+		OrganizationID:     org.ID,
+		PlanID:             plan.ID,
+		Status:             model.SubscriptionStatusActive,
+		BillingCycle:       cycle,
+		Currency:           currency,
+		CurrentPeriodStart: now,
+		CurrentPeriodEnd:   trialEnd,
+		TrialEnd:           &trialEnd,
+		CreatedBy:          userID,
+	}
+
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		subscriptionDao := dao.NewSubscriptionDao().WithTx(tx)
+		if err := subscriptionDao.Create(newSubscription); err != nil {
+			return err
+		}
+		// The trial replaces the free subscription the organization may have
+		if err := subscriptionDao.CancelOthers(org.ID, newSubscription.ID); err != nil {
+			return err
+		}
+		if couponCode != "" {
+			if _, err := payments.RedeemCoupon(tx, couponCode, newSubscription, plan.ID, userID, now); err != nil {
+				return err
+			}
+		}
+
+		org.PlanID = &plan.ID
+		org.BillingCycle = cycle
+		org.Currency = currency
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusTrialing
+		org.TrialEndsAt = &trialEnd
+		org.NextBillingDate = &trialEnd
+		if err := dao.NewOrganizationDao().WithTx(tx).Update(org); err != nil {
+			return err
+		}
+
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(org.ID, model.BillingEventTypeTrialStarted, map[string]interface{}{
+			"subscription_id": newSubscription.ID,
+			"plan_id":         plan.ID,
+			"billing_cycle":   cycle,
+			"trial_end":       trialEnd,
+		})
+	})
 	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to update organization")
+		if !controller.reportCouponError(context, err) {
+			utils.ReportInternalServerError(context, "Failed to start trial")
+		}
 		return
 	}
 
 	response := subscription.SubscriptionOut{
 		BaseResponse: inout.BaseResponse{
 			ErrorCode:        0,
-			ErrorDescription: "Subscription created successfully",
+			ErrorDescription: "Trial started successfully",
 		},
 		Data: subscription.FromSubscriptionModel(newSubscription),
 	}
@@ -175,8 +385,7 @@ func (controller SubscriptionController) ChangePlan(context *gin.Context) {
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage the subscription"); !ok {
 		return
 	}
 
@@ -185,6 +394,10 @@ func (controller SubscriptionController) ChangePlan(context *gin.Context) {
 		utils.ReportBadRequest(context, "Invalid request body")
 		return
 	}
+	if request.BillingCycle != model.BillingCycleMonthly && request.BillingCycle != model.BillingCycleYearly {
+		utils.ReportBadRequest(context, "Billing cycle must be monthly or yearly")
+		return
+	}
 
 	// Validate new plan exists
 	planDao := dao.NewPlanDao()
@@ -197,6 +410,10 @@ func (controller SubscriptionController) ChangePlan(context *gin.Context) {
 		}
 		return
 	}
+	if !newPlan.IsActive {
+		utils.ReportBadRequest(context, "Plan is no longer available")
+		return
+	}
 
 	// Get current subscription
 	subscriptionDao := dao.NewSubscriptionDao()
@@ -210,45 +427,217 @@ func (controller SubscriptionController) ChangePlan(context *gin.Context) {
 		return
 	}
 
-	// Update subscription
-	currentSub.PlanID = request.NewPlanID
-	currentSub.BillingCycle = request.BillingCycle
-	err = subscriptionDao.Update(currentSub)
-	if err != nil {
+	if currentSub.PlanID == newPlan.ID && currentSub.BillingCycle == request.BillingCycle {
+		utils.ReportBadRequest(context, "Subscription is already on this plan")
+		return
+	}
+	// The subscription keeps the currency it was created in
+	newPrice, found := currentSub.Price(newPlan, request.BillingCycle)
+	if !found {
+		utils.ReportBadRequest(context, "Plan is not available in "+currentSub.Currency)
+		return
+	}
+
+	if request.CouponCode != "" {
+		if newPrice == 0 {
+			utils.ReportBadRequest(context, "Coupons only apply to paid plans")
+			return
+		}
+		if _, err := payments.CheckCoupon(request.CouponCode, organizationID, newPlan.ID, time.Now()); err != nil {
+			if !controller.reportCouponError(context, err) {
+				utils.ReportInternalServerError(context, "Database error")
+			}
+			return
+		}
+	}
+
+	if isPlanUpgrade(&currentSub.Plan, currentSub.BillingCycle, newPlan, request.BillingCycle) {
+		controller.upgradePlan(context, currentSub, newPlan, request.BillingCycle, request.CouponCode, userID)
+	} else {
+		controller.scheduleDowngrade(context, currentSub, newPlan, request.BillingCycle, request.CouponCode, userID)
+	}
+}
+
+// isPlanUpgrade reports whether moving between plans takes effect immediately.
+// A more expensive plan is an upgrade, and so is committing to a yearly cycle
+// on a plan of the same price; everything else waits for the period to end.
+func isPlanUpgrade(currentPlan *model.Plan, currentCycle model.BillingCycle, newPlan *model.Plan, newCycle model.BillingCycle) bool {
+	if newPlan.PriceMonthly != currentPlan.PriceMonthly {
+		return newPlan.PriceMonthly > currentPlan.PriceMonthly
+	}
+	return currentCycle == model.BillingCycleMonthly && newCycle == model.BillingCycleYearly
+}
+
+// upgradePlan moves the subscription to the new plan right away and invoices
+// the difference for the rest of the period.
+func (controller SubscriptionController) upgradePlan(context *gin.Context, currentSub *model.Subscription, newPlan *model.Plan, cycle model.BillingCycle, couponCode string, userID uuid.UUID) {
+	upgrade, err := payments.UpgradeSubscription(context.Request.Context(), currentSub, newPlan, cycle, couponCode, userID, time.Now())
+	switch {
+	case errors.Is(err, payments.ErrPlanNotPurchasable):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Plan is not available for purchase yet")
+		return
+	case errors.Is(err, payments.ErrFreeToPaidUpgrade):
+		utils.ReportBadRequest(context, "Create a new subscription to move from a free to a paid plan")
+		return
+	case errors.Is(err, payments.ErrUpgradePaymentMethod):
+		utils.ReportCustomError(context, http.StatusPaymentRequired, http.StatusPaymentRequired, "Add a payment method to pay for the upgrade")
+		return
+	case errors.Is(err, payments.ErrGatewayRevise):
+		utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider error")
+		return
+	case err != nil:
+		if controller.reportCouponError(context, err) {
+			return
+		}
+		log.Printf("Failed to upgrade subscription %s: %v", currentSub.ID, err)
 		utils.ReportInternalServerError(context, "Failed to update subscription")
 		return
 	}
 
-	// Update organization
-	orgDao := dao.NewOrganizationDao()
-	org, err := orgDao.GetByID(organizationID)
+	data := subscription.FromSubscriptionModel(currentSub)
+	data.ApprovalURL = upgrade.ApprovalURL
+	if upgrade.Invoice != nil {
+		payments.SendBillingNotice(currentSub.OrganizationID, payments.InvoiceIssuedNotice(upgrade.Invoice))
+		data.ProrationInvoiceID = &upgrade.Invoice.ID
+	}
+
+	context.JSON(http.StatusOK, subscription.SubscriptionOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Plan changed successfully",
+		},
+		Data: data,
+	})
+}
+
+// scheduleDowngrade records the change for the billing job to apply when the
+// current period ends, provided the organization already fits the new plan. A
+// coupon redeemed with it discounts the periods of the new plan.
+func (controller SubscriptionController) scheduleDowngrade(context *gin.Context, currentSub *model.Subscription, newPlan *model.Plan, cycle model.BillingCycle, couponCode string, userID uuid.UUID) {
+	usage, err := dao.NewOrganizationUsageDao().CountLiveUsage(currentSub.OrganizationID)
 	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to get organization")
+		utils.ReportInternalServerError(context, "Failed to count usage")
 		return
 	}
+	if exceeded := usage.ExceededLimits(newPlan); len(exceeded) > 0 {
+		context.JSON(http.StatusConflict, subscription.PlanLimitsExceededOut{
+			BaseResponse: inout.BaseResponse{
+				ErrorCode:        http.StatusConflict,
+				ErrorDescription: "Current usage exceeds the limits of the new plan",
+			},
+			Data: exceeded,
+		})
+		return
+	}
+	// A subscription the billing run charges moves without the provider
+	if newPrice, _ := currentSub.Price(newPlan, cycle); newPrice > 0 && !payments.IsVaultBilled(currentSub) {
+		if newPlan.PayPalPlanIDIn(currentSub.Currency, cycle) == nil {
+			utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Plan is not available for purchase yet")
+			return
+		}
+		if !currentSub.IsGatewayBilled() {
+			utils.ReportBadRequest(context, "Create a new subscription to move from a free to a paid plan")
+			return
+		}
+	}
 
-	org.PlanID = &newPlan.ID
-	org.BillingCycle = request.BillingCycle
-	err = orgDao.Update(org)
+	currentSub.ScheduledPlanID = &newPlan.ID
+	currentSub.ScheduledBillingCycle = &cycle
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := dao.NewSubscriptionDao().WithTx(tx).Update(currentSub); err != nil {
+			return err
+		}
+		err := dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(currentSub.OrganizationID, model.BillingEventTypeSubscriptionUpdated, map[string]interface{}{
+			"subscription_id":         currentSub.ID,
+			"scheduled_plan_id":       newPlan.ID,
+			"scheduled_billing_cycle": cycle,
+			"effective_from":          currentSub.CurrentPeriodEnd,
+		})
+		if err != nil || couponCode == "" {
+			return err
+		}
+		_, err = payments.RedeemCoupon(tx, couponCode, currentSub, newPlan.ID, userID, time.Now())
+		return err
+	})
 	if err != nil {
-		utils.ReportInternalServerError(context, "Failed to update organization")
+		if !controller.reportCouponError(context, err) {
+			utils.ReportInternalServerError(context, "Failed to update subscription")
+		}
 		return
 	}
 
-	response := subscription.SubscriptionOut{
+	context.JSON(http.StatusOK, subscription.SubscriptionOut{
 		BaseResponse: inout.BaseResponse{
 			ErrorCode:        0,
-			ErrorDescription: "Plan changed successfully",
+			ErrorDescription: "Plan change scheduled for " + currentSub.CurrentPeriodEnd.Format("2006-01-02"),
 		},
 		Data: subscription.FromSubscriptionModel(currentSub),
+	})
+}
+
+// CancelScheduledChange drops a downgrade that has not been applied yet.
+func (controller SubscriptionController) CancelScheduledChange(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
 	}
 
-	context.JSON(http.StatusOK, response)
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage the subscription"); !ok {
+		return
+	}
+
+	subscriptionDao := dao.NewSubscriptionDao()
+	currentSub, err := subscriptionDao.GetActiveByOrganizationID(organizationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "No active subscription found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+	if !currentSub.HasScheduledChange() {
+		utils.ReportBadRequest(context, "No plan change is scheduled")
+		return
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := subscriptionDao.WithTx(tx).ClearScheduledChange(currentSub.ID); err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(organizationID, model.BillingEventTypeSubscriptionUpdated, map[string]interface{}{
+			"subscription_id":       currentSub.ID,
+			"dropped_plan_id":       currentSub.ScheduledPlanID,
+			"dropped_billing_cycle": currentSub.ScheduledBillingCycle,
+			"reason":                "scheduled plan change cancelled",
+		})
+	})
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to update subscription")
+		return
+	}
+	currentSub.ScheduledPlanID = nil
+	currentSub.ScheduledBillingCycle = nil
+
+	context.JSON(http.StatusOK, subscription.SubscriptionOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Scheduled plan change cancelled",
+		},
+		Data: subscription.FromSubscriptionModel(currentSub),
+	})
 }
 
 // CancelSubscription cancels a subscription
 func (controller SubscriptionController) CancelSubscription(context *gin.Context) {
-	orgIDParam := context.Param("orgId")
+	orgIDParam := context.Param("id")
 	organizationID, err := uuid.Parse(orgIDParam)
 	if err != nil {
 		utils.ReportBadRequest(context, "Invalid organization ID")
@@ -261,8 +650,7 @@ func (controller SubscriptionController) CancelSubscription(context *gin.Context
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage the subscription"); !ok {
 		return
 	}
 
@@ -278,8 +666,28 @@ func (controller SubscriptionController) CancelSubscription(context *gin.Context
 		return
 	}
 
+	// Stop the provider from renewing. Suspending rather than cancelling keeps
+	// the subscription reactivatable until the period ends
+	if currentSub.IsGatewayBilled() {
+		err = utils.GetPaymentGateway().SuspendSubscription(context.Request.Context(), *currentSub.PayPalSubscriptionID, "Cancelled at period end")
+		if err != nil {
+			log.Printf("Failed to suspend gateway subscription %s: %v", *currentSub.PayPalSubscriptionID, err)
+			utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider error")
+			return
+		}
+	}
+
 	// Cancel subscription at period end
-	err = subscriptionDao.Cancel(currentSub.ID, true)
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := subscriptionDao.WithTx(tx).Cancel(currentSub.ID, true); err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(organizationID, model.BillingEventTypeSubscriptionCancelled, map[string]interface{}{
+			"subscription_id":      currentSub.ID,
+			"cancel_at_period_end": true,
+			"ends_at":              currentSub.CurrentPeriodEnd,
+		})
+	})
 	if err != nil {
 		utils.ReportInternalServerError(context, "Failed to cancel subscription")
 		return
@@ -295,7 +703,7 @@ func (controller SubscriptionController) CancelSubscription(context *gin.Context
 
 // ReactivateSubscription reactivates a cancelled subscription
 func (controller SubscriptionController) ReactivateSubscription(context *gin.Context) {
-	orgIDParam := context.Param("orgId")
+	orgIDParam := context.Param("id")
 	organizationID, err := uuid.Parse(orgIDParam)
 	if err != nil {
 		utils.ReportBadRequest(context, "Invalid organization ID")
@@ -308,8 +716,7 @@ func (controller SubscriptionController) ReactivateSubscription(context *gin.Con
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	if _, ok := organizationForAdmin(context, organizationID, "Only organization owners and admins can manage the subscription"); !ok {
 		return
 	}
 
@@ -325,10 +732,33 @@ func (controller SubscriptionController) ReactivateSubscription(context *gin.Con
 		return
 	}
 
+	if currentSub.Status == model.SubscriptionStatusCancelled || currentSub.Status == model.SubscriptionStatusExpired {
+		utils.ReportBadRequest(context, "Subscription has ended, create a new subscription instead")
+		return
+	}
+
+	if currentSub.IsGatewayBilled() {
+		err = utils.GetPaymentGateway().ActivateSubscription(context.Request.Context(), *currentSub.PayPalSubscriptionID, "Reactivated")
+		if err != nil {
+			log.Printf("Failed to activate gateway subscription %s: %v", *currentSub.PayPalSubscriptionID, err)
+			utils.ReportCustomError(context, http.StatusBadGateway, http.StatusBadGateway, "Payment provider error")
+			return
+		}
+	}
+
 	// Reactivate subscription
+	previousStatus := currentSub.Status
 	currentSub.CancelAtPeriodEnd = false
 	currentSub.Status = model.SubscriptionStatusActive
-	err = subscriptionDao.Update(currentSub)
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if err := subscriptionDao.WithTx(tx).Update(currentSub); err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(organizationID, model.BillingEventTypeSubscriptionReactivated, map[string]interface{}{
+			"subscription_id": currentSub.ID,
+			"previous_status": previousStatus,
+		})
+	})
 	if err != nil {
 		utils.ReportInternalServerError(context, "Failed to reactivate subscription")
 		return
@@ -345,23 +775,43 @@ func (controller SubscriptionController) ReactivateSubscription(context *gin.Con
 	context.JSON(http.StatusOK, response)
 }
 
-// GetSubscriptionUsage returns current usage metrics for the organization
-func (controller SubscriptionController) GetSubscriptionUsage(context *gin.Context) {
-	orgIDParam := context.Param("orgId")
-	organizationID, err := uuid.Parse(orgIDParam)
+// GetCouponRedemptions lists the coupons the organization redeemed, newest first
+func (controller SubscriptionController) GetCouponRedemptions(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
 	if err != nil {
 		utils.ReportBadRequest(context, "Invalid organization ID")
 		return
 	}
 
-	userID, err := utils.ExtractUserID(context)
+	if _, ok := organizationForMember(context, organizationID); !ok {
+		return
+	}
+
+	redemptions, err := dao.NewCouponDao().GetRedemptionsByOrganizationID(organizationID)
 	if err != nil {
-		utils.ReportUnauthorized(context, "Authentication required")
+		utils.ReportInternalServerError(context, "Database error")
 		return
 	}
 
-	if !controller.verifyOrganizationAccess(userID, organizationID) {
-		utils.ReportForbidden(context, "Access denied")
+	context.JSON(http.StatusOK, subscription.CouponRedemptionsOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: coupon.FromRedemptionModelList(redemptions),
+	})
+}
+
+// GetSubscriptionUsage returns current usage metrics for the organization
+func (controller SubscriptionController) GetSubscriptionUsage(context *gin.Context) {
+	orgIDParam := context.Param("id")
+	organizationID, err := uuid.Parse(orgIDParam)
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	if _, ok := organizationForMember(context, organizationID); !ok {
 		return
 	}
 
@@ -427,9 +877,24 @@ func (controller SubscriptionController) GetSubscriptionUsage(context *gin.Conte
 	context.JSON(http.StatusOK, response)
 }
 
-// Helper method to verify organization access
-func (controller SubscriptionController) verifyOrganizationAccess(userID, organizationID uuid.UUID) bool {
-	orgDao := dao.NewOrganizationDao()
-	_, err := orgDao.GetByID(organizationID)
-	return err == nil
+// reportCouponError reports why a coupon cannot be redeemed. It returns false,
+// reporting nothing, when err is not about the coupon.
+func (controller SubscriptionController) reportCouponError(context *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, payments.ErrCouponNotFound):
+		utils.ReportNotFound(context, "Coupon not found")
+	case errors.Is(err, payments.ErrCouponExpired):
+		utils.ReportBadRequest(context, "Coupon has expired")
+	case errors.Is(err, payments.ErrCouponNotForPlan):
+		utils.ReportBadRequest(context, "Coupon does not apply to this plan")
+	case errors.Is(err, payments.ErrCouponExhausted):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Coupon has reached its redemption limit")
+	case errors.Is(err, payments.ErrCouponAlreadyRedeemed):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Coupon was already redeemed by this organization")
+	case errors.Is(err, payments.ErrDiscountActive):
+		utils.ReportCustomError(context, http.StatusConflict, http.StatusConflict, "Subscription already has a discount")
+	default:
+		return false
+	}
+	return true
 }
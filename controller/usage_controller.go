@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"testlake/dao"
+	"testlake/inout"
+	"testlake/inout/usage"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UsageController struct{}
+
+// GetCurrentUsage returns the organization's usage of the current period:
+// live resource counts and the API requests metered so far, against its plan.
+func (controller UsageController) GetCurrentUsage(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	if _, ok := organizationForMember(context, organizationID); !ok {
+		return
+	}
+
+	limits, err := utils.LoadOrganizationLimits(organizationID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to count usage")
+		return
+	}
+
+	now := time.Now()
+	periodStart, periodEnd := model.UsagePeriod(now)
+	current := &model.OrganizationUsage{
+		OrganizationID:    organizationID,
+		PeriodStart:       periodStart,
+		PeriodEnd:         periodEnd,
+		UsersCount:        limits.Usage.Users,
+		ProjectsCount:     limits.Usage.Projects,
+		EnvironmentsCount: limits.Usage.Environments,
+		SchemasCount:      limits.Usage.Schemas,
+		TestRecordsCount:  limits.Usage.MaxTestRecordsPerSchema,
+		RecordedAt:        now,
+	}
+
+	recorded, err := dao.NewOrganizationUsageDao().GetCurrentUsage(organizationID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		utils.ReportInternalServerError(context, "Failed to get usage")
+		return
+	}
+	if recorded != nil {
+		current.APIRequestsCount = recorded.APIRequestsCount
+	}
+	// Requests this instance has not flushed yet
+	current.APIRequestsCount += utils.APIUsageMeter().Pending(organizationID)
+
+	response := usage.CurrentUsageOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: usage.FromUsageModel(current, limits.Plan),
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetUsageHistory returns the organization's usage of its last months, oldest first.
+func (controller UsageController) GetUsageHistory(context *gin.Context) {
+	organizationID, err := uuid.Parse(context.Param("id"))
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid organization ID")
+		return
+	}
+
+	if _, ok := organizationForMember(context, organizationID); !ok {
+		return
+	}
+
+	months, err := strconv.Atoi(context.DefaultQuery("months", "6"))
+	if err != nil || months < 1 || months > 24 {
+		utils.ReportBadRequest(context, "Invalid months parameter, expected 1 to 24")
+		return
+	}
+
+	periodStart, _ := model.UsagePeriod(time.Now())
+	usages, err := dao.NewOrganizationUsageDao().GetUsageBetween(organizationID, periodStart.AddDate(0, 1-months, 0), periodStart.AddDate(0, 1, 0))
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to get usage history")
+		return
+	}
+
+	response := usage.UsageHistoryOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: usage.UsageHistory{
+			OrganizationID: organizationID,
+			Period:         "monthly",
+			DataPoints:     usage.FromUsageModelList(usages),
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
@@ -2,7 +2,11 @@ package controller
 
 import (
 	"errors"
+	"log"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 	"testlake/model"
 	"time"
 
@@ -160,6 +164,182 @@ func (controller UserController) UpdateProfile(context *gin.Context) {
 	context.JSON(http.StatusOK, response)
 }
 
+// RequestEmailChange sends a confirmation link to the new address and a security notice to the current one
+func (controller UserController) RequestEmailChange(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	var request user.ChangeEmailRequest
+	if err := context.ShouldBindJSON(&request); err != nil {
+		utils.ReportBadRequest(context, "Invalid request data")
+		return
+	}
+
+	userDao := dao.NewUserDao()
+	existingUser, err := userDao.GetByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "User not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	// Re-authenticate before changing the address used for account recovery
+	if existingUser.PasswordHash == nil || !utils.CheckPasswordHash(request.Password, *existingUser.PasswordHash) {
+		utils.ReportUnauthorized(context, "Invalid password")
+		return
+	}
+
+	newEmail := strings.TrimSpace(request.NewEmail)
+	if strings.EqualFold(newEmail, existingUser.Email) {
+		utils.ReportBadRequest(context, "New email must be different from the current email")
+		return
+	}
+
+	emailExists, err := userDao.EmailExists(newEmail)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+	if emailExists {
+		utils.ReportBadRequest(context, "Email already exists")
+		return
+	}
+
+	if err := utils.SendEmailChangeConfirmation(newEmail, existingUser.Username, existingUser.ID); err != nil {
+		utils.ReportInternalServerError(context, "Failed to send confirmation email")
+		return
+	}
+
+	if err := utils.SendEmailChangeNotice(existingUser.Email, existingUser.Username, newEmail); err != nil {
+		// The change still requires confirmation from the new address, so do not fail the request
+		log.Printf("Failed to send email change notice: %v", err)
+	}
+
+	response := inout.BaseResponse{
+		ErrorCode:        0,
+		ErrorDescription: "A confirmation link has been sent to the new email address",
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetSessions returns the current user's active sessions
+func (controller UserController) GetSessions(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	sessionDao := dao.NewUserSessionDao()
+	sessions, err := sessionDao.GetActiveByUserID(userID)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	response := user.SessionsOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		Data: user.FromSessionModelList(sessions, utils.ExtractSessionID(context)),
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// RevokeSession signs the current user out of one of their sessions
+func (controller UserController) RevokeSession(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	sessionIDParam := context.Param("id")
+	sessionID, err := uuid.Parse(sessionIDParam)
+	if err != nil {
+		utils.ReportBadRequest(context, "Invalid session ID format")
+		return
+	}
+
+	sessionDao := dao.NewUserSessionDao()
+	session, err := sessionDao.GetByID(sessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			utils.ReportNotFound(context, "Session not found")
+		} else {
+			utils.ReportInternalServerError(context, "Database error")
+		}
+		return
+	}
+
+	// Do not reveal other users' sessions
+	if session.UserID != userID {
+		utils.ReportNotFound(context, "Session not found")
+		return
+	}
+
+	if err := sessionDao.Revoke(sessionID); err != nil {
+		utils.ReportInternalServerError(context, "Failed to revoke session")
+		return
+	}
+
+	response := inout.BaseResponse{
+		ErrorCode:        0,
+		ErrorDescription: "Session revoked successfully",
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
+// GetLoginHistory returns the current user's recent sign-in attempts
+func (controller UserController) GetLoginHistory(context *gin.Context) {
+	userID, err := utils.ExtractUserID(context)
+	if err != nil {
+		utils.ReportUnauthorized(context, "Authentication required")
+		return
+	}
+
+	pageStr := context.DefaultQuery("page", "0")
+	page, err := strconv.Atoi(pageStr)
+	if err != nil || page < 0 {
+		page = 0
+	}
+
+	eventDao := dao.NewLoginEventDao()
+	events, total, err := eventDao.GetByUserID(userID, page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Database error")
+		return
+	}
+
+	totalPages := int(math.Ceil(float64(total) / float64(eventDao.Limit)))
+
+	response := user.LoginHistoryOut{
+		BaseResponse: inout.BaseResponse{
+			ErrorCode:        0,
+			ErrorDescription: "Success",
+		},
+		List: user.FromLoginEventModelList(events),
+		Meta: inout.PaginationMeta{
+			Page:       page,
+			Limit:      eventDao.Limit,
+			Total:      total,
+			TotalPages: totalPages,
+		},
+	}
+
+	context.JSON(http.StatusOK, response)
+}
+
 // DeleteAccount deletes current user's account
 func (controller UserController) DeleteAccount(context *gin.Context) {
 	userID, err := utils.ExtractUserID(context)
@@ -236,16 +416,24 @@ func (controller UserController) GetNotifications(context *gin.Context) {
 		return
 	}
 
-	// TODO: Implement notifications retrieval
-	// For now, use userID in a placeholder way to avoid compiler error
-	_ = userID
+	page, err := strconv.Atoi(context.DefaultQuery("page", "0"))
+	if err != nil || page < 0 {
+		utils.ReportBadRequest(context, "Invalid page parameter")
+		return
+	}
+
+	notifications, _, err := dao.NewNotificationDao().GetByUserID(userID, page)
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to get notifications")
+		return
+	}
 
 	response := user.NotificationsOut{
 		BaseResponse: inout.BaseResponse{
 			ErrorCode:        0,
 			ErrorDescription: "Success",
 		},
-		Data: []user.Notification{}, // TODO: Get actual notifications
+		Data: user.FromNotificationModelList(notifications),
 	}
 
 	context.JSON(http.StatusOK, response)
@@ -266,10 +454,15 @@ func (controller UserController) MarkNotificationRead(context *gin.Context) {
 		return
 	}
 
-	// TODO: Implement notification mark as read functionality
-	// For now, just return success
-	_ = userID
-	_ = notificationID
+	found, err := dao.NewNotificationDao().MarkRead(notificationID, userID, time.Now())
+	if err != nil {
+		utils.ReportInternalServerError(context, "Failed to mark notification as read")
+		return
+	}
+	if !found {
+		utils.ReportNotFound(context, "Notification not found")
+		return
+	}
 
 	response := inout.BaseResponse{
 		ErrorCode:        0,
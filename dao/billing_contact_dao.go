@@ -0,0 +1,70 @@
+package dao
+
+import (
+	"testlake/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BillingContactDao struct {
+	Limit int
+	tx    *gorm.DB
+}
+
+func NewBillingContactDao() *BillingContactDao {
+	return &BillingContactDao{Limit: 50}
+}
+
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *BillingContactDao) WithTx(tx *gorm.DB) *BillingContactDao {
+	return &BillingContactDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *BillingContactDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
+func (dao *BillingContactDao) Create(contact *model.BillingContact) error {
+	return dao.db().Create(contact).Error
+}
+
+func (dao *BillingContactDao) GetByID(id uuid.UUID) (*model.BillingContact, error) {
+	var contact model.BillingContact
+	err := dao.db().First(&contact, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// GetByOrganizationID returns the billing contacts of an organization in the
+// order they were added.
+func (dao *BillingContactDao) GetByOrganizationID(organizationID uuid.UUID) ([]model.BillingContact, error) {
+	var contacts []model.BillingContact
+	err := dao.db().Where("organization_id = ?", organizationID).
+		Order("created_at ASC").
+		Limit(dao.Limit).
+		Find(&contacts).Error
+	if err != nil {
+		return nil, err
+	}
+	return contacts, nil
+}
+
+// ExistsByEmail reports whether the organization has a billing contact with
+// the email, which is stored lower case.
+func (dao *BillingContactDao) ExistsByEmail(organizationID uuid.UUID, email string) (bool, error) {
+	var count int64
+	err := dao.db().Model(&model.BillingContact{}).
+		Where("organization_id = ? AND email = ?", organizationID, email).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (dao *BillingContactDao) Delete(id uuid.UUID) error {
+	return dao.db().Delete(&model.BillingContact{}, "id = ?", id).Error
+}
@@ -1,26 +1,81 @@
 package dao
 
 import (
+	"encoding/json"
 	"testlake/model"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type BillingEventDao struct {
 	Limit int
+	tx    *gorm.DB
+	actor model.BillingActor
+}
+
+// BillingEventFilter narrows down the billing event log. Fields left zero
+// match every event.
+type BillingEventFilter struct {
+	OrganizationID *uuid.UUID
+	EventTypes     []model.BillingEventType
+	From           *time.Time // inclusive
+	To             *time.Time // exclusive
+	ActorType      model.BillingActorType
+	ActorID        *uuid.UUID
 }
 
 func NewBillingEventDao() *BillingEventDao {
 	return &BillingEventDao{Limit: 50}
 }
 
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *BillingEventDao) WithTx(tx *gorm.DB) *BillingEventDao {
+	return &BillingEventDao{Limit: dao.Limit, tx: tx, actor: dao.actor}
+}
+
+// WithActor returns a copy of the dao that records events as caused by actor.
+// Events are recorded as caused by the system otherwise.
+func (dao *BillingEventDao) WithActor(actor model.BillingActor) *BillingEventDao {
+	return &BillingEventDao{Limit: dao.Limit, tx: dao.tx, actor: actor}
+}
+
+func (dao *BillingEventDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
 func (dao *BillingEventDao) Create(event *model.BillingEvent) error {
-	return Database.Create(event).Error
+	return dao.db().Create(event).Error
+}
+
+// Record stores an event raised by the application itself, with data encoded as JSON.
+func (dao *BillingEventDao) Record(organizationID uuid.UUID, eventType model.BillingEventType, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	eventData := string(payload)
+	actor := dao.actor
+	if actor.Type == "" {
+		actor = model.SystemActor
+	}
+	return dao.Create(&model.BillingEvent{
+		OrganizationID: organizationID,
+		EventType:      eventType,
+		EventData:      &eventData,
+		ProcessedAt:    time.Now(),
+		ActorType:      actor.Type,
+		ActorID:        actor.ID,
+	})
 }
 
 func (dao *BillingEventDao) GetByID(id uuid.UUID) (*model.BillingEvent, error) {
 	var event model.BillingEvent
-	err := Database.First(&event, "id = ?", id).Error
+	err := dao.db().First(&event, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +86,7 @@ func (dao *BillingEventDao) GetByOrganizationID(organizationID uuid.UUID, page i
 	var events []model.BillingEvent
 	var total int64
 
-	err := Database.Model(&model.BillingEvent{}).
+	err := dao.db().Model(&model.BillingEvent{}).
 		Where("organization_id = ?", organizationID).
 		Count(&total).Error
 	if err != nil {
@@ -39,7 +94,7 @@ func (dao *BillingEventDao) GetByOrganizationID(organizationID uuid.UUID, page i
 	}
 
 	offset := page * dao.Limit
-	err = Database.Where("organization_id = ?", organizationID).
+	err = dao.db().Where("organization_id = ?", organizationID).
 		Order("created_at DESC").
 		Offset(offset).Limit(dao.Limit).
 		Find(&events).Error
@@ -52,7 +107,7 @@ func (dao *BillingEventDao) GetByOrganizationID(organizationID uuid.UUID, page i
 
 func (dao *BillingEventDao) GetByPayPalEventID(paypalEventID string) (*model.BillingEvent, error) {
 	var event model.BillingEvent
-	err := Database.First(&event, "paypal_event_id = ?", paypalEventID).Error
+	err := dao.db().First(&event, "pay_pal_event_id = ?", paypalEventID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -63,7 +118,7 @@ func (dao *BillingEventDao) GetByEventType(organizationID uuid.UUID, eventType m
 	var events []model.BillingEvent
 	var total int64
 
-	err := Database.Model(&model.BillingEvent{}).
+	err := dao.db().Model(&model.BillingEvent{}).
 		Where("organization_id = ? AND event_type = ?", organizationID, eventType).
 		Count(&total).Error
 	if err != nil {
@@ -71,7 +126,49 @@ func (dao *BillingEventDao) GetByEventType(organizationID uuid.UUID, eventType m
 	}
 
 	offset := page * dao.Limit
-	err = Database.Where("organization_id = ? AND event_type = ?", organizationID, eventType).
+	err = dao.db().Where("organization_id = ? AND event_type = ?", organizationID, eventType).
+		Order("created_at DESC").
+		Offset(offset).Limit(dao.Limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
+
+// Search returns a page of the events matching the filter, newest first, with
+// their organization.
+func (dao *BillingEventDao) Search(filter BillingEventFilter, page int) ([]model.BillingEvent, int64, error) {
+	var events []model.BillingEvent
+	var total int64
+
+	query := dao.db().Model(&model.BillingEvent{})
+	if filter.OrganizationID != nil {
+		query = query.Where("organization_id = ?", *filter.OrganizationID)
+	}
+	if len(filter.EventTypes) > 0 {
+		query = query.Where("event_type IN ?", filter.EventTypes)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if filter.ActorType != "" {
+		query = query.Where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err := query.Preload("Organization").
 		Order("created_at DESC").
 		Offset(offset).Limit(dao.Limit).
 		Find(&events).Error
@@ -83,24 +180,24 @@ func (dao *BillingEventDao) GetByEventType(organizationID uuid.UUID, eventType m
 }
 
 func (dao *BillingEventDao) Update(event *model.BillingEvent) error {
-	return Database.Save(event).Error
+	return dao.db().Save(event).Error
 }
 
 func (dao *BillingEventDao) Delete(id uuid.UUID) error {
-	return Database.Delete(&model.BillingEvent{}, "id = ?", id).Error
+	return dao.db().Delete(&model.BillingEvent{}, "id = ?", id).Error
 }
 
 func (dao *BillingEventDao) GetAll(page int) ([]model.BillingEvent, int64, error) {
 	var events []model.BillingEvent
 	var total int64
 
-	err := Database.Model(&model.BillingEvent{}).Count(&total).Error
+	err := dao.db().Model(&model.BillingEvent{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	offset := page * dao.Limit
-	err = Database.Preload("Organization").
+	err = dao.db().Preload("Organization").
 		Order("created_at DESC").
 		Offset(offset).Limit(dao.Limit).
 		Find(&events).Error
@@ -113,8 +210,8 @@ func (dao *BillingEventDao) GetAll(page int) ([]model.BillingEvent, int64, error
 
 func (dao *BillingEventDao) EventExists(paypalEventID string) (bool, error) {
 	var count int64
-	err := Database.Model(&model.BillingEvent{}).
-		Where("paypal_event_id = ?", paypalEventID).
+	err := dao.db().Model(&model.BillingEvent{}).
+		Where("pay_pal_event_id = ?", paypalEventID).
 		Count(&count).Error
 	return count > 0, err
 }
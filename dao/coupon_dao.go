@@ -0,0 +1,190 @@
+package dao
+
+import (
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CouponDao struct {
+	Limit int
+	tx    *gorm.DB
+}
+
+func NewCouponDao() *CouponDao {
+	return &CouponDao{Limit: 50}
+}
+
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *CouponDao) WithTx(tx *gorm.DB) *CouponDao {
+	return &CouponDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *CouponDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
+func (dao *CouponDao) Create(coupon *model.Coupon) error {
+	return dao.db().Create(coupon).Error
+}
+
+func (dao *CouponDao) GetByID(id uuid.UUID) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := dao.db().First(&coupon, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+// GetByCodeForUpdate loads the coupon with the code and locks its row until the
+// transaction ends, so concurrent redemptions cannot exceed its limit.
+func (dao *CouponDao) GetByCodeForUpdate(code string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := dao.db().Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&coupon, "code = ?", model.NormalizeCouponCode(code)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (dao *CouponDao) GetByCode(code string) (*model.Coupon, error) {
+	var coupon model.Coupon
+	err := dao.db().First(&coupon, "code = ?", model.NormalizeCouponCode(code)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &coupon, nil
+}
+
+func (dao *CouponDao) GetAll(page int) ([]model.Coupon, int64, error) {
+	var coupons []model.Coupon
+	var total int64
+
+	if err := dao.db().Model(&model.Coupon{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err := dao.db().Order("created_at DESC").
+		Offset(offset).Limit(dao.Limit).
+		Find(&coupons).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return coupons, total, nil
+}
+
+func (dao *CouponDao) Update(coupon *model.Coupon) error {
+	return dao.db().Save(coupon).Error
+}
+
+func (dao *CouponDao) CreateRedemption(redemption *model.CouponRedemption) error {
+	return dao.db().Omit(clause.Associations).Create(redemption).Error
+}
+
+// HasRedeemed reports whether the organization already redeemed the coupon.
+func (dao *CouponDao) HasRedeemed(couponID, organizationID uuid.UUID) (bool, error) {
+	var count int64
+	err := dao.db().Model(&model.CouponRedemption{}).
+		Where("coupon_id = ? AND organization_id = ?", couponID, organizationID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetActiveRedemption returns the redemption still discounting the
+// subscription, with its coupon.
+func (dao *CouponDao) GetActiveRedemption(subscriptionID uuid.UUID) (*model.CouponRedemption, error) {
+	var redemption model.CouponRedemption
+	err := dao.db().Preload("Coupon").
+		Where("subscription_id = ? AND ended_at IS NULL", subscriptionID).
+		Order("created_at DESC").
+		First(&redemption).Error
+	if err != nil {
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+func (dao *CouponDao) GetRedemptionsByCouponID(couponID uuid.UUID, page int) ([]model.CouponRedemption, int64, error) {
+	var redemptions []model.CouponRedemption
+	var total int64
+
+	err := dao.db().Model(&model.CouponRedemption{}).
+		Where("coupon_id = ?", couponID).
+		Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err = dao.db().Preload("Coupon").
+		Preload("Organization").
+		Where("coupon_id = ?", couponID).
+		Order("created_at DESC").
+		Offset(offset).Limit(dao.Limit).
+		Find(&redemptions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return redemptions, total, nil
+}
+
+func (dao *CouponDao) GetRedemptionsByOrganizationID(organizationID uuid.UUID) ([]model.CouponRedemption, error) {
+	var redemptions []model.CouponRedemption
+	err := dao.db().Preload("Coupon").
+		Where("organization_id = ?", organizationID).
+		Order("created_at DESC").
+		Find(&redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// GetGatewayBilledRedemptions returns the redemptions of active provider
+// subscriptions whose discount is running or still priced in at the provider,
+// with their coupon and subscription plan.
+func (dao *CouponDao) GetGatewayBilledRedemptions() ([]model.CouponRedemption, error) {
+	var redemptions []model.CouponRedemption
+	err := dao.db().Preload("Coupon").
+		Preload("Subscription.Plan.Prices").
+		Joins("JOIN subscriptions ON subscriptions.id = coupon_redemptions.subscription_id").
+		Where("subscriptions.status = ? AND subscriptions.pay_pal_subscription_id IS NOT NULL", model.SubscriptionStatusActive).
+		Where("coupon_redemptions.ended_at IS NULL OR coupon_redemptions.gateway_price IS NOT NULL").
+		Order("coupon_redemptions.created_at ASC").
+		Find(&redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+func (dao *CouponDao) UpdateRedemption(redemption *model.CouponRedemption) error {
+	return dao.db().Omit(clause.Associations).Save(redemption).Error
+}
+
+// SetGatewayPrice records the discounted price the provider charges the
+// subscription.
+func (dao *CouponDao) SetGatewayPrice(subscriptionID uuid.UUID, price int64) error {
+	return dao.db().Model(&model.CouponRedemption{}).
+		Where("subscription_id = ? AND ended_at IS NULL", subscriptionID).
+		Updates(map[string]interface{}{"gateway_price": price, "updated_at": time.Now()}).Error
+}
+
+// ClearGatewayPrice records that the provider charges the plan price again,
+// e.g. after the subscription moved to another provider plan.
+func (dao *CouponDao) ClearGatewayPrice(subscriptionID uuid.UUID) error {
+	return dao.db().Model(&model.CouponRedemption{}).
+		Where("subscription_id = ? AND gateway_price IS NOT NULL", subscriptionID).
+		Updates(map[string]interface{}{"gateway_price": nil, "updated_at": time.Now()}).Error
+}
@@ -0,0 +1,152 @@
+package dao
+
+import (
+	"fmt"
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CreditNoteDao struct {
+	Limit int
+	tx    *gorm.DB
+}
+
+func NewCreditNoteDao() *CreditNoteDao {
+	return &CreditNoteDao{Limit: 50}
+}
+
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *CreditNoteDao) WithTx(tx *gorm.DB) *CreditNoteDao {
+	return &CreditNoteDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *CreditNoteDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
+func (dao *CreditNoteDao) Create(creditNote *model.CreditNote) error {
+	return dao.db().Create(creditNote).Error
+}
+
+func (dao *CreditNoteDao) GetByID(id uuid.UUID) (*model.CreditNote, error) {
+	var creditNote model.CreditNote
+	err := dao.db().Preload("Organization").
+		Preload("Invoice").
+		Preload("Payment").
+		First(&creditNote, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &creditNote, nil
+}
+
+// GetForUpdate loads the credit note and locks its row until the transaction ends.
+func (dao *CreditNoteDao) GetForUpdate(id uuid.UUID) (*model.CreditNote, error) {
+	var creditNote model.CreditNote
+	err := dao.db().Clauses(clause.Locking{Strength: "UPDATE"}).First(&creditNote, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &creditNote, nil
+}
+
+func (dao *CreditNoteDao) GetByOrganizationID(organizationID uuid.UUID, page int) ([]model.CreditNote, int64, error) {
+	var creditNotes []model.CreditNote
+	var total int64
+
+	err := dao.db().Model(&model.CreditNote{}).
+		Where("organization_id = ?", organizationID).
+		Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err = dao.db().Omit("pdf_content").
+		Preload("Invoice").
+		Where("organization_id = ?", organizationID).
+		Order("created_at DESC").
+		Offset(offset).Limit(dao.Limit).
+		Find(&creditNotes).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return creditNotes, total, nil
+}
+
+func (dao *CreditNoteDao) GetByInvoiceID(invoiceID uuid.UUID) ([]model.CreditNote, error) {
+	var creditNotes []model.CreditNote
+	err := dao.db().Where("invoice_id = ?", invoiceID).
+		Order("created_at ASC").
+		Find(&creditNotes).Error
+	if err != nil {
+		return nil, err
+	}
+	return creditNotes, nil
+}
+
+// GetPendingBefore returns the credit notes whose refund was requested before
+// the given time and never confirmed, oldest first.
+func (dao *CreditNoteDao) GetPendingBefore(before time.Time) ([]model.CreditNote, error) {
+	var creditNotes []model.CreditNote
+	err := dao.db().Where("status = ? AND created_at < ?", model.CreditNoteStatusPending, before).
+		Order("created_at ASC").
+		Limit(dao.Limit).
+		Find(&creditNotes).Error
+	if err != nil {
+		return nil, err
+	}
+	return creditNotes, nil
+}
+
+// GetIssuedBetween returns the credit notes issued between from and until, with
+// the invoice they refund, oldest first.
+func (dao *CreditNoteDao) GetIssuedBetween(from, until time.Time) ([]model.CreditNote, error) {
+	var creditNotes []model.CreditNote
+	err := dao.db().Preload("Invoice").
+		Where("status = ? AND issued_at >= ? AND issued_at < ?", model.CreditNoteStatusIssued, from, until).
+		Order("issued_at ASC").
+		Find(&creditNotes).Error
+	if err != nil {
+		return nil, err
+	}
+	return creditNotes, nil
+}
+
+// SaveDocument stores the rendered PDF of the credit note, unless it already
+// has one: the first PDF stored is final.
+func (dao *CreditNoteDao) SaveDocument(id uuid.UUID, content []byte, checksum string) error {
+	return dao.db().Model(&model.CreditNote{}).
+		Where("id = ? AND pdf_checksum IS NULL", id).
+		Updates(map[string]interface{}{
+			"pdf_content":  content,
+			"pdf_checksum": checksum,
+		}).Error
+}
+
+func (dao *CreditNoteDao) Update(creditNote *model.CreditNote) error {
+	return dao.db().Save(creditNote).Error
+}
+
+// GenerateCreditNoteNumber reserves the next number of the year, CN-YYYY-NNNNNN,
+// within the transaction creating the credit note, like GenerateInvoiceNumber.
+func (dao *CreditNoteDao) GenerateCreditNoteNumber() (string, error) {
+	year := time.Now().Year()
+	var number int64
+	err := dao.db().Raw(`INSERT INTO credit_note_sequences (year, last_number) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = credit_note_sequences.last_number + 1
+		RETURNING last_number`, year).Scan(&number).Error
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("CN-%d-%06d", year, number), nil
+}
@@ -1,12 +1,25 @@
 package dao
 
 import (
+	"errors"
 	"testlake/model"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrEmailAlreadyInUse = errors.New("email already in use")
+	// ErrTokenAlreadyUsed is returned when a concurrent request used the token first
+	ErrTokenAlreadyUsed = errors.New("token already used")
+)
+
+// uniqueViolation is the PostgreSQL error code of a duplicate key
+const uniqueViolation = "23505"
+
 type EmailVerificationDao struct{}
 
 func NewEmailVerificationDao() *EmailVerificationDao {
@@ -17,9 +30,9 @@ func (dao *EmailVerificationDao) Create(token *model.EmailVerificationToken) err
 	return Database.Create(token).Error
 }
 
-func (dao *EmailVerificationDao) GetByToken(tokenStr string) (*model.EmailVerificationToken, error) {
+func (dao *EmailVerificationDao) GetByToken(tokenStr string, purpose model.EmailTokenPurpose) (*model.EmailVerificationToken, error) {
 	var token model.EmailVerificationToken
-	err := Database.Where("token = ? AND deleted_at IS NULL", tokenStr).
+	err := Database.Where("token = ? AND purpose = ? AND deleted_at IS NULL", tokenStr, purpose).
 		Preload("User").
 		First(&token).Error
 	return &token, err
@@ -39,8 +52,8 @@ func (dao *EmailVerificationDao) DeleteExpiredTokens() error {
 		Delete(&model.EmailVerificationToken{}).Error
 }
 
-func (dao *EmailVerificationDao) DeleteTokensForUser(userID uuid.UUID) error {
-	return Database.Where("user_id = ?", userID).
+func (dao *EmailVerificationDao) DeleteTokensForUser(userID uuid.UUID, purpose model.EmailTokenPurpose) error {
+	return Database.Where("user_id = ? AND purpose = ?", userID, purpose).
 		Delete(&model.EmailVerificationToken{}).Error
 }
 
@@ -50,3 +63,55 @@ func (dao *EmailVerificationDao) GetActiveTokensForUser(userID uuid.UUID) ([]mod
 		userID, false, time.Now()).Find(&tokens).Error
 	return tokens, err
 }
+
+// ApplyEmailChange moves the user to the new address of a change_email token and
+// marks the token used in one transaction. The token is locked and re-checked
+// so that it is applied once, and uniqueness is re-checked because the address
+// may have been taken since the request; the unique index settles a race.
+func (dao *EmailVerificationDao) ApplyEmailChange(token *model.EmailVerificationToken) error {
+	if token.NewEmail == nil {
+		return errors.New("token has no new email")
+	}
+
+	return Database.Transaction(func(tx *gorm.DB) error {
+		var locked model.EmailVerificationToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&locked, "id = ?", token.ID).Error
+		if err != nil {
+			return err
+		}
+		if locked.IsUsed {
+			return ErrTokenAlreadyUsed
+		}
+
+		var count int64
+		err = tx.Model(&model.User{}).
+			Where("email = ? AND id <> ?", *token.NewEmail, token.UserID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailAlreadyInUse
+		}
+
+		err = tx.Model(&model.User{}).Where("id = ?", token.UserID).Updates(map[string]interface{}{
+			"email":             *token.NewEmail,
+			"is_email_verified": true,
+		}).Error
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return ErrEmailAlreadyInUse
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&model.EmailVerificationToken{}).
+			Where("id = ?", token.ID).
+			Updates(map[string]interface{}{
+				"is_used":    true,
+				"updated_at": time.Now(),
+			}).Error
+	})
+}
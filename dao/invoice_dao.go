@@ -6,42 +6,56 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type InvoiceDao struct {
 	Limit int
+	tx    *gorm.DB
 }
 
 func NewInvoiceDao() *InvoiceDao {
 	return &InvoiceDao{Limit: 50}
 }
 
-func (dao *InvoiceDao) Create(invoice *model.Invoice) error {
-	return Database.Create(invoice).Error
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *InvoiceDao) WithTx(tx *gorm.DB) *InvoiceDao {
+	return &InvoiceDao{Limit: dao.Limit, tx: tx}
 }
 
-func (dao *InvoiceDao) CreateWithLineItems(invoice *model.Invoice, lineItems []model.InvoiceLineItem) error {
-	tx := Database.Begin()
-
-	if err := tx.Create(invoice).Error; err != nil {
-		tx.Rollback()
-		return err
+func (dao *InvoiceDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
 	}
+	return Database
+}
 
-	for i := range lineItems {
-		lineItems[i].InvoiceID = invoice.ID
-		if err := tx.Create(&lineItems[i]).Error; err != nil {
-			tx.Rollback()
+func (dao *InvoiceDao) Create(invoice *model.Invoice) error {
+	return dao.db().Create(invoice).Error
+}
+
+// CreateWithLineItems creates the invoice and its line items atomically. Inside
+// a WithTx dao it runs as a nested transaction (savepoint).
+func (dao *InvoiceDao) CreateWithLineItems(invoice *model.Invoice, lineItems []model.InvoiceLineItem) error {
+	return dao.db().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(invoice).Error; err != nil {
 			return err
 		}
-	}
-
-	return tx.Commit().Error
+		for i := range lineItems {
+			lineItems[i].InvoiceID = invoice.ID
+			if err := tx.Create(&lineItems[i]).Error; err != nil {
+				return err
+			}
+		}
+		invoice.LineItems = lineItems
+		return nil
+	})
 }
 
 func (dao *InvoiceDao) GetByID(id uuid.UUID) (*model.Invoice, error) {
 	var invoice model.Invoice
-	err := Database.Preload("LineItems").
+	err := dao.db().Preload("LineItems").
 		Preload("Organization").
 		Preload("Subscription").
 		First(&invoice, "id = ?", id).Error
@@ -51,9 +65,19 @@ func (dao *InvoiceDao) GetByID(id uuid.UUID) (*model.Invoice, error) {
 	return &invoice, nil
 }
 
+// GetForUpdate loads the invoice and locks its row until the transaction ends.
+func (dao *InvoiceDao) GetForUpdate(id uuid.UUID) (*model.Invoice, error) {
+	var invoice model.Invoice
+	err := dao.db().Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
 func (dao *InvoiceDao) GetByInvoiceNumber(invoiceNumber string) (*model.Invoice, error) {
 	var invoice model.Invoice
-	err := Database.Preload("LineItems").
+	err := dao.db().Preload("LineItems").
 		Preload("Organization").
 		Preload("Subscription").
 		First(&invoice, "invoice_number = ?", invoiceNumber).Error
@@ -63,11 +87,33 @@ func (dao *InvoiceDao) GetByInvoiceNumber(invoiceNumber string) (*model.Invoice,
 	return &invoice, nil
 }
 
+func (dao *InvoiceDao) GetByPayPalInvoiceID(paypalInvoiceID string) (*model.Invoice, error) {
+	var invoice model.Invoice
+	err := dao.db().First(&invoice, "pay_pal_invoice_id = ?", paypalInvoiceID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetOpenBySubscriptionID returns the draft and sent invoices of a subscription, oldest first.
+func (dao *InvoiceDao) GetOpenBySubscriptionID(subscriptionID uuid.UUID) ([]model.Invoice, error) {
+	var invoices []model.Invoice
+	err := dao.db().
+		Where("subscription_id = ? AND status IN ?", subscriptionID, []model.InvoiceStatus{model.InvoiceStatusDraft, model.InvoiceStatusSent}).
+		Order("created_at ASC").
+		Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
 func (dao *InvoiceDao) GetByOrganizationID(organizationID uuid.UUID, page int) ([]model.Invoice, int64, error) {
 	var invoices []model.Invoice
 	var total int64
 
-	err := Database.Model(&model.Invoice{}).
+	err := dao.db().Model(&model.Invoice{}).
 		Where("organization_id = ?", organizationID).
 		Count(&total).Error
 	if err != nil {
@@ -75,7 +121,7 @@ func (dao *InvoiceDao) GetByOrganizationID(organizationID uuid.UUID, page int) (
 	}
 
 	offset := page * dao.Limit
-	err = Database.Preload("LineItems").
+	err = dao.db().Preload("LineItems").
 		Where("organization_id = ?", organizationID).
 		Order("created_at DESC").
 		Offset(offset).Limit(dao.Limit).
@@ -89,7 +135,7 @@ func (dao *InvoiceDao) GetByOrganizationID(organizationID uuid.UUID, page int) (
 
 func (dao *InvoiceDao) GetUnpaidByOrganizationID(organizationID uuid.UUID) ([]model.Invoice, error) {
 	var invoices []model.Invoice
-	err := Database.Preload("LineItems").
+	err := dao.db().Preload("LineItems").
 		Where("organization_id = ? AND status IN ?", organizationID, []model.InvoiceStatus{
 			model.InvoiceStatusDraft,
 			model.InvoiceStatusSent,
@@ -102,8 +148,65 @@ func (dao *InvoiceDao) GetUnpaidByOrganizationID(organizationID uuid.UUID) ([]mo
 	return invoices, nil
 }
 
+// GetPaidBetween returns the paid invoices, refunded or not, that earn revenue
+// between from and until: the ones whose billing period overlaps it and the
+// ones without a period that were paid in it.
+func (dao *InvoiceDao) GetPaidBetween(from, until time.Time) ([]model.Invoice, error) {
+	var invoices []model.Invoice
+	err := dao.db().
+		Where("paid_at IS NOT NULL AND status IN ?", []model.InvoiceStatus{
+			model.InvoiceStatusPaid,
+			model.InvoiceStatusPartiallyRefunded,
+			model.InvoiceStatusRefunded,
+		}).
+		Where("(billing_period_start IS NOT NULL AND billing_period_end > ? AND billing_period_start < ?) OR (billing_period_start IS NULL AND paid_at >= ? AND paid_at < ?)",
+			from, until, from, until).
+		Order("paid_at ASC").
+		Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
 func (dao *InvoiceDao) Update(invoice *model.Invoice) error {
-	return Database.Save(invoice).Error
+	return dao.db().Save(invoice).Error
+}
+
+func (dao *InvoiceDao) GetDocument(invoiceID uuid.UUID) (*model.InvoiceDocument, error) {
+	var document model.InvoiceDocument
+	err := dao.db().First(&document, "invoice_id = ?", invoiceID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// SaveDocument stores the rendered document of an invoice, replacing the
+// previous one unless that one is final.
+func (dao *InvoiceDao) SaveDocument(document *model.InvoiceDocument) error {
+	return dao.db().Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "invoice_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"filename", "content", "checksum", "final", "invoice_updated_at", "updated_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: clause.Column{Table: "invoice_documents", Name: "final"}, Value: false}}},
+	}).Create(document).Error
+}
+
+// RecordDelivery logs an attempt at emailing an invoice.
+func (dao *InvoiceDao) RecordDelivery(delivery *model.InvoiceDelivery) error {
+	return dao.db().Create(delivery).Error
+}
+
+// GetDeliveries returns every attempt at emailing the invoice, newest first.
+func (dao *InvoiceDao) GetDeliveries(invoiceID uuid.UUID) ([]model.InvoiceDelivery, error) {
+	var deliveries []model.InvoiceDelivery
+	err := dao.db().Where("invoice_id = ?", invoiceID).
+		Order("attempted_at DESC").
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
 }
 
 func (dao *InvoiceDao) UpdateStatus(id uuid.UUID, status model.InvoiceStatus) error {
@@ -113,23 +216,27 @@ func (dao *InvoiceDao) UpdateStatus(id uuid.UUID, status model.InvoiceStatus) er
 	if status == model.InvoiceStatusPaid {
 		updates["paid_at"] = "NOW()"
 	}
-	return Database.Model(&model.Invoice{}).Where("id = ?", id).Updates(updates).Error
+	return dao.db().Model(&model.Invoice{}).Where("id = ?", id).Updates(updates).Error
 }
 
 func (dao *InvoiceDao) UpdatePayPalInvoiceID(id uuid.UUID, paypalInvoiceID string) error {
-	return Database.Model(&model.Invoice{}).
+	return dao.db().Model(&model.Invoice{}).
 		Where("id = ?", id).
-		Update("paypal_invoice_id", paypalInvoiceID).Error
+		Update("pay_pal_invoice_id", paypalInvoiceID).Error
 }
 
 func (dao *InvoiceDao) Delete(id uuid.UUID) error {
-	tx := Database.Begin()
+	tx := dao.db().Begin()
 
-	// Delete line items first
+	// Delete line items and the rendered document first
 	if err := tx.Delete(&model.InvoiceLineItem{}, "invoice_id = ?", id).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
+	if err := tx.Delete(&model.InvoiceDocument{}, "invoice_id = ?", id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 
 	// Delete invoice
 	if err := tx.Delete(&model.Invoice{}, "id = ?", id).Error; err != nil {
@@ -144,13 +251,13 @@ func (dao *InvoiceDao) GetAll(page int) ([]model.Invoice, int64, error) {
 	var invoices []model.Invoice
 	var total int64
 
-	err := Database.Model(&model.Invoice{}).Count(&total).Error
+	err := dao.db().Model(&model.Invoice{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	offset := page * dao.Limit
-	err = Database.Preload("LineItems").
+	err = dao.db().Preload("LineItems").
 		Preload("Organization").
 		Preload("Subscription").
 		Order("created_at DESC").
@@ -163,13 +270,69 @@ func (dao *InvoiceDao) GetAll(page int) ([]model.Invoice, int64, error) {
 	return invoices, total, nil
 }
 
+// GenerateInvoiceNumber reserves the next number of the year, INV-YYYY-NNNNNN.
+// Call it inside the transaction creating the invoice: the reservation is
+// rolled back with it, which keeps the numbers free of gaps.
 func (dao *InvoiceDao) GenerateInvoiceNumber() (string, error) {
-	var count int64
-	err := Database.Model(&model.Invoice{}).Count(&count).Error
+	year := time.Now().Year()
+	var number int64
+	err := dao.db().Raw(`INSERT INTO invoice_sequences (year, last_number) VALUES (?, 1)
+		ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number`, year).Scan(&number).Error
 	if err != nil {
 		return "", err
 	}
 
-	// Generate invoice number in format INV-YYYY-NNNNNN
-	return fmt.Sprintf("INV-%d-%06d", time.Now().Year(), count+1), nil
+	return fmt.Sprintf("INV-%d-%06d", year, number), nil
+}
+
+// GetUncharged returns open invoices of subscriptions billed by the application,
+// rather than by a provider subscription, and proration invoices of any
+// subscription, that no payment was attempted for yet and whose organization
+// has a default payment method that can be charged.
+func (dao *InvoiceDao) GetUncharged() ([]model.Invoice, error) {
+	var invoices []model.Invoice
+	err := dao.db().
+		Joins("JOIN subscriptions ON subscriptions.id = invoices.subscription_id").
+		Where("invoices.status = ? AND (subscriptions.pay_pal_subscription_id IS NULL OR invoices.is_proration = ?)", model.InvoiceStatusSent, true).
+		Where("NOT EXISTS (SELECT 1 FROM payments WHERE payments.invoice_id = invoices.id)").
+		Where(`EXISTS (SELECT 1 FROM payment_methods WHERE payment_methods.organization_id = invoices.organization_id
+			AND payment_methods.is_default = ? AND payment_methods.is_active = ? AND payment_methods.status = ?
+			AND payment_methods.pay_pal_vault_id IS NOT NULL)`, true, true, model.PaymentMethodStatusVerified).
+		Order("invoices.created_at ASC").
+		Limit(dao.Limit).
+		Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// GetOverdueOutsideDunning returns open invoices due before the given time
+// that no dunning was started for, e.g. because there was nothing to charge.
+func (dao *InvoiceDao) GetOverdueOutsideDunning(dueBefore time.Time) ([]model.Invoice, error) {
+	var invoices []model.Invoice
+	err := dao.db().Preload("Subscription").
+		Where("status = ? AND dunning_status = ? AND due_date <= ?", model.InvoiceStatusSent, model.DunningStatusNone, dueBefore).
+		Order("due_date ASC").
+		Limit(dao.Limit).
+		Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// GetDueDunningRetries returns open invoices in dunning whose next retry is due.
+func (dao *InvoiceDao) GetDueDunningRetries(now time.Time) ([]model.Invoice, error) {
+	var invoices []model.Invoice
+	err := dao.db().Preload("Subscription").
+		Where("status = ? AND dunning_status = ? AND next_retry_at <= ?", model.InvoiceStatusSent, model.DunningStatusRetrying, now).
+		Order("next_retry_at ASC").
+		Limit(dao.Limit).
+		Find(&invoices).Error
+	if err != nil {
+		return nil, err
+	}
+	return invoices, nil
 }
@@ -0,0 +1,42 @@
+package dao
+
+import (
+	"testlake/model"
+
+	"github.com/google/uuid"
+)
+
+type LoginEventDao struct {
+	Limit int
+}
+
+func NewLoginEventDao() *LoginEventDao {
+	return &LoginEventDao{Limit: 50}
+}
+
+func (dao *LoginEventDao) Create(event *model.LoginEvent) error {
+	return Database.Create(event).Error
+}
+
+func (dao *LoginEventDao) GetByUserID(userID uuid.UUID, page int) ([]model.LoginEvent, int64, error) {
+	var events []model.LoginEvent
+	var total int64
+
+	err := Database.Model(&model.LoginEvent{}).
+		Where("user_id = ?", userID).
+		Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err = Database.Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Offset(offset).Limit(dao.Limit).
+		Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return events, total, nil
+}
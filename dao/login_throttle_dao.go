@@ -0,0 +1,84 @@
+package dao
+
+import (
+	"errors"
+	"testlake/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LoginThrottleDao struct {
+	Limit int
+}
+
+func NewLoginThrottleDao() *LoginThrottleDao {
+	return &LoginThrottleDao{Limit: 50}
+}
+
+// Get returns the throttle for the given scope and key, or an empty throttle
+// when no failure has been recorded yet.
+func (dao *LoginThrottleDao) Get(scope model.LoginThrottleScope, key string) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := Database.First(&throttle, "scope = ? AND key = ?", scope, key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &model.LoginThrottle{Scope: scope, Key: key}, nil
+		}
+		return nil, err
+	}
+	return &throttle, nil
+}
+
+// RecordFailure increments the failure counter, restarting it when the window
+// has elapsed, and locks the key once threshold failures are reached.
+// It returns the updated throttle and whether this failure triggered a new lockout.
+func (dao *LoginThrottleDao) RecordFailure(scope model.LoginThrottleScope, key string, threshold int, window, lockout time.Duration) (*model.LoginThrottle, bool, error) {
+	var throttle model.LoginThrottle
+	locked := false
+
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		// Create the row unless a concurrent failure did, then lock it: a
+		// missing row cannot be locked, and two creates would conflict
+		err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.LoginThrottle{Scope: scope, Key: key}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&throttle, "scope = ? AND key = ?", scope, key).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if throttle.IsWindowExpired(now, window) && !throttle.IsLocked(now) {
+			throttle.FailedCount = 0
+			throttle.FirstFailedAt = &now
+			throttle.LockedUntil = nil
+		}
+
+		throttle.FailedCount++
+		throttle.LastFailedAt = &now
+
+		if threshold > 0 && throttle.FailedCount >= threshold && !throttle.IsLocked(now) {
+			lockedUntil := now.Add(lockout)
+			throttle.LockedUntil = &lockedUntil
+			locked = true
+		}
+
+		return tx.Save(&throttle).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return &throttle, locked, nil
+}
+
+// Reset clears the failures and any lockout for the given scope and key.
+func (dao *LoginThrottleDao) Reset(scope model.LoginThrottleScope, key string) error {
+	return Database.Where("scope = ? AND key = ?", scope, key).
+		Delete(&model.LoginThrottle{}).Error
+}
@@ -1,10 +1,12 @@
 package dao
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
 
+	"testlake/migrations"
 	"testlake/model"
 
 	"gorm.io/driver/postgres"
@@ -14,7 +16,37 @@ import (
 
 var Database *gorm.DB
 
+// Connect opens the database and brings its schema up to date. Pending SQL
+// migrations are applied unless DB_MIGRATE_ON_START=false. DB_AUTO_MIGRATE=true
+// additionally runs GORM AutoMigrate, for development only: it picks up model
+// changes that have no migration yet, which production must never rely on.
 func Connect() {
+	Open()
+
+	if os.Getenv("DB_MIGRATE_ON_START") != "false" {
+		sqlDB, err := Database.DB()
+		if err != nil {
+			log.Fatal("Failed to get database connection:", err)
+		}
+		applied, err := migrations.Up(context.Background(), sqlDB)
+		if err != nil {
+			log.Fatal("Failed to run database migrations:", err)
+		}
+		for _, migration := range applied {
+			log.Printf("Applied migration %04d_%s", migration.Version, migration.Name)
+		}
+	}
+
+	if os.Getenv("DB_AUTO_MIGRATE") == "true" {
+		log.Println("DB_AUTO_MIGRATE is enabled, running AutoMigrate (development only)")
+		if err := AutoMigrate(); err != nil {
+			log.Fatal("Failed to auto-migrate database:", err)
+		}
+	}
+}
+
+// Open connects to the database without touching its schema.
+func Open() {
 	host := os.Getenv("DB_HOST")
 	username := os.Getenv("DB_USER")
 	password := os.Getenv("DB_PASSWORD")
@@ -32,8 +64,17 @@ func Connect() {
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
+}
 
-	err = Database.AutoMigrate(
+// AutoMigrate creates and alters tables to match the models.
+func AutoMigrate() error {
+	return Database.AutoMigrate(Models()...)
+}
+
+// Models lists every persisted model. Each one must also be covered by a SQL
+// migration in the migrations directory.
+func Models() []interface{} {
+	return []interface{}{
 		&model.User{},
 		&model.Organization{},
 		&model.OrganizationMember{},
@@ -55,8 +96,59 @@ func Connect() {
 		&model.EmailVerificationToken{},
 		&model.PaymentMethod{},
 		&model.Subscription{},
-	)
+		&model.LoginThrottle{},
+		&model.UserSession{},
+		&model.LoginEvent{},
+		&model.OrganizationSSOConfig{},
+		&model.SSOLoginState{},
+		&model.OrganizationSSODomain{},
+		&model.Plan{},
+		&model.PlanPrice{},
+		&model.Invoice{},
+		&model.InvoiceLineItem{},
+		&model.InvoiceSequence{},
+		&model.InvoiceDocument{},
+		&model.InvoiceDelivery{},
+		&model.CreditNote{},
+		&model.CreditNoteSequence{},
+		&model.Payment{},
+		&model.Coupon{},
+		&model.CouponRedemption{},
+		&model.BillingEvent{},
+		&model.BillingContact{},
+		&model.OrganizationUsage{},
+		&model.UsageAlert{},
+		&model.Notification{},
+	}
+}
+
+// Transaction runs fn in a database transaction. Pass tx to the WithTx method
+// of each dao taking part so that their queries commit or roll back together.
+func Transaction(fn func(tx *gorm.DB) error) error {
+	return Database.Transaction(fn)
+}
+
+// TryAdvisoryLock takes the Postgres session advisory lock key without waiting.
+// acquired is false when another session holds it. The lock lives on a
+// dedicated connection until release is called.
+func TryAdvisoryLock(ctx context.Context, key int64) (release func(), acquired bool, err error) {
+	sqlDB, err := Database.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
 	if err != nil {
-		log.Fatal("Failed to run database migrations:", err)
+		return nil, false, err
+	}
+
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil || !acquired {
+		conn.Close()
+		return nil, false, err
+	}
+
+	release = func() {
+		conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		conn.Close()
 	}
+	return release, true, nil
 }
@@ -0,0 +1,73 @@
+package dao
+
+import (
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationDao struct {
+	Limit int
+	tx    *gorm.DB
+}
+
+func NewNotificationDao() *NotificationDao {
+	return &NotificationDao{Limit: 50}
+}
+
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *NotificationDao) WithTx(tx *gorm.DB) *NotificationDao {
+	return &NotificationDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *NotificationDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
+func (dao *NotificationDao) Create(notification *model.Notification) error {
+	return dao.db().Create(notification).Error
+}
+
+// GetByUserID returns a page of the user's notifications, newest first.
+func (dao *NotificationDao) GetByUserID(userID uuid.UUID, page int) ([]model.Notification, int64, error) {
+	var notifications []model.Notification
+	var total int64
+
+	err := dao.db().Model(&model.Notification{}).Where("user_id = ?", userID).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err = dao.db().Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Offset(offset).Limit(dao.Limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, total, nil
+}
+
+// MarkRead marks the user's notification read. It reports whether the user
+// has such a notification.
+func (dao *NotificationDao) MarkRead(id, userID uuid.UUID, at time.Time) (bool, error) {
+	var notification model.Notification
+	err := dao.db().Where("id = ? AND user_id = ?", id, userID).First(&notification).Error
+	if err == gorm.ErrRecordNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if notification.IsRead() {
+		return true, nil
+	}
+	return true, dao.db().Model(&notification).Update("read_at", at).Error
+}
@@ -4,33 +4,63 @@ import (
 	"testlake/model"
 
 	"github.com/google/uuid"
-	_ "gorm.io/gorm"
+	"gorm.io/gorm"
 )
 
 type OrganizationDao struct {
 	Limit int
+	tx    *gorm.DB
 }
 
 func NewOrganizationDao() *OrganizationDao {
 	return &OrganizationDao{Limit: 50}
 }
 
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *OrganizationDao) WithTx(tx *gorm.DB) *OrganizationDao {
+	return &OrganizationDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *OrganizationDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
 func (dao *OrganizationDao) Create(org *model.Organization) error {
-	return Database.Create(org).Error
+	return dao.db().Create(org).Error
 }
 
 func (dao *OrganizationDao) GetByID(id uuid.UUID) (*model.Organization, error) {
 	var org model.Organization
-	err := Database.First(&org, "id = ?", id).Error
+	err := dao.db().First(&org, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &org, nil
 }
 
+// ReactivateIfSettled returns a past due or suspended organization to active once
+// none of its open invoices is in dunning anymore, and reports whether it did.
+func (dao *OrganizationDao) ReactivateIfSettled(id uuid.UUID) (bool, error) {
+	result := dao.db().Model(&model.Organization{}).
+		Where("id = ? AND subscription_status IN ?", id, []model.OrganizationSubscriptionStatus{
+			model.OrganizationSubscriptionStatusPastDue,
+			model.OrganizationSubscriptionStatusSuspended,
+		}).
+		Where(`NOT EXISTS (SELECT 1 FROM invoices WHERE invoices.organization_id = organizations.id
+			AND invoices.status = ? AND invoices.dunning_status IN ?)`, model.InvoiceStatusSent, []model.DunningStatus{
+			model.DunningStatusRetrying,
+			model.DunningStatusExhausted,
+		}).
+		Update("subscription_status", model.OrganizationSubscriptionStatusActive)
+	return result.RowsAffected > 0, result.Error
+}
+
 func (dao *OrganizationDao) GetBySlug(slug string) (*model.Organization, error) {
 	var org model.Organization
-	err := Database.First(&org, "slug = ?", slug).Error
+	err := dao.db().First(&org, "slug = ?", slug).Error
 	if err != nil {
 		return nil, err
 	}
@@ -41,13 +71,13 @@ func (dao *OrganizationDao) GetAll(page int) ([]model.Organization, int64, error
 	var orgs []model.Organization
 	var total int64
 
-	err := Database.Model(&model.Organization{}).Count(&total).Error
+	err := dao.db().Model(&model.Organization{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	offset := page * dao.Limit
-	err = Database.Offset(offset).Limit(dao.Limit).Find(&orgs).Error
+	err = dao.db().Order("created_at, id").Offset(offset).Limit(dao.Limit).Find(&orgs).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -59,7 +89,7 @@ func (dao *OrganizationDao) GetByCreatedBy(userID uuid.UUID, page int) ([]model.
 	var orgs []model.Organization
 	var total int64
 
-	query := Database.Model(&model.Organization{}).Where("created_by = ?", userID)
+	query := dao.db().Model(&model.Organization{}).Where("created_by = ?", userID)
 
 	err := query.Count(&total).Error
 	if err != nil {
@@ -76,20 +106,20 @@ func (dao *OrganizationDao) GetByCreatedBy(userID uuid.UUID, page int) ([]model.
 }
 
 func (dao *OrganizationDao) Update(org *model.Organization) error {
-	return Database.Save(org).Error
+	return dao.db().Save(org).Error
 }
 
 func (dao *OrganizationDao) Delete(id uuid.UUID) error {
-	return Database.Delete(&model.Organization{}, "id = ?", id).Error
+	return dao.db().Delete(&model.Organization{}, "id = ?", id).Error
 }
 
 func (dao *OrganizationDao) UpdateStatus(id uuid.UUID, status model.OrganizationStatus) error {
-	return Database.Model(&model.Organization{}).Where("id = ?", id).Update("status", status).Error
+	return dao.db().Model(&model.Organization{}).Where("id = ?", id).Update("status", status).Error
 }
 
 func (dao *OrganizationDao) SlugExists(slug string) (bool, error) {
 	var count int64
-	err := Database.Model(&model.Organization{}).Where("slug = ?", slug).Count(&count).Error
+	err := dao.db().Model(&model.Organization{}).Where("slug = ?", slug).Count(&count).Error
 	return count > 0, err
 }
 
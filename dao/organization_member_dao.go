@@ -25,6 +25,17 @@ func (dao *OrganizationMemberDao) GetMembers(orgID uuid.UUID) ([]model.Organizat
 	return members, err
 }
 
+// GetAdmins returns the joined owners and admins of an organization
+func (dao *OrganizationMemberDao) GetAdmins(orgID uuid.UUID) ([]model.OrganizationMember, error) {
+	var members []model.OrganizationMember
+	err := Database.
+		Preload("User").
+		Where("organization_id = ? AND status = ? AND role IN ?", orgID, "joined",
+			[]model.OrganizationMemberRole{model.OrganizationMemberRoleOwner, model.OrganizationMemberRoleAdmin}).
+		Find(&members).Error
+	return members, err
+}
+
 // GetMemberByUserID returns a specific member by organization and user ID
 func (dao *OrganizationMemberDao) GetMemberByUserID(orgID, userID uuid.UUID) (*model.OrganizationMember, error) {
 	var member model.OrganizationMember
@@ -0,0 +1,233 @@
+package dao
+
+import (
+	"errors"
+	"strings"
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrSSODomainTaken is returned when another organization already verified a domain
+var ErrSSODomainTaken = errors.New("domain is verified by another organization")
+
+type OrganizationSSODao struct {
+	Limit int
+}
+
+func NewOrganizationSSODao() *OrganizationSSODao {
+	return &OrganizationSSODao{Limit: 50}
+}
+
+func (dao *OrganizationSSODao) GetByOrganizationID(orgID uuid.UUID) (*model.OrganizationSSOConfig, error) {
+	var config model.OrganizationSSOConfig
+	err := Database.First(&config, "organization_id = ?", orgID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetByEmailDomain returns the enabled configuration that routes the email's
+// domain. Only domains the organization has verified are routed.
+func (dao *OrganizationSSODao) GetByEmailDomain(email string) (*model.OrganizationSSOConfig, error) {
+	domain := model.EmailDomain(email)
+	if domain == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	var config model.OrganizationSSOConfig
+	err := Database.
+		Joins("JOIN organization_sso_domains ON organization_sso_domains.organization_id = organization_sso_configs.organization_id").
+		Where("organization_sso_configs.is_enabled = ?", true).
+		Where("organization_sso_domains.domain = ? AND organization_sso_domains.verified_at IS NOT NULL", domain).
+		First(&config).Error
+	if err != nil {
+		return nil, err
+	}
+	if !config.AllowsEmail(email) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &config, nil
+}
+
+// IsEmailDomainVerified reports whether the organization verified the email's domain
+func (dao *OrganizationSSODao) IsEmailDomainVerified(orgID uuid.UUID, email string) (bool, error) {
+	var count int64
+	err := Database.Model(&model.OrganizationSSODomain{}).
+		Where("organization_id = ? AND domain = ? AND verified_at IS NOT NULL", orgID, model.EmailDomain(email)).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Save creates or replaces the configuration of an organization. Every allowed
+// domain gets a verification record; domains that were removed lose theirs.
+func (dao *OrganizationSSODao) Save(config *model.OrganizationSSOConfig) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(config).Error; err != nil {
+			return err
+		}
+
+		domains := config.DomainList()
+		if err := tx.Where("organization_id = ? AND domain NOT IN ?", config.OrganizationID, domains).
+			Delete(&model.OrganizationSSODomain{}).Error; err != nil {
+			return err
+		}
+		for _, domain := range domains {
+			err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&model.OrganizationSSODomain{OrganizationID: config.OrganizationID, Domain: domain}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (dao *OrganizationSSODao) DeleteByOrganizationID(orgID uuid.UUID) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", orgID).Delete(&model.OrganizationSSODomain{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("organization_id = ?", orgID).Delete(&model.OrganizationSSOConfig{}).Error
+	})
+}
+
+// GetDomains returns the domains an organization claims, verified or not
+func (dao *OrganizationSSODao) GetDomains(orgID uuid.UUID) ([]model.OrganizationSSODomain, error) {
+	var domains []model.OrganizationSSODomain
+	err := Database.Where("organization_id = ?", orgID).Order("domain ASC").Find(&domains).Error
+	return domains, err
+}
+
+func (dao *OrganizationSSODao) GetDomain(orgID uuid.UUID, domain string) (*model.OrganizationSSODomain, error) {
+	var ssoDomain model.OrganizationSSODomain
+	err := Database.First(&ssoDomain, "organization_id = ? AND domain = ?", orgID, strings.ToLower(domain)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ssoDomain, nil
+}
+
+// GetVerifiedDomain returns the verification of the domain by whichever
+// organization owns it
+func (dao *OrganizationSSODao) GetVerifiedDomain(domain string) (*model.OrganizationSSODomain, error) {
+	var ssoDomain model.OrganizationSSODomain
+	err := Database.First(&ssoDomain, "domain = ? AND verified_at IS NOT NULL", strings.ToLower(domain)).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ssoDomain, nil
+}
+
+// MarkDomainVerified records that the organization proved it controls the
+// domain. It fails with ErrSSODomainTaken when another organization verified
+// the domain first.
+func (dao *OrganizationSSODao) MarkDomainVerified(ssoDomain *model.OrganizationSSODomain, verifiedAt time.Time) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Model(&model.OrganizationSSODomain{}).
+			Where("domain = ? AND organization_id <> ? AND verified_at IS NOT NULL", ssoDomain.Domain, ssoDomain.OrganizationID).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSSODomainTaken
+		}
+
+		if err := tx.Model(ssoDomain).Update("verified_at", verifiedAt).Error; err != nil {
+			return err
+		}
+		ssoDomain.VerifiedAt = &verifiedAt
+		return nil
+	})
+}
+
+// IsEnforcedForUser reports whether the user belongs to an organization that
+// requires its members to sign in through SSO
+func (dao *OrganizationSSODao) IsEnforcedForUser(userID uuid.UUID) (bool, error) {
+	var count int64
+	err := Database.Model(&model.OrganizationSSOConfig{}).
+		Joins("JOIN organization_members ON organization_members.organization_id = organization_sso_configs.organization_id").
+		Where("organization_members.user_id = ? AND organization_members.status = ?", userID, "joined").
+		Where("organization_sso_configs.enforce_sso = ? AND organization_sso_configs.is_enabled = ?", true, true).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ProvisionMember creates the user when it does not exist yet and makes sure it
+// is a joined member of the organization. Existing roles are left untouched.
+func (dao *OrganizationSSODao) ProvisionMember(config *model.OrganizationSSOConfig, user *model.User, isNewUser bool) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		if isNewUser {
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		var member model.OrganizationMember
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("organization_id = ? AND user_id = ?", config.OrganizationID, user.ID).
+			First(&member).Error
+		if err == gorm.ErrRecordNotFound {
+			return tx.Create(&model.OrganizationMember{
+				OrganizationID: config.OrganizationID,
+				UserID:         user.ID,
+				Role:           config.DefaultRole,
+				InvitedBy:      config.CreatedBy,
+				InvitedAt:      now,
+				JoinedAt:       &now,
+				Status:         "joined",
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if member.Status == "joined" {
+			return nil
+		}
+		return tx.Model(&member).Updates(map[string]interface{}{
+			"status":    "joined",
+			"joined_at": now,
+		}).Error
+	})
+}
+
+type SSOLoginStateDao struct{}
+
+func NewSSOLoginStateDao() *SSOLoginStateDao {
+	return &SSOLoginStateDao{}
+}
+
+func (dao *SSOLoginStateDao) Create(state *model.SSOLoginState) error {
+	return Database.Create(state).Error
+}
+
+// Consume marks a pending state as used and returns it. A state can only be
+// consumed once, which stops an authorization response from being replayed.
+func (dao *SSOLoginStateDao) Consume(state string) (*model.SSOLoginState, error) {
+	var loginState model.SSOLoginState
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.SSOLoginState{}).
+			Where("state = ? AND used_at IS NULL AND expires_at > ?", state, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.First(&loginState, "state = ?", state).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &loginState, nil
+}
@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type OrganizationUsageDao struct {
@@ -31,8 +33,7 @@ func (dao *OrganizationUsageDao) GetByID(id uuid.UUID) (*model.OrganizationUsage
 func (dao *OrganizationUsageDao) GetCurrentUsage(organizationID uuid.UUID) (*model.OrganizationUsage, error) {
 	var usage model.OrganizationUsage
 	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	startOfMonth, endOfMonth := model.UsagePeriod(now)
 
 	err := Database.Where("organization_id = ? AND period_start = ? AND period_end = ?",
 		organizationID, startOfMonth, endOfMonth).
@@ -83,8 +84,7 @@ func (dao *OrganizationUsageDao) Update(usage *model.OrganizationUsage) error {
 
 func (dao *OrganizationUsageDao) UpsertCurrentUsage(organizationID uuid.UUID, updates map[string]interface{}) error {
 	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
+	startOfMonth, endOfMonth := model.UsagePeriod(now)
 
 	var usage model.OrganizationUsage
 	err := Database.Where("organization_id = ? AND period_start = ? AND period_end = ?",
@@ -138,45 +138,118 @@ func (dao *OrganizationUsageDao) UpsertCurrentUsage(organizationID uuid.UUID, up
 	return Database.Model(&usage).Updates(updates).Error
 }
 
-func (dao *OrganizationUsageDao) IncrementUsage(organizationID uuid.UUID, field string, increment int) error {
-	now := time.Now()
-	startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
-	endOfMonth := startOfMonth.AddDate(0, 1, 0).Add(-time.Nanosecond)
-
-	// First try to update existing record
-	result := Database.Model(&model.OrganizationUsage{}).
-		Where("organization_id = ? AND period_start = ? AND period_end = ?",
-			organizationID, startOfMonth, endOfMonth).
-		UpdateColumn(field, Database.Raw(field+" + ?", increment))
-
-	if result.RowsAffected == 0 {
-		// Create new record if none exists
-		usage := model.OrganizationUsage{
-			OrganizationID: organizationID,
-			PeriodStart:    startOfMonth,
-			PeriodEnd:      endOfMonth,
-			RecordedAt:     now,
-		}
+// GetUsageBetween returns the usage records of the periods starting in
+// [from, to), oldest first.
+func (dao *OrganizationUsageDao) GetUsageBetween(organizationID uuid.UUID, from, to time.Time) ([]model.OrganizationUsage, error) {
+	var usages []model.OrganizationUsage
+	err := Database.Where("organization_id = ? AND period_start >= ? AND period_start < ?", organizationID, from, to).
+		Order("period_start").
+		Find(&usages).Error
+	if err != nil {
+		return nil, err
+	}
+	return usages, nil
+}
 
-		switch field {
-		case "users_count":
-			usage.UsersCount = increment
-		case "projects_count":
-			usage.ProjectsCount = increment
-		case "environments_count":
-			usage.EnvironmentsCount = increment
-		case "schemas_count":
-			usage.SchemasCount = increment
-		case "test_records_count":
-			usage.TestRecordsCount = increment
-		case "api_requests_count":
-			usage.APIRequestsCount = increment
-		}
+// AddAPIRequests adds metered API requests to the organization's usage for
+// the period of at, creating the record of the period if needed.
+func (dao *OrganizationUsageDao) AddAPIRequests(organizationID uuid.UUID, requests int, at time.Time) error {
+	periodStart, periodEnd := model.UsagePeriod(at)
+	usage := model.OrganizationUsage{
+		OrganizationID:   organizationID,
+		PeriodStart:      periodStart,
+		PeriodEnd:        periodEnd,
+		APIRequestsCount: requests,
+		RecordedAt:       at,
+	}
+	return Database.Clauses(clause.OnConflict{
+		Columns: usagePeriodColumns,
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"api_requests_count": gorm.Expr("organization_usages.api_requests_count + ?", requests),
+		}),
+	}).Create(&usage).Error
+}
 
-		return Database.Create(&usage).Error
+// RecordResourceCounts sets the resource counts of the organization's usage
+// for the period of at to the live counts, leaving metered requests as they are.
+func (dao *OrganizationUsageDao) RecordResourceCounts(organizationID uuid.UUID, counts *model.UsageCounts, at time.Time) error {
+	periodStart, periodEnd := model.UsagePeriod(at)
+	usage := model.OrganizationUsage{
+		OrganizationID:    organizationID,
+		PeriodStart:       periodStart,
+		PeriodEnd:         periodEnd,
+		UsersCount:        counts.Users,
+		ProjectsCount:     counts.Projects,
+		EnvironmentsCount: counts.Environments,
+		SchemasCount:      counts.Schemas,
+		TestRecordsCount:  counts.MaxTestRecordsPerSchema,
+		RecordedAt:        at,
+	}
+	return Database.Clauses(clause.OnConflict{
+		Columns: usagePeriodColumns,
+		DoUpdates: clause.AssignmentColumns([]string{
+			"users_count", "projects_count", "environments_count", "schemas_count", "test_records_count", "recorded_at",
+		}),
+	}).Create(&usage).Error
+}
+
+// usagePeriodColumns is the unique key of a usage record.
+var usagePeriodColumns = []clause.Column{{Name: "organization_id"}, {Name: "period_start"}, {Name: "period_end"}}
+
+// CountLiveUsage counts the organization's current members and resources.
+// The creator, invited members and pending invitations count towards the user
+// limit since they hold a seat.
+func (dao *OrganizationUsageDao) CountLiveUsage(organizationID uuid.UUID) (*model.UsageCounts, error) {
+	var members, invitations, projects, environments, schemas int64
+	var maxRecords int
+
+	creator := Database.Model(&model.Organization{}).Select("created_by").Where("id = ?", organizationID)
+	err := Database.Model(&model.OrganizationMember{}).
+		Where("organization_id = ? AND status IN ? AND user_id NOT IN (?)", organizationID, []string{"invited", "joined"}, creator).
+		Count(&members).Error
+	if err != nil {
+		return nil, err
+	}
+	err = Database.Model(&model.OrganizationInvitation{}).
+		Where("organization_id = ? AND status = ? AND expires_at > ?", organizationID, "pending", time.Now()).
+		Count(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	projectIDs := Database.Model(&model.Project{}).Select("id").Where("organization_id = ?", organizationID)
+	err = Database.Model(&model.Project{}).Where("organization_id = ?", organizationID).Count(&projects).Error
+	if err != nil {
+		return nil, err
+	}
+	err = Database.Model(&model.Environment{}).Where("project_id IN (?)", projectIDs).Count(&environments).Error
+	if err != nil {
+		return nil, err
+	}
+	err = Database.Model(&model.DataSchema{}).Where("project_id IN (?)", projectIDs).Count(&schemas).Error
+	if err != nil {
+		return nil, err
+	}
+
+	schemaIDs := Database.Model(&model.DataSchema{}).Select("id").Where("project_id IN (?)", projectIDs)
+	perSchema := Database.Model(&model.TestData{}).
+		Select("COUNT(*) AS records").
+		Where("schema_id IN (?)", schemaIDs).
+		Group("schema_id")
+	err = Database.Table("(?) AS per_schema", perSchema).
+		Select("COALESCE(MAX(records), 0)").
+		Scan(&maxRecords).Error
+	if err != nil {
+		return nil, err
 	}
 
-	return result.Error
+	return &model.UsageCounts{
+		Users:                   1 + int(members) + int(invitations),
+		Projects:                int(projects),
+		Environments:            int(environments),
+		Schemas:                 int(schemas),
+		MaxTestRecordsPerSchema: maxRecords,
+	}, nil
 }
 
 func (dao *OrganizationUsageDao) Delete(id uuid.UUID) error {
@@ -2,25 +2,41 @@ package dao
 
 import (
 	"testlake/model"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentDao struct {
 	Limit int
+	tx    *gorm.DB
 }
 
 func NewPaymentDao() *PaymentDao {
 	return &PaymentDao{Limit: 50}
 }
 
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *PaymentDao) WithTx(tx *gorm.DB) *PaymentDao {
+	return &PaymentDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *PaymentDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
 func (dao *PaymentDao) Create(payment *model.Payment) error {
-	return Database.Create(payment).Error
+	return dao.db().Create(payment).Error
 }
 
 func (dao *PaymentDao) GetByID(id uuid.UUID) (*model.Payment, error) {
 	var payment model.Payment
-	err := Database.Preload("Organization").
+	err := dao.db().Preload("Organization").
 		Preload("Invoice").
 		Preload("Subscription").
 		First(&payment, "id = ?", id).Error
@@ -30,12 +46,22 @@ func (dao *PaymentDao) GetByID(id uuid.UUID) (*model.Payment, error) {
 	return &payment, nil
 }
 
+// GetForUpdate loads the payment and locks its row until the transaction ends.
+func (dao *PaymentDao) GetForUpdate(id uuid.UUID) (*model.Payment, error) {
+	var payment model.Payment
+	err := dao.db().Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
 func (dao *PaymentDao) GetByPayPalPaymentID(paypalPaymentID string) (*model.Payment, error) {
 	var payment model.Payment
-	err := Database.Preload("Organization").
+	err := dao.db().Preload("Organization").
 		Preload("Invoice").
 		Preload("Subscription").
-		First(&payment, "paypal_payment_id = ?", paypalPaymentID).Error
+		First(&payment, "pay_pal_payment_id = ?", paypalPaymentID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -46,7 +72,7 @@ func (dao *PaymentDao) GetByOrganizationID(organizationID uuid.UUID, page int) (
 	var payments []model.Payment
 	var total int64
 
-	err := Database.Model(&model.Payment{}).
+	err := dao.db().Model(&model.Payment{}).
 		Where("organization_id = ?", organizationID).
 		Count(&total).Error
 	if err != nil {
@@ -54,7 +80,7 @@ func (dao *PaymentDao) GetByOrganizationID(organizationID uuid.UUID, page int) (
 	}
 
 	offset := page * dao.Limit
-	err = Database.Preload("Invoice").
+	err = dao.db().Preload("Invoice").
 		Preload("Subscription").
 		Where("organization_id = ?", organizationID).
 		Order("created_at DESC").
@@ -69,7 +95,7 @@ func (dao *PaymentDao) GetByOrganizationID(organizationID uuid.UUID, page int) (
 
 func (dao *PaymentDao) GetRecentByOrganizationID(organizationID uuid.UUID, limit int) ([]model.Payment, error) {
 	var payments []model.Payment
-	err := Database.Preload("Invoice").
+	err := dao.db().Preload("Invoice").
 		Preload("Subscription").
 		Where("organization_id = ?", organizationID).
 		Order("created_at DESC").
@@ -83,7 +109,7 @@ func (dao *PaymentDao) GetRecentByOrganizationID(organizationID uuid.UUID, limit
 
 func (dao *PaymentDao) GetByInvoiceID(invoiceID uuid.UUID) ([]model.Payment, error) {
 	var payments []model.Payment
-	err := Database.Where("invoice_id = ?", invoiceID).
+	err := dao.db().Where("invoice_id = ?", invoiceID).
 		Order("created_at DESC").
 		Find(&payments).Error
 	if err != nil {
@@ -94,7 +120,7 @@ func (dao *PaymentDao) GetByInvoiceID(invoiceID uuid.UUID) ([]model.Payment, err
 
 func (dao *PaymentDao) GetBySubscriptionID(subscriptionID uuid.UUID) ([]model.Payment, error) {
 	var payments []model.Payment
-	err := Database.Where("subscription_id = ?", subscriptionID).
+	err := dao.db().Where("subscription_id = ?", subscriptionID).
 		Order("created_at DESC").
 		Find(&payments).Error
 	if err != nil {
@@ -103,8 +129,47 @@ func (dao *PaymentDao) GetBySubscriptionID(subscriptionID uuid.UUID) ([]model.Pa
 	return payments, nil
 }
 
+// GetUnmatchedCompleted returns completed payments of the subscription since the
+// given time that no invoice was settled with, oldest first.
+func (dao *PaymentDao) GetUnmatchedCompleted(subscriptionID uuid.UUID, since time.Time) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := dao.db().
+		Where("subscription_id = ? AND invoice_id IS NULL AND status = ? AND created_at >= ?", subscriptionID, model.PaymentStatusCompleted, since).
+		Order("created_at ASC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+// HasCompletedSince reports whether the subscription was charged since the given time.
+func (dao *PaymentDao) HasCompletedSince(subscriptionID uuid.UUID, since time.Time) (bool, error) {
+	var count int64
+	err := dao.db().Model(&model.Payment{}).
+		Where("subscription_id = ? AND status IN ? AND created_at >= ?", subscriptionID,
+			[]model.PaymentStatus{model.PaymentStatusCompleted, model.PaymentStatusPartiallyRefunded, model.PaymentStatusRefunded}, since).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetCollectedBetween returns the payments processed between from and until that
+// were collected, including the ones refunded since, oldest first.
+func (dao *PaymentDao) GetCollectedBetween(from, until time.Time) ([]model.Payment, error) {
+	var payments []model.Payment
+	err := dao.db().
+		Where("status IN ? AND processed_at >= ? AND processed_at < ?",
+			[]model.PaymentStatus{model.PaymentStatusCompleted, model.PaymentStatusPartiallyRefunded, model.PaymentStatusRefunded}, from, until).
+		Order("processed_at ASC").
+		Find(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
 func (dao *PaymentDao) Update(payment *model.Payment) error {
-	return Database.Save(payment).Error
+	return dao.db().Save(payment).Error
 }
 
 func (dao *PaymentDao) UpdateStatus(id uuid.UUID, status model.PaymentStatus) error {
@@ -114,11 +179,11 @@ func (dao *PaymentDao) UpdateStatus(id uuid.UUID, status model.PaymentStatus) er
 	if status == model.PaymentStatusCompleted {
 		updates["processed_at"] = "NOW()"
 	}
-	return Database.Model(&model.Payment{}).Where("id = ?", id).Updates(updates).Error
+	return dao.db().Model(&model.Payment{}).Where("id = ?", id).Updates(updates).Error
 }
 
 func (dao *PaymentDao) UpdateFailureReason(id uuid.UUID, reason string) error {
-	return Database.Model(&model.Payment{}).
+	return dao.db().Model(&model.Payment{}).
 		Where("id = ?", id).
 		Updates(map[string]interface{}{
 			"status":         model.PaymentStatusFailed,
@@ -127,20 +192,20 @@ func (dao *PaymentDao) UpdateFailureReason(id uuid.UUID, reason string) error {
 }
 
 func (dao *PaymentDao) Delete(id uuid.UUID) error {
-	return Database.Delete(&model.Payment{}, "id = ?", id).Error
+	return dao.db().Delete(&model.Payment{}, "id = ?", id).Error
 }
 
 func (dao *PaymentDao) GetAll(page int) ([]model.Payment, int64, error) {
 	var payments []model.Payment
 	var total int64
 
-	err := Database.Model(&model.Payment{}).Count(&total).Error
+	err := dao.db().Model(&model.Payment{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	offset := page * dao.Limit
-	err = Database.Preload("Organization").
+	err = dao.db().Preload("Organization").
 		Preload("Invoice").
 		Preload("Subscription").
 		Order("created_at DESC").
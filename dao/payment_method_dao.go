@@ -4,23 +4,38 @@ import (
 	"testlake/model"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PaymentMethodDao struct {
 	Limit int
+	tx    *gorm.DB
 }
 
 func NewPaymentMethodDao() *PaymentMethodDao {
 	return &PaymentMethodDao{Limit: 50}
 }
 
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *PaymentMethodDao) WithTx(tx *gorm.DB) *PaymentMethodDao {
+	return &PaymentMethodDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *PaymentMethodDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
 func (dao *PaymentMethodDao) Create(paymentMethod *model.PaymentMethod) error {
-	return Database.Create(paymentMethod).Error
+	return dao.db().Create(paymentMethod).Error
 }
 
 func (dao *PaymentMethodDao) GetByID(id uuid.UUID) (*model.PaymentMethod, error) {
 	var paymentMethod model.PaymentMethod
-	err := Database.First(&paymentMethod, "id = ?", id).Error
+	err := dao.db().First(&paymentMethod, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +44,42 @@ func (dao *PaymentMethodDao) GetByID(id uuid.UUID) (*model.PaymentMethod, error)
 
 func (dao *PaymentMethodDao) GetByOrganizationID(organizationID uuid.UUID) ([]model.PaymentMethod, error) {
 	var paymentMethods []model.PaymentMethod
-	err := Database.Where("organization_id = ? AND is_active = ?", organizationID, true).Find(&paymentMethods).Error
+	err := dao.db().Where("organization_id = ? AND is_active = ? AND status <> ?", organizationID, true, model.PaymentMethodStatusPending).
+		Order("created_at ASC").
+		Find(&paymentMethods).Error
+	if err != nil {
+		return nil, err
+	}
+	return paymentMethods, nil
+}
+
+// GetForUpdate loads the payment method and locks its row until the transaction ends.
+func (dao *PaymentMethodDao) GetForUpdate(id uuid.UUID) (*model.PaymentMethod, error) {
+	var paymentMethod model.PaymentMethod
+	err := dao.db().Clauses(clause.Locking{Strength: "UPDATE"}).First(&paymentMethod, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paymentMethod, nil
+}
+
+// GetByPayPalVaultID returns the payment method saved with the vault token.
+func (dao *PaymentMethodDao) GetByPayPalVaultID(vaultID string) (*model.PaymentMethod, error) {
+	var paymentMethod model.PaymentMethod
+	err := dao.db().Where("pay_pal_vault_id = ?", vaultID).First(&paymentMethod).Error
+	if err != nil {
+		return nil, err
+	}
+	return &paymentMethod, nil
+}
+
+// GetVerifiedByOrganizationID returns the organization's payment methods that
+// can be charged, newest first.
+func (dao *PaymentMethodDao) GetVerifiedByOrganizationID(organizationID uuid.UUID) ([]model.PaymentMethod, error) {
+	var paymentMethods []model.PaymentMethod
+	err := dao.db().Where("organization_id = ? AND is_active = ? AND status = ?", organizationID, true, model.PaymentMethodStatusVerified).
+		Order("created_at DESC").
+		Find(&paymentMethods).Error
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +88,7 @@ func (dao *PaymentMethodDao) GetByOrganizationID(organizationID uuid.UUID) ([]mo
 
 func (dao *PaymentMethodDao) GetDefaultByOrganizationID(organizationID uuid.UUID) (*model.PaymentMethod, error) {
 	var paymentMethod model.PaymentMethod
-	err := Database.Where("organization_id = ? AND is_default = ? AND is_active = ?", organizationID, true, true).First(&paymentMethod).Error
+	err := dao.db().Where("organization_id = ? AND is_default = ? AND is_active = ?", organizationID, true, true).First(&paymentMethod).Error
 	if err != nil {
 		return nil, err
 	}
@@ -46,20 +96,20 @@ func (dao *PaymentMethodDao) GetDefaultByOrganizationID(organizationID uuid.UUID
 }
 
 func (dao *PaymentMethodDao) Update(paymentMethod *model.PaymentMethod) error {
-	return Database.Save(paymentMethod).Error
+	return dao.db().Save(paymentMethod).Error
 }
 
 func (dao *PaymentMethodDao) Delete(id uuid.UUID) error {
-	return Database.Delete(&model.PaymentMethod{}, "id = ?", id).Error
+	return dao.db().Delete(&model.PaymentMethod{}, "id = ?", id).Error
 }
 
 func (dao *PaymentMethodDao) SetActive(id uuid.UUID, active bool) error {
-	return Database.Model(&model.PaymentMethod{}).Where("id = ?", id).Update("is_active", active).Error
+	return dao.db().Model(&model.PaymentMethod{}).Where("id = ?", id).Update("is_active", active).Error
 }
 
 func (dao *PaymentMethodDao) SetDefault(organizationID, paymentMethodID uuid.UUID) error {
 	// First, unset all default payment methods for this organization
-	err := Database.Model(&model.PaymentMethod{}).
+	err := dao.db().Model(&model.PaymentMethod{}).
 		Where("organization_id = ?", organizationID).
 		Update("is_default", false).Error
 	if err != nil {
@@ -67,14 +117,14 @@ func (dao *PaymentMethodDao) SetDefault(organizationID, paymentMethodID uuid.UUI
 	}
 
 	// Then set the specified payment method as default
-	return Database.Model(&model.PaymentMethod{}).
+	return dao.db().Model(&model.PaymentMethod{}).
 		Where("id = ? AND organization_id = ?", paymentMethodID, organizationID).
 		Update("is_default", true).Error
 }
 
 func (dao *PaymentMethodDao) CountByOrganizationID(organizationID uuid.UUID) (int64, error) {
 	var count int64
-	err := Database.Model(&model.PaymentMethod{}).
+	err := dao.db().Model(&model.PaymentMethod{}).
 		Where("organization_id = ? AND is_active = ?", organizationID, true).
 		Count(&count).Error
 	return count, err
@@ -2,34 +2,52 @@ package dao
 
 import (
 	"testlake/model"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PlanDao struct {
 	Limit int
+	tx    *gorm.DB
 }
 
 func NewPlanDao() *PlanDao {
 	return &PlanDao{Limit: 50}
 }
 
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *PlanDao) WithTx(tx *gorm.DB) *PlanDao {
+	return &PlanDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *PlanDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
 func (dao *PlanDao) Create(plan *model.Plan) error {
-	return Database.Create(plan).Error
+	return dao.db().Create(plan).Error
 }
 
 func (dao *PlanDao) GetByID(id uuid.UUID) (*model.Plan, error) {
 	var plan model.Plan
-	err := Database.First(&plan, "id = ?", id).Error
+	err := dao.db().Preload("Prices").First(&plan, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
 	return &plan, nil
 }
 
+// GetBySlug returns the current version of the plan with the slug, or its
+// latest version when every version was retired.
 func (dao *PlanDao) GetBySlug(slug string) (*model.Plan, error) {
 	var plan model.Plan
-	err := Database.First(&plan, "slug = ?", slug).Error
+	err := dao.db().Preload("Prices").Where("slug = ?", slug).Order("retired_at IS NOT NULL").Order("version DESC").First(&plan).Error
 	if err != nil {
 		return nil, err
 	}
@@ -38,7 +56,7 @@ func (dao *PlanDao) GetBySlug(slug string) (*model.Plan, error) {
 
 func (dao *PlanDao) GetAll() ([]model.Plan, error) {
 	var plans []model.Plan
-	err := Database.Where("is_active = ?", true).Find(&plans).Error
+	err := dao.db().Preload("Prices").Where("is_active = ?", true).Find(&plans).Error
 	if err != nil {
 		return nil, err
 	}
@@ -49,13 +67,13 @@ func (dao *PlanDao) GetAllWithPagination(page int) ([]model.Plan, int64, error)
 	var plans []model.Plan
 	var total int64
 
-	err := Database.Model(&model.Plan{}).Where("is_active = ?", true).Count(&total).Error
+	err := dao.db().Model(&model.Plan{}).Where("is_active = ?", true).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	offset := page * dao.Limit
-	err = Database.Where("is_active = ?", true).Offset(offset).Limit(dao.Limit).Find(&plans).Error
+	err = dao.db().Preload("Prices").Where("is_active = ?", true).Offset(offset).Limit(dao.Limit).Find(&plans).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -63,22 +81,91 @@ func (dao *PlanDao) GetAllWithPagination(page int) ([]model.Plan, int64, error)
 	return plans, total, nil
 }
 
+// GetCatalogue returns every version of every plan, retired ones included,
+// ordered by slug and newest version first.
+func (dao *PlanDao) GetCatalogue(page int) ([]model.Plan, int64, error) {
+	var plans []model.Plan
+	var total int64
+
+	err := dao.db().Model(&model.Plan{}).Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err = dao.db().Preload("Prices").Order("slug").Order("version DESC").Offset(offset).Limit(dao.Limit).Find(&plans).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return plans, total, nil
+}
+
+// GetForUpdate locks the plan row until the transaction ends.
+func (dao *PlanDao) GetForUpdate(id uuid.UUID) (*model.Plan, error) {
+	var plan model.Plan
+	err := dao.db().Clauses(clause.Locking{Strength: "UPDATE"}).First(&plan, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
 func (dao *PlanDao) Update(plan *model.Plan) error {
-	return Database.Save(plan).Error
+	return dao.db().Omit("Prices").Save(plan).Error
 }
 
 func (dao *PlanDao) Delete(id uuid.UUID) error {
-	return Database.Delete(&model.Plan{}, "id = ?", id).Error
+	return dao.db().Delete(&model.Plan{}, "id = ?", id).Error
 }
 
 func (dao *PlanDao) SetActive(id uuid.UUID, active bool) error {
-	return Database.Model(&model.Plan{}).Where("id = ?", id).Update("is_active", active).Error
+	return dao.db().Model(&model.Plan{}).Where("id = ?", id).Update("is_active", active).Error
+}
+
+// Retire takes the plan off the catalogue; subscriptions on it keep running.
+func (dao *PlanDao) Retire(id uuid.UUID, at time.Time) error {
+	updates := map[string]interface{}{
+		"is_active":  false,
+		"retired_at": at,
+	}
+	return dao.db().Model(&model.Plan{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (dao *PlanDao) UpdatePayPalProductID(id uuid.UUID, productID string) error {
+	return dao.db().Model(&model.Plan{}).Where("id = ?", id).Update("pay_pal_product_id", productID).Error
 }
 
-func (dao *PlanDao) UpdatePayPalPlanIDs(id uuid.UUID, monthlyPlanID, yearlyPlanID string) error {
+func (dao *PlanDao) UpdatePayPalPlanIDs(id uuid.UUID, monthlyPlanID, yearlyPlanID *string) error {
 	updates := map[string]interface{}{
-		"paypal_monthly_plan_id": monthlyPlanID,
-		"paypal_yearly_plan_id":  yearlyPlanID,
+		"pay_pal_monthly_plan_id": monthlyPlanID,
+		"pay_pal_yearly_plan_id":  yearlyPlanID,
 	}
-	return Database.Model(&model.Plan{}).Where("id = ?", id).Updates(updates).Error
+	return dao.db().Model(&model.Plan{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// GetPrice returns the plan's price point in a currency and billing cycle.
+func (dao *PlanDao) GetPrice(planID uuid.UUID, currency string, cycle model.BillingCycle) (*model.PlanPrice, error) {
+	var price model.PlanPrice
+	err := dao.db().Where("plan_id = ? AND currency = ? AND billing_cycle = ?", planID, currency, cycle).First(&price).Error
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+func (dao *PlanDao) CreatePrice(price *model.PlanPrice) error {
+	return dao.db().Create(price).Error
+}
+
+func (dao *PlanDao) UpdatePrice(price *model.PlanPrice) error {
+	return dao.db().Save(price).Error
+}
+
+func (dao *PlanDao) UpdatePricePayPalPlanID(id uuid.UUID, gatewayPlanID string) error {
+	return dao.db().Model(&model.PlanPrice{}).Where("id = ?", id).Update("pay_pal_plan_id", gatewayPlanID).Error
+}
+
+func (dao *PlanDao) DeletePrice(id uuid.UUID) error {
+	return dao.db().Delete(&model.PlanPrice{}, "id = ?", id).Error
 }
@@ -2,25 +2,41 @@ package dao
 
 import (
 	"testlake/model"
+	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SubscriptionDao struct {
 	Limit int
+	tx    *gorm.DB
 }
 
 func NewSubscriptionDao() *SubscriptionDao {
 	return &SubscriptionDao{Limit: 50}
 }
 
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *SubscriptionDao) WithTx(tx *gorm.DB) *SubscriptionDao {
+	return &SubscriptionDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *SubscriptionDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
 func (dao *SubscriptionDao) Create(subscription *model.Subscription) error {
-	return Database.Create(subscription).Error
+	return dao.db().Create(subscription).Error
 }
 
 func (dao *SubscriptionDao) GetByID(id uuid.UUID) (*model.Subscription, error) {
 	var subscription model.Subscription
-	err := Database.Preload("Organization").Preload("Plan").First(&subscription, "id = ?", id).Error
+	err := dao.db().Preload("Organization").Preload("Plan.Prices").First(&subscription, "id = ?", id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +45,7 @@ func (dao *SubscriptionDao) GetByID(id uuid.UUID) (*model.Subscription, error) {
 
 func (dao *SubscriptionDao) GetByOrganizationID(organizationID uuid.UUID) (*model.Subscription, error) {
 	var subscription model.Subscription
-	err := Database.Preload("Organization").Preload("Plan").
+	err := dao.db().Preload("Organization").Preload("Plan.Prices").
 		Where("organization_id = ?", organizationID).
 		Order("created_at DESC").
 		First(&subscription).Error
@@ -41,8 +57,8 @@ func (dao *SubscriptionDao) GetByOrganizationID(organizationID uuid.UUID) (*mode
 
 func (dao *SubscriptionDao) GetByPayPalSubscriptionID(paypalSubID string) (*model.Subscription, error) {
 	var subscription model.Subscription
-	err := Database.Preload("Organization").Preload("Plan").
-		First(&subscription, "paypal_subscription_id = ?", paypalSubID).Error
+	err := dao.db().Preload("Organization").Preload("Plan.Prices").
+		First(&subscription, "pay_pal_subscription_id = ?", paypalSubID).Error
 	if err != nil {
 		return nil, err
 	}
@@ -51,7 +67,7 @@ func (dao *SubscriptionDao) GetByPayPalSubscriptionID(paypalSubID string) (*mode
 
 func (dao *SubscriptionDao) GetActiveByOrganizationID(organizationID uuid.UUID) (*model.Subscription, error) {
 	var subscription model.Subscription
-	err := Database.Preload("Organization").Preload("Plan").
+	err := dao.db().Preload("Organization").Preload("Plan.Prices").
 		Where("organization_id = ? AND status = ?", organizationID, model.SubscriptionStatusActive).
 		Order("created_at DESC").
 		First(&subscription).Error
@@ -61,12 +77,160 @@ func (dao *SubscriptionDao) GetActiveByOrganizationID(organizationID uuid.UUID)
 	return &subscription, nil
 }
 
+// CountActiveByPlanID counts the active subscriptions on a plan, the ones a
+// retired plan keeps billing at its price.
+func (dao *SubscriptionDao) CountActiveByPlanID(planID uuid.UUID) (int64, error) {
+	var count int64
+	err := dao.db().Model(&model.Subscription{}).
+		Where("plan_id = ? AND status = ?", planID, model.SubscriptionStatusActive).
+		Count(&count).Error
+	return count, err
+}
+
+// CancelOthers cancels the organization's active subscriptions other than keepID,
+// used when a new subscription replaces the current one.
+func (dao *SubscriptionDao) CancelOthers(organizationID, keepID uuid.UUID) error {
+	return dao.db().Model(&model.Subscription{}).
+		Where("organization_id = ? AND id <> ? AND status = ?", organizationID, keepID, model.SubscriptionStatusActive).
+		Updates(map[string]interface{}{
+			"status":       model.SubscriptionStatusCancelled,
+			"cancelled_at": time.Now(),
+		}).Error
+}
+
+// GetDueScheduledChanges returns active, renewing subscriptions with a scheduled plan change
+// whose current period ended at or before now.
+func (dao *SubscriptionDao) GetDueScheduledChanges(now time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	err := dao.db().Preload("Plan.Prices").
+		Where("status = ? AND cancel_at_period_end = ? AND scheduled_plan_id IS NOT NULL AND current_period_end <= ?", model.SubscriptionStatusActive, false, now).
+		Order("current_period_end ASC").
+		Limit(dao.Limit).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetDueForRenewal returns active subscriptions whose current period ended at or before now.
+func (dao *SubscriptionDao) GetDueForRenewal(now time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	err := dao.db().
+		Where("status = ? AND current_period_end <= ?", model.SubscriptionStatusActive, now).
+		Order("current_period_end ASC").
+		Limit(dao.Limit).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetStartedBefore returns every subscription created before until that was not
+// left pending, with its plan, for reports over the whole subscription history.
+func (dao *SubscriptionDao) GetStartedBefore(until time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	err := dao.db().Preload("Plan.Prices").
+		Where("status <> ? AND created_at < ?", model.SubscriptionStatusPending, until).
+		Order("created_at ASC").
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// trialing restricts a query to the subscriptions of organizations still in their trial.
+func (dao *SubscriptionDao) trialing() *gorm.DB {
+	return dao.db().
+		Joins("JOIN organizations ON organizations.id = subscriptions.organization_id").
+		Where("subscriptions.status = ? AND subscriptions.trial_end IS NOT NULL AND organizations.subscription_status = ?",
+			model.SubscriptionStatusActive, model.OrganizationSubscriptionStatusTrialing)
+}
+
+// GetTrialsToRemind returns trials ending between now and until whose reminder was not sent yet.
+func (dao *SubscriptionDao) GetTrialsToRemind(now, until time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	err := dao.trialing().Preload("Plan.Prices").
+		Where("subscriptions.trial_end > ? AND subscriptions.trial_end <= ? AND subscriptions.trial_reminder_sent_at IS NULL", now, until).
+		Order("subscriptions.trial_end ASC").
+		Limit(dao.Limit).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetEndedTrials returns trials that ended at or before now and were not cancelled.
+func (dao *SubscriptionDao) GetEndedTrials(now time.Time) ([]model.Subscription, error) {
+	var subscriptions []model.Subscription
+	err := dao.trialing().Preload("Plan.Prices").
+		Where("subscriptions.trial_end <= ? AND subscriptions.cancel_at_period_end = ?", now, false).
+		Order("subscriptions.trial_end ASC").
+		Limit(dao.Limit).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetTrialsEnding returns a page of the trials ending between now and until, soonest first.
+func (dao *SubscriptionDao) GetTrialsEnding(now, until time.Time, page int) ([]model.Subscription, int64, error) {
+	var subscriptions []model.Subscription
+	var total int64
+
+	err := dao.trialing().Model(&model.Subscription{}).
+		Where("subscriptions.trial_end > ? AND subscriptions.trial_end <= ?", now, until).
+		Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	offset := page * dao.Limit
+	err = dao.trialing().Preload("Organization.Creator").Preload("Plan.Prices").
+		Where("subscriptions.trial_end > ? AND subscriptions.trial_end <= ?", now, until).
+		Order("subscriptions.trial_end ASC").
+		Offset(offset).Limit(dao.Limit).
+		Find(&subscriptions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return subscriptions, total, nil
+}
+
+func (dao *SubscriptionDao) MarkTrialReminderSent(id uuid.UUID, at time.Time) error {
+	return dao.db().Model(&model.Subscription{}).Where("id = ?", id).Update("trial_reminder_sent_at", at).Error
+}
+
+// GetForUpdate loads the subscription and locks its row until the transaction ends.
+func (dao *SubscriptionDao) GetForUpdate(id uuid.UUID) (*model.Subscription, error) {
+	var subscription model.Subscription
+	err := dao.db().Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Plan.Prices").First(&subscription, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+func (dao *SubscriptionDao) ClearScheduledChange(id uuid.UUID) error {
+	return dao.db().Model(&model.Subscription{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"scheduled_plan_id":       nil,
+			"scheduled_billing_cycle": nil,
+		}).Error
+}
+
 func (dao *SubscriptionDao) Update(subscription *model.Subscription) error {
-	return Database.Save(subscription).Error
+	return dao.db().Save(subscription).Error
 }
 
 func (dao *SubscriptionDao) UpdateStatus(id uuid.UUID, status model.SubscriptionStatus) error {
-	return Database.Model(&model.Subscription{}).
+	return dao.db().Model(&model.Subscription{}).
 		Where("id = ?", id).
 		Update("status", status).Error
 }
@@ -79,20 +243,20 @@ func (dao *SubscriptionDao) Cancel(id uuid.UUID, cancelAtPeriodEnd bool) error {
 		updates["status"] = model.SubscriptionStatusCancelled
 		updates["cancelled_at"] = "NOW()"
 	}
-	return Database.Model(&model.Subscription{}).Where("id = ?", id).Updates(updates).Error
+	return dao.db().Model(&model.Subscription{}).Where("id = ?", id).Updates(updates).Error
 }
 
 func (dao *SubscriptionDao) GetAll(page int) ([]model.Subscription, int64, error) {
 	var subscriptions []model.Subscription
 	var total int64
 
-	err := Database.Model(&model.Subscription{}).Count(&total).Error
+	err := dao.db().Model(&model.Subscription{}).Count(&total).Error
 	if err != nil {
 		return nil, 0, err
 	}
 
 	offset := page * dao.Limit
-	err = Database.Preload("Organization").Preload("Plan").
+	err = dao.db().Preload("Organization").Preload("Plan.Prices").
 		Offset(offset).Limit(dao.Limit).
 		Find(&subscriptions).Error
 	if err != nil {
@@ -103,5 +267,5 @@ func (dao *SubscriptionDao) GetAll(page int) ([]model.Subscription, int64, error
 }
 
 func (dao *SubscriptionDao) Delete(id uuid.UUID) error {
-	return Database.Delete(&model.Subscription{}, "id = ?", id).Error
+	return dao.db().Delete(&model.Subscription{}, "id = ?", id).Error
 }
@@ -0,0 +1,39 @@
+package dao
+
+import (
+	"testlake/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type UsageAlertDao struct {
+	Limit int
+	tx    *gorm.DB
+}
+
+func NewUsageAlertDao() *UsageAlertDao {
+	return &UsageAlertDao{Limit: 50}
+}
+
+// WithTx returns a copy of the dao that runs its queries inside tx.
+func (dao *UsageAlertDao) WithTx(tx *gorm.DB) *UsageAlertDao {
+	return &UsageAlertDao{Limit: dao.Limit, tx: tx}
+}
+
+func (dao *UsageAlertDao) db() *gorm.DB {
+	if dao.tx != nil {
+		return dao.tx
+	}
+	return Database
+}
+
+// Claim records the alert unless the organization was already alerted about
+// the threshold in the same period. It reports whether the alert is new.
+func (dao *UsageAlertDao) Claim(alert *model.UsageAlert) (bool, error) {
+	result := dao.db().Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
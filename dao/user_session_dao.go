@@ -0,0 +1,82 @@
+package dao
+
+import (
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserSessionDao struct {
+	Limit int
+}
+
+func NewUserSessionDao() *UserSessionDao {
+	return &UserSessionDao{Limit: 50}
+}
+
+// MemberActivity summarises the sessions of a single user
+type MemberActivity struct {
+	UserID         uuid.UUID
+	LastSeenAt     *time.Time
+	ActiveSessions int64
+}
+
+func (dao *UserSessionDao) Create(session *model.UserSession) error {
+	return Database.Create(session).Error
+}
+
+func (dao *UserSessionDao) GetByID(id uuid.UUID) (*model.UserSession, error) {
+	var session model.UserSession
+	err := Database.First(&session, "id = ?", id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveByUserID returns the sessions that are neither revoked nor expired
+func (dao *UserSessionDao) GetActiveByUserID(userID uuid.UUID) ([]model.UserSession, error) {
+	var sessions []model.UserSession
+	err := Database.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records activity on a session and extends it to the given expiry when later
+func (dao *UserSessionDao) Touch(id uuid.UUID, expiresAt *time.Time) error {
+	updates := map[string]interface{}{
+		"last_seen_at": time.Now(),
+	}
+	if expiresAt != nil {
+		updates["expires_at"] = *expiresAt
+	}
+	return Database.Model(&model.UserSession{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (dao *UserSessionDao) Revoke(id uuid.UUID) error {
+	return Database.Model(&model.UserSession{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+// GetMemberActivity returns the last activity and active session count for each user
+func (dao *UserSessionDao) GetMemberActivity(userIDs []uuid.UUID) (map[uuid.UUID]MemberActivity, error) {
+	var rows []MemberActivity
+	err := Database.Model(&model.UserSession{}).
+		Select("user_id, MAX(last_seen_at) AS last_seen_at, "+
+			"COUNT(CASE WHEN revoked_at IS NULL AND expires_at > ? THEN 1 END) AS active_sessions", time.Now()).
+		Where("user_id IN ?", userIDs).
+		Group("user_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	activity := make(map[uuid.UUID]MemberActivity, len(rows))
+	for _, row := range rows {
+		activity[row.UserID] = row
+	}
+	return activity, nil
+}
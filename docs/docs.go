@@ -15,33 +15,25 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/v1/auth/forgot-password": {
-            "post": {
-                "description": "Send password reset email to user",
-                "consumes": [
-                    "application/json"
-                ],
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Public keys used to verify TestLake access tokens, identified by the kid token header",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
                     "Authentication"
                 ],
-                "summary": "Request password reset",
-                "parameters": [
-                    {
-                        "description": "Email for password reset",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.ForgotPasswordRequest"
-                        }
-                    }
-                ],
+                "summary": "JSON Web Key Set",
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.JWKSOut"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -49,14 +41,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/auth/refresh": {
-            "post": {
+        "/api/v1/admin/billing-events": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Generate a new JWT token from valid existing token",
+                "description": "Get the billing event log across organizations, newest first, for support investigations: every change to subscriptions, invoices, payments, payment methods and billing details, with who made it and the data recorded with it. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -64,9 +56,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Authentication"
+                    "Admin"
                 ],
-                "summary": "Refresh JWT token",
+                "summary": "Get billing events of all organizations",
                 "parameters": [
                     {
                         "type": "string",
@@ -75,51 +67,71 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "organization_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Event types, comma separated",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest time, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest day (YYYY-MM-DD, inclusive) or time (RFC 3339, exclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "user, admin, paypal or system",
+                        "name": "actor_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User who caused the events",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/auth.RefreshTokenOut"
+                            "$ref": "#/definitions/admin.BillingEventListOut"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/v1/auth/resend-email-confirmation": {
-            "post": {
-                "description": "Resend email confirmation to user",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Authentication"
-                ],
-                "summary": "Resend email confirmation",
-                "parameters": [
-                    {
-                        "description": "Email to resend confirmation",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/auth.ResendEmailConfirmationRequest"
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -127,9 +139,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/auth/reset-password": {
-            "post": {
-                "description": "Reset password with valid reset token",
+        "/api/v1/admin/coupons": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the coupons, newest first. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -137,9 +154,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Authentication"
+                    "Admin"
                 ],
-                "summary": "Reset user password",
+                "summary": "Get coupons",
                 "parameters": [
                     {
                         "type": "string",
@@ -150,28 +167,46 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Password reset data",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/auth.ResetPasswordRequest"
-                        }
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/coupon.CouponListOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
                     }
                 }
-            }
-        },
-        "/api/v1/auth/signin": {
+            },
             "post": {
-                "description": "Authenticate user with email and password, returns JWT token",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a discount code taking percent_off percent or amount_off in its currency off each period it discounts: the first one (once), duration_in_cycles of them (repeating) or all of them (forever). Codes are upper-cased; max_redemptions, expires_at and plan_ids optionally limit who can redeem it. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -179,25 +214,39 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Authentication"
+                    "Admin"
                 ],
-                "summary": "User login",
+                "summary": "Create a coupon",
                 "parameters": [
                     {
-                        "description": "Login credentials",
-                        "name": "credentials",
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Coupon",
+                        "name": "coupon",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/auth.SignInRequest"
+                            "$ref": "#/definitions/coupon.CreateCouponRequest"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/auth.SignInOut"
+                            "$ref": "#/definitions/coupon.CouponOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "401": {
@@ -205,13 +254,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/auth/signout": {
+        "/api/v1/admin/coupons/{id}/deactivate": {
             "post": {
-                "description": "Sign out current user (invalidate JWT token)",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stop a coupon from being redeemed. Discounts it already gave keep running for their duration. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -219,9 +285,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Authentication"
+                    "Admin"
                 ],
-                "summary": "User logout",
+                "summary": "Deactivate a coupon",
                 "parameters": [
                     {
                         "type": "string",
@@ -230,51 +296,42 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Coupon ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/coupon.CouponOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    }
-                }
-            }
-        },
-        "/api/v1/auth/signup": {
-            "post": {
-                "description": "Create a new user account with email and password",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Authentication"
-                ],
-                "summary": "User registration",
-                "parameters": [
-                    {
-                        "description": "Registration data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
+                    },
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/auth.SignUpRequest"
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    }
-                ],
-                "responses": {
-                    "201": {
-                        "description": "Created",
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/auth.SignUpOut"
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "404": {
+                        "description": "Not Found",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -282,9 +339,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/auth/verify-email/{token}": {
+        "/api/v1/admin/coupons/{id}/redemptions": {
             "get": {
-                "description": "Verify user email with verification token",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the organizations that redeemed a coupon, with the periods it discounted for each. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -292,46 +354,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Authentication"
+                    "Admin"
                 ],
-                "summary": "Verify email address",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Email verification token",
-                        "name": "token",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    }
-                }
-            }
-        },
-        "/api/v1/invoices/{id}": {
-            "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get a specific invoice by ID",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Billing"
-                ],
-                "summary": "Get invoice",
+                "summary": "Get coupon redemptions",
                 "parameters": [
                     {
                         "type": "string",
@@ -343,17 +368,23 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Invoice ID",
+                        "description": "Coupon ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/billing.InvoiceOut"
+                            "$ref": "#/definitions/coupon.RedemptionListOut"
                         }
                     },
                     "400": {
@@ -383,14 +414,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/invoices/{id}/download": {
+        "/api/v1/admin/invoices/{id}/deliveries": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Download a specific invoice",
+                "description": "List every attempt at emailing an invoice to the billing email and billing contacts, newest first, with the error of those that failed. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -398,9 +429,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Billing"
+                    "Admin"
                 ],
-                "summary": "Download invoice",
+                "summary": "Get invoice deliveries",
                 "parameters": [
                     {
                         "type": "string",
@@ -419,8 +450,11 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "302": {
-                        "description": "Redirect to invoice PDF"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/admin.InvoiceDeliveryListOut"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -449,14 +483,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/invoices/{id}/pay": {
+        "/api/v1/admin/payments/{id}/refund": {
             "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Process payment for an invoice",
+                "description": "Refund a payment in full, or in part when amount is given, through the payment provider. The refund is documented by a credit note against the invoice the payment settled and never exceeds what is left to refund of the payment or invoice. Answers 202 while the provider has not confirmed the refund. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -464,9 +498,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Billing"
+                    "Admin"
                 ],
-                "summary": "Pay invoice",
+                "summary": "Refund a payment",
                 "parameters": [
                     {
                         "type": "string",
@@ -478,18 +512,18 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Invoice ID",
+                        "description": "Payment ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Payment data",
-                        "name": "payment",
+                        "description": "Refund",
+                        "name": "refund",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/billing.PayInvoiceRequest"
+                            "$ref": "#/definitions/admin.RefundPaymentRequest"
                         }
                     }
                 ],
@@ -497,7 +531,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/billing.PaymentOut"
+                            "$ref": "#/definitions/billing.CreditNoteOut"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/billing.CreditNoteOut"
                         }
                     },
                     "400": {
@@ -523,18 +563,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations": {
+        "/api/v1/admin/plans": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get paginated list of organizations for current user",
+                "description": "List every version of every plan, retired ones included, by slug and newest version first. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -542,9 +588,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Admin"
                 ],
-                "summary": "Get organizations",
+                "summary": "List the plan catalogue",
                 "parameters": [
                     {
                         "type": "string",
@@ -556,7 +602,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "integer",
-                        "description": "Page number (default 0)",
+                        "description": "Page number",
                         "name": "page",
                         "in": "query"
                     }
@@ -565,7 +611,13 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/organization.OrganizationListOut"
+                            "$ref": "#/definitions/plan.CataloguePlanListOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "401": {
@@ -573,6 +625,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             },
@@ -582,7 +640,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new organization",
+                "description": "Add a plan to the catalogue, priced in USD by price_monthly and price_yearly and in other currencies by prices, and create its PayPal product and billing plans. When PayPal fails the plan is still created, without PayPal plan IDs, and the sync endpoint retries. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -590,9 +648,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Admin"
                 ],
-                "summary": "Create organization",
+                "summary": "Create a plan",
                 "parameters": [
                     {
                         "type": "string",
@@ -603,12 +661,12 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "description": "Organization data",
-                        "name": "organization",
+                        "description": "Plan",
+                        "name": "plan",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/organization.CreateOrganizationRequest"
+                            "$ref": "#/definitions/plan.CreatePlanRequest"
                         }
                     }
                 ],
@@ -616,7 +674,7 @@ const docTemplate = `{
                     "201": {
                         "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/organization.OrganizationOut"
+                            "$ref": "#/definitions/plan.CataloguePlanOut"
                         }
                     },
                     "400": {
@@ -630,18 +688,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}": {
+        "/api/v1/admin/plans/{id}": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get organization details by ID",
+                "description": "Get a plan version with all its prices, PayPal plan IDs and the number of active subscriptions on it. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -649,9 +719,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Admin"
                 ],
-                "summary": "Get organization",
+                "summary": "Get a plan version",
                 "parameters": [
                     {
                         "type": "string",
@@ -663,7 +733,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -673,7 +743,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/organization.OrganizationOut"
+                            "$ref": "#/definitions/plan.CataloguePlanOut"
                         }
                     },
                     "400": {
@@ -708,7 +778,7 @@ const docTemplate = `{
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update organization details",
+                "description": "Edit a plan's name, description, limits and features in place, which applies to its subscribers too. Prices only change with a new version. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -716,9 +786,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Admin"
                 ],
-                "summary": "Update organization",
+                "summary": "Update a plan",
                 "parameters": [
                     {
                         "type": "string",
@@ -730,18 +800,18 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Organization update data",
-                        "name": "organization",
+                        "description": "Fields to change",
+                        "name": "plan",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/organization.UpdateOrganizationRequest"
+                            "$ref": "#/definitions/plan.UpdatePlanRequest"
                         }
                     }
                 ],
@@ -749,7 +819,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/organization.OrganizationOut"
+                            "$ref": "#/definitions/plan.CataloguePlanOut"
                         }
                     },
                     "400": {
@@ -777,14 +847,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/admin/plans/{id}/retire": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Soft delete an organization",
+                "description": "Take a plan off the catalogue and deactivate its PayPal plans. Its subscribers keep being billed at its price until they change plan. The free plan cannot be retired. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -792,9 +864,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Admin"
                 ],
-                "summary": "Delete organization",
+                "summary": "Retire a plan",
                 "parameters": [
                     {
                         "type": "string",
@@ -806,7 +878,7 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -816,7 +888,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/plan.CataloguePlanOut"
                         }
                     },
                     "400": {
@@ -842,18 +914,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/billing/history": {
-            "get": {
+        "/api/v1/admin/plans/{id}/sync": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get billing history for an organization",
+                "description": "Create the PayPal product of a plan and a PayPal billing plan for each paid price that has none yet. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -861,9 +939,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Billing"
+                    "Admin"
                 ],
-                "summary": "Get billing history",
+                "summary": "Sync a plan with PayPal",
                 "parameters": [
                     {
                         "type": "string",
@@ -875,23 +953,17 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/billing.BillingHistoryOut"
+                            "$ref": "#/definitions/plan.CataloguePlanOut"
                         }
                     },
                     "400": {
@@ -911,18 +983,36 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/billing/overview": {
-            "get": {
+        "/api/v1/admin/plans/{id}/versions": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get billing overview for an organization",
+                "description": "Replace the current version of a plan with a new one, e.g. at new prices, copying the fields left out. New subscribers get the new version; the current one is retired and its subscribers keep their grandfathered price. Its PayPal plans are deactivated and new ones created. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -930,9 +1020,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Billing"
+                    "Admin"
                 ],
-                "summary": "Get billing overview",
+                "summary": "Create a plan version",
                 "parameters": [
                     {
                         "type": "string",
@@ -944,17 +1034,26 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Changes of the new version",
+                        "name": "version",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/plan.CreatePlanVersionRequest"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "Created",
                         "schema": {
-                            "$ref": "#/definitions/billing.BillingOverviewOut"
+                            "$ref": "#/definitions/plan.CataloguePlanOut"
                         }
                     },
                     "400": {
@@ -974,28 +1073,41 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/invite": {
-            "post": {
+        "/api/v1/admin/revenue": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Invite a user to join the organization",
+                "description": "Monthly revenue metrics per currency: MRR and ARR, new and churned subscriptions, churn rate, revenue recognized over the billing periods of paid invoices less refunds, and payments collected. Months are calendar months in UTC. Answers a CSV file with format=csv. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "text/csv"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Admin"
                 ],
-                "summary": "Invite member",
+                "summary": "Get revenue report",
                 "parameters": [
                     {
                         "type": "string",
@@ -1007,26 +1119,28 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "First month, YYYY-MM (default 11 months ago)",
+                        "name": "from",
+                        "in": "query"
                     },
                     {
-                        "description": "Invitation data",
-                        "name": "invite",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/organization.InviteMemberRequest"
-                        }
+                        "type": "string",
+                        "description": "Last month, YYYY-MM (default the current month)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv for a CSV file",
+                        "name": "format",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/organization.InviteOut"
+                            "$ref": "#/definitions/admin.RevenueReportOut"
                         }
                     },
                     "400": {
@@ -1046,24 +1160,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/invites": {
+        "/api/v1/admin/trials/ending": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get list of pending invitations for the organization",
+                "description": "Report of the trials ending within the next days, for the sales team. Platform admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -1071,9 +1179,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Admin"
                 ],
-                "summary": "Get pending invites",
+                "summary": "Get trials ending soon",
                 "parameters": [
                     {
                         "type": "string",
@@ -1084,18 +1192,23 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "Days ahead, 1 to 90 (default 7)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/organization.PendingInvitesOut"
+                            "$ref": "#/definitions/admin.EndingTrialListOut"
                         }
                     },
                     "400": {
@@ -1115,24 +1228,3334 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/invoices": {
+        "/api/v1/auth/confirm-email-change/{token}": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
+                "description": "Apply a pending email change with the token sent to the new address",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Confirm email change",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email change token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "HTML page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "HTML page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/forgot-password": {
+            "post": {
+                "description": "Send password reset email to user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Request password reset",
+                "parameters": [
+                    {
+                        "description": "Email for password reset",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.ForgotPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/refresh": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Generate a new JWT token from valid existing token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Refresh JWT token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.RefreshTokenOut"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/resend-email-confirmation": {
+            "post": {
+                "description": "Resend email confirmation to user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Resend email confirmation",
+                "parameters": [
+                    {
+                        "description": "Email to resend confirmation",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.ResendEmailConfirmationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/reset-password": {
+            "post": {
+                "description": "Reset password with valid reset token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Reset user password",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Password reset data",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.ResetPasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/signin": {
+            "post": {
+                "description": "Authenticate user with email and password, returns JWT token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "User login",
+                "parameters": [
+                    {
+                        "description": "Login credentials",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.SignInRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.SignInOut"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/signout": {
+            "post": {
+                "description": "Sign out current user (invalidate JWT token)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "User logout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/signup": {
+            "post": {
+                "description": "Create a new user account with email and password",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "User registration",
+                "parameters": [
+                    {
+                        "description": "Registration data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.SignUpRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/auth.SignUpOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/sso/callback": {
+            "get": {
+                "description": "Exchange the authorization code, provision the user into the organization on first sign-in and return a JWT token. When SSO_FRONTEND_CALLBACK_URL is set the browser is redirected there with the token in the URL fragment instead.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Complete SSO sign-in",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Authorization code",
+                        "name": "code",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "State returned by the identity provider",
+                        "name": "state",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.SignInOut"
+                        }
+                    },
+                    "302": {
+                        "description": "Found"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/sso/discover": {
+            "post": {
+                "description": "Find the organization that handles single sign-on for the email's domain and return the identity provider authorization URL",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Discover SSO for an email",
+                "parameters": [
+                    {
+                        "description": "Email address",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/auth.SSODiscoverRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/auth.SSODiscoverOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/sso/{slug}/login": {
+            "get": {
+                "description": "Redirect the browser to the organization's OIDC identity provider",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Start SSO sign-in",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Organization slug",
+                        "name": "slug",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Email to pre-fill at the identity provider",
+                        "name": "login_hint",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "302": {
+                        "description": "Found"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/unlock-account/{token}": {
+            "get": {
+                "description": "Lift a temporary sign-in lockout using the signed link sent by email",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Unlock account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Signed unlock token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "HTML page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "HTML page",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/auth/verify-email/{token}": {
+            "get": {
+                "description": "Verify user email with verification token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Authentication"
+                ],
+                "summary": "Verify email address",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Email verification token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/credit-notes/{id}/download": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the PDF of an issued credit note, named after its credit note number. The PDF never changes once rendered",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Download credit note",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Credit note ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Credit note PDF",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/invoices/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get a specific invoice by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Get invoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.InvoiceOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/invoices/{id}/download": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Download the PDF of an issued invoice, named after its invoice number. The PDF of a paid invoice never changes; the ETag header allows conditional requests",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/pdf"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Download invoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Invoice PDF",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/invoices/{id}/pay": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Pay an invoice through the payment provider. Without order_id a checkout order is created and its approval_url returned (202); call again with order_id once approved to capture it. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Pay invoice",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invoice ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment data",
+                        "name": "payment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/billing.PayInvoiceRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.PaymentOut"
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/billing.PaymentOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get paginated list of organizations for current user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Get organizations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default 0)",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.OrganizationListOut"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Create organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Organization data",
+                        "name": "organization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/organization.CreateOrganizationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/organization.OrganizationOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get organization details by ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Get organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.OrganizationOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update organization details",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Update organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Organization update data",
+                        "name": "organization",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/organization.UpdateOrganizationRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.OrganizationOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Soft delete an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Delete organization",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/billing-contacts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "List the addresses invoices, receipts and payment failures are emailed to besides the billing email (creator or admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "List billing contacts",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.BillingContactsOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Add an address invoices, receipts and payment failures are emailed to, with the invoice PDF attached (creator or admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Add a billing contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Billing contact",
+                        "name": "contact",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/organization.AddBillingContactRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/organization.BillingContactOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/billing-contacts/{contactId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Stop emailing the billing emails to a billing contact (creator or admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Remove a billing contact",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Billing contact ID",
+                        "name": "contactId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/billing-details": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the billing email, address and tax ID invoices are addressed to, with the tax they are charged (creator or admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Get billing details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.BillingDetailsOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Replace the billing email, address and tax ID (creator or admin only). Invoices are taxed at the VAT rate of the billing country; businesses with a valid VAT ID in another country are reverse charged. The tax applies to invoices issued from now on.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Update billing details",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Billing details",
+                        "name": "details",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/organization.UpdateBillingDetailsRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.BillingDetailsOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/billing/events": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the billing event log of an organization, newest first: every change to its subscription, invoices, payments, payment methods and billing details, with who made it and the data recorded with it (the webhook body for PayPal events). Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Get billing events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Event types, comma separated",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Earliest time, YYYY-MM-DD or RFC 3339",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Latest day (YYYY-MM-DD, inclusive) or time (RFC 3339, exclusive)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "user, admin, paypal or system",
+                        "name": "actor_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "User who caused the events",
+                        "name": "actor_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.BillingEventListOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/billing/forecast": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Forecast of the invoices the organization is expected to be issued over the next months: subscription renewals, the first period of a scheduled plan change and the conversion of its trial, less coupon discounts and plus tax. Answers a CSV file with format=csv",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json",
+                    "text/csv"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Get billing forecast",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Months ahead, 1 to 24 (default 3)",
+                        "name": "months",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "csv for a CSV file",
+                        "name": "format",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.BillingForecastOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/billing/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get billing history for an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Get billing history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.BillingHistoryOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/billing/overview": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get billing overview for an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Get billing overview",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.BillingOverviewOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/credit-notes": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the credit notes of an organization, one per refund, newest first",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Get credit notes",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.CreditNoteListOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/invite": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Invite a user to join the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Invite member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Invitation data",
+                        "name": "invite",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/organization.InviteMemberRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.InviteOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/utils.LimitExceededError"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/invites": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get list of pending invitations for the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Get pending invites",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.PendingInvitesOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/invoices": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get invoices for an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Get invoices",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/billing.InvoiceListOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/limits/check": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Compare the organization's live usage with its plan limits. With a resource, tell whether count more of it fit and which plans would allow them.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Check plan limits",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "users",
+                            "projects",
+                            "environments",
+                            "schemas",
+                            "test_records_per_schema"
+                        ],
+                        "type": "string",
+                        "description": "Resource to check",
+                        "name": "resource",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "How many more of the resource (default: 1)",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.LimitsCheckOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/members": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get list of organization members",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Get organization members",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.MembersOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/members/activity": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get each member's last sign-in, last activity and active session count (creator or admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Get members' last activity",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.MembersActivityOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/members/{userId}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove a member from the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Remove member",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID to remove",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/members/{userId}/role": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update a member's role in the organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Update member role",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "User ID",
+                        "name": "userId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Role update data",
+                        "name": "role",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/organization.UpdateMemberRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/payment-methods": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all payment methods for an organization",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment Methods"
+                ],
+                "summary": "Get payment methods",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentMethodListOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Start saving a PayPal account as payment method. The method is pending until the payer approves it at approval_url and it is confirmed. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment Methods"
+                ],
+                "summary": "Create payment method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentMethodOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/payment-methods/{pmId}": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Update an existing payment method; only a verified method can be made the default. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment Methods"
+                ],
+                "summary": "Update payment method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment Method ID",
+                        "name": "pmId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Payment method data",
+                        "name": "paymentMethod",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/payment.UpdatePaymentMethodRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentMethodOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Delete a payment method and its PayPal vault token. The default method of an organization with a paid subscription cannot be deleted while it has no other verified method. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment Methods"
+                ],
+                "summary": "Delete payment method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment Method ID",
+                        "name": "pmId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/payment-methods/{pmId}/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Save a pending payment method the payer approved with PayPal. It becomes the default when requested or when the organization has no other verified method. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment Methods"
+                ],
+                "summary": "Confirm payment method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment Method ID",
+                        "name": "pmId",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Confirmation options",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/payment.ConfirmPaymentMethodRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/payment.PaymentMethodOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/payment-methods/{pmId}/set-default": {
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Set a verified payment method as the default for the organization. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Payment Methods"
+                ],
+                "summary": "Set default payment method",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Payment Method ID",
+                        "name": "pmId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/sso": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get the organization's OIDC identity provider configuration (creator or admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Get SSO configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.SSOConfigOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Register or replace the organization's OIDC identity provider (enterprise plans, creator or admin only). The issuer must serve a discovery document.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Configure SSO",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Identity provider configuration",
+                        "name": "config",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/organization.SSOConfigRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.SSOConfigOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Remove the organization's identity provider; members sign in with their password again (creator or admin only)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Delete SSO configuration",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/sso/domains/{domain}/verify": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Check that the domain publishes the TXT record listed in the SSO configuration. Logins for the domain are only routed to the organization once it is verified (creator or admin only).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Organization Management"
+                ],
+                "summary": "Verify an SSO domain",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Allowed domain",
+                        "name": "domain",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/organization.SSOConfigOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/subscription": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
                 ],
-                "description": "Get invoices for an organization",
+                "description": "Get current subscription for an organization",
                 "consumes": [
                     "application/json"
                 ],
@@ -1140,9 +4563,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Billing"
+                    "Subscriptions"
                 ],
-                "summary": "Get invoices",
+                "summary": "Get organization subscription",
                 "parameters": [
                     {
                         "type": "string",
@@ -1158,19 +4581,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Page number",
-                        "name": "page",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/billing.InvoiceListOut"
+                            "$ref": "#/definitions/subscription.SubscriptionOut"
                         }
                     },
                     "400": {
@@ -1190,18 +4607,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/members": {
-            "get": {
+        "/api/v1/organizations/{id}/subscription/cancel": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get list of organization members",
+                "description": "Cancel an organization's subscription. Organization owners and admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -1209,9 +4632,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Subscriptions"
                 ],
-                "summary": "Get organization members",
+                "summary": "Cancel subscription",
                 "parameters": [
                     {
                         "type": "string",
@@ -1233,7 +4656,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/organization.MembersOut"
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "400": {
@@ -1259,18 +4682,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/members/{userId}": {
-            "delete": {
+        "/api/v1/organizations/{id}/subscription/change-plan": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Remove a member from the organization",
+                "description": "Change the plan for an existing subscription. Upgrades apply at once and return a proration invoice for the rest of the period; downgrades are scheduled for the end of the period and rejected with 409 when current usage exceeds the new plan's limits. coupon_code redeems a coupon for the new plan: it discounts the proration charge of an upgrade and the periods that follow. An upgrade with something to prorate needs a default payment method to charge, else 402. Organization owners and admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -1278,9 +4707,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Subscriptions"
                 ],
-                "summary": "Remove member",
+                "summary": "Change subscription plan",
                 "parameters": [
                     {
                         "type": "string",
@@ -1298,18 +4727,20 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "User ID to remove",
-                        "name": "userId",
-                        "in": "path",
-                        "required": true
+                        "description": "Plan change data",
+                        "name": "plan",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/subscription.ChangePlanRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/subscription.SubscriptionOut"
                         }
                     },
                     "400": {
@@ -1324,6 +4755,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
+                    "402": {
+                        "description": "Payment Required",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
                     "403": {
                         "description": "Forbidden",
                         "schema": {
@@ -1335,18 +4772,30 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/subscription.PlanLimitsExceededOut"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/members/{userId}/role": {
-            "put": {
+        "/api/v1/organizations/{id}/subscription/coupons": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update a member's role in the organization",
+                "description": "List the coupons the organization redeemed, with the billing periods each one discounted and has left",
                 "consumes": [
                     "application/json"
                 ],
@@ -1354,9 +4803,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Organization Management"
+                    "Subscriptions"
                 ],
-                "summary": "Update member role",
+                "summary": "Get coupon redemptions",
                 "parameters": [
                     {
                         "type": "string",
@@ -1372,29 +4821,166 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/subscription.CouponRedemptionsOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/subscription/create": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Create a new subscription for an organization. Paid plans stay pending until the payer approves them at approval_url, unless start_trial starts a free trial, once per organization, that is charged to the default payment method when it ends. coupon_code redeems a coupon discounting the paid periods. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Create subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "User ID",
-                        "name": "userId",
+                        "description": "Organization ID",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Role update data",
-                        "name": "role",
+                        "description": "Subscription data",
+                        "name": "subscription",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/organization.UpdateMemberRoleRequest"
+                            "$ref": "#/definitions/subscription.CreateSubscriptionRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/subscription.SubscriptionOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     }
+                }
+            }
+        },
+        "/api/v1/organizations/{id}/subscription/reactivate": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Reactivate a cancelled subscription. Organization owners and admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Subscriptions"
+                ],
+                "summary": "Reactivate subscription",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Organization ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/subscription.SubscriptionOut"
                         }
                     },
                     "400": {
@@ -1420,18 +5006,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "502": {
+                        "description": "Bad Gateway",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/payment-methods": {
-            "get": {
+        "/api/v1/organizations/{id}/subscription/scheduled-change": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all payment methods for an organization",
+                "description": "Drop a downgrade scheduled for the end of the current period. Organization owners and admins only",
                 "consumes": [
                     "application/json"
                 ],
@@ -1439,9 +5031,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment Methods"
+                    "Subscriptions"
                 ],
-                "summary": "Get payment methods",
+                "summary": "Cancel scheduled plan change",
                 "parameters": [
                     {
                         "type": "string",
@@ -1463,7 +5055,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/payment.PaymentMethodListOut"
+                            "$ref": "#/definitions/subscription.SubscriptionOut"
                         }
                     },
                     "400": {
@@ -1483,16 +5075,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
-            },
-            "post": {
+            }
+        },
+        "/api/v1/organizations/{id}/subscription/usage": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new payment method for an organization",
+                "description": "Get current usage metrics for an organization's subscription",
                 "consumes": [
                     "application/json"
                 ],
@@ -1500,9 +5100,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment Methods"
+                    "Subscriptions"
                 ],
-                "summary": "Create payment method",
+                "summary": "Get subscription usage",
                 "parameters": [
                     {
                         "type": "string",
@@ -1518,22 +5118,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Payment method data",
-                        "name": "paymentMethod",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/payment.CreatePaymentMethodRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/payment.PaymentMethodOut"
+                            "$ref": "#/definitions/subscription.SubscriptionUsageOut"
                         }
                     },
                     "400": {
@@ -1557,14 +5148,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/organizations/{id}/payment-methods/{pmId}": {
-            "put": {
+        "/api/v1/organizations/{id}/usage/current": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Update an existing payment method",
+                "description": "Get the organization's usage of the current month: live resource counts and metered API requests, against its plan limits",
                 "consumes": [
                     "application/json"
                 ],
@@ -1572,9 +5163,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment Methods"
+                    "Usage"
                 ],
-                "summary": "Update payment method",
+                "summary": "Get current usage",
                 "parameters": [
                     {
                         "type": "string",
@@ -1590,29 +5181,13 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Payment Method ID",
-                        "name": "pmId",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Payment method data",
-                        "name": "paymentMethod",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/payment.UpdatePaymentMethodRequest"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/payment.PaymentMethodOut"
+                            "$ref": "#/definitions/usage.CurrentUsageOut"
                         }
                     },
                     "400": {
@@ -1640,14 +5215,16 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/v1/organizations/{id}/usage/history": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete a payment method",
+                "description": "Get the organization's monthly usage records, oldest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -1655,9 +5232,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment Methods"
+                    "Usage"
                 ],
-                "summary": "Delete payment method",
+                "summary": "Get usage history",
                 "parameters": [
                     {
                         "type": "string",
@@ -1675,18 +5252,17 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Payment Method ID",
-                        "name": "pmId",
-                        "in": "path",
-                        "required": true
+                        "type": "integer",
+                        "description": "Number of months including the current one, 1 to 24 (default: 6)",
+                        "name": "months",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/usage.UsageHistoryOut"
                         }
                     },
                     "400": {
@@ -1716,14 +5292,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/organizations/{id}/payment-methods/{pmId}/set-default": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Set a payment method as the default for the organization",
+        "/api/v1/payments/paypal/webhooks": {
+            "post": {
+                "description": "Receive PayPal webhook events. The transmission signature is verified with PayPal, each event ID is processed once and the subscription, organization, invoice and payment changes are stored together with a billing event. Handled events: BILLING.SUBSCRIPTION.ACTIVATED, BILLING.SUBSCRIPTION.CANCELLED, BILLING.SUBSCRIPTION.SUSPENDED, BILLING.SUBSCRIPTION.PAYMENT.FAILED, PAYMENT.SALE.COMPLETED, PAYMENT.SALE.DENIED, INVOICING.INVOICE.PAID and INVOICING.INVOICE.CANCELLED; other events are acknowledged and ignored.",
                 "consumes": [
                     "application/json"
                 ],
@@ -1731,30 +5302,43 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Payment Methods"
+                    "Payments"
                 ],
-                "summary": "Set default payment method",
+                "summary": "PayPal webhook",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "Bearer {token}",
-                        "description": "Bearer token",
-                        "name": "Authorization",
+                        "description": "Transmission ID",
+                        "name": "PAYPAL-TRANSMISSION-ID",
                         "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
+                        "description": "Transmission time",
+                        "name": "PAYPAL-TRANSMISSION-TIME",
+                        "in": "header",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Payment Method ID",
-                        "name": "pmId",
-                        "in": "path",
+                        "description": "Transmission signature",
+                        "name": "PAYPAL-TRANSMISSION-SIG",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signing certificate URL",
+                        "name": "PAYPAL-CERT-URL",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Signature algorithm",
+                        "name": "PAYPAL-AUTH-ALGO",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -1771,20 +5355,14 @@ const docTemplate = `{
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "503": {
+                        "description": "Service Unavailable",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -1792,14 +5370,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/organizations/{id}/subscription": {
+        "/api/v1/plans": {
             "get": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Get current subscription for an organization",
+                "description": "Get all available subscription plans",
                 "consumes": [
                     "application/json"
                 ],
@@ -1807,53 +5380,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscriptions"
+                    "Plans"
                 ],
-                "summary": "Get organization subscription",
+                "summary": "Get all plans",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "Bearer {token}",
-                        "description": "Bearer token",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
+                        "description": "ISO 4217 currency to price plans in, e.g. EUR",
+                        "name": "currency",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Price plans in the currency of this organization, which the bearer token must belong to",
+                        "name": "organization_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/subscription.SubscriptionOut"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/plan.PlanListOut"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -1861,14 +5413,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/organizations/{id}/subscription/cancel": {
-            "post": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Cancel an organization's subscription",
+        "/api/v1/plans/compare": {
+            "get": {
+                "description": "Get all plans for comparison",
                 "consumes": [
                     "application/json"
                 ],
@@ -1876,53 +5423,32 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscriptions"
+                    "Plans"
                 ],
-                "summary": "Cancel subscription",
+                "summary": "Compare plans",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "Bearer {token}",
-                        "description": "Bearer token",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
+                        "description": "ISO 4217 currency to price plans in, e.g. EUR",
+                        "name": "currency",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "Price plans in the currency of this organization, which the bearer token must belong to",
+                        "name": "organization_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/plan.PlanComparisonOut"
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "500": {
+                        "description": "Internal Server Error",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -1930,14 +5456,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/organizations/{id}/subscription/change-plan": {
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Change the plan for an existing subscription",
+        "/api/v1/plans/{id}": {
+            "get": {
+                "description": "Get specific plan information by ID",
                 "consumes": [
                     "application/json"
                 ],
@@ -1945,40 +5466,35 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscriptions"
+                    "Plans"
                 ],
-                "summary": "Change subscription plan",
+                "summary": "Get plan by ID",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "Bearer {token}",
-                        "description": "Bearer token",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Organization ID",
+                        "description": "Plan ID",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "Plan change data",
-                        "name": "plan",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/subscription.ChangePlanRequest"
-                        }
+                        "type": "string",
+                        "description": "ISO 4217 currency to price plans in, e.g. EUR",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Price plans in the currency of this organization, which the bearer token must belong to",
+                        "name": "organization_id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/subscription.SubscriptionOut"
+                            "$ref": "#/definitions/plan.PlanOut"
                         }
                     },
                     "400": {
@@ -1987,18 +5503,6 @@ const docTemplate = `{
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -2008,14 +5512,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/organizations/{id}/subscription/create": {
-            "post": {
+        "/api/v1/users/account": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Create a new subscription for an organization",
+                "description": "Delete current user's account permanently",
                 "consumes": [
                     "application/json"
                 ],
@@ -2023,9 +5527,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscriptions"
+                    "User Management"
                 ],
-                "summary": "Create subscription",
+                "summary": "Delete user account",
                 "parameters": [
                     {
                         "type": "string",
@@ -2034,33 +5538,11 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "description": "Subscription data",
-                        "name": "subscription",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/subscription.CreateSubscriptionRequest"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
-                        "schema": {
-                            "$ref": "#/definitions/subscription.SubscriptionOut"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -2070,24 +5552,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/subscription/reactivate": {
-            "post": {
+        "/api/v1/users/dashboard": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Reactivate a cancelled subscription",
+                "description": "Get user dashboard with overview of projects and activities",
                 "consumes": [
                     "application/json"
                 ],
@@ -2095,9 +5571,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscriptions"
+                    "User Management"
                 ],
-                "summary": "Reactivate subscription",
+                "summary": "Get user dashboard",
                 "parameters": [
                     {
                         "type": "string",
@@ -2106,26 +5582,13 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/subscription.SubscriptionOut"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/user.DashboardOut"
                         }
                     },
                     "401": {
@@ -2133,30 +5596,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/organizations/{id}/subscription/usage": {
-            "get": {
+        "/api/v1/users/email/change": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get current usage metrics for an organization's subscription",
+                "description": "Send a confirmation link to the new email address and a security notice to the current one. The email is changed only after confirmation.",
                 "consumes": [
                     "application/json"
                 ],
@@ -2164,9 +5615,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Subscriptions"
+                    "User Management"
                 ],
-                "summary": "Get subscription usage",
+                "summary": "Request email change",
                 "parameters": [
                     {
                         "type": "string",
@@ -2177,18 +5628,20 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Organization ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
+                        "description": "New email and current password",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.ChangeEmailRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/subscription.SubscriptionUsageOut"
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "400": {
@@ -2202,19 +5655,18 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    },
-                    "403": {
-                        "description": "Forbidden",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
                     }
                 }
             }
         },
-        "/api/v1/plans": {
+        "/api/v1/users/invites": {
             "get": {
-                "description": "Get all available subscription plans",
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Get all pending organization invitations for the current user",
                 "consumes": [
                     "application/json"
                 ],
@@ -2222,47 +5674,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Plans"
+                    "User Management"
                 ],
-                "summary": "Get all plans",
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/plan.PlanListOut"
-                        }
-                    },
-                    "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
+                "summary": "Get pending invitations",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     }
-                }
-            }
-        },
-        "/api/v1/plans/compare": {
-            "get": {
-                "description": "Get all plans for comparison",
-                "consumes": [
-                    "application/json"
                 ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "Plans"
-                ],
-                "summary": "Compare plans",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/plan.PlanComparisonOut"
+                            "$ref": "#/definitions/user.PendingInvitesOut"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "401": {
+                        "description": "Unauthorized",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -2270,9 +5703,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/plans/{id}": {
-            "get": {
-                "description": "Get specific plan information by ID",
+        "/api/v1/users/invites/{token}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Accept an organization invitation using the invitation token",
                 "consumes": [
                     "application/json"
                 ],
@@ -2280,14 +5718,22 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Plans"
+                    "User Management"
                 ],
-                "summary": "Get plan by ID",
+                "summary": "Accept organization invite",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Plan ID",
-                        "name": "id",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invitation token",
+                        "name": "token",
                         "in": "path",
                         "required": true
                     }
@@ -2296,7 +5742,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/plan.PlanOut"
+                            "$ref": "#/definitions/user.AcceptInviteOut"
                         }
                     },
                     "400": {
@@ -2305,6 +5751,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
                     "404": {
                         "description": "Not Found",
                         "schema": {
@@ -2314,14 +5766,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/account": {
-            "delete": {
+        "/api/v1/users/invites/{token}/deny": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Delete current user's account permanently",
+                "description": "Decline an organization invitation using the invitation token",
                 "consumes": [
                     "application/json"
                 ],
@@ -2331,7 +5783,7 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Delete user account",
+                "summary": "Deny organization invite",
                 "parameters": [
                     {
                         "type": "string",
@@ -2340,11 +5792,24 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Invitation token",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/user.DenyInviteOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
@@ -2354,18 +5819,24 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
                     }
                 }
             }
         },
-        "/api/v1/users/dashboard": {
+        "/api/v1/users/login-history": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get user dashboard with overview of projects and activities",
+                "description": "Get the current user's recent sign-in attempts with IP, user agent, method and outcome",
                 "consumes": [
                     "application/json"
                 ],
@@ -2375,7 +5846,7 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Get user dashboard",
+                "summary": "Get login history",
                 "parameters": [
                     {
                         "type": "string",
@@ -2384,13 +5855,19 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (0-based)",
+                        "name": "page",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.DashboardOut"
+                            "$ref": "#/definitions/user.LoginHistoryOut"
                         }
                     },
                     "401": {
@@ -2402,14 +5879,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/invites": {
+        "/api/v1/users/notifications": {
             "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get all pending organization invitations for the current user",
+                "description": "Get the user's notifications, such as usage alerts of their organizations, newest first",
                 "consumes": [
                     "application/json"
                 ],
@@ -2419,7 +5896,7 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Get pending invitations",
+                "summary": "Get user notifications",
                 "parameters": [
                     {
                         "type": "string",
@@ -2428,13 +5905,25 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Page number (default: 0)",
+                        "name": "page",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.PendingInvitesOut"
+                            "$ref": "#/definitions/user.NotificationsOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "401": {
@@ -2446,14 +5935,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/invites/{token}/accept": {
-            "post": {
+        "/api/v1/users/notifications/{id}/read": {
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Accept an organization invitation using the invitation token",
+                "description": "Mark a specific notification as read",
                 "consumes": [
                     "application/json"
                 ],
@@ -2463,21 +5952,21 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Accept organization invite",
+                "summary": "Mark notification as read",
                 "parameters": [
                     {
                         "type": "string",
-                        "format": "Bearer {token}",
-                        "description": "Bearer token",
-                        "name": "Authorization",
-                        "in": "header",
+                        "description": "Notification ID",
+                        "name": "id",
+                        "in": "path",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Invitation token",
-                        "name": "token",
-                        "in": "path",
+                        "format": "Bearer {token}",
+                        "description": "Bearer token",
+                        "name": "Authorization",
+                        "in": "header",
                         "required": true
                     }
                 ],
@@ -2485,7 +5974,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.AcceptInviteOut"
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "400": {
@@ -2509,14 +5998,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/invites/{token}/deny": {
-            "post": {
+        "/api/v1/users/profile": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Decline an organization invitation using the invitation token",
+                "description": "Get current user's profile information",
                 "consumes": [
                     "application/json"
                 ],
@@ -2526,7 +6015,7 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Deny organization invite",
+                "summary": "Get user profile",
                 "parameters": [
                     {
                         "type": "string",
@@ -2535,26 +6024,13 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Invitation token",
-                        "name": "token",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.DenyInviteOut"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/user.UserOut"
                         }
                     },
                     "401": {
@@ -2562,24 +6038,16 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    },
-                    "404": {
-                        "description": "Not Found",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
                     }
                 }
-            }
-        },
-        "/api/v1/users/notifications": {
-            "get": {
+            },
+            "put": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get list of user notifications",
+                "description": "Update current user's profile information",
                 "consumes": [
                     "application/json"
                 ],
@@ -2589,7 +6057,7 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Get user notifications",
+                "summary": "Update user profile",
                 "parameters": [
                     {
                         "type": "string",
@@ -2598,13 +6066,28 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "description": "User profile data",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/user.UpdateUserRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.NotificationsOut"
+                            "$ref": "#/definitions/user.UserOut"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "401": {
@@ -2616,14 +6099,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/notifications/{id}/read": {
-            "put": {
+        "/api/v1/users/sessions": {
+            "get": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Mark a specific notification as read",
+                "description": "Get the current user's active sessions, flagging the one used by this request",
                 "consumes": [
                     "application/json"
                 ],
@@ -2633,15 +6116,8 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Mark notification as read",
+                "summary": "Get active sessions",
                 "parameters": [
-                    {
-                        "type": "string",
-                        "description": "Notification ID",
-                        "name": "id",
-                        "in": "path",
-                        "required": true
-                    },
                     {
                         "type": "string",
                         "format": "Bearer {token}",
@@ -2655,13 +6131,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
+                            "$ref": "#/definitions/user.SessionsOut"
                         }
                     },
                     "401": {
@@ -2673,14 +6143,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/v1/users/profile": {
-            "get": {
+        "/api/v1/users/sessions/{id}": {
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Get current user's profile information",
+                "description": "Revoke one of the current user's sessions; tokens issued for it stop working immediately",
                 "consumes": [
                     "application/json"
                 ],
@@ -2690,7 +6160,7 @@ const docTemplate = `{
                 "tags": [
                     "User Management"
                 ],
-                "summary": "Get user profile",
+                "summary": "Revoke session",
                 "parameters": [
                     {
                         "type": "string",
@@ -2699,13 +6169,26 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Session ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/user.UserOut"
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
                         }
                     },
                     "401": {
@@ -2713,69 +6196,282 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/inout.BaseResponse"
                         }
-                    }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/inout.BaseResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "admin.BillingEvent": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "string"
+                },
+                "actor_type": {
+                    "description": "\"user\", \"admin\", \"paypal\" or \"system\"",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BillingActorType"
+                        }
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_data": {
+                    "type": "object"
+                },
+                "event_type": {
+                    "$ref": "#/definitions/model.BillingEventType"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "organization_name": {
+                    "type": "string"
+                },
+                "organization_slug": {
+                    "type": "string"
+                },
+                "paypal_event_id": {
+                    "type": "string"
+                },
+                "processed_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "admin.BillingEventListOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/admin.BillingEvent"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
+                }
+            }
+        },
+        "admin.EndingTrial": {
+            "type": "object",
+            "properties": {
+                "billing_cycle": {
+                    "$ref": "#/definitions/model.BillingCycle"
+                },
+                "billing_email": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "days_left": {
+                    "type": "integer"
+                },
+                "has_payment_method": {
+                    "description": "the trial converts instead of moving to the free plan",
+                    "type": "boolean"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "organization_name": {
+                    "type": "string"
+                },
+                "organization_slug": {
+                    "type": "string"
+                },
+                "owner_email": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "price": {
+                    "description": "charged per cycle once the trial converts",
+                    "type": "number"
+                },
+                "reminder_sent": {
+                    "type": "boolean"
+                },
+                "subscription_id": {
+                    "type": "string"
+                },
+                "trial_ends_at": {
+                    "type": "string"
+                },
+                "trial_started_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "admin.EndingTrialListOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/admin.EndingTrial"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
+                }
+            }
+        },
+        "admin.InvoiceDelivery": {
+            "type": "object",
+            "properties": {
+                "attempted_at": {
+                    "type": "string"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "kind": {
+                    "$ref": "#/definitions/model.InvoiceEmailKind"
+                },
+                "recipient": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "sent once the mail server accepted the email",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.InvoiceDeliveryStatus"
+                        }
+                    ]
+                },
+                "subject": {
+                    "type": "string"
                 }
-            },
-            "put": {
-                "security": [
-                    {
-                        "BearerAuth": []
-                    }
-                ],
-                "description": "Update current user's profile information",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "tags": [
-                    "User Management"
-                ],
-                "summary": "Update user profile",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "format": "Bearer {token}",
-                        "description": "Bearer token",
-                        "name": "Authorization",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "description": "User profile data",
-                        "name": "user",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/user.UpdateUserRequest"
-                        }
+            }
+        },
+        "admin.InvoiceDeliveryListOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/admin.InvoiceDelivery"
                     }
-                ],
-                "responses": {
-                    "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/user.UserOut"
-                        }
-                    },
-                    "400": {
-                        "description": "Bad Request",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized",
-                        "schema": {
-                            "$ref": "#/definitions/inout.BaseResponse"
-                        }
+                }
+            }
+        },
+        "admin.RefundPaymentRequest": {
+            "type": "object",
+            "required": [
+                "reason"
+            ],
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "reason": {
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "admin.RevenueMonth": {
+            "type": "object",
+            "properties": {
+                "arr": {
+                    "type": "number"
+                },
+                "churn_rate": {
+                    "description": "percent of the paying subscriptions at the start of the month",
+                    "type": "number"
+                },
+                "churned_mrr": {
+                    "type": "number"
+                },
+                "churned_subscriptions": {
+                    "type": "integer"
+                },
+                "collected": {
+                    "description": "payments received, tax included",
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "month": {
+                    "description": "YYYY-MM",
+                    "type": "string"
+                },
+                "mrr": {
+                    "description": "at the end of the month, or now for the current one",
+                    "type": "number"
+                },
+                "new_subscriptions": {
+                    "type": "integer"
+                },
+                "paying_subscriptions": {
+                    "type": "integer"
+                },
+                "recognized_revenue": {
+                    "description": "before tax, less refunds",
+                    "type": "number"
+                },
+                "refunds": {
+                    "type": "number"
+                }
+            }
+        },
+        "admin.RevenueReportOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/admin.RevenueMonth"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
+        },
         "auth.AuthData": {
             "type": "object",
             "properties": {
@@ -2839,6 +6535,17 @@ const docTemplate = `{
                 }
             }
         },
+        "auth.JWKSOut": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.JSONWebKey"
+                    }
+                }
+            }
+        },
         "auth.RefreshTokenOut": {
             "type": "object",
             "properties": {
@@ -2880,6 +6587,48 @@ const docTemplate = `{
                 }
             }
         },
+        "auth.SSODiscoverData": {
+            "type": "object",
+            "properties": {
+                "authorization_url": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "organization_name": {
+                    "type": "string"
+                },
+                "organization_slug": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.SSODiscoverOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/auth.SSODiscoverData"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "auth.SSODiscoverRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
         "auth.SignInOut": {
             "type": "object",
             "properties": {
@@ -2925,40 +6674,145 @@ const docTemplate = `{
         },
         "auth.SignUpRequest": {
             "type": "object",
-            "required": [
-                "auth_provider",
-                "email",
-                "password",
-                "username"
-            ],
+            "required": [
+                "auth_provider",
+                "email",
+                "password",
+                "username"
+            ],
+            "properties": {
+                "auth_provider": {
+                    "$ref": "#/definitions/model.AuthProvider"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "first_name": {
+                    "type": "string"
+                },
+                "last_name": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "username": {
+                    "type": "string",
+                    "maxLength": 100,
+                    "minLength": 3
+                }
+            }
+        },
+        "auth.TokenData": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "billing.BillingEvent": {
+            "type": "object",
+            "properties": {
+                "actor_id": {
+                    "type": "string"
+                },
+                "actor_type": {
+                    "description": "\"user\", \"admin\", \"paypal\" or \"system\"",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.BillingActorType"
+                        }
+                    ]
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_data": {
+                    "type": "object"
+                },
+                "event_type": {
+                    "$ref": "#/definitions/model.BillingEventType"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "paypal_event_id": {
+                    "type": "string"
+                },
+                "processed_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "billing.BillingEventListOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/billing.BillingEvent"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
+                }
+            }
+        },
+        "billing.BillingForecast": {
+            "type": "object",
             "properties": {
-                "auth_provider": {
-                    "$ref": "#/definitions/model.AuthProvider"
+                "charges": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/billing.ForecastCharge"
+                    }
                 },
-                "email": {
+                "currency": {
                     "type": "string"
                 },
-                "first_name": {
+                "ends_at": {
+                    "description": "the subscription ends instead of renewing",
                     "type": "string"
                 },
-                "last_name": {
+                "forecasted_at": {
                     "type": "string"
                 },
-                "password": {
-                    "type": "string",
-                    "minLength": 6
+                "organization_id": {
+                    "type": "string"
                 },
-                "username": {
-                    "type": "string",
-                    "maxLength": 100,
-                    "minLength": 3
+                "outstanding_amount": {
+                    "description": "open invoices, charged on the next billing run",
+                    "type": "number"
+                },
+                "total_amount": {
+                    "type": "number"
+                },
+                "until": {
+                    "type": "string"
                 }
             }
         },
-        "auth.TokenData": {
+        "billing.BillingForecastOut": {
             "type": "object",
             "properties": {
-                "token": {
+                "data": {
+                    "$ref": "#/definitions/billing.BillingForecast"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
                     "type": "string"
                 }
             }
@@ -2985,7 +6839,7 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "type": {
-                    "description": "\"invoice\" or \"payment\"",
+                    "description": "\"invoice\", \"payment\" or \"credit_note\"",
                     "type": "string"
                 }
             }
@@ -3013,6 +6867,9 @@ const docTemplate = `{
         "billing.BillingOverview": {
             "type": "object",
             "properties": {
+                "currency": {
+                    "type": "string"
+                },
                 "current_plan": {
                     "$ref": "#/definitions/plan.Plan"
                 },
@@ -3059,6 +6916,130 @@ const docTemplate = `{
                 }
             }
         },
+        "billing.CreditNote": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "credit_note_number": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "failure_reason": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "invoice_id": {
+                    "type": "string"
+                },
+                "invoice_number": {
+                    "type": "string"
+                },
+                "issued_at": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "payment_id": {
+                    "type": "string"
+                },
+                "paypal_refund_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.CreditNoteStatus"
+                }
+            }
+        },
+        "billing.CreditNoteListOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/billing.CreditNote"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
+                }
+            }
+        },
+        "billing.CreditNoteOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/billing.CreditNote"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "billing.ForecastCharge": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "billing_cycle": {
+                    "$ref": "#/definitions/model.BillingCycle"
+                },
+                "date": {
+                    "type": "string"
+                },
+                "discount": {
+                    "type": "number"
+                },
+                "kind": {
+                    "description": "\"renewal\", \"plan_change\" or \"trial_conversion\"",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/payments.ForecastChargeKind"
+                        }
+                    ]
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "tax_amount": {
+                    "type": "number"
+                },
+                "total_amount": {
+                    "type": "number"
+                }
+            }
+        },
         "billing.Invoice": {
             "type": "object",
             "properties": {
@@ -3104,6 +7085,12 @@ const docTemplate = `{
                 "paypal_invoice_id": {
                     "type": "string"
                 },
+                "refunded_amount": {
+                    "type": "number"
+                },
+                "reverse_charge": {
+                    "type": "boolean"
+                },
                 "status": {
                     "$ref": "#/definitions/model.InvoiceStatus"
                 },
@@ -3113,6 +7100,12 @@ const docTemplate = `{
                 "tax_amount": {
                     "type": "number"
                 },
+                "tax_description": {
+                    "type": "string"
+                },
+                "tax_rate": {
+                    "type": "number"
+                },
                 "total_amount": {
                     "type": "number"
                 },
@@ -3141,7 +7134,125 @@ const docTemplate = `{
                 }
             }
         },
-        "billing.InvoiceListOut": {
+        "billing.InvoiceListOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/billing.Invoice"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
+                }
+            }
+        },
+        "billing.InvoiceOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/billing.Invoice"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "billing.PayInvoiceRequest": {
+            "type": "object",
+            "properties": {
+                "order_id": {
+                    "type": "string"
+                },
+                "payment_method_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "billing.PaymentOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/payment.Payment"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "coupon.Coupon": {
+            "type": "object",
+            "properties": {
+                "amount_off": {
+                    "type": "number"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "discount_type": {
+                    "$ref": "#/definitions/model.CouponDiscountType"
+                },
+                "duration": {
+                    "$ref": "#/definitions/model.CouponDuration"
+                },
+                "duration_in_cycles": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "max_redemptions": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "percent_off": {
+                    "type": "number"
+                },
+                "plan_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "times_redeemed": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "coupon.CouponListOut": {
             "type": "object",
             "properties": {
                 "error_code": {
@@ -3153,7 +7264,7 @@ const docTemplate = `{
                 "list": {
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/billing.Invoice"
+                        "$ref": "#/definitions/coupon.Coupon"
                     }
                 },
                 "meta": {
@@ -3161,11 +7272,11 @@ const docTemplate = `{
                 }
             }
         },
-        "billing.InvoiceOut": {
+        "coupon.CouponOut": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/billing.Invoice"
+                    "$ref": "#/definitions/coupon.Coupon"
                 },
                 "error_code": {
                     "type": "integer"
@@ -3175,28 +7286,155 @@ const docTemplate = `{
                 }
             }
         },
-        "billing.PayInvoiceRequest": {
+        "coupon.CreateCouponRequest": {
             "type": "object",
             "required": [
-                "payment_method_id"
+                "code",
+                "discount_type",
+                "duration",
+                "name"
             ],
             "properties": {
-                "payment_method_id": {
+                "amount_off": {
+                    "type": "number"
+                },
+                "code": {
+                    "type": "string",
+                    "maxLength": 50,
+                    "minLength": 3
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "discount_type": {
+                    "enum": [
+                        "percent",
+                        "fixed"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.CouponDiscountType"
+                        }
+                    ]
+                },
+                "duration": {
+                    "enum": [
+                        "once",
+                        "repeating",
+                        "forever"
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/model.CouponDuration"
+                        }
+                    ]
+                },
+                "duration_in_cycles": {
+                    "type": "integer",
+                    "maximum": 120,
+                    "minimum": 1
+                },
+                "expires_at": {
                     "type": "string"
+                },
+                "max_redemptions": {
+                    "type": "integer",
+                    "minimum": 1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "percent_off": {
+                    "type": "number",
+                    "maximum": 100
+                },
+                "plan_ids": {
+                    "description": "empty for every plan",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
                 }
             }
         },
-        "billing.PaymentOut": {
+        "coupon.Redemption": {
             "type": "object",
             "properties": {
-                "data": {
-                    "$ref": "#/definitions/payment.Payment"
+                "active": {
+                    "type": "boolean"
+                },
+                "amount_off": {
+                    "type": "number"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "coupon_id": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "cycles_applied": {
+                    "type": "integer"
+                },
+                "cycles_remaining": {
+                    "description": "nil when the discount runs forever",
+                    "type": "integer"
+                },
+                "discount_type": {
+                    "$ref": "#/definitions/model.CouponDiscountType"
+                },
+                "duration": {
+                    "$ref": "#/definitions/model.CouponDuration"
+                },
+                "ended_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "organization_name": {
+                    "type": "string"
+                },
+                "percent_off": {
+                    "type": "number"
+                },
+                "redeemed_at": {
+                    "type": "string"
+                },
+                "redeemed_by": {
+                    "type": "string"
                 },
+                "subscription_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "coupon.RedemptionListOut": {
+            "type": "object",
+            "properties": {
                 "error_code": {
                     "type": "integer"
                 },
                 "error_description": {
                     "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/coupon.Redemption"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
                 }
             }
         },
@@ -3228,17 +7466,53 @@ const docTemplate = `{
                 }
             }
         },
+        "model.AuthMethod": {
+            "type": "string",
+            "enum": [
+                "password",
+                "signup",
+                "sso"
+            ],
+            "x-enum-varnames": [
+                "AuthMethodPassword",
+                "AuthMethodSignUp",
+                "AuthMethodSSO"
+            ]
+        },
         "model.AuthProvider": {
             "type": "string",
             "enum": [
                 "email",
                 "gmail",
-                "apple"
+                "apple",
+                "oidc"
             ],
             "x-enum-varnames": [
                 "AuthProviderEmail",
                 "AuthProviderGmail",
-                "AuthProviderApple"
+                "AuthProviderApple",
+                "AuthProviderOIDC"
+            ]
+        },
+        "model.BillingActorType": {
+            "type": "string",
+            "enum": [
+                "system",
+                "user",
+                "admin",
+                "paypal"
+            ],
+            "x-enum-comments": {
+                "BillingActorTypeAdmin": "a platform admin",
+                "BillingActorTypePayPal": "a PayPal webhook",
+                "BillingActorTypeSystem": "the billing run and other jobs",
+                "BillingActorTypeUser": "a member of the organization"
+            },
+            "x-enum-varnames": [
+                "BillingActorTypeSystem",
+                "BillingActorTypeUser",
+                "BillingActorTypeAdmin",
+                "BillingActorTypePayPal"
             ]
         },
         "model.BillingCycle": {
@@ -3252,6 +7526,135 @@ const docTemplate = `{
                 "BillingCycleYearly"
             ]
         },
+        "model.BillingEventType": {
+            "type": "string",
+            "enum": [
+                "subscription_created",
+                "subscription_updated",
+                "subscription_cancelled",
+                "payment_succeeded",
+                "payment_failed",
+                "invoice_created",
+                "plan_changed",
+                "subscription_activated",
+                "subscription_suspended",
+                "invoice_paid",
+                "invoice_cancelled",
+                "dunning_started",
+                "dunning_retry_failed",
+                "dunning_exhausted",
+                "dunning_recovered",
+                "trial_started",
+                "trial_converted",
+                "trial_expired",
+                "payment_refunded",
+                "refund_failed",
+                "coupon_redeemed",
+                "billing_details_updated",
+                "payment_method_added",
+                "payment_method_removed",
+                "payment_method_invalidated",
+                "billing_contact_added",
+                "billing_contact_removed",
+                "subscription_reactivated",
+                "payment_method_default_changed"
+            ],
+            "x-enum-varnames": [
+                "BillingEventTypeSubscriptionCreated",
+                "BillingEventTypeSubscriptionUpdated",
+                "BillingEventTypeSubscriptionCancelled",
+                "BillingEventTypePaymentSucceeded",
+                "BillingEventTypePaymentFailed",
+                "BillingEventTypeInvoiceCreated",
+                "BillingEventTypePlanChanged",
+                "BillingEventTypeSubscriptionActivated",
+                "BillingEventTypeSubscriptionSuspended",
+                "BillingEventTypeInvoicePaid",
+                "BillingEventTypeInvoiceCancelled",
+                "BillingEventTypeDunningStarted",
+                "BillingEventTypeDunningRetryFailed",
+                "BillingEventTypeDunningExhausted",
+                "BillingEventTypeDunningRecovered",
+                "BillingEventTypeTrialStarted",
+                "BillingEventTypeTrialConverted",
+                "BillingEventTypeTrialExpired",
+                "BillingEventTypePaymentRefunded",
+                "BillingEventTypeRefundFailed",
+                "BillingEventTypeCouponRedeemed",
+                "BillingEventTypeBillingDetailsUpdated",
+                "BillingEventTypePaymentMethodAdded",
+                "BillingEventTypePaymentMethodRemoved",
+                "BillingEventTypePaymentMethodInvalidated",
+                "BillingEventTypeBillingContactAdded",
+                "BillingEventTypeBillingContactRemoved",
+                "BillingEventTypeSubscriptionReactivated",
+                "BillingEventTypePaymentMethodDefault"
+            ]
+        },
+        "model.CouponDiscountType": {
+            "type": "string",
+            "enum": [
+                "percent",
+                "fixed"
+            ],
+            "x-enum-varnames": [
+                "CouponDiscountTypePercent",
+                "CouponDiscountTypeFixed"
+            ]
+        },
+        "model.CouponDuration": {
+            "type": "string",
+            "enum": [
+                "once",
+                "repeating",
+                "forever"
+            ],
+            "x-enum-comments": {
+                "CouponDurationRepeating": "DurationInCycles periods"
+            },
+            "x-enum-varnames": [
+                "CouponDurationOnce",
+                "CouponDurationRepeating",
+                "CouponDurationForever"
+            ]
+        },
+        "model.CreditNoteStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "issued",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "CreditNoteStatusPending",
+                "CreditNoteStatusIssued",
+                "CreditNoteStatusFailed"
+            ]
+        },
+        "model.InvoiceDeliveryStatus": {
+            "type": "string",
+            "enum": [
+                "sent",
+                "failed"
+            ],
+            "x-enum-varnames": [
+                "InvoiceDeliveryStatusSent",
+                "InvoiceDeliveryStatusFailed"
+            ]
+        },
+        "model.InvoiceEmailKind": {
+            "type": "string",
+            "enum": [
+                "invoice_issued",
+                "payment_receipt",
+                "payment_failed"
+            ],
+            "x-enum-varnames": [
+                "InvoiceEmailKindIssued",
+                "InvoiceEmailKindReceipt",
+                "InvoiceEmailKindFailed"
+            ]
+        },
         "model.InvoiceStatus": {
             "type": "string",
             "enum": [
@@ -3259,14 +7662,64 @@ const docTemplate = `{
                 "sent",
                 "paid",
                 "cancelled",
-                "refunded"
+                "refunded",
+                "partially_refunded"
             ],
             "x-enum-varnames": [
                 "InvoiceStatusDraft",
                 "InvoiceStatusSent",
                 "InvoiceStatusPaid",
                 "InvoiceStatusCancelled",
-                "InvoiceStatusRefunded"
+                "InvoiceStatusRefunded",
+                "InvoiceStatusPartiallyRefunded"
+            ]
+        },
+        "model.LimitExcess": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "resource": {
+                    "type": "string"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.LoginOutcome": {
+            "type": "string",
+            "enum": [
+                "success",
+                "invalid_credentials",
+                "throttled",
+                "locked",
+                "inactive_account",
+                "sso_required",
+                "sso_failed"
+            ],
+            "x-enum-varnames": [
+                "LoginOutcomeSuccess",
+                "LoginOutcomeInvalidCredentials",
+                "LoginOutcomeThrottled",
+                "LoginOutcomeLocked",
+                "LoginOutcomeInactiveAccount",
+                "LoginOutcomeSSORequired",
+                "LoginOutcomeSSOFailed"
+            ]
+        },
+        "model.OrganizationMemberRole": {
+            "type": "string",
+            "enum": [
+                "member",
+                "admin",
+                "owner"
+            ],
+            "x-enum-varnames": [
+                "OrganizationMemberRoleMember",
+                "OrganizationMemberRoleAdmin",
+                "OrganizationMemberRoleOwner"
             ]
         },
         "model.OrganizationStatus": {
@@ -3291,6 +7744,19 @@ const docTemplate = `{
                 "PaymentMethodEnumPayPal"
             ]
         },
+        "model.PaymentMethodStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "verified",
+                "invalid"
+            ],
+            "x-enum-varnames": [
+                "PaymentMethodStatusPending",
+                "PaymentMethodStatusVerified",
+                "PaymentMethodStatusInvalid"
+            ]
+        },
         "model.PaymentMethodType": {
             "type": "string",
             "enum": [
@@ -3307,14 +7773,16 @@ const docTemplate = `{
                 "completed",
                 "failed",
                 "cancelled",
-                "refunded"
+                "refunded",
+                "partially_refunded"
             ],
             "x-enum-varnames": [
                 "PaymentStatusPending",
                 "PaymentStatusCompleted",
                 "PaymentStatusFailed",
                 "PaymentStatusCancelled",
-                "PaymentStatusRefunded"
+                "PaymentStatusRefunded",
+                "PaymentStatusPartiallyRefunded"
             ]
         },
         "model.PlanType": {
@@ -3349,18 +7817,154 @@ const docTemplate = `{
                 "SubscriptionStatusPending"
             ]
         },
-        "model.UserStatus": {
-            "type": "string",
-            "enum": [
-                "active",
-                "suspended",
-                "inactive"
-            ],
-            "x-enum-varnames": [
-                "UserStatusActive",
-                "UserStatusSuspended",
-                "UserStatusInactive"
-            ]
+        "model.UsageCounts": {
+            "type": "object",
+            "properties": {
+                "environments": {
+                    "type": "integer"
+                },
+                "max_test_records_per_schema": {
+                    "type": "integer"
+                },
+                "projects": {
+                    "type": "integer"
+                },
+                "schemas": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "integer"
+                }
+            }
+        },
+        "model.UserStatus": {
+            "type": "string",
+            "enum": [
+                "active",
+                "suspended",
+                "inactive"
+            ],
+            "x-enum-varnames": [
+                "UserStatusActive",
+                "UserStatusSuspended",
+                "UserStatusInactive"
+            ]
+        },
+        "organization.AddBillingContactRequest": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                }
+            }
+        },
+        "organization.BillingContact": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.BillingContactOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/organization.BillingContact"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.BillingContactsOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/organization.BillingContact"
+                    }
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.BillingDetails": {
+            "type": "object",
+            "properties": {
+                "address_line1": {
+                    "type": "string"
+                },
+                "address_line2": {
+                    "type": "string"
+                },
+                "billing_email": {
+                    "type": "string"
+                },
+                "city": {
+                    "type": "string"
+                },
+                "country": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "postal_code": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                },
+                "tax": {
+                    "$ref": "#/definitions/organization.TaxPreview"
+                },
+                "tax_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.BillingDetailsOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/organization.BillingDetails"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
         },
         "organization.CreateOrganizationRequest": {
             "type": "object",
@@ -3443,6 +8047,47 @@ const docTemplate = `{
                 }
             }
         },
+        "organization.LimitsCheck": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.LimitCheck"
+                    }
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "upgrade_options": {
+                    "description": "when the checked resource does not fit",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.UpgradeOption"
+                    }
+                },
+                "usage": {
+                    "$ref": "#/definitions/model.UsageCounts"
+                }
+            }
+        },
+        "organization.LimitsCheckOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/organization.LimitsCheck"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
         "organization.Member": {
             "type": "object",
             "properties": {
@@ -3466,6 +8111,58 @@ const docTemplate = `{
                 }
             }
         },
+        "organization.MemberActivity": {
+            "type": "object",
+            "properties": {
+                "active_sessions": {
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "full_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "joined_at": {
+                    "type": "string"
+                },
+                "last_login_at": {
+                    "type": "string"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.MembersActivityOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/organization.MemberActivity"
+                    }
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
         "organization.MembersOut": {
             "type": "object",
             "properties": {
@@ -3573,28 +8270,211 @@ const docTemplate = `{
                 "invited_at": {
                     "type": "string"
                 },
-                "role": {
-                    "type": "string"
+                "role": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.PendingInvitesOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/organization.PendingInvite"
+                    }
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.SSOConfig": {
+            "type": "object",
+            "properties": {
+                "allowed_domains": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "client_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "default_role": {
+                    "$ref": "#/definitions/model.OrganizationMemberRole"
+                },
+                "domains": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/organization.SSODomain"
+                    }
+                },
+                "enforce_sso": {
+                    "type": "boolean"
+                },
+                "has_client_secret": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_enabled": {
+                    "type": "boolean"
+                },
+                "issuer": {
+                    "type": "string"
+                },
+                "login_url": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "redirect_url": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.SSOConfigOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/organization.SSOConfig"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.SSOConfigRequest": {
+            "type": "object",
+            "required": [
+                "allowed_domains",
+                "client_id",
+                "issuer"
+            ],
+            "properties": {
+                "allowed_domains": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "client_id": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "client_secret": {
+                    "description": "kept when omitted",
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "default_role": {
+                    "type": "string",
+                    "enum": [
+                        "member",
+                        "admin"
+                    ]
+                },
+                "enforce_sso": {
+                    "type": "boolean"
+                },
+                "is_enabled": {
+                    "type": "boolean"
+                },
+                "issuer": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.SSODomain": {
+            "type": "object",
+            "properties": {
+                "domain": {
+                    "type": "string"
+                },
+                "txt_name": {
+                    "type": "string"
+                },
+                "txt_value": {
+                    "type": "string"
+                },
+                "verified": {
+                    "type": "boolean"
+                },
+                "verified_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "organization.TaxPreview": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "rate": {
+                    "type": "number"
                 },
-                "token": {
-                    "type": "string"
+                "reverse_charge": {
+                    "type": "boolean"
                 }
             }
         },
-        "organization.PendingInvitesOut": {
+        "organization.UpdateBillingDetailsRequest": {
             "type": "object",
             "properties": {
-                "data": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/organization.PendingInvite"
-                    }
+                "address_line1": {
+                    "type": "string",
+                    "maxLength": 255
                 },
-                "error_code": {
-                    "type": "integer"
+                "address_line2": {
+                    "type": "string",
+                    "maxLength": 255
                 },
-                "error_description": {
+                "billing_email": {
+                    "type": "string",
+                    "maxLength": 255
+                },
+                "city": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "country": {
                     "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 200
+                },
+                "postal_code": {
+                    "type": "string",
+                    "maxLength": 20
+                },
+                "state": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "tax_id": {
+                    "type": "string",
+                    "maxLength": 50
                 }
             }
         },
@@ -3640,23 +8520,11 @@ const docTemplate = `{
                 }
             }
         },
-        "payment.CreatePaymentMethodRequest": {
+        "payment.ConfirmPaymentMethodRequest": {
             "type": "object",
-            "required": [
-                "paypal_email"
-            ],
             "properties": {
                 "is_default": {
                     "type": "boolean"
-                },
-                "payment_method_type": {
-                    "$ref": "#/definitions/model.PaymentMethodType"
-                },
-                "paypal_email": {
-                    "type": "string"
-                },
-                "paypal_payer_id": {
-                    "type": "string"
                 }
             }
         },
@@ -3666,6 +8534,9 @@ const docTemplate = `{
                 "amount": {
                     "type": "number"
                 },
+                "approval_url": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
@@ -3696,6 +8567,9 @@ const docTemplate = `{
                 "processed_at": {
                     "type": "string"
                 },
+                "refunded_amount": {
+                    "type": "number"
+                },
                 "status": {
                     "$ref": "#/definitions/model.PaymentStatus"
                 },
@@ -3710,15 +8584,25 @@ const docTemplate = `{
         "payment.PaymentMethod": {
             "type": "object",
             "properties": {
+                "approval_url": {
+                    "description": "Set on a pending method: the payer approves saving it there",
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
                 "created_by": {
                     "type": "string"
                 },
+                "failure_reason": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
+                "invalidated_at": {
+                    "type": "string"
+                },
                 "is_active": {
                     "type": "boolean"
                 },
@@ -3737,6 +8621,9 @@ const docTemplate = `{
                 "paypal_payer_id": {
                     "type": "string"
                 },
+                "status": {
+                    "$ref": "#/definitions/model.PaymentMethodStatus"
+                },
                 "updated_at": {
                     "type": "string"
                 }
@@ -3754,36 +8641,276 @@ const docTemplate = `{
                 "error_code": {
                     "type": "integer"
                 },
-                "error_description": {
-                    "type": "string"
-                }
-            }
-        },
-        "payment.PaymentMethodOut": {
-            "type": "object",
-            "properties": {
-                "data": {
-                    "$ref": "#/definitions/payment.PaymentMethod"
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "payment.PaymentMethodOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/payment.PaymentMethod"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "payment.UpdatePaymentMethodRequest": {
+            "type": "object",
+            "properties": {
+                "is_default": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "payments.ForecastChargeKind": {
+            "type": "string",
+            "enum": [
+                "renewal",
+                "plan_change",
+                "trial_conversion"
+            ],
+            "x-enum-varnames": [
+                "ForecastChargeRenewal",
+                "ForecastChargePlanChange",
+                "ForecastChargeTrialConversion"
+            ]
+        },
+        "plan.CataloguePlan": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "is_active": {
+                    "type": "boolean"
+                },
+                "max_environments": {
+                    "type": "integer"
+                },
+                "max_projects": {
+                    "type": "integer"
+                },
+                "max_schemas": {
+                    "type": "integer"
+                },
+                "max_test_records_per_schema": {
+                    "type": "integer"
+                },
+                "max_users": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "paypal_product_id": {
+                    "type": "string"
+                },
+                "previous_version_id": {
+                    "type": "string"
+                },
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/plan.Price"
+                    }
+                },
+                "retired_at": {
+                    "type": "string"
+                },
+                "slug": {
+                    "type": "string"
+                },
+                "subscribers": {
+                    "description": "active subscriptions on this version",
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "usage_alert_thresholds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "plan.CataloguePlanListOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/plan.CataloguePlan"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
+                }
+            }
+        },
+        "plan.CataloguePlanOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/plan.CataloguePlan"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "plan.CreatePlanRequest": {
+            "type": "object",
+            "required": [
+                "name",
+                "slug"
+            ],
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "max_environments": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_projects": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_schemas": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_test_records_per_schema": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_users": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "price_monthly": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "price_yearly": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/plan.PlanPriceRequest"
+                    }
+                },
+                "slug": {
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "usage_alert_thresholds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
+        "plan.CreatePlanVersionRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "max_environments": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_projects": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_schemas": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_test_records_per_schema": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_users": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
                 },
-                "error_code": {
-                    "type": "integer"
+                "price_monthly": {
+                    "type": "number",
+                    "minimum": 0
                 },
-                "error_description": {
-                    "type": "string"
-                }
-            }
-        },
-        "payment.UpdatePaymentMethodRequest": {
-            "type": "object",
-            "properties": {
-                "is_default": {
-                    "type": "boolean"
+                "price_yearly": {
+                    "type": "number",
+                    "minimum": 0
                 },
-                "paypal_email": {
-                    "type": "string"
+                "prices": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/plan.PlanPriceRequest"
+                    }
                 },
-                "paypal_payer_id": {
-                    "type": "string"
+                "usage_alert_thresholds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
                 }
             }
         },
@@ -3793,6 +8920,17 @@ const docTemplate = `{
                 "created_at": {
                     "type": "string"
                 },
+                "currencies": {
+                    "description": "the plan can be subscribed in",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "currency": {
+                    "description": "of the prices",
+                    "type": "string"
+                },
                 "description": {
                     "type": "string"
                 },
@@ -3896,6 +9034,86 @@ const docTemplate = `{
                 }
             }
         },
+        "plan.PlanPriceRequest": {
+            "type": "object",
+            "required": [
+                "currency"
+            ],
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "price_monthly": {
+                    "type": "number",
+                    "minimum": 0
+                },
+                "price_yearly": {
+                    "type": "number",
+                    "minimum": 0
+                }
+            }
+        },
+        "plan.Price": {
+            "type": "object",
+            "properties": {
+                "billing_cycle": {
+                    "$ref": "#/definitions/model.BillingCycle"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "paypal_plan_id": {
+                    "type": "string"
+                },
+                "price": {
+                    "type": "number"
+                }
+            }
+        },
+        "plan.UpdatePlanRequest": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "max_environments": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_projects": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_schemas": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_test_records_per_schema": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "max_users": {
+                    "type": "integer",
+                    "minimum": -1
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "usage_alert_thresholds": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                }
+            }
+        },
         "subscription.ChangePlanRequest": {
             "type": "object",
             "required": [
@@ -3906,11 +9124,33 @@ const docTemplate = `{
                 "billing_cycle": {
                     "$ref": "#/definitions/model.BillingCycle"
                 },
+                "coupon_code": {
+                    "description": "CouponCode redeems a coupon for the new plan",
+                    "type": "string",
+                    "maxLength": 50
+                },
                 "new_plan_id": {
                     "type": "string"
                 }
             }
         },
+        "subscription.CouponRedemptionsOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/coupon.Redemption"
+                    }
+                }
+            }
+        },
         "subscription.CreateSubscriptionRequest": {
             "type": "object",
             "required": [
@@ -3921,8 +9161,21 @@ const docTemplate = `{
                 "billing_cycle": {
                     "$ref": "#/definitions/model.BillingCycle"
                 },
+                "coupon_code": {
+                    "description": "CouponCode redeems a coupon, discounting the subscription",
+                    "type": "string",
+                    "maxLength": 50
+                },
+                "currency": {
+                    "description": "Currency is the ISO 4217 currency the subscription is billed in, the\norganization's currency by default",
+                    "type": "string"
+                },
                 "plan_id": {
                     "type": "string"
+                },
+                "start_trial": {
+                    "description": "StartTrial starts a free trial of a paid plan instead of charging right away",
+                    "type": "boolean"
                 }
             }
         },
@@ -3946,9 +9199,30 @@ const docTemplate = `{
                 }
             }
         },
+        "subscription.PlanLimitsExceededOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.LimitExcess"
+                    }
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
         "subscription.Subscription": {
             "type": "object",
             "properties": {
+                "approval_url": {
+                    "description": "where the payer approves the subscription at the provider",
+                    "type": "string"
+                },
                 "billing_cycle": {
                     "$ref": "#/definitions/model.BillingCycle"
                 },
@@ -3967,37 +9241,152 @@ const docTemplate = `{
                 "current_period_end": {
                     "type": "string"
                 },
-                "current_period_start": {
+                "current_period_start": {
+                    "type": "string"
+                },
+                "discount": {
+                    "description": "coupon discounting the subscription",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/coupon.Redemption"
+                        }
+                    ]
+                },
+                "id": {
+                    "type": "string"
+                },
+                "organization_id": {
+                    "type": "string"
+                },
+                "paypal_subscription_id": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "proration_invoice_id": {
+                    "type": "string"
+                },
+                "scheduled_billing_cycle": {
+                    "$ref": "#/definitions/model.BillingCycle"
+                },
+                "scheduled_plan_id": {
+                    "description": "downgrade applied when the current period ends",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/model.SubscriptionStatus"
+                },
+                "trial_end": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription.SubscriptionOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/subscription.Subscription"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription.SubscriptionUsageOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/subscription.UsageData"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "subscription.UsageData": {
+            "type": "object",
+            "properties": {
+                "current_usage": {
+                    "$ref": "#/definitions/subscription.UsageMetrics"
+                },
+                "period_end": {
+                    "type": "string"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "plan_limits": {
+                    "$ref": "#/definitions/subscription.PlanLimits"
+                }
+            }
+        },
+        "subscription.UsageMetrics": {
+            "type": "object",
+            "properties": {
+                "api_requests_count": {
+                    "type": "integer"
+                },
+                "environments_count": {
+                    "type": "integer"
+                },
+                "projects_count": {
+                    "type": "integer"
+                },
+                "schemas_count": {
+                    "type": "integer"
+                },
+                "test_records_count": {
+                    "type": "integer"
+                },
+                "users_count": {
+                    "type": "integer"
+                }
+            }
+        },
+        "usage.CurrentUsage": {
+            "type": "object",
+            "properties": {
+                "current": {
+                    "$ref": "#/definitions/subscription.UsageMetrics"
+                },
+                "last_updated": {
                     "type": "string"
                 },
-                "id": {
-                    "type": "string"
+                "limits": {
+                    "$ref": "#/definitions/subscription.PlanLimits"
                 },
                 "organization_id": {
                     "type": "string"
                 },
-                "paypal_subscription_id": {
-                    "type": "string"
-                },
-                "plan_id": {
+                "period_end": {
                     "type": "string"
                 },
-                "status": {
-                    "$ref": "#/definitions/model.SubscriptionStatus"
-                },
-                "trial_end": {
+                "period_start": {
                     "type": "string"
                 },
-                "updated_at": {
-                    "type": "string"
+                "utilization_pct": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number"
+                    }
                 }
             }
         },
-        "subscription.SubscriptionOut": {
+        "usage.CurrentUsageOut": {
             "type": "object",
             "properties": {
                 "data": {
-                    "$ref": "#/definitions/subscription.Subscription"
+                    "$ref": "#/definitions/usage.CurrentUsage"
                 },
                 "error_code": {
                     "type": "integer"
@@ -4007,57 +9396,60 @@ const docTemplate = `{
                 }
             }
         },
-        "subscription.SubscriptionUsageOut": {
+        "usage.UsageDataPoint": {
             "type": "object",
             "properties": {
-                "data": {
-                    "$ref": "#/definitions/subscription.UsageData"
-                },
-                "error_code": {
+                "api_requests_count": {
                     "type": "integer"
                 },
-                "error_description": {
+                "date": {
                     "type": "string"
+                },
+                "environments_count": {
+                    "type": "integer"
+                },
+                "projects_count": {
+                    "type": "integer"
+                },
+                "schemas_count": {
+                    "type": "integer"
+                },
+                "test_records_count": {
+                    "type": "integer"
+                },
+                "users_count": {
+                    "type": "integer"
                 }
             }
         },
-        "subscription.UsageData": {
+        "usage.UsageHistory": {
             "type": "object",
             "properties": {
-                "current_usage": {
-                    "$ref": "#/definitions/subscription.UsageMetrics"
+                "data_points": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/usage.UsageDataPoint"
+                    }
                 },
-                "period_end": {
+                "organization_id": {
                     "type": "string"
                 },
-                "period_start": {
+                "period": {
                     "type": "string"
-                },
-                "plan_limits": {
-                    "$ref": "#/definitions/subscription.PlanLimits"
                 }
             }
         },
-        "subscription.UsageMetrics": {
+        "usage.UsageHistoryOut": {
             "type": "object",
             "properties": {
-                "api_requests_count": {
-                    "type": "integer"
-                },
-                "environments_count": {
-                    "type": "integer"
-                },
-                "projects_count": {
-                    "type": "integer"
-                },
-                "schemas_count": {
-                    "type": "integer"
+                "data": {
+                    "$ref": "#/definitions/usage.UsageHistory"
                 },
-                "test_records_count": {
+                "error_code": {
                     "type": "integer"
                 },
-                "users_count": {
-                    "type": "integer"
+                "error_description": {
+                    "type": "string"
                 }
             }
         },
@@ -4112,6 +9504,21 @@ const docTemplate = `{
                 }
             }
         },
+        "user.ChangeEmailRequest": {
+            "type": "object",
+            "required": [
+                "new_email",
+                "password"
+            ],
+            "properties": {
+                "new_email": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
         "user.DashboardData": {
             "type": "object",
             "properties": {
@@ -4177,6 +9584,49 @@ const docTemplate = `{
                 }
             }
         },
+        "user.LoginHistoryItem": {
+            "type": "object",
+            "properties": {
+                "auth_method": {
+                    "$ref": "#/definitions/model.AuthMethod"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "outcome": {
+                    "$ref": "#/definitions/model.LoginOutcome"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "user.LoginHistoryOut": {
+            "type": "object",
+            "properties": {
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                },
+                "list": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/user.LoginHistoryItem"
+                    }
+                },
+                "meta": {
+                    "$ref": "#/definitions/inout.PaginationMeta"
+                }
+            }
+        },
         "user.Notification": {
             "type": "object",
             "properties": {
@@ -4263,6 +9713,52 @@ const docTemplate = `{
                 }
             }
         },
+        "user.Session": {
+            "type": "object",
+            "properties": {
+                "auth_method": {
+                    "$ref": "#/definitions/model.AuthMethod"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip_address": {
+                    "type": "string"
+                },
+                "is_current": {
+                    "type": "boolean"
+                },
+                "last_seen_at": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "user.SessionsOut": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/user.Session"
+                    }
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
         "user.UpdateUserRequest": {
             "type": "object",
             "properties": {
@@ -4331,6 +9827,138 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "utils.JSONWebKey": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.LimitCheck": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "description": "negative when unlimited",
+                    "type": "integer"
+                },
+                "remaining": {
+                    "description": "negative when unlimited",
+                    "type": "integer"
+                },
+                "requested": {
+                    "type": "integer"
+                },
+                "resource": {
+                    "type": "string"
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.LimitExceeded": {
+            "type": "object",
+            "properties": {
+                "allowed": {
+                    "type": "boolean"
+                },
+                "limit": {
+                    "description": "negative when unlimited",
+                    "type": "integer"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "remaining": {
+                    "description": "negative when unlimited",
+                    "type": "integer"
+                },
+                "requested": {
+                    "type": "integer"
+                },
+                "resource": {
+                    "type": "string"
+                },
+                "upgrade_options": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/utils.UpgradeOption"
+                    }
+                },
+                "used": {
+                    "type": "integer"
+                }
+            }
+        },
+        "utils.LimitExceededError": {
+            "type": "object",
+            "properties": {
+                "data": {
+                    "$ref": "#/definitions/utils.LimitExceeded"
+                },
+                "error_code": {
+                    "type": "integer"
+                },
+                "error_description": {
+                    "type": "string"
+                }
+            }
+        },
+        "utils.UpgradeOption": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string"
+                },
+                "limit": {
+                    "description": "negative when unlimited",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "plan_id": {
+                    "type": "string"
+                },
+                "price_monthly": {
+                    "type": "number"
+                },
+                "price_yearly": {
+                    "type": "number"
+                },
+                "slug": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,7 @@
+package admin
+
+// RefundPaymentRequest refunds what is left of the payment when Amount is empty.
+type RefundPaymentRequest struct {
+	Amount *float64 `json:"amount" binding:"omitempty,gt=0"`
+	Reason string   `json:"reason" binding:"required,max=500"`
+}
@@ -0,0 +1,190 @@
+package admin
+
+import (
+	"strconv"
+	"testlake/inout"
+	"testlake/inout/billing"
+	"testlake/model"
+	"testlake/payments"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EndingTrial is a row of the trials-ending report.
+type EndingTrial struct {
+	OrganizationID   uuid.UUID          `json:"organization_id"`
+	OrganizationName string             `json:"organization_name"`
+	OrganizationSlug string             `json:"organization_slug"`
+	BillingEmail     *string            `json:"billing_email"`
+	OwnerEmail       string             `json:"owner_email"`
+	SubscriptionID   uuid.UUID          `json:"subscription_id"`
+	PlanID           uuid.UUID          `json:"plan_id"`
+	PlanName         string             `json:"plan_name"`
+	BillingCycle     model.BillingCycle `json:"billing_cycle"`
+	Price            float64            `json:"price"` // charged per cycle once the trial converts
+	Currency         string             `json:"currency"`
+	TrialStartedAt   time.Time          `json:"trial_started_at"`
+	TrialEndsAt      time.Time          `json:"trial_ends_at"`
+	DaysLeft         int                `json:"days_left"`
+	HasPaymentMethod bool               `json:"has_payment_method"` // the trial converts instead of moving to the free plan
+	ReminderSent     bool               `json:"reminder_sent"`
+}
+
+type EndingTrialListOut struct {
+	inout.BaseResponse
+	List []EndingTrial        `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
+func FromEndingTrialModel(s *model.Subscription, hasPaymentMethod bool, now time.Time) EndingTrial {
+	price, _ := s.Price(&s.Plan, s.BillingCycle)
+	return EndingTrial{
+		OrganizationID:   s.OrganizationID,
+		OrganizationName: s.Organization.Name,
+		OrganizationSlug: s.Organization.Slug,
+		BillingEmail:     s.Organization.BillingEmail,
+		OwnerEmail:       s.Organization.Creator.Email,
+		SubscriptionID:   s.ID,
+		PlanID:           s.PlanID,
+		PlanName:         s.Plan.Name,
+		BillingCycle:     s.BillingCycle,
+		Price:            model.FromMinorUnits(price, s.Currency),
+		Currency:         s.Currency,
+		TrialStartedAt:   s.CurrentPeriodStart,
+		TrialEndsAt:      *s.TrialEnd,
+		DaysLeft:         int(s.TrialEnd.Sub(now).Hours() / 24),
+		HasPaymentMethod: hasPaymentMethod,
+		ReminderSent:     s.TrialReminderSentAt != nil,
+	}
+}
+
+// RevenueMonth is a row of the revenue report: the metrics of a calendar
+// month, in UTC, in one currency.
+type RevenueMonth struct {
+	Month                string  `json:"month"` // YYYY-MM
+	Currency             string  `json:"currency"`
+	MRR                  float64 `json:"mrr"` // at the end of the month, or now for the current one
+	ARR                  float64 `json:"arr"`
+	PayingSubscriptions  int     `json:"paying_subscriptions"`
+	NewSubscriptions     int     `json:"new_subscriptions"`
+	ChurnedSubscriptions int     `json:"churned_subscriptions"`
+	ChurnRate            float64 `json:"churn_rate"` // percent of the paying subscriptions at the start of the month
+	ChurnedMRR           float64 `json:"churned_mrr"`
+	RecognizedRevenue    float64 `json:"recognized_revenue"` // before tax, less refunds
+	Refunds              float64 `json:"refunds"`
+	Collected            float64 `json:"collected"` // payments received, tax included
+}
+
+type RevenueReportOut struct {
+	inout.BaseResponse
+	List []RevenueMonth `json:"list"`
+}
+
+func FromRevenueMonth(month *payments.RevenueMonth) RevenueMonth {
+	currency := month.Currency
+	return RevenueMonth{
+		Month:                month.Month.Format("2006-01"),
+		Currency:             currency,
+		MRR:                  model.FromMinorUnits(month.MRR, currency),
+		ARR:                  model.FromMinorUnits(month.ARR, currency),
+		PayingSubscriptions:  month.PayingSubscriptions,
+		NewSubscriptions:     month.NewSubscriptions,
+		ChurnedSubscriptions: month.ChurnedSubscriptions,
+		ChurnRate:            month.ChurnRate,
+		ChurnedMRR:           model.FromMinorUnits(month.ChurnedMRR, currency),
+		RecognizedRevenue:    model.FromMinorUnits(month.RecognizedRevenue, currency),
+		Refunds:              model.FromMinorUnits(month.Refunds, currency),
+		Collected:            model.FromMinorUnits(month.Collected, currency),
+	}
+}
+
+func FromRevenueMonthList(months []payments.RevenueMonth) []RevenueMonth {
+	result := make([]RevenueMonth, len(months))
+	for i, month := range months {
+		result[i] = FromRevenueMonth(&month)
+	}
+	return result
+}
+
+// InvoiceDelivery is an attempt at emailing an invoice to one recipient.
+type InvoiceDelivery struct {
+	ID          uuid.UUID                   `json:"id"`
+	Kind        model.InvoiceEmailKind      `json:"kind"`
+	Recipient   string                      `json:"recipient"`
+	Subject     string                      `json:"subject"`
+	Status      model.InvoiceDeliveryStatus `json:"status"` // sent once the mail server accepted the email
+	Error       *string                     `json:"error"`
+	AttemptedAt time.Time                   `json:"attempted_at"`
+}
+
+type InvoiceDeliveryListOut struct {
+	inout.BaseResponse
+	List []InvoiceDelivery `json:"list"`
+}
+
+func FromInvoiceDeliveryModelList(deliveries []model.InvoiceDelivery) []InvoiceDelivery {
+	result := make([]InvoiceDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		result[i] = InvoiceDelivery{
+			ID:          delivery.ID,
+			Kind:        delivery.Kind,
+			Recipient:   delivery.Recipient,
+			Subject:     delivery.Subject,
+			Status:      delivery.Status,
+			Error:       delivery.Error,
+			AttemptedAt: delivery.AttemptedAt,
+		}
+	}
+	return result
+}
+
+// BillingEvent is an entry of the billing event log of any organization.
+type BillingEvent struct {
+	billing.BillingEvent
+	OrganizationName string `json:"organization_name"`
+	OrganizationSlug string `json:"organization_slug"`
+}
+
+type BillingEventListOut struct {
+	inout.BaseResponse
+	List []BillingEvent       `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
+func FromBillingEventModelList(events []model.BillingEvent) []BillingEvent {
+	result := make([]BillingEvent, len(events))
+	for i := range events {
+		result[i] = BillingEvent{
+			BillingEvent:     billing.FromBillingEventModel(&events[i]),
+			OrganizationName: events[i].Organization.Name,
+			OrganizationSlug: events[i].Organization.Slug,
+		}
+	}
+	return result
+}
+
+// RevenueReportCSV lays the revenue report out as CSV records, a header first,
+// with amounts as decimals.
+func RevenueReportCSV(months []payments.RevenueMonth) [][]string {
+	records := [][]string{{"month", "currency", "mrr", "arr", "paying_subscriptions", "new_subscriptions", "churned_subscriptions",
+		"churn_rate", "churned_mrr", "recognized_revenue", "refunds", "collected"}}
+	for _, month := range months {
+		currency := month.Currency
+		records = append(records, []string{
+			month.Month.Format("2006-01"),
+			currency,
+			model.FormatMinorUnits(month.MRR, currency),
+			model.FormatMinorUnits(month.ARR, currency),
+			strconv.Itoa(month.PayingSubscriptions),
+			strconv.Itoa(month.NewSubscriptions),
+			strconv.Itoa(month.ChurnedSubscriptions),
+			strconv.FormatFloat(month.ChurnRate, 'f', 2, 64),
+			model.FormatMinorUnits(month.ChurnedMRR, currency),
+			model.FormatMinorUnits(month.RecognizedRevenue, currency),
+			model.FormatMinorUnits(month.Refunds, currency),
+			model.FormatMinorUnits(month.Collected, currency),
+		})
+	}
+	return records
+}
@@ -28,3 +28,7 @@ type ResetPasswordRequest struct {
 type ResendEmailConfirmationRequest struct {
 	Email string `json:"email" binding:"required,email"`
 }
+
+type SSODiscoverRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
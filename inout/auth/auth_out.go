@@ -4,6 +4,7 @@ import (
 	"time"
 	"testlake/inout"
 	"testlake/model"
+	"testlake/utils"
 	
 	"github.com/google/uuid"
 )
@@ -47,6 +48,18 @@ type RefreshTokenOut struct {
 	Data TokenData `json:"data"`
 }
 
+type SSODiscoverData struct {
+	OrganizationID   uuid.UUID `json:"organization_id"`
+	OrganizationName string    `json:"organization_name"`
+	OrganizationSlug string    `json:"organization_slug"`
+	AuthorizationURL string    `json:"authorization_url"`
+}
+
+type SSODiscoverOut struct {
+	inout.BaseResponse
+	Data SSODiscoverData `json:"data"`
+}
+
 func UserFromModel(user *model.User) AuthUser {
 	return AuthUser{
 		ID:              user.ID,
@@ -62,4 +75,10 @@ func UserFromModel(user *model.User) AuthUser {
 		LastLoginAt:     user.LastLoginAt,
 		Status:          user.Status,
 	}
-}
\ No newline at end of file
+}
+
+// JWKSOut follows the JSON Web Key Set format (RFC 7517) rather than the
+// BaseResponse envelope so that standard JWT libraries can consume it.
+type JWKSOut struct {
+	Keys []utils.JSONWebKey `json:"keys"`
+}
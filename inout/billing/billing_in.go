@@ -1,5 +1,8 @@
 package billing
 
+// PayInvoiceRequest starts a checkout when OrderID is empty and captures the
+// approved order otherwise.
 type PayInvoiceRequest struct {
-	PaymentMethodID string `json:"payment_method_id" binding:"required"`
+	PaymentMethodID *string `json:"payment_method_id"`
+	OrderID         *string `json:"order_id"`
 }
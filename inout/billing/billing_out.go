@@ -1,11 +1,13 @@
 package billing
 
 import (
+	"encoding/json"
 	"testlake/inout"
 	"testlake/inout/payment"
 	"testlake/inout/plan"
 	"testlake/inout/subscription"
 	"testlake/model"
+	"testlake/payments"
 	"time"
 
 	"github.com/google/uuid"
@@ -27,6 +29,9 @@ type Invoice struct {
 	InvoiceNumber      string              `json:"invoice_number"`
 	Amount             float64             `json:"amount"`
 	TaxAmount          float64             `json:"tax_amount"`
+	TaxRate            float64             `json:"tax_rate"`
+	TaxDescription     string              `json:"tax_description"`
+	ReverseCharge      bool                `json:"reverse_charge"`
 	TotalAmount        float64             `json:"total_amount"`
 	Currency           string              `json:"currency"`
 	Status             model.InvoiceStatus `json:"status"`
@@ -34,6 +39,7 @@ type Invoice struct {
 	BillingPeriodEnd   *time.Time          `json:"billing_period_end"`
 	DueDate            *time.Time          `json:"due_date"`
 	PaidAt             *time.Time          `json:"paid_at"`
+	RefundedAmount     float64             `json:"refunded_amount"`
 	InvoiceURL         *string             `json:"invoice_url"`
 	CreatedAt          time.Time           `json:"created_at"`
 	UpdatedAt          time.Time           `json:"updated_at"`
@@ -51,11 +57,40 @@ type InvoiceListOut struct {
 	Meta inout.PaginationMeta `json:"meta"`
 }
 
+type CreditNote struct {
+	ID               uuid.UUID              `json:"id"`
+	OrganizationID   uuid.UUID              `json:"organization_id"`
+	InvoiceID        uuid.UUID              `json:"invoice_id"`
+	InvoiceNumber    string                 `json:"invoice_number,omitempty"`
+	PaymentID        uuid.UUID              `json:"payment_id"`
+	CreditNoteNumber string                 `json:"credit_note_number"`
+	Amount           float64                `json:"amount"`
+	Currency         string                 `json:"currency"`
+	Reason           *string                `json:"reason"`
+	Status           model.CreditNoteStatus `json:"status"`
+	PayPalRefundID   *string                `json:"paypal_refund_id"`
+	FailureReason    *string                `json:"failure_reason"`
+	IssuedAt         *time.Time             `json:"issued_at"`
+	CreatedAt        time.Time              `json:"created_at"`
+}
+
+type CreditNoteOut struct {
+	inout.BaseResponse
+	Data CreditNote `json:"data"`
+}
+
+type CreditNoteListOut struct {
+	inout.BaseResponse
+	List []CreditNote         `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
 type BillingOverview struct {
 	CurrentSubscription *subscription.Subscription `json:"current_subscription"`
 	CurrentPlan         *plan.Plan                 `json:"current_plan"`
 	NextBillingDate     *time.Time                 `json:"next_billing_date"`
 	NextBillingAmount   float64                    `json:"next_billing_amount"`
+	Currency            string                     `json:"currency"`
 	CurrentUsage        subscription.UsageMetrics  `json:"current_usage"`
 	PlanLimits          subscription.PlanLimits    `json:"plan_limits"`
 	UnpaidInvoices      []Invoice                  `json:"unpaid_invoices"`
@@ -69,7 +104,7 @@ type BillingOverviewOut struct {
 
 type BillingHistoryItem struct {
 	ID          uuid.UUID `json:"id"`
-	Type        string    `json:"type"` // "invoice" or "payment"
+	Type        string    `json:"type"` // "invoice", "payment" or "credit_note"
 	Amount      float64   `json:"amount"`
 	Currency    string    `json:"currency"`
 	Status      string    `json:"status"`
@@ -87,25 +122,104 @@ type BillingHistoryOut struct {
 	Meta inout.PaginationMeta `json:"meta"`
 }
 
+// BillingEvent is an entry of the billing event log, with the data it was
+// recorded with as is: the webhook body for PayPal events.
+type BillingEvent struct {
+	ID             uuid.UUID              `json:"id"`
+	OrganizationID uuid.UUID              `json:"organization_id"`
+	EventType      model.BillingEventType `json:"event_type"`
+	ActorType      model.BillingActorType `json:"actor_type"` // "user", "admin", "paypal" or "system"
+	ActorID        *uuid.UUID             `json:"actor_id"`
+	PayPalEventID  *string                `json:"paypal_event_id"`
+	EventData      json.RawMessage        `json:"event_data" swaggertype:"object"`
+	ProcessedAt    time.Time              `json:"processed_at"`
+	CreatedAt      time.Time              `json:"created_at"`
+}
+
+type BillingEventListOut struct {
+	inout.BaseResponse
+	List []BillingEvent       `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
+func FromBillingEventModel(event *model.BillingEvent) BillingEvent {
+	out := BillingEvent{
+		ID:             event.ID,
+		OrganizationID: event.OrganizationID,
+		EventType:      event.EventType,
+		ActorType:      event.ActorType,
+		ActorID:        event.ActorID,
+		PayPalEventID:  event.PayPalEventID,
+		ProcessedAt:    event.ProcessedAt,
+		CreatedAt:      event.CreatedAt,
+	}
+	if event.EventData != nil {
+		out.EventData = json.RawMessage(*event.EventData)
+		if !json.Valid(out.EventData) {
+			out.EventData, _ = json.Marshal(*event.EventData)
+		}
+	}
+	return out
+}
+
+func FromBillingEventModelList(events []model.BillingEvent) []BillingEvent {
+	result := make([]BillingEvent, len(events))
+	for i := range events {
+		result[i] = FromBillingEventModel(&events[i])
+	}
+	return result
+}
+
+// ForecastCharge is an invoice the organization is expected to be issued.
+type ForecastCharge struct {
+	Kind         payments.ForecastChargeKind `json:"kind"` // "renewal", "plan_change" or "trial_conversion"
+	Date         time.Time                   `json:"date"`
+	PlanID       uuid.UUID                   `json:"plan_id"`
+	PlanName     string                      `json:"plan_name"`
+	BillingCycle model.BillingCycle          `json:"billing_cycle"`
+	PeriodStart  time.Time                   `json:"period_start"`
+	PeriodEnd    time.Time                   `json:"period_end"`
+	Amount       float64                     `json:"amount"`
+	Discount     float64                     `json:"discount"`
+	TaxAmount    float64                     `json:"tax_amount"`
+	TotalAmount  float64                     `json:"total_amount"`
+}
+
+type BillingForecast struct {
+	OrganizationID    uuid.UUID        `json:"organization_id"`
+	Currency          string           `json:"currency"`
+	ForecastedAt      time.Time        `json:"forecasted_at"`
+	Until             time.Time        `json:"until"`
+	Charges           []ForecastCharge `json:"charges"`
+	TotalAmount       float64          `json:"total_amount"`
+	OutstandingAmount float64          `json:"outstanding_amount"` // open invoices, charged on the next billing run
+	EndsAt            *time.Time       `json:"ends_at"`            // the subscription ends instead of renewing
+}
+
+type BillingForecastOut struct {
+	inout.BaseResponse
+	Data BillingForecast `json:"data"`
+}
+
 type PaymentOut struct {
 	inout.BaseResponse
 	Data payment.Payment `json:"data"`
 }
 
-func FromInvoiceLineItemModel(item *model.InvoiceLineItem) InvoiceLineItem {
+func FromInvoiceLineItemModel(item *model.InvoiceLineItem, currency string) InvoiceLineItem {
 	return InvoiceLineItem{
 		ID:          item.ID,
 		Description: item.Description,
 		Quantity:    item.Quantity,
-		UnitPrice:   item.UnitPrice,
-		TotalPrice:  item.TotalPrice,
+		UnitPrice:   model.FromMinorUnits(item.UnitPrice, currency),
+		TotalPrice:  model.FromMinorUnits(item.TotalPrice, currency),
 	}
 }
 
-func FromInvoiceLineItemModelList(items []model.InvoiceLineItem) []InvoiceLineItem {
+func FromInvoiceLineItemModelList(items []model.InvoiceLineItem, currency string) []InvoiceLineItem {
 	result := make([]InvoiceLineItem, len(items))
 	for i, item := range items {
-		result[i] = FromInvoiceLineItemModel(&item)
+		result[i] = FromInvoiceLineItemModel(&item, currency)
 	}
 	return result
 }
@@ -117,22 +231,26 @@ func FromInvoiceModel(invoice *model.Invoice) Invoice {
 		SubscriptionID:     invoice.SubscriptionID,
 		PayPalInvoiceID:    invoice.PayPalInvoiceID,
 		InvoiceNumber:      invoice.InvoiceNumber,
-		Amount:             invoice.Amount,
-		TaxAmount:          invoice.TaxAmount,
-		TotalAmount:        invoice.TotalAmount,
+		Amount:             model.FromMinorUnits(invoice.Amount, invoice.Currency),
+		TaxAmount:          model.FromMinorUnits(invoice.TaxAmount, invoice.Currency),
+		TaxRate:            invoice.TaxRate,
+		TaxDescription:     invoice.TaxDescription,
+		ReverseCharge:      invoice.ReverseCharge,
+		TotalAmount:        model.FromMinorUnits(invoice.TotalAmount, invoice.Currency),
 		Currency:           invoice.Currency,
 		Status:             invoice.Status,
 		BillingPeriodStart: invoice.BillingPeriodStart,
 		BillingPeriodEnd:   invoice.BillingPeriodEnd,
 		DueDate:            invoice.DueDate,
 		PaidAt:             invoice.PaidAt,
+		RefundedAmount:     model.FromMinorUnits(invoice.RefundedAmount, invoice.Currency),
 		InvoiceURL:         invoice.InvoiceURL,
 		CreatedAt:          invoice.CreatedAt,
 		UpdatedAt:          invoice.UpdatedAt,
 	}
 
 	if invoice.LineItems != nil {
-		result.LineItems = FromInvoiceLineItemModelList(invoice.LineItems)
+		result.LineItems = FromInvoiceLineItemModelList(invoice.LineItems, invoice.Currency)
 	}
 
 	return result
@@ -145,3 +263,83 @@ func FromInvoiceModelList(invoices []model.Invoice) []Invoice {
 	}
 	return result
 }
+
+func FromCreditNoteModel(creditNote *model.CreditNote) CreditNote {
+	return CreditNote{
+		ID:               creditNote.ID,
+		OrganizationID:   creditNote.OrganizationID,
+		InvoiceID:        creditNote.InvoiceID,
+		InvoiceNumber:    creditNote.Invoice.InvoiceNumber,
+		PaymentID:        creditNote.PaymentID,
+		CreditNoteNumber: creditNote.CreditNoteNumber,
+		Amount:           model.FromMinorUnits(creditNote.Amount, creditNote.Currency),
+		Currency:         creditNote.Currency,
+		Reason:           creditNote.Reason,
+		Status:           creditNote.Status,
+		PayPalRefundID:   creditNote.PayPalRefundID,
+		FailureReason:    creditNote.FailureReason,
+		IssuedAt:         creditNote.IssuedAt,
+		CreatedAt:        creditNote.CreatedAt,
+	}
+}
+
+func FromCreditNoteModelList(creditNotes []model.CreditNote) []CreditNote {
+	result := make([]CreditNote, len(creditNotes))
+	for i, creditNote := range creditNotes {
+		result[i] = FromCreditNoteModel(&creditNote)
+	}
+	return result
+}
+
+func FromBillingForecast(forecast *payments.BillingForecast, now time.Time) BillingForecast {
+	currency := forecast.Currency
+	charges := make([]ForecastCharge, len(forecast.Charges))
+	for i, charge := range forecast.Charges {
+		charges[i] = ForecastCharge{
+			Kind:         charge.Kind,
+			Date:         charge.Date,
+			PlanID:       charge.PlanID,
+			PlanName:     charge.PlanName,
+			BillingCycle: charge.BillingCycle,
+			PeriodStart:  charge.PeriodStart,
+			PeriodEnd:    charge.PeriodEnd,
+			Amount:       model.FromMinorUnits(charge.Amount, currency),
+			Discount:     model.FromMinorUnits(charge.Discount, currency),
+			TaxAmount:    model.FromMinorUnits(charge.TaxAmount, currency),
+			TotalAmount:  model.FromMinorUnits(charge.TotalAmount, currency),
+		}
+	}
+	return BillingForecast{
+		OrganizationID:    forecast.OrganizationID,
+		Currency:          currency,
+		ForecastedAt:      now,
+		Until:             forecast.Until,
+		Charges:           charges,
+		TotalAmount:       model.FromMinorUnits(forecast.TotalAmount, currency),
+		OutstandingAmount: model.FromMinorUnits(forecast.OutstandingAmount, currency),
+		EndsAt:            forecast.EndsAt,
+	}
+}
+
+// BillingForecastCSV lays the forecast charges out as CSV records, a header
+// first, with amounts as decimals.
+func BillingForecastCSV(forecast *payments.BillingForecast) [][]string {
+	currency := forecast.Currency
+	records := [][]string{{"date", "kind", "plan", "billing_cycle", "period_start", "period_end", "amount", "discount", "tax_amount", "total_amount", "currency"}}
+	for _, charge := range forecast.Charges {
+		records = append(records, []string{
+			charge.Date.UTC().Format(time.RFC3339),
+			string(charge.Kind),
+			charge.PlanName,
+			string(charge.BillingCycle),
+			charge.PeriodStart.UTC().Format("2006-01-02"),
+			charge.PeriodEnd.UTC().Format("2006-01-02"),
+			model.FormatMinorUnits(charge.Amount, currency),
+			model.FormatMinorUnits(charge.Discount, currency),
+			model.FormatMinorUnits(charge.TaxAmount, currency),
+			model.FormatMinorUnits(charge.TotalAmount, currency),
+			currency,
+		})
+	}
+	return records
+}
@@ -0,0 +1,24 @@
+package coupon
+
+import (
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CreateCouponRequest needs PercentOff for percent coupons, AmountOff for
+// fixed ones and DurationInCycles for repeating ones.
+type CreateCouponRequest struct {
+	Code             string                   `json:"code" binding:"required,min=3,max=50,alphanum"`
+	Name             string                   `json:"name" binding:"required,max=100"`
+	DiscountType     model.CouponDiscountType `json:"discount_type" binding:"required,oneof=percent fixed"`
+	PercentOff       *float64                 `json:"percent_off" binding:"omitempty,gt=0,lte=100"`
+	AmountOff        *float64                 `json:"amount_off" binding:"omitempty,gt=0"`
+	Currency         string                   `json:"currency" binding:"omitempty,len=3"`
+	Duration         model.CouponDuration     `json:"duration" binding:"required,oneof=once repeating forever"`
+	DurationInCycles *int                     `json:"duration_in_cycles" binding:"omitempty,gte=1,lte=120"`
+	MaxRedemptions   *int                     `json:"max_redemptions" binding:"omitempty,gte=1"`
+	ExpiresAt        *time.Time               `json:"expires_at"`
+	PlanIDs          []uuid.UUID              `json:"plan_ids"` // empty for every plan
+}
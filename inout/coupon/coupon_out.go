@@ -0,0 +1,151 @@
+package coupon
+
+import (
+	"testlake/inout"
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Coupon struct {
+	ID               uuid.UUID                `json:"id"`
+	Code             string                   `json:"code"`
+	Name             string                   `json:"name"`
+	DiscountType     model.CouponDiscountType `json:"discount_type"`
+	PercentOff       *float64                 `json:"percent_off"`
+	AmountOff        *float64                 `json:"amount_off"`
+	Currency         string                   `json:"currency"`
+	Duration         model.CouponDuration     `json:"duration"`
+	DurationInCycles *int                     `json:"duration_in_cycles"`
+	MaxRedemptions   *int                     `json:"max_redemptions"`
+	TimesRedeemed    int                      `json:"times_redeemed"`
+	ExpiresAt        *time.Time               `json:"expires_at"`
+	PlanIDs          []uuid.UUID              `json:"plan_ids"`
+	IsActive         bool                     `json:"is_active"`
+	CreatedBy        *uuid.UUID               `json:"created_by"`
+	CreatedAt        time.Time                `json:"created_at"`
+	UpdatedAt        time.Time                `json:"updated_at"`
+}
+
+type CouponOut struct {
+	inout.BaseResponse
+	Data Coupon `json:"data"`
+}
+
+type CouponListOut struct {
+	inout.BaseResponse
+	List []Coupon             `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
+// Redemption is a coupon an organization redeemed and how much of it was used.
+type Redemption struct {
+	ID               uuid.UUID                `json:"id"`
+	CouponID         uuid.UUID                `json:"coupon_id"`
+	Code             string                   `json:"code"`
+	Name             string                   `json:"name"`
+	DiscountType     model.CouponDiscountType `json:"discount_type"`
+	PercentOff       *float64                 `json:"percent_off"`
+	AmountOff        *float64                 `json:"amount_off"`
+	Currency         string                   `json:"currency"`
+	Duration         model.CouponDuration     `json:"duration"`
+	OrganizationID   uuid.UUID                `json:"organization_id"`
+	OrganizationName string                   `json:"organization_name,omitempty"`
+	SubscriptionID   uuid.UUID                `json:"subscription_id"`
+	RedeemedBy       uuid.UUID                `json:"redeemed_by"`
+	CyclesApplied    int                      `json:"cycles_applied"`
+	CyclesRemaining  *int                     `json:"cycles_remaining"` // nil when the discount runs forever
+	Active           bool                     `json:"active"`
+	EndedAt          *time.Time               `json:"ended_at"`
+	RedeemedAt       time.Time                `json:"redeemed_at"`
+}
+
+type RedemptionListOut struct {
+	inout.BaseResponse
+	List []Redemption         `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
+func FromCouponModel(coupon *model.Coupon) Coupon {
+	planIDs := coupon.RestrictedPlanIDs()
+	if planIDs == nil {
+		planIDs = []uuid.UUID{}
+	}
+	return Coupon{
+		ID:               coupon.ID,
+		Code:             coupon.Code,
+		Name:             coupon.Name,
+		DiscountType:     coupon.DiscountType,
+		PercentOff:       coupon.PercentOff,
+		AmountOff:        fromMinorUnits(coupon.AmountOff, coupon.Currency),
+		Currency:         coupon.Currency,
+		Duration:         coupon.Duration,
+		DurationInCycles: coupon.DurationInCycles,
+		MaxRedemptions:   coupon.MaxRedemptions,
+		TimesRedeemed:    coupon.TimesRedeemed,
+		ExpiresAt:        coupon.ExpiresAt,
+		PlanIDs:          planIDs,
+		IsActive:         coupon.IsActive,
+		CreatedBy:        coupon.CreatedBy,
+		CreatedAt:        coupon.CreatedAt,
+		UpdatedAt:        coupon.UpdatedAt,
+	}
+}
+
+func FromCouponModelList(coupons []model.Coupon) []Coupon {
+	result := make([]Coupon, len(coupons))
+	for i, coupon := range coupons {
+		result[i] = FromCouponModel(&coupon)
+	}
+	return result
+}
+
+// FromRedemptionModel needs the Coupon relation loaded, and Organization for
+// the organization name.
+func FromRedemptionModel(redemption *model.CouponRedemption) Redemption {
+	var remaining *int
+	if total := redemption.Coupon.TotalCycles(); total > 0 {
+		left := max(0, total-redemption.CyclesApplied)
+		if redemption.EndedAt != nil {
+			left = 0
+		}
+		remaining = &left
+	}
+	return Redemption{
+		ID:               redemption.ID,
+		CouponID:         redemption.CouponID,
+		Code:             redemption.Coupon.Code,
+		Name:             redemption.Coupon.Name,
+		DiscountType:     redemption.Coupon.DiscountType,
+		PercentOff:       redemption.Coupon.PercentOff,
+		AmountOff:        fromMinorUnits(redemption.Coupon.AmountOff, redemption.Coupon.Currency),
+		Currency:         redemption.Coupon.Currency,
+		Duration:         redemption.Coupon.Duration,
+		OrganizationID:   redemption.OrganizationID,
+		OrganizationName: redemption.Organization.Name,
+		SubscriptionID:   redemption.SubscriptionID,
+		RedeemedBy:       redemption.RedeemedBy,
+		CyclesApplied:    redemption.CyclesApplied,
+		CyclesRemaining:  remaining,
+		Active:           redemption.HasCyclesLeft(),
+		EndedAt:          redemption.EndedAt,
+		RedeemedAt:       redemption.CreatedAt,
+	}
+}
+
+func FromRedemptionModelList(redemptions []model.CouponRedemption) []Redemption {
+	result := make([]Redemption, len(redemptions))
+	for i, redemption := range redemptions {
+		result[i] = FromRedemptionModel(&redemption)
+	}
+	return result
+}
+
+func fromMinorUnits(amount *int64, currency string) *float64 {
+	if amount == nil {
+		return nil
+	}
+	decimal := model.FromMinorUnits(*amount, currency)
+	return &decimal
+}
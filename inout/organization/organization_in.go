@@ -21,6 +21,26 @@ type UpdateOrganizationRequest struct {
 	MaxProjects *int            `json:"max_projects" binding:"omitempty,min=1"`
 }
 
+// UpdateBillingDetailsRequest replaces the billing details, fields left out are
+// cleared. Country is an ISO 3166-1 alpha-2 code such as DE.
+type UpdateBillingDetailsRequest struct {
+	BillingEmail *string `json:"billing_email" binding:"omitempty,email,max=255"`
+	Name         *string `json:"name" binding:"omitempty,max=200"`
+	AddressLine1 *string `json:"address_line1" binding:"omitempty,max=255"`
+	AddressLine2 *string `json:"address_line2" binding:"omitempty,max=255"`
+	City         *string `json:"city" binding:"omitempty,max=100"`
+	PostalCode   *string `json:"postal_code" binding:"omitempty,max=20"`
+	State        *string `json:"state" binding:"omitempty,max=100"`
+	Country      *string `json:"country" binding:"omitempty,iso3166_1_alpha2"`
+	TaxID        *string `json:"tax_id" binding:"omitempty,max=50"`
+}
+
+// AddBillingContactRequest adds an address the billing emails are also sent to.
+type AddBillingContactRequest struct {
+	Email string  `json:"email" binding:"required,email,max=255"`
+	Name  *string `json:"name" binding:"omitempty,max=200"`
+}
+
 type InviteMemberRequest struct {
 	Email string `json:"email" binding:"required,email"`
 	Role  string `json:"role" binding:"required,oneof=member admin"`
@@ -29,3 +49,13 @@ type InviteMemberRequest struct {
 type UpdateMemberRoleRequest struct {
 	Role string `json:"role" binding:"required,oneof=member admin"`
 }
+
+type SSOConfigRequest struct {
+	Issuer         string   `json:"issuer" binding:"required,url"`
+	ClientID       string   `json:"client_id" binding:"required,max=255"`
+	ClientSecret   *string  `json:"client_secret" binding:"omitempty,max=500"` // kept when omitted
+	AllowedDomains []string `json:"allowed_domains" binding:"required,min=1,dive,required,fqdn"`
+	DefaultRole    string   `json:"default_role" binding:"omitempty,oneof=member admin"`
+	EnforceSSO     bool     `json:"enforce_sso"`
+	IsEnabled      *bool    `json:"is_enabled"`
+}
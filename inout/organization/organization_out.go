@@ -5,6 +5,7 @@ import (
 
 	"testlake/inout"
 	"testlake/model"
+	"testlake/utils"
 
 	"github.com/google/uuid"
 )
@@ -49,6 +50,19 @@ type MembersOut struct {
 	Data []Member `json:"data"`
 }
 
+type MemberActivity struct {
+	Member
+	Status         string     `json:"status"`
+	LastLoginAt    *time.Time `json:"last_login_at"`
+	LastSeenAt     *time.Time `json:"last_seen_at"`
+	ActiveSessions int64      `json:"active_sessions"`
+}
+
+type MembersActivityOut struct {
+	inout.BaseResponse
+	Data []MemberActivity `json:"data"`
+}
+
 type InviteResult struct {
 	Email   string `json:"email"`
 	Status  string `json:"status"`
@@ -74,6 +88,20 @@ type PendingInvitesOut struct {
 	Data []PendingInvite `json:"data"`
 }
 
+// LimitsCheck is the usage of an organization checked against its plan.
+type LimitsCheck struct {
+	PlanID         *uuid.UUID            `json:"plan_id"`
+	PlanName       string                `json:"plan_name"`
+	Usage          model.UsageCounts     `json:"usage"`
+	Checks         []utils.LimitCheck    `json:"checks"`
+	UpgradeOptions []utils.UpgradeOption `json:"upgrade_options,omitempty"` // when the checked resource does not fit
+}
+
+type LimitsCheckOut struct {
+	inout.BaseResponse
+	Data LimitsCheck `json:"data"`
+}
+
 func FromModel(org *model.Organization) Organization {
 	return Organization{
 		ID:          org.ID,
@@ -137,3 +165,141 @@ func FromInvitationModelList(invitations []model.OrganizationInvitation) []Pendi
 	}
 	return result
 }
+
+type SSOConfig struct {
+	ID              uuid.UUID                    `json:"id"`
+	OrganizationID  uuid.UUID                    `json:"organization_id"`
+	Issuer          string                       `json:"issuer"`
+	ClientID        string                       `json:"client_id"`
+	HasClientSecret bool                         `json:"has_client_secret"`
+	AllowedDomains  []string                     `json:"allowed_domains"`
+	Domains         []SSODomain                  `json:"domains"`
+	DefaultRole     model.OrganizationMemberRole `json:"default_role"`
+	EnforceSSO      bool                         `json:"enforce_sso"`
+	IsEnabled       bool                         `json:"is_enabled"`
+	RedirectURL     string                       `json:"redirect_url"`
+	LoginURL        string                       `json:"login_url"`
+	CreatedAt       time.Time                    `json:"created_at"`
+	UpdatedAt       time.Time                    `json:"updated_at"`
+}
+
+type SSOConfigOut struct {
+	inout.BaseResponse
+	Data SSOConfig `json:"data"`
+}
+
+// SSODomain is an allowed domain and the TXT record that verifies it. Logins
+// are only routed to the organization once the domain is verified.
+type SSODomain struct {
+	Domain     string     `json:"domain"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	TXTName    string     `json:"txt_name"`
+	TXTValue   string     `json:"txt_value"`
+}
+
+// FromSSOConfigModel never exposes the client secret, only whether one is set
+func FromSSOConfigModel(config *model.OrganizationSSOConfig, domains []model.OrganizationSSODomain, redirectURL, loginURL string) SSOConfig {
+	domainsOut := make([]SSODomain, len(domains))
+	for i, domain := range domains {
+		domainsOut[i] = SSODomain{
+			Domain:     domain.Domain,
+			Verified:   domain.IsVerified(),
+			VerifiedAt: domain.VerifiedAt,
+			TXTName:    domain.TXTRecordName(),
+			TXTValue:   domain.TXTRecordValue(),
+		}
+	}
+
+	return SSOConfig{
+		ID:              config.ID,
+		OrganizationID:  config.OrganizationID,
+		Issuer:          config.Issuer,
+		ClientID:        config.ClientID,
+		HasClientSecret: config.ClientSecret != "",
+		AllowedDomains:  config.DomainList(),
+		Domains:         domainsOut,
+		DefaultRole:     config.DefaultRole,
+		EnforceSSO:      config.EnforceSSO,
+		IsEnabled:       config.IsEnabled,
+		RedirectURL:     redirectURL,
+		LoginURL:        loginURL,
+		CreatedAt:       config.CreatedAt,
+		UpdatedAt:       config.UpdatedAt,
+	}
+}
+
+// BillingDetails is who invoices are addressed to and the tax they are charged.
+type BillingDetails struct {
+	BillingEmail *string    `json:"billing_email"`
+	Name         *string    `json:"name"`
+	AddressLine1 *string    `json:"address_line1"`
+	AddressLine2 *string    `json:"address_line2"`
+	City         *string    `json:"city"`
+	PostalCode   *string    `json:"postal_code"`
+	State        *string    `json:"state"`
+	Country      *string    `json:"country"`
+	TaxID        *string    `json:"tax_id"`
+	Tax          TaxPreview `json:"tax"`
+}
+
+// TaxPreview is the tax the organization's invoices are charged.
+type TaxPreview struct {
+	Rate          float64 `json:"rate"`
+	ReverseCharge bool    `json:"reverse_charge"`
+	Description   string  `json:"description"`
+}
+
+type BillingDetailsOut struct {
+	inout.BaseResponse
+	Data BillingDetails `json:"data"`
+}
+
+func FromBillingDetailsModel(org *model.Organization, tax utils.TaxResult) BillingDetails {
+	return BillingDetails{
+		BillingEmail: org.BillingEmail,
+		Name:         org.BillingName,
+		AddressLine1: org.BillingAddressLine1,
+		AddressLine2: org.BillingAddressLine2,
+		City:         org.BillingCity,
+		PostalCode:   org.BillingPostalCode,
+		State:        org.BillingState,
+		Country:      org.BillingCountry,
+		TaxID:        org.TaxID,
+		Tax: TaxPreview{
+			Rate:          tax.Rate,
+			ReverseCharge: tax.ReverseCharge,
+			Description:   tax.Description,
+		},
+	}
+}
+
+// BillingContact is an address the billing emails go to besides the billing
+// email.
+type BillingContact struct {
+	ID        uuid.UUID `json:"id"`
+	Email     string    `json:"email"`
+	Name      *string   `json:"name"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type BillingContactOut struct {
+	inout.BaseResponse
+	Data BillingContact `json:"data"`
+}
+
+type BillingContactsOut struct {
+	inout.BaseResponse
+	Data []BillingContact `json:"data"`
+}
+
+func FromBillingContactModel(contact *model.BillingContact) BillingContact {
+	return BillingContact{
+		ID:        contact.ID,
+		Email:     contact.Email,
+		Name:      contact.Name,
+		CreatedBy: contact.CreatedBy,
+		CreatedAt: contact.CreatedAt,
+	}
+}
@@ -19,6 +19,12 @@ type PaymentMethod struct {
 	CreatedBy         uuid.UUID               `json:"created_by"`
 	CreatedAt         time.Time               `json:"created_at"`
 	UpdatedAt         time.Time               `json:"updated_at"`
+
+	Status        model.PaymentMethodStatus `json:"status"`
+	FailureReason *string                   `json:"failure_reason"`
+	InvalidatedAt *time.Time                `json:"invalidated_at"`
+	// Set on a pending method: the payer approves saving it there
+	ApprovalURL string `json:"approval_url,omitempty"`
 }
 
 type PaymentMethodOut struct {
@@ -43,9 +49,11 @@ type Payment struct {
 	PaymentMethod   model.PaymentMethodEnum `json:"payment_method"`
 	Status          model.PaymentStatus     `json:"status"`
 	FailureReason   *string                 `json:"failure_reason"`
+	RefundedAmount  float64                 `json:"refunded_amount"`
 	ProcessedAt     *time.Time              `json:"processed_at"`
 	CreatedAt       time.Time               `json:"created_at"`
 	UpdatedAt       time.Time               `json:"updated_at"`
+	ApprovalURL     string                  `json:"approval_url,omitempty"`
 }
 
 type PaymentOut struct {
@@ -71,6 +79,9 @@ func FromPaymentMethodModel(pm *model.PaymentMethod) PaymentMethod {
 		CreatedBy:         pm.CreatedBy,
 		CreatedAt:         pm.CreatedAt,
 		UpdatedAt:         pm.UpdatedAt,
+		Status:            pm.Status,
+		FailureReason:     pm.FailureReason,
+		InvalidatedAt:     pm.InvalidatedAt,
 	}
 }
 
@@ -90,11 +101,12 @@ func FromPaymentModel(payment *model.Payment) Payment {
 		SubscriptionID:  payment.SubscriptionID,
 		PayPalPaymentID: payment.PayPalPaymentID,
 		PayPalPayerID:   payment.PayPalPayerID,
-		Amount:          payment.Amount,
+		Amount:          model.FromMinorUnits(payment.Amount, payment.Currency),
 		Currency:        payment.Currency,
 		PaymentMethod:   payment.PaymentMethod,
 		Status:          payment.Status,
 		FailureReason:   payment.FailureReason,
+		RefundedAmount:  model.FromMinorUnits(payment.RefundedAmount, payment.Currency),
 		ProcessedAt:     payment.ProcessedAt,
 		CreatedAt:       payment.CreatedAt,
 		UpdatedAt:       payment.UpdatedAt,
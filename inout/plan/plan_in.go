@@ -0,0 +1,51 @@
+package plan
+
+// PlanPriceRequest prices a plan in a currency other than USD, in decimals of
+// that currency.
+type PlanPriceRequest struct {
+	Currency     string  `json:"currency" binding:"required,len=3"`
+	PriceMonthly float64 `json:"price_monthly" binding:"gte=0"`
+	PriceYearly  float64 `json:"price_yearly" binding:"gte=0"`
+}
+
+// CreatePlanRequest adds a plan to the catalogue. PriceMonthly and PriceYearly
+// are USD decimals; limits are -1 for unlimited.
+type CreatePlanRequest struct {
+	Name                    string             `json:"name" binding:"required,max=100"`
+	Slug                    string             `json:"slug" binding:"required,max=50"`
+	Description             *string            `json:"description"`
+	PriceMonthly            float64            `json:"price_monthly" binding:"gte=0"`
+	PriceYearly             float64            `json:"price_yearly" binding:"gte=0"`
+	Prices                  []PlanPriceRequest `json:"prices" binding:"omitempty,dive"`
+	MaxUsers                int                `json:"max_users" binding:"gte=-1"`
+	MaxProjects             int                `json:"max_projects" binding:"gte=-1"`
+	MaxEnvironments         int                `json:"max_environments" binding:"gte=-1"`
+	MaxSchemas              int                `json:"max_schemas" binding:"gte=-1"`
+	MaxTestRecordsPerSchema int                `json:"max_test_records_per_schema" binding:"gte=-1"`
+	Features                []string           `json:"features"`
+	UsageAlertThresholds    []int              `json:"usage_alert_thresholds" binding:"omitempty,dive,gt=0,lte=100"`
+}
+
+// UpdatePlanRequest edits a plan in place, for its current subscribers too.
+// Fields left out are unchanged. Prices only change with a new version.
+type UpdatePlanRequest struct {
+	Name                    *string  `json:"name" binding:"omitempty,max=100"`
+	Description             *string  `json:"description"`
+	MaxUsers                *int     `json:"max_users" binding:"omitempty,gte=-1"`
+	MaxProjects             *int     `json:"max_projects" binding:"omitempty,gte=-1"`
+	MaxEnvironments         *int     `json:"max_environments" binding:"omitempty,gte=-1"`
+	MaxSchemas              *int     `json:"max_schemas" binding:"omitempty,gte=-1"`
+	MaxTestRecordsPerSchema *int     `json:"max_test_records_per_schema" binding:"omitempty,gte=-1"`
+	Features                []string `json:"features"`
+	UsageAlertThresholds    []int    `json:"usage_alert_thresholds" binding:"omitempty,dive,gt=0,lte=100"`
+}
+
+// CreatePlanVersionRequest replaces a plan with a new version for new
+// subscribers. Fields left out, Prices included, are copied from the current
+// version; Prices, when given, replace every non-USD price.
+type CreatePlanVersionRequest struct {
+	UpdatePlanRequest
+	PriceMonthly *float64           `json:"price_monthly" binding:"omitempty,gte=0"`
+	PriceYearly  *float64           `json:"price_yearly" binding:"omitempty,gte=0"`
+	Prices       []PlanPriceRequest `json:"prices" binding:"omitempty,dive"`
+}
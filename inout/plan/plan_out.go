@@ -1,6 +1,8 @@
 package plan
 
 import (
+	"encoding/json"
+	"slices"
 	"testlake/inout"
 	"testlake/model"
 	"time"
@@ -15,6 +17,8 @@ type Plan struct {
 	Description             *string   `json:"description"`
 	PriceMonthly            float64   `json:"price_monthly"`
 	PriceYearly             float64   `json:"price_yearly"`
+	Currency                string    `json:"currency"`   // of the prices
+	Currencies              []string  `json:"currencies"` // the plan can be subscribed in
 	MaxUsers                int       `json:"max_users"`
 	MaxProjects             int       `json:"max_projects"`
 	MaxEnvironments         int       `json:"max_environments"`
@@ -45,14 +49,25 @@ type PlanComparisonOut struct {
 	Data PlanComparison `json:"data"`
 }
 
-func FromPlanModel(p *model.Plan) Plan {
+// FromPlanModel prices the plan in currency, or in USD when the plan is not
+// priced in it. The Prices relation must be loaded.
+func FromPlanModel(p *model.Plan, currency string) Plan {
+	monthly, monthlyFound := p.PriceIn(currency, model.BillingCycleMonthly)
+	yearly, yearlyFound := p.PriceIn(currency, model.BillingCycleYearly)
+	if !monthlyFound || !yearlyFound || currency == "" {
+		currency = model.DefaultCurrency
+		monthly, yearly = p.PriceMonthly, p.PriceYearly
+	}
+
 	plan := Plan{
 		ID:                      p.ID,
 		Name:                    p.Name,
 		Slug:                    p.Slug,
 		Description:             p.Description,
-		PriceMonthly:            p.PriceMonthly,
-		PriceYearly:             p.PriceYearly,
+		PriceMonthly:            model.FromMinorUnits(monthly, currency),
+		PriceYearly:             model.FromMinorUnits(yearly, currency),
+		Currency:                currency,
+		Currencies:              planCurrencies(p),
 		MaxUsers:                p.MaxUsers,
 		MaxProjects:             p.MaxProjects,
 		MaxEnvironments:         p.MaxEnvironments,
@@ -71,10 +86,121 @@ func FromPlanModel(p *model.Plan) Plan {
 	return plan
 }
 
-func FromPlanModelList(plans []model.Plan) []Plan {
+func FromPlanModelList(plans []model.Plan, currency string) []Plan {
 	result := make([]Plan, len(plans))
 	for i, plan := range plans {
-		result[i] = FromPlanModel(&plan)
+		result[i] = FromPlanModel(&plan, currency)
+	}
+	return result
+}
+
+// planCurrencies lists USD and every currency the plan has both prices in.
+func planCurrencies(p *model.Plan) []string {
+	currencies := []string{model.DefaultCurrency}
+	for _, price := range p.Prices {
+		if slices.Contains(currencies, price.Currency) {
+			continue
+		}
+		_, monthly := p.PriceIn(price.Currency, model.BillingCycleMonthly)
+		_, yearly := p.PriceIn(price.Currency, model.BillingCycleYearly)
+		if monthly && yearly {
+			currencies = append(currencies, price.Currency)
+		}
+	}
+	return currencies
+}
+
+// Price is a price point of a plan, in decimals of its currency.
+type Price struct {
+	Currency     string             `json:"currency"`
+	BillingCycle model.BillingCycle `json:"billing_cycle"`
+	Price        float64            `json:"price"`
+	PayPalPlanID *string            `json:"paypal_plan_id"`
+}
+
+// CataloguePlan is a version of a plan as platform admins manage it, with
+// every price point and its provider plan.
+type CataloguePlan struct {
+	ID                      uuid.UUID  `json:"id"`
+	Name                    string     `json:"name"`
+	Slug                    string     `json:"slug"`
+	Version                 int        `json:"version"`
+	PreviousVersionID       *uuid.UUID `json:"previous_version_id"`
+	Description             *string    `json:"description"`
+	Prices                  []Price    `json:"prices"`
+	MaxUsers                int        `json:"max_users"`
+	MaxProjects             int        `json:"max_projects"`
+	MaxEnvironments         int        `json:"max_environments"`
+	MaxSchemas              int        `json:"max_schemas"`
+	MaxTestRecordsPerSchema int        `json:"max_test_records_per_schema"`
+	Features                []string   `json:"features"`
+	UsageAlertThresholds    []int      `json:"usage_alert_thresholds"`
+	PayPalProductID         *string    `json:"paypal_product_id"`
+	IsActive                bool       `json:"is_active"`
+	RetiredAt               *time.Time `json:"retired_at"`
+	Subscribers             *int64     `json:"subscribers,omitempty"` // active subscriptions on this version
+	CreatedAt               time.Time  `json:"created_at"`
+	UpdatedAt               time.Time  `json:"updated_at"`
+}
+
+type CataloguePlanOut struct {
+	inout.BaseResponse
+	Data CataloguePlan `json:"data"`
+}
+
+type CataloguePlanListOut struct {
+	inout.BaseResponse
+	List []CataloguePlan      `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
+// FromCataloguePlanModel needs the Prices relation loaded.
+func FromCataloguePlanModel(p *model.Plan) CataloguePlan {
+	prices := []Price{
+		{Currency: model.DefaultCurrency, BillingCycle: model.BillingCycleMonthly, Price: model.FromMinorUnits(p.PriceMonthly, model.DefaultCurrency), PayPalPlanID: p.PayPalMonthlyPlanID},
+		{Currency: model.DefaultCurrency, BillingCycle: model.BillingCycleYearly, Price: model.FromMinorUnits(p.PriceYearly, model.DefaultCurrency), PayPalPlanID: p.PayPalYearlyPlanID},
+	}
+	for _, price := range p.Prices {
+		prices = append(prices, Price{
+			Currency:     price.Currency,
+			BillingCycle: price.BillingCycle,
+			Price:        model.FromMinorUnits(price.Amount, price.Currency),
+			PayPalPlanID: price.PayPalPlanID,
+		})
+	}
+
+	features := []string{}
+	if err := json.Unmarshal([]byte(p.Features), &features); err != nil || features == nil {
+		features = []string{}
+	}
+
+	return CataloguePlan{
+		ID:                      p.ID,
+		Name:                    p.Name,
+		Slug:                    p.Slug,
+		Version:                 p.Version,
+		PreviousVersionID:       p.PreviousVersionID,
+		Description:             p.Description,
+		Prices:                  prices,
+		MaxUsers:                p.MaxUsers,
+		MaxProjects:             p.MaxProjects,
+		MaxEnvironments:         p.MaxEnvironments,
+		MaxSchemas:              p.MaxSchemas,
+		MaxTestRecordsPerSchema: p.MaxTestRecordsPerSchema,
+		Features:                features,
+		UsageAlertThresholds:    p.AlertThresholds(),
+		PayPalProductID:         p.PayPalProductID,
+		IsActive:                p.IsActive,
+		RetiredAt:               p.RetiredAt,
+		CreatedAt:               p.CreatedAt,
+		UpdatedAt:               p.UpdatedAt,
+	}
+}
+
+func FromCataloguePlanModelList(plans []model.Plan) []CataloguePlan {
+	result := make([]CataloguePlan, len(plans))
+	for i, plan := range plans {
+		result[i] = FromCataloguePlanModel(&plan)
 	}
 	return result
 }
@@ -9,9 +9,18 @@ import (
 type CreateSubscriptionRequest struct {
 	PlanID       uuid.UUID          `json:"plan_id" binding:"required"`
 	BillingCycle model.BillingCycle `json:"billing_cycle" binding:"required"`
+	// StartTrial starts a free trial of a paid plan instead of charging right away
+	StartTrial bool `json:"start_trial"`
+	// CouponCode redeems a coupon, discounting the subscription
+	CouponCode string `json:"coupon_code" binding:"omitempty,max=50"`
+	// Currency is the ISO 4217 currency the subscription is billed in, the
+	// organization's currency by default
+	Currency string `json:"currency" binding:"omitempty,len=3"`
 }
 
 type ChangePlanRequest struct {
 	NewPlanID    uuid.UUID          `json:"new_plan_id" binding:"required"`
 	BillingCycle model.BillingCycle `json:"billing_cycle" binding:"required"`
+	// CouponCode redeems a coupon for the new plan
+	CouponCode string `json:"coupon_code" binding:"omitempty,max=50"`
 }
@@ -2,6 +2,7 @@ package subscription
 
 import (
 	"testlake/inout"
+	"testlake/inout/coupon"
 	"testlake/model"
 	"time"
 
@@ -9,20 +10,25 @@ import (
 )
 
 type Subscription struct {
-	ID                   uuid.UUID                `json:"id"`
-	OrganizationID       uuid.UUID                `json:"organization_id"`
-	PlanID               uuid.UUID                `json:"plan_id"`
-	PayPalSubscriptionID string                   `json:"paypal_subscription_id"`
-	Status               model.SubscriptionStatus `json:"status"`
-	BillingCycle         model.BillingCycle       `json:"billing_cycle"`
-	CurrentPeriodStart   time.Time                `json:"current_period_start"`
-	CurrentPeriodEnd     time.Time                `json:"current_period_end"`
-	TrialEnd             *time.Time               `json:"trial_end"`
-	CancelAtPeriodEnd    bool                     `json:"cancel_at_period_end"`
-	CancelledAt          *time.Time               `json:"cancelled_at"`
-	CreatedBy            uuid.UUID                `json:"created_by"`
-	CreatedAt            time.Time                `json:"created_at"`
-	UpdatedAt            time.Time                `json:"updated_at"`
+	ID                    uuid.UUID                `json:"id"`
+	OrganizationID        uuid.UUID                `json:"organization_id"`
+	PlanID                uuid.UUID                `json:"plan_id"`
+	PayPalSubscriptionID  *string                  `json:"paypal_subscription_id"`
+	Status                model.SubscriptionStatus `json:"status"`
+	BillingCycle          model.BillingCycle       `json:"billing_cycle"`
+	CurrentPeriodStart    time.Time                `json:"current_period_start"`
+	CurrentPeriodEnd      time.Time                `json:"current_period_end"`
+	TrialEnd              *time.Time               `json:"trial_end"`
+	CancelAtPeriodEnd     bool                     `json:"cancel_at_period_end"`
+	CancelledAt           *time.Time               `json:"cancelled_at"`
+	ScheduledPlanID       *uuid.UUID               `json:"scheduled_plan_id"` // downgrade applied when the current period ends
+	ScheduledBillingCycle *model.BillingCycle      `json:"scheduled_billing_cycle"`
+	CreatedBy             uuid.UUID                `json:"created_by"`
+	CreatedAt             time.Time                `json:"created_at"`
+	UpdatedAt             time.Time                `json:"updated_at"`
+	ApprovalURL           string                   `json:"approval_url,omitempty"` // where the payer approves the subscription at the provider
+	ProrationInvoiceID    *uuid.UUID               `json:"proration_invoice_id,omitempty"`
+	Discount              *coupon.Redemption       `json:"discount,omitempty"` // coupon discounting the subscription
 }
 
 type SubscriptionOut struct {
@@ -30,6 +36,18 @@ type SubscriptionOut struct {
 	Data Subscription `json:"data"`
 }
 
+// CouponRedemptionsOut lists the coupons an organization redeemed.
+type CouponRedemptionsOut struct {
+	inout.BaseResponse
+	List []coupon.Redemption `json:"list"`
+}
+
+// PlanLimitsExceededOut lists the resources that keep a plan change from being made.
+type PlanLimitsExceededOut struct {
+	inout.BaseResponse
+	Data []model.LimitExcess `json:"data"`
+}
+
 type UsageMetrics struct {
 	UsersCount        int `json:"users_count"`
 	ProjectsCount     int `json:"projects_count"`
@@ -61,19 +79,21 @@ type SubscriptionUsageOut struct {
 
 func FromSubscriptionModel(s *model.Subscription) Subscription {
 	return Subscription{
-		ID:                   s.ID,
-		OrganizationID:       s.OrganizationID,
-		PlanID:               s.PlanID,
-		PayPalSubscriptionID: s.PayPalSubscriptionID,
-		Status:               s.Status,
-		BillingCycle:         s.BillingCycle,
-		CurrentPeriodStart:   s.CurrentPeriodStart,
-		CurrentPeriodEnd:     s.CurrentPeriodEnd,
-		TrialEnd:             s.TrialEnd,
-		CancelAtPeriodEnd:    s.CancelAtPeriodEnd,
-		CancelledAt:          s.CancelledAt,
-		CreatedBy:            s.CreatedBy,
-		CreatedAt:            s.CreatedAt,
-		UpdatedAt:            s.UpdatedAt,
+		ID:                    s.ID,
+		OrganizationID:        s.OrganizationID,
+		PlanID:                s.PlanID,
+		PayPalSubscriptionID:  s.PayPalSubscriptionID,
+		Status:                s.Status,
+		BillingCycle:          s.BillingCycle,
+		CurrentPeriodStart:    s.CurrentPeriodStart,
+		CurrentPeriodEnd:      s.CurrentPeriodEnd,
+		TrialEnd:              s.TrialEnd,
+		CancelAtPeriodEnd:     s.CancelAtPeriodEnd,
+		CancelledAt:           s.CancelledAt,
+		ScheduledPlanID:       s.ScheduledPlanID,
+		ScheduledBillingCycle: s.ScheduledBillingCycle,
+		CreatedBy:             s.CreatedBy,
+		CreatedAt:             s.CreatedAt,
+		UpdatedAt:             s.UpdatedAt,
 	}
 }
@@ -65,21 +65,6 @@ type LimitsCheckOut struct {
 	Data LimitsCheck `json:"data"`
 }
 
-type BillingForecast struct {
-	OrganizationID      uuid.UUID `json:"organization_id"`
-	CurrentPeriodAmount float64   `json:"current_period_amount"`
-	NextPeriodAmount    float64   `json:"next_period_amount"`
-	ProjectedAmount     float64   `json:"projected_amount"`
-	Currency            string    `json:"currency"`
-	ForecastedAt        time.Time `json:"forecasted_at"`
-	BillingCycle        string    `json:"billing_cycle"`
-}
-
-type BillingForecastOut struct {
-	inout.BaseResponse
-	Data BillingForecast `json:"data"`
-}
-
 func FromUsageModel(usage *model.OrganizationUsage, limits *model.Plan) CurrentUsage {
 	utilizationPct := make(map[string]float64)
 
@@ -127,7 +112,7 @@ func FromUsageModelList(usages []model.OrganizationUsage) []UsageDataPoint {
 	result := make([]UsageDataPoint, len(usages))
 	for i, usage := range usages {
 		result[i] = UsageDataPoint{
-			Date:              usage.RecordedAt,
+			Date:              usage.PeriodStart,
 			UsersCount:        usage.UsersCount,
 			ProjectsCount:     usage.ProjectsCount,
 			EnvironmentsCount: usage.EnvironmentsCount,
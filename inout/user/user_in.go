@@ -6,6 +6,11 @@ type UpdateUserRequest struct {
 	AvatarURL *string `json:"avatar_url"`
 }
 
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
 type AcceptInviteRequest struct {
 	Token string `json:"token"`
 }
@@ -94,6 +94,22 @@ type NotificationsOut struct {
 	Data []Notification `json:"data"`
 }
 
+func FromNotificationModelList(notifications []model.Notification) []Notification {
+	result := make([]Notification, len(notifications))
+	for i, notification := range notifications {
+		result[i] = Notification{
+			ID:        notification.ID,
+			Title:     notification.Title,
+			Message:   notification.Message,
+			Type:      string(notification.Type),
+			IsRead:    notification.IsRead(),
+			CreatedAt: notification.CreatedAt,
+			ReadAt:    notification.ReadAt,
+		}
+	}
+	return result
+}
+
 type PendingInvite struct {
 	ID               uuid.UUID `json:"id"`
 	OrganizationID   uuid.UUID `json:"organization_id"`
@@ -109,6 +125,73 @@ type PendingInvitesOut struct {
 	Data []PendingInvite `json:"data"`
 }
 
+type Session struct {
+	ID         uuid.UUID        `json:"id"`
+	IPAddress  string           `json:"ip_address"`
+	UserAgent  string           `json:"user_agent"`
+	AuthMethod model.AuthMethod `json:"auth_method"`
+	CreatedAt  time.Time        `json:"created_at"`
+	LastSeenAt time.Time        `json:"last_seen_at"`
+	ExpiresAt  time.Time        `json:"expires_at"`
+	IsCurrent  bool             `json:"is_current"`
+}
+
+type SessionsOut struct {
+	inout.BaseResponse
+	Data []Session `json:"data"`
+}
+
+type LoginHistoryItem struct {
+	ID         uuid.UUID          `json:"id"`
+	IPAddress  string             `json:"ip_address"`
+	UserAgent  string             `json:"user_agent"`
+	AuthMethod model.AuthMethod   `json:"auth_method"`
+	Outcome    model.LoginOutcome `json:"outcome"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type LoginHistoryOut struct {
+	inout.BaseResponse
+	List []LoginHistoryItem   `json:"list"`
+	Meta inout.PaginationMeta `json:"meta"`
+}
+
+func FromSessionModel(session *model.UserSession, currentSessionID uuid.UUID) Session {
+	return Session{
+		ID:         session.ID,
+		IPAddress:  session.IPAddress,
+		UserAgent:  session.UserAgent,
+		AuthMethod: session.AuthMethod,
+		CreatedAt:  session.CreatedAt,
+		LastSeenAt: session.LastSeenAt,
+		ExpiresAt:  session.ExpiresAt,
+		IsCurrent:  session.ID == currentSessionID,
+	}
+}
+
+func FromSessionModelList(sessions []model.UserSession, currentSessionID uuid.UUID) []Session {
+	result := make([]Session, len(sessions))
+	for i, session := range sessions {
+		result[i] = FromSessionModel(&session, currentSessionID)
+	}
+	return result
+}
+
+func FromLoginEventModelList(events []model.LoginEvent) []LoginHistoryItem {
+	result := make([]LoginHistoryItem, len(events))
+	for i, event := range events {
+		result[i] = LoginHistoryItem{
+			ID:         event.ID,
+			IPAddress:  event.IPAddress,
+			UserAgent:  event.UserAgent,
+			AuthMethod: event.AuthMethod,
+			Outcome:    event.Outcome,
+			CreatedAt:  event.CreatedAt,
+		}
+	}
+	return result
+}
+
 func FromModel(user *model.User) User {
 	return User{
 		ID:              user.ID,
@@ -0,0 +1,464 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const billingLockKey = 727155202
+
+// BillingJob is the billing run: it ends trials, applies scheduled plan
+// changes, rolls subscriptions over at the end of their period, keeps the
+// discounted prices of provider subscriptions up to date, charges the invoices
+// this creates, runs the dunning of the ones that do not get paid and
+// completes the refunds the payment provider did not confirm.
+// The interval is BILLING_JOB_INTERVAL_MINUTES, hourly by default.
+func BillingJob() Job {
+	return Job{
+		Name:     "billing",
+		Interval: intervalFromEnv("BILLING_JOB_INTERVAL_MINUTES", time.Hour),
+		LockKey:  billingLockKey,
+		Run: func(ctx context.Context) error {
+			return RunBilling(ctx, time.Now())
+		},
+	}
+}
+
+// RunBilling runs every billing step once. Each step only picks up work that is
+// still pending, so running it again, e.g. after a crash, never bills twice.
+func RunBilling(ctx context.Context, now time.Time) error {
+	if err := SendTrialReminders(now); err != nil {
+		return fmt.Errorf("send trial reminders: %w", err)
+	}
+	if err := EndTrials(now); err != nil {
+		return fmt.Errorf("end trials: %w", err)
+	}
+	if err := ApplyScheduledPlanChanges(ctx, now); err != nil {
+		return fmt.Errorf("apply scheduled plan changes: %w", err)
+	}
+	if err := RenewSubscriptions(ctx, now); err != nil {
+		return fmt.Errorf("renew subscriptions: %w", err)
+	}
+	if err := SyncGatewayDiscounts(ctx, now); err != nil {
+		return fmt.Errorf("sync gateway discounts: %w", err)
+	}
+	if err := ChargeOpenInvoices(ctx, now); err != nil {
+		return fmt.Errorf("charge invoices: %w", err)
+	}
+	if err := RunDunning(ctx, now); err != nil {
+		return fmt.Errorf("run dunning: %w", err)
+	}
+	if err := RetryPendingRefunds(ctx, now); err != nil {
+		return fmt.Errorf("retry pending refunds: %w", err)
+	}
+	return nil
+}
+
+// RenewSubscriptions moves every subscription whose period ended into its next
+// period and invoices that period, or ends it when it was cancelled at period
+// end. A subscription several periods behind is rolled one period at a time.
+func RenewSubscriptions(ctx context.Context, now time.Time) error {
+	subscriptionDao := dao.NewSubscriptionDao()
+	failed := map[string]bool{}
+
+	for {
+		subscriptions, err := subscriptionDao.GetDueForRenewal(now)
+		if err != nil {
+			return err
+		}
+
+		renewed := 0
+		for _, sub := range subscriptions {
+			if failed[sub.ID.String()] {
+				continue
+			}
+			if err := renewSubscription(ctx, sub.ID, now); err != nil {
+				log.Printf("Failed to renew subscription %s: %v", sub.ID, err)
+				failed[sub.ID.String()] = true
+				continue
+			}
+			renewed++
+		}
+
+		if renewed == 0 {
+			return nil
+		}
+	}
+}
+
+func renewSubscription(ctx context.Context, subscriptionID uuid.UUID, now time.Time) error {
+	var cancelledGatewayID *string
+	var organizationID uuid.UUID
+	var notice *utils.BillingNotice
+
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		// The row lock and the re-check keep two runs from rolling the same period
+		sub, err := dao.NewSubscriptionDao().WithTx(tx).GetForUpdate(subscriptionID)
+		if err != nil {
+			return err
+		}
+		if sub.Status != model.SubscriptionStatusActive || sub.CurrentPeriodEnd.After(now) {
+			return nil
+		}
+		organizationID = sub.OrganizationID
+
+		if sub.CancelAtPeriodEnd {
+			cancelledGatewayID = sub.PayPalSubscriptionID
+			return endSubscription(tx, sub)
+		}
+		// A trial is only billed once EndTrials converted it
+		if sub.InTrialPeriod() {
+			org, err := dao.NewOrganizationDao().WithTx(tx).GetByID(sub.OrganizationID)
+			if err != nil || org.SubscriptionStatus == model.OrganizationSubscriptionStatusTrialing {
+				return err
+			}
+		}
+		notice, err = rollPeriod(tx, sub)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sendBillingNotice(organizationID, notice)
+
+	// The provider subscription was suspended when the cancellation was requested
+	if cancelledGatewayID != nil && *cancelledGatewayID != "" {
+		if err := utils.GetPaymentGateway().CancelSubscription(ctx, *cancelledGatewayID, "Cancelled at period end"); err != nil {
+			log.Printf("Failed to cancel gateway subscription %s: %v", *cancelledGatewayID, err)
+		}
+	}
+	return nil
+}
+
+// endSubscription cancels a subscription at the end of its last period and
+// moves the organization to the free plan.
+func endSubscription(tx *gorm.DB, sub *model.Subscription) error {
+	cancelledAt := sub.CurrentPeriodEnd
+	sub.Status = model.SubscriptionStatusCancelled
+	sub.CancelledAt = &cancelledAt
+	sub.ScheduledPlanID = nil
+	sub.ScheduledBillingCycle = nil
+	if err := dao.NewSubscriptionDao().WithTx(tx).Update(sub); err != nil {
+		return err
+	}
+
+	orgDao := dao.NewOrganizationDao().WithTx(tx)
+	org, err := orgDao.GetByID(sub.OrganizationID)
+	if err != nil {
+		return err
+	}
+	org.SubscriptionStatus = model.OrganizationSubscriptionStatusCancelled
+	org.NextBillingDate = nil
+	freePlan, err := dao.NewPlanDao().WithTx(tx).GetBySlug("free")
+	if err == nil {
+		org.PlanID = &freePlan.ID
+		org.BillingCycle = model.BillingCycleMonthly
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err := orgDao.Update(org); err != nil {
+		return err
+	}
+
+	return dao.NewBillingEventDao().WithTx(tx).Record(sub.OrganizationID, model.BillingEventTypeSubscriptionCancelled, map[string]interface{}{
+		"subscription_id": sub.ID,
+		"plan_id":         sub.PlanID,
+		"cancelled_at":    cancelledAt,
+		"reason":          "cancelled at period end",
+	})
+}
+
+// rollPeriod starts the subscription's next period and invoices it in advance,
+// less the discount of a coupon the organization redeemed and plus tax. Free
+// plans get no invoice. It returns the email sending the invoice, once tx is
+// committed.
+func rollPeriod(tx *gorm.DB, sub *model.Subscription) (*utils.BillingNotice, error) {
+	periodStart := sub.CurrentPeriodEnd
+	periodEnd := model.PeriodEndAfter(periodStart, sub.BillingCycle)
+	price, found := sub.Price(&sub.Plan, sub.BillingCycle)
+	if !found {
+		return nil, fmt.Errorf("plan %s has no %s %s price", sub.Plan.Slug, sub.BillingCycle, sub.Currency)
+	}
+
+	orgDao := dao.NewOrganizationDao().WithTx(tx)
+	org, err := orgDao.GetByID(sub.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoice *model.Invoice
+	if price > 0 {
+		invoiceDao := dao.NewInvoiceDao().WithTx(tx)
+		invoiceNumber, err := invoiceDao.GenerateInvoiceNumber()
+		if err != nil {
+			return nil, err
+		}
+		lineItems := []model.InvoiceLineItem{{
+			Description: fmt.Sprintf("%s plan (%s), %s to %s", sub.Plan.Name, sub.BillingCycle, periodStart.Format("2006-01-02"), periodEnd.Format("2006-01-02")),
+			Quantity:    1,
+			UnitPrice:   price,
+			TotalPrice:  price,
+		}}
+		total := price
+		discount, err := payments.DiscountLine(tx, sub, periodStart, price, 1, sub.Currency)
+		if err != nil {
+			return nil, err
+		}
+		if discount != nil {
+			lineItems = append(lineItems, *discount)
+			total += discount.TotalPrice
+		}
+
+		invoice = &model.Invoice{
+			OrganizationID:     sub.OrganizationID,
+			SubscriptionID:     &sub.ID,
+			InvoiceNumber:      invoiceNumber,
+			Amount:             total,
+			Currency:           sub.Currency,
+			Status:             model.InvoiceStatusSent,
+			BillingPeriodStart: &periodStart,
+			BillingPeriodEnd:   &periodEnd,
+			DueDate:            &periodStart,
+		}
+		if err := payments.ApplyTax(org, invoice); err != nil {
+			return nil, err
+		}
+		// A period discounted in full has nothing to collect
+		if invoice.TotalAmount <= 0 {
+			invoice.MarkPaid(periodStart)
+		}
+		if err := invoiceDao.CreateWithLineItems(invoice, lineItems); err != nil {
+			return nil, err
+		}
+		if sub.IsGatewayBilled() && !invoice.IsPaid() {
+			if err := matchProviderPayment(tx, sub, invoice); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sub.CurrentPeriodStart = periodStart
+	sub.CurrentPeriodEnd = periodEnd
+	if err := dao.NewSubscriptionDao().WithTx(tx).Update(sub); err != nil {
+		return nil, err
+	}
+
+	org.NextBillingDate = &periodEnd
+	if err := orgDao.Update(org); err != nil {
+		return nil, err
+	}
+
+	if invoice == nil {
+		return nil, nil
+	}
+	err = dao.NewBillingEventDao().WithTx(tx).Record(sub.OrganizationID, model.BillingEventTypeInvoiceCreated, map[string]interface{}{
+		"subscription_id": sub.ID,
+		"invoice_id":      invoice.ID,
+		"invoice_number":  invoice.InvoiceNumber,
+		"amount":          invoice.TotalAmount,
+		"currency":        invoice.Currency,
+		"period_start":    periodStart,
+		"period_end":      periodEnd,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return payments.InvoiceIssuedNotice(invoice), nil
+}
+
+// matchProviderPayment settles the invoice with a renewal payment the provider
+// reported before the invoice existed. Provider subscriptions charge themselves,
+// usually around the same time the billing run issues the invoice.
+func matchProviderPayment(tx *gorm.DB, sub *model.Subscription, invoice *model.Invoice) error {
+	paymentDao := dao.NewPaymentDao().WithTx(tx)
+	unmatched, err := paymentDao.GetUnmatchedCompleted(sub.ID, invoice.BillingPeriodStart.AddDate(0, 0, -3))
+	if err != nil {
+		return err
+	}
+	for _, payment := range unmatched {
+		if payment.Currency != invoice.Currency || payment.Amount != invoice.TotalAmount {
+			continue
+		}
+		paidAt := time.Now()
+		if payment.ProcessedAt != nil {
+			paidAt = *payment.ProcessedAt
+		}
+		invoice.MarkPaid(paidAt)
+		if err := dao.NewInvoiceDao().WithTx(tx).Update(invoice); err != nil {
+			return err
+		}
+		payment.InvoiceID = &invoice.ID
+		return paymentDao.Update(&payment)
+	}
+	return nil
+}
+
+// ChargeOpenInvoices charges the default payment method of organizations for
+// the open invoices nobody tried to collect yet. Period invoices of
+// subscriptions billed by the provider are left alone, the provider charges
+// them itself, but their proration invoices are charged here.
+func ChargeOpenInvoices(ctx context.Context, now time.Time) error {
+	invoiceDao := dao.NewInvoiceDao()
+	failed := map[string]bool{}
+
+	for {
+		invoices, err := invoiceDao.GetUncharged()
+		if err != nil {
+			return err
+		}
+
+		attempted := 0
+		for i := range invoices {
+			invoice := &invoices[i]
+			if failed[invoice.ID.String()] {
+				continue
+			}
+			charged, err := chargeInvoice(ctx, invoice, now)
+			if err != nil {
+				log.Printf("Failed to charge invoice %s: %v", invoice.InvoiceNumber, err)
+			}
+			if !charged {
+				failed[invoice.ID.String()] = true
+				continue
+			}
+			attempted++
+		}
+
+		if attempted == 0 {
+			return nil
+		}
+	}
+}
+
+// chargeInvoice reports whether a payment was attempted, successful or not. The
+// payment row is stored before the provider is called and its ID is the
+// idempotency key, so a crash in between cannot lead to a second charge. A
+// failed charge starts or advances the dunning of the invoice.
+func chargeInvoice(ctx context.Context, invoice *model.Invoice, now time.Time) (bool, error) {
+	paymentMethod, err := dao.NewPaymentMethodDao().GetDefaultByOrganizationID(invoice.OrganizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && !paymentMethod.IsChargeable()) {
+		log.Printf("Invoice %s stays open: organization %s has no chargeable default payment method", invoice.InvoiceNumber, invoice.OrganizationID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	payment := &model.Payment{
+		OrganizationID: invoice.OrganizationID,
+		InvoiceID:      &invoice.ID,
+		SubscriptionID: invoice.SubscriptionID,
+		Amount:         invoice.TotalAmount,
+		Currency:       invoice.Currency,
+		PaymentMethod:  model.PaymentMethodEnumPayPal,
+		Status:         model.PaymentStatusPending,
+	}
+	paymentDao := dao.NewPaymentDao()
+	if err := paymentDao.Create(payment); err != nil {
+		return false, err
+	}
+
+	capture, err := utils.GetPaymentGateway().ChargePaymentMethod(ctx, utils.GatewayChargeRequest{
+		VaultID:        *paymentMethod.PayPalVaultID,
+		ReferenceID:    invoice.ID.String(),
+		CustomID:       invoice.ID.String(),
+		InvoiceNumber:  invoice.InvoiceNumber,
+		Description:    "Invoice " + invoice.InvoiceNumber,
+		Amount:         invoice.TotalAmount,
+		Currency:       invoice.Currency,
+		IdempotencyKey: payment.ID.String(),
+	})
+	if err == nil && (capture.Status != "COMPLETED" || capture.Amount != invoice.TotalAmount) {
+		err = fmt.Errorf("capture %s is %s for %s %s", capture.ID, capture.Status, model.FormatMinorUnits(capture.Amount, capture.Currency), capture.Currency)
+	}
+	if err != nil {
+		return true, recordFailedCharge(invoice, payment, paymentMethod, err, now)
+	}
+
+	var notice *utils.BillingNotice
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		payment.Status = model.PaymentStatusCompleted
+		payment.PayPalPaymentID = &capture.ID
+		payment.PayPalPayerID = &capture.PayerID
+		payment.ProcessedAt = &now
+		if err := dao.NewPaymentDao().WithTx(tx).Update(payment); err != nil {
+			return err
+		}
+
+		var err error
+		notice, err = payments.SettleInvoice(tx, invoice, now, model.SystemActor)
+		if err != nil {
+			return err
+		}
+
+		return dao.NewBillingEventDao().WithTx(tx).Record(invoice.OrganizationID, model.BillingEventTypePaymentSucceeded, map[string]interface{}{
+			"invoice_id": invoice.ID,
+			"payment_id": payment.ID,
+			"capture_id": capture.ID,
+			"amount":     capture.Amount,
+		})
+	})
+	if err != nil {
+		return true, err
+	}
+	sendBillingNotice(invoice.OrganizationID, notice)
+	return true, nil
+}
+
+// recordFailedCharge fails the payment and advances the dunning of the invoice.
+// A payment method the provider reports unusable is invalidated as well, so
+// dunning retries wait for another one instead of failing the same way.
+func recordFailedCharge(invoice *model.Invoice, payment *model.Payment, paymentMethod *model.PaymentMethod, chargeErr error, now time.Time) error {
+	var notice *utils.BillingNotice
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		reason := chargeErr.Error()
+		payment.Status = model.PaymentStatusFailed
+		payment.FailureReason = &reason
+		payment.ProcessedAt = &now
+		if err := dao.NewPaymentDao().WithTx(tx).Update(payment); err != nil {
+			return err
+		}
+
+		err := dao.NewBillingEventDao().WithTx(tx).Record(payment.OrganizationID, model.BillingEventTypePaymentFailed, map[string]interface{}{
+			"invoice_id": payment.InvoiceID,
+			"payment_id": payment.ID,
+			"reason":     reason,
+		})
+		if err != nil {
+			return err
+		}
+
+		if utils.IsPaymentMethodFailure(chargeErr) {
+			if err := payments.InvalidatePaymentMethod(tx, paymentMethod, reason, now); err != nil {
+				return err
+			}
+			err := dao.NewBillingEventDao().WithTx(tx).Record(payment.OrganizationID, model.BillingEventTypePaymentMethodInvalidated, map[string]interface{}{
+				"payment_method_id": paymentMethod.ID,
+				"payment_id":        payment.ID,
+				"reason":            reason,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		notice, err = advanceDunning(tx, invoice, now, reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sendBillingNotice(invoice.OrganizationID, notice)
+	return nil
+}
@@ -0,0 +1,82 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"gorm.io/gorm"
+)
+
+// SyncGatewayDiscounts keeps what the provider charges discounted subscriptions
+// in line with the invoices the billing run issues for them: the discounted
+// price while the discount lasts, the plan price once it is used up or the
+// subscription moved to a plan the coupon is not for.
+func SyncGatewayDiscounts(ctx context.Context, now time.Time) error {
+	redemptions, err := dao.NewCouponDao().GetGatewayBilledRedemptions()
+	if err != nil {
+		return err
+	}
+
+	for i := range redemptions {
+		if err := syncGatewayDiscount(ctx, &redemptions[i], now); err != nil {
+			log.Printf("Failed to sync the discounted price of subscription %s: %v", redemptions[i].SubscriptionID, err)
+		}
+	}
+	return nil
+}
+
+func syncGatewayDiscount(ctx context.Context, redemption *model.CouponRedemption, now time.Time) error {
+	sub := &redemption.Subscription
+	// A scheduled plan change revises the provider subscription first
+	if sub.HasScheduledChange() {
+		return nil
+	}
+	price, found := sub.Price(&sub.Plan, sub.BillingCycle)
+	if !found {
+		return fmt.Errorf("plan %s has no %s %s price", sub.Plan.Slug, sub.BillingCycle, sub.Currency)
+	}
+	var discounted *int64
+	if redemption.HasCyclesLeft() && redemption.Coupon.AppliesToPlan(sub.PlanID) {
+		discountedPrice := price - redemption.Coupon.Discount(price, sub.Currency)
+		discounted = &discountedPrice
+	}
+	if (discounted == nil && redemption.GatewayPrice == nil) ||
+		(discounted != nil && redemption.GatewayPrice != nil && *discounted == *redemption.GatewayPrice) {
+		return nil
+	}
+
+	// The provider charges each period when it starts: wait for the charge of
+	// the current period so that the new price applies from the next one
+	charged, err := dao.NewPaymentDao().HasCompletedSince(sub.ID, sub.CurrentPeriodStart.AddDate(0, 0, -3))
+	if err != nil || !charged {
+		return err
+	}
+
+	if discounted != nil {
+		price = *discounted
+	}
+	if err := utils.GetPaymentGateway().UpdateSubscriptionPrice(ctx, *sub.PayPalSubscriptionID, price, sub.Currency); err != nil {
+		return err
+	}
+
+	return dao.Transaction(func(tx *gorm.DB) error {
+		redemption.GatewayPrice = discounted
+		if err := dao.NewCouponDao().WithTx(tx).UpdateRedemption(redemption); err != nil {
+			return err
+		}
+		return dao.NewBillingEventDao().WithTx(tx).Record(sub.OrganizationID, model.BillingEventTypeSubscriptionUpdated, map[string]interface{}{
+			"subscription_id": sub.ID,
+			"coupon_id":       redemption.CouponID,
+			"gateway_price":   price,
+			"discounted":      discounted != nil,
+			"effective_from":  sub.CurrentPeriodEnd,
+			"updated_at":      now,
+		})
+	})
+}
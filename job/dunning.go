@@ -0,0 +1,221 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// dunningGracePeriod is how long an invoice nobody tried to charge may stay
+// overdue before its dunning starts.
+const dunningGracePeriod = 24 * time.Hour
+
+// RunDunning collects the invoices that are not getting paid. It starts dunning
+// for invoices overdue past the grace period, a failed charge starts it right
+// away, and retries those whose next retry is due. Every step moves the
+// organization to past due, and to suspended after the last retry, and emails
+// its billing contact.
+func RunDunning(ctx context.Context, now time.Time) error {
+	if err := startOverdueDunning(now); err != nil {
+		return err
+	}
+	return retryDunning(ctx, now)
+}
+
+func startOverdueDunning(now time.Time) error {
+	invoiceDao := dao.NewInvoiceDao()
+	failed := map[string]bool{}
+
+	for {
+		invoices, err := invoiceDao.GetOverdueOutsideDunning(now.Add(-dunningGracePeriod))
+		if err != nil {
+			return err
+		}
+
+		started := 0
+		for i := range invoices {
+			invoice := &invoices[i]
+			if failed[invoice.ID.String()] {
+				continue
+			}
+			if err := advanceDunningAlone(invoice, now, "Invoice is overdue"); err != nil {
+				log.Printf("Failed to start dunning for invoice %s: %v", invoice.InvoiceNumber, err)
+				failed[invoice.ID.String()] = true
+				continue
+			}
+			started++
+		}
+
+		if started == 0 {
+			return nil
+		}
+	}
+}
+
+func retryDunning(ctx context.Context, now time.Time) error {
+	invoiceDao := dao.NewInvoiceDao()
+	failed := map[string]bool{}
+
+	for {
+		invoices, err := invoiceDao.GetDueDunningRetries(now)
+		if err != nil {
+			return err
+		}
+
+		retried := 0
+		for i := range invoices {
+			invoice := &invoices[i]
+			if failed[invoice.ID.String()] {
+				continue
+			}
+			if err := retryInvoice(ctx, invoice, now); err != nil {
+				log.Printf("Failed to retry invoice %s: %v", invoice.InvoiceNumber, err)
+				failed[invoice.ID.String()] = true
+				continue
+			}
+			retried++
+		}
+
+		if retried == 0 {
+			return nil
+		}
+	}
+}
+
+// retryInvoice charges the invoice again; a failed charge advances its dunning.
+// Provider subscriptions retry their payments themselves, except for proration
+// invoices, and invoices without a payment method cannot be charged, so for
+// those the retry only reminds the organization and moves the dunning along.
+func retryInvoice(ctx context.Context, invoice *model.Invoice, now time.Time) error {
+	if invoice.IsProration || invoice.Subscription == nil || !invoice.Subscription.IsGatewayBilled() {
+		attempted, err := chargeInvoice(ctx, invoice, now)
+		if attempted || err != nil {
+			return err
+		}
+	}
+	return advanceDunningAlone(invoice, now, "No payment could be attempted")
+}
+
+func advanceDunningAlone(invoice *model.Invoice, now time.Time, reason string) error {
+	var notice *utils.BillingNotice
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		var err error
+		notice, err = advanceDunning(tx, invoice, now, reason)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	sendBillingNotice(invoice.OrganizationID, notice)
+	return nil
+}
+
+// advanceDunning moves the invoice one step through dunning after its payment
+// failed or could not be attempted: the first step starts dunning, the next
+// ones schedule the following retry and, once the last retry failed, the
+// organization is suspended until the invoice is paid. It returns the notice
+// to email once the transaction is committed.
+func advanceDunning(tx *gorm.DB, invoice *model.Invoice, now time.Time, reason string) (*utils.BillingNotice, error) {
+	orgStatus := model.OrganizationSubscriptionStatusPastDue
+	var eventType model.BillingEventType
+	notice := &utils.BillingNotice{
+		InvoiceNumber: invoice.InvoiceNumber,
+		AmountDue:     formatAmount(invoice.TotalAmount, invoice.Currency),
+		InvoiceID:     &invoice.ID,
+		Kind:          model.InvoiceEmailKindFailed,
+	}
+
+	switch invoice.DunningStatus {
+	case model.DunningStatusRetrying:
+		invoice.DunningRetries++
+		if invoice.DunningRetries >= len(model.DunningRetrySchedule) {
+			invoice.DunningStatus = model.DunningStatusExhausted
+			invoice.NextRetryAt = nil
+			orgStatus = model.OrganizationSubscriptionStatusSuspended
+			eventType = model.BillingEventTypeDunningExhausted
+			notice.Subject = "Your Organization Has Been Suspended"
+			notice.Heading = "Organization Suspended"
+			notice.Message = fmt.Sprintf("We still could not collect the payment for invoice %s after %d attempts, so your organization has been suspended. It stays read-only until the invoice is paid.",
+				invoice.InvoiceNumber, invoice.DunningRetries+1)
+			break
+		}
+		nextRetry := dunningStart(invoice, now).Add(model.DunningRetrySchedule[invoice.DunningRetries])
+		invoice.NextRetryAt = &nextRetry
+		eventType = model.BillingEventTypeDunningRetryFailed
+		notice.Subject = "Payment Retry Failed"
+		notice.Heading = "Payment Retry Failed"
+		notice.Message = fmt.Sprintf("Attempt %d of %d to collect the payment for invoice %s failed. Please pay the invoice or update your payment method before %s, when we will try again.",
+			invoice.DunningRetries+1, len(model.DunningRetrySchedule)+1, invoice.InvoiceNumber, nextRetry.Format("January 2, 2006"))
+	case model.DunningStatusExhausted:
+		return nil, nil
+	default:
+		nextRetry := now.Add(model.DunningRetrySchedule[0])
+		invoice.DunningStatus = model.DunningStatusRetrying
+		invoice.DunningRetries = 0
+		invoice.DunningStartedAt = &now
+		invoice.NextRetryAt = &nextRetry
+		eventType = model.BillingEventTypeDunningStarted
+		notice.Subject = "Payment Failed"
+		notice.Heading = "We Could Not Collect Your Payment"
+		notice.Message = fmt.Sprintf("The payment for invoice %s did not go through. Please pay the invoice or update your payment method before %s, when we will try again. Your organization will be suspended if the last attempt fails too.",
+			invoice.InvoiceNumber, nextRetry.Format("January 2, 2006"))
+	}
+
+	if err := dao.NewInvoiceDao().WithTx(tx).Update(invoice); err != nil {
+		return nil, err
+	}
+
+	orgDao := dao.NewOrganizationDao().WithTx(tx)
+	org, err := orgDao.GetByID(invoice.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	// Another invoice may already have suspended the organization
+	if org.SubscriptionStatus != model.OrganizationSubscriptionStatusSuspended {
+		org.SubscriptionStatus = orgStatus
+		if err := orgDao.Update(org); err != nil {
+			return nil, err
+		}
+	}
+
+	err = dao.NewBillingEventDao().WithTx(tx).Record(invoice.OrganizationID, eventType, map[string]interface{}{
+		"invoice_id":     invoice.ID,
+		"invoice_number": invoice.InvoiceNumber,
+		"retries":        invoice.DunningRetries,
+		"next_retry_at":  invoice.NextRetryAt,
+		"reason":         reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notice, nil
+}
+
+// sendBillingNotice emails the notice, if any. A failed email never fails billing.
+func sendBillingNotice(organizationID uuid.UUID, notice *utils.BillingNotice) {
+	if notice == nil {
+		return
+	}
+	if err := utils.SendBillingNotice(organizationID, *notice); err != nil {
+		log.Printf("Failed to send billing notice %q to organization %s: %v", notice.Subject, organizationID, err)
+	}
+}
+
+func dunningStart(invoice *model.Invoice, now time.Time) time.Time {
+	if invoice.DunningStartedAt != nil {
+		return *invoice.DunningStartedAt
+	}
+	return now
+}
+
+func formatAmount(amount int64, currency string) string {
+	return model.FormatMinorUnits(amount, currency) + " " + currency
+}
@@ -0,0 +1,75 @@
+// Package job runs the periodic background work of the application, such as
+// billing. Every instance starts the jobs; a Postgres advisory lock per job
+// makes sure only one of them runs it at a time.
+package job
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"testlake/dao"
+)
+
+type Job struct {
+	Name     string
+	Interval time.Duration
+	// LockKey is the advisory lock that keeps instances from running the job together
+	LockKey int64
+	Run     func(ctx context.Context) error
+}
+
+// Start runs each job now and then once per interval until ctx is done.
+func Start(ctx context.Context, jobs ...Job) {
+	for _, job := range jobs {
+		go loop(ctx, job)
+	}
+}
+
+func loop(ctx context.Context, job Job) {
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := RunOnce(ctx, job); err != nil {
+			log.Printf("Job %s failed: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce runs the job unless another instance is already running it.
+func RunOnce(ctx context.Context, job Job) error {
+	release, acquired, err := dao.TryAdvisoryLock(ctx, job.LockKey)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		log.Printf("Job %s is running on another instance, skipping", job.Name)
+		return nil
+	}
+	defer release()
+
+	started := time.Now()
+	if err := job.Run(ctx); err != nil {
+		return err
+	}
+	log.Printf("Job %s finished in %s", job.Name, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+// intervalFromEnv reads an interval in minutes, falling back when unset or invalid.
+func intervalFromEnv(name string, fallback time.Duration) time.Duration {
+	minutes, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || minutes <= 0 {
+		return fallback
+	}
+	return time.Duration(minutes) * time.Minute
+}
@@ -0,0 +1,138 @@
+package job
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+
+	"gorm.io/gorm"
+)
+
+// ApplyScheduledPlanChanges moves subscriptions whose period ended to the plan a
+// downgrade scheduled for them. A subscription that fails is logged and tried
+// again on the next run.
+func ApplyScheduledPlanChanges(ctx context.Context, now time.Time) error {
+	subscriptionDao := dao.NewSubscriptionDao()
+	failed := map[string]bool{}
+
+	for {
+		subscriptions, err := subscriptionDao.GetDueScheduledChanges(now)
+		if err != nil {
+			return err
+		}
+
+		applied := 0
+		for i := range subscriptions {
+			sub := &subscriptions[i]
+			if failed[sub.ID.String()] {
+				continue
+			}
+			if err := applyScheduledPlanChange(ctx, sub); err != nil {
+				log.Printf("Failed to apply scheduled plan change of subscription %s: %v", sub.ID, err)
+				failed[sub.ID.String()] = true
+				continue
+			}
+			applied++
+		}
+
+		if applied == 0 || len(subscriptions) < subscriptionDao.Limit {
+			return nil
+		}
+	}
+}
+
+func applyScheduledPlanChange(ctx context.Context, sub *model.Subscription) error {
+	newPlan, err := dao.NewPlanDao().GetByID(*sub.ScheduledPlanID)
+	if err != nil {
+		return err
+	}
+	cycle := sub.BillingCycle
+	if sub.ScheduledBillingCycle != nil {
+		cycle = *sub.ScheduledBillingCycle
+	}
+
+	// Usage may have grown since the change was scheduled; keep the current plan then
+	usage, err := dao.NewOrganizationUsageDao().CountLiveUsage(sub.OrganizationID)
+	if err != nil {
+		return err
+	}
+	if exceeded := usage.ExceededLimits(newPlan); len(exceeded) > 0 {
+		log.Printf("Dropping scheduled plan change of subscription %s: usage exceeds %d limit(s) of plan %s", sub.ID, len(exceeded), newPlan.Slug)
+		return dao.Transaction(func(tx *gorm.DB) error {
+			if err := dao.NewSubscriptionDao().WithTx(tx).ClearScheduledChange(sub.ID); err != nil {
+				return err
+			}
+			return dao.NewBillingEventDao().WithTx(tx).Record(sub.OrganizationID, model.BillingEventTypeSubscriptionUpdated, map[string]interface{}{
+				"subscription_id": sub.ID,
+				"dropped_plan_id": newPlan.ID,
+				"exceeded_limits": exceeded,
+				"reason":          "usage exceeds the limits of the scheduled plan",
+			})
+		})
+	}
+
+	gateway := utils.GetPaymentGateway()
+	newPrice, found := sub.Price(newPlan, cycle)
+	if !found {
+		return fmt.Errorf("plan %s has no %s %s price", newPlan.Slug, cycle, sub.Currency)
+	}
+	// A subscription the billing run charges is renewed at the new plan's price
+	if newPrice > 0 && !payments.IsVaultBilled(sub) {
+		gatewayPlanID := newPlan.PayPalPlanIDIn(sub.Currency, cycle)
+		if gatewayPlanID == nil || !sub.IsGatewayBilled() {
+			return fmt.Errorf("plan %s cannot be billed by the payment provider", newPlan.Slug)
+		}
+		if _, err := gateway.ReviseSubscription(ctx, *sub.PayPalSubscriptionID, *gatewayPlanID); err != nil {
+			return err
+		}
+	} else if newPrice == 0 && sub.IsGatewayBilled() {
+		if err := gateway.CancelSubscription(ctx, *sub.PayPalSubscriptionID, "Changed to a free plan"); err != nil {
+			return err
+		}
+		sub.PayPalSubscriptionID = nil
+	}
+
+	previousPlanID := sub.PlanID
+	previousCycle := sub.BillingCycle
+	sub.PlanID = newPlan.ID
+	sub.Plan = *newPlan
+	sub.BillingCycle = cycle
+	sub.ScheduledPlanID = nil
+	sub.ScheduledBillingCycle = nil
+
+	return dao.Transaction(func(tx *gorm.DB) error {
+		if err := dao.NewSubscriptionDao().WithTx(tx).Update(sub); err != nil {
+			return err
+		}
+		// The provider charges the new plan's price; SyncGatewayDiscounts prices a discount in again
+		if err := dao.NewCouponDao().WithTx(tx).ClearGatewayPrice(sub.ID); err != nil {
+			return err
+		}
+
+		orgDao := dao.NewOrganizationDao().WithTx(tx)
+		org, err := orgDao.GetByID(sub.OrganizationID)
+		if err != nil {
+			return err
+		}
+		org.PlanID = &newPlan.ID
+		org.BillingCycle = cycle
+		if err := orgDao.Update(org); err != nil {
+			return err
+		}
+
+		return dao.NewBillingEventDao().WithTx(tx).Record(sub.OrganizationID, model.BillingEventTypePlanChanged, map[string]interface{}{
+			"subscription_id":    sub.ID,
+			"from_plan_id":       previousPlanID,
+			"from_billing_cycle": previousCycle,
+			"to_plan_id":         newPlan.ID,
+			"to_billing_cycle":   cycle,
+			"scheduled":          true,
+		})
+	})
+}
@@ -0,0 +1,32 @@
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/payments"
+)
+
+// pendingRefundAge is how long a refund stays pending before the billing run
+// asks the provider again, e.g. after the provider did not answer.
+const pendingRefundAge = 15 * time.Minute
+
+// RetryPendingRefunds asks the provider again for the refunds it never
+// confirmed. The credit note ID is the idempotency key, so a refund the
+// provider already made is not made twice.
+func RetryPendingRefunds(ctx context.Context, now time.Time) error {
+	creditNotes, err := dao.NewCreditNoteDao().GetPendingBefore(now.Add(-pendingRefundAge))
+	if err != nil {
+		return err
+	}
+
+	for i := range creditNotes {
+		creditNote := &creditNotes[i]
+		if _, err := payments.RetryRefund(ctx, creditNote, now); err != nil {
+			log.Printf("Failed to complete refund %s: %v", creditNote.CreditNoteNumber, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package job
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SendTrialReminders reminds organizations that their trial ends soon, once per
+// trial, TRIAL_REMINDER_DAYS before it ends.
+func SendTrialReminders(now time.Time) error {
+	subscriptionDao := dao.NewSubscriptionDao()
+	subscriptions, err := subscriptionDao.GetTrialsToRemind(now, now.Add(utils.LoadTrialConfig().ReminderBefore))
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subscriptions {
+		// Marked first: a reminder that failed to send is not worth a second one
+		if err := subscriptionDao.MarkTrialReminderSent(sub.ID, now); err != nil {
+			log.Printf("Failed to mark trial reminder of subscription %s: %v", sub.ID, err)
+			continue
+		}
+
+		message := fmt.Sprintf("Your trial of the %s plan ends on %s. Add a payment method before then to keep the plan; otherwise your organization moves to the free plan.",
+			sub.Plan.Name, sub.TrialEnd.Format("January 2, 2006"))
+		if payments.HasChargeablePaymentMethod(sub.OrganizationID) {
+			message = fmt.Sprintf("Your trial of the %s plan ends on %s. Your default payment method will then be charged %s for the first %s period.",
+				sub.Plan.Name, sub.TrialEnd.Format("January 2, 2006"), formatAmount(firstPeriodPrice(&sub), sub.Currency), sub.BillingCycle)
+		}
+		sendBillingNotice(sub.OrganizationID, &utils.BillingNotice{
+			Subject: "Your Trial Ends Soon",
+			Heading: "Your Trial Ends Soon",
+			Message: message,
+		})
+	}
+	return nil
+}
+
+// EndTrials ends the trials that are over. Organizations with a payment method
+// convert to the paid plan: the renewal that follows invoices and charges its
+// first period. The others move to the free plan.
+func EndTrials(now time.Time) error {
+	subscriptionDao := dao.NewSubscriptionDao()
+	failed := map[string]bool{}
+
+	for {
+		subscriptions, err := subscriptionDao.GetEndedTrials(now)
+		if err != nil {
+			return err
+		}
+
+		ended := 0
+		for _, sub := range subscriptions {
+			if failed[sub.ID.String()] {
+				continue
+			}
+			if err := endTrial(sub.ID, payments.HasChargeablePaymentMethod(sub.OrganizationID), now); err != nil {
+				log.Printf("Failed to end trial of subscription %s: %v", sub.ID, err)
+				failed[sub.ID.String()] = true
+				continue
+			}
+			ended++
+		}
+
+		if ended == 0 {
+			return nil
+		}
+	}
+}
+
+func endTrial(subscriptionID uuid.UUID, convert bool, now time.Time) error {
+	var notice *utils.BillingNotice
+	var organizationID uuid.UUID
+
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		sub, err := dao.NewSubscriptionDao().WithTx(tx).GetForUpdate(subscriptionID)
+		if err != nil {
+			return err
+		}
+		orgDao := dao.NewOrganizationDao().WithTx(tx)
+		org, err := orgDao.GetByID(sub.OrganizationID)
+		if err != nil {
+			return err
+		}
+		if sub.Status != model.SubscriptionStatusActive || sub.IsTrialing(now) ||
+			org.SubscriptionStatus != model.OrganizationSubscriptionStatusTrialing {
+			return nil
+		}
+		organizationID = org.ID
+
+		if convert {
+			org.SubscriptionStatus = model.OrganizationSubscriptionStatusActive
+			if err := orgDao.Update(org); err != nil {
+				return err
+			}
+			notice = &utils.BillingNotice{
+				Subject: "Your Trial Has Ended",
+				Heading: "Welcome To The " + sub.Plan.Name + " Plan",
+				Message: fmt.Sprintf("Your trial has ended and your organization stays on the %s plan. Your default payment method is charged %s for the first %s period.",
+					sub.Plan.Name, formatAmount(firstPeriodPrice(sub), sub.Currency), sub.BillingCycle),
+			}
+			return dao.NewBillingEventDao().WithTx(tx).Record(org.ID, model.BillingEventTypeTrialConverted, map[string]interface{}{
+				"subscription_id": sub.ID,
+				"plan_id":         sub.PlanID,
+				"trial_end":       sub.TrialEnd,
+			})
+		}
+
+		sub.Status = model.SubscriptionStatusExpired
+		sub.CancelledAt = sub.TrialEnd
+		if err := dao.NewSubscriptionDao().WithTx(tx).Update(sub); err != nil {
+			return err
+		}
+
+		org.SubscriptionStatus = model.OrganizationSubscriptionStatusActive
+		org.NextBillingDate = nil
+		freePlan, err := dao.NewPlanDao().WithTx(tx).GetBySlug("free")
+		if err == nil {
+			org.PlanID = &freePlan.ID
+			org.BillingCycle = model.BillingCycleMonthly
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := orgDao.Update(org); err != nil {
+			return err
+		}
+
+		notice = &utils.BillingNotice{
+			Subject: "Your Trial Has Ended",
+			Heading: "Your Trial Has Ended",
+			Message: fmt.Sprintf("Your trial of the %s plan has ended without a payment method, so your organization moved to the free plan. You can subscribe again at any time.",
+				sub.Plan.Name),
+		}
+		return dao.NewBillingEventDao().WithTx(tx).Record(org.ID, model.BillingEventTypeTrialExpired, map[string]interface{}{
+			"subscription_id": sub.ID,
+			"plan_id":         sub.PlanID,
+			"trial_end":       sub.TrialEnd,
+		})
+	})
+	if err != nil {
+		return err
+	}
+	sendBillingNotice(organizationID, notice)
+	return nil
+}
+
+// firstPeriodPrice is what the first paid period after the trial is charged,
+// less the discount of a coupon redeemed with the trial.
+func firstPeriodPrice(sub *model.Subscription) int64 {
+	price, _ := sub.Price(&sub.Plan, sub.BillingCycle)
+	return price - payments.UpcomingDiscount(sub, price, sub.Currency)
+}
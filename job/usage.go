@@ -0,0 +1,57 @@
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"testlake/dao"
+)
+
+const usageReconciliationLockKey = 727155203
+
+// UsageReconciliationJob records every organization's resource counts for the
+// current period from the resources themselves, correcting whatever drifted.
+// The interval is USAGE_RECONCILIATION_INTERVAL_MINUTES, daily by default.
+func UsageReconciliationJob() Job {
+	return Job{
+		Name:     "usage-reconciliation",
+		Interval: intervalFromEnv("USAGE_RECONCILIATION_INTERVAL_MINUTES", 24*time.Hour),
+		LockKey:  usageReconciliationLockKey,
+		Run: func(ctx context.Context) error {
+			return ReconcileUsage(ctx, time.Now())
+		},
+	}
+}
+
+// ReconcileUsage counts the live usage of every organization into its usage
+// record of the period of now. Metered API requests are left untouched. An
+// organization that fails is logged and reconciled on the next run.
+func ReconcileUsage(ctx context.Context, now time.Time) error {
+	orgDao := dao.NewOrganizationDao()
+	usageDao := dao.NewOrganizationUsageDao()
+
+	for page := 0; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		orgs, _, err := orgDao.GetAll(page)
+		if err != nil {
+			return err
+		}
+
+		for _, org := range orgs {
+			counts, err := usageDao.CountLiveUsage(org.ID)
+			if err == nil {
+				err = usageDao.RecordResourceCounts(org.ID, counts, now)
+			}
+			if err != nil {
+				log.Printf("Failed to reconcile usage of organization %s: %v", org.ID, err)
+			}
+		}
+
+		if len(orgs) < orgDao.Limit {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const usageAlertLockKey = 727155204
+
+// UsageAlertJob alerts organizations whose usage approaches their plan limits.
+// The interval is USAGE_ALERT_INTERVAL_MINUTES, hourly by default.
+func UsageAlertJob() Job {
+	return Job{
+		Name:     "usage-alerts",
+		Interval: intervalFromEnv("USAGE_ALERT_INTERVAL_MINUTES", time.Hour),
+		LockKey:  usageAlertLockKey,
+		Run: func(ctx context.Context) error {
+			return SendUsageAlerts(ctx, time.Now())
+		},
+	}
+}
+
+// usageAlert is a resource whose usage reached an alert threshold of its limit.
+type usageAlert struct {
+	Resource  string
+	Threshold int
+	Used      int
+	Limit     int
+}
+
+// SendUsageAlerts checks the live usage of every organization against the
+// alert thresholds of its plan. The first time a threshold is reached in a
+// billing period, the owners and admins are emailed and notified in the app;
+// reaching it again in the same period alerts nobody. An organization that
+// fails is logged and checked again on the next run.
+func SendUsageAlerts(ctx context.Context, now time.Time) error {
+	orgDao := dao.NewOrganizationDao()
+
+	for page := 0; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		orgs, _, err := orgDao.GetAll(page)
+		if err != nil {
+			return err
+		}
+
+		for i := range orgs {
+			if err := alertUsage(&orgs[i], now); err != nil {
+				log.Printf("Failed to check usage alerts of organization %s: %v", orgs[i].ID, err)
+			}
+		}
+
+		if len(orgs) < orgDao.Limit {
+			return nil
+		}
+	}
+}
+
+func alertUsage(org *model.Organization, now time.Time) error {
+	limits, err := utils.LoadOrganizationLimits(org.ID)
+	if err != nil {
+		return err
+	}
+	thresholds := limits.Plan.AlertThresholds()
+	if len(thresholds) == 0 {
+		return nil
+	}
+
+	periodStart, err := alertPeriodStart(org.ID, now)
+	if err != nil {
+		return err
+	}
+
+	recipients, err := alertRecipients(org)
+	if err != nil {
+		return err
+	}
+
+	var alerts []usageAlert
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		alerts = nil
+		alertDao := dao.NewUsageAlertDao().WithTx(tx)
+		for _, resource := range model.LimitResources {
+			check := limits.Check(resource, 0)
+			if check.Limit <= 0 {
+				continue
+			}
+
+			// Claim every threshold reached, so that usage jumping past several
+			// of them at once alerts only about the highest one
+			var reached *usageAlert
+			for _, threshold := range thresholds {
+				if check.Used*100 < threshold*check.Limit {
+					break
+				}
+				claimed, err := alertDao.Claim(&model.UsageAlert{
+					OrganizationID: org.ID,
+					Resource:       resource,
+					Threshold:      threshold,
+					PeriodStart:    periodStart,
+					Used:           check.Used,
+					Limit:          check.Limit,
+				})
+				if err != nil {
+					return err
+				}
+				if claimed {
+					reached = &usageAlert{Resource: resource, Threshold: threshold, Used: check.Used, Limit: check.Limit}
+				}
+			}
+			if reached == nil {
+				continue
+			}
+			alerts = append(alerts, *reached)
+
+			notificationDao := dao.NewNotificationDao().WithTx(tx)
+			for _, recipient := range recipients {
+				err := notificationDao.Create(&model.Notification{
+					UserID:         recipient.ID,
+					OrganizationID: &org.ID,
+					Type:           model.NotificationTypeUsageAlert,
+					Title:          fmt.Sprintf("%s is approaching its %s limit", org.Name, resourceLabel(resource)),
+					Message: fmt.Sprintf("%s has used %d%% of its %s limit (%d of %d). Upgrade the plan to keep adding more.",
+						org.Name, reached.Threshold, resourceLabel(resource), reached.Used, reached.Limit),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Emails go out once the alerts are recorded: a failed email is not retried
+	for _, alert := range alerts {
+		for _, recipient := range recipients {
+			err := utils.SendUsageAlert(recipient.Email, utils.UsageAlertTemplateData{
+				OrganizationName: org.Name,
+				Resource:         resourceLabel(alert.Resource),
+				Threshold:        alert.Threshold,
+				Used:             alert.Used,
+				Limit:            alert.Limit,
+			})
+			if err != nil {
+				log.Printf("Failed to send %s usage alert of organization %s to %s: %v", alert.Resource, org.ID, recipient.Email, err)
+			}
+		}
+	}
+	return nil
+}
+
+// alertPeriodStart returns the start of the organization's billing period, the
+// calendar month for organizations without a subscription.
+func alertPeriodStart(organizationID uuid.UUID, now time.Time) (time.Time, error) {
+	sub, err := dao.NewSubscriptionDao().GetActiveByOrganizationID(organizationID)
+	if err == nil {
+		return sub.CurrentPeriodStart, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return time.Time{}, err
+	}
+	periodStart, _ := model.UsagePeriod(now)
+	return periodStart, nil
+}
+
+// alertRecipients returns the creator and the owners and admins of the organization.
+func alertRecipients(org *model.Organization) ([]model.User, error) {
+	creator, err := dao.NewUserDao().GetByID(org.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	recipients := []model.User{*creator}
+
+	admins, err := dao.NewOrganizationMemberDao().GetAdmins(org.ID)
+	if err != nil {
+		return nil, err
+	}
+	for _, admin := range admins {
+		if admin.UserID != org.CreatedBy {
+			recipients = append(recipients, admin.User)
+		}
+	}
+	return recipients, nil
+}
+
+func resourceLabel(resource string) string {
+	return strings.ReplaceAll(resource, "_", " ")
+}
@@ -0,0 +1,87 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBillingEventsRecordTheirActor(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	method := f.vaultPaymentMethod(t, true)
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	added := refundEvents(t, model.BillingEventTypePaymentMethodAdded)
+	require.Len(t, added, 1)
+	assert.Equal(t, model.BillingActorTypeUser, added[0].ActorType)
+	require.NotNil(t, added[0].ActorID)
+	assert.Equal(t, f.org.CreatedBy, *added[0].ActorID)
+
+	charged := refundEvents(t, model.BillingEventTypePaymentSucceeded)
+	require.Len(t, charged, 1)
+	assert.Equal(t, model.BillingActorTypeSystem, charged[0].ActorType)
+	assert.Nil(t, charged[0].ActorID)
+
+	// Making the default method the default again changes nothing
+	_, err := payments.SetDefaultPaymentMethod(method.ID, f.owner())
+	require.NoError(t, err)
+	assert.Empty(t, refundEvents(t, model.BillingEventTypePaymentMethodDefault))
+	second := f.vaultPaymentMethod(t, false)
+	_, err = payments.SetDefaultPaymentMethod(second.ID, f.owner())
+	require.NoError(t, err)
+	assert.Len(t, refundEvents(t, model.BillingEventTypePaymentMethodDefault), 1)
+}
+
+func TestSearchBillingEvents(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.vaultPaymentMethod(t, true)
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	other := &model.Organization{Name: "Other", Slug: "other", CreatedBy: f.org.CreatedBy}
+	require.NoError(t, dao.Database.Create(other).Error)
+	require.NoError(t, dao.NewBillingEventDao().Record(other.ID, model.BillingEventTypeInvoiceCreated, map[string]interface{}{}))
+
+	eventDao := dao.NewBillingEventDao()
+	all, total, err := eventDao.Search(dao.BillingEventFilter{}, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, len(all), total)
+	assert.Greater(t, total, int64(3))
+
+	ofOrganization, _, err := eventDao.Search(dao.BillingEventFilter{OrganizationID: &f.org.ID}, 0)
+	require.NoError(t, err)
+	assert.Len(t, ofOrganization, len(all)-1)
+	for _, event := range ofOrganization {
+		assert.Equal(t, "Acme", event.Organization.Name)
+	}
+
+	invoices, total, err := eventDao.Search(dao.BillingEventFilter{EventTypes: []model.BillingEventType{model.BillingEventTypeInvoiceCreated}}, 0)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Len(t, invoices, 2)
+
+	byOwner, _, err := eventDao.Search(dao.BillingEventFilter{ActorType: model.BillingActorTypeUser, ActorID: &f.org.CreatedBy}, 0)
+	require.NoError(t, err)
+	require.Len(t, byOwner, 1)
+	assert.Equal(t, model.BillingEventTypePaymentMethodAdded, byOwner[0].EventType)
+	require.NotNil(t, byOwner[0].EventData)
+	assert.Contains(t, *byOwner[0].EventData, "payer@example.com")
+
+	later := now.Add(time.Hour)
+	none, total, err := eventDao.Search(dao.BillingEventFilter{From: &later}, 0)
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, none)
+	before, _, err := eventDao.Search(dao.BillingEventFilter{To: &later}, 0)
+	require.NoError(t, err)
+	assert.Len(t, before, len(all))
+}
@@ -0,0 +1,268 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type billingFixture struct {
+	gateway *utils.FakePaymentGateway
+	org     *model.Organization
+	plan    *model.Plan
+	sub     *model.Subscription
+}
+
+// setupBilling creates an organization on a 29/month plan whose period ended
+// an hour before now, on an in-memory database and the fake gateway.
+func setupBilling(t *testing.T, now time.Time) *billingFixture {
+	// Billing notices fail to render without templates, keep their error log out of the tree
+	t.Chdir(t.TempDir())
+
+	// Shared cache so that every pooled connection sees the same in-memory database
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Plan{}, &model.PlanPrice{}, &model.Organization{}, &model.Project{},
+		&model.Environment{}, &model.DataSchema{}, &model.TestData{}, &model.Subscription{}, &model.PaymentMethod{},
+		&model.Invoice{}, &model.InvoiceLineItem{}, &model.InvoiceSequence{}, &model.Payment{}, &model.BillingEvent{},
+		&model.OrganizationUsage{}, &model.UsageAlert{}, &model.Notification{}, &model.CreditNote{}, &model.CreditNoteSequence{},
+		&model.Coupon{}, &model.CouponRedemption{}, &model.InvoiceDocument{}, &model.InvoiceDelivery{}, &model.BillingContact{}))
+	// Raw tables: their now() defaults do not exist in sqlite
+	require.NoError(t, db.Exec("CREATE TABLE organization_members (id text, organization_id text, user_id text, role text, status text)").Error)
+	require.NoError(t, db.Exec("CREATE TABLE organization_invitations (id text, organization_id text, status text, expires_at datetime)").Error)
+	previous := dao.Database
+	dao.Database = db
+	t.Cleanup(func() { dao.Database = previous })
+
+	gateway := utils.NewFakePaymentGateway()
+	utils.SetPaymentGateway(gateway)
+	t.Cleanup(func() { utils.SetPaymentGateway(nil) })
+
+	userID := uuid.New()
+	require.NoError(t, db.Create(&model.User{ID: userID, Email: "owner@example.com", Username: "owner"}).Error)
+
+	free := &model.Plan{Name: "Free", Slug: "free", MaxUsers: 1, MaxProjects: 2, MaxEnvironments: 2, MaxSchemas: 5, MaxTestRecordsPerSchema: 100, Features: "[]", IsActive: true}
+	plan := &model.Plan{Name: "Starter", Slug: "starter", PriceMonthly: 2900, PriceYearly: 29000, MaxUsers: 5, MaxProjects: 10, MaxEnvironments: 5, MaxSchemas: 25, MaxTestRecordsPerSchema: 1000, Features: "[]", IsActive: true}
+	require.NoError(t, db.Create(free).Error)
+	require.NoError(t, db.Create(plan).Error)
+
+	org := &model.Organization{Name: "Acme", Slug: "acme", CreatedBy: userID, PlanID: &plan.ID, SubscriptionStatus: model.OrganizationSubscriptionStatusActive}
+	require.NoError(t, db.Create(org).Error)
+
+	periodEnd := now.Add(-time.Hour)
+	sub := &model.Subscription{
+		OrganizationID:     org.ID,
+		PlanID:             plan.ID,
+		Status:             model.SubscriptionStatusActive,
+		BillingCycle:       model.BillingCycleMonthly,
+		CurrentPeriodStart: periodEnd.AddDate(0, -1, 0),
+		CurrentPeriodEnd:   periodEnd,
+		CreatedBy:          userID,
+	}
+	require.NoError(t, db.Create(sub).Error)
+
+	return &billingFixture{gateway: gateway, org: org, plan: plan, sub: sub}
+}
+
+func (f *billingFixture) addPaymentMethod(t *testing.T, vaultID string) {
+	method := &model.PaymentMethod{OrganizationID: f.org.ID, PayPalVaultID: &vaultID, IsDefault: true, IsActive: true, CreatedBy: f.org.CreatedBy}
+	require.NoError(t, dao.Database.Create(method).Error)
+}
+
+func (f *billingFixture) invoices(t *testing.T) []model.Invoice {
+	var invoices []model.Invoice
+	require.NoError(t, dao.Database.Preload("LineItems").Order("invoice_number").Find(&invoices).Error)
+	return invoices
+}
+
+func TestRunBillingInvoicesAndChargesRenewal(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoices := f.invoices(t)
+	require.Len(t, invoices, 1)
+	invoice := invoices[0]
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(t, int64(2900), invoice.TotalAmount)
+	assert.Equal(t, f.sub.CurrentPeriodEnd.Unix(), invoice.BillingPeriodStart.Unix())
+	assert.Regexp(t, `^INV-\d{4}-000001$`, invoice.InvoiceNumber)
+	require.Len(t, invoice.LineItems, 1)
+	assert.Equal(t, int64(2900), invoice.LineItems[0].TotalPrice)
+
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.sub.CurrentPeriodEnd.Unix(), sub.CurrentPeriodStart.Unix())
+	assert.Equal(t, f.sub.CurrentPeriodEnd.AddDate(0, 1, 0).Unix(), sub.CurrentPeriodEnd.Unix())
+
+	payments, err := dao.NewPaymentDao().GetByInvoiceID(invoice.ID)
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, model.PaymentStatusCompleted, payments[0].Status)
+	assert.Len(t, f.gateway.Captures, 1)
+}
+
+func TestRunBillingChargesInSubscriptionCurrency(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	require.NoError(t, dao.NewPlanDao().CreatePrice(&model.PlanPrice{PlanID: f.plan.ID, Currency: "EUR", BillingCycle: model.BillingCycleMonthly, Amount: 2700}))
+	require.NoError(t, dao.Database.Model(f.sub).Update("currency", "EUR").Error)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(t, "EUR", invoice.Currency)
+	assert.Equal(t, int64(2700), invoice.TotalAmount)
+	for _, capture := range f.gateway.Captures {
+		assert.Equal(t, int64(2700), capture.Amount)
+		assert.Equal(t, "EUR", capture.Currency)
+	}
+}
+
+func TestRunBillingSkipsPlanWithoutPriceInCurrency(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	require.NoError(t, dao.Database.Model(f.sub).Update("currency", "GBP").Error)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	assert.Empty(t, f.invoices(t))
+}
+
+func TestRunBillingTwiceNeverDoubleBills(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	assert.Len(t, f.invoices(t), 1)
+	assert.Len(t, f.gateway.Captures, 1)
+}
+
+func TestRunBillingCatchesUpMissedPeriods(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"current_period_start": now.AddDate(0, -3, 0),
+		"current_period_end":   now.AddDate(0, -2, 0),
+	}).Error)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoices := f.invoices(t)
+	require.Len(t, invoices, 3)
+	assert.Regexp(t, `-000003$`, invoices[2].InvoiceNumber)
+	for _, invoice := range invoices {
+		assert.Equal(t, model.InvoiceStatusSent, invoice.Status, "nothing to charge without a payment method")
+	}
+
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.True(t, sub.CurrentPeriodEnd.After(now))
+}
+
+func TestRunBillingDeclinedChargeMarksPastDue(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "declined-1")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoices := f.invoices(t)
+	require.Len(t, invoices, 1)
+	assert.Equal(t, model.InvoiceStatusSent, invoices[0].Status)
+
+	payments, err := dao.NewPaymentDao().GetByInvoiceID(invoices[0].ID)
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	assert.Equal(t, model.PaymentStatusFailed, payments[0].Status)
+
+	org, err := dao.NewOrganizationDao().GetByID(f.org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrganizationSubscriptionStatusPastDue, org.SubscriptionStatus)
+}
+
+func TestRunBillingEndsSubscriptionCancelledAtPeriodEnd(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	require.NoError(t, dao.Database.Model(f.sub).Update("cancel_at_period_end", true).Error)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	assert.Empty(t, f.invoices(t))
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusCancelled, sub.Status)
+	require.NotNil(t, sub.CancelledAt)
+
+	org, err := dao.NewOrganizationDao().GetByID(f.org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrganizationSubscriptionStatusCancelled, org.SubscriptionStatus)
+	free, err := dao.NewPlanDao().GetBySlug("free")
+	require.NoError(t, err)
+	assert.Equal(t, free.ID, *org.PlanID)
+}
+
+func TestRunBillingAppliesScheduledDowngradeBeforeInvoicing(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	free, err := dao.NewPlanDao().GetBySlug("free")
+	require.NoError(t, err)
+	require.NoError(t, dao.Database.Model(f.sub).Update("scheduled_plan_id", free.ID).Error)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	assert.Empty(t, f.invoices(t), "free plans are not invoiced")
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, free.ID, sub.PlanID)
+	assert.Nil(t, sub.ScheduledPlanID)
+	assert.True(t, sub.CurrentPeriodEnd.After(now))
+}
+
+func TestRunBillingMatchesProviderRenewalPayment(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	gatewayID := "I-BW452GLLEP1G"
+	require.NoError(t, dao.Database.Model(f.sub).Update("pay_pal_subscription_id", gatewayID).Error)
+	saleID := "80021663DE681814L"
+	require.NoError(t, dao.Database.Create(&model.Payment{
+		OrganizationID:  f.org.ID,
+		SubscriptionID:  &f.sub.ID,
+		PayPalPaymentID: &saleID,
+		Amount:          2900,
+		Currency:        "USD",
+		Status:          model.PaymentStatusCompleted,
+		ProcessedAt:     &now,
+	}).Error)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoices := f.invoices(t)
+	require.Len(t, invoices, 1)
+	assert.Equal(t, model.InvoiceStatusPaid, invoices[0].Status)
+	assert.Empty(t, f.gateway.Captures, "the provider already charged the renewal")
+
+	payment, err := dao.NewPaymentDao().GetByPayPalPaymentID(saleID)
+	require.NoError(t, err)
+	require.NotNil(t, payment.InvoiceID)
+	assert.Equal(t, invoices[0].ID, *payment.InvoiceID)
+}
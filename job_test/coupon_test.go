@@ -0,0 +1,200 @@
+package job_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// addCoupon creates a percent coupon with the code, adjusted by configure.
+func addCoupon(t *testing.T, code string, percentOff float64, configure func(*model.Coupon)) *model.Coupon {
+	coupon := &model.Coupon{
+		Code:         code,
+		Name:         code,
+		DiscountType: model.CouponDiscountTypePercent,
+		PercentOff:   &percentOff,
+		Currency:     "USD",
+		Duration:     model.CouponDurationOnce,
+		PlanIDs:      "[]",
+		IsActive:     true,
+	}
+	if configure != nil {
+		configure(coupon)
+	}
+	require.NoError(t, dao.NewCouponDao().Create(coupon))
+	return coupon
+}
+
+func (f *billingFixture) redeem(code string, now time.Time) (*model.CouponRedemption, error) {
+	var redemption *model.CouponRedemption
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		var err error
+		redemption, err = payments.RedeemCoupon(tx, code, f.sub, f.sub.PlanID, f.org.CreatedBy, now)
+		return err
+	})
+	return redemption, err
+}
+
+func TestRepeatingCouponDiscountsItsCyclesOnly(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	addCoupon(t, "LAUNCH25", 25, func(coupon *model.Coupon) {
+		cycles := 2
+		coupon.Duration = model.CouponDurationRepeating
+		coupon.DurationInCycles = &cycles
+	})
+	_, err := f.redeem("launch25", now)
+	require.NoError(t, err)
+
+	for month := 0; month < 3; month++ {
+		require.NoError(t, job.RunBilling(context.Background(), now.AddDate(0, month, 0)))
+	}
+
+	invoices := f.invoices(t)
+	require.Len(t, invoices, 3)
+	for i, invoice := range invoices[:2] {
+		assert.Equal(t, int64(2175), invoice.TotalAmount, "invoice %d", i)
+		require.Len(t, invoice.LineItems, 2)
+		discount := invoice.LineItems[0]
+		if discount.TotalPrice > 0 {
+			discount = invoice.LineItems[1]
+		}
+		assert.Equal(t, int64(-725), discount.TotalPrice)
+		assert.Equal(t, "Discount LAUNCH25 (25% off)", discount.Description)
+	}
+	assert.Equal(t, int64(2900), invoices[2].TotalAmount)
+	assert.Len(t, invoices[2].LineItems, 1)
+
+	redemption, err := dao.NewCouponDao().GetRedemptionsByOrganizationID(f.org.ID)
+	require.NoError(t, err)
+	require.Len(t, redemption, 1)
+	assert.Equal(t, 2, redemption[0].CyclesApplied)
+	assert.NotNil(t, redemption[0].EndedAt)
+}
+
+func TestRunBillingTwiceCountsDiscountOnce(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	addCoupon(t, "ONCE10", 10, nil)
+	_, err := f.redeem("ONCE10", now)
+	require.NoError(t, err)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	assert.Equal(t, int64(2610), f.onlyInvoice(t).TotalAmount)
+	redemptions, err := dao.NewCouponDao().GetRedemptionsByOrganizationID(f.org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, redemptions[0].CyclesApplied)
+}
+
+func TestFullDiscountInvoiceIsPaidWithoutCharge(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	addCoupon(t, "FREEMONTH", 100, nil)
+	_, err := f.redeem("FREEMONTH", now)
+	require.NoError(t, err)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(t, int64(0), invoice.TotalAmount)
+	assert.Empty(t, f.gateway.Captures)
+}
+
+func TestRedeemCouponEnforcesLimits(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	otherPlan := uuid.New()
+	otherPlanIDs, err := json.Marshal([]uuid.UUID{otherPlan})
+	require.NoError(t, err)
+	expired := now.Add(-time.Minute)
+	limit := 3
+
+	addCoupon(t, "EXPIRED", 10, func(coupon *model.Coupon) { coupon.ExpiresAt = &expired })
+	inactive := addCoupon(t, "INACTIVE", 10, nil)
+	inactive.IsActive = false
+	require.NoError(t, dao.NewCouponDao().Update(inactive))
+	addCoupon(t, "SOLDOUT", 10, func(coupon *model.Coupon) { coupon.MaxRedemptions = &limit; coupon.TimesRedeemed = limit })
+	addCoupon(t, "OTHERPLAN", 10, func(coupon *model.Coupon) { coupon.PlanIDs = string(otherPlanIDs) })
+	first := addCoupon(t, "FIRST", 10, func(coupon *model.Coupon) { coupon.MaxRedemptions = &limit })
+	addCoupon(t, "SECOND", 10, func(coupon *model.Coupon) { coupon.Duration = model.CouponDurationForever })
+
+	for code, expected := range map[string]error{
+		"MISSING":   payments.ErrCouponNotFound,
+		"EXPIRED":   payments.ErrCouponExpired,
+		"INACTIVE":  payments.ErrCouponExpired,
+		"SOLDOUT":   payments.ErrCouponExhausted,
+		"OTHERPLAN": payments.ErrCouponNotForPlan,
+	} {
+		_, err := f.redeem(code, now)
+		assert.ErrorIs(t, err, expected, code)
+	}
+
+	_, err = f.redeem("FIRST", now)
+	require.NoError(t, err)
+	coupon, err := dao.NewCouponDao().GetByID(first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, coupon.TimesRedeemed)
+
+	_, err = f.redeem("FIRST", now)
+	assert.ErrorIs(t, err, payments.ErrCouponAlreadyRedeemed)
+	_, err = f.redeem("SECOND", now)
+	assert.ErrorIs(t, err, payments.ErrDiscountActive, "the first discount was not used yet")
+}
+
+func TestSyncGatewayDiscountsRestoresPlanPriceOnceUsedUp(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	ctx := context.Background()
+	product, err := f.gateway.CreateProduct(ctx, utils.GatewayProductRequest{Name: "Starter"})
+	require.NoError(t, err)
+	plan, err := f.gateway.CreatePlan(ctx, utils.GatewayPlanRequest{ProductID: product.ID})
+	require.NoError(t, err)
+	discounted := int64(1450)
+	gatewaySub, err := f.gateway.CreateSubscription(ctx, utils.GatewaySubscriptionRequest{PlanID: plan.ID, Price: &discounted})
+	require.NoError(t, err)
+	require.NoError(t, dao.Database.Model(f.sub).Update("pay_pal_subscription_id", gatewaySub.ID).Error)
+
+	addCoupon(t, "HALFOFF", 50, nil)
+	_, err = f.redeem("HALFOFF", now)
+	require.NoError(t, err)
+	require.NoError(t, dao.NewCouponDao().SetGatewayPrice(f.sub.ID, discounted))
+	saleID := "80021663DE681814L"
+	require.NoError(t, dao.Database.Create(&model.Payment{
+		OrganizationID:  f.org.ID,
+		SubscriptionID:  &f.sub.ID,
+		PayPalPaymentID: &saleID,
+		Amount:          discounted,
+		Currency:        "USD",
+		Status:          model.PaymentStatusCompleted,
+		ProcessedAt:     &now,
+	}).Error)
+
+	require.NoError(t, job.RunBilling(ctx, now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status, "the provider charged the discounted price")
+	assert.Empty(t, f.gateway.Captures)
+	assert.Equal(t, int64(2900), f.gateway.Prices[gatewaySub.ID], "the next period is charged the plan price")
+
+	redemptions, err := dao.NewCouponDao().GetRedemptionsByOrganizationID(f.org.ID)
+	require.NoError(t, err)
+	assert.Nil(t, redemptions[0].GatewayPrice)
+}
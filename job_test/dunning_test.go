@@ -0,0 +1,129 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/payments"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func (f *billingFixture) organization(t *testing.T) *model.Organization {
+	org, err := dao.NewOrganizationDao().GetByID(f.org.ID)
+	require.NoError(t, err)
+	return org
+}
+
+func (f *billingFixture) onlyInvoice(t *testing.T) model.Invoice {
+	invoices := f.invoices(t)
+	require.Len(t, invoices, 1)
+	return invoices[0]
+}
+
+func TestDeclinedChargeStartsDunning(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "declined-1")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.DunningStatusRetrying, invoice.DunningStatus)
+	assert.Zero(t, invoice.DunningRetries)
+	require.NotNil(t, invoice.NextRetryAt)
+	assert.Equal(t, now.Add(24*time.Hour).Unix(), invoice.NextRetryAt.Unix())
+	assert.Equal(t, model.OrganizationSubscriptionStatusPastDue, f.organization(t).SubscriptionStatus)
+
+	var started int64
+	require.NoError(t, dao.Database.Model(&model.BillingEvent{}).Where("event_type = ?", model.BillingEventTypeDunningStarted).Count(&started).Error)
+	assert.Equal(t, int64(1), started)
+}
+
+func TestDunningSuspendsAfterThreeFailedRetries(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "declined-1")
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	// Nothing is retried before it is due
+	require.NoError(t, job.RunDunning(context.Background(), now.Add(time.Hour)))
+	assert.Zero(t, f.onlyInvoice(t).DunningRetries)
+
+	require.NoError(t, job.RunDunning(context.Background(), now.Add(25*time.Hour)))
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, 1, invoice.DunningRetries)
+	assert.Equal(t, now.Add(3*24*time.Hour).Unix(), invoice.NextRetryAt.Unix())
+
+	require.NoError(t, job.RunDunning(context.Background(), now.Add(3*24*time.Hour+time.Hour)))
+	assert.Equal(t, 2, f.onlyInvoice(t).DunningRetries)
+	assert.Equal(t, model.OrganizationSubscriptionStatusPastDue, f.organization(t).SubscriptionStatus)
+
+	require.NoError(t, job.RunDunning(context.Background(), now.Add(7*24*time.Hour+time.Hour)))
+	invoice = f.onlyInvoice(t)
+	assert.Equal(t, model.DunningStatusExhausted, invoice.DunningStatus)
+	assert.Nil(t, invoice.NextRetryAt)
+	assert.Equal(t, model.OrganizationSubscriptionStatusSuspended, f.organization(t).SubscriptionStatus)
+
+	payments, err := dao.NewPaymentDao().GetByInvoiceID(invoice.ID)
+	require.NoError(t, err)
+	assert.Len(t, payments, 4, "the first charge and three retries")
+}
+
+func TestDunningRetryRecoversOrganization(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "declined-1")
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	require.NoError(t, dao.Database.Model(&model.PaymentMethod{}).Where("organization_id = ?", f.org.ID).Update("pay_pal_vault_id", "vault-1").Error)
+	require.NoError(t, job.RunDunning(context.Background(), now.Add(25*time.Hour)))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(t, model.DunningStatusRecovered, invoice.DunningStatus)
+	assert.Equal(t, model.OrganizationSubscriptionStatusActive, f.organization(t).SubscriptionStatus)
+}
+
+func TestSettleInvoiceLiftsSuspension(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	require.NoError(t, job.RunBilling(context.Background(), now))
+	require.NoError(t, dao.Database.Model(&model.Invoice{}).Where("organization_id = ?", f.org.ID).Updates(map[string]interface{}{
+		"dunning_status": model.DunningStatusExhausted,
+	}).Error)
+	require.NoError(t, dao.Database.Model(&model.Organization{}).Where("id = ?", f.org.ID).
+		Update("subscription_status", model.OrganizationSubscriptionStatusSuspended).Error)
+
+	invoice := f.onlyInvoice(t)
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		notice, err := payments.SettleInvoice(tx, &invoice, now, model.SystemActor)
+		assert.NotNil(t, notice)
+		return err
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, model.DunningStatusRecovered, f.onlyInvoice(t).DunningStatus)
+	assert.Equal(t, model.OrganizationSubscriptionStatusActive, f.organization(t).SubscriptionStatus)
+}
+
+func TestDunningStartsForOverdueInvoiceWithoutPaymentMethod(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.DunningStatusNone, invoice.DunningStatus, "still within the grace period")
+	assert.Equal(t, model.OrganizationSubscriptionStatusActive, f.organization(t).SubscriptionStatus)
+
+	require.NoError(t, job.RunDunning(context.Background(), now.Add(25*time.Hour)))
+
+	invoice = f.onlyInvoice(t)
+	assert.Equal(t, model.DunningStatusRetrying, invoice.DunningStatus)
+	assert.Equal(t, model.OrganizationSubscriptionStatusPastDue, f.organization(t).SubscriptionStatus)
+}
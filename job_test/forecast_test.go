@@ -0,0 +1,45 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"testlake/job"
+	"testlake/payments"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecastBillingMatchesIssuedInvoices(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	f.billTo(t, "DE", "")
+	addCoupon(t, "WELCOME", 25, nil)
+	_, err := f.redeem("WELCOME", now)
+	require.NoError(t, err)
+
+	forecast, err := payments.ForecastBilling(f.org.ID, now, now.AddDate(0, 2, 0))
+	require.NoError(t, err)
+
+	assert.Equal(t, "USD", forecast.Currency)
+	assert.Nil(t, forecast.EndsAt)
+	require.Len(t, forecast.Charges, 3, "the overdue period and the next two")
+	first := forecast.Charges[0]
+	assert.Equal(t, payments.ForecastChargeRenewal, first.Kind)
+	assert.Equal(t, f.sub.CurrentPeriodEnd.Unix(), first.PeriodStart.Unix())
+	assert.Equal(t, int64(725), first.Discount, "a once coupon discounts the first period only")
+	assert.Equal(t, int64(0), forecast.Charges[1].Discount)
+	assert.Equal(t, int64(3451), forecast.Charges[1].TotalAmount)
+	assert.Equal(t, first.TotalAmount+2*3451, forecast.TotalAmount)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, first.TotalAmount, invoice.TotalAmount)
+	assert.Equal(t, first.TaxAmount, invoice.TaxAmount)
+	forecast, err = payments.ForecastBilling(f.org.ID, now, now.AddDate(0, 2, 0))
+	require.NoError(t, err)
+	assert.Len(t, forecast.Charges, 2)
+}
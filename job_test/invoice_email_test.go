@@ -0,0 +1,76 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *billingFixture) addBillingContact(t *testing.T, email string) {
+	contact := &model.BillingContact{OrganizationID: f.org.ID, Email: email, CreatedBy: f.org.CreatedBy}
+	require.NoError(t, dao.NewBillingContactDao().Create(contact))
+}
+
+// deliveries returns the recipients each kind of email about the invoice was
+// attempted to, in the order they were attempted.
+func deliveries(t *testing.T, invoice model.Invoice) map[model.InvoiceEmailKind][]string {
+	logged, err := dao.NewInvoiceDao().GetDeliveries(invoice.ID)
+	require.NoError(t, err)
+	recipients := map[model.InvoiceEmailKind][]string{}
+	for i := len(logged) - 1; i >= 0; i-- {
+		delivery := logged[i]
+		assert.Equal(t, invoice.OrganizationID, delivery.OrganizationID)
+		recipients[delivery.Kind] = append(recipients[delivery.Kind], delivery.Recipient)
+	}
+	return recipients
+}
+
+func TestInvoiceEmailsGoToBillingEmailAndContacts(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	billingEmail := "billing@acme.test"
+	f.org.BillingEmail = &billingEmail
+	require.NoError(t, dao.NewOrganizationDao().Update(f.org))
+	f.addBillingContact(t, "ap@acme.test")
+	f.addBillingContact(t, "BILLING@acme.test")
+	f.vaultPaymentMethod(t, true)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	require.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	recipients := deliveries(t, invoice)
+	assert.Equal(t, []string{"billing@acme.test", "ap@acme.test"}, recipients[model.InvoiceEmailKindIssued])
+	assert.Equal(t, []string{"billing@acme.test", "ap@acme.test"}, recipients[model.InvoiceEmailKindReceipt])
+	assert.Empty(t, recipients[model.InvoiceEmailKindFailed])
+
+	// The email templates are not available to the tests, which shows as failed attempts
+	logged, err := dao.NewInvoiceDao().GetDeliveries(invoice.ID)
+	require.NoError(t, err)
+	for _, delivery := range logged {
+		assert.Equal(t, model.InvoiceDeliveryStatusFailed, delivery.Status)
+		require.NotNil(t, delivery.Error)
+		assert.Contains(t, *delivery.Error, "template")
+	}
+}
+
+func TestFailedPaymentEmailIsLoggedAgainstInvoice(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "declined-1")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	recipients := deliveries(t, invoice)
+	assert.Equal(t, []string{"owner@example.com"}, recipients[model.InvoiceEmailKindIssued], "the creator stands in for a missing billing email")
+	assert.Equal(t, []string{"owner@example.com"}, recipients[model.InvoiceEmailKindFailed])
+	assert.Empty(t, recipients[model.InvoiceEmailKindReceipt])
+}
@@ -0,0 +1,59 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// owner is the organization's creator, acting as a user.
+func (f *billingFixture) owner() model.BillingActor {
+	return model.UserActor(f.org.CreatedBy)
+}
+
+// vaultPaymentMethod saves a payment method the way the payer does: set up,
+// approved at the gateway and confirmed.
+func (f *billingFixture) vaultPaymentMethod(t *testing.T, makeDefault bool) *model.PaymentMethod {
+	method, _, err := payments.StartPaymentMethodSetup(context.Background(), f.org.ID, f.org.CreatedBy)
+	require.NoError(t, err)
+	f.gateway.ApproveSetupToken(*method.PayPalSetupTokenID)
+	method, err = payments.ConfirmPaymentMethod(context.Background(), method.ID, makeDefault, f.owner())
+	require.NoError(t, err)
+	return method
+}
+
+func TestFailingPaymentMethodIsInvalidated(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "closed-1")
+	backup := f.vaultPaymentMethod(t, false)
+	closed, err := dao.NewPaymentMethodDao().GetDefaultByOrganizationID(f.org.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, backup.ID, closed.ID)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invalid, err := dao.NewPaymentMethodDao().GetByID(closed.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentMethodStatusInvalid, invalid.Status)
+	assert.False(t, invalid.IsDefault)
+	assert.NotNil(t, invalid.InvalidatedAt)
+	require.NotNil(t, invalid.FailureReason)
+	assert.Contains(t, *invalid.FailureReason, "PAYER_ACCOUNT_LOCKED_OR_CLOSED")
+	assert.Len(t, refundEvents(t, model.BillingEventTypePaymentMethodInvalidated), 1)
+
+	current, err := dao.NewPaymentMethodDao().GetDefaultByOrganizationID(f.org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, backup.ID, current.ID, "the other verified method takes over")
+
+	_, err = payments.SetDefaultPaymentMethod(closed.ID, f.owner())
+	assert.ErrorIs(t, err, payments.ErrPaymentMethodNotVerified)
+}
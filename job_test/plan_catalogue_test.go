@@ -0,0 +1,52 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/job"
+	"testlake/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersionPlanGrandfathersSubscribers(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	_, err := payments.SyncGatewayPlan(context.Background(), f.plan.ID)
+	require.NoError(t, err)
+	previous, err := dao.NewPlanDao().GetByID(f.plan.ID)
+	require.NoError(t, err)
+
+	next := *previous
+	next.PriceMonthly = 3900
+	next.Prices = nil
+	created, err := payments.VersionPlan(context.Background(), f.plan.ID, &next, now)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, created.Version)
+	assert.Equal(t, "starter", created.Slug)
+	assert.Equal(t, &f.plan.ID, created.PreviousVersionID)
+	assert.Equal(t, previous.PayPalProductID, created.PayPalProductID)
+	require.NotNil(t, created.PayPalMonthlyPlanID)
+	assert.NotEqual(t, *previous.PayPalMonthlyPlanID, *created.PayPalMonthlyPlanID)
+	assert.Equal(t, "INACTIVE", f.gateway.Plans[*previous.PayPalMonthlyPlanID].Status)
+
+	current, err := dao.NewPlanDao().GetBySlug("starter")
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, current.ID)
+	retired, err := dao.NewPlanDao().GetByID(f.plan.ID)
+	require.NoError(t, err)
+	assert.True(t, retired.IsRetired())
+	assert.False(t, retired.IsActive)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+	assert.Equal(t, int64(2900), f.onlyInvoice(t).TotalAmount, "existing subscribers keep their price")
+
+	_, err = payments.VersionPlan(context.Background(), f.plan.ID, &next, now)
+	assert.ErrorIs(t, err, payments.ErrPlanRetired)
+}
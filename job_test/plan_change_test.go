@@ -0,0 +1,149 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// proPlan adds a more expensive plan that is not synced with the provider.
+func (f *billingFixture) proPlan(t *testing.T) *model.Plan {
+	pro := &model.Plan{Name: "Pro", Slug: "pro", PriceMonthly: 7900, PriceYearly: 79000, MaxUsers: 20, MaxProjects: 50, MaxEnvironments: 10, MaxSchemas: 100, MaxTestRecordsPerSchema: 10000, Features: "[]", IsActive: true}
+	require.NoError(t, dao.Database.Create(pro).Error)
+	return pro
+}
+
+// gatewayBilledUpgrade puts the fixture's subscription halfway through a
+// period billed by a provider subscription and returns a more expensive plan,
+// synced with the provider, to upgrade to.
+func (f *billingFixture) gatewayBilledUpgrade(t *testing.T, now time.Time) (*model.Subscription, *model.Plan) {
+	ctx := context.Background()
+	starter, err := payments.SyncGatewayPlan(ctx, f.plan.ID)
+	require.NoError(t, err)
+	pro, err := payments.SyncGatewayPlan(ctx, f.proPlan(t).ID)
+	require.NoError(t, err)
+
+	gatewaySub, err := f.gateway.CreateSubscription(ctx, utils.GatewaySubscriptionRequest{PlanID: *starter.PayPalMonthlyPlanID})
+	require.NoError(t, err)
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"pay_pal_subscription_id": gatewaySub.ID,
+		"current_period_start":    now.AddDate(0, 0, -15),
+		"current_period_end":      now.AddDate(0, 0, 15),
+	}).Error)
+
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	return sub, pro
+}
+
+func TestProrationOfGatewayBilledUpgradeIsCharged(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	sub, pro := f.gatewayBilledUpgrade(t, now)
+
+	upgrade, err := payments.UpgradeSubscription(context.Background(), sub, pro, model.BillingCycleMonthly, "", f.org.CreatedBy, now)
+	require.NoError(t, err)
+	require.NotNil(t, upgrade.Invoice)
+	assert.True(t, upgrade.Invoice.IsProration)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+	require.NoError(t, job.RunDunning(context.Background(), now.Add(48*time.Hour)))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	require.Len(t, f.gateway.Captures, 1)
+	org, err := dao.NewOrganizationDao().GetByID(f.org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.OrganizationSubscriptionStatusActive, org.SubscriptionStatus)
+}
+
+func TestDeclinedProrationOfGatewayBilledUpgradeIsRetried(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "declined-1")
+	sub, pro := f.gatewayBilledUpgrade(t, now)
+
+	_, err := payments.UpgradeSubscription(context.Background(), sub, pro, model.BillingCycleMonthly, "", f.org.CreatedBy, now)
+	require.NoError(t, err)
+	require.NoError(t, job.RunBilling(context.Background(), now))
+	invoice := f.onlyInvoice(t)
+	require.NotNil(t, invoice.NextRetryAt)
+
+	require.NoError(t, job.RunDunning(context.Background(), *invoice.NextRetryAt))
+
+	payments, err := dao.NewPaymentDao().GetByInvoiceID(invoice.ID)
+	require.NoError(t, err)
+	assert.Len(t, payments, 2, "the provider does not retry the proration, the retry charges it again")
+}
+
+func TestGatewayBilledUpgradeNeedsPaymentMethod(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	sub, pro := f.gatewayBilledUpgrade(t, now)
+
+	_, err := payments.UpgradeSubscription(context.Background(), sub, pro, model.BillingCycleMonthly, "", f.org.CreatedBy, now)
+	assert.ErrorIs(t, err, payments.ErrUpgradePaymentMethod)
+
+	unchanged, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.plan.ID, unchanged.PlanID)
+	assert.Empty(t, f.invoices(t))
+}
+
+func TestVaultBilledUpgradeChargesProration(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"current_period_start": now.AddDate(0, 0, -15),
+		"current_period_end":   now.AddDate(0, 0, 15),
+	}).Error)
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	require.True(t, payments.IsVaultBilled(sub))
+	pro := f.proPlan(t)
+
+	upgrade, err := payments.UpgradeSubscription(context.Background(), sub, pro, model.BillingCycleMonthly, "", f.org.CreatedBy, now)
+	require.NoError(t, err)
+	require.NotNil(t, upgrade.Invoice)
+	assert.Empty(t, upgrade.ApprovalURL)
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	sub, err = dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pro.ID, sub.PlanID)
+	assert.Nil(t, sub.PayPalSubscriptionID)
+}
+
+func TestVaultBilledScheduledDowngradeRenewsAtNewPrice(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	pro := f.proPlan(t)
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"plan_id":           pro.ID,
+		"scheduled_plan_id": f.plan.ID,
+	}).Error)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.plan.ID, sub.PlanID)
+	assert.Nil(t, sub.ScheduledPlanID)
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(t, int64(2900), invoice.TotalAmount)
+}
@@ -0,0 +1,55 @@
+package job_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/payments"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// paidInvoice runs the billing so that the renewal invoice is paid with the
+// organization's payment method, and returns its payment.
+func (f *billingFixture) paidInvoice(t *testing.T, now time.Time) model.Payment {
+	f.addPaymentMethod(t, "vault-1")
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	payments, err := dao.NewPaymentDao().GetByInvoiceID(f.onlyInvoice(t).ID)
+	require.NoError(t, err)
+	require.Len(t, payments, 1)
+	return payments[0]
+}
+
+func refundEvents(t *testing.T, eventType model.BillingEventType) []model.BillingEvent {
+	var events []model.BillingEvent
+	require.NoError(t, dao.Database.Where("event_type = ?", eventType).Find(&events).Error)
+	return events
+}
+
+func TestUnconfirmedRefundIsCompletedByBillingRun(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	payment := f.paidInvoice(t, now)
+	f.gateway.FailNext("RefundCapture", errors.New("connection reset"))
+
+	creditNote, err := payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID}, now)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditNoteStatusPending, creditNote.Status)
+	assert.Equal(t, int64(2900), f.onlyInvoice(t).RefundedAmount, "the pending refund holds its amount")
+
+	_, err = payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID}, now)
+	assert.ErrorIs(t, err, payments.ErrRefundNotAllowed)
+
+	require.NoError(t, job.RunBilling(context.Background(), now.Add(time.Hour)))
+
+	stored, err := dao.NewCreditNoteDao().GetByID(creditNote.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditNoteStatusIssued, stored.Status)
+	assert.Equal(t, model.InvoiceStatusRefunded, f.onlyInvoice(t).Status)
+}
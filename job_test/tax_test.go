@@ -0,0 +1,79 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"testlake/utils"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// billTo sets the organization's billing country and tax ID, taxed by the
+// default table as if invoices were issued from Germany.
+func (f *billingFixture) billTo(t *testing.T, country, taxID string) {
+	utils.SetTaxCalculator(&utils.TableTaxCalculator{Rates: utils.DefaultTaxRates, OriginCountry: "DE"})
+	t.Cleanup(func() { utils.SetTaxCalculator(nil) })
+
+	updates := map[string]interface{}{"billing_country": country}
+	if taxID != "" {
+		updates["tax_id"] = taxID
+	}
+	require.NoError(t, dao.Database.Model(f.org).Updates(updates).Error)
+}
+
+func TestRunBillingTaxesRenewalInvoice(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	f.billTo(t, "DE", "")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, int64(2900), invoice.Amount)
+	assert.Equal(t, 19.0, invoice.TaxRate)
+	assert.Equal(t, int64(551), invoice.TaxAmount)
+	assert.Equal(t, int64(3451), invoice.TotalAmount)
+	assert.Equal(t, "VAT 19% (DE)", invoice.TaxDescription)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	for _, capture := range f.gateway.Captures {
+		assert.Equal(t, int64(3451), capture.Amount)
+	}
+}
+
+func TestRunBillingReverseChargesBusinessAbroad(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	f.billTo(t, "NL", "NL123456789B01")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.True(t, invoice.ReverseCharge)
+	assert.Equal(t, int64(0), invoice.TaxAmount)
+	assert.Equal(t, int64(2900), invoice.TotalAmount)
+	assert.Equal(t, "Reverse charge", invoice.TaxDescription)
+}
+
+func TestRunBillingTaxesDiscountedAmount(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	f.billTo(t, "FR", "")
+	addCoupon(t, "HALFOFF", 50, nil)
+	_, err := f.redeem("HALFOFF", now)
+	require.NoError(t, err)
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, int64(1450), invoice.Amount)
+	assert.Equal(t, int64(290), invoice.TaxAmount)
+	assert.Equal(t, int64(1740), invoice.TotalAmount)
+}
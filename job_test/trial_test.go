@@ -0,0 +1,83 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startTrial turns the fixture subscription into a trial ending at trialEnd.
+func (f *billingFixture) startTrial(t *testing.T, trialEnd time.Time) {
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"current_period_start": trialEnd.AddDate(0, 0, -14),
+		"current_period_end":   trialEnd,
+		"trial_end":            trialEnd,
+	}).Error)
+	require.NoError(t, dao.Database.Model(f.org).Updates(map[string]interface{}{
+		"subscription_status": model.OrganizationSubscriptionStatusTrialing,
+		"trial_ends_at":       trialEnd,
+	}).Error)
+}
+
+func TestRunBillingRemindsTrialOnce(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.startTrial(t, now.Add(48*time.Hour))
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	require.NotNil(t, sub.TrialReminderSentAt)
+	assert.Equal(t, now.Unix(), sub.TrialReminderSentAt.Unix())
+
+	require.NoError(t, job.RunBilling(context.Background(), now.Add(time.Hour)))
+	sub, err = dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, now.Unix(), sub.TrialReminderSentAt.Unix(), "reminded only once")
+	assert.Equal(t, model.OrganizationSubscriptionStatusTrialing, f.organization(t).SubscriptionStatus)
+	assert.Empty(t, f.invoices(t))
+}
+
+func TestRunBillingConvertsEndedTrialWithPaymentMethod(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.startTrial(t, now.Add(-time.Hour))
+	f.addPaymentMethod(t, "vault-1")
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	assert.Equal(t, model.OrganizationSubscriptionStatusActive, f.organization(t).SubscriptionStatus)
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Equal(t, int64(2900), invoice.TotalAmount)
+
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusActive, sub.Status)
+	assert.True(t, sub.CurrentPeriodEnd.After(now))
+}
+
+func TestRunBillingMovesEndedTrialWithoutPaymentMethodToFree(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.startTrial(t, now.Add(-time.Hour))
+
+	require.NoError(t, job.RunBilling(context.Background(), now))
+
+	assert.Empty(t, f.invoices(t), "nothing is charged for a trial")
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.SubscriptionStatusExpired, sub.Status)
+
+	org := f.organization(t)
+	assert.Equal(t, model.OrganizationSubscriptionStatusActive, org.SubscriptionStatus)
+	free, err := dao.NewPlanDao().GetBySlug("free")
+	require.NoError(t, err)
+	assert.Equal(t, free.ID, *org.PlanID)
+}
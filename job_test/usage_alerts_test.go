@@ -0,0 +1,81 @@
+package job_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func (f *billingFixture) addProjects(t *testing.T, count int) {
+	for i := 0; i < count; i++ {
+		project := &model.Project{OrganizationID: &f.org.ID, Name: fmt.Sprintf("Project %d", i), CreatedBy: f.org.CreatedBy}
+		require.NoError(t, dao.Database.Create(project).Error)
+	}
+}
+
+func notifications(t *testing.T, userID uuid.UUID) []model.Notification {
+	found, _, err := dao.NewNotificationDao().GetByUserID(userID, 0)
+	require.NoError(t, err)
+	return found
+}
+
+func TestSendUsageAlertsOncePerThreshold(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	adminID := uuid.New()
+	require.NoError(t, dao.Database.Create(&model.User{ID: adminID, Email: "admin@example.com", Username: "admin"}).Error)
+	require.NoError(t, dao.Database.Exec("INSERT INTO organization_members VALUES (?, ?, ?, 'admin', 'joined')", uuid.NewString(), f.org.ID, adminID).Error)
+	f.addProjects(t, 8) // 80% of the Starter plan's 10 projects
+
+	require.NoError(t, job.SendUsageAlerts(context.Background(), now))
+	require.NoError(t, job.SendUsageAlerts(context.Background(), now))
+
+	for _, userID := range []uuid.UUID{f.org.CreatedBy, adminID} {
+		found := notifications(t, userID)
+		require.Len(t, found, 1)
+		assert.Equal(t, model.NotificationTypeUsageAlert, found[0].Type)
+		assert.Contains(t, found[0].Message, "80% of its projects limit (8 of 10)")
+	}
+}
+
+func TestSendUsageAlertsAboutHighestThresholdReached(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addProjects(t, 8)
+	require.NoError(t, job.SendUsageAlerts(context.Background(), now))
+
+	f.addProjects(t, 2)
+	require.NoError(t, job.SendUsageAlerts(context.Background(), now))
+
+	found := notifications(t, f.org.CreatedBy)
+	require.Len(t, found, 2, "90% is claimed along with 95% without an alert of its own")
+	assert.Contains(t, found[0].Message, "95% of its projects limit (10 of 10)")
+
+	var claimed int64
+	require.NoError(t, dao.Database.Model(&model.UsageAlert{}).Where("organization_id = ?", f.org.ID).Count(&claimed).Error)
+	assert.Equal(t, int64(3), claimed)
+}
+
+func TestSendUsageAlertsFollowPlanThresholds(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addProjects(t, 9)
+
+	require.NoError(t, dao.Database.Model(f.plan).Update("usage_alert_thresholds", "[]").Error)
+	require.NoError(t, job.SendUsageAlerts(context.Background(), now))
+	assert.Empty(t, notifications(t, f.org.CreatedBy), "alerts are off for the plan")
+
+	require.NoError(t, dao.Database.Model(f.plan).Update("usage_alert_thresholds", "[50]").Error)
+	require.NoError(t, job.SendUsageAlerts(context.Background(), now))
+	found := notifications(t, f.org.CreatedBy)
+	require.Len(t, found, 1)
+	assert.Contains(t, found[0].Message, "50% of its projects limit (9 of 10)")
+}
@@ -0,0 +1,32 @@
+package job_test
+
+import (
+	"context"
+	"testing"
+	"testlake/dao"
+	"testlake/job"
+	"testlake/model"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcileUsageRecordsLiveCountsAndKeepsAPIRequests(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	usageDao := dao.NewOrganizationUsageDao()
+	require.NoError(t, usageDao.AddAPIRequests(f.org.ID, 42, now))
+	require.NoError(t, dao.Database.Model(&model.OrganizationUsage{}).Where("organization_id = ?", f.org.ID).Update("projects_count", 7).Error)
+
+	project := &model.Project{OrganizationID: &f.org.ID, Name: "Checkout", CreatedBy: f.org.CreatedBy}
+	require.NoError(t, dao.Database.Create(project).Error)
+
+	require.NoError(t, job.ReconcileUsage(context.Background(), now))
+
+	usage, err := usageDao.GetCurrentUsage(f.org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, usage.UsersCount, "the creator")
+	assert.Equal(t, 1, usage.ProjectsCount, "drift is corrected")
+	assert.Equal(t, 42, usage.APIRequestsCount)
+}
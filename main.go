@@ -2,6 +2,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"testlake/app"
 	"testlake/dao"
@@ -16,6 +17,12 @@ func main() {
 		log.Fatal("Error loading .env file")
 	}
 
+	// go run main.go migrate up|status|create <name>
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		app.RunMigrateCommand(os.Args[2:])
+		return
+	}
+
 	dao.Connect()
 
 	app.ServeApplication()
@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"testlake/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIUsageMetering counts the requests served on an organization's routes
+// towards its API usage. Requests rejected because the caller has no access to
+// the organization, or it does not exist, are not its usage and are not counted.
+func APIUsageMetering() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		context.Next()
+
+		if !strings.HasPrefix(context.FullPath(), organizationRoute) {
+			return
+		}
+		switch context.Writer.Status() {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return
+		}
+		organizationID, err := uuid.Parse(context.Param("id"))
+		if err != nil {
+			return
+		}
+		utils.APIUsageMeter().RecordRequest(organizationID)
+	}
+}
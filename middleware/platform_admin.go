@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+
+	"testlake/dao"
+	"testlake/inout"
+	"testlake/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlatformAdminMiddleware restricts routes to platform admins, the staff that
+// works across organizations. It must run after JWTAuthMiddleware.
+func PlatformAdminMiddleware() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		userID, err := utils.ExtractUserID(context)
+		if err != nil {
+			response := inout.BaseResponse{
+				ErrorCode:        401,
+				ErrorDescription: "Authentication required",
+			}
+			context.JSON(http.StatusUnauthorized, response)
+			context.Abort()
+			return
+		}
+
+		user, err := dao.NewUserDao().GetByID(userID)
+		if err != nil || !user.IsPlatformAdmin {
+			response := inout.BaseResponse{
+				ErrorCode:        403,
+				ErrorDescription: "Platform admin access required",
+			}
+			context.JSON(http.StatusForbidden, response)
+			context.Abort()
+			return
+		}
+		context.Next()
+	}
+}
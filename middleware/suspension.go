@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"testlake/dao"
+	"testlake/inout"
+	"testlake/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const organizationRoute = "/api/v1/organizations/:id"
+
+// writableWhileSuspended are the organization routes a suspended organization
+// can still change, so that it can settle what it owes.
+var writableWhileSuspended = []string{"/payment-methods", "/subscription", "/billing", "/invoices"}
+
+// SuspendedOrganizationReadOnly rejects changes to an organization suspended for
+// non-payment, other than to its billing, until its overdue invoices are paid.
+func SuspendedOrganizationReadOnly() gin.HandlerFunc {
+	return func(context *gin.Context) {
+		switch context.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			context.Next()
+			return
+		}
+
+		route := context.FullPath()
+		if !strings.HasPrefix(route, organizationRoute) {
+			context.Next()
+			return
+		}
+		subRoute := strings.TrimPrefix(route, organizationRoute)
+		for _, writable := range writableWhileSuspended {
+			if strings.HasPrefix(subRoute, writable) {
+				context.Next()
+				return
+			}
+		}
+
+		// Invalid or unknown organizations are reported by the handler
+		organizationID, err := uuid.Parse(context.Param("id"))
+		if err != nil {
+			context.Next()
+			return
+		}
+		org, err := dao.NewOrganizationDao().GetByID(organizationID)
+		if err != nil {
+			log.Printf("Failed to check suspension of organization %s: %v", organizationID, err)
+			context.Next()
+			return
+		}
+
+		if org.SubscriptionStatus == model.OrganizationSubscriptionStatusSuspended {
+			response := inout.BaseResponse{
+				ErrorCode:        402,
+				ErrorDescription: "Organization is suspended for non-payment and is read-only until its overdue invoices are paid",
+			}
+			context.JSON(http.StatusPaymentRequired, response)
+			context.Abort()
+			return
+		}
+		context.Next()
+	}
+}
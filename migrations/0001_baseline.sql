@@ -0,0 +1,577 @@
+-- Baseline schema: every table the application used before versioned
+-- migrations existed. Statements are idempotent so databases previously
+-- created with AutoMigrate can adopt the migrations without changes.
+
+CREATE TABLE IF NOT EXISTS "users" (
+    "id" uuid,
+    "email" varchar(255) NOT NULL,
+    "username" varchar(100) NOT NULL,
+    "first_name" varchar(100),
+    "last_name" varchar(100),
+    "avatar_url" varchar(500),
+    "auth_provider" varchar(20) NOT NULL,
+    "auth_provider_id" varchar(255),
+    "password_hash" varchar(255),
+    "is_email_verified" boolean DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "last_login_at" timestamptz,
+    "status" varchar(20) DEFAULT 'active',
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_username" ON "users" ("username");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_users_email" ON "users" ("email");
+
+CREATE TABLE IF NOT EXISTS "plans" (
+    "id" uuid,
+    "name" varchar(100) NOT NULL,
+    "slug" varchar(50) NOT NULL,
+    "description" text,
+    "price_monthly" decimal(10,2) NOT NULL,
+    "price_yearly" decimal(10,2) NOT NULL,
+    "max_users" bigint NOT NULL,
+    "max_projects" bigint NOT NULL,
+    "max_environments" bigint NOT NULL,
+    "max_schemas" bigint NOT NULL,
+    "max_test_records_per_schema" bigint NOT NULL,
+    "features" jsonb NOT NULL,
+    "pay_pal_monthly_plan_id" varchar(100),
+    "pay_pal_yearly_plan_id" varchar(100),
+    "is_active" boolean DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_plans_slug" ON "plans" ("slug");
+
+CREATE TABLE IF NOT EXISTS "organizations" (
+    "id" uuid,
+    "name" varchar(200) NOT NULL,
+    "slug" varchar(100) NOT NULL,
+    "description" text,
+    "logo_url" varchar(500),
+    "plan_type" varchar(20) DEFAULT 'starter',
+    "max_users" bigint DEFAULT 10,
+    "max_projects" bigint DEFAULT 5,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "status" varchar(20) DEFAULT 'active',
+    "deleted_at" timestamptz,
+    "plan_id" uuid,
+    "billing_cycle" varchar(20) DEFAULT 'monthly',
+    "subscription_status" varchar(20) DEFAULT 'active',
+    "trial_ends_at" timestamptz,
+    "next_billing_date" timestamptz,
+    "pay_pal_subscription_id" varchar(100),
+    "billing_email" varchar(255),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_organizations_plan" FOREIGN KEY ("plan_id") REFERENCES "plans"("id"),
+    CONSTRAINT "fk_organizations_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_organizations_deleted_at" ON "organizations" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_organizations_slug" ON "organizations" ("slug");
+
+CREATE TABLE IF NOT EXISTS "organization_members" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "role" varchar(20) DEFAULT 'member',
+    "invited_by" uuid NOT NULL,
+    "invited_at" timestamptz DEFAULT now(),
+    "joined_at" timestamptz,
+    "status" varchar(20) DEFAULT 'invited',
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_organization_members_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_organization_members_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_organization_members_invited_by_user" FOREIGN KEY ("invited_by") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_org_user" ON "organization_members" ("organization_id","user_id");
+
+CREATE TABLE IF NOT EXISTS "organization_invitations" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "email" varchar(255) NOT NULL,
+    "role" varchar(20) DEFAULT 'member',
+    "token" varchar(255) NOT NULL,
+    "invited_by" uuid NOT NULL,
+    "invited_at" timestamptz DEFAULT now(),
+    "expires_at" timestamptz,
+    "used_at" timestamptz,
+    "status" varchar(20) DEFAULT 'pending',
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_organization_invitations_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_organization_invitations_invited_by_user" FOREIGN KEY ("invited_by") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_organization_invitations_token" ON "organization_invitations" ("token");
+
+CREATE TABLE IF NOT EXISTS "projects" (
+    "id" uuid,
+    "name" varchar(200) NOT NULL,
+    "description" text,
+    "organization_id" uuid,
+    "user_id" uuid,
+    "is_personal" boolean DEFAULT false,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "status" varchar(20) DEFAULT 'active',
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_projects_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id"),
+    CONSTRAINT "fk_projects_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_projects_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_projects_deleted_at" ON "projects" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "teams" (
+    "id" uuid,
+    "name" varchar(200) NOT NULL,
+    "description" text,
+    "organization_id" uuid NOT NULL,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_teams_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_teams_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_teams_deleted_at" ON "teams" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "team_members" (
+    "id" uuid,
+    "team_id" uuid NOT NULL,
+    "user_id" uuid NOT NULL,
+    "role" varchar(20) DEFAULT 'member',
+    "added_by" uuid NOT NULL,
+    "added_at" timestamptz DEFAULT now(),
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_team_members_team" FOREIGN KEY ("team_id") REFERENCES "teams"("id"),
+    CONSTRAINT "fk_team_members_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_team_members_added_by_user" FOREIGN KEY ("added_by") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "project_accesses" (
+    "id" uuid,
+    "project_id" uuid NOT NULL,
+    "team_id" uuid,
+    "user_id" uuid,
+    "permission" varchar(20) DEFAULT 'read',
+    "granted_by" uuid NOT NULL,
+    "granted_at" timestamptz DEFAULT now(),
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_project_accesses_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_project_accesses_team" FOREIGN KEY ("team_id") REFERENCES "teams"("id"),
+    CONSTRAINT "fk_project_accesses_user" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_project_accesses_granted_by_user" FOREIGN KEY ("granted_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_project_accesses_deleted_at" ON "project_accesses" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "environments" (
+    "id" uuid,
+    "name" varchar(100) NOT NULL,
+    "slug" varchar(100) NOT NULL,
+    "description" text,
+    "color" varchar(7) DEFAULT '#3B82F6',
+    "project_id" uuid NOT NULL,
+    "is_default" boolean DEFAULT false,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "status" varchar(20) DEFAULT 'active',
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_environments_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_environments_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_environments_deleted_at" ON "environments" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "features" (
+    "id" uuid,
+    "name" varchar(200) NOT NULL,
+    "description" text,
+    "project_id" uuid NOT NULL,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_features_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_features_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_features_deleted_at" ON "features" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "feature_environment_statuses" (
+    "id" uuid,
+    "feature_id" uuid NOT NULL,
+    "environment_id" uuid NOT NULL,
+    "is_working" boolean DEFAULT true,
+    "error_message" text,
+    "last_tested_at" timestamptz,
+    "last_tested_by" uuid,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_feature_environment_statuses_feature" FOREIGN KEY ("feature_id") REFERENCES "features"("id"),
+    CONSTRAINT "fk_feature_environment_statuses_environment" FOREIGN KEY ("environment_id") REFERENCES "environments"("id"),
+    CONSTRAINT "fk_feature_environment_statuses_last_tested_by_user" FOREIGN KEY ("last_tested_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_feature_environment_statuses_deleted_at" ON "feature_environment_statuses" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "feature_error_logs" (
+    "id" uuid,
+    "feature_id" uuid NOT NULL,
+    "environment_id" uuid NOT NULL,
+    "error_message" text NOT NULL,
+    "error_details" jsonb,
+    "reported_by" uuid NOT NULL,
+    "reported_at" timestamptz DEFAULT now(),
+    "resolved_at" timestamptz,
+    "resolved_by" uuid,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_feature_error_logs_resolver" FOREIGN KEY ("resolved_by") REFERENCES "users"("id"),
+    CONSTRAINT "fk_feature_error_logs_feature" FOREIGN KEY ("feature_id") REFERENCES "features"("id"),
+    CONSTRAINT "fk_feature_error_logs_environment" FOREIGN KEY ("environment_id") REFERENCES "environments"("id"),
+    CONSTRAINT "fk_feature_error_logs_reporter" FOREIGN KEY ("reported_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_feature_error_logs_deleted_at" ON "feature_error_logs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "error_images" (
+    "id" uuid,
+    "error_log_id" uuid NOT NULL,
+    "image_url" varchar(500) NOT NULL,
+    "image_name" varchar(200),
+    "uploaded_at" timestamptz DEFAULT now(),
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_error_images_error_log" FOREIGN KEY ("error_log_id") REFERENCES "feature_error_logs"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_error_images_deleted_at" ON "error_images" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "data_schemas" (
+    "id" uuid,
+    "name" varchar(200) NOT NULL,
+    "description" text,
+    "project_id" uuid NOT NULL,
+    "is_reusable" boolean DEFAULT true,
+    "schema_definition" jsonb NOT NULL,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "status" varchar(20) DEFAULT 'active',
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_data_schemas_project" FOREIGN KEY ("project_id") REFERENCES "projects"("id"),
+    CONSTRAINT "fk_data_schemas_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_data_schemas_deleted_at" ON "data_schemas" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "feature_schemas" (
+    "id" uuid,
+    "feature_id" uuid NOT NULL,
+    "schema_id" uuid NOT NULL,
+    "is_primary" boolean DEFAULT false,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_feature_schemas_feature" FOREIGN KEY ("feature_id") REFERENCES "features"("id"),
+    CONSTRAINT "fk_feature_schemas_schema" FOREIGN KEY ("schema_id") REFERENCES "data_schemas"("id"),
+    CONSTRAINT "fk_feature_schemas_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_feature_schemas_deleted_at" ON "feature_schemas" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "schema_fields" (
+    "id" uuid,
+    "schema_id" uuid NOT NULL,
+    "field_name" varchar(100) NOT NULL,
+    "field_type" varchar(20) NOT NULL,
+    "is_required" boolean DEFAULT false,
+    "validation_regex" varchar(500),
+    "min_value" varchar(100),
+    "max_value" varchar(100),
+    "options" jsonb,
+    "reference_schema_id" uuid,
+    "display_order" bigint DEFAULT 0,
+    "created_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_schema_fields_schema" FOREIGN KEY ("schema_id") REFERENCES "data_schemas"("id"),
+    CONSTRAINT "fk_schema_fields_reference_schema" FOREIGN KEY ("reference_schema_id") REFERENCES "data_schemas"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_schema_fields_deleted_at" ON "schema_fields" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "test_data" (
+    "id" uuid,
+    "schema_id" uuid NOT NULL,
+    "environment_id" uuid NOT NULL,
+    "data_values" jsonb NOT NULL,
+    "is_used" boolean DEFAULT false,
+    "used_at" timestamptz,
+    "used_by" uuid,
+    "feature_id" uuid,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "status" varchar(20) DEFAULT 'active',
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_test_data_schema" FOREIGN KEY ("schema_id") REFERENCES "data_schemas"("id"),
+    CONSTRAINT "fk_test_data_environment" FOREIGN KEY ("environment_id") REFERENCES "environments"("id"),
+    CONSTRAINT "fk_test_data_used_by_user" FOREIGN KEY ("used_by") REFERENCES "users"("id"),
+    CONSTRAINT "fk_test_data_feature" FOREIGN KEY ("feature_id") REFERENCES "features"("id"),
+    CONSTRAINT "fk_test_data_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_test_data_deleted_at" ON "test_data" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "test_data_requests" (
+    "id" uuid,
+    "feature_id" uuid NOT NULL,
+    "environment_id" uuid NOT NULL,
+    "schema_id" uuid NOT NULL,
+    "requested_by" uuid NOT NULL,
+    "provided_data_id" uuid,
+    "request_notes" text,
+    "response_notes" text,
+    "requested_at" timestamptz DEFAULT now(),
+    "fulfilled_at" timestamptz,
+    "status" varchar(20) DEFAULT 'pending',
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_test_data_requests_feature" FOREIGN KEY ("feature_id") REFERENCES "features"("id"),
+    CONSTRAINT "fk_test_data_requests_environment" FOREIGN KEY ("environment_id") REFERENCES "environments"("id"),
+    CONSTRAINT "fk_test_data_requests_schema" FOREIGN KEY ("schema_id") REFERENCES "data_schemas"("id"),
+    CONSTRAINT "fk_test_data_requests_requester" FOREIGN KEY ("requested_by") REFERENCES "users"("id"),
+    CONSTRAINT "fk_test_data_requests_provided_data" FOREIGN KEY ("provided_data_id") REFERENCES "test_data"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_test_data_requests_deleted_at" ON "test_data_requests" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "email_verification_tokens" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "token" varchar(255) NOT NULL,
+    "purpose" varchar(30) NOT NULL DEFAULT 'verify_email',
+    "new_email" varchar(255),
+    "expires_at" timestamptz NOT NULL,
+    "is_used" boolean DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "deleted_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_email_verification_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_email_verification_tokens_deleted_at" ON "email_verification_tokens" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_email_verification_tokens_purpose" ON "email_verification_tokens" ("purpose");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_email_verification_tokens_token" ON "email_verification_tokens" ("token");
+CREATE INDEX IF NOT EXISTS "idx_email_verification_tokens_user_id" ON "email_verification_tokens" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "payment_methods" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "pay_pal_payer_id" varchar(100),
+    "pay_pal_email" varchar(255),
+    "payment_method_type" varchar(20) DEFAULT 'paypal',
+    "is_default" boolean DEFAULT false,
+    "is_active" boolean DEFAULT true,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_payment_methods_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_payment_methods_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "subscriptions" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "plan_id" uuid NOT NULL,
+    "pay_pal_subscription_id" varchar(100),
+    "status" varchar(20) DEFAULT 'pending',
+    "billing_cycle" varchar(20) NOT NULL,
+    "current_period_start" timestamptz NOT NULL,
+    "current_period_end" timestamptz NOT NULL,
+    "trial_end" timestamptz,
+    "cancel_at_period_end" boolean DEFAULT false,
+    "cancelled_at" timestamptz,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_subscriptions_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_subscriptions_plan" FOREIGN KEY ("plan_id") REFERENCES "plans"("id"),
+    CONSTRAINT "fk_subscriptions_creator" FOREIGN KEY ("created_by") REFERENCES "users"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_subscriptions_pay_pal_subscription_id" ON "subscriptions" ("pay_pal_subscription_id");
+
+CREATE TABLE IF NOT EXISTS "login_throttles" (
+    "id" uuid,
+    "scope" varchar(20) NOT NULL,
+    "key" varchar(255) NOT NULL,
+    "failed_count" bigint DEFAULT 0,
+    "first_failed_at" timestamptz,
+    "last_failed_at" timestamptz,
+    "locked_until" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_login_throttle_scope_key" ON "login_throttles" ("scope","key");
+
+CREATE TABLE IF NOT EXISTS "user_sessions" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "ip_address" varchar(45),
+    "user_agent" varchar(500),
+    "auth_method" varchar(20) NOT NULL,
+    "last_seen_at" timestamptz,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_user_sessions_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_user_sessions_expires_at" ON "user_sessions" ("expires_at");
+CREATE INDEX IF NOT EXISTS "idx_user_sessions_user_id" ON "user_sessions" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "login_events" (
+    "id" uuid,
+    "user_id" uuid,
+    "email" varchar(255),
+    "ip_address" varchar(45),
+    "user_agent" varchar(500),
+    "auth_method" varchar(20) NOT NULL,
+    "outcome" varchar(30) NOT NULL,
+    "session_id" uuid,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_login_events_created_at" ON "login_events" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_login_events_email" ON "login_events" ("email");
+CREATE INDEX IF NOT EXISTS "idx_login_events_user_id" ON "login_events" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "organization_sso_configs" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "issuer" varchar(500) NOT NULL,
+    "client_id" varchar(255) NOT NULL,
+    "client_secret" varchar(500),
+    "allowed_domains" varchar(1000) NOT NULL,
+    "default_role" varchar(20) DEFAULT 'member',
+    "enforce_sso" boolean DEFAULT false,
+    "is_enabled" boolean DEFAULT true,
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_organization_sso_configs_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_organization_sso_configs_organization_id" ON "organization_sso_configs" ("organization_id");
+
+CREATE TABLE IF NOT EXISTS "sso_login_states" (
+    "id" uuid,
+    "state" varchar(100) NOT NULL,
+    "nonce" varchar(100) NOT NULL,
+    "code_verifier" varchar(128) NOT NULL,
+    "organization_id" uuid NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_sso_login_states_organization_id" ON "sso_login_states" ("organization_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sso_login_states_state" ON "sso_login_states" ("state");
+
+CREATE TABLE IF NOT EXISTS "invoices" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "subscription_id" uuid,
+    "pay_pal_invoice_id" varchar(100),
+    "invoice_number" varchar(50) NOT NULL,
+    "amount" decimal(10,2) NOT NULL,
+    "tax_amount" decimal(10,2) DEFAULT 0,
+    "total_amount" decimal(10,2) NOT NULL,
+    "currency" varchar(3) DEFAULT 'USD',
+    "status" varchar(20) DEFAULT 'draft',
+    "billing_period_start" timestamptz,
+    "billing_period_end" timestamptz,
+    "due_date" timestamptz,
+    "paid_at" timestamptz,
+    "invoice_url" varchar(500),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_invoices_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_invoices_subscription" FOREIGN KEY ("subscription_id") REFERENCES "subscriptions"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invoices_invoice_number" ON "invoices" ("invoice_number");
+
+CREATE TABLE IF NOT EXISTS "invoice_line_items" (
+    "id" uuid,
+    "invoice_id" uuid NOT NULL,
+    "description" varchar(255) NOT NULL,
+    "quantity" bigint DEFAULT 1,
+    "unit_price" decimal(10,2) NOT NULL,
+    "total_price" decimal(10,2) NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_invoices_line_items" FOREIGN KEY ("invoice_id") REFERENCES "invoices"("id")
+);
+
+CREATE TABLE IF NOT EXISTS "payments" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "invoice_id" uuid,
+    "subscription_id" uuid,
+    "pay_pal_payment_id" varchar(100),
+    "pay_pal_payer_id" varchar(100),
+    "amount" decimal(10,2) NOT NULL,
+    "currency" varchar(3) DEFAULT 'USD',
+    "payment_method" varchar(20) DEFAULT 'paypal',
+    "status" varchar(20) DEFAULT 'pending',
+    "failure_reason" text,
+    "processed_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_payments_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_payments_invoice" FOREIGN KEY ("invoice_id") REFERENCES "invoices"("id"),
+    CONSTRAINT "fk_payments_subscription" FOREIGN KEY ("subscription_id") REFERENCES "subscriptions"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_payments_pay_pal_payment_id" ON "payments" ("pay_pal_payment_id");
+
+CREATE TABLE IF NOT EXISTS "billing_events" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "event_type" varchar(50) NOT NULL,
+    "event_data" jsonb,
+    "pay_pal_event_id" varchar(100),
+    "processed_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_billing_events_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_billing_events_pay_pal_event_id" ON "billing_events" ("pay_pal_event_id");
+
+CREATE TABLE IF NOT EXISTS "organization_usages" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "period_start" timestamptz NOT NULL,
+    "period_end" timestamptz NOT NULL,
+    "users_count" bigint DEFAULT 0,
+    "projects_count" bigint DEFAULT 0,
+    "environments_count" bigint DEFAULT 0,
+    "schemas_count" bigint DEFAULT 0,
+    "test_records_count" bigint DEFAULT 0,
+    "api_requests_count" bigint DEFAULT 0,
+    "recorded_at" timestamptz DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_organization_usages_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_organization_usage_period" ON "organization_usages" ("organization_id","period_start","period_end");
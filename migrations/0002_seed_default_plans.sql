@@ -0,0 +1,13 @@
+-- Default plans. Existing plans with the same slug are left untouched.
+INSERT INTO plans (id, name, slug, description, price_monthly, price_yearly, max_users, max_projects, max_environments, max_schemas, max_test_records_per_schema, features, is_active, created_at, updated_at) VALUES
+('00000000-0000-0000-0000-000000000001', 'Free', 'free', 'Perfect for personal projects', 0.00, 0.00, 1, 2, 2, 5, 100, '["basic_analytics", "email_support"]', true, NOW(), NOW()),
+('00000000-0000-0000-0000-000000000002', 'Starter', 'starter', 'Great for small teams', 29.00, 290.00, 5, 10, 5, 25, 1000, '["basic_analytics", "email_support", "team_collaboration", "csv_import"]', true, NOW(), NOW()),
+('00000000-0000-0000-0000-000000000003', 'Professional', 'professional', 'For growing development teams', 79.00, 790.00, 25, 50, 15, 100, 10000, '["advanced_analytics", "priority_support", "team_collaboration", "csv_import", "api_access", "custom_validation"]', true, NOW(), NOW()),
+('00000000-0000-0000-0000-000000000004', 'Enterprise', 'enterprise', 'For large organizations', 199.00, 1990.00, 100, 200, 50, 500, 100000, '["advanced_analytics", "priority_support", "team_collaboration", "csv_import", "api_access", "custom_validation", "sso", "audit_logs", "custom_integrations"]', true, NOW(), NOW())
+ON CONFLICT (slug) DO NOTHING;
+
+-- Link organizations created before plans existed to the plan matching their plan type
+UPDATE organizations o
+SET plan_id = p.id
+FROM plans p
+WHERE o.plan_id IS NULL AND p.slug = o.plan_type;
@@ -0,0 +1,4 @@
+-- Downgrades are applied by the billing job once the current period ends.
+ALTER TABLE "subscriptions" ADD COLUMN IF NOT EXISTS "scheduled_plan_id" uuid;
+ALTER TABLE "subscriptions" ADD COLUMN IF NOT EXISTS "scheduled_billing_cycle" varchar(20);
+CREATE INDEX IF NOT EXISTS "idx_subscriptions_current_period_end" ON "subscriptions" ("current_period_end");
@@ -0,0 +1,18 @@
+-- Gap-free invoice numbers per year, continuing from the invoices issued so far.
+CREATE TABLE IF NOT EXISTS "invoice_sequences" (
+    "year" bigint,
+    "last_number" bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY ("year")
+);
+INSERT INTO "invoice_sequences" ("year", "last_number")
+SELECT CAST(split_part(invoice_number, '-', 2) AS bigint), MAX(CAST(split_part(invoice_number, '-', 3) AS bigint))
+FROM "invoices"
+WHERE invoice_number ~ '^INV-[0-9]{4}-[0-9]+$'
+GROUP BY 1
+ON CONFLICT ("year") DO NOTHING;
+
+-- A subscription is invoiced once per period.
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_invoices_subscription_period" ON "invoices" ("subscription_id", "billing_period_start");
+
+-- Vaulted PayPal accounts can be charged by the billing run.
+ALTER TABLE "payment_methods" ADD COLUMN IF NOT EXISTS "pay_pal_vault_id" varchar(100);
@@ -0,0 +1,6 @@
+-- Dunning state of invoices whose payment failed, driven by the billing job.
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "dunning_status" varchar(20) DEFAULT 'none';
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "dunning_retries" bigint DEFAULT 0;
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "dunning_started_at" timestamptz;
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "next_retry_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_invoices_next_retry_at" ON "invoices" ("next_retry_at");
@@ -0,0 +1,7 @@
+-- Trials of paid plans end through the billing job, which reminds the
+-- organization a few days before.
+ALTER TABLE "subscriptions" ADD COLUMN IF NOT EXISTS "trial_reminder_sent_at" timestamptz;
+CREATE INDEX IF NOT EXISTS "idx_subscriptions_trial_end" ON "subscriptions" ("trial_end");
+
+-- Staff with access to the cross-organization admin endpoints, granted in the database.
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "is_platform_admin" boolean DEFAULT false;
@@ -0,0 +1,31 @@
+-- Usage alerts are sent when usage reaches these percentages of a plan limit.
+ALTER TABLE "plans" ADD COLUMN IF NOT EXISTS "usage_alert_thresholds" jsonb NOT NULL DEFAULT '[80,90,95]';
+
+-- One alert per organization, resource and threshold in a billing period.
+CREATE TABLE IF NOT EXISTS "usage_alerts" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "resource" varchar(50) NOT NULL,
+    "threshold" bigint NOT NULL,
+    "period_start" timestamptz NOT NULL,
+    "used" bigint NOT NULL,
+    "limit" bigint NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_usage_alerts_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_usage_alerts_threshold" ON "usage_alerts" ("organization_id","resource","threshold","period_start");
+
+CREATE TABLE IF NOT EXISTS "notifications" (
+    "id" uuid,
+    "user_id" uuid NOT NULL,
+    "organization_id" uuid,
+    "type" varchar(50) NOT NULL,
+    "title" varchar(200) NOT NULL,
+    "message" text NOT NULL,
+    "read_at" timestamptz,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_notifications_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_notifications_user_id" ON "notifications" ("user_id");
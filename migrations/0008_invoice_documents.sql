@@ -0,0 +1,13 @@
+-- Rendered invoice PDFs, final once the invoice is paid.
+CREATE TABLE IF NOT EXISTS "invoice_documents" (
+    "invoice_id" uuid,
+    "filename" varchar(100) NOT NULL,
+    "content" bytea NOT NULL,
+    "checksum" varchar(64) NOT NULL,
+    "final" boolean DEFAULT false,
+    "invoice_updated_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("invoice_id"),
+    CONSTRAINT "fk_invoice_documents_invoice" FOREIGN KEY ("invoice_id") REFERENCES "invoices"("id")
+);
@@ -0,0 +1,39 @@
+-- Running refund balances of payments and invoices.
+ALTER TABLE "payments" ADD COLUMN IF NOT EXISTS "refunded_amount" decimal(10,2) DEFAULT 0;
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "refunded_amount" decimal(10,2) DEFAULT 0;
+
+-- Credit notes document each refund against the invoice the payment settled.
+CREATE TABLE IF NOT EXISTS "credit_notes" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "invoice_id" uuid NOT NULL,
+    "payment_id" uuid NOT NULL,
+    "credit_note_number" varchar(50) NOT NULL,
+    "amount" decimal(10,2) NOT NULL,
+    "currency" varchar(3) DEFAULT 'USD',
+    "reason" text,
+    "status" varchar(20) DEFAULT 'pending',
+    "pay_pal_refund_id" varchar(100),
+    "failure_reason" text,
+    "created_by" uuid,
+    "issued_at" timestamptz,
+    "pdf_content" bytea,
+    "pdf_checksum" varchar(64),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_credit_notes_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_credit_notes_invoice" FOREIGN KEY ("invoice_id") REFERENCES "invoices"("id"),
+    CONSTRAINT "fk_credit_notes_payment" FOREIGN KEY ("payment_id") REFERENCES "payments"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_credit_notes_credit_note_number" ON "credit_notes" ("credit_note_number");
+CREATE INDEX IF NOT EXISTS "idx_credit_notes_organization_id" ON "credit_notes" ("organization_id");
+CREATE INDEX IF NOT EXISTS "idx_credit_notes_invoice_id" ON "credit_notes" ("invoice_id");
+CREATE INDEX IF NOT EXISTS "idx_credit_notes_payment_id" ON "credit_notes" ("payment_id");
+
+-- Gap-free credit note numbers per year.
+CREATE TABLE IF NOT EXISTS "credit_note_sequences" (
+    "year" bigint,
+    "last_number" bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY ("year")
+);
@@ -0,0 +1,44 @@
+-- Discount codes handed out by sales.
+CREATE TABLE IF NOT EXISTS "coupons" (
+    "id" uuid,
+    "code" varchar(50) NOT NULL,
+    "name" varchar(100) NOT NULL,
+    "discount_type" varchar(20) NOT NULL,
+    "percent_off" decimal(5,2),
+    "amount_off" decimal(10,2),
+    "currency" varchar(3) DEFAULT 'USD',
+    "duration" varchar(20) NOT NULL,
+    "duration_in_cycles" bigint,
+    "max_redemptions" bigint,
+    "times_redeemed" bigint NOT NULL DEFAULT 0,
+    "expires_at" timestamptz,
+    "plan_ids" jsonb NOT NULL DEFAULT '[]',
+    "is_active" boolean DEFAULT true,
+    "created_by" uuid,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_coupons_code" ON "coupons" ("code");
+
+-- One redemption per coupon and organization, following the subscription it discounts.
+CREATE TABLE IF NOT EXISTS "coupon_redemptions" (
+    "id" uuid,
+    "coupon_id" uuid NOT NULL,
+    "organization_id" uuid NOT NULL,
+    "subscription_id" uuid NOT NULL,
+    "redeemed_by" uuid NOT NULL,
+    "cycles_applied" bigint NOT NULL DEFAULT 0,
+    "last_period_start" timestamptz,
+    "gateway_price" decimal(10,2),
+    "ended_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_coupon_redemptions_coupon" FOREIGN KEY ("coupon_id") REFERENCES "coupons"("id"),
+    CONSTRAINT "fk_coupon_redemptions_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id"),
+    CONSTRAINT "fk_coupon_redemptions_subscription" FOREIGN KEY ("subscription_id") REFERENCES "subscriptions"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_coupon_redemptions_organization" ON "coupon_redemptions" ("coupon_id","organization_id");
+CREATE INDEX IF NOT EXISTS "idx_coupon_redemptions_organization_id" ON "coupon_redemptions" ("organization_id");
+CREATE INDEX IF NOT EXISTS "idx_coupon_redemptions_subscription_id" ON "coupon_redemptions" ("subscription_id");
@@ -0,0 +1,14 @@
+-- Billing address and tax ID of organizations, used to tax their invoices.
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "billing_name" varchar(200);
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "billing_address_line1" varchar(255);
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "billing_address_line2" varchar(255);
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "billing_city" varchar(100);
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "billing_postal_code" varchar(20);
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "billing_state" varchar(100);
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "billing_country" varchar(2);
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "tax_id" varchar(50);
+
+-- The tax each invoice was charged, next to the existing tax_amount.
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "tax_rate" decimal(5,2) DEFAULT 0;
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "tax_description" varchar(100);
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "reverse_charge" boolean DEFAULT false;
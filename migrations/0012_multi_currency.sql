@@ -0,0 +1,41 @@
+-- Money is stored as integer minor units of its currency, e.g. cents, instead
+-- of decimals. Every amount so far was in a currency with two decimals except
+-- where a row says otherwise.
+ALTER TABLE "plans" ALTER COLUMN "price_monthly" TYPE bigint USING round("price_monthly" * 100);
+ALTER TABLE "plans" ALTER COLUMN "price_yearly" TYPE bigint USING round("price_yearly" * 100);
+ALTER TABLE "invoices" ALTER COLUMN "amount" TYPE bigint USING round("amount" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+ALTER TABLE "invoices" ALTER COLUMN "tax_amount" TYPE bigint USING round("tax_amount" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+ALTER TABLE "invoices" ALTER COLUMN "total_amount" TYPE bigint USING round("total_amount" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+ALTER TABLE "invoices" ALTER COLUMN "refunded_amount" TYPE bigint USING round("refunded_amount" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+-- Line items are in the currency of their invoice, which a USING clause cannot
+-- look up, so they are scaled before the type changes.
+UPDATE "invoice_line_items" SET
+    "unit_price" = "invoice_line_items"."unit_price" * CASE WHEN "invoices"."currency" = 'JPY' THEN 1 ELSE 100 END,
+    "total_price" = "invoice_line_items"."total_price" * CASE WHEN "invoices"."currency" = 'JPY' THEN 1 ELSE 100 END
+FROM "invoices" WHERE "invoices"."id" = "invoice_line_items"."invoice_id";
+ALTER TABLE "invoice_line_items" ALTER COLUMN "unit_price" TYPE bigint USING round("unit_price");
+ALTER TABLE "invoice_line_items" ALTER COLUMN "total_price" TYPE bigint USING round("total_price");
+ALTER TABLE "payments" ALTER COLUMN "amount" TYPE bigint USING round("amount" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+ALTER TABLE "payments" ALTER COLUMN "refunded_amount" TYPE bigint USING round("refunded_amount" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+ALTER TABLE "credit_notes" ALTER COLUMN "amount" TYPE bigint USING round("amount" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+ALTER TABLE "coupons" ALTER COLUMN "amount_off" TYPE bigint USING round("amount_off" * CASE WHEN "currency" = 'JPY' THEN 1 ELSE 100 END);
+ALTER TABLE "coupon_redemptions" ALTER COLUMN "gateway_price" TYPE bigint USING round("gateway_price" * 100);
+
+-- The currency an organization is billed in, chosen when it subscribes.
+ALTER TABLE "organizations" ADD COLUMN IF NOT EXISTS "currency" varchar(3) DEFAULT 'USD';
+ALTER TABLE "subscriptions" ADD COLUMN IF NOT EXISTS "currency" varchar(3) NOT NULL DEFAULT 'USD';
+
+-- Plan prices in currencies other than USD, which stays on the plans row.
+CREATE TABLE IF NOT EXISTS "plan_prices" (
+    "id" uuid,
+    "plan_id" uuid NOT NULL,
+    "currency" varchar(3) NOT NULL,
+    "billing_cycle" varchar(20) NOT NULL,
+    "amount" bigint NOT NULL,
+    "pay_pal_plan_id" varchar(100),
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_plans_prices" FOREIGN KEY ("plan_id") REFERENCES "plans"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_plan_prices_point" ON "plan_prices" ("plan_id","currency","billing_cycle");
@@ -0,0 +1,13 @@
+-- Plans are versioned: a new version with the same slug replaces a plan for new
+-- subscribers and the retired one keeps billing its subscribers at its price.
+ALTER TABLE "plans" ADD COLUMN IF NOT EXISTS "version" bigint NOT NULL DEFAULT 1;
+ALTER TABLE "plans" ADD COLUMN IF NOT EXISTS "previous_version_id" uuid;
+ALTER TABLE "plans" ADD COLUMN IF NOT EXISTS "retired_at" timestamptz;
+DROP INDEX IF EXISTS "idx_plans_slug";
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_plans_slug_version" ON "plans" ("slug","version");
+
+-- The PayPal catalog product the PayPal plans of every version belong to.
+ALTER TABLE "plans" ADD COLUMN IF NOT EXISTS "pay_pal_product_id" varchar(100);
+
+-- Plans taken off the catalogue before versioning existed are retired.
+UPDATE "plans" SET "retired_at" = "updated_at" WHERE "is_active" = false AND "retired_at" IS NULL;
@@ -0,0 +1,12 @@
+-- Payment methods are only created through the PayPal vault flow: a setup
+-- token the payer approves is exchanged for the vault token the billing run
+-- charges. Methods the provider reports failing are marked invalid.
+ALTER TABLE "payment_methods" ADD COLUMN IF NOT EXISTS "status" varchar(20) DEFAULT 'verified';
+ALTER TABLE "payment_methods" ADD COLUMN IF NOT EXISTS "pay_pal_setup_token_id" varchar(100);
+ALTER TABLE "payment_methods" ADD COLUMN IF NOT EXISTS "failure_reason" text;
+ALTER TABLE "payment_methods" ADD COLUMN IF NOT EXISTS "invalidated_at" timestamptz;
+
+-- Methods typed in by clients were never approved by the payer and cannot be charged.
+UPDATE "payment_methods"
+SET "status" = 'invalid', "failure_reason" = 'Not saved with PayPal, add the payment method again', "is_default" = false, "invalidated_at" = now()
+WHERE "pay_pal_vault_id" IS NULL AND "status" = 'verified';
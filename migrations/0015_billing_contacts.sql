@@ -0,0 +1,30 @@
+-- Additional addresses billing emails are sent to, next to the billing email.
+CREATE TABLE IF NOT EXISTS "billing_contacts" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "email" varchar(255) NOT NULL,
+    "name" varchar(200),
+    "created_by" uuid NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_billing_contacts_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_billing_contacts_email" ON "billing_contacts" ("organization_id", "email");
+
+-- Every attempt at emailing an invoice, per recipient.
+CREATE TABLE IF NOT EXISTS "invoice_deliveries" (
+    "id" uuid,
+    "invoice_id" uuid NOT NULL,
+    "organization_id" uuid NOT NULL,
+    "kind" varchar(30) NOT NULL,
+    "recipient" varchar(255) NOT NULL,
+    "subject" varchar(255) NOT NULL,
+    "status" varchar(20) NOT NULL,
+    "error" text,
+    "attempted_at" timestamptz NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_invoice_deliveries_invoice" FOREIGN KEY ("invoice_id") REFERENCES "invoices"("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_invoice_deliveries_invoice_id" ON "invoice_deliveries" ("invoice_id");
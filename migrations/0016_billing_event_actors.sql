@@ -0,0 +1,9 @@
+-- Who caused each billing event: a user, a platform admin, PayPal or the
+-- billing run, so the event log can be filtered by actor.
+ALTER TABLE "billing_events" ADD COLUMN IF NOT EXISTS "actor_type" varchar(20) NOT NULL DEFAULT 'system';
+ALTER TABLE "billing_events" ADD COLUMN IF NOT EXISTS "actor_id" uuid;
+CREATE INDEX IF NOT EXISTS "idx_billing_events_actor_id" ON "billing_events" ("actor_id");
+CREATE INDEX IF NOT EXISTS "idx_billing_events_organization_created" ON "billing_events" ("organization_id", "created_at");
+
+-- Events recorded before came from webhooks or from the billing run.
+UPDATE "billing_events" SET "actor_type" = 'paypal' WHERE "pay_pal_event_id" IS NOT NULL;
@@ -0,0 +1,24 @@
+-- Email domains claimed for single sign-on. A domain only routes logins once the
+-- organization verified it through a DNS TXT record, and only one organization
+-- can hold the verification of a domain.
+CREATE TABLE IF NOT EXISTS "organization_sso_domains" (
+    "id" uuid,
+    "organization_id" uuid NOT NULL,
+    "domain" varchar(255) NOT NULL,
+    "verification_token" varchar(100) NOT NULL,
+    "verified_at" timestamptz,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_organization_sso_domains_organization" FOREIGN KEY ("organization_id") REFERENCES "organizations"("id")
+);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_organization_sso_domains_domain" ON "organization_sso_domains" ("organization_id", "domain");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_organization_sso_domains_verified" ON "organization_sso_domains" ("domain") WHERE "verified_at" IS NOT NULL;
+
+-- Domains configured before verification existed start unverified
+INSERT INTO "organization_sso_domains" ("id", "organization_id", "domain", "verification_token", "created_at", "updated_at")
+SELECT DISTINCT ON (c."organization_id", LOWER(TRIM(d.domain)))
+       gen_random_uuid(), c."organization_id", LOWER(TRIM(d.domain)), md5(random()::text || clock_timestamp()::text), NOW(), NOW()
+FROM "organization_sso_configs" c, unnest(string_to_array(c."allowed_domains", ',')) AS d(domain)
+WHERE TRIM(d.domain) <> ''
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+-- Proration invoices of plan upgrades are charged to the vaulted payment
+-- method also when a PayPal subscription bills the plan, so they are told
+-- apart from the period invoices the provider pays.
+ALTER TABLE "invoices" ADD COLUMN IF NOT EXISTS "is_proration" boolean DEFAULT false;
+
+UPDATE "invoices" SET "is_proration" = true
+WHERE EXISTS (
+    SELECT 1 FROM "invoice_line_items"
+    WHERE "invoice_line_items"."invoice_id" = "invoices"."id"
+      AND ("invoice_line_items"."description" LIKE 'Unused time on %'
+        OR "invoice_line_items"."description" LIKE 'Remaining time on %')
+);
@@ -0,0 +1,276 @@
+// Package migrations applies the versioned, forward-only SQL migrations in this
+// directory and records them in the schema_migrations table.
+//
+// A migration is a file named <version>_<name>.sql, e.g. 0003_add_coupons.sql.
+// Applied migrations must never be edited; add a new one instead.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// advisoryLockKey serializes migration runs across app instances sharing a database.
+const advisoryLockKey = 727155201
+
+// ErrModifiedMigration is returned by Up when an applied migration no longer
+// matches its file.
+var ErrModifiedMigration = errors.New("applied migration was modified")
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.sql$`)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	SQL      string
+	Checksum string
+}
+
+// MigrationStatus describes a migration as found in the source tree and the database.
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+	// Modified is set when an applied migration no longer matches its file.
+	Modified bool
+}
+
+// Load returns the embedded migrations ordered by version.
+func Load() ([]Migration, error) {
+	return load(files)
+}
+
+func load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []Migration
+	seen := map[int64]string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s must be named <version>_<name>.sql", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		if other, found := seen[version]; found {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		migrations = append(migrations, Migration{
+			Version:  version,
+			Name:     match[2],
+			SQL:      string(content),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction. A Postgres advisory lock makes instances starting together
+// wait for each other instead of applying the same migration twice. Nothing is
+// applied when an applied migration was modified.
+func Up(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	// Session level advisory locks belong to a connection, so everything runs on one
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", advisoryLockKey); err != nil {
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", advisoryLockKey)
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make(map[int64]string, len(applied))
+	for version, record := range applied {
+		checksums[version] = record.checksum
+	}
+	pending, err := Pending(migrations, checksums)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		if err := apply(ctx, conn, migration); err != nil {
+			return done, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Pending returns the migrations that are not in applied, which maps the
+// versions of the applied migrations to their checksums. It fails with
+// ErrModifiedMigration when an applied migration no longer matches its file,
+// since the database may then not have the schema the file describes.
+func Pending(migrations []Migration, applied map[int64]string) ([]Migration, error) {
+	var pending []Migration
+	for _, migration := range migrations {
+		checksum, found := applied[migration.Version]
+		if !found {
+			pending = append(pending, migration)
+			continue
+		}
+		if checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, ErrModifiedMigration)
+		}
+	}
+	return pending, nil
+}
+
+// Status lists every known migration with the time it was applied, if it was.
+func Status(ctx context.Context, db *sql.DB) ([]MigrationStatus, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := ensureTable(ctx, conn); err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, len(migrations))
+	for i, migration := range migrations {
+		statuses[i] = MigrationStatus{Migration: migration}
+		if record, found := applied[migration.Version]; found {
+			appliedAt := record.appliedAt
+			statuses[i].AppliedAt = &appliedAt
+			statuses[i].Modified = record.checksum != migration.Checksum
+		}
+	}
+	return statuses, nil
+}
+
+// Create writes an empty migration numbered after the newest one in dir and
+// returns its path.
+func Create(dir, name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(name, "_")
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return "", fmt.Errorf("migration name is required")
+	}
+
+	existing, err := load(os.DirFS(dir))
+	if err != nil {
+		return "", err
+	}
+	version := int64(1)
+	if len(existing) > 0 {
+		version = existing[len(existing)-1].Version + 1
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%04d_%s.sql", version, name))
+	content := fmt.Sprintf("-- %s\n-- Migrations are forward-only: never edit this file once it has been applied.\n\n", strings.ReplaceAll(name, "_", " "))
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version bigint PRIMARY KEY,
+		name varchar(255) NOT NULL,
+		checksum varchar(64) NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT NOW()
+	)`)
+	return err
+}
+
+type appliedMigration struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func appliedMigrations(ctx context.Context, conn *sql.Conn) (map[int64]appliedMigration, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, checksum, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := map[int64]appliedMigration{}
+	for rows.Next() {
+		var version int64
+		var record appliedMigration
+		if err := rows.Scan(&version, &record.checksum, &record.appliedAt); err != nil {
+			return nil, err
+		}
+		applied[version] = record
+	}
+	return applied, rows.Err()
+}
+
+func apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)",
+		migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
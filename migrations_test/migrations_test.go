@@ -0,0 +1,125 @@
+package migrations_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"testlake/dao"
+	"testlake/migrations"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/schema"
+)
+
+func TestLoadOrdersMigrations(t *testing.T) {
+	loaded, err := migrations.Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, loaded)
+
+	assert.Equal(t, int64(1), loaded[0].Version)
+	assert.Equal(t, "baseline", loaded[0].Name)
+	for i := 1; i < len(loaded); i++ {
+		assert.Greater(t, loaded[i].Version, loaded[i-1].Version)
+	}
+	for _, migration := range loaded {
+		assert.Len(t, migration.Checksum, 64)
+		assert.NotEmpty(t, strings.TrimSpace(migration.SQL))
+	}
+}
+
+func TestPendingSkipsAppliedMigrations(t *testing.T) {
+	loaded, err := migrations.Load()
+	require.NoError(t, err)
+	require.Greater(t, len(loaded), 2)
+
+	applied := map[int64]string{loaded[0].Version: loaded[0].Checksum, loaded[1].Version: loaded[1].Checksum}
+	pending, err := migrations.Pending(loaded, applied)
+	require.NoError(t, err)
+	assert.Equal(t, loaded[2:], pending)
+
+	for _, migration := range loaded {
+		applied[migration.Version] = migration.Checksum
+	}
+	pending, err = migrations.Pending(loaded, applied)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestPendingRefusesModifiedMigrations(t *testing.T) {
+	loaded, err := migrations.Load()
+	require.NoError(t, err)
+
+	applied := map[int64]string{loaded[0].Version: strings.Repeat("0", 64)}
+	pending, err := migrations.Pending(loaded, applied)
+	assert.ErrorIs(t, err, migrations.ErrModifiedMigration)
+	assert.Contains(t, err.Error(), "0001_baseline")
+	assert.Empty(t, pending)
+}
+
+// Every model column must exist in a migration, either in its CREATE TABLE or
+// added later with ALTER TABLE ... ADD COLUMN.
+func TestMigrationsCoverModels(t *testing.T) {
+	loaded, err := migrations.Load()
+	require.NoError(t, err)
+
+	var all strings.Builder
+	for _, migration := range loaded {
+		all.WriteString(migration.SQL)
+		all.WriteString("\n")
+	}
+	sql := all.String()
+
+	cache := &sync.Map{}
+	for _, model := range dao.Models() {
+		parsed, err := schema.Parse(model, cache, schema.NamingStrategy{})
+		require.NoError(t, err)
+
+		create := regexp.MustCompile(`(?s)CREATE TABLE IF NOT EXISTS "` + parsed.Table + `" \((.*?)\n\);`).FindStringSubmatch(sql)
+		if !assert.NotNil(t, create, "no migration creates table %s", parsed.Table) {
+			continue
+		}
+		for _, field := range parsed.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			inCreate := strings.Contains(create[1], `"`+field.DBName+`"`)
+			added := regexp.MustCompile(`(?i)ALTER TABLE "?` + parsed.Table + `"?\s+ADD COLUMN (IF NOT EXISTS )?"?` + field.DBName + `"?\s`).MatchString(sql)
+			assert.True(t, inCreate || added, "no migration adds column %s.%s", parsed.Table, field.DBName)
+		}
+	}
+}
+
+func TestCreateNumbersAfterNewest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_baseline.sql"), []byte("SELECT 1;"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0007_add_things.sql"), []byte("SELECT 1;"), 0o644))
+
+	path, err := migrations.Create(dir, "Add Coupons!")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "0008_add_coupons.sql"), path)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "forward-only")
+}
+
+func TestCreateRejectsInvalidNames(t *testing.T) {
+	dir := t.TempDir()
+
+	_, err := migrations.Create(dir, "  !!  ")
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "baseline.sql"), []byte("SELECT 1;"), 0o644))
+	_, err = migrations.Create(dir, "next")
+	assert.Error(t, err, "files without a version are rejected")
+}
+
+func TestCreateStartsAtOne(t *testing.T) {
+	path, err := migrations.Create(t.TempDir(), "first")
+	require.NoError(t, err)
+	assert.Equal(t, "0001_first.sql", filepath.Base(path))
+}
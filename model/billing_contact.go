@@ -0,0 +1,30 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// BillingContact is an additional address the billing emails of an
+// organization go to, next to its billing email.
+type BillingContact struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_billing_contacts_email" json:"organization_id"`
+	Email          string    `gorm:"type:varchar(255);not null;uniqueIndex:idx_billing_contacts_email" json:"email"`
+	Name           *string   `gorm:"type:varchar(200)" json:"name"`
+	CreatedBy      uuid.UUID `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
+}
+
+func (bc *BillingContact) BeforeCreate(tx *gorm.DB) (err error) {
+	if bc.ID == uuid.Nil {
+		bc.ID = uuid.New()
+	}
+	return
+}
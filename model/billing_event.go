@@ -17,17 +17,75 @@ const (
 	BillingEventTypePaymentFailed         BillingEventType = "payment_failed"
 	BillingEventTypeInvoiceCreated        BillingEventType = "invoice_created"
 	BillingEventTypePlanChanged           BillingEventType = "plan_changed"
+	BillingEventTypeSubscriptionActivated BillingEventType = "subscription_activated"
+	BillingEventTypeSubscriptionSuspended BillingEventType = "subscription_suspended"
+	BillingEventTypeInvoicePaid           BillingEventType = "invoice_paid"
+	BillingEventTypeInvoiceCancelled      BillingEventType = "invoice_cancelled"
+	BillingEventTypeDunningStarted        BillingEventType = "dunning_started"
+	BillingEventTypeDunningRetryFailed    BillingEventType = "dunning_retry_failed"
+	BillingEventTypeDunningExhausted      BillingEventType = "dunning_exhausted"
+	BillingEventTypeDunningRecovered      BillingEventType = "dunning_recovered"
+	BillingEventTypeTrialStarted          BillingEventType = "trial_started"
+	BillingEventTypeTrialConverted        BillingEventType = "trial_converted"
+	BillingEventTypeTrialExpired          BillingEventType = "trial_expired"
+	BillingEventTypePaymentRefunded       BillingEventType = "payment_refunded"
+	BillingEventTypeRefundFailed          BillingEventType = "refund_failed"
+	BillingEventTypeCouponRedeemed        BillingEventType = "coupon_redeemed"
+	BillingEventTypeBillingDetailsUpdated BillingEventType = "billing_details_updated"
+
+	BillingEventTypePaymentMethodAdded       BillingEventType = "payment_method_added"
+	BillingEventTypePaymentMethodRemoved     BillingEventType = "payment_method_removed"
+	BillingEventTypePaymentMethodInvalidated BillingEventType = "payment_method_invalidated"
+
+	BillingEventTypeBillingContactAdded   BillingEventType = "billing_contact_added"
+	BillingEventTypeBillingContactRemoved BillingEventType = "billing_contact_removed"
+
+	BillingEventTypeSubscriptionReactivated BillingEventType = "subscription_reactivated"
+	BillingEventTypePaymentMethodDefault    BillingEventType = "payment_method_default_changed"
+)
+
+// BillingActorType is who caused a billing event.
+type BillingActorType string
+
+const (
+	BillingActorTypeSystem BillingActorType = "system" // the billing run and other jobs
+	BillingActorTypeUser   BillingActorType = "user"   // a member of the organization
+	BillingActorTypeAdmin  BillingActorType = "admin"  // a platform admin
+	BillingActorTypePayPal BillingActorType = "paypal" // a PayPal webhook
+)
+
+// BillingActor is who caused a billing event. ID is the user of user and
+// admin actors.
+type BillingActor struct {
+	Type BillingActorType
+	ID   *uuid.UUID
+}
+
+var (
+	SystemActor = BillingActor{Type: BillingActorTypeSystem}
+	PayPalActor = BillingActor{Type: BillingActorTypePayPal}
 )
 
+func UserActor(userID uuid.UUID) BillingActor {
+	return BillingActor{Type: BillingActorTypeUser, ID: &userID}
+}
+
+func AdminActor(userID uuid.UUID) BillingActor {
+	return BillingActor{Type: BillingActorTypeAdmin, ID: &userID}
+}
+
 type BillingEvent struct {
 	ID             uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
 	OrganizationID uuid.UUID        `gorm:"type:uuid;not null" json:"organization_id"`
 	EventType      BillingEventType `gorm:"type:varchar(50);not null" json:"event_type"`
 	EventData      *string          `gorm:"type:jsonb" json:"event_data"`
-	PayPalEventID  *string          `gorm:"type:varchar(100)" json:"paypal_event_id"`
+	PayPalEventID  *string          `gorm:"type:varchar(100);uniqueIndex" json:"paypal_event_id"`
 	ProcessedAt    time.Time        `gorm:"default:CURRENT_TIMESTAMP" json:"processed_at"`
 	CreatedAt      time.Time        `json:"created_at"`
 
+	ActorType BillingActorType `gorm:"type:varchar(20);not null;default:system" json:"actor_type"`
+	ActorID   *uuid.UUID       `gorm:"type:uuid;index" json:"actor_id"`
+
 	// Relationships
 	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
 }
@@ -0,0 +1,178 @@
+package model
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CouponDiscountType string
+
+const (
+	CouponDiscountTypePercent CouponDiscountType = "percent"
+	CouponDiscountTypeFixed   CouponDiscountType = "fixed"
+)
+
+// CouponDuration is how many billing periods of a subscription a redeemed
+// coupon discounts.
+type CouponDuration string
+
+const (
+	CouponDurationOnce      CouponDuration = "once"
+	CouponDurationRepeating CouponDuration = "repeating" // DurationInCycles periods
+	CouponDurationForever   CouponDuration = "forever"
+)
+
+// Coupon is a discount code an organization redeems when subscribing or
+// changing plans. Codes are stored upper case and matched case-insensitively.
+type Coupon struct {
+	ID               uuid.UUID          `gorm:"type:uuid;primaryKey" json:"id"`
+	Code             string             `gorm:"type:varchar(50);uniqueIndex;not null" json:"code"`
+	Name             string             `gorm:"type:varchar(100);not null" json:"name"`
+	DiscountType     CouponDiscountType `gorm:"type:varchar(20);not null" json:"discount_type"`
+	PercentOff       *float64           `gorm:"type:decimal(5,2)" json:"percent_off"`
+	AmountOff        *int64             `json:"amount_off"` // minor units of Currency
+	Currency         string             `gorm:"type:varchar(3);default:USD" json:"currency"`
+	Duration         CouponDuration     `gorm:"type:varchar(20);not null" json:"duration"`
+	DurationInCycles *int               `json:"duration_in_cycles"`
+	MaxRedemptions   *int               `json:"max_redemptions"` // nil for no limit
+	TimesRedeemed    int                `gorm:"not null;default:0" json:"times_redeemed"`
+	ExpiresAt        *time.Time         `json:"expires_at"`                                       // last moment the coupon can be redeemed
+	PlanIDs          string             `gorm:"type:jsonb;not null;default:'[]'" json:"plan_ids"` // JSON array of the plans it is limited to, empty for all plans
+	IsActive         bool               `gorm:"default:true" json:"is_active"`
+	CreatedBy        *uuid.UUID         `gorm:"type:uuid" json:"created_by"`
+	CreatedAt        time.Time          `json:"created_at"`
+	UpdatedAt        time.Time          `json:"updated_at"`
+}
+
+// CouponRedemption is the coupon an organization redeemed for a subscription.
+// An organization redeems a coupon once; the discount follows the subscription
+// until the coupon's duration is used up.
+type CouponRedemption struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	CouponID       uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_coupon_redemptions_organization" json:"coupon_id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_coupon_redemptions_organization;index" json:"organization_id"`
+	SubscriptionID uuid.UUID `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	RedeemedBy     uuid.UUID `gorm:"type:uuid;not null" json:"redeemed_by"`
+	CyclesApplied  int       `gorm:"not null;default:0" json:"cycles_applied"`
+	// LastPeriodStart is the start of the last billing period discounted, so a
+	// period is never counted twice
+	LastPeriodStart *time.Time `json:"last_period_start"`
+	// GatewayPrice is the price the payment provider charges the subscription
+	// instead of the plan price, nil while it charges the plan price
+	GatewayPrice *int64     `json:"gateway_price"` // minor units of the subscription currency
+	EndedAt      *time.Time `json:"ended_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Relationships
+	Coupon       Coupon       `gorm:"foreignKey:CouponID;references:ID" json:"-"`
+	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
+	Subscription Subscription `gorm:"foreignKey:SubscriptionID;references:ID" json:"-"`
+}
+
+func (c *Coupon) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+func (r *CouponRedemption) BeforeCreate(tx *gorm.DB) (err error) {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return
+}
+
+// NormalizeCouponCode is the form coupon codes are stored and looked up in.
+func NormalizeCouponCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// IsExpired reports whether the coupon can no longer be redeemed at now.
+func (c *Coupon) IsExpired(now time.Time) bool {
+	return c.ExpiresAt != nil && now.After(*c.ExpiresAt)
+}
+
+// IsExhausted reports whether the coupon reached its redemption limit.
+func (c *Coupon) IsExhausted() bool {
+	return c.MaxRedemptions != nil && c.TimesRedeemed >= *c.MaxRedemptions
+}
+
+// RestrictedPlanIDs returns the plans the coupon is limited to, empty when it
+// applies to every plan.
+func (c *Coupon) RestrictedPlanIDs() []uuid.UUID {
+	var planIDs []uuid.UUID
+	if c.PlanIDs == "" || json.Unmarshal([]byte(c.PlanIDs), &planIDs) != nil {
+		return nil
+	}
+	return planIDs
+}
+
+// AppliesToPlan reports whether the coupon discounts the plan.
+func (c *Coupon) AppliesToPlan(planID uuid.UUID) bool {
+	planIDs := c.RestrictedPlanIDs()
+	if len(planIDs) == 0 {
+		return true
+	}
+	for _, id := range planIDs {
+		if id == planID {
+			return true
+		}
+	}
+	return false
+}
+
+// Discount returns what the coupon takes off a price in minor units, rounded
+// to the nearest unit and never more than the price. A fixed amount only
+// applies to prices in the coupon's currency.
+func (c *Coupon) Discount(price int64, currency string) int64 {
+	var discount int64
+	switch c.DiscountType {
+	case CouponDiscountTypePercent:
+		if c.PercentOff != nil {
+			discount = int64(math.Round(float64(price) * *c.PercentOff / 100))
+		}
+	case CouponDiscountTypeFixed:
+		if c.AmountOff != nil && strings.EqualFold(c.Currency, currency) {
+			discount = *c.AmountOff
+		}
+	}
+	return max(0, min(discount, price))
+}
+
+// TotalCycles is the number of billing periods a redemption discounts, 0 when
+// it discounts every period.
+func (c *Coupon) TotalCycles() int {
+	switch c.Duration {
+	case CouponDurationOnce:
+		return 1
+	case CouponDurationRepeating:
+		if c.DurationInCycles != nil {
+			return *c.DurationInCycles
+		}
+		return 1
+	}
+	return 0
+}
+
+// HasCyclesLeft reports whether the redemption still discounts another billing
+// period. The Coupon relation must be loaded.
+func (r *CouponRedemption) HasCyclesLeft() bool {
+	if r.EndedAt != nil {
+		return false
+	}
+	total := r.Coupon.TotalCycles()
+	return total == 0 || r.CyclesApplied < total
+}
+
+// CoversPeriod reports whether the billing period starting at periodStart was
+// discounted.
+func (r *CouponRedemption) CoversPeriod(periodStart time.Time) bool {
+	return r.LastPeriodStart != nil && r.LastPeriodStart.Equal(periodStart)
+}
@@ -0,0 +1,64 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreditNoteStatus follows the refund behind the credit note: it is pending
+// while the payment provider has not confirmed the refund.
+type CreditNoteStatus string
+
+const (
+	CreditNoteStatusPending CreditNoteStatus = "pending"
+	CreditNoteStatusIssued  CreditNoteStatus = "issued"
+	CreditNoteStatusFailed  CreditNoteStatus = "failed"
+)
+
+// CreditNote documents a full or partial refund of a payment, against the
+// invoice the payment settled.
+type CreditNote struct {
+	ID               uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationID   uuid.UUID        `gorm:"type:uuid;not null;index" json:"organization_id"`
+	InvoiceID        uuid.UUID        `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	PaymentID        uuid.UUID        `gorm:"type:uuid;not null;index" json:"payment_id"`
+	CreditNoteNumber string           `gorm:"type:varchar(50);uniqueIndex;not null" json:"credit_note_number"`
+	Amount           int64            `gorm:"not null" json:"amount"` // minor units of Currency
+	Currency         string           `gorm:"type:varchar(3);default:USD" json:"currency"`
+	Reason           *string          `gorm:"type:text" json:"reason"`
+	Status           CreditNoteStatus `gorm:"type:varchar(20);default:pending" json:"status"`
+	PayPalRefundID   *string          `gorm:"type:varchar(100)" json:"paypal_refund_id"`
+	FailureReason    *string          `gorm:"type:text" json:"failure_reason"`
+	CreatedBy        *uuid.UUID       `gorm:"type:uuid" json:"created_by"`
+	IssuedAt         *time.Time       `json:"issued_at"`
+	// PDFContent is the PDF rendered once the credit note was issued, final from then on
+	PDFContent  []byte    `json:"-"`
+	PDFChecksum *string   `gorm:"type:varchar(64)" json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
+	Invoice      Invoice      `gorm:"foreignKey:InvoiceID;references:ID" json:"-"`
+	Payment      Payment      `gorm:"foreignKey:PaymentID;references:ID" json:"-"`
+}
+
+// CreditNoteSequence hands out gap-free credit note numbers, restarting every year.
+type CreditNoteSequence struct {
+	Year       int   `gorm:"primaryKey;autoIncrement:false" json:"year"`
+	LastNumber int64 `gorm:"not null;default:0" json:"last_number"`
+}
+
+// PDFFilename is the stable download name of the credit note's PDF.
+func (c *CreditNote) PDFFilename() string {
+	return c.CreditNoteNumber + ".pdf"
+}
+
+func (c *CreditNote) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCurrency is the currency of plan base prices and of organizations
+// that never chose one.
+const DefaultCurrency = "USD"
+
+// currencyExponents are the currencies plans can be priced in and the number
+// of decimals of each, i.e. how many minor units make a major one.
+var currencyExponents = map[string]int{
+	"USD": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"CAD": 2,
+	"AUD": 2,
+	"CHF": 2,
+	"SEK": 2,
+	"NOK": 2,
+	"DKK": 2,
+	"PLN": 2,
+	"JPY": 0,
+}
+
+// IsSupportedCurrency reports whether plans can be priced in the ISO 4217 currency.
+func IsSupportedCurrency(currency string) bool {
+	_, found := currencyExponents[strings.ToUpper(currency)]
+	return found
+}
+
+// CurrencyExponent returns the number of decimals of a currency, 2 for
+// currencies not in the table.
+func CurrencyExponent(currency string) int {
+	if exponent, found := currencyExponents[strings.ToUpper(currency)]; found {
+		return exponent
+	}
+	return 2
+}
+
+// ToMinorUnits converts a decimal amount, e.g. 29.99 USD, to the integer minor
+// units money is stored in, e.g. 2999 cents.
+func ToMinorUnits(amount float64, currency string) int64 {
+	return int64(math.Round(amount * math.Pow10(CurrencyExponent(currency))))
+}
+
+// FromMinorUnits converts minor units back to a decimal amount, for APIs and
+// payment providers taking decimals.
+func FromMinorUnits(amount int64, currency string) float64 {
+	return float64(amount) / math.Pow10(CurrencyExponent(currency))
+}
+
+// FormatMinorUnits formats minor units as a decimal string with the currency's
+// number of decimals, e.g. "29.99" for 2999 USD cents.
+func FormatMinorUnits(amount int64, currency string) string {
+	return strconv.FormatFloat(FromMinorUnits(amount, currency), 'f', CurrencyExponent(currency), 64)
+}
+
+// ParseMinorUnits parses a decimal string, e.g. "29.99", to minor units.
+func ParseMinorUnits(value, currency string) (int64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return 0, err
+	}
+	return ToMinorUnits(amount, currency), nil
+}
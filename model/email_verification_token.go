@@ -7,15 +7,24 @@ import (
 	"gorm.io/gorm"
 )
 
+type EmailTokenPurpose string
+
+const (
+	EmailTokenPurposeVerifyEmail EmailTokenPurpose = "verify_email"
+	EmailTokenPurposeChangeEmail EmailTokenPurpose = "change_email"
+)
+
 type EmailVerificationToken struct {
-	ID        uuid.UUID      `gorm:"type:uuid;primaryKey" json:"id"`
-	UserID    uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
-	Token     string         `gorm:"type:varchar(255);uniqueIndex;not null" json:"token"`
-	ExpiresAt time.Time      `gorm:"not null" json:"expires_at"`
-	IsUsed    bool           `gorm:"default:false" json:"is_used"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+	ID        uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID    uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+	Token     string            `gorm:"type:varchar(255);uniqueIndex;not null" json:"token"`
+	Purpose   EmailTokenPurpose `gorm:"type:varchar(30);not null;default:verify_email;index" json:"purpose"`
+	NewEmail  *string           `gorm:"type:varchar(255)" json:"new_email"`
+	ExpiresAt time.Time         `gorm:"not null" json:"expires_at"`
+	IsUsed    bool              `gorm:"default:false" json:"is_used"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+	DeletedAt gorm.DeletedAt    `gorm:"index" json:"-"`
 
 	// Relationship
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
@@ -25,6 +34,9 @@ func (e *EmailVerificationToken) BeforeCreate(tx *gorm.DB) (err error) {
 	if e.ID == uuid.Nil {
 		e.ID = uuid.New()
 	}
+	if e.Purpose == "" {
+		e.Purpose = EmailTokenPurposeVerifyEmail
+	}
 	return
 }
 
@@ -15,24 +15,50 @@ const (
 	InvoiceStatusPaid      InvoiceStatus = "paid"
 	InvoiceStatusCancelled InvoiceStatus = "cancelled"
 	InvoiceStatusRefunded  InvoiceStatus = "refunded"
+	// InvoiceStatusPartiallyRefunded is a paid invoice of which part was refunded
+	InvoiceStatusPartiallyRefunded InvoiceStatus = "partially_refunded"
 )
 
+// DunningStatus tracks the collection of an invoice whose payment failed or
+// that is overdue: retries are scheduled after DunningStartedAt and, when all
+// of them failed, the organization is suspended until the invoice is paid.
+type DunningStatus string
+
+const (
+	DunningStatusNone      DunningStatus = "none"
+	DunningStatusRetrying  DunningStatus = "retrying"
+	DunningStatusExhausted DunningStatus = "exhausted"
+	DunningStatusRecovered DunningStatus = "recovered"
+)
+
+// DunningRetrySchedule is when each retry happens, counted from the start of dunning.
+var DunningRetrySchedule = []time.Duration{24 * time.Hour, 3 * 24 * time.Hour, 7 * 24 * time.Hour}
+
 type Invoice struct {
 	ID                 uuid.UUID     `gorm:"type:uuid;primaryKey" json:"id"`
 	OrganizationID     uuid.UUID     `gorm:"type:uuid;not null" json:"organization_id"`
-	SubscriptionID     *uuid.UUID    `gorm:"type:uuid" json:"subscription_id"`
+	SubscriptionID     *uuid.UUID    `gorm:"type:uuid;uniqueIndex:idx_invoices_subscription_period" json:"subscription_id"`
 	PayPalInvoiceID    *string       `gorm:"type:varchar(100)" json:"paypal_invoice_id"`
 	InvoiceNumber      string        `gorm:"type:varchar(50);uniqueIndex;not null" json:"invoice_number"`
-	Amount             float64       `gorm:"type:decimal(10,2);not null" json:"amount"`
-	TaxAmount          float64       `gorm:"type:decimal(10,2);default:0" json:"tax_amount"`
-	TotalAmount        float64       `gorm:"type:decimal(10,2);not null" json:"total_amount"`
+	Amount             int64         `gorm:"not null" json:"amount"` // minor units of Currency, like every amount below
+	TaxAmount          int64         `gorm:"default:0" json:"tax_amount"`
+	TaxRate            float64       `gorm:"type:decimal(5,2);default:0" json:"tax_rate"` // percent
+	TaxDescription     string        `gorm:"type:varchar(100)" json:"tax_description"`    // e.g. "VAT 19% (DE)" or "Reverse charge"
+	ReverseCharge      bool          `gorm:"default:false" json:"reverse_charge"`
+	TotalAmount        int64         `gorm:"not null" json:"total_amount"`
 	Currency           string        `gorm:"type:varchar(3);default:USD" json:"currency"`
 	Status             InvoiceStatus `gorm:"type:varchar(20);default:draft" json:"status"`
-	BillingPeriodStart *time.Time    `json:"billing_period_start"`
+	IsProration        bool          `gorm:"default:false" json:"is_proration"` // charges a plan change within a period
+	BillingPeriodStart *time.Time    `gorm:"uniqueIndex:idx_invoices_subscription_period" json:"billing_period_start"`
 	BillingPeriodEnd   *time.Time    `json:"billing_period_end"`
 	DueDate            *time.Time    `json:"due_date"`
 	PaidAt             *time.Time    `json:"paid_at"`
+	RefundedAmount     int64         `gorm:"default:0" json:"refunded_amount"`
 	InvoiceURL         *string       `gorm:"type:varchar(500)" json:"invoice_url"`
+	DunningStatus      DunningStatus `gorm:"type:varchar(20);default:none" json:"dunning_status"`
+	DunningRetries     int           `gorm:"default:0" json:"dunning_retries"`
+	DunningStartedAt   *time.Time    `json:"dunning_started_at"`
+	NextRetryAt        *time.Time    `gorm:"index" json:"next_retry_at"`
 	CreatedAt          time.Time     `json:"created_at"`
 	UpdatedAt          time.Time     `json:"updated_at"`
 
@@ -47,14 +73,43 @@ type InvoiceLineItem struct {
 	InvoiceID   uuid.UUID `gorm:"type:uuid;not null" json:"invoice_id"`
 	Description string    `gorm:"type:varchar(255);not null" json:"description"`
 	Quantity    int       `gorm:"default:1" json:"quantity"`
-	UnitPrice   float64   `gorm:"type:decimal(10,2);not null" json:"unit_price"`
-	TotalPrice  float64   `gorm:"type:decimal(10,2);not null" json:"total_price"`
+	UnitPrice   int64     `gorm:"not null" json:"unit_price"` // minor units of the invoice currency
+	TotalPrice  int64     `gorm:"not null" json:"total_price"`
 	CreatedAt   time.Time `json:"created_at"`
 
 	// Relationships
 	Invoice Invoice `gorm:"foreignKey:InvoiceID;references:ID" json:"-"`
 }
 
+// InvoiceSequence hands out gap-free invoice numbers, restarting every year.
+type InvoiceSequence struct {
+	Year       int   `gorm:"primaryKey;autoIncrement:false" json:"year"`
+	LastNumber int64 `gorm:"not null;default:0" json:"last_number"`
+}
+
+// InvoiceDocument is the rendered PDF of an invoice. It is rendered again when
+// the invoice changes, until the invoice is paid: the PDF of a paid invoice is
+// final and never changes again.
+type InvoiceDocument struct {
+	InvoiceID uuid.UUID `gorm:"type:uuid;primaryKey" json:"invoice_id"`
+	Filename  string    `gorm:"type:varchar(100);not null" json:"filename"`
+	Content   []byte    `gorm:"not null" json:"-"`
+	Checksum  string    `gorm:"type:varchar(64);not null" json:"checksum"` // hex SHA-256 of Content
+	Final     bool      `gorm:"default:false" json:"final"`
+	// InvoiceUpdatedAt is the version of the invoice the document was rendered from
+	InvoiceUpdatedAt time.Time `gorm:"not null" json:"invoice_updated_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+
+	// Relationships
+	Invoice Invoice `gorm:"foreignKey:InvoiceID;references:ID" json:"-"`
+}
+
+// PDFFilename is the stable download name of the invoice's PDF.
+func (i *Invoice) PDFFilename() string {
+	return i.InvoiceNumber + ".pdf"
+}
+
 func (i *Invoice) BeforeCreate(tx *gorm.DB) (err error) {
 	if i.ID == uuid.Nil {
 		i.ID = uuid.New()
@@ -62,6 +117,34 @@ func (i *Invoice) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// InDunning reports whether the invoice is still being collected after a failure.
+func (i *Invoice) InDunning() bool {
+	return i.DunningStatus == DunningStatusRetrying || i.DunningStatus == DunningStatusExhausted
+}
+
+// IsPaid reports whether the invoice was paid, including when it was refunded since.
+func (i *Invoice) IsPaid() bool {
+	return i.Status == InvoiceStatusPaid || i.Status == InvoiceStatusPartiallyRefunded || i.Status == InvoiceStatusRefunded
+}
+
+// RefundableAmount is what is left to refund of a paid invoice.
+func (i *Invoice) RefundableAmount() int64 {
+	if !i.IsPaid() {
+		return 0
+	}
+	return max(0, i.TotalAmount-i.RefundedAmount)
+}
+
+// MarkPaid settles the invoice, ending its dunning if it was in one.
+func (i *Invoice) MarkPaid(at time.Time) {
+	i.Status = InvoiceStatusPaid
+	i.PaidAt = &at
+	if i.InDunning() {
+		i.DunningStatus = DunningStatusRecovered
+		i.NextRetryAt = nil
+	}
+}
+
 func (ili *InvoiceLineItem) BeforeCreate(tx *gorm.DB) (err error) {
 	if ili.ID == uuid.Nil {
 		ili.ID = uuid.New()
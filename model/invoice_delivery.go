@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InvoiceEmailKind is the billing email an invoice was sent with.
+type InvoiceEmailKind string
+
+const (
+	InvoiceEmailKindIssued  InvoiceEmailKind = "invoice_issued"
+	InvoiceEmailKindReceipt InvoiceEmailKind = "payment_receipt"
+	InvoiceEmailKindFailed  InvoiceEmailKind = "payment_failed"
+)
+
+type InvoiceDeliveryStatus string
+
+const (
+	InvoiceDeliveryStatusSent   InvoiceDeliveryStatus = "sent"
+	InvoiceDeliveryStatusFailed InvoiceDeliveryStatus = "failed"
+)
+
+// InvoiceDelivery is one attempt at emailing an invoice to one recipient. Sent
+// means the mail server accepted the email.
+type InvoiceDelivery struct {
+	ID             uuid.UUID             `gorm:"type:uuid;primaryKey" json:"id"`
+	InvoiceID      uuid.UUID             `gorm:"type:uuid;not null;index" json:"invoice_id"`
+	OrganizationID uuid.UUID             `gorm:"type:uuid;not null" json:"organization_id"`
+	Kind           InvoiceEmailKind      `gorm:"type:varchar(30);not null" json:"kind"`
+	Recipient      string                `gorm:"type:varchar(255);not null" json:"recipient"`
+	Subject        string                `gorm:"type:varchar(255);not null" json:"subject"`
+	Status         InvoiceDeliveryStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Error          *string               `gorm:"type:text" json:"error"`
+	AttemptedAt    time.Time             `gorm:"not null" json:"attempted_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+
+	// Relationships
+	Invoice Invoice `gorm:"foreignKey:InvoiceID;references:ID" json:"-"`
+}
+
+func (d *InvoiceDelivery) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return
+}
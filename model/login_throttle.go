@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type LoginThrottleScope string
+
+const (
+	LoginThrottleScopeAccount LoginThrottleScope = "account"
+	LoginThrottleScopeIP      LoginThrottleScope = "ip"
+)
+
+// LoginThrottle tracks failed sign-in attempts for a single account (keyed by
+// normalized email) or a single client IP.
+type LoginThrottle struct {
+	ID            uuid.UUID          `gorm:"type:uuid;primaryKey" json:"id"`
+	Scope         LoginThrottleScope `gorm:"type:varchar(20);not null;uniqueIndex:idx_login_throttle_scope_key" json:"scope"`
+	Key           string             `gorm:"type:varchar(255);not null;uniqueIndex:idx_login_throttle_scope_key" json:"key"`
+	FailedCount   int                `gorm:"default:0" json:"failed_count"`
+	FirstFailedAt *time.Time         `json:"first_failed_at"`
+	LastFailedAt  *time.Time         `json:"last_failed_at"`
+	LockedUntil   *time.Time         `json:"locked_until"`
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+func (lt *LoginThrottle) BeforeCreate(tx *gorm.DB) (err error) {
+	if lt.ID == uuid.Nil {
+		lt.ID = uuid.New()
+	}
+	return
+}
+
+// IsLocked reports whether the throttle is inside a lockout period.
+func (lt *LoginThrottle) IsLocked(now time.Time) bool {
+	return lt.LockedUntil != nil && now.Before(*lt.LockedUntil)
+}
+
+// IsWindowExpired reports whether the failure window has elapsed, after which
+// the failure counter starts again from zero.
+func (lt *LoginThrottle) IsWindowExpired(now time.Time, window time.Duration) bool {
+	return lt.FirstFailedAt == nil || now.Sub(*lt.FirstFailedAt) > window
+}
+
+// NextAttemptAt returns the earliest time another attempt is accepted. The
+// delay doubles with every failure inside the window, starting at baseDelay
+// and capped at maxDelay.
+func (lt *LoginThrottle) NextAttemptAt(baseDelay, maxDelay time.Duration) time.Time {
+	if lt.LockedUntil != nil {
+		return *lt.LockedUntil
+	}
+	if lt.LastFailedAt == nil || lt.FailedCount < 2 || baseDelay <= 0 {
+		return time.Time{}
+	}
+
+	delay := baseDelay
+	for i := 1; i < lt.FailedCount-1 && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return lt.LastFailedAt.Add(delay)
+}
@@ -0,0 +1,40 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type NotificationType string
+
+const (
+	NotificationTypeUsageAlert NotificationType = "usage_alert"
+)
+
+// Notification is an in-app message for a user, listed under /users/notifications.
+type Notification struct {
+	ID             uuid.UUID        `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID         uuid.UUID        `gorm:"type:uuid;not null;index" json:"user_id"`
+	OrganizationID *uuid.UUID       `gorm:"type:uuid" json:"organization_id"`
+	Type           NotificationType `gorm:"type:varchar(50);not null" json:"type"`
+	Title          string           `gorm:"type:varchar(200);not null" json:"title"`
+	Message        string           `gorm:"type:text;not null" json:"message"`
+	ReadAt         *time.Time       `json:"read_at"`
+	CreatedAt      time.Time        `json:"created_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) (err error) {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return
+}
+
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
@@ -57,6 +57,17 @@ type Organization struct {
 	NextBillingDate      *time.Time                     `json:"next_billing_date"`
 	PayPalSubscriptionID *string                        `gorm:"type:varchar(100)" json:"paypal_subscription_id"`
 	BillingEmail         *string                        `gorm:"type:varchar(255)" json:"billing_email"`
+	Currency             string                         `gorm:"type:varchar(3);default:USD" json:"currency"` // chosen when subscribing
+
+	// Billing address and tax ID, printed on invoices and used to tax them
+	BillingName         *string `gorm:"type:varchar(200)" json:"billing_name"`
+	BillingAddressLine1 *string `gorm:"type:varchar(255)" json:"billing_address_line1"`
+	BillingAddressLine2 *string `gorm:"type:varchar(255)" json:"billing_address_line2"`
+	BillingCity         *string `gorm:"type:varchar(100)" json:"billing_city"`
+	BillingPostalCode   *string `gorm:"type:varchar(20)" json:"billing_postal_code"`
+	BillingState        *string `gorm:"type:varchar(100)" json:"billing_state"`
+	BillingCountry      *string `gorm:"type:varchar(2)" json:"billing_country"` // ISO 3166-1 alpha-2
+	TaxID               *string `gorm:"type:varchar(50)" json:"tax_id"`
 
 	// Relationships
 	Creator User  `gorm:"foreignKey:CreatedBy;references:ID" json:"-"`
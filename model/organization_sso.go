@@ -0,0 +1,140 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OrganizationSSOConfig registers an OIDC identity provider for an organization.
+// Users whose email domain is in AllowedDomains sign in through the provider and
+// are provisioned into the organization with DefaultRole on their first login.
+type OrganizationSSOConfig struct {
+	ID             uuid.UUID              `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationID uuid.UUID              `gorm:"type:uuid;not null;uniqueIndex" json:"organization_id"`
+	Issuer         string                 `gorm:"type:varchar(500);not null" json:"issuer"`
+	ClientID       string                 `gorm:"type:varchar(255);not null" json:"client_id"`
+	ClientSecret   string                 `gorm:"type:varchar(500)" json:"-"`
+	AllowedDomains string                 `gorm:"type:varchar(1000);not null" json:"allowed_domains"` // comma separated, lower case
+	DefaultRole    OrganizationMemberRole `gorm:"type:varchar(20);default:member" json:"default_role"`
+	EnforceSSO     bool                   `gorm:"default:false" json:"enforce_sso"` // disables password sign-in for members
+	IsEnabled      bool                   `gorm:"default:true" json:"is_enabled"`
+	CreatedBy      uuid.UUID              `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt      time.Time              `json:"created_at"`
+	UpdatedAt      time.Time              `json:"updated_at"`
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
+}
+
+func (c *OrganizationSSOConfig) BeforeCreate(tx *gorm.DB) (err error) {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return
+}
+
+// DomainList returns the allowed email domains.
+func (c *OrganizationSSOConfig) DomainList() []string {
+	domains := []string{}
+	for _, domain := range strings.Split(c.AllowedDomains, ",") {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// AllowsEmail reports whether the email belongs to one of the allowed domains.
+// It does not check that the domain is verified.
+func (c *OrganizationSSOConfig) AllowsEmail(email string) bool {
+	emailDomain := EmailDomain(email)
+	if emailDomain == "" {
+		return false
+	}
+	for _, domain := range c.DomainList() {
+		if domain == emailDomain {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailDomain returns the lower-cased domain of an email address, or "" when it
+// has none.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(email[at+1:]))
+}
+
+// SSODomainVerificationPrefix is the DNS label an organization publishes its
+// domain verification token under.
+const SSODomainVerificationPrefix = "_testlake-verification"
+
+// OrganizationSSODomain is an email domain an organization claims for single
+// sign-on. Logins are only routed to the organization once it proved it controls
+// the domain by publishing VerificationToken in a DNS TXT record; until then the
+// claim does not stop another organization from verifying the domain.
+type OrganizationSSODomain struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_organization_sso_domains_domain" json:"organization_id"`
+	Domain            string     `gorm:"type:varchar(255);not null;uniqueIndex:idx_organization_sso_domains_domain" json:"domain"`
+	VerificationToken string     `gorm:"type:varchar(100);not null" json:"-"`
+	VerifiedAt        *time.Time `json:"verified_at"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+func (d *OrganizationSSODomain) BeforeCreate(tx *gorm.DB) (err error) {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.VerificationToken == "" {
+		d.VerificationToken = strings.ReplaceAll(uuid.NewString(), "-", "")
+	}
+	return
+}
+
+func (d *OrganizationSSODomain) IsVerified() bool {
+	return d.VerifiedAt != nil
+}
+
+// TXTRecordName is the DNS name the verification record is published under.
+func (d *OrganizationSSODomain) TXTRecordName() string {
+	return SSODomainVerificationPrefix + "." + d.Domain
+}
+
+// TXTRecordValue is the content the verification record must have.
+func (d *OrganizationSSODomain) TXTRecordValue() string {
+	return "testlake-verification=" + d.VerificationToken
+}
+
+// SSOLoginState binds an authorization request to its callback. It carries the
+// nonce expected in the ID token and the PKCE verifier for the code exchange.
+type SSOLoginState struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	State          string     `gorm:"type:varchar(100);uniqueIndex;not null" json:"-"`
+	Nonce          string     `gorm:"type:varchar(100);not null" json:"-"`
+	CodeVerifier   string     `gorm:"type:varchar(128);not null" json:"-"`
+	OrganizationID uuid.UUID  `gorm:"type:uuid;not null;index" json:"organization_id"`
+	ExpiresAt      time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt         *time.Time `json:"used_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (s *SSOLoginState) BeforeCreate(tx *gorm.DB) (err error) {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return
+}
+
+func (s *SSOLoginState) IsValid(now time.Time) bool {
+	return s.UsedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -9,20 +9,17 @@ import (
 
 type OrganizationUsage struct {
 	ID                uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
-	OrganizationID    uuid.UUID `gorm:"type:uuid;not null" json:"organization_id"`
-	PeriodStart       time.Time `gorm:"not null" json:"period_start"`
-	PeriodEnd         time.Time `gorm:"not null" json:"period_end"`
+	OrganizationID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_organization_usage_period" json:"organization_id"`
+	PeriodStart       time.Time `gorm:"not null;uniqueIndex:idx_organization_usage_period" json:"period_start"`
+	PeriodEnd         time.Time `gorm:"not null;uniqueIndex:idx_organization_usage_period" json:"period_end"`
 	UsersCount        int       `gorm:"default:0" json:"users_count"`
 	ProjectsCount     int       `gorm:"default:0" json:"projects_count"`
 	EnvironmentsCount int       `gorm:"default:0" json:"environments_count"`
 	SchemasCount      int       `gorm:"default:0" json:"schemas_count"`
-	TestRecordsCount  int       `gorm:"default:0" json:"test_records_count"`
+	TestRecordsCount  int       `gorm:"default:0" json:"test_records_count"` // records of the largest schema
 	APIRequestsCount  int       `gorm:"default:0" json:"api_requests_count"`
 	RecordedAt        time.Time `gorm:"default:CURRENT_TIMESTAMP" json:"recorded_at"`
 
-	// Unique constraint on organization and period
-	_ struct{} `gorm:"uniqueIndex:idx_organization_usage_period,column:organization_id,period_start,period_end"`
-
 	// Relationships
 	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
 }
@@ -33,3 +30,96 @@ func (ou *OrganizationUsage) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// UsageAlert records that an organization was alerted about its usage of a
+// resource reaching a threshold of its plan limit, once per billing period.
+type UsageAlert struct {
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_usage_alerts_threshold" json:"organization_id"`
+	Resource       string    `gorm:"type:varchar(50);not null;uniqueIndex:idx_usage_alerts_threshold" json:"resource"`
+	Threshold      int       `gorm:"not null;uniqueIndex:idx_usage_alerts_threshold" json:"threshold"`
+	PeriodStart    time.Time `gorm:"not null;uniqueIndex:idx_usage_alerts_threshold" json:"period_start"`
+	Used           int       `gorm:"not null" json:"used"`
+	Limit          int       `gorm:"not null" json:"limit"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	// Relationships
+	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
+}
+
+func (ua *UsageAlert) BeforeCreate(tx *gorm.DB) (err error) {
+	if ua.ID == uuid.Nil {
+		ua.ID = uuid.New()
+	}
+	return
+}
+
+// UsagePeriod returns the calendar month of t that usage is recorded for.
+func UsagePeriod(t time.Time) (start, end time.Time) {
+	start = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	return start, start.AddDate(0, 1, 0).Add(-time.Nanosecond)
+}
+
+// Resources limited by plans, in the order they are reported.
+const (
+	LimitResourceUsers                = "users"
+	LimitResourceProjects             = "projects"
+	LimitResourceEnvironments         = "environments"
+	LimitResourceSchemas              = "schemas"
+	LimitResourceTestRecordsPerSchema = "test_records_per_schema"
+)
+
+var LimitResources = []string{
+	LimitResourceUsers,
+	LimitResourceProjects,
+	LimitResourceEnvironments,
+	LimitResourceSchemas,
+	LimitResourceTestRecordsPerSchema,
+}
+
+// UsageCounts is the live resource usage of an organization, counted from the
+// resources themselves rather than the monthly usage records.
+type UsageCounts struct {
+	Users                   int `json:"users"`
+	Projects                int `json:"projects"`
+	Environments            int `json:"environments"`
+	Schemas                 int `json:"schemas"`
+	MaxTestRecordsPerSchema int `json:"max_test_records_per_schema"`
+}
+
+// LimitExcess is a resource whose usage is above a plan limit.
+type LimitExcess struct {
+	Resource string `json:"resource"`
+	Used     int    `json:"used"`
+	Limit    int    `json:"limit"`
+}
+
+// Used returns the usage of a resource limited by plans.
+func (u UsageCounts) Used(resource string) int {
+	switch resource {
+	case LimitResourceUsers:
+		return u.Users
+	case LimitResourceProjects:
+		return u.Projects
+	case LimitResourceEnvironments:
+		return u.Environments
+	case LimitResourceSchemas:
+		return u.Schemas
+	case LimitResourceTestRecordsPerSchema:
+		return u.MaxTestRecordsPerSchema
+	}
+	return 0
+}
+
+// ExceededLimits lists the resources whose usage is above the plan's limits.
+// A negative limit means unlimited.
+func (u UsageCounts) ExceededLimits(plan *Plan) []LimitExcess {
+	var exceeded []LimitExcess
+	for _, resource := range LimitResources {
+		check := LimitExcess{Resource: resource, Used: u.Used(resource), Limit: plan.Limit(resource)}
+		if check.Limit >= 0 && check.Used > check.Limit {
+			exceeded = append(exceeded, check)
+		}
+	}
+	return exceeded
+}
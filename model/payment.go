@@ -21,6 +21,8 @@ const (
 	PaymentStatusFailed    PaymentStatus = "failed"
 	PaymentStatusCancelled PaymentStatus = "cancelled"
 	PaymentStatusRefunded  PaymentStatus = "refunded"
+	// PaymentStatusPartiallyRefunded is a completed payment of which part was refunded
+	PaymentStatusPartiallyRefunded PaymentStatus = "partially_refunded"
 )
 
 type Payment struct {
@@ -30,11 +32,12 @@ type Payment struct {
 	SubscriptionID  *uuid.UUID        `gorm:"type:uuid" json:"subscription_id"`
 	PayPalPaymentID *string           `gorm:"type:varchar(100);uniqueIndex" json:"paypal_payment_id"`
 	PayPalPayerID   *string           `gorm:"type:varchar(100)" json:"paypal_payer_id"`
-	Amount          float64           `gorm:"type:decimal(10,2);not null" json:"amount"`
+	Amount          int64             `gorm:"not null" json:"amount"` // minor units of Currency
 	Currency        string            `gorm:"type:varchar(3);default:USD" json:"currency"`
 	PaymentMethod   PaymentMethodEnum `gorm:"type:varchar(20);default:paypal" json:"payment_method"`
 	Status          PaymentStatus     `gorm:"type:varchar(20);default:pending" json:"status"`
 	FailureReason   *string           `gorm:"type:text" json:"failure_reason"`
+	RefundedAmount  int64             `gorm:"default:0" json:"refunded_amount"`
 	ProcessedAt     *time.Time        `json:"processed_at"`
 	CreatedAt       time.Time         `json:"created_at"`
 	UpdatedAt       time.Time         `json:"updated_at"`
@@ -45,6 +48,14 @@ type Payment struct {
 	Subscription *Subscription `gorm:"foreignKey:SubscriptionID;references:ID" json:"-"`
 }
 
+// RefundableAmount is what is left to refund of a completed payment.
+func (p *Payment) RefundableAmount() int64 {
+	if p.Status != PaymentStatusCompleted && p.Status != PaymentStatusPartiallyRefunded {
+		return 0
+	}
+	return max(0, p.Amount-p.RefundedAmount)
+}
+
 func (p *Payment) BeforeCreate(tx *gorm.DB) (err error) {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
@@ -13,11 +13,23 @@ const (
 	PaymentMethodTypePayPal PaymentMethodType = "paypal"
 )
 
+// PaymentMethodStatus follows the vault token behind a payment method: pending
+// until the payer approved saving it at the provider, verified once saved, and
+// invalid once the provider reported it failing.
+type PaymentMethodStatus string
+
+const (
+	PaymentMethodStatusPending  PaymentMethodStatus = "pending"
+	PaymentMethodStatusVerified PaymentMethodStatus = "verified"
+	PaymentMethodStatusInvalid  PaymentMethodStatus = "invalid"
+)
+
 type PaymentMethod struct {
 	ID                uuid.UUID         `gorm:"type:uuid;primaryKey" json:"id"`
 	OrganizationID    uuid.UUID         `gorm:"type:uuid;not null" json:"organization_id"`
 	PayPalPayerID     *string           `gorm:"type:varchar(100)" json:"paypal_payer_id"`
 	PayPalEmail       *string           `gorm:"type:varchar(255)" json:"paypal_email"`
+	PayPalVaultID     *string           `gorm:"type:varchar(100)" json:"-"` // lets the billing run charge the account without the payer
 	PaymentMethodType PaymentMethodType `gorm:"type:varchar(20);default:paypal" json:"payment_method_type"`
 	IsDefault         bool              `gorm:"default:false" json:"is_default"`
 	IsActive          bool              `gorm:"default:true" json:"is_active"`
@@ -25,6 +37,11 @@ type PaymentMethod struct {
 	CreatedAt         time.Time         `json:"created_at"`
 	UpdatedAt         time.Time         `json:"updated_at"`
 
+	Status             PaymentMethodStatus `gorm:"type:varchar(20);default:verified" json:"status"`
+	PayPalSetupTokenID *string             `gorm:"type:varchar(100)" json:"-"` // approved by the payer to create the vault token
+	FailureReason      *string             `gorm:"type:text" json:"failure_reason"`
+	InvalidatedAt      *time.Time          `json:"invalidated_at"`
+
 	// Relationships
 	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
 	Creator      User         `gorm:"foreignKey:CreatedBy;references:ID" json:"-"`
@@ -36,3 +53,14 @@ func (pm *PaymentMethod) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// IsChargeable reports whether the billing run can charge the method without the payer.
+func (pm *PaymentMethod) IsChargeable() bool {
+	return pm.IsActive && pm.IsVerified() && pm.PayPalVaultID != nil && *pm.PayPalVaultID != ""
+}
+
+// IsVerified reports whether the method was saved at the provider and did not
+// fail since.
+func (pm *PaymentMethod) IsVerified() bool {
+	return pm.Status == PaymentMethodStatusVerified
+}
@@ -1,6 +1,9 @@
 package model
 
 import (
+	"encoding/json"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,21 +13,52 @@ import (
 type Plan struct {
 	ID                      uuid.UUID `gorm:"type:uuid;primaryKey" json:"id"`
 	Name                    string    `gorm:"type:varchar(100);not null" json:"name"`
-	Slug                    string    `gorm:"type:varchar(50);uniqueIndex;not null" json:"slug"`
+	Slug                    string    `gorm:"type:varchar(50);uniqueIndex:idx_plans_slug_version;not null" json:"slug"`
 	Description             *string   `gorm:"type:text" json:"description"`
-	PriceMonthly            float64   `gorm:"type:decimal(10,2);not null" json:"price_monthly"`
-	PriceYearly             float64   `gorm:"type:decimal(10,2);not null" json:"price_yearly"`
+	PriceMonthly            int64     `gorm:"not null" json:"price_monthly"` // USD cents
+	PriceYearly             int64     `gorm:"not null" json:"price_yearly"`  // USD cents
 	MaxUsers                int       `gorm:"not null" json:"max_users"`
 	MaxProjects             int       `gorm:"not null" json:"max_projects"`
 	MaxEnvironments         int       `gorm:"not null" json:"max_environments"`
 	MaxSchemas              int       `gorm:"not null" json:"max_schemas"`
 	MaxTestRecordsPerSchema int       `gorm:"not null" json:"max_test_records_per_schema"`
 	Features                string    `gorm:"type:jsonb;not null" json:"features"` // JSON array of enabled features
+	UsageAlertThresholds    string    `gorm:"type:jsonb;not null;default:'[80,90,95]'" json:"usage_alert_thresholds"`
 	PayPalMonthlyPlanID     *string   `gorm:"type:varchar(100)" json:"paypal_monthly_plan_id"`
 	PayPalYearlyPlanID      *string   `gorm:"type:varchar(100)" json:"paypal_yearly_plan_id"`
 	IsActive                bool      `gorm:"default:true" json:"is_active"`
 	CreatedAt               time.Time `json:"created_at"`
 	UpdatedAt               time.Time `json:"updated_at"`
+
+	// Prices never change: a new version of the plan, with the same slug, replaces
+	// it for new subscribers while existing ones keep the retired version
+	Version           int        `gorm:"not null;default:1;uniqueIndex:idx_plans_slug_version" json:"version"`
+	PreviousVersionID *uuid.UUID `gorm:"type:uuid" json:"previous_version_id"`
+	RetiredAt         *time.Time `json:"retired_at"`
+	PayPalProductID   *string    `gorm:"type:varchar(100)" json:"paypal_product_id"`
+
+	// Relationships
+	Prices []PlanPrice `gorm:"foreignKey:PlanID;references:ID" json:"prices,omitempty"`
+}
+
+// PlanPrice is the price of a plan in a currency other than USD, the currency
+// of the plan's own prices. Each price point has its own gateway plan.
+type PlanPrice struct {
+	ID           uuid.UUID    `gorm:"type:uuid;primaryKey" json:"id"`
+	PlanID       uuid.UUID    `gorm:"type:uuid;not null;uniqueIndex:idx_plan_prices_point" json:"plan_id"`
+	Currency     string       `gorm:"type:varchar(3);not null;uniqueIndex:idx_plan_prices_point" json:"currency"`
+	BillingCycle BillingCycle `gorm:"type:varchar(20);not null;uniqueIndex:idx_plan_prices_point" json:"billing_cycle"`
+	Amount       int64        `gorm:"not null" json:"amount"` // minor units of Currency
+	PayPalPlanID *string      `gorm:"type:varchar(100)" json:"paypal_plan_id"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+func (p *PlanPrice) BeforeCreate(tx *gorm.DB) (err error) {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return
 }
 
 func (p *Plan) BeforeCreate(tx *gorm.DB) (err error) {
@@ -33,3 +67,123 @@ func (p *Plan) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// IsRetired reports whether the plan was retired or replaced by a newer version.
+// Retired plans cannot be subscribed to; their subscribers keep their price.
+func (p *Plan) IsRetired() bool {
+	return p.RetiredAt != nil
+}
+
+// HasFeature reports whether the plan's feature list enables the given feature.
+func (p *Plan) HasFeature(feature string) bool {
+	var features []string
+	if err := json.Unmarshal([]byte(p.Features), &features); err != nil {
+		return false
+	}
+	for _, f := range features {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultUsageAlertThresholds are the percentages of a limit that usage alerts
+// are sent at, unless the plan sets its own.
+var DefaultUsageAlertThresholds = []int{80, 90, 95}
+
+// AlertThresholds returns the percentages of a limit that usage alerts are sent
+// at, in increasing order, from the plan's UsageAlertThresholds JSON array. An
+// empty array turns alerts off for the plan.
+func (p *Plan) AlertThresholds() []int {
+	if p.UsageAlertThresholds == "" {
+		return DefaultUsageAlertThresholds
+	}
+	var thresholds []int
+	if err := json.Unmarshal([]byte(p.UsageAlertThresholds), &thresholds); err != nil {
+		return DefaultUsageAlertThresholds
+	}
+	valid := thresholds[:0]
+	for _, threshold := range thresholds {
+		if threshold > 0 && threshold <= 100 {
+			valid = append(valid, threshold)
+		}
+	}
+	slices.Sort(valid)
+	return slices.Compact(valid)
+}
+
+// Limit returns the plan's limit on a resource, negative when unlimited.
+func (p *Plan) Limit(resource string) int {
+	switch resource {
+	case LimitResourceUsers:
+		return p.MaxUsers
+	case LimitResourceProjects:
+		return p.MaxProjects
+	case LimitResourceEnvironments:
+		return p.MaxEnvironments
+	case LimitResourceSchemas:
+		return p.MaxSchemas
+	case LimitResourceTestRecordsPerSchema:
+		return p.MaxTestRecordsPerSchema
+	}
+	return -1
+}
+
+// PriceFor returns the USD plan price for a billing cycle, in cents.
+func (p *Plan) PriceFor(cycle BillingCycle) int64 {
+	if cycle == BillingCycleYearly {
+		return p.PriceYearly
+	}
+	return p.PriceMonthly
+}
+
+// PriceIn returns the plan price for a billing cycle in minor units of a
+// currency, and false when the plan has no price in that currency. Free plans
+// are free in every currency. The Prices relation must be loaded.
+func (p *Plan) PriceIn(currency string, cycle BillingCycle) (int64, bool) {
+	if currency == "" || strings.EqualFold(currency, DefaultCurrency) {
+		return p.PriceFor(cycle), true
+	}
+	if price := p.priceIn(currency, cycle); price != nil {
+		return price.Amount, true
+	}
+	if p.PriceFor(cycle) == 0 {
+		return 0, true
+	}
+	return 0, false
+}
+
+func (p *Plan) priceIn(currency string, cycle BillingCycle) *PlanPrice {
+	for i := range p.Prices {
+		if strings.EqualFold(p.Prices[i].Currency, currency) && p.Prices[i].BillingCycle == cycle {
+			return &p.Prices[i]
+		}
+	}
+	return nil
+}
+
+// PayPalPlanIDFor returns the USD gateway plan for a billing cycle, nil until the plan is synced.
+func (p *Plan) PayPalPlanIDFor(cycle BillingCycle) *string {
+	planID := p.PayPalMonthlyPlanID
+	if cycle == BillingCycleYearly {
+		planID = p.PayPalYearlyPlanID
+	}
+	if planID == nil || *planID == "" {
+		return nil
+	}
+	return planID
+}
+
+// PayPalPlanIDIn returns the gateway plan for a billing cycle in a currency,
+// nil until the price point is synced. The Prices relation must be loaded.
+func (p *Plan) PayPalPlanIDIn(currency string, cycle BillingCycle) *string {
+	if currency == "" || strings.EqualFold(currency, DefaultCurrency) {
+		return p.PayPalPlanIDFor(cycle)
+	}
+	price := p.priceIn(currency, cycle)
+	if price == nil || price.PayPalPlanID == nil || *price.PayPalPlanID == "" {
+		return nil
+	}
+	return price.PayPalPlanID
+}
@@ -28,17 +28,22 @@ type Subscription struct {
 	ID                   uuid.UUID          `gorm:"type:uuid;primaryKey" json:"id"`
 	OrganizationID       uuid.UUID          `gorm:"type:uuid;not null" json:"organization_id"`
 	PlanID               uuid.UUID          `gorm:"type:uuid;not null" json:"plan_id"`
-	PayPalSubscriptionID string             `gorm:"type:varchar(100);uniqueIndex;not null" json:"paypal_subscription_id"`
+	PayPalSubscriptionID *string            `gorm:"type:varchar(100);uniqueIndex" json:"paypal_subscription_id"` // nil for plans billed without the gateway
 	Status               SubscriptionStatus `gorm:"type:varchar(20);default:pending" json:"status"`
 	BillingCycle         BillingCycle       `gorm:"type:varchar(20);not null" json:"billing_cycle"`
+	Currency             string             `gorm:"type:varchar(3);not null;default:USD" json:"currency"` // of the plan price the subscription is billed
 	CurrentPeriodStart   time.Time          `gorm:"not null" json:"current_period_start"`
 	CurrentPeriodEnd     time.Time          `gorm:"not null" json:"current_period_end"`
 	TrialEnd             *time.Time         `json:"trial_end"`
+	TrialReminderSentAt  *time.Time         `json:"-"`
 	CancelAtPeriodEnd    bool               `gorm:"default:false" json:"cancel_at_period_end"`
 	CancelledAt          *time.Time         `json:"cancelled_at"`
-	CreatedBy            uuid.UUID          `gorm:"type:uuid;not null" json:"created_by"`
-	CreatedAt            time.Time          `json:"created_at"`
-	UpdatedAt            time.Time          `json:"updated_at"`
+	// Downgrade waiting for the end of the current period
+	ScheduledPlanID       *uuid.UUID    `gorm:"type:uuid" json:"scheduled_plan_id"`
+	ScheduledBillingCycle *BillingCycle `gorm:"type:varchar(20)" json:"scheduled_billing_cycle"`
+	CreatedBy             uuid.UUID     `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt             time.Time     `json:"created_at"`
+	UpdatedAt             time.Time     `json:"updated_at"`
 
 	// Relationships
 	Organization Organization `gorm:"foreignKey:OrganizationID;references:ID" json:"-"`
@@ -52,3 +57,38 @@ func (s *Subscription) BeforeCreate(tx *gorm.DB) (err error) {
 	}
 	return
 }
+
+// IsGatewayBilled reports whether the payment provider bills this subscription.
+func (s *Subscription) IsGatewayBilled() bool {
+	return s.PayPalSubscriptionID != nil && *s.PayPalSubscriptionID != ""
+}
+
+// Price returns what a billing period of the subscription costs on a plan and
+// cycle, in minor units of its currency, and false when the plan is not priced
+// in it.
+func (s *Subscription) Price(plan *Plan, cycle BillingCycle) (int64, bool) {
+	return plan.PriceIn(s.Currency, cycle)
+}
+
+// HasScheduledChange reports whether a plan change waits for the end of the period.
+func (s *Subscription) HasScheduledChange() bool {
+	return s.ScheduledPlanID != nil
+}
+
+// PeriodEndAfter returns the end of a billing period of the given cycle starting at start.
+func PeriodEndAfter(start time.Time, cycle BillingCycle) time.Time {
+	if cycle == BillingCycleYearly {
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 1, 0)
+}
+
+// IsTrialing reports whether the subscription is in a trial that has not ended at now.
+func (s *Subscription) IsTrialing(now time.Time) bool {
+	return s.TrialEnd != nil && s.TrialEnd.After(now)
+}
+
+// InTrialPeriod reports whether the current period is the trial, ended or not.
+func (s *Subscription) InTrialPeriod() bool {
+	return s.TrialEnd != nil && s.CurrentPeriodEnd.Equal(*s.TrialEnd)
+}
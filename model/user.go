@@ -13,6 +13,7 @@ const (
 	AuthProviderEmail AuthProvider = "email"
 	AuthProviderGmail AuthProvider = "gmail"
 	AuthProviderApple AuthProvider = "apple"
+	AuthProviderOIDC  AuthProvider = "oidc"
 )
 
 type UserStatus string
@@ -38,6 +39,8 @@ type User struct {
 	UpdatedAt         time.Time     `json:"updated_at"`
 	LastLoginAt       *time.Time    `json:"last_login_at"`
 	Status            UserStatus    `gorm:"type:varchar(20);default:active" json:"status"`
+	// Platform admins are staff with access to the cross-organization admin endpoints
+	IsPlatformAdmin   bool          `gorm:"default:false" json:"-"`
 	DeletedAt         gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type AuthMethod string
+
+const (
+	AuthMethodPassword AuthMethod = "password"
+	AuthMethodSignUp   AuthMethod = "signup"
+	AuthMethodSSO      AuthMethod = "sso"
+)
+
+type LoginOutcome string
+
+const (
+	LoginOutcomeSuccess            LoginOutcome = "success"
+	LoginOutcomeInvalidCredentials LoginOutcome = "invalid_credentials"
+	LoginOutcomeThrottled          LoginOutcome = "throttled"
+	LoginOutcomeLocked             LoginOutcome = "locked"
+	LoginOutcomeInactiveAccount    LoginOutcome = "inactive_account"
+	LoginOutcomeSSORequired        LoginOutcome = "sso_required"
+	LoginOutcomeSSOFailed          LoginOutcome = "sso_failed"
+)
+
+// UserSession is created for every successful sign-in. Its ID is embedded in
+// the JWT (sid claim) so that a session can be revoked before the token expires.
+type UserSession struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	IPAddress  string     `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent  string     `gorm:"type:varchar(500)" json:"user_agent"`
+	AuthMethod AuthMethod `gorm:"type:varchar(20);not null" json:"auth_method"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;references:ID" json:"-"`
+}
+
+func (us *UserSession) BeforeCreate(tx *gorm.DB) (err error) {
+	if us.ID == uuid.Nil {
+		us.ID = uuid.New()
+	}
+	return
+}
+
+func (us *UserSession) IsActive(now time.Time) bool {
+	return us.RevokedAt == nil && now.Before(us.ExpiresAt)
+}
+
+// LoginEvent records a single sign-in attempt, successful or not.
+type LoginEvent struct {
+	ID         uuid.UUID    `gorm:"type:uuid;primaryKey" json:"id"`
+	UserID     *uuid.UUID   `gorm:"type:uuid;index" json:"user_id"`
+	Email      string       `gorm:"type:varchar(255);index" json:"email"`
+	IPAddress  string       `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent  string       `gorm:"type:varchar(500)" json:"user_agent"`
+	AuthMethod AuthMethod   `gorm:"type:varchar(20);not null" json:"auth_method"`
+	Outcome    LoginOutcome `gorm:"type:varchar(30);not null" json:"outcome"`
+	SessionID  *uuid.UUID   `gorm:"type:uuid" json:"session_id"`
+	CreatedAt  time.Time    `gorm:"index" json:"created_at"`
+}
+
+func (le *LoginEvent) BeforeCreate(tx *gorm.DB) (err error) {
+	if le.ID == uuid.Nil {
+		le.ID = uuid.New()
+	}
+	return
+}
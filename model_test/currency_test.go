@@ -0,0 +1,56 @@
+package model_test
+
+import (
+	"testing"
+	"testlake/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinorUnitsFollowCurrencyExponent(t *testing.T) {
+	assert.Equal(t, int64(2999), model.ToMinorUnits(29.99, "USD"))
+	assert.Equal(t, int64(1010), model.ToMinorUnits(10.1, "eur"), "0.1 is not exact in binary")
+	assert.Equal(t, int64(3500), model.ToMinorUnits(3500, "JPY"))
+	assert.Equal(t, 29.99, model.FromMinorUnits(2999, "USD"))
+	assert.Equal(t, "29.99", model.FormatMinorUnits(2999, "USD"))
+	assert.Equal(t, "-7.25", model.FormatMinorUnits(-725, "GBP"))
+	assert.Equal(t, "3500", model.FormatMinorUnits(3500, "JPY"))
+
+	amount, err := model.ParseMinorUnits(" 17.40 ", "EUR")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1740), amount)
+	_, err = model.ParseMinorUnits("free", "EUR")
+	assert.Error(t, err)
+
+	assert.True(t, model.IsSupportedCurrency("eur"))
+	assert.False(t, model.IsSupportedCurrency("XYZ"))
+}
+
+func TestPlanPriceIn(t *testing.T) {
+	paypalPlanID := "P-EUR-MONTHLY"
+	plan := &model.Plan{
+		PriceMonthly: 2900,
+		PriceYearly:  29000,
+		Prices: []model.PlanPrice{
+			{Currency: "EUR", BillingCycle: model.BillingCycleMonthly, Amount: 2700, PayPalPlanID: &paypalPlanID},
+		},
+	}
+
+	price, found := plan.PriceIn("", model.BillingCycleMonthly)
+	assert.True(t, found)
+	assert.Equal(t, int64(2900), price)
+	price, found = plan.PriceIn("EUR", model.BillingCycleMonthly)
+	assert.True(t, found)
+	assert.Equal(t, int64(2700), price)
+	_, found = plan.PriceIn("EUR", model.BillingCycleYearly)
+	assert.False(t, found, "each cycle is priced on its own")
+	_, found = plan.PriceIn("GBP", model.BillingCycleMonthly)
+	assert.False(t, found)
+	assert.Equal(t, &paypalPlanID, plan.PayPalPlanIDIn("EUR", model.BillingCycleMonthly))
+
+	free := &model.Plan{}
+	price, found = free.PriceIn("GBP", model.BillingCycleYearly)
+	assert.True(t, found, "free plans are free in every currency")
+	assert.Zero(t, price)
+}
@@ -218,3 +218,39 @@ func TestEmailVerificationToken_DatabaseIntegration(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, deletedToken.DeletedAt)
 }
+
+func TestEmailVerificationToken_PurposeDefaultsToVerifyEmail(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	db.AutoMigrate(&model.EmailVerificationToken{})
+
+	verifyToken := &model.EmailVerificationToken{
+		UserID:    uuid.New(),
+		Token:     "verify-token",
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	assert.NoError(t, db.Create(verifyToken).Error)
+	assert.Equal(t, model.EmailTokenPurposeVerifyEmail, verifyToken.Purpose)
+
+	newEmail := "new@example.com"
+	changeToken := &model.EmailVerificationToken{
+		UserID:    uuid.New(),
+		Token:     "change-token",
+		Purpose:   model.EmailTokenPurposeChangeEmail,
+		NewEmail:  &newEmail,
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	assert.NoError(t, db.Create(changeToken).Error)
+
+	// A change_email token must not be found when looking for a verify_email token
+	var found model.EmailVerificationToken
+	err = db.Where("token = ? AND purpose = ?", "change-token", model.EmailTokenPurposeVerifyEmail).First(&found).Error
+	assert.Error(t, err)
+
+	err = db.Where("token = ? AND purpose = ?", "change-token", model.EmailTokenPurposeChangeEmail).First(&found).Error
+	assert.NoError(t, err)
+	assert.Equal(t, newEmail, *found.NewEmail)
+}
@@ -0,0 +1,93 @@
+package model_test
+
+import (
+	"testing"
+	"testlake/model"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLoginThrottle_BeforeCreate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	db.AutoMigrate(&model.LoginThrottle{})
+
+	throttle := &model.LoginThrottle{
+		Scope: model.LoginThrottleScopeAccount,
+		Key:   "user@example.com",
+	}
+
+	err = db.Create(throttle).Error
+	assert.NoError(t, err)
+	assert.NotEmpty(t, throttle.ID)
+
+	// The same scope and key must not be tracked twice
+	duplicate := &model.LoginThrottle{
+		Scope: model.LoginThrottleScopeAccount,
+		Key:   "user@example.com",
+	}
+	assert.Error(t, db.Create(duplicate).Error)
+}
+
+func TestLoginThrottle_IsLocked(t *testing.T) {
+	now := time.Now()
+
+	unlocked := &model.LoginThrottle{}
+	assert.False(t, unlocked.IsLocked(now))
+
+	future := now.Add(10 * time.Minute)
+	locked := &model.LoginThrottle{LockedUntil: &future}
+	assert.True(t, locked.IsLocked(now))
+
+	past := now.Add(-10 * time.Minute)
+	expired := &model.LoginThrottle{LockedUntil: &past}
+	assert.False(t, expired.IsLocked(now))
+}
+
+func TestLoginThrottle_IsWindowExpired(t *testing.T) {
+	now := time.Now()
+
+	fresh := &model.LoginThrottle{}
+	assert.True(t, fresh.IsWindowExpired(now, 15*time.Minute))
+
+	recent := now.Add(-5 * time.Minute)
+	inWindow := &model.LoginThrottle{FirstFailedAt: &recent}
+	assert.False(t, inWindow.IsWindowExpired(now, 15*time.Minute))
+
+	old := now.Add(-20 * time.Minute)
+	outOfWindow := &model.LoginThrottle{FirstFailedAt: &old}
+	assert.True(t, outOfWindow.IsWindowExpired(now, 15*time.Minute))
+}
+
+func TestLoginThrottle_NextAttemptAtBacksOffExponentially(t *testing.T) {
+	last := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	base := time.Second
+	max := 60 * time.Second
+
+	// A single failure does not delay the next attempt
+	single := &model.LoginThrottle{FailedCount: 1, LastFailedAt: &last}
+	assert.True(t, single.NextAttemptAt(base, max).IsZero())
+
+	expected := map[int]time.Duration{
+		2: 1 * time.Second,
+		3: 2 * time.Second,
+		4: 4 * time.Second,
+		5: 8 * time.Second,
+		9: 60 * time.Second, // capped
+	}
+	for failures, delay := range expected {
+		throttle := &model.LoginThrottle{FailedCount: failures, LastFailedAt: &last}
+		assert.Equal(t, last.Add(delay), throttle.NextAttemptAt(base, max), "failures=%d", failures)
+	}
+
+	// A lockout takes precedence over the back-off delay
+	lockedUntil := last.Add(30 * time.Minute)
+	locked := &model.LoginThrottle{FailedCount: 5, LastFailedAt: &last, LockedUntil: &lockedUntil}
+	assert.Equal(t, lockedUntil, locked.NextAttemptAt(base, max))
+}
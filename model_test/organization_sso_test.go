@@ -0,0 +1,94 @@
+package model_test
+
+import (
+	"testing"
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestOrganizationSSOConfig_BeforeCreate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	db.AutoMigrate(&model.OrganizationSSOConfig{}, &model.SSOLoginState{})
+	assert.True(t, db.Migrator().HasTable("organization_sso_configs"))
+
+	orgID := uuid.New()
+	config := &model.OrganizationSSOConfig{
+		OrganizationID: orgID,
+		Issuer:         "https://idp.acme.test",
+		ClientID:       "testlake",
+		AllowedDomains: "acme.test",
+		DefaultRole:    model.OrganizationMemberRoleMember,
+		CreatedBy:      uuid.New(),
+	}
+	assert.NoError(t, db.Create(config).Error)
+	assert.NotEmpty(t, config.ID)
+
+	// An organization has a single identity provider
+	duplicate := &model.OrganizationSSOConfig{OrganizationID: orgID, Issuer: "https://other.test", ClientID: "x", AllowedDomains: "acme.test", CreatedBy: uuid.New()}
+	assert.Error(t, db.Create(duplicate).Error)
+}
+
+func TestOrganizationSSOConfig_AllowsEmail(t *testing.T) {
+	config := &model.OrganizationSSOConfig{AllowedDomains: "acme.test, Acme.co.uk ,"}
+
+	assert.Equal(t, []string{"acme.test", "acme.co.uk"}, config.DomainList())
+	assert.True(t, config.AllowsEmail("jane@acme.test"))
+	assert.True(t, config.AllowsEmail("JANE@ACME.CO.UK"))
+	assert.False(t, config.AllowsEmail("jane@notacme.test"))
+	assert.False(t, config.AllowsEmail("jane@sub.acme.test"))
+	assert.False(t, config.AllowsEmail("acme.test"))
+}
+
+func TestOrganizationSSODomain_Verification(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	db.AutoMigrate(&model.OrganizationSSODomain{})
+
+	orgID := uuid.New()
+	domain := &model.OrganizationSSODomain{OrganizationID: orgID, Domain: "acme.test"}
+	assert.NoError(t, db.Create(domain).Error)
+	assert.NotEmpty(t, domain.ID)
+	assert.Len(t, domain.VerificationToken, 32)
+	assert.False(t, domain.IsVerified())
+	assert.Equal(t, "_testlake-verification.acme.test", domain.TXTRecordName())
+	assert.Equal(t, "testlake-verification="+domain.VerificationToken, domain.TXTRecordValue())
+
+	// Each organization claims a domain once; other organizations may claim it too
+	assert.Error(t, db.Create(&model.OrganizationSSODomain{OrganizationID: orgID, Domain: "acme.test"}).Error)
+	other := &model.OrganizationSSODomain{OrganizationID: uuid.New(), Domain: "acme.test"}
+	assert.NoError(t, db.Create(other).Error)
+	assert.NotEqual(t, domain.VerificationToken, other.VerificationToken)
+}
+
+func TestSSOLoginState_IsValid(t *testing.T) {
+	now := time.Now()
+
+	pending := &model.SSOLoginState{ExpiresAt: now.Add(time.Minute)}
+	assert.True(t, pending.IsValid(now))
+
+	expired := &model.SSOLoginState{ExpiresAt: now.Add(-time.Minute)}
+	assert.False(t, expired.IsValid(now))
+
+	used := &model.SSOLoginState{ExpiresAt: now.Add(time.Minute), UsedAt: &now}
+	assert.False(t, used.IsValid(now))
+}
+
+func TestPlan_HasFeature(t *testing.T) {
+	plan := &model.Plan{Features: `["basic_testing", "sso"]`}
+	assert.True(t, plan.HasFeature("sso"))
+	assert.False(t, plan.HasFeature("audit_logs"))
+
+	invalid := &model.Plan{Features: "not json"}
+	assert.False(t, invalid.HasFeature("sso"))
+}
@@ -0,0 +1,41 @@
+package model_test
+
+import (
+	"testing"
+	"testlake/model"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUsageCounts_ExceededLimits(t *testing.T) {
+	plan := &model.Plan{MaxUsers: 5, MaxProjects: 10, MaxEnvironments: 5, MaxSchemas: 25, MaxTestRecordsPerSchema: 1000}
+
+	within := model.UsageCounts{Users: 5, Projects: 10, Environments: 2, Schemas: 25, MaxTestRecordsPerSchema: 1000}
+	assert.Empty(t, within.ExceededLimits(plan))
+
+	over := model.UsageCounts{Users: 6, Projects: 3, Environments: 2, Schemas: 4, MaxTestRecordsPerSchema: 1500}
+	assert.Equal(t, []model.LimitExcess{
+		{Resource: "users", Used: 6, Limit: 5},
+		{Resource: "test_records_per_schema", Used: 1500, Limit: 1000},
+	}, over.ExceededLimits(plan))
+}
+
+func TestUsageCounts_NegativeLimitIsUnlimited(t *testing.T) {
+	plan := &model.Plan{MaxUsers: -1, MaxProjects: -1, MaxEnvironments: -1, MaxSchemas: -1, MaxTestRecordsPerSchema: -1}
+	usage := model.UsageCounts{Users: 500, Projects: 500, Environments: 500, Schemas: 500, MaxTestRecordsPerSchema: 500000}
+	assert.Empty(t, usage.ExceededLimits(plan))
+}
+
+func TestPeriodEndAfter(t *testing.T) {
+	start := time.Date(2025, 1, 15, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2025, 2, 15, 10, 0, 0, 0, time.UTC), model.PeriodEndAfter(start, model.BillingCycleMonthly))
+	assert.Equal(t, time.Date(2026, 1, 15, 10, 0, 0, 0, time.UTC), model.PeriodEndAfter(start, model.BillingCycleYearly))
+}
+
+func TestPlan_AlertThresholds(t *testing.T) {
+	assert.Equal(t, []int{80, 90, 95}, (&model.Plan{}).AlertThresholds())
+	assert.Equal(t, []int{50, 75}, (&model.Plan{UsageAlertThresholds: "[75, 50, 75, 0, 120]"}).AlertThresholds())
+	assert.Empty(t, (&model.Plan{UsageAlertThresholds: "[]"}).AlertThresholds())
+	assert.Equal(t, []int{80, 90, 95}, (&model.Plan{UsageAlertThresholds: "not json"}).AlertThresholds())
+}
@@ -0,0 +1,53 @@
+package model_test
+
+import (
+	"testing"
+	"testlake/model"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestUserSession_BeforeCreate(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+
+	db.AutoMigrate(&model.UserSession{}, &model.LoginEvent{})
+
+	session := &model.UserSession{
+		UserID:     uuid.New(),
+		AuthMethod: model.AuthMethodPassword,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	assert.NoError(t, db.Create(session).Error)
+	assert.NotEqual(t, uuid.Nil, session.ID)
+
+	event := &model.LoginEvent{
+		Email:      "unknown@example.com",
+		AuthMethod: model.AuthMethodPassword,
+		Outcome:    model.LoginOutcomeInvalidCredentials,
+	}
+	assert.NoError(t, db.Create(event).Error)
+	assert.NotEqual(t, uuid.Nil, event.ID)
+	assert.Nil(t, event.UserID)
+}
+
+func TestUserSession_IsActive(t *testing.T) {
+	now := time.Now()
+
+	active := &model.UserSession{ExpiresAt: now.Add(time.Hour)}
+	assert.True(t, active.IsActive(now))
+
+	expired := &model.UserSession{ExpiresAt: now.Add(-time.Minute)}
+	assert.False(t, expired.IsActive(now))
+
+	revokedAt := now.Add(-time.Minute)
+	revoked := &model.UserSession{ExpiresAt: now.Add(time.Hour), RevokedAt: &revokedAt}
+	assert.False(t, revoked.IsActive(now))
+}
@@ -0,0 +1,227 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCouponNotFound is returned for codes no coupon has.
+	ErrCouponNotFound = errors.New("coupon does not exist")
+	// ErrCouponExpired is returned for coupons past their expiry or deactivated.
+	ErrCouponExpired = errors.New("coupon has expired")
+	// ErrCouponExhausted is returned for coupons that reached their redemption limit.
+	ErrCouponExhausted = errors.New("coupon reached its redemption limit")
+	// ErrCouponNotForPlan is returned for coupons restricted to other plans.
+	ErrCouponNotForPlan = errors.New("coupon does not apply to this plan")
+	// ErrCouponAlreadyRedeemed is returned when the organization redeemed the coupon before.
+	ErrCouponAlreadyRedeemed = errors.New("coupon was already redeemed by the organization")
+	// ErrDiscountActive is returned when the subscription is still discounted by another coupon.
+	ErrDiscountActive = errors.New("subscription already has a discount")
+)
+
+// CheckCoupon looks up a coupon code and checks that the organization can
+// redeem it for the plan, without redeeming it.
+func CheckCoupon(code string, organizationID, planID uuid.UUID, now time.Time) (*model.Coupon, error) {
+	couponDao := dao.NewCouponDao()
+	coupon, err := couponDao.GetByCode(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCouponNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return coupon, checkCoupon(couponDao, coupon, organizationID, planID, now)
+}
+
+func checkCoupon(couponDao *dao.CouponDao, coupon *model.Coupon, organizationID, planID uuid.UUID, now time.Time) error {
+	if !coupon.IsActive || coupon.IsExpired(now) {
+		return ErrCouponExpired
+	}
+	if coupon.IsExhausted() {
+		return ErrCouponExhausted
+	}
+	if !coupon.AppliesToPlan(planID) {
+		return ErrCouponNotForPlan
+	}
+	redeemed, err := couponDao.HasRedeemed(coupon.ID, organizationID)
+	if err != nil {
+		return err
+	}
+	if redeemed {
+		return ErrCouponAlreadyRedeemed
+	}
+	return nil
+}
+
+// checkRedemption checks that RedeemCoupon would redeem the code for the
+// subscription, without locking or redeeming anything.
+func checkRedemption(code string, sub *model.Subscription, planID uuid.UUID, now time.Time) error {
+	if _, err := CheckCoupon(code, sub.OrganizationID, planID, now); err != nil {
+		return err
+	}
+	active, err := dao.NewCouponDao().GetActiveRedemption(sub.ID)
+	switch {
+	case err == nil && active.HasCyclesLeft():
+		return ErrDiscountActive
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+	return nil
+}
+
+// RedeemCoupon redeems a coupon code for the subscription, which is or is about
+// to be on the plan. The coupon row is locked so that concurrent redemptions
+// never exceed its limit. Nothing is discounted yet: DiscountLine counts each
+// billing period the redemption discounts.
+func RedeemCoupon(tx *gorm.DB, code string, sub *model.Subscription, planID, redeemedBy uuid.UUID, now time.Time) (*model.CouponRedemption, error) {
+	couponDao := dao.NewCouponDao().WithTx(tx)
+	coupon, err := couponDao.GetByCodeForUpdate(code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCouponNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := checkCoupon(couponDao, coupon, sub.OrganizationID, planID, now); err != nil {
+		return nil, err
+	}
+
+	// One discount at a time: a used-up one ends here, a running one blocks the coupon
+	active, err := couponDao.GetActiveRedemption(sub.ID)
+	switch {
+	case err == nil && active.HasCyclesLeft():
+		return nil, ErrDiscountActive
+	case err == nil:
+		active.EndedAt = &now
+		if err := couponDao.UpdateRedemption(active); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	redemption := &model.CouponRedemption{
+		CouponID:       coupon.ID,
+		OrganizationID: sub.OrganizationID,
+		SubscriptionID: sub.ID,
+		RedeemedBy:     redeemedBy,
+	}
+	if err := couponDao.CreateRedemption(redemption); err != nil {
+		return nil, err
+	}
+	redemption.Coupon = *coupon
+
+	coupon.TimesRedeemed++
+	if err := couponDao.Update(coupon); err != nil {
+		return nil, err
+	}
+
+	return redemption, dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(redeemedBy)).Record(sub.OrganizationID, model.BillingEventTypeCouponRedeemed, map[string]interface{}{
+		"coupon_id":       coupon.ID,
+		"code":            coupon.Code,
+		"redemption_id":   redemption.ID,
+		"subscription_id": sub.ID,
+		"plan_id":         planID,
+		"redeemed_by":     redeemedBy,
+	})
+}
+
+// DiscountLine returns the negative line item the subscription's discount adds
+// to an invoice of the billing period starting at periodStart, or nil when the
+// period is not discounted. The first time a period is asked for it counts
+// against the coupon's duration; asking again, e.g. for a proration invoice
+// later in the period, does not. share is the part of the period price the
+// invoice charges, 1 for a full period. Amounts are in minor units.
+func DiscountLine(tx *gorm.DB, sub *model.Subscription, periodStart time.Time, price int64, share float64, currency string) (*model.InvoiceLineItem, error) {
+	couponDao := dao.NewCouponDao().WithTx(tx)
+	redemption, err := couponDao.GetActiveRedemption(sub.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !redemption.CoversPeriod(periodStart) {
+		if !redemption.HasCyclesLeft() {
+			redemption.EndedAt = &periodStart
+			return nil, couponDao.UpdateRedemption(redemption)
+		}
+		// Periods on plans the coupon is not for do not use it up
+		if !redemption.Coupon.AppliesToPlan(sub.PlanID) {
+			return nil, nil
+		}
+		redemption.CyclesApplied++
+		redemption.LastPeriodStart = &periodStart
+		if err := couponDao.UpdateRedemption(redemption); err != nil {
+			return nil, err
+		}
+	} else if !redemption.Coupon.AppliesToPlan(sub.PlanID) {
+		return nil, nil
+	}
+
+	discount := utils.RoundMoney(float64(redemption.Coupon.Discount(price, currency)) * share)
+	if discount <= 0 {
+		return nil, nil
+	}
+	return &model.InvoiceLineItem{
+		Description: discountDescription(&redemption.Coupon, currency),
+		Quantity:    1,
+		UnitPrice:   -discount,
+		TotalPrice:  -discount,
+	}, nil
+}
+
+// CurrentDiscount returns what the subscription's discount took off price in
+// its current period, without counting anything against the coupon.
+func CurrentDiscount(sub *model.Subscription, price int64, currency string) (int64, error) {
+	redemption, err := dao.NewCouponDao().GetActiveRedemption(sub.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !redemption.CoversPeriod(sub.CurrentPeriodStart) || !redemption.Coupon.AppliesToPlan(sub.PlanID) {
+		return 0, nil
+	}
+	return redemption.Coupon.Discount(price, currency), nil
+}
+
+// UpcomingDiscount returns what the subscription's discount takes off price in
+// its next billing period, to tell the organization what it will be charged.
+func UpcomingDiscount(sub *model.Subscription, price int64, currency string) int64 {
+	redemption, err := dao.NewCouponDao().GetActiveRedemption(sub.ID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load the discount of subscription %s: %v", sub.ID, err)
+		}
+		return 0
+	}
+	if !redemption.HasCyclesLeft() || !redemption.Coupon.AppliesToPlan(sub.PlanID) {
+		return 0
+	}
+	return redemption.Coupon.Discount(price, currency)
+}
+
+func discountDescription(coupon *model.Coupon, currency string) string {
+	if coupon.DiscountType == model.CouponDiscountTypePercent && coupon.PercentOff != nil {
+		return fmt.Sprintf("Discount %s (%s%% off)", coupon.Code, strconv.FormatFloat(*coupon.PercentOff, 'f', -1, 64))
+	}
+	var amountOff int64
+	if coupon.AmountOff != nil {
+		amountOff = *coupon.AmountOff
+	}
+	return fmt.Sprintf("Discount %s (%s off)", coupon.Code, formatAmount(amountOff, currency))
+}
@@ -0,0 +1,183 @@
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ForecastChargeKind tells why a forecast charge is billed.
+type ForecastChargeKind string
+
+const (
+	ForecastChargeRenewal         ForecastChargeKind = "renewal"
+	ForecastChargePlanChange      ForecastChargeKind = "plan_change"
+	ForecastChargeTrialConversion ForecastChargeKind = "trial_conversion"
+)
+
+// ForecastCharge is an invoice the billing run is expected to issue, dated at
+// the start of the period it bills. Amounts are in minor units.
+type ForecastCharge struct {
+	Kind         ForecastChargeKind
+	Date         time.Time
+	PlanID       uuid.UUID
+	PlanName     string
+	BillingCycle model.BillingCycle
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	Amount       int64
+	Discount     int64
+	TaxAmount    int64
+	TotalAmount  int64
+}
+
+// BillingForecast is what an organization is expected to be charged until a
+// given time, in the currency of its subscription. Amounts are in minor units.
+type BillingForecast struct {
+	OrganizationID uuid.UUID
+	Currency       string
+	Until          time.Time
+	Charges        []ForecastCharge
+	TotalAmount    int64
+	// Open invoices in Currency, charged on the next billing run
+	OutstandingAmount int64
+	// Set when the subscription ends instead of renewing: it was cancelled at
+	// period end, or its trial ends without a payment method to convert
+	EndsAt *time.Time
+}
+
+// ForecastBilling forecasts the invoices the billing run issues the
+// organization from now until until: the renewals of its subscription, the
+// first period of a scheduled plan change and the conversion of its trial,
+// less coupon discounts and plus tax, as things stand at now. Periods on a
+// free plan are not invoiced and left out.
+func ForecastBilling(organizationID uuid.UUID, now, until time.Time) (*BillingForecast, error) {
+	org, err := dao.NewOrganizationDao().GetByID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	forecast := &BillingForecast{OrganizationID: organizationID, Currency: org.Currency, Until: until}
+
+	sub, err := dao.NewSubscriptionDao().GetActiveByOrganizationID(organizationID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if sub != nil {
+		forecast.Currency = sub.Currency
+		if err := forecastCharges(forecast, org, sub, now); err != nil {
+			return nil, err
+		}
+	}
+
+	unpaid, err := dao.NewInvoiceDao().GetUnpaidByOrganizationID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+	for _, invoice := range unpaid {
+		if invoice.Currency == forecast.Currency {
+			forecast.OutstandingAmount += invoice.TotalAmount
+		}
+	}
+	return forecast, nil
+}
+
+func forecastCharges(forecast *BillingForecast, org *model.Organization, sub *model.Subscription, now time.Time) error {
+	trialing := org.SubscriptionStatus == model.OrganizationSubscriptionStatusTrialing && sub.InTrialPeriod()
+	if sub.CancelAtPeriodEnd || (trialing && !HasChargeablePaymentMethod(org.ID)) {
+		endsAt := sub.CurrentPeriodEnd
+		forecast.EndsAt = &endsAt
+		return nil
+	}
+
+	plan, cycle := &sub.Plan, sub.BillingCycle
+	kind := ForecastChargeRenewal
+	if trialing {
+		kind = ForecastChargeTrialConversion
+	}
+	if sub.HasScheduledChange() {
+		scheduled, err := dao.NewPlanDao().GetByID(*sub.ScheduledPlanID)
+		if err != nil {
+			return err
+		}
+		plan, kind = scheduled, ForecastChargePlanChange
+		if sub.ScheduledBillingCycle != nil {
+			cycle = *sub.ScheduledBillingCycle
+		}
+	}
+
+	discounts, err := newDiscountForecast(sub)
+	if err != nil {
+		return err
+	}
+
+	for periodStart := sub.CurrentPeriodEnd; periodStart.Before(forecast.Until); {
+		periodEnd := model.PeriodEndAfter(periodStart, cycle)
+		price, found := sub.Price(plan, cycle)
+		if !found {
+			return fmt.Errorf("plan %s has no %s %s price", plan.Slug, cycle, sub.Currency)
+		}
+
+		if price > 0 {
+			discount := discounts.next(plan.ID, price, sub.Currency)
+			tax, err := CalculateTax(org, price-discount, sub.Currency)
+			if err != nil {
+				return err
+			}
+			charge := ForecastCharge{
+				Kind:         kind,
+				Date:         periodStart,
+				PlanID:       plan.ID,
+				PlanName:     plan.Name,
+				BillingCycle: cycle,
+				PeriodStart:  periodStart,
+				PeriodEnd:    periodEnd,
+				Amount:       price,
+				Discount:     discount,
+				TaxAmount:    tax.Amount,
+				TotalAmount:  price - discount + tax.Amount,
+			}
+			forecast.Charges = append(forecast.Charges, charge)
+			forecast.TotalAmount += charge.TotalAmount
+		}
+
+		kind = ForecastChargeRenewal
+		periodStart = periodEnd
+	}
+	return nil
+}
+
+// discountForecast counts the periods a redemption has left the way
+// DiscountLine does, without using any of them up.
+type discountForecast struct {
+	redemption    *model.CouponRedemption
+	cyclesApplied int
+}
+
+func newDiscountForecast(sub *model.Subscription) (*discountForecast, error) {
+	redemption, err := dao.NewCouponDao().GetActiveRedemption(sub.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &discountForecast{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &discountForecast{redemption: redemption, cyclesApplied: redemption.CyclesApplied}, nil
+}
+
+// next returns the discount of the next period on a plan.
+func (f *discountForecast) next(planID uuid.UUID, price int64, currency string) int64 {
+	if f.redemption == nil || f.redemption.EndedAt != nil || !f.redemption.Coupon.AppliesToPlan(planID) {
+		return 0
+	}
+	if total := f.redemption.Coupon.TotalCycles(); total > 0 && f.cyclesApplied >= total {
+		return 0
+	}
+	f.cyclesApplied++
+	return f.redemption.Coupon.Discount(price, currency)
+}
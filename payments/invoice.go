@@ -0,0 +1,94 @@
+package payments
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SettleInvoice marks the invoice paid within tx and returns the receipt to
+// email once tx is committed. When this ends its dunning and the organization
+// has nothing else in dunning, the organization becomes active again and the
+// receipt tells it so. actor is who paid: the billing run, the payer or PayPal.
+func SettleInvoice(tx *gorm.DB, invoice *model.Invoice, paidAt time.Time, actor model.BillingActor) (*utils.BillingNotice, error) {
+	wasInDunning := invoice.InDunning()
+	invoice.MarkPaid(paidAt)
+	if err := dao.NewInvoiceDao().WithTx(tx).Update(invoice); err != nil {
+		return nil, err
+	}
+	receipt := &utils.BillingNotice{
+		Subject:       "Payment Receipt",
+		Heading:       "Thank You For Your Payment",
+		Message:       fmt.Sprintf("We received the payment of %s for invoice %s. The paid invoice is attached.", formatAmount(invoice.TotalAmount, invoice.Currency), invoice.InvoiceNumber),
+		InvoiceNumber: invoice.InvoiceNumber,
+		InvoiceID:     &invoice.ID,
+		Kind:          model.InvoiceEmailKindReceipt,
+	}
+	if !wasInDunning {
+		return receipt, nil
+	}
+
+	err := dao.NewBillingEventDao().WithTx(tx).WithActor(actor).Record(invoice.OrganizationID, model.BillingEventTypeDunningRecovered, map[string]interface{}{
+		"invoice_id":     invoice.ID,
+		"invoice_number": invoice.InvoiceNumber,
+		"retries":        invoice.DunningRetries,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reactivated, err := dao.NewOrganizationDao().WithTx(tx).ReactivateIfSettled(invoice.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	if reactivated {
+		receipt.Message += " Your organization is active again."
+	}
+	return receipt, nil
+}
+
+// InvoiceIssuedNotice is the email sending a new invoice to the organization.
+func InvoiceIssuedNotice(invoice *model.Invoice) *utils.BillingNotice {
+	notice := &utils.BillingNotice{
+		Subject:       "Invoice " + invoice.InvoiceNumber,
+		Heading:       "Your Invoice",
+		InvoiceNumber: invoice.InvoiceNumber,
+		InvoiceID:     &invoice.ID,
+		Kind:          model.InvoiceEmailKindIssued,
+	}
+	if invoice.IsPaid() {
+		notice.Message = fmt.Sprintf("Invoice %s is attached. It is already paid, nothing is due.", invoice.InvoiceNumber)
+		return notice
+	}
+	notice.AmountDue = formatAmount(invoice.TotalAmount, invoice.Currency)
+	notice.Message = fmt.Sprintf("Invoice %s is attached.", invoice.InvoiceNumber)
+	if invoice.DueDate != nil {
+		notice.Message = fmt.Sprintf("Invoice %s is attached. It is due on %s.", invoice.InvoiceNumber, invoice.DueDate.Format("January 2, 2006"))
+	}
+	return notice
+}
+
+// sendBillingNotice emails the notice, if any. A failed email never fails billing.
+func sendBillingNotice(organizationID uuid.UUID, notice *utils.BillingNotice) {
+	if notice == nil {
+		return
+	}
+	if err := utils.SendBillingNotice(organizationID, *notice); err != nil {
+		log.Printf("Failed to send billing notice %q to organization %s: %v", notice.Subject, organizationID, err)
+	}
+}
+
+// SendBillingNotice emails the notice in the background, for callers that
+// should not wait on the mail server.
+func SendBillingNotice(organizationID uuid.UUID, notice *utils.BillingNotice) {
+	if notice != nil {
+		go sendBillingNotice(organizationID, notice)
+	}
+}
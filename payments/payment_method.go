@@ -0,0 +1,294 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPaymentMethodNotPending is returned when confirming a payment method
+	// that was already confirmed or given up.
+	ErrPaymentMethodNotPending = errors.New("payment method is not awaiting confirmation")
+	// ErrPaymentMethodNotApproved is returned when the provider refused to save
+	// the payment method, usually because the payer did not approve it yet.
+	ErrPaymentMethodNotApproved = errors.New("payment method was not approved by the payer")
+	// ErrPaymentMethodNotVerified is returned when making a payment method that
+	// cannot be charged the default one.
+	ErrPaymentMethodNotVerified = errors.New("payment method is not verified")
+	// ErrLastPaymentMethod is returned when deleting the default payment method
+	// of an organization with a paid subscription and no other method to charge.
+	ErrLastPaymentMethod = errors.New("the only payment method of an active subscription cannot be deleted")
+)
+
+// StartPaymentMethodSetup asks the payment provider to save a payment method of
+// the organization and returns it pending, with the URL the payer approves it
+// at. ConfirmPaymentMethod completes it once approved.
+func StartPaymentMethodSetup(ctx context.Context, organizationID, createdBy uuid.UUID) (*model.PaymentMethod, string, error) {
+	paymentMethod := &model.PaymentMethod{
+		ID:                uuid.New(),
+		OrganizationID:    organizationID,
+		PaymentMethodType: model.PaymentMethodTypePayPal,
+		Status:            model.PaymentMethodStatusPending,
+		IsActive:          true,
+		CreatedBy:         createdBy,
+	}
+
+	returnURL, cancelURL := utils.PaymentReturnURLs()
+	setupToken, err := utils.GetPaymentGateway().CreateSetupToken(ctx, utils.GatewaySetupTokenRequest{
+		ReturnURL:      returnURL,
+		CancelURL:      cancelURL,
+		IdempotencyKey: paymentMethod.ID.String(),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	paymentMethod.PayPalSetupTokenID = &setupToken.ID
+	if err := dao.NewPaymentMethodDao().Create(paymentMethod); err != nil {
+		return nil, "", err
+	}
+	return paymentMethod, setupToken.ApprovalURL, nil
+}
+
+// ConfirmPaymentMethod saves an approved payment method at the provider and
+// keeps its vault token, along with the payer the provider reports. It becomes
+// the default when asked to or when the organization has no other method to
+// charge. The setup token is the idempotency key, so a confirmation retried
+// after a failure does not save the method twice.
+func ConfirmPaymentMethod(ctx context.Context, paymentMethodID uuid.UUID, makeDefault bool, actor model.BillingActor) (*model.PaymentMethod, error) {
+	paymentMethod, err := dao.NewPaymentMethodDao().GetByID(paymentMethodID)
+	if err != nil {
+		return nil, err
+	}
+	if paymentMethod.Status != model.PaymentMethodStatusPending || paymentMethod.PayPalSetupTokenID == nil {
+		return nil, ErrPaymentMethodNotPending
+	}
+
+	setupTokenID := *paymentMethod.PayPalSetupTokenID
+	paymentToken, err := utils.GetPaymentGateway().CreatePaymentToken(ctx, setupTokenID, setupTokenID)
+	var gatewayErr *utils.GatewayError
+	if errors.As(err, &gatewayErr) && gatewayErr.StatusCode < http.StatusInternalServerError {
+		return nil, fmt.Errorf("%w: %v", ErrPaymentMethodNotApproved, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		paymentMethodDao := dao.NewPaymentMethodDao().WithTx(tx)
+		locked, err := paymentMethodDao.GetForUpdate(paymentMethodID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != model.PaymentMethodStatusPending {
+			return ErrPaymentMethodNotPending
+		}
+		paymentMethod = locked
+
+		verified, err := paymentMethodDao.GetVerifiedByOrganizationID(paymentMethod.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		paymentMethod.Status = model.PaymentMethodStatusVerified
+		paymentMethod.PayPalVaultID = &paymentToken.ID
+		if paymentToken.PayerID != "" {
+			paymentMethod.PayPalPayerID = &paymentToken.PayerID
+		}
+		if paymentToken.PayerEmail != "" {
+			paymentMethod.PayPalEmail = &paymentToken.PayerEmail
+		}
+		if err := paymentMethodDao.Update(paymentMethod); err != nil {
+			return err
+		}
+
+		if makeDefault || len(verified) == 0 {
+			if err := paymentMethodDao.SetDefault(paymentMethod.OrganizationID, paymentMethod.ID); err != nil {
+				return err
+			}
+			paymentMethod.IsDefault = true
+		}
+
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(actor).Record(paymentMethod.OrganizationID, model.BillingEventTypePaymentMethodAdded, map[string]interface{}{
+			"payment_method_id": paymentMethod.ID,
+			"payer_email":       paymentMethod.PayPalEmail,
+			"is_default":        paymentMethod.IsDefault,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paymentMethod, nil
+}
+
+// SetDefaultPaymentMethod makes a verified payment method the one the billing
+// run charges.
+func SetDefaultPaymentMethod(paymentMethodID uuid.UUID, actor model.BillingActor) (*model.PaymentMethod, error) {
+	var paymentMethod *model.PaymentMethod
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		paymentMethodDao := dao.NewPaymentMethodDao().WithTx(tx)
+		var err error
+		paymentMethod, err = paymentMethodDao.GetForUpdate(paymentMethodID)
+		if err != nil {
+			return err
+		}
+		if !paymentMethod.IsActive || !paymentMethod.IsVerified() {
+			return ErrPaymentMethodNotVerified
+		}
+		if paymentMethod.IsDefault {
+			return nil
+		}
+		if err := paymentMethodDao.SetDefault(paymentMethod.OrganizationID, paymentMethod.ID); err != nil {
+			return err
+		}
+		paymentMethod.IsDefault = true
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(actor).Record(paymentMethod.OrganizationID, model.BillingEventTypePaymentMethodDefault, map[string]interface{}{
+			"payment_method_id": paymentMethod.ID,
+			"payer_email":       paymentMethod.PayPalEmail,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paymentMethod, nil
+}
+
+// DeletePaymentMethod deactivates a payment method and removes its vault token
+// at the provider. The default method moves to the newest other verified one.
+// Deleting the default method fails with ErrLastPaymentMethod while the
+// organization has a paid subscription that renews and no other method to
+// charge.
+func DeletePaymentMethod(ctx context.Context, paymentMethodID uuid.UUID, actor model.BillingActor) error {
+	var vaultID *string
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		paymentMethodDao := dao.NewPaymentMethodDao().WithTx(tx)
+		paymentMethod, err := paymentMethodDao.GetForUpdate(paymentMethodID)
+		if err != nil {
+			return err
+		}
+		if !paymentMethod.IsActive {
+			return gorm.ErrRecordNotFound
+		}
+
+		if paymentMethod.IsDefault {
+			replacement, err := replacementPaymentMethod(paymentMethodDao, paymentMethod)
+			if err != nil {
+				return err
+			}
+			if replacement == nil {
+				renews, err := hasRenewingPaidSubscription(tx, paymentMethod.OrganizationID)
+				if err != nil {
+					return err
+				}
+				if renews {
+					return ErrLastPaymentMethod
+				}
+			} else if err := paymentMethodDao.SetDefault(paymentMethod.OrganizationID, replacement.ID); err != nil {
+				return err
+			}
+		}
+
+		if err := paymentMethodDao.SetActive(paymentMethod.ID, false); err != nil {
+			return err
+		}
+		vaultID = paymentMethod.PayPalVaultID
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(actor).Record(paymentMethod.OrganizationID, model.BillingEventTypePaymentMethodRemoved, map[string]interface{}{
+			"payment_method_id": paymentMethod.ID,
+			"payer_email":       paymentMethod.PayPalEmail,
+			"was_default":       paymentMethod.IsDefault,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	// The method is no longer used either way; a token left at the provider is harmless
+	if vaultID != nil {
+		if err := utils.GetPaymentGateway().DeletePaymentToken(ctx, *vaultID); err != nil {
+			log.Printf("Failed to delete vault token of payment method %s: %v", paymentMethodID, err)
+		}
+	}
+	return nil
+}
+
+// HasChargeablePaymentMethod reports whether the billing run can charge the
+// organization, i.e. it has a vaulted default payment method.
+func HasChargeablePaymentMethod(organizationID uuid.UUID) bool {
+	paymentMethod, err := dao.NewPaymentMethodDao().GetDefaultByOrganizationID(organizationID)
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("Failed to load default payment method of organization %s: %v", organizationID, err)
+		}
+		return false
+	}
+	return paymentMethod.IsChargeable()
+}
+
+// InvalidatePaymentMethod marks a payment method the provider reported failing
+// as invalid, within tx, so the billing run stops charging it. When it was the
+// default the newest other verified method replaces it. The caller records the
+// billing event, as it knows what reported the failure.
+func InvalidatePaymentMethod(tx *gorm.DB, paymentMethod *model.PaymentMethod, reason string, now time.Time) error {
+	paymentMethodDao := dao.NewPaymentMethodDao().WithTx(tx)
+	wasDefault := paymentMethod.IsDefault
+
+	paymentMethod.Status = model.PaymentMethodStatusInvalid
+	paymentMethod.FailureReason = &reason
+	paymentMethod.InvalidatedAt = &now
+	paymentMethod.IsDefault = false
+	if err := paymentMethodDao.Update(paymentMethod); err != nil {
+		return err
+	}
+	if !wasDefault {
+		return nil
+	}
+
+	replacement, err := replacementPaymentMethod(paymentMethodDao, paymentMethod)
+	if err != nil || replacement == nil {
+		return err
+	}
+	return paymentMethodDao.SetDefault(paymentMethod.OrganizationID, replacement.ID)
+}
+
+// replacementPaymentMethod returns the newest verified method of the
+// organization other than paymentMethod, or nil when there is none.
+func replacementPaymentMethod(paymentMethodDao *dao.PaymentMethodDao, paymentMethod *model.PaymentMethod) (*model.PaymentMethod, error) {
+	verified, err := paymentMethodDao.GetVerifiedByOrganizationID(paymentMethod.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range verified {
+		if verified[i].ID != paymentMethod.ID {
+			return &verified[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// hasRenewingPaidSubscription reports whether the organization's active
+// subscription is going to be charged again.
+func hasRenewingPaidSubscription(tx *gorm.DB, organizationID uuid.UUID) (bool, error) {
+	sub, err := dao.NewSubscriptionDao().WithTx(tx).GetActiveByOrganizationID(organizationID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if sub.CancelAtPeriodEnd {
+		return false, nil
+	}
+	price, found := sub.Price(&sub.Plan, sub.BillingCycle)
+	return !found || price > 0, nil
+}
@@ -0,0 +1,10 @@
+// Package payments holds the billing operations that run on request, e.g. from
+// an API call, such as refunding a payment. The periodic billing run in package
+// job builds on the same operations.
+package payments
+
+import "testlake/model"
+
+func formatAmount(amount int64, currency string) string {
+	return model.FormatMinorUnits(amount, currency) + " " + currency
+}
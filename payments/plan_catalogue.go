@@ -0,0 +1,234 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPlanSlugTaken is returned when creating a plan with the slug of another one.
+	ErrPlanSlugTaken = errors.New("plan slug already exists")
+	// ErrPlanRetired is returned when changing a plan that was retired or replaced.
+	ErrPlanRetired = errors.New("plan is retired")
+	// ErrFreePlanRetire is returned when retiring the free plan, which
+	// cancelled subscriptions fall back to.
+	ErrFreePlanRetire = errors.New("the free plan cannot be retired")
+	// ErrGatewaySyncFailed is returned along with the plan when the plan was
+	// saved but not synced with the payment provider; SyncGatewayPlan retries.
+	ErrGatewaySyncFailed = errors.New("plan could not be synced with the payment provider")
+)
+
+// freePlanSlug is the plan subscriptions move to when they end.
+const freePlanSlug = "free"
+
+// CreatePlan adds a plan, with its Prices, to the catalogue and creates its
+// product and billing plans at the payment provider.
+func CreatePlan(ctx context.Context, plan *model.Plan) (*model.Plan, error) {
+	planDao := dao.NewPlanDao()
+	if _, err := planDao.GetBySlug(plan.Slug); err == nil {
+		return nil, ErrPlanSlugTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	plan.Version = 1
+	plan.IsActive = true
+	if err := planDao.Create(plan); err != nil {
+		return nil, err
+	}
+	return SyncGatewayPlan(ctx, plan.ID)
+}
+
+// VersionPlan replaces a plan with next, a new version at new prices, for new
+// subscribers. The current version is retired: its subscribers keep their
+// grandfathered price, and its provider plans stop taking new subscriptions.
+func VersionPlan(ctx context.Context, planID uuid.UUID, next *model.Plan, now time.Time) (*model.Plan, error) {
+	var previous *model.Plan
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		planDao := dao.NewPlanDao().WithTx(tx)
+		current, err := planDao.GetForUpdate(planID)
+		if err != nil {
+			return err
+		}
+		if current.IsRetired() {
+			return ErrPlanRetired
+		}
+
+		next.ID = uuid.Nil
+		next.Slug = current.Slug
+		next.Version = current.Version + 1
+		next.PreviousVersionID = &current.ID
+		next.PayPalProductID = current.PayPalProductID
+		next.PayPalMonthlyPlanID = nil
+		next.PayPalYearlyPlanID = nil
+		next.IsActive = true
+		next.RetiredAt = nil
+		next.CreatedAt, next.UpdatedAt = time.Time{}, time.Time{}
+		if err := planDao.Create(next); err != nil {
+			return err
+		}
+		if err := planDao.Retire(current.ID, now); err != nil {
+			return err
+		}
+		previous = current
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err = dao.NewPlanDao().GetByID(previous.ID)
+	if err != nil {
+		return nil, err
+	}
+	deactivateGatewayPlans(ctx, previous)
+	return SyncGatewayPlan(ctx, next.ID)
+}
+
+// RetirePlan takes a plan off the catalogue without replacing it. Its
+// subscribers keep being billed at its price until they change plan.
+func RetirePlan(ctx context.Context, planID uuid.UUID, now time.Time) (*model.Plan, error) {
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		planDao := dao.NewPlanDao().WithTx(tx)
+		plan, err := planDao.GetForUpdate(planID)
+		if err != nil {
+			return err
+		}
+		if plan.IsRetired() {
+			return ErrPlanRetired
+		}
+		if plan.Slug == freePlanSlug {
+			return ErrFreePlanRetire
+		}
+		return planDao.Retire(plan.ID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := dao.NewPlanDao().GetByID(planID)
+	if err != nil {
+		return nil, err
+	}
+	deactivateGatewayPlans(ctx, plan)
+	return plan, nil
+}
+
+// SyncGatewayPlan creates the plan's product at the payment provider and a
+// provider billing plan for each of its paid prices that has none yet, USD
+// ones in PayPalMonthlyPlanID and PayPalYearlyPlanID and the others in
+// PlanPrice.PayPalPlanID. Each ID is saved as soon as it is created, so a
+// failed sync can be run again. The synced plan is returned even on failure,
+// with an error wrapping ErrGatewaySyncFailed.
+func SyncGatewayPlan(ctx context.Context, planID uuid.UUID) (*model.Plan, error) {
+	planDao := dao.NewPlanDao()
+	plan, err := planDao.GetByID(planID)
+	if err != nil {
+		return nil, err
+	}
+	if plan.IsRetired() {
+		return plan, ErrPlanRetired
+	}
+
+	if err := syncGatewayPlan(ctx, planDao, plan); err != nil {
+		log.Printf("Failed to sync plan %s v%d with the payment provider: %v", plan.Slug, plan.Version, err)
+		return plan, fmt.Errorf("%w: %v", ErrGatewaySyncFailed, err)
+	}
+	return plan, nil
+}
+
+func syncGatewayPlan(ctx context.Context, planDao *dao.PlanDao, plan *model.Plan) error {
+	type pricePoint struct {
+		currency string
+		cycle    model.BillingCycle
+		amount   int64
+		planID   **string
+		save     func(gatewayPlanID string) error
+	}
+
+	saveUSD := func(string) error {
+		return planDao.UpdatePayPalPlanIDs(plan.ID, plan.PayPalMonthlyPlanID, plan.PayPalYearlyPlanID)
+	}
+	points := []pricePoint{
+		{model.DefaultCurrency, model.BillingCycleMonthly, plan.PriceMonthly, &plan.PayPalMonthlyPlanID, saveUSD},
+		{model.DefaultCurrency, model.BillingCycleYearly, plan.PriceYearly, &plan.PayPalYearlyPlanID, saveUSD},
+	}
+	for i := range plan.Prices {
+		price := &plan.Prices[i]
+		points = append(points, pricePoint{price.Currency, price.BillingCycle, price.Amount, &price.PayPalPlanID, func(gatewayPlanID string) error {
+			return planDao.UpdatePricePayPalPlanID(price.ID, gatewayPlanID)
+		}})
+	}
+
+	gateway := utils.GetPaymentGateway()
+	for _, point := range points {
+		if point.amount <= 0 || *point.planID != nil {
+			continue
+		}
+
+		if plan.PayPalProductID == nil {
+			request := utils.GatewayProductRequest{Name: plan.Name}
+			if plan.Description != nil {
+				request.Description = *plan.Description
+			}
+			product, err := gateway.CreateProduct(ctx, request)
+			if err != nil {
+				return err
+			}
+			if err := planDao.UpdatePayPalProductID(plan.ID, product.ID); err != nil {
+				return err
+			}
+			plan.PayPalProductID = &product.ID
+		}
+
+		interval := utils.GatewayIntervalMonth
+		if point.cycle == model.BillingCycleYearly {
+			interval = utils.GatewayIntervalYear
+		}
+		gatewayPlan, err := gateway.CreatePlan(ctx, utils.GatewayPlanRequest{
+			ProductID:   *plan.PayPalProductID,
+			Name:        fmt.Sprintf("%s v%d %s (%s)", plan.Name, plan.Version, point.cycle, point.currency),
+			Description: fmt.Sprintf("%s plan, billed %s", plan.Name, point.cycle),
+			Interval:    interval,
+			Price:       point.amount,
+			Currency:    point.currency,
+		})
+		if err != nil {
+			return err
+		}
+		*point.planID = &gatewayPlan.ID
+		if err := point.save(gatewayPlan.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deactivateGatewayPlans stops new subscriptions to a retired plan's provider
+// plans. Failures are only logged: retired plans are not offered anyway.
+func deactivateGatewayPlans(ctx context.Context, plan *model.Plan) {
+	gatewayPlanIDs := []*string{plan.PayPalMonthlyPlanID, plan.PayPalYearlyPlanID}
+	for _, price := range plan.Prices {
+		gatewayPlanIDs = append(gatewayPlanIDs, price.PayPalPlanID)
+	}
+
+	gateway := utils.GetPaymentGateway()
+	for _, gatewayPlanID := range gatewayPlanIDs {
+		if gatewayPlanID == nil {
+			continue
+		}
+		if err := gateway.DeactivatePlan(ctx, *gatewayPlanID); err != nil {
+			log.Printf("Failed to deactivate provider plan %s of plan %s v%d: %v", *gatewayPlanID, plan.Slug, plan.Version, err)
+		}
+	}
+}
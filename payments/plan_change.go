@@ -0,0 +1,287 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPlanNotPurchasable is returned when the new plan has no provider plan
+	// for the subscription's currency and billing cycle yet.
+	ErrPlanNotPurchasable = errors.New("plan is not available for purchase yet")
+	// ErrFreeToPaidUpgrade is returned when a subscription that is neither
+	// billed by a provider subscription nor by the billing run would move to a
+	// paid plan; it needs a new subscription.
+	ErrFreeToPaidUpgrade = errors.New("a free subscription cannot move to a paid plan")
+	// ErrUpgradePaymentMethod is returned when the upgrade invoices a
+	// proration but the organization has no payment method to charge it to.
+	ErrUpgradePaymentMethod = errors.New("no payment method to charge the upgrade to")
+	// ErrGatewayRevise is returned when the provider subscription could not be
+	// moved to the new plan; nothing was changed.
+	ErrGatewayRevise = errors.New("provider subscription could not be revised")
+)
+
+// PlanUpgrade is what upgrading a subscription produced.
+type PlanUpgrade struct {
+	// Invoice is the proration invoice, nil when nothing is due.
+	Invoice *model.Invoice
+	// ApprovalURL is where the payer approves the new plan, when the provider asks for it.
+	ApprovalURL string
+}
+
+// UpgradeSubscription moves the subscription to the new plan right away and
+// invoices the difference for the rest of the period: the unused time of the
+// current plan is credited and the new plan is charged until the period ends.
+// A cycle change starts a new period, charged in full. A discount, running or
+// redeemed with the change, applies to the charge.
+//
+// The proration invoice is charged to the organization's default payment
+// method by the billing run, also when a provider subscription bills the
+// plan, since the provider only ever charges the plan price. An upgrade with
+// something to prorate therefore needs a chargeable payment method. A
+// subscription the billing run charges, see IsVaultBilled, is charged the new
+// plan from its next period on. The caller emails the invoice, see
+// InvoiceIssuedNotice.
+//
+// The provider subscription is revised before the change is saved, once the
+// coupon is known to be redeemable, and revised back when saving fails.
+func UpgradeSubscription(ctx context.Context, sub *model.Subscription, newPlan *model.Plan, cycle model.BillingCycle, couponCode string, userID uuid.UUID, now time.Time) (*PlanUpgrade, error) {
+	// The unused time is credited at the price the period was charged
+	currency := sub.Currency
+	currentPrice, _ := sub.Price(&sub.Plan, sub.BillingCycle)
+	newPrice, _ := sub.Price(newPlan, cycle)
+	currentDiscount, err := CurrentDiscount(sub, currentPrice, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	restartPeriod := cycle != sub.BillingCycle
+	proration := utils.CalculateProration(currentPrice-currentDiscount, newPrice,
+		sub.CurrentPeriodStart, sub.CurrentPeriodEnd, now, restartPeriod)
+
+	// A subscription the billing run charges moves without the provider
+	var gatewayPlanID *string
+	if newPrice > 0 && !IsVaultBilled(sub) {
+		gatewayPlanID = newPlan.PayPalPlanIDIn(currency, cycle)
+		if gatewayPlanID == nil {
+			return nil, ErrPlanNotPurchasable
+		}
+		if !sub.IsGatewayBilled() {
+			return nil, ErrFreeToPaidUpgrade
+		}
+	}
+	if proration.Net() > 0 && !HasChargeablePaymentMethod(sub.OrganizationID) {
+		return nil, ErrUpgradePaymentMethod
+	}
+	if couponCode != "" {
+		if err := checkRedemption(couponCode, sub, newPlan.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	upgrade := &PlanUpgrade{}
+	previousGatewayPlanID := sub.Plan.PayPalPlanIDIn(currency, sub.BillingCycle)
+	if gatewayPlanID != nil {
+		revised, err := utils.GetPaymentGateway().ReviseSubscription(ctx, *sub.PayPalSubscriptionID, *gatewayPlanID)
+		if err != nil {
+			log.Printf("Failed to revise gateway subscription %s: %v", *sub.PayPalSubscriptionID, err)
+			return nil, fmt.Errorf("%w: %v", ErrGatewayRevise, err)
+		}
+		upgrade.ApprovalURL = revised.ApprovalURL
+	}
+
+	previousPlan := sub.Plan
+	previousCycle := sub.BillingCycle
+	sub.PlanID = newPlan.ID
+	sub.Plan = *newPlan
+	sub.BillingCycle = cycle
+	sub.ScheduledPlanID = nil
+	sub.ScheduledBillingCycle = nil
+	if restartPeriod {
+		sub.CurrentPeriodStart = now
+		sub.CurrentPeriodEnd = model.PeriodEndAfter(now, cycle)
+	}
+
+	err = dao.Transaction(func(tx *gorm.DB) error {
+		if couponCode != "" {
+			if _, err := RedeemCoupon(tx, couponCode, sub, newPlan.ID, userID, now); err != nil {
+				return err
+			}
+		}
+		share := proration.RemainingFraction
+		if restartPeriod {
+			share = 1
+		}
+		discount, err := DiscountLine(tx, sub, sub.CurrentPeriodStart, newPrice, share, currency)
+		if err != nil {
+			return err
+		}
+
+		orgDao := dao.NewOrganizationDao().WithTx(tx)
+		org, err := orgDao.GetByID(sub.OrganizationID)
+		if err != nil {
+			return err
+		}
+
+		if net := prorationNet(proration, discount); net > 0 {
+			invoice, err := createProrationInvoice(tx, org, sub, &previousPlan, previousCycle, proration, discount, net, now)
+			if err != nil {
+				return err
+			}
+			upgrade.Invoice = invoice
+			err = dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(sub.OrganizationID, model.BillingEventTypeInvoiceCreated, map[string]interface{}{
+				"subscription_id": sub.ID,
+				"invoice_id":      invoice.ID,
+				"invoice_number":  invoice.InvoiceNumber,
+				"amount":          invoice.TotalAmount,
+				"currency":        invoice.Currency,
+				"period_start":    invoice.BillingPeriodStart,
+				"period_end":      invoice.BillingPeriodEnd,
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := dao.NewSubscriptionDao().WithTx(tx).Update(sub); err != nil {
+			return err
+		}
+		// The revised provider subscription charges the new plan's price until
+		// the billing run prices the discount in again
+		if newPrice > 0 {
+			if err := dao.NewCouponDao().WithTx(tx).ClearGatewayPrice(sub.ID); err != nil {
+				return err
+			}
+		}
+
+		org.PlanID = &newPlan.ID
+		org.BillingCycle = cycle
+		org.NextBillingDate = &sub.CurrentPeriodEnd
+		if err := orgDao.Update(org); err != nil {
+			return err
+		}
+
+		eventData := map[string]interface{}{
+			"from_plan_id":       previousPlan.ID,
+			"from_billing_cycle": previousCycle,
+			"to_plan_id":         newPlan.ID,
+			"to_billing_cycle":   cycle,
+			"proration":          proration.Net(),
+		}
+		if discount != nil {
+			eventData["discount"] = -discount.TotalPrice
+		}
+		if upgrade.Invoice != nil {
+			eventData["invoice_id"] = upgrade.Invoice.ID
+		}
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(model.UserActor(userID)).Record(sub.OrganizationID, model.BillingEventTypePlanChanged, eventData)
+	})
+	if err != nil {
+		if gatewayPlanID != nil {
+			revertRevision(ctx, sub, previousGatewayPlanID)
+		}
+		return nil, err
+	}
+	return upgrade, nil
+}
+
+// revertRevision moves the provider subscription back to the plan it was on
+// before an upgrade that could not be saved. The revision dropped a discounted
+// price, so SyncGatewayDiscounts is left to price the discount in again.
+func revertRevision(ctx context.Context, sub *model.Subscription, previousGatewayPlanID *string) {
+	subscriptionID := *sub.PayPalSubscriptionID
+	if previousGatewayPlanID == nil {
+		log.Printf("Provider subscription %s stays on the new plan: the previous plan has no provider plan", subscriptionID)
+		return
+	}
+	if _, err := utils.GetPaymentGateway().ReviseSubscription(ctx, subscriptionID, *previousGatewayPlanID); err != nil {
+		log.Printf("Failed to revise provider subscription %s back to plan %s: %v", subscriptionID, *previousGatewayPlanID, err)
+		return
+	}
+	if err := dao.NewCouponDao().ClearGatewayPrice(sub.ID); err != nil {
+		log.Printf("Failed to clear the discounted price of subscription %s: %v", sub.ID, err)
+	}
+}
+
+// IsVaultBilled reports whether the billing run charges the paid periods of the
+// subscription to the organization's vaulted payment method, as it does once a
+// trial converted, rather than a provider subscription. The Plan relation must
+// be loaded.
+func IsVaultBilled(sub *model.Subscription) bool {
+	if sub.IsGatewayBilled() || sub.InTrialPeriod() {
+		return false
+	}
+	price, _ := sub.Price(&sub.Plan, sub.BillingCycle)
+	return price > 0
+}
+
+// prorationNet is the amount due for a plan change, less its discount.
+func prorationNet(proration utils.Proration, discount *model.InvoiceLineItem) int64 {
+	if discount == nil {
+		return proration.Net()
+	}
+	return proration.Net() + discount.TotalPrice
+}
+
+func createProrationInvoice(tx *gorm.DB, org *model.Organization, sub *model.Subscription, previousPlan *model.Plan, previousCycle model.BillingCycle, proration utils.Proration, discount *model.InvoiceLineItem, net int64, now time.Time) (*model.Invoice, error) {
+	invoiceDao := dao.NewInvoiceDao().WithTx(tx)
+	invoiceNumber, err := invoiceDao.GenerateInvoiceNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	var lineItems []model.InvoiceLineItem
+	if proration.Credit > 0 {
+		lineItems = append(lineItems, model.InvoiceLineItem{
+			Description: fmt.Sprintf("Unused time on %s (%s)", previousPlan.Name, previousCycle),
+			Quantity:    1,
+			UnitPrice:   -proration.Credit,
+			TotalPrice:  -proration.Credit,
+		})
+	}
+	chargeDescription := fmt.Sprintf("Remaining time on %s (%s)", sub.Plan.Name, sub.BillingCycle)
+	if sub.CurrentPeriodStart.Equal(now) {
+		chargeDescription = fmt.Sprintf("%s (%s)", sub.Plan.Name, sub.BillingCycle)
+	}
+	lineItems = append(lineItems, model.InvoiceLineItem{
+		Description: chargeDescription,
+		Quantity:    1,
+		UnitPrice:   proration.Charge,
+		TotalPrice:  proration.Charge,
+	})
+	if discount != nil {
+		lineItems = append(lineItems, *discount)
+	}
+
+	periodStart := now
+	periodEnd := sub.CurrentPeriodEnd
+	invoice := &model.Invoice{
+		OrganizationID:     sub.OrganizationID,
+		SubscriptionID:     &sub.ID,
+		InvoiceNumber:      invoiceNumber,
+		Amount:             net,
+		Currency:           sub.Currency,
+		Status:             model.InvoiceStatusSent,
+		IsProration:        true,
+		BillingPeriodStart: &periodStart,
+		BillingPeriodEnd:   &periodEnd,
+		DueDate:            &periodStart,
+	}
+	if err := ApplyTax(org, invoice); err != nil {
+		return nil, err
+	}
+	if err := invoiceDao.CreateWithLineItems(invoice, lineItems); err != nil {
+		return nil, err
+	}
+	return invoice, nil
+}
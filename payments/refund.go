@@ -0,0 +1,310 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrRefundNotAllowed is returned for payments that cannot be refunded: not
+// completed, already refunded in full or not settling an invoice.
+var ErrRefundNotAllowed = errors.New("payment cannot be refunded")
+
+// RefundBalanceError is returned when a refund is more than what is left to
+// refund of the payment or of its invoice.
+type RefundBalanceError struct {
+	Refundable int64 // minor units of Currency
+	Currency   string
+}
+
+func (e *RefundBalanceError) Error() string {
+	return "refund exceeds the refundable balance of " + formatAmount(e.Refundable, e.Currency)
+}
+
+// ErrRefundDeclined is returned when the payment provider rejected the refund.
+var ErrRefundDeclined = errors.New("refund was declined by the payment provider")
+
+// RefundRequest refunds a payment in full when Amount, a decimal amount of the
+// payment currency, is nil.
+type RefundRequest struct {
+	PaymentID   uuid.UUID
+	Amount      *float64
+	Reason      string
+	RequestedBy uuid.UUID
+}
+
+// RefundPayment refunds a payment through the payment gateway and documents the
+// refund with a credit note against the invoice the payment settled. The amount
+// is reserved on the payment and invoice balances before the provider is
+// called, so concurrent refunds can never exceed what was paid, and the credit
+// note ID is the idempotency key of the provider refund.
+//
+// The returned credit note is issued, or still pending when the provider did
+// not answer: the billing run completes it later. When the provider declines
+// the refund the credit note fails, its amount is released and the error wraps
+// ErrRefundDeclined.
+func RefundPayment(ctx context.Context, request RefundRequest, now time.Time) (*model.CreditNote, error) {
+	var creditNote *model.CreditNote
+	var captureID string
+
+	err := dao.Transaction(func(tx *gorm.DB) error {
+		payment, err := dao.NewPaymentDao().WithTx(tx).GetForUpdate(request.PaymentID)
+		if err != nil {
+			return err
+		}
+		if payment.InvoiceID == nil || payment.PayPalPaymentID == nil || payment.RefundableAmount() == 0 {
+			return ErrRefundNotAllowed
+		}
+		invoice, err := dao.NewInvoiceDao().WithTx(tx).GetForUpdate(*payment.InvoiceID)
+		if err != nil {
+			return err
+		}
+
+		refundable := min(payment.RefundableAmount(), invoice.RefundableAmount())
+		amount := refundable
+		if request.Amount != nil {
+			amount = model.ToMinorUnits(*request.Amount, payment.Currency)
+		}
+		if amount <= 0 || amount > refundable {
+			return &RefundBalanceError{Refundable: refundable, Currency: payment.Currency}
+		}
+
+		creditNoteDao := dao.NewCreditNoteDao().WithTx(tx)
+		number, err := creditNoteDao.GenerateCreditNoteNumber()
+		if err != nil {
+			return err
+		}
+		creditNote = &model.CreditNote{
+			OrganizationID:   payment.OrganizationID,
+			InvoiceID:        invoice.ID,
+			PaymentID:        payment.ID,
+			CreditNoteNumber: number,
+			Amount:           amount,
+			Currency:         payment.Currency,
+			Status:           model.CreditNoteStatusPending,
+		}
+		if request.Reason != "" {
+			creditNote.Reason = &request.Reason
+		}
+		if request.RequestedBy != uuid.Nil {
+			creditNote.CreatedBy = &request.RequestedBy
+		}
+		if err := creditNoteDao.Create(creditNote); err != nil {
+			return err
+		}
+
+		captureID = *payment.PayPalPaymentID
+		return reserveRefund(tx, payment, invoice, amount)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return completeRefund(ctx, creditNote, captureID, now)
+}
+
+// RetryRefund asks the provider again for a refund it never confirmed. The
+// credit note ID is the idempotency key, so a refund the provider already made
+// is not made twice.
+func RetryRefund(ctx context.Context, creditNote *model.CreditNote, now time.Time) (*model.CreditNote, error) {
+	payment, err := dao.NewPaymentDao().GetByID(creditNote.PaymentID)
+	if err != nil {
+		return nil, err
+	}
+	return completeRefund(ctx, creditNote, *payment.PayPalPaymentID, now)
+}
+
+func completeRefund(ctx context.Context, creditNote *model.CreditNote, captureID string, now time.Time) (*model.CreditNote, error) {
+	noteToPayer := "Refund " + creditNote.CreditNoteNumber
+	if creditNote.Reason != nil {
+		noteToPayer = *creditNote.Reason
+	}
+	refund, err := utils.GetPaymentGateway().RefundCapture(ctx, utils.GatewayRefundRequest{
+		CaptureID:      captureID,
+		Amount:         &creditNote.Amount,
+		Currency:       creditNote.Currency,
+		InvoiceNumber:  creditNote.CreditNoteNumber,
+		NoteToPayer:    noteToPayer,
+		IdempotencyKey: creditNote.ID.String(),
+	})
+	if err == nil && (refund.Status == "CANCELLED" || refund.Status == "FAILED") {
+		err = &utils.GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: refund.Status, Message: "refund " + refund.ID + " is " + refund.Status}
+	}
+
+	var gatewayErr *utils.GatewayError
+	if err != nil && !(errors.As(err, &gatewayErr) && gatewayErr.StatusCode < http.StatusInternalServerError) {
+		// The provider may or may not have refunded: the billing run retries it
+		log.Printf("Refund %s stays pending: %v", creditNote.CreditNoteNumber, err)
+		return creditNote, nil
+	}
+	if err != nil {
+		if failErr := failRefund(creditNote, err, now); failErr != nil {
+			return nil, failErr
+		}
+		return creditNote, fmt.Errorf("%w: %v", ErrRefundDeclined, err)
+	}
+
+	return creditNote, issueRefund(creditNote, refund, now)
+}
+
+// issueRefund records the refund the provider made and settles the statuses.
+func issueRefund(creditNote *model.CreditNote, refund *utils.GatewayRefund, now time.Time) error {
+	return dao.Transaction(func(tx *gorm.DB) error {
+		creditNoteDao := dao.NewCreditNoteDao().WithTx(tx)
+		locked, err := creditNoteDao.GetForUpdate(creditNote.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != model.CreditNoteStatusPending {
+			*creditNote = *locked
+			return nil
+		}
+
+		paymentDao := dao.NewPaymentDao().WithTx(tx)
+		payment, err := paymentDao.GetForUpdate(locked.PaymentID)
+		if err != nil {
+			return err
+		}
+		invoiceDao := dao.NewInvoiceDao().WithTx(tx)
+		invoice, err := invoiceDao.GetForUpdate(locked.InvoiceID)
+		if err != nil {
+			return err
+		}
+
+		locked.Status = model.CreditNoteStatusIssued
+		locked.PayPalRefundID = &refund.ID
+		locked.IssuedAt = &now
+		if err := creditNoteDao.Update(locked); err != nil {
+			return err
+		}
+		*creditNote = *locked
+
+		if err := settleRefundStatuses(tx, payment, invoice); err != nil {
+			return err
+		}
+
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(refundActor(locked)).Record(locked.OrganizationID, model.BillingEventTypePaymentRefunded, map[string]interface{}{
+			"credit_note_id":          locked.ID,
+			"credit_note_number":      locked.CreditNoteNumber,
+			"payment_id":              payment.ID,
+			"invoice_id":              invoice.ID,
+			"invoice_number":          invoice.InvoiceNumber,
+			"refund_id":               refund.ID,
+			"amount":                  locked.Amount,
+			"currency":                locked.Currency,
+			"reason":                  locked.Reason,
+			"refunded_by":             locked.CreatedBy,
+			"payment_refunded_amount": payment.RefundedAmount,
+			"payment_balance":         payment.RefundableAmount(),
+			"invoice_refunded_amount": invoice.RefundedAmount,
+			"invoice_balance":         invoice.RefundableAmount(),
+		})
+	})
+}
+
+// failRefund fails the credit note of a refund the provider declined and
+// releases its amount.
+func failRefund(creditNote *model.CreditNote, refundErr error, now time.Time) error {
+	return dao.Transaction(func(tx *gorm.DB) error {
+		creditNoteDao := dao.NewCreditNoteDao().WithTx(tx)
+		locked, err := creditNoteDao.GetForUpdate(creditNote.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != model.CreditNoteStatusPending {
+			*creditNote = *locked
+			return nil
+		}
+
+		payment, err := dao.NewPaymentDao().WithTx(tx).GetForUpdate(locked.PaymentID)
+		if err != nil {
+			return err
+		}
+		invoice, err := dao.NewInvoiceDao().WithTx(tx).GetForUpdate(locked.InvoiceID)
+		if err != nil {
+			return err
+		}
+
+		reason := refundErr.Error()
+		locked.Status = model.CreditNoteStatusFailed
+		locked.FailureReason = &reason
+		if err := creditNoteDao.Update(locked); err != nil {
+			return err
+		}
+		*creditNote = *locked
+
+		if err := reserveRefund(tx, payment, invoice, -locked.Amount); err != nil {
+			return err
+		}
+		if err := settleRefundStatuses(tx, payment, invoice); err != nil {
+			return err
+		}
+
+		return dao.NewBillingEventDao().WithTx(tx).WithActor(refundActor(locked)).Record(locked.OrganizationID, model.BillingEventTypeRefundFailed, map[string]interface{}{
+			"credit_note_id":     locked.ID,
+			"credit_note_number": locked.CreditNoteNumber,
+			"payment_id":         payment.ID,
+			"invoice_id":         invoice.ID,
+			"amount":             locked.Amount,
+			"currency":           locked.Currency,
+			"reason":             reason,
+			"failed_at":          now,
+		})
+	})
+}
+
+// reserveRefund adds the amount, negative to release it, to the refunded
+// amounts of the payment and invoice.
+func reserveRefund(tx *gorm.DB, payment *model.Payment, invoice *model.Invoice, amount int64) error {
+	payment.RefundedAmount = max(0, payment.RefundedAmount+amount)
+	if err := dao.NewPaymentDao().WithTx(tx).Update(payment); err != nil {
+		return err
+	}
+	invoice.RefundedAmount = max(0, invoice.RefundedAmount+amount)
+	return dao.NewInvoiceDao().WithTx(tx).Update(invoice)
+}
+
+// settleRefundStatuses derives the statuses of the payment and invoice from
+// their refunded amounts. Refunds still pending count as refunded.
+func settleRefundStatuses(tx *gorm.DB, payment *model.Payment, invoice *model.Invoice) error {
+	switch {
+	case payment.RefundedAmount <= 0:
+		payment.Status = model.PaymentStatusCompleted
+	case payment.RefundedAmount >= payment.Amount:
+		payment.Status = model.PaymentStatusRefunded
+	default:
+		payment.Status = model.PaymentStatusPartiallyRefunded
+	}
+	if err := dao.NewPaymentDao().WithTx(tx).Update(payment); err != nil {
+		return err
+	}
+
+	switch {
+	case invoice.RefundedAmount <= 0:
+		invoice.Status = model.InvoiceStatusPaid
+	case invoice.RefundedAmount >= invoice.TotalAmount:
+		invoice.Status = model.InvoiceStatusRefunded
+	default:
+		invoice.Status = model.InvoiceStatusPartiallyRefunded
+	}
+	return dao.NewInvoiceDao().WithTx(tx).Update(invoice)
+}
+
+// refundActor is the platform admin who asked for the refund, also when a
+// retry completes it.
+func refundActor(creditNote *model.CreditNote) model.BillingActor {
+	if creditNote.CreatedBy == nil {
+		return model.SystemActor
+	}
+	return model.AdminActor(*creditNote.CreatedBy)
+}
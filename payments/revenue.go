@@ -0,0 +1,237 @@
+package payments
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/google/uuid"
+)
+
+// RevenueMonth holds the revenue metrics of a calendar month, in UTC, in one
+// currency. Amounts are in minor units.
+type RevenueMonth struct {
+	Month    time.Time
+	Currency string
+	// Monthly recurring revenue of the paying subscriptions at the end of the
+	// month, or now for the current month; yearly prices count for a twelfth
+	MRR                 int64
+	ARR                 int64
+	PayingSubscriptions int
+	NewSubscriptions    int
+	// Paying subscriptions that ended in the month without another one
+	// replacing them, and their share of the paying ones at its start
+	ChurnedSubscriptions int
+	ChurnRate            float64
+	ChurnedMRR           int64
+	// Paid invoices before tax, spread evenly over their billing period, less
+	// the refunds issued in the month; the current month only counts until now
+	RecognizedRevenue int64
+	Refunds           int64
+	// Payments collected in the month, tax included
+	Collected int64
+}
+
+// payingSpan is the time a subscription paid for its plan: from the end of its
+// trial until it was cancelled.
+type payingSpan struct {
+	sub   *model.Subscription
+	start time.Time
+	end   *time.Time
+	mrr   int64
+}
+
+func (s payingSpan) payingAt(t time.Time) bool {
+	return !s.start.After(t) && (s.end == nil || s.end.After(t))
+}
+
+// RevenueReport computes the revenue metrics of every month from the month of
+// from through the month of to, or of now when to is later. Each month has a
+// row per currency it had revenue in, and one in the default currency at
+// least. MRR uses the prices subscriptions pay on their plan today.
+func RevenueReport(from, to, now time.Time) ([]RevenueMonth, error) {
+	first := monthStart(from)
+	last := monthStart(to)
+	if current := monthStart(now); last.After(current) {
+		last = current
+	}
+	until := last.AddDate(0, 1, 0)
+	if first.After(last) {
+		return nil, nil
+	}
+
+	subscriptions, err := dao.NewSubscriptionDao().GetStartedBefore(until)
+	if err != nil {
+		return nil, err
+	}
+	invoices, err := dao.NewInvoiceDao().GetPaidBetween(first, until)
+	if err != nil {
+		return nil, err
+	}
+	creditNotes, err := dao.NewCreditNoteDao().GetIssuedBetween(first, until)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := dao.NewPaymentDao().GetCollectedBetween(first, until)
+	if err != nil {
+		return nil, err
+	}
+
+	spans := map[uuid.UUID][]payingSpan{}
+	for i := range subscriptions {
+		if span, ok := payingSpanOf(&subscriptions[i]); ok {
+			spans[span.sub.OrganizationID] = append(spans[span.sub.OrganizationID], span)
+		}
+	}
+	// paidByOther reports whether another subscription of the organization paid at t
+	paidByOther := func(span payingSpan, t time.Time) bool {
+		for _, other := range spans[span.sub.OrganizationID] {
+			if other.sub.ID != span.sub.ID && other.payingAt(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	type rowKey struct {
+		month    int
+		currency string
+	}
+	rows := map[rowKey]*RevenueMonth{}
+	row := func(month int, currency string) *RevenueMonth {
+		key := rowKey{month, currency}
+		if rows[key] == nil {
+			rows[key] = &RevenueMonth{Month: first.AddDate(0, month, 0), Currency: currency}
+		}
+		return rows[key]
+	}
+	monthOf := func(t time.Time) int {
+		t = t.UTC()
+		return (t.Year()-first.Year())*12 + int(t.Month()-first.Month())
+	}
+
+	months := monthOf(last) + 1
+	for month := 0; month < months; month++ {
+		start := first.AddDate(0, month, 0)
+		end := start.AddDate(0, 1, 0)
+		if end.After(now) {
+			end = now
+		}
+		row(month, model.DefaultCurrency)
+
+		payingAtStart := map[string]int{}
+		for _, orgSpans := range spans {
+			for _, span := range orgSpans {
+				currency := span.sub.Currency
+				if span.payingAt(start) {
+					payingAtStart[currency]++
+				}
+				if span.payingAt(end) {
+					r := row(month, currency)
+					r.MRR += span.mrr
+					r.PayingSubscriptions++
+				}
+				if span.start.After(start) && !span.start.After(end) && !paidByOther(span, start) {
+					row(month, currency).NewSubscriptions++
+				}
+				if span.end != nil && span.end.After(start) && !span.end.After(end) && !paidByOther(span, *span.end) {
+					r := row(month, currency)
+					r.ChurnedSubscriptions++
+					r.ChurnedMRR += span.mrr
+				}
+			}
+		}
+		for currency, paying := range payingAtStart {
+			r := row(month, currency)
+			r.ChurnRate = math.Round(float64(r.ChurnedSubscriptions)/float64(paying)*10000) / 100
+		}
+
+		for i := range invoices {
+			if amount := recognizedIn(&invoices[i], start, end); amount != 0 {
+				row(month, invoices[i].Currency).RecognizedRevenue += amount
+			}
+		}
+	}
+
+	for _, creditNote := range creditNotes {
+		refund := creditNote.Amount
+		// Credit notes refund tax too; revenue is recognized before tax
+		if invoice := creditNote.Invoice; invoice.TotalAmount > 0 {
+			refund = utils.RoundMoney(float64(creditNote.Amount) * float64(invoice.Amount) / float64(invoice.TotalAmount))
+		}
+		r := row(monthOf(*creditNote.IssuedAt), creditNote.Currency)
+		r.Refunds += refund
+		r.RecognizedRevenue -= refund
+	}
+	for _, payment := range payments {
+		row(monthOf(*payment.ProcessedAt), payment.Currency).Collected += payment.Amount
+	}
+
+	report := make([]RevenueMonth, 0, len(rows))
+	for _, r := range rows {
+		r.ARR = r.MRR * 12
+		report = append(report, *r)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if !report[i].Month.Equal(report[j].Month) {
+			return report[i].Month.Before(report[j].Month)
+		}
+		return report[i].Currency < report[j].Currency
+	})
+	return report, nil
+}
+
+func payingSpanOf(sub *model.Subscription) (payingSpan, bool) {
+	price, found := sub.Price(&sub.Plan, sub.BillingCycle)
+	if !found || price <= 0 {
+		return payingSpan{}, false
+	}
+	mrr := price
+	if sub.BillingCycle == model.BillingCycleYearly {
+		mrr = utils.RoundMoney(float64(price) / 12)
+	}
+
+	start := sub.CreatedAt
+	if sub.TrialEnd != nil && sub.TrialEnd.After(start) {
+		start = *sub.TrialEnd
+	}
+	// Trials that expired never paid
+	if sub.CancelledAt != nil && !sub.CancelledAt.After(start) {
+		return payingSpan{}, false
+	}
+	return payingSpan{sub: sub, start: start, end: sub.CancelledAt, mrr: mrr}, true
+}
+
+// recognizedIn returns the part of the invoice's amount before tax earned
+// between from and until: its billing period's share, or all of it when it
+// has no period and was paid then.
+func recognizedIn(invoice *model.Invoice, from, until time.Time) int64 {
+	start, end := invoice.BillingPeriodStart, invoice.BillingPeriodEnd
+	if start == nil || end == nil || !end.After(*start) {
+		if !invoice.PaidAt.Before(from) && invoice.PaidAt.Before(until) {
+			return invoice.Amount
+		}
+		return 0
+	}
+
+	// Rounding the running total keeps the monthly shares adding up to the amount
+	earnedBy := func(t time.Time) int64 {
+		if t.Before(*start) {
+			t = *start
+		}
+		if t.After(*end) {
+			t = *end
+		}
+		return utils.RoundMoney(float64(invoice.Amount) * float64(t.Sub(*start)) / float64(end.Sub(*start)))
+	}
+	return earnedBy(until) - earnedBy(from)
+}
+
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,34 @@
+package payments
+
+import (
+	"testlake/model"
+	"testlake/utils"
+)
+
+// CalculateTax returns the tax the organization is charged on an amount in
+// minor units, from its billing country and tax ID.
+func CalculateTax(org *model.Organization, amount int64, currency string) (utils.TaxResult, error) {
+	request := utils.TaxRequest{Amount: amount, Currency: currency}
+	if org.BillingCountry != nil {
+		request.Country = *org.BillingCountry
+	}
+	if org.TaxID != nil {
+		request.TaxID = *org.TaxID
+	}
+	return utils.GetTaxCalculator().Calculate(request)
+}
+
+// ApplyTax taxes an invoice whose Amount, after discounts, is set and sets its
+// TotalAmount.
+func ApplyTax(org *model.Organization, invoice *model.Invoice) error {
+	tax, err := CalculateTax(org, invoice.Amount, invoice.Currency)
+	if err != nil {
+		return err
+	}
+	invoice.TaxRate = tax.Rate
+	invoice.TaxAmount = tax.Amount
+	invoice.TaxDescription = tax.Description
+	invoice.ReverseCharge = tax.ReverseCharge
+	invoice.TotalAmount = invoice.Amount + tax.Amount
+	return nil
+}
@@ -0,0 +1,150 @@
+package payments_test
+
+import (
+	"context"
+	"testing"
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type billingFixture struct {
+	gateway *utils.FakePaymentGateway
+	org     *model.Organization
+	plan    *model.Plan
+	sub     *model.Subscription
+}
+
+// setupBilling creates an organization on a 29/month plan whose period ended
+// an hour before now, on an in-memory database and the fake gateway.
+func setupBilling(t *testing.T, now time.Time) *billingFixture {
+	// Billing notices fail to render without templates, keep their error log out of the tree
+	t.Chdir(t.TempDir())
+
+	// Shared cache so that every pooled connection sees the same in-memory database
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Plan{}, &model.PlanPrice{}, &model.Organization{}, &model.Project{},
+		&model.Environment{}, &model.DataSchema{}, &model.TestData{}, &model.Subscription{}, &model.PaymentMethod{},
+		&model.Invoice{}, &model.InvoiceLineItem{}, &model.InvoiceSequence{}, &model.Payment{}, &model.BillingEvent{},
+		&model.OrganizationUsage{}, &model.UsageAlert{}, &model.Notification{}, &model.CreditNote{}, &model.CreditNoteSequence{},
+		&model.Coupon{}, &model.CouponRedemption{}, &model.InvoiceDocument{}, &model.InvoiceDelivery{}, &model.BillingContact{}))
+	// Raw tables: their now() defaults do not exist in sqlite
+	require.NoError(t, db.Exec("CREATE TABLE organization_members (id text, organization_id text, user_id text, role text, status text)").Error)
+	require.NoError(t, db.Exec("CREATE TABLE organization_invitations (id text, organization_id text, status text, expires_at datetime)").Error)
+	previous := dao.Database
+	dao.Database = db
+	t.Cleanup(func() { dao.Database = previous })
+
+	gateway := utils.NewFakePaymentGateway()
+	utils.SetPaymentGateway(gateway)
+	t.Cleanup(func() { utils.SetPaymentGateway(nil) })
+
+	userID := uuid.New()
+	require.NoError(t, db.Create(&model.User{ID: userID, Email: "owner@example.com", Username: "owner"}).Error)
+
+	free := &model.Plan{Name: "Free", Slug: "free", MaxUsers: 1, MaxProjects: 2, MaxEnvironments: 2, MaxSchemas: 5, MaxTestRecordsPerSchema: 100, Features: "[]", IsActive: true}
+	plan := &model.Plan{Name: "Starter", Slug: "starter", PriceMonthly: 2900, PriceYearly: 29000, MaxUsers: 5, MaxProjects: 10, MaxEnvironments: 5, MaxSchemas: 25, MaxTestRecordsPerSchema: 1000, Features: "[]", IsActive: true}
+	require.NoError(t, db.Create(free).Error)
+	require.NoError(t, db.Create(plan).Error)
+
+	org := &model.Organization{Name: "Acme", Slug: "acme", CreatedBy: userID, PlanID: &plan.ID, SubscriptionStatus: model.OrganizationSubscriptionStatusActive}
+	require.NoError(t, db.Create(org).Error)
+
+	periodEnd := now.Add(-time.Hour)
+	sub := &model.Subscription{
+		OrganizationID:     org.ID,
+		PlanID:             plan.ID,
+		Status:             model.SubscriptionStatusActive,
+		BillingCycle:       model.BillingCycleMonthly,
+		CurrentPeriodStart: periodEnd.AddDate(0, -1, 0),
+		CurrentPeriodEnd:   periodEnd,
+		CreatedBy:          userID,
+	}
+	require.NoError(t, db.Create(sub).Error)
+
+	return &billingFixture{gateway: gateway, org: org, plan: plan, sub: sub}
+}
+
+func (f *billingFixture) addPaymentMethod(t *testing.T, vaultID string) {
+	method := &model.PaymentMethod{OrganizationID: f.org.ID, PayPalVaultID: &vaultID, IsDefault: true, IsActive: true, CreatedBy: f.org.CreatedBy}
+	require.NoError(t, dao.Database.Create(method).Error)
+}
+
+func (f *billingFixture) invoices(t *testing.T) []model.Invoice {
+	var invoices []model.Invoice
+	require.NoError(t, dao.Database.Preload("LineItems").Order("invoice_number").Find(&invoices).Error)
+	return invoices
+}
+
+func (f *billingFixture) onlyInvoice(t *testing.T) model.Invoice {
+	invoices := f.invoices(t)
+	require.Len(t, invoices, 1)
+	return invoices[0]
+}
+
+// paidInvoice invoices the period after the fixture's and pays it with a
+// capture at the gateway, as the billing run would, and returns its payment.
+func (f *billingFixture) paidInvoice(t *testing.T, now time.Time) model.Payment {
+	periodStart := f.sub.CurrentPeriodEnd
+	periodEnd := periodStart.AddDate(0, 1, 0)
+	invoice := &model.Invoice{
+		OrganizationID:     f.org.ID,
+		SubscriptionID:     &f.sub.ID,
+		InvoiceNumber:      "INV-0001",
+		Amount:             2900,
+		TotalAmount:        2900,
+		Currency:           "USD",
+		Status:             model.InvoiceStatusSent,
+		BillingPeriodStart: &periodStart,
+		BillingPeriodEnd:   &periodEnd,
+		DueDate:            &periodStart,
+	}
+	require.NoError(t, dao.NewInvoiceDao().CreateWithLineItems(invoice, []model.InvoiceLineItem{
+		{Description: "Starter (monthly)", Quantity: 1, UnitPrice: 2900, TotalPrice: 2900},
+	}))
+
+	capture, err := f.gateway.ChargePaymentMethod(context.Background(), utils.GatewayChargeRequest{VaultID: "vault-1", Amount: invoice.TotalAmount, Currency: invoice.Currency})
+	require.NoError(t, err)
+	payment := model.Payment{
+		OrganizationID:  f.org.ID,
+		InvoiceID:       &invoice.ID,
+		SubscriptionID:  &f.sub.ID,
+		Amount:          invoice.TotalAmount,
+		Currency:        invoice.Currency,
+		PaymentMethod:   model.PaymentMethodEnumPayPal,
+		Status:          model.PaymentStatusCompleted,
+		PayPalPaymentID: &capture.ID,
+		ProcessedAt:     &now,
+	}
+	require.NoError(t, dao.Database.Create(&payment).Error)
+	_, err = payments.SettleInvoice(dao.Database, invoice, now, model.SystemActor)
+	require.NoError(t, err)
+	return payment
+}
+
+func billingEvents(t *testing.T, eventType model.BillingEventType) []model.BillingEvent {
+	var events []model.BillingEvent
+	require.NoError(t, dao.Database.Where("event_type = ?", eventType).Find(&events).Error)
+	return events
+}
+
+// startTrial turns the fixture subscription into a trial ending at trialEnd.
+func (f *billingFixture) startTrial(t *testing.T, trialEnd time.Time) {
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"current_period_start": trialEnd.AddDate(0, 0, -14),
+		"current_period_end":   trialEnd,
+		"trial_end":            trialEnd,
+	}).Error)
+	require.NoError(t, dao.Database.Model(f.org).Updates(map[string]interface{}{
+		"subscription_status": model.OrganizationSubscriptionStatusTrialing,
+		"trial_ends_at":       trialEnd,
+	}).Error)
+}
@@ -0,0 +1,60 @@
+package payments_test
+
+import (
+	"testing"
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecastBillingTrialConversionAndPlanChange(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	trialEnd := now.AddDate(0, 0, 5)
+	f.startTrial(t, trialEnd)
+
+	forecast, err := payments.ForecastBilling(f.org.ID, now, now.AddDate(0, 3, 0))
+	require.NoError(t, err)
+	assert.Empty(t, forecast.Charges, "trials without a payment method move to the free plan")
+	require.NotNil(t, forecast.EndsAt)
+	assert.Equal(t, trialEnd.Unix(), forecast.EndsAt.Unix())
+
+	f.addPaymentMethod(t, "vault-1")
+	forecast, err = payments.ForecastBilling(f.org.ID, now, now.AddDate(0, 3, 0))
+	require.NoError(t, err)
+	require.NotEmpty(t, forecast.Charges)
+	assert.Equal(t, payments.ForecastChargeTrialConversion, forecast.Charges[0].Kind)
+	assert.Equal(t, trialEnd.Unix(), forecast.Charges[0].Date.Unix())
+	assert.Equal(t, payments.ForecastChargeRenewal, forecast.Charges[1].Kind)
+
+	yearly := model.BillingCycleYearly
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"scheduled_plan_id":       f.plan.ID,
+		"scheduled_billing_cycle": yearly,
+	}).Error)
+	forecast, err = payments.ForecastBilling(f.org.ID, now, now.AddDate(0, 3, 0))
+	require.NoError(t, err)
+	require.Len(t, forecast.Charges, 1, "the next yearly period starts after the forecast")
+	assert.Equal(t, payments.ForecastChargePlanChange, forecast.Charges[0].Kind)
+	assert.Equal(t, int64(29000), forecast.Charges[0].TotalAmount)
+	assert.Equal(t, trialEnd.AddDate(1, 0, 0).Unix(), forecast.Charges[0].PeriodEnd.Unix())
+}
+
+func TestForecastBillingEndsWithCancelledSubscription(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	require.NoError(t, dao.Database.Model(f.sub).Update("cancel_at_period_end", true).Error)
+	open := &model.Invoice{OrganizationID: f.org.ID, InvoiceNumber: "INV-OPEN", Amount: 1200, TotalAmount: 1200, Currency: "USD", Status: model.InvoiceStatusSent}
+	require.NoError(t, dao.Database.Create(open).Error)
+
+	forecast, err := payments.ForecastBilling(f.org.ID, now, now.AddDate(0, 3, 0))
+	require.NoError(t, err)
+	assert.Empty(t, forecast.Charges)
+	require.NotNil(t, forecast.EndsAt)
+	assert.Equal(t, f.sub.CurrentPeriodEnd.Unix(), forecast.EndsAt.Unix())
+	assert.Equal(t, int64(1200), forecast.OutstandingAmount)
+}
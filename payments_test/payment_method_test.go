@@ -0,0 +1,88 @@
+package payments_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// owner is the organization's creator, acting as a user.
+func (f *billingFixture) owner() model.BillingActor {
+	return model.UserActor(f.org.CreatedBy)
+}
+
+// vaultPaymentMethod saves a payment method the way the payer does: set up,
+// approved at the gateway and confirmed.
+func (f *billingFixture) vaultPaymentMethod(t *testing.T, makeDefault bool) *model.PaymentMethod {
+	method, _, err := payments.StartPaymentMethodSetup(context.Background(), f.org.ID, f.org.CreatedBy)
+	require.NoError(t, err)
+	f.gateway.ApproveSetupToken(*method.PayPalSetupTokenID)
+	method, err = payments.ConfirmPaymentMethod(context.Background(), method.ID, makeDefault, f.owner())
+	require.NoError(t, err)
+	return method
+}
+
+func TestPaymentMethodIsSavedOnlyOnceApproved(t *testing.T) {
+	f := setupBilling(t, time.Now())
+
+	method, approvalURL, err := payments.StartPaymentMethodSetup(context.Background(), f.org.ID, f.org.CreatedBy)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentMethodStatusPending, method.Status)
+	assert.Equal(t, "https://fake.gateway/vault/"+*method.PayPalSetupTokenID, approvalURL)
+	assert.False(t, method.IsChargeable())
+	listed, err := dao.NewPaymentMethodDao().GetByOrganizationID(f.org.ID)
+	require.NoError(t, err)
+	assert.Empty(t, listed, "pending methods are not listed")
+
+	_, err = payments.ConfirmPaymentMethod(context.Background(), method.ID, false, f.owner())
+	assert.ErrorIs(t, err, payments.ErrPaymentMethodNotApproved)
+
+	f.gateway.ApproveSetupToken(*method.PayPalSetupTokenID)
+	confirmed, err := payments.ConfirmPaymentMethod(context.Background(), method.ID, false, f.owner())
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentMethodStatusVerified, confirmed.Status)
+	require.NotNil(t, confirmed.PayPalVaultID)
+	assert.Contains(t, f.gateway.PaymentTokens, *confirmed.PayPalVaultID)
+	assert.Equal(t, "payer@example.com", *confirmed.PayPalEmail)
+	assert.True(t, confirmed.IsDefault, "the first method becomes the default")
+	assert.True(t, confirmed.IsChargeable())
+	assert.Len(t, billingEvents(t, model.BillingEventTypePaymentMethodAdded), 1)
+
+	_, err = payments.ConfirmPaymentMethod(context.Background(), method.ID, false, f.owner())
+	assert.ErrorIs(t, err, payments.ErrPaymentMethodNotPending)
+
+	second := f.vaultPaymentMethod(t, false)
+	assert.False(t, second.IsDefault)
+}
+
+func TestDeleteDefaultPaymentMethodNeedsAnotherWhileSubscribed(t *testing.T) {
+	f := setupBilling(t, time.Now())
+	first := f.vaultPaymentMethod(t, false)
+
+	err := payments.DeletePaymentMethod(context.Background(), first.ID, f.owner())
+	assert.ErrorIs(t, err, payments.ErrLastPaymentMethod)
+
+	second := f.vaultPaymentMethod(t, false)
+	require.NoError(t, payments.DeletePaymentMethod(context.Background(), first.ID, f.owner()))
+
+	deleted, err := dao.NewPaymentMethodDao().GetByID(first.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted.IsActive)
+	assert.NotContains(t, f.gateway.PaymentTokens, *first.PayPalVaultID)
+	current, err := dao.NewPaymentMethodDao().GetDefaultByOrganizationID(f.org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, current.ID)
+	assert.Len(t, billingEvents(t, model.BillingEventTypePaymentMethodRemoved), 1)
+
+	// Nothing is charged again once the subscription is cancelled at period end
+	f.sub.CancelAtPeriodEnd = true
+	require.NoError(t, dao.NewSubscriptionDao().Update(f.sub))
+	require.NoError(t, payments.DeletePaymentMethod(context.Background(), second.ID, f.owner()))
+}
@@ -0,0 +1,86 @@
+package payments_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newCataloguePlan(slug string) *model.Plan {
+	return &model.Plan{
+		Name:         "Team",
+		Slug:         slug,
+		PriceMonthly: 4900,
+		PriceYearly:  49000,
+		MaxUsers:     20,
+		Features:     "[]",
+		Prices: []model.PlanPrice{
+			{Currency: "EUR", BillingCycle: model.BillingCycleMonthly, Amount: 4500},
+			{Currency: "EUR", BillingCycle: model.BillingCycleYearly, Amount: 45000},
+		},
+	}
+}
+
+func TestCreatePlanSyncsEveryPaidPriceWithGateway(t *testing.T) {
+	f := setupBilling(t, time.Now())
+
+	plan, err := payments.CreatePlan(context.Background(), newCataloguePlan("team"))
+	require.NoError(t, err)
+
+	assert.Equal(t, 1, plan.Version)
+	require.NotNil(t, plan.PayPalProductID)
+	require.NotNil(t, plan.PayPalMonthlyPlanID)
+	require.NotNil(t, plan.PayPalYearlyPlanID)
+	for _, price := range plan.Prices {
+		require.NotNil(t, price.PayPalPlanID, "%s %s", price.Currency, price.BillingCycle)
+	}
+	assert.Len(t, f.gateway.Products, 1)
+	assert.Len(t, f.gateway.Plans, 4)
+
+	_, err = payments.CreatePlan(context.Background(), newCataloguePlan("team"))
+	assert.ErrorIs(t, err, payments.ErrPlanSlugTaken)
+}
+
+func TestSyncGatewayPlanResumesFailedSync(t *testing.T) {
+	f := setupBilling(t, time.Now())
+	f.gateway.FailNext("CreatePlan", errors.New("connection reset"))
+
+	plan, err := payments.CreatePlan(context.Background(), newCataloguePlan("team"))
+	require.ErrorIs(t, err, payments.ErrGatewaySyncFailed)
+	require.NotNil(t, plan, "the plan is created anyway")
+	assert.NotNil(t, plan.PayPalProductID)
+	assert.Nil(t, plan.PayPalMonthlyPlanID)
+
+	plan, err = payments.SyncGatewayPlan(context.Background(), plan.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, plan.PayPalMonthlyPlanID)
+	assert.NotNil(t, plan.PayPalYearlyPlanID)
+	assert.Len(t, f.gateway.Products, 1, "the product is not created twice")
+	assert.Len(t, f.gateway.Plans, 4)
+}
+
+func TestRetirePlanKeepsFreePlan(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+
+	free, err := dao.NewPlanDao().GetBySlug("free")
+	require.NoError(t, err)
+	_, err = payments.RetirePlan(context.Background(), free.ID, now)
+	assert.ErrorIs(t, err, payments.ErrFreePlanRetire)
+
+	retired, err := payments.RetirePlan(context.Background(), f.plan.ID, now)
+	require.NoError(t, err)
+	assert.True(t, retired.IsRetired())
+	_, err = payments.RetirePlan(context.Background(), f.plan.ID, now)
+	assert.ErrorIs(t, err, payments.ErrPlanRetired)
+	_, err = payments.SyncGatewayPlan(context.Background(), f.plan.ID)
+	assert.ErrorIs(t, err, payments.ErrPlanRetired)
+}
@@ -0,0 +1,72 @@
+package payments_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gatewayBilledUpgrade puts the fixture's subscription halfway through a
+// period billed by a provider subscription and returns a more expensive plan,
+// synced with the provider, to upgrade to.
+func (f *billingFixture) gatewayBilledUpgrade(t *testing.T, now time.Time) (*model.Subscription, *model.Plan) {
+	ctx := context.Background()
+	starter, err := payments.SyncGatewayPlan(ctx, f.plan.ID)
+	require.NoError(t, err)
+	pro := &model.Plan{Name: "Pro", Slug: "pro", PriceMonthly: 7900, PriceYearly: 79000, MaxUsers: 20, MaxProjects: 50, MaxEnvironments: 10, MaxSchemas: 100, MaxTestRecordsPerSchema: 10000, Features: "[]", IsActive: true}
+	require.NoError(t, dao.Database.Create(pro).Error)
+	pro, err = payments.SyncGatewayPlan(ctx, pro.ID)
+	require.NoError(t, err)
+
+	gatewaySub, err := f.gateway.CreateSubscription(ctx, utils.GatewaySubscriptionRequest{PlanID: *starter.PayPalMonthlyPlanID})
+	require.NoError(t, err)
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"pay_pal_subscription_id": gatewaySub.ID,
+		"current_period_start":    now.AddDate(0, 0, -15),
+		"current_period_end":      now.AddDate(0, 0, 15),
+	}).Error)
+
+	sub, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	return sub, pro
+}
+
+func TestUpgradeWithUnusableCouponLeavesProviderSubscription(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	sub, pro := f.gatewayBilledUpgrade(t, now)
+	starterPlanID := f.gateway.Subscriptions[*sub.PayPalSubscriptionID].PlanID
+
+	_, err := payments.UpgradeSubscription(context.Background(), sub, pro, model.BillingCycleMonthly, "NOPE", f.org.CreatedBy, now)
+	assert.ErrorIs(t, err, payments.ErrCouponNotFound)
+
+	assert.Equal(t, starterPlanID, f.gateway.Subscriptions[*sub.PayPalSubscriptionID].PlanID)
+	assert.NotContains(t, f.gateway.Calls, "ReviseSubscription")
+}
+
+func TestFailedUpgradeRevertsProviderSubscription(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	f.addPaymentMethod(t, "vault-1")
+	sub, pro := f.gatewayBilledUpgrade(t, now)
+	starterPlanID := f.gateway.Subscriptions[*sub.PayPalSubscriptionID].PlanID
+	// Recording the plan change fails once the provider was revised
+	require.NoError(t, dao.Database.Migrator().DropTable(&model.BillingEvent{}))
+
+	_, err := payments.UpgradeSubscription(context.Background(), sub, pro, model.BillingCycleMonthly, "", f.org.CreatedBy, now)
+	require.Error(t, err)
+
+	assert.Equal(t, starterPlanID, f.gateway.Subscriptions[*sub.PayPalSubscriptionID].PlanID)
+	unchanged, err := dao.NewSubscriptionDao().GetByID(f.sub.ID)
+	require.NoError(t, err)
+	assert.Equal(t, f.plan.ID, unchanged.PlanID)
+}
@@ -0,0 +1,90 @@
+package payments_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"testlake/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefundPaymentInPartsUpToWhatWasPaid(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	payment := f.paidInvoice(t, now)
+
+	amount := 10.0
+	creditNote, err := payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID, Amount: &amount, Reason: "Outage", RequestedBy: uuid.New()}, now)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditNoteStatusIssued, creditNote.Status)
+	assert.Regexp(t, `^CN-\d{4}-000001$`, creditNote.CreditNoteNumber)
+	assert.NotNil(t, creditNote.PayPalRefundID)
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPartiallyRefunded, invoice.Status)
+	assert.Equal(t, int64(1000), invoice.RefundedAmount)
+	refunded, err := dao.NewPaymentDao().GetByID(payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusPartiallyRefunded, refunded.Status)
+	assert.Equal(t, int64(1900), refunded.RefundableAmount())
+
+	// Without an amount, whatever is left is refunded
+	creditNote, err = payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID, Reason: "Cancelled"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1900), creditNote.Amount)
+	assert.Equal(t, model.InvoiceStatusRefunded, f.onlyInvoice(t).Status)
+	refunded, err = dao.NewPaymentDao().GetByID(payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.PaymentStatusRefunded, refunded.Status)
+
+	_, err = payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID}, now)
+	assert.ErrorIs(t, err, payments.ErrRefundNotAllowed)
+	assert.Len(t, billingEvents(t, model.BillingEventTypePaymentRefunded), 2)
+	assert.Len(t, f.gateway.Refunds, 1)
+}
+
+func TestRefundPaymentNeverExceedsBalance(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	payment := f.paidInvoice(t, now)
+
+	amount := 29.5
+	_, err := payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID, Amount: &amount}, now)
+	var balanceErr *payments.RefundBalanceError
+	require.ErrorAs(t, err, &balanceErr)
+	assert.Equal(t, int64(2900), balanceErr.Refundable)
+
+	var count int64
+	require.NoError(t, dao.Database.Model(&model.CreditNote{}).Count(&count).Error)
+	assert.Zero(t, count)
+	assert.Zero(t, f.onlyInvoice(t).RefundedAmount)
+	assert.Empty(t, f.gateway.Refunds)
+}
+
+func TestDeclinedRefundReleasesBalance(t *testing.T) {
+	now := time.Now()
+	f := setupBilling(t, now)
+	payment := f.paidInvoice(t, now)
+	f.gateway.FailNext("RefundCapture", &utils.GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "TRANSACTION_REFUSED"})
+
+	creditNote, err := payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID}, now)
+	require.ErrorIs(t, err, payments.ErrRefundDeclined)
+	assert.Equal(t, model.CreditNoteStatusFailed, creditNote.Status)
+
+	invoice := f.onlyInvoice(t)
+	assert.Equal(t, model.InvoiceStatusPaid, invoice.Status)
+	assert.Zero(t, invoice.RefundedAmount)
+	assert.Len(t, billingEvents(t, model.BillingEventTypeRefundFailed), 1)
+
+	// The payment can still be refunded
+	creditNote, err = payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID}, now)
+	require.NoError(t, err)
+	assert.Equal(t, model.CreditNoteStatusIssued, creditNote.Status)
+}
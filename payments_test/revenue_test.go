@@ -0,0 +1,99 @@
+package payments_test
+
+import (
+	"context"
+	"testing"
+	"testlake/dao"
+	"testlake/model"
+	"testlake/payments"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRevenueReportRecognizesRevenueOverBillingPeriods(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	f := setupBilling(t, now)
+	require.NoError(t, dao.Database.Model(f.sub).Update("created_at", time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)).Error)
+	payment := f.paidInvoice(t, now)
+	amount := 10.0
+	_, err := payments.RefundPayment(context.Background(), payments.RefundRequest{PaymentID: payment.ID, Amount: &amount, Reason: "Outage"}, now)
+	require.NoError(t, err)
+
+	// The organization moves to a yearly subscription on April 20th
+	replacedAt := time.Date(2026, 4, 20, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"status":       model.SubscriptionStatusCancelled,
+		"cancelled_at": replacedAt,
+	}).Error)
+	yearly := &model.Subscription{
+		OrganizationID:     f.org.ID,
+		PlanID:             f.plan.ID,
+		Status:             model.SubscriptionStatusActive,
+		BillingCycle:       model.BillingCycleYearly,
+		CurrentPeriodStart: replacedAt,
+		CurrentPeriodEnd:   replacedAt.AddDate(1, 0, 0),
+		CreatedBy:          f.org.CreatedBy,
+		CreatedAt:          replacedAt,
+	}
+	require.NoError(t, dao.Database.Create(yearly).Error)
+
+	report, err := payments.RevenueReport(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), now.AddDate(0, 2, 0))
+	require.NoError(t, err)
+	require.Len(t, report, 5, "January through the current month, May")
+
+	january, march, april := report[0], report[2], report[3]
+	assert.Equal(t, "2026-01", january.Month.Format("2006-01"))
+	assert.Equal(t, "USD", january.Currency)
+	assert.Equal(t, 1, january.NewSubscriptions)
+	assert.Equal(t, int64(2900), january.MRR)
+	assert.Equal(t, int64(34800), january.ARR)
+
+	// The renewal covers March 15th 11:00 to April 15th 11:00: 397 of its 744 hours are in March
+	assert.Equal(t, int64(1000), march.Refunds)
+	assert.Equal(t, int64(1547-1000), march.RecognizedRevenue)
+	assert.Equal(t, int64(2900), march.Collected)
+	assert.Equal(t, int64(2900-1547), april.RecognizedRevenue)
+
+	assert.Equal(t, 0, april.ChurnedSubscriptions, "a replaced subscription is not churn")
+	assert.Equal(t, 0, april.NewSubscriptions)
+	assert.Equal(t, int64(2417), april.MRR)
+
+	// Cancelling the yearly subscription churns the organization
+	require.NoError(t, dao.Database.Model(yearly).Updates(map[string]interface{}{
+		"status":       model.SubscriptionStatusCancelled,
+		"cancelled_at": time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC),
+	}).Error)
+	report, err = payments.RevenueReport(time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC), now.AddDate(0, 2, 0))
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, 1, report[0].ChurnedSubscriptions)
+	assert.Equal(t, int64(2417), report[0].ChurnedMRR)
+	assert.Equal(t, 100.0, report[0].ChurnRate)
+	assert.Equal(t, int64(0), report[0].MRR)
+}
+
+func TestRevenueReportLeavesOutTrialsThatNeverPaid(t *testing.T) {
+	now := time.Date(2026, 3, 15, 12, 0, 0, 0, time.UTC)
+	f := setupBilling(t, now)
+	trialEnd := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, dao.Database.Model(f.sub).Updates(map[string]interface{}{
+		"created_at":   trialEnd.AddDate(0, 0, -14),
+		"trial_end":    trialEnd,
+		"status":       model.SubscriptionStatusExpired,
+		"cancelled_at": trialEnd,
+	}).Error)
+	pending := &model.Subscription{OrganizationID: uuid.New(), PlanID: f.plan.ID, Status: model.SubscriptionStatusPending, BillingCycle: model.BillingCycleMonthly, CreatedBy: f.org.CreatedBy, CreatedAt: trialEnd}
+	require.NoError(t, dao.Database.Create(pending).Error)
+
+	report, err := payments.RevenueReport(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), now, now)
+	require.NoError(t, err)
+	require.Len(t, report, 2)
+	for _, month := range report {
+		assert.Equal(t, int64(0), month.MRR)
+		assert.Equal(t, 0, month.NewSubscriptions)
+		assert.Equal(t, 0, month.ChurnedSubscriptions)
+	}
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"testlake/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdminService struct {
+	Route      string
+	Controller controller.AdminController
+}
+
+// GetEndingTrials godoc
+// @Summary Get trials ending soon
+// @Description Report of the trials ending within the next days, for the sales team. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param days query int false "Days ahead, 1 to 90 (default 7)"
+// @Param page query int false "Page number"
+// @Success 200 {object} admin.EndingTrialListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/admin/trials/ending [GET]
+func (s AdminService) GetEndingTrials(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetEndingTrials)
+}
+
+// RefundPayment godoc
+// @Summary Refund a payment
+// @Description Refund a payment in full, or in part when amount is given, through the payment provider. The refund is documented by a credit note against the invoice the payment settled and never exceeds what is left to refund of the payment or invoice. Answers 202 while the provider has not confirmed the refund. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Payment ID"
+// @Param refund body admin.RefundPaymentRequest true "Refund"
+// @Success 200 {object} billing.CreditNoteOut
+// @Success 202 {object} billing.CreditNoteOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
+// @Router /api/v1/admin/payments/{id}/refund [POST]
+func (s AdminService) RefundPayment(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route+"/:id/refund", s.Controller.RefundPayment)
+}
+
+// CreateCoupon godoc
+// @Summary Create a coupon
+// @Description Create a discount code taking percent_off percent or amount_off in its currency off each period it discounts: the first one (once), duration_in_cycles of them (repeating) or all of them (forever). Codes are upper-cased; max_redemptions, expires_at and plan_ids optionally limit who can redeem it. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param coupon body coupon.CreateCouponRequest true "Coupon"
+// @Success 201 {object} coupon.CouponOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/admin/coupons [POST]
+func (s AdminService) CreateCoupon(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route, s.Controller.CreateCoupon)
+}
+
+// GetCoupons godoc
+// @Summary Get coupons
+// @Description List the coupons, newest first. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param page query int false "Page number"
+// @Success 200 {object} coupon.CouponListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/admin/coupons [GET]
+func (s AdminService) GetCoupons(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetCoupons)
+}
+
+// GetCouponRedemptions godoc
+// @Summary Get coupon redemptions
+// @Description List the organizations that redeemed a coupon, with the periods it discounted for each. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Coupon ID"
+// @Param page query int false "Page number"
+// @Success 200 {object} coupon.RedemptionListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/admin/coupons/{id}/redemptions [GET]
+func (s AdminService) GetCouponRedemptions(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route+"/:id/redemptions", s.Controller.GetCouponRedemptions)
+}
+
+// DeactivateCoupon godoc
+// @Summary Deactivate a coupon
+// @Description Stop a coupon from being redeemed. Discounts it already gave keep running for their duration. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Coupon ID"
+// @Success 200 {object} coupon.CouponOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/admin/coupons/{id}/deactivate [POST]
+func (s AdminService) DeactivateCoupon(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route+"/:id/deactivate", s.Controller.DeactivateCoupon)
+}
+
+// CreatePlan godoc
+// @Summary Create a plan
+// @Description Add a plan to the catalogue, priced in USD by price_monthly and price_yearly and in other currencies by prices, and create its PayPal product and billing plans. When PayPal fails the plan is still created, without PayPal plan IDs, and the sync endpoint retries. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param plan body plan.CreatePlanRequest true "Plan"
+// @Success 201 {object} plan.CataloguePlanOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/admin/plans [POST]
+func (s AdminService) CreatePlan(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route, s.Controller.CreatePlan)
+}
+
+// GetCataloguePlans godoc
+// @Summary List the plan catalogue
+// @Description List every version of every plan, retired ones included, by slug and newest version first. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param page query int false "Page number"
+// @Success 200 {object} plan.CataloguePlanListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/admin/plans [GET]
+func (s AdminService) GetCataloguePlans(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetCataloguePlans)
+}
+
+// GetCataloguePlan godoc
+// @Summary Get a plan version
+// @Description Get a plan version with all its prices, PayPal plan IDs and the number of active subscriptions on it. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Plan ID"
+// @Success 200 {object} plan.CataloguePlanOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/admin/plans/{id} [GET]
+func (s AdminService) GetCataloguePlan(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route+"/:id", s.Controller.GetCataloguePlan)
+}
+
+// UpdatePlan godoc
+// @Summary Update a plan
+// @Description Edit a plan's name, description, limits and features in place, which applies to its subscribers too. Prices only change with a new version. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Plan ID"
+// @Param plan body plan.UpdatePlanRequest true "Fields to change"
+// @Success 200 {object} plan.CataloguePlanOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/admin/plans/{id} [PUT]
+func (s AdminService) UpdatePlan(r *gin.RouterGroup, route string) {
+	r.PUT("/"+s.Route+"/"+route+"/:id", s.Controller.UpdatePlan)
+}
+
+// CreatePlanVersion godoc
+// @Summary Create a plan version
+// @Description Replace the current version of a plan with a new one, e.g. at new prices, copying the fields left out. New subscribers get the new version; the current one is retired and its subscribers keep their grandfathered price. Its PayPal plans are deactivated and new ones created. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Plan ID"
+// @Param version body plan.CreatePlanVersionRequest true "Changes of the new version"
+// @Success 201 {object} plan.CataloguePlanOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/admin/plans/{id}/versions [POST]
+func (s AdminService) CreatePlanVersion(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route+"/:id/versions", s.Controller.CreatePlanVersion)
+}
+
+// RetirePlan godoc
+// @Summary Retire a plan
+// @Description Take a plan off the catalogue and deactivate its PayPal plans. Its subscribers keep being billed at its price until they change plan. The free plan cannot be retired. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Plan ID"
+// @Success 200 {object} plan.CataloguePlanOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/admin/plans/{id}/retire [POST]
+func (s AdminService) RetirePlan(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route+"/:id/retire", s.Controller.RetirePlan)
+}
+
+// SyncPlan godoc
+// @Summary Sync a plan with PayPal
+// @Description Create the PayPal product of a plan and a PayPal billing plan for each paid price that has none yet. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Plan ID"
+// @Success 200 {object} plan.CataloguePlanOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
+// @Router /api/v1/admin/plans/{id}/sync [POST]
+func (s AdminService) SyncPlan(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route+"/:id/sync", s.Controller.SyncPlan)
+}
+
+// GetRevenueReport godoc
+// @Summary Get revenue report
+// @Description Monthly revenue metrics per currency: MRR and ARR, new and churned subscriptions, churn rate, revenue recognized over the billing periods of paid invoices less refunds, and payments collected. Months are calendar months in UTC. Answers a CSV file with format=csv. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param from query string false "First month, YYYY-MM (default 11 months ago)"
+// @Param to query string false "Last month, YYYY-MM (default the current month)"
+// @Param format query string false "csv for a CSV file"
+// @Success 200 {object} admin.RevenueReportOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/admin/revenue [GET]
+func (s AdminService) GetRevenueReport(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetRevenueReport)
+}
+
+// GetInvoiceDeliveries godoc
+// @Summary Get invoice deliveries
+// @Description List every attempt at emailing an invoice to the billing email and billing contacts, newest first, with the error of those that failed. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Invoice ID"
+// @Success 200 {object} admin.InvoiceDeliveryListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/admin/invoices/{id}/deliveries [GET]
+func (s AdminService) GetInvoiceDeliveries(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route+"/:id/deliveries", s.Controller.GetInvoiceDeliveries)
+}
+
+// GetBillingEvents godoc
+// @Summary Get billing events of all organizations
+// @Description Get the billing event log across organizations, newest first, for support investigations: every change to subscriptions, invoices, payments, payment methods and billing details, with who made it and the data recorded with it. Platform admins only
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param organization_id query string false "Organization ID"
+// @Param type query string false "Event types, comma separated"
+// @Param from query string false "Earliest time, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "Latest day (YYYY-MM-DD, inclusive) or time (RFC 3339, exclusive)"
+// @Param actor_type query string false "user, admin, paypal or system"
+// @Param actor_id query string false "User who caused the events"
+// @Param page query int false "Page number"
+// @Success 200 {object} admin.BillingEventListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/admin/billing-events [GET]
+func (s AdminService) GetBillingEvents(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetBillingEvents)
+}
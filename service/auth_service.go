@@ -34,6 +34,7 @@ func (s AuthService) SignUp(r *gin.RouterGroup, route string) {
 // @Param credentials body auth.SignInRequest true "Login credentials"
 // @Success 200 {object} auth.SignInOut
 // @Failure 401 {object} inout.BaseResponse
+// @Failure 429 {object} inout.BaseResponse
 // @Router /api/v1/auth/signin [POST]
 func (s AuthService) SignIn(r *gin.RouterGroup, route string) {
 	r.POST("/"+s.Route+"/"+route, s.Controller.SignIn)
@@ -107,6 +108,20 @@ func (s AuthService) VerifyEmail(r *gin.RouterGroup, route string) {
 	r.GET("/"+s.Route+"/"+route+"/:token", s.Controller.VerifyEmail)
 }
 
+// ConfirmEmailChange godoc
+// @Summary Confirm email change
+// @Description Apply a pending email change with the token sent to the new address
+// @Tags Authentication
+// @Produce html
+// @Param token path string true "Email change token"
+// @Success 200 {string} string "HTML page"
+// @Failure 400 {string} string "HTML page"
+// @Failure 409 {string} string "HTML page"
+// @Router /api/v1/auth/confirm-email-change/{token} [GET]
+func (s AuthService) ConfirmEmailChange(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route+"/:token", s.Controller.ConfirmEmailChange)
+}
+
 // ResendEmailConfirmation godoc
 // @Summary Resend email confirmation
 // @Description Resend email confirmation to user
@@ -119,3 +134,28 @@ func (s AuthService) VerifyEmail(r *gin.RouterGroup, route string) {
 func (s AuthService) ResendEmailConfirmation(r *gin.RouterGroup, route string) {
 	r.POST("/"+s.Route+"/"+route, s.Controller.ResendEmailConfirmation)
 }
+
+// UnlockAccount godoc
+// @Summary Unlock account
+// @Description Lift a temporary sign-in lockout using the signed link sent by email
+// @Tags Authentication
+// @Produce html
+// @Param token path string true "Signed unlock token"
+// @Success 200 {string} string "HTML page"
+// @Failure 400 {string} string "HTML page"
+// @Router /api/v1/auth/unlock-account/{token} [GET]
+func (s AuthService) UnlockAccount(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route+"/:token", s.Controller.UnlockAccount)
+}
+
+// GetJWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys used to verify TestLake access tokens, identified by the kid token header
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} auth.JWKSOut
+// @Failure 500 {object} inout.BaseResponse
+// @Router /.well-known/jwks.json [GET]
+func (s AuthService) GetJWKS(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetJWKS)
+}
@@ -69,26 +69,68 @@ func (s BillingService) GetInvoice(r *gin.RouterGroup, route string) {
 
 // DownloadInvoice godoc
 // @Summary Download invoice
-// @Description Download a specific invoice
+// @Description Download the PDF of an issued invoice, named after its invoice number. The PDF of a paid invoice never changes; the ETag header allows conditional requests
 // @Tags Billing
 // @Accept json
-// @Produce json
+// @Produce application/pdf
 // @Security BearerAuth
 // @Param Authorization header string true "Bearer token" format(Bearer {token})
 // @Param id path string true "Invoice ID"
-// @Success 302 "Redirect to invoice PDF"
+// @Success 200 {file} file "Invoice PDF"
+// @Success 304 "Not modified"
 // @Failure 400 {object} inout.BaseResponse
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
+// @Failure 500 {object} inout.BaseResponse
 // @Router /api/v1/invoices/{id}/download [GET]
 func (s BillingService) DownloadInvoice(r *gin.RouterGroup, route string) {
 	r.GET("/"+s.Route+"/"+route+"/:id/download", s.Controller.DownloadInvoice)
 }
 
+// GetCreditNotes godoc
+// @Summary Get credit notes
+// @Description Get the credit notes of an organization, one per refund, newest first
+// @Tags Billing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param page query int false "Page number"
+// @Success 200 {object} billing.CreditNoteListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/credit-notes [GET]
+func (s BillingService) GetCreditNotes(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetCreditNotes)
+}
+
+// DownloadCreditNote godoc
+// @Summary Download credit note
+// @Description Download the PDF of an issued credit note, named after its credit note number. The PDF never changes once rendered
+// @Tags Billing
+// @Accept json
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Credit note ID"
+// @Success 200 {file} file "Credit note PDF"
+// @Success 304 "Not modified"
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 500 {object} inout.BaseResponse
+// @Router /api/v1/credit-notes/{id}/download [GET]
+func (s BillingService) DownloadCreditNote(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route+"/:id/download", s.Controller.DownloadCreditNote)
+}
+
 // PayInvoice godoc
 // @Summary Pay invoice
-// @Description Process payment for an invoice
+// @Description Pay an invoice through the payment provider. Without order_id a checkout order is created and its approval_url returned (202); call again with order_id once approved to capture it. Organization owners and admins only
 // @Tags Billing
 // @Accept json
 // @Produce json
@@ -97,10 +139,13 @@ func (s BillingService) DownloadInvoice(r *gin.RouterGroup, route string) {
 // @Param id path string true "Invoice ID"
 // @Param payment body billing.PayInvoiceRequest true "Payment data"
 // @Success 200 {object} billing.PaymentOut
+// @Success 202 {object} billing.PaymentOut
 // @Failure 400 {object} inout.BaseResponse
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
+// @Failure 402 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
 // @Router /api/v1/invoices/{id}/pay [POST]
 func (s BillingService) PayInvoice(r *gin.RouterGroup, route string) {
 	r.POST("/"+s.Route+"/"+route+"/:id/pay", s.Controller.PayInvoice)
@@ -124,3 +169,49 @@ func (s BillingService) PayInvoice(r *gin.RouterGroup, route string) {
 func (s BillingService) GetBillingHistory(r *gin.RouterGroup, route string) {
 	r.GET("/"+s.Route+"/"+route, s.Controller.GetBillingHistory)
 }
+
+// GetBillingEvents godoc
+// @Summary Get billing events
+// @Description Get the billing event log of an organization, newest first: every change to its subscription, invoices, payments, payment methods and billing details, with who made it and the data recorded with it (the webhook body for PayPal events). Organization owners and admins only
+// @Tags Billing
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param type query string false "Event types, comma separated"
+// @Param from query string false "Earliest time, YYYY-MM-DD or RFC 3339"
+// @Param to query string false "Latest day (YYYY-MM-DD, inclusive) or time (RFC 3339, exclusive)"
+// @Param actor_type query string false "user, admin, paypal or system"
+// @Param actor_id query string false "User who caused the events"
+// @Param page query int false "Page number"
+// @Success 200 {object} billing.BillingEventListOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/billing/events [GET]
+func (s BillingService) GetBillingEvents(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetBillingEvents)
+}
+
+// GetBillingForecast godoc
+// @Summary Get billing forecast
+// @Description Forecast of the invoices the organization is expected to be issued over the next months: subscription renewals, the first period of a scheduled plan change and the conversion of its trial, less coupon discounts and plus tax. Answers a CSV file with format=csv
+// @Tags Billing
+// @Accept json
+// @Produce json
+// @Produce text/csv
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param months query int false "Months ahead, 1 to 24 (default 3)"
+// @Param format query string false "csv for a CSV file"
+// @Success 200 {object} billing.BillingForecastOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/billing/forecast [GET]
+func (s BillingService) GetBillingForecast(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetBillingForecast)
+}
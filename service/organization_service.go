@@ -121,6 +121,25 @@ func (s OrganizationService) GetOrganizationMembers(r *gin.RouterGroup) {
 	r.GET("/"+s.Route+"/:id/members", s.Controller.GetOrganizationMembers)
 }
 
+// GetMembersActivity godoc
+// @Summary Get members' last activity
+// @Description Get each member's last sign-in, last activity and active session count (creator or admin only)
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} organization.MembersActivityOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/members/activity [GET]
+func (s OrganizationService) GetMembersActivity(r *gin.RouterGroup) {
+	r.GET("/"+s.Route+"/:id/members/activity", s.Controller.GetMembersActivity)
+}
+
 // InviteMember godoc
 // @Summary Invite member
 // @Description Invite a user to join the organization
@@ -136,6 +155,7 @@ func (s OrganizationService) GetOrganizationMembers(r *gin.RouterGroup) {
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
+// @Failure 402 {object} utils.LimitExceededError
 // @Router /api/v1/organizations/{id}/invite [POST]
 func (s OrganizationService) InviteMember(r *gin.RouterGroup) {
 	r.POST("/"+s.Route+"/:id/invite", s.Controller.InviteMember)
@@ -200,3 +220,205 @@ func (s OrganizationService) UpdateMemberRole(r *gin.RouterGroup) {
 func (s OrganizationService) GetPendingInvites(r *gin.RouterGroup) {
 	r.GET("/"+s.Route+"/:id/invites", s.Controller.GetPendingInvites)
 }
+
+// GetSSOConfig godoc
+// @Summary Get SSO configuration
+// @Description Get the organization's OIDC identity provider configuration (creator or admin only)
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} organization.SSOConfigOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/sso [GET]
+func (s OrganizationService) GetSSOConfig(r *gin.RouterGroup) {
+	r.GET("/"+s.Route+"/:id/sso", s.Controller.GetSSOConfig)
+}
+
+// UpdateSSOConfig godoc
+// @Summary Configure SSO
+// @Description Register or replace the organization's OIDC identity provider (enterprise plans, creator or admin only). The issuer must serve a discovery document.
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param config body organization.SSOConfigRequest true "Identity provider configuration"
+// @Success 200 {object} organization.SSOConfigOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/sso [PUT]
+func (s OrganizationService) UpdateSSOConfig(r *gin.RouterGroup) {
+	r.PUT("/"+s.Route+"/:id/sso", s.Controller.UpdateSSOConfig)
+}
+
+// VerifySSODomain godoc
+// @Summary Verify an SSO domain
+// @Description Check that the domain publishes the TXT record listed in the SSO configuration. Logins for the domain are only routed to the organization once it is verified (creator or admin only).
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param domain path string true "Allowed domain"
+// @Success 200 {object} organization.SSOConfigOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/sso/domains/{domain}/verify [POST]
+func (s OrganizationService) VerifySSODomain(r *gin.RouterGroup) {
+	r.POST("/"+s.Route+"/:id/sso/domains/:domain/verify", s.Controller.VerifySSODomain)
+}
+
+// DeleteSSOConfig godoc
+// @Summary Delete SSO configuration
+// @Description Remove the organization's identity provider; members sign in with their password again (creator or admin only)
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} inout.BaseResponse
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/sso [DELETE]
+func (s OrganizationService) DeleteSSOConfig(r *gin.RouterGroup) {
+	r.DELETE("/"+s.Route+"/:id/sso", s.Controller.DeleteSSOConfig)
+}
+
+// CheckLimits godoc
+// @Summary Check plan limits
+// @Description Compare the organization's live usage with its plan limits. With a resource, tell whether count more of it fit and which plans would allow them.
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param resource query string false "Resource to check" Enums(users, projects, environments, schemas, test_records_per_schema)
+// @Param count query int false "How many more of the resource (default: 1)"
+// @Success 200 {object} organization.LimitsCheckOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/limits/check [GET]
+func (s OrganizationService) CheckLimits(r *gin.RouterGroup) {
+	r.GET("/"+s.Route+"/:id/limits/check", s.Controller.CheckLimits)
+}
+
+// GetBillingDetails godoc
+// @Summary Get billing details
+// @Description Get the billing email, address and tax ID invoices are addressed to, with the tax they are charged (creator or admin only)
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} organization.BillingDetailsOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/billing-details [GET]
+func (s OrganizationService) GetBillingDetails(r *gin.RouterGroup) {
+	r.GET("/"+s.Route+"/:id/billing-details", s.Controller.GetBillingDetails)
+}
+
+// UpdateBillingDetails godoc
+// @Summary Update billing details
+// @Description Replace the billing email, address and tax ID (creator or admin only). Invoices are taxed at the VAT rate of the billing country; businesses with a valid VAT ID in another country are reverse charged. The tax applies to invoices issued from now on.
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param details body organization.UpdateBillingDetailsRequest true "Billing details"
+// @Success 200 {object} organization.BillingDetailsOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/billing-details [PUT]
+func (s OrganizationService) UpdateBillingDetails(r *gin.RouterGroup) {
+	r.PUT("/"+s.Route+"/:id/billing-details", s.Controller.UpdateBillingDetails)
+}
+
+// GetBillingContacts godoc
+// @Summary List billing contacts
+// @Description List the addresses invoices, receipts and payment failures are emailed to besides the billing email (creator or admin only)
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} organization.BillingContactsOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/billing-contacts [GET]
+func (s OrganizationService) GetBillingContacts(r *gin.RouterGroup) {
+	r.GET("/"+s.Route+"/:id/billing-contacts", s.Controller.GetBillingContacts)
+}
+
+// AddBillingContact godoc
+// @Summary Add a billing contact
+// @Description Add an address invoices, receipts and payment failures are emailed to, with the invoice PDF attached (creator or admin only)
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param contact body organization.AddBillingContactRequest true "Billing contact"
+// @Success 201 {object} organization.BillingContactOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/billing-contacts [POST]
+func (s OrganizationService) AddBillingContact(r *gin.RouterGroup) {
+	r.POST("/"+s.Route+"/:id/billing-contacts", s.Controller.AddBillingContact)
+}
+
+// RemoveBillingContact godoc
+// @Summary Remove a billing contact
+// @Description Stop emailing the billing emails to a billing contact (creator or admin only)
+// @Tags Organization Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param contactId path string true "Billing contact ID"
+// @Success 200 {object} inout.BaseResponse
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/billing-contacts/{contactId} [DELETE]
+func (s OrganizationService) RemoveBillingContact(r *gin.RouterGroup) {
+	r.DELETE("/"+s.Route+"/:id/billing-contacts/:contactId", s.Controller.RemoveBillingContact)
+}
@@ -35,18 +35,18 @@ func (s PaymentMethodService) GetPaymentMethods(r *gin.RouterGroup, route string
 
 // CreatePaymentMethod godoc
 // @Summary Create payment method
-// @Description Create a new payment method for an organization
+// @Description Start saving a PayPal account as payment method. The method is pending until the payer approves it at approval_url and it is confirmed. Organization owners and admins only
 // @Tags Payment Methods
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param Authorization header string true "Bearer token" format(Bearer {token})
 // @Param id path string true "Organization ID"
-// @Param paymentMethod body payment.CreatePaymentMethodRequest true "Payment method data"
 // @Success 201 {object} payment.PaymentMethodOut
 // @Failure 400 {object} inout.BaseResponse
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
 // @Router /api/v1/organizations/{id}/payment-methods [POST]
 func (s PaymentMethodService) CreatePaymentMethod(r *gin.RouterGroup, route string) {
 	routePath := "/" + s.Route
@@ -56,9 +56,36 @@ func (s PaymentMethodService) CreatePaymentMethod(r *gin.RouterGroup, route stri
 	r.POST(routePath, s.Controller.CreatePaymentMethod)
 }
 
+// ConfirmPaymentMethod godoc
+// @Summary Confirm payment method
+// @Description Save a pending payment method the payer approved with PayPal. It becomes the default when requested or when the organization has no other verified method. Organization owners and admins only
+// @Tags Payment Methods
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param pmId path string true "Payment Method ID"
+// @Param request body payment.ConfirmPaymentMethodRequest false "Confirmation options"
+// @Success 200 {object} payment.PaymentMethodOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/payment-methods/{pmId}/confirm [POST]
+func (s PaymentMethodService) ConfirmPaymentMethod(r *gin.RouterGroup, route string) {
+	routePath := "/" + s.Route
+	if route != "" {
+		routePath += "/" + route
+	}
+	routePath += "/:pmId/confirm"
+	r.POST(routePath, s.Controller.ConfirmPaymentMethod)
+}
+
 // UpdatePaymentMethod godoc
 // @Summary Update payment method
-// @Description Update an existing payment method
+// @Description Update an existing payment method; only a verified method can be made the default. Organization owners and admins only
 // @Tags Payment Methods
 // @Accept json
 // @Produce json
@@ -84,7 +111,7 @@ func (s PaymentMethodService) UpdatePaymentMethod(r *gin.RouterGroup, route stri
 
 // DeletePaymentMethod godoc
 // @Summary Delete payment method
-// @Description Delete a payment method
+// @Description Delete a payment method and its PayPal vault token. The default method of an organization with a paid subscription cannot be deleted while it has no other verified method. Organization owners and admins only
 // @Tags Payment Methods
 // @Accept json
 // @Produce json
@@ -97,6 +124,7 @@ func (s PaymentMethodService) UpdatePaymentMethod(r *gin.RouterGroup, route stri
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
 // @Router /api/v1/organizations/{id}/payment-methods/{pmId} [DELETE]
 func (s PaymentMethodService) DeletePaymentMethod(r *gin.RouterGroup, route string) {
 	routePath := "/" + s.Route
@@ -109,7 +137,7 @@ func (s PaymentMethodService) DeletePaymentMethod(r *gin.RouterGroup, route stri
 
 // SetDefaultPaymentMethod godoc
 // @Summary Set default payment method
-// @Description Set a payment method as the default for the organization
+// @Description Set a verified payment method as the default for the organization. Organization owners and admins only
 // @Tags Payment Methods
 // @Accept json
 // @Produce json
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testlake/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PayPalService struct {
+	Route      string
+	Controller controller.PayPalWebhookController
+}
+
+// HandleWebhook godoc
+// @Summary PayPal webhook
+// @Description Receive PayPal webhook events. The transmission signature is verified with PayPal, each event ID is processed once and the subscription, organization, invoice and payment changes are stored together with a billing event. Handled events: BILLING.SUBSCRIPTION.ACTIVATED, BILLING.SUBSCRIPTION.CANCELLED, BILLING.SUBSCRIPTION.SUSPENDED, BILLING.SUBSCRIPTION.PAYMENT.FAILED, PAYMENT.SALE.COMPLETED, PAYMENT.SALE.DENIED, INVOICING.INVOICE.PAID and INVOICING.INVOICE.CANCELLED; other events are acknowledged and ignored.
+// @Tags Payments
+// @Accept json
+// @Produce json
+// @Param PAYPAL-TRANSMISSION-ID header string true "Transmission ID"
+// @Param PAYPAL-TRANSMISSION-TIME header string true "Transmission time"
+// @Param PAYPAL-TRANSMISSION-SIG header string true "Transmission signature"
+// @Param PAYPAL-CERT-URL header string true "Signing certificate URL"
+// @Param PAYPAL-AUTH-ALGO header string true "Signature algorithm"
+// @Success 200 {object} inout.BaseResponse
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 500 {object} inout.BaseResponse
+// @Failure 503 {object} inout.BaseResponse
+// @Router /api/v1/payments/paypal/webhooks [POST]
+func (s PayPalService) HandleWebhook(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route, s.Controller.HandleWebhook)
+}
@@ -17,6 +17,8 @@ type PlanService struct {
 // @Tags Plans
 // @Accept json
 // @Produce json
+// @Param currency query string false "ISO 4217 currency to price plans in, e.g. EUR"
+// @Param organization_id query string false "Price plans in the currency of this organization, which the bearer token must belong to"
 // @Success 200 {object} plan.PlanListOut
 // @Failure 500 {object} inout.BaseResponse
 // @Router /api/v1/plans [GET]
@@ -31,6 +33,8 @@ func (s PlanService) GetAllPlans(r *gin.RouterGroup, route string) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Plan ID"
+// @Param currency query string false "ISO 4217 currency to price plans in, e.g. EUR"
+// @Param organization_id query string false "Price plans in the currency of this organization, which the bearer token must belong to"
 // @Success 200 {object} plan.PlanOut
 // @Failure 400 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
@@ -45,6 +49,8 @@ func (s PlanService) GetPlan(r *gin.RouterGroup, route string) {
 // @Tags Plans
 // @Accept json
 // @Produce json
+// @Param currency query string false "ISO 4217 currency to price plans in, e.g. EUR"
+// @Param organization_id query string false "Price plans in the currency of this organization, which the bearer token must belong to"
 // @Success 200 {object} plan.PlanComparisonOut
 // @Failure 500 {object} inout.BaseResponse
 // @Router /api/v1/plans/compare [GET]
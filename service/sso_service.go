@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testlake/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SSOService struct {
+	Route      string
+	Controller controller.SSOController
+}
+
+// Discover godoc
+// @Summary Discover SSO for an email
+// @Description Find the organization that handles single sign-on for the email's domain and return the identity provider authorization URL
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body auth.SSODiscoverRequest true "Email address"
+// @Success 200 {object} auth.SSODiscoverOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
+// @Router /api/v1/auth/sso/discover [POST]
+func (s SSOService) Discover(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route, s.Controller.Discover)
+}
+
+// Login godoc
+// @Summary Start SSO sign-in
+// @Description Redirect the browser to the organization's OIDC identity provider
+// @Tags Authentication
+// @Produce json
+// @Param slug path string true "Organization slug"
+// @Param login_hint query string false "Email to pre-fill at the identity provider"
+// @Success 302
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
+// @Router /api/v1/auth/sso/{slug}/login [GET]
+func (s SSOService) Login(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/:slug/"+route, s.Controller.Login)
+}
+
+// Callback godoc
+// @Summary Complete SSO sign-in
+// @Description Exchange the authorization code, provision the user into the organization on first sign-in and return a JWT token. When SSO_FRONTEND_CALLBACK_URL is set the browser is redirected there with the token in the URL fragment instead.
+// @Tags Authentication
+// @Produce json
+// @Param code query string true "Authorization code"
+// @Param state query string true "State returned by the identity provider"
+// @Success 200 {object} auth.SignInOut
+// @Success 302
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Router /api/v1/auth/sso/callback [GET]
+func (s SSOService) Callback(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.Callback)
+}
@@ -32,7 +32,7 @@ func (s SubscriptionService) GetSubscription(r *gin.RouterGroup, route string) {
 
 // CreateSubscription godoc
 // @Summary Create subscription
-// @Description Create a new subscription for an organization
+// @Description Create a new subscription for an organization. Paid plans stay pending until the payer approves them at approval_url, unless start_trial starts a free trial, once per organization, that is charged to the default payment method when it ends. coupon_code redeems a coupon discounting the paid periods. Organization owners and admins only
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
@@ -44,6 +44,9 @@ func (s SubscriptionService) GetSubscription(r *gin.RouterGroup, route string) {
 // @Failure 400 {object} inout.BaseResponse
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
 // @Router /api/v1/organizations/{id}/subscription/create [POST]
 func (s SubscriptionService) CreateSubscription(r *gin.RouterGroup, route string) {
 	r.POST("/"+s.Route+"/"+route, s.Controller.CreateSubscription)
@@ -51,7 +54,7 @@ func (s SubscriptionService) CreateSubscription(r *gin.RouterGroup, route string
 
 // ChangePlan godoc
 // @Summary Change subscription plan
-// @Description Change the plan for an existing subscription
+// @Description Change the plan for an existing subscription. Upgrades apply at once and return a proration invoice for the rest of the period; downgrades are scheduled for the end of the period and rejected with 409 when current usage exceeds the new plan's limits. coupon_code redeems a coupon for the new plan: it discounts the proration charge of an upgrade and the periods that follow. An upgrade with something to prorate needs a default payment method to charge, else 402. Organization owners and admins only
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
@@ -62,16 +65,38 @@ func (s SubscriptionService) CreateSubscription(r *gin.RouterGroup, route string
 // @Success 200 {object} subscription.SubscriptionOut
 // @Failure 400 {object} inout.BaseResponse
 // @Failure 401 {object} inout.BaseResponse
+// @Failure 402 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
+// @Failure 409 {object} subscription.PlanLimitsExceededOut
+// @Failure 502 {object} inout.BaseResponse
 // @Router /api/v1/organizations/{id}/subscription/change-plan [PUT]
 func (s SubscriptionService) ChangePlan(r *gin.RouterGroup, route string) {
 	r.PUT("/"+s.Route+"/"+route, s.Controller.ChangePlan)
 }
 
+// CancelScheduledChange godoc
+// @Summary Cancel scheduled plan change
+// @Description Drop a downgrade scheduled for the end of the current period. Organization owners and admins only
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} subscription.SubscriptionOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/subscription/scheduled-change [DELETE]
+func (s SubscriptionService) CancelScheduledChange(r *gin.RouterGroup, route string) {
+	r.DELETE("/"+s.Route+"/"+route, s.Controller.CancelScheduledChange)
+}
+
 // CancelSubscription godoc
 // @Summary Cancel subscription
-// @Description Cancel an organization's subscription
+// @Description Cancel an organization's subscription. Organization owners and admins only
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
@@ -83,6 +108,7 @@ func (s SubscriptionService) ChangePlan(r *gin.RouterGroup, route string) {
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
 // @Router /api/v1/organizations/{id}/subscription/cancel [POST]
 func (s SubscriptionService) CancelSubscription(r *gin.RouterGroup, route string) {
 	r.POST("/"+s.Route+"/"+route, s.Controller.CancelSubscription)
@@ -90,7 +116,7 @@ func (s SubscriptionService) CancelSubscription(r *gin.RouterGroup, route string
 
 // ReactivateSubscription godoc
 // @Summary Reactivate subscription
-// @Description Reactivate a cancelled subscription
+// @Description Reactivate a cancelled subscription. Organization owners and admins only
 // @Tags Subscriptions
 // @Accept json
 // @Produce json
@@ -102,6 +128,7 @@ func (s SubscriptionService) CancelSubscription(r *gin.RouterGroup, route string
 // @Failure 401 {object} inout.BaseResponse
 // @Failure 403 {object} inout.BaseResponse
 // @Failure 404 {object} inout.BaseResponse
+// @Failure 502 {object} inout.BaseResponse
 // @Router /api/v1/organizations/{id}/subscription/reactivate [POST]
 func (s SubscriptionService) ReactivateSubscription(r *gin.RouterGroup, route string) {
 	r.POST("/"+s.Route+"/"+route, s.Controller.ReactivateSubscription)
@@ -124,3 +151,21 @@ func (s SubscriptionService) ReactivateSubscription(r *gin.RouterGroup, route st
 func (s SubscriptionService) GetSubscriptionUsage(r *gin.RouterGroup, route string) {
 	r.GET("/"+s.Route+"/"+route, s.Controller.GetSubscriptionUsage)
 }
+
+// GetCouponRedemptions godoc
+// @Summary Get coupon redemptions
+// @Description List the coupons the organization redeemed, with the billing periods each one discounted and has left
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} subscription.CouponRedemptionsOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/subscription/coupons [GET]
+func (s SubscriptionService) GetCouponRedemptions(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetCouponRedemptions)
+}
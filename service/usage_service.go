@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testlake/controller"
+
+	"github.com/gin-gonic/gin"
+)
+
+type UsageService struct {
+	Route      string
+	Controller controller.UsageController
+}
+
+// GetCurrentUsage godoc
+// @Summary Get current usage
+// @Description Get the organization's usage of the current month: live resource counts and metered API requests, against its plan limits
+// @Tags Usage
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Success 200 {object} usage.CurrentUsageOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/usage/current [GET]
+func (s UsageService) GetCurrentUsage(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetCurrentUsage)
+}
+
+// GetUsageHistory godoc
+// @Summary Get usage history
+// @Description Get the organization's monthly usage records, oldest first
+// @Tags Usage
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Organization ID"
+// @Param months query int false "Number of months including the current one, 1 to 24 (default: 6)"
+// @Success 200 {object} usage.UsageHistoryOut
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 403 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/organizations/{id}/usage/history [GET]
+func (s UsageService) GetUsageHistory(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetUsageHistory)
+}
@@ -43,6 +43,72 @@ func (s UserService) UpdateProfile(r *gin.RouterGroup, route string) {
 	r.PUT("/"+s.Route+"/"+route, s.Controller.UpdateProfile)
 }
 
+// RequestEmailChange godoc
+// @Summary Request email change
+// @Description Send a confirmation link to the new email address and a security notice to the current one. The email is changed only after confirmation.
+// @Tags User Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param request body user.ChangeEmailRequest true "New email and current password"
+// @Success 200 {object} inout.BaseResponse
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Router /api/v1/users/email/change [POST]
+func (s UserService) RequestEmailChange(r *gin.RouterGroup, route string) {
+	r.POST("/"+s.Route+"/"+route, s.Controller.RequestEmailChange)
+}
+
+// GetSessions godoc
+// @Summary Get active sessions
+// @Description Get the current user's active sessions, flagging the one used by this request
+// @Tags User Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Success 200 {object} user.SessionsOut
+// @Failure 401 {object} inout.BaseResponse
+// @Router /api/v1/users/sessions [GET]
+func (s UserService) GetSessions(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetSessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke session
+// @Description Revoke one of the current user's sessions; tokens issued for it stop working immediately
+// @Tags User Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param id path string true "Session ID"
+// @Success 200 {object} inout.BaseResponse
+// @Failure 400 {object} inout.BaseResponse
+// @Failure 401 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
+// @Router /api/v1/users/sessions/{id} [DELETE]
+func (s UserService) RevokeSession(r *gin.RouterGroup, route string) {
+	r.DELETE("/"+s.Route+"/"+route+"/:id", s.Controller.RevokeSession)
+}
+
+// GetLoginHistory godoc
+// @Summary Get login history
+// @Description Get the current user's recent sign-in attempts with IP, user agent, method and outcome
+// @Tags User Management
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param page query int false "Page number (0-based)"
+// @Success 200 {object} user.LoginHistoryOut
+// @Failure 401 {object} inout.BaseResponse
+// @Router /api/v1/users/login-history [GET]
+func (s UserService) GetLoginHistory(r *gin.RouterGroup, route string) {
+	r.GET("/"+s.Route+"/"+route, s.Controller.GetLoginHistory)
+}
+
 // DeleteAccount godoc
 // @Summary Delete user account
 // @Description Delete current user's account permanently
@@ -75,13 +141,15 @@ func (s UserService) GetDashboard(r *gin.RouterGroup, route string) {
 
 // GetNotifications godoc
 // @Summary Get user notifications
-// @Description Get list of user notifications
+// @Description Get the user's notifications, such as usage alerts of their organizations, newest first
 // @Tags User Management
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param Authorization header string true "Bearer token" format(Bearer {token})
+// @Param page query int false "Page number (default: 0)"
 // @Success 200 {object} user.NotificationsOut
+// @Failure 400 {object} inout.BaseResponse
 // @Failure 401 {object} inout.BaseResponse
 // @Router /api/v1/users/notifications [GET]
 func (s UserService) GetNotifications(r *gin.RouterGroup, route string) {
@@ -100,6 +168,7 @@ func (s UserService) GetNotifications(r *gin.RouterGroup, route string) {
 // @Success 200 {object} inout.BaseResponse
 // @Failure 400 {object} inout.BaseResponse
 // @Failure 401 {object} inout.BaseResponse
+// @Failure 404 {object} inout.BaseResponse
 // @Router /api/v1/users/notifications/{id}/read [PUT]
 func (s UserService) MarkNotificationRead(r *gin.RouterGroup, route string) {
 	r.PUT("/"+s.Route+"/"+route+"/:id/read", s.Controller.MarkNotificationRead)
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WantsCSV reports whether the request asked for CSV with ?format=csv.
+func WantsCSV(context *gin.Context) bool {
+	return context.Query("format") == "csv"
+}
+
+// ServeCSV sends records as a CSV file download.
+func ServeCSV(context *gin.Context, filename string, records [][]string) {
+	var buffer bytes.Buffer
+	if err := csv.NewWriter(&buffer).WriteAll(records); err != nil {
+		ReportInternalServerError(context, "Failed to write CSV")
+		return
+	}
+	context.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	context.Data(http.StatusOK, "text/csv; charset=utf-8", buffer.Bytes())
+}
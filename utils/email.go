@@ -2,12 +2,15 @@ package utils
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testlake/dao"
 	"testlake/model"
 	"time"
@@ -26,6 +29,36 @@ func ResendEmailConfirmation(email, username string, userID uuid.UUID) error {
 	return emailService.ResendEmailConfirmation(email, username, userID)
 }
 
+func SendAccountLocked(email, username, unlockToken string, lockedUntil time.Time) error {
+	emailService := NewEmailService()
+	return emailService.SendAccountLocked(email, username, unlockToken, lockedUntil)
+}
+
+func SendEmailChangeConfirmation(newEmail, username string, userID uuid.UUID) error {
+	emailService := NewEmailService()
+	return emailService.SendEmailChangeConfirmation(newEmail, username, userID)
+}
+
+func SendEmailChangeNotice(oldEmail, username, newEmail string) error {
+	emailService := NewEmailService()
+	return emailService.SendEmailChangeNotice(oldEmail, username, newEmail)
+}
+
+// SendBillingNotice emails a billing notice about the organization to its
+// billing email, or to its creator when it has none, and to its billing
+// contacts.
+func SendBillingNotice(organizationID uuid.UUID, notice BillingNotice) error {
+	emailService := NewEmailService()
+	return emailService.SendBillingNotice(organizationID, notice)
+}
+
+// SendUsageAlert emails an owner or admin that the organization's usage of a
+// resource reached a threshold of its plan limit.
+func SendUsageAlert(email string, alert UsageAlertTemplateData) error {
+	emailService := NewEmailService()
+	return emailService.SendUsageAlert(email, alert)
+}
+
 type EmailService struct {
 	dialer *gomail.Dialer
 	from   string
@@ -44,6 +77,67 @@ type ErrorTemplateData struct {
 	Message string
 }
 
+type SuccessTemplateData struct {
+	Title   string
+	Heading string
+	Message string
+	BaseURL string
+}
+
+type EmailChangeTemplateData struct {
+	Username string
+	Token    string
+	BaseURL  string
+	NewEmail string
+}
+
+type AccountLockedTemplateData struct {
+	Username    string
+	Token       string
+	BaseURL     string
+	LockedUntil string
+}
+
+// BillingNotice is an email about the billing of an organization, such as a
+// failed payment or a suspension.
+type BillingNotice struct {
+	Subject       string
+	Heading       string
+	Message       string
+	InvoiceNumber string
+	AmountDue     string
+
+	// Set on notices about an invoice: its PDF is attached and every delivery
+	// is logged against it as Kind
+	InvoiceID *uuid.UUID
+	Kind      model.InvoiceEmailKind
+}
+
+// EmailAttachment is a file attached to an email.
+type EmailAttachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+type BillingNoticeTemplateData struct {
+	OrganizationName string
+	Heading          string
+	Message          string
+	InvoiceNumber    string
+	AmountDue        string
+	BaseURL          string
+}
+
+type UsageAlertTemplateData struct {
+	OrganizationName string
+	Resource         string
+	Threshold        int
+	Used             int
+	Limit            int
+	BaseURL          string
+}
+
 func NewEmailService() *EmailService {
 	host := os.Getenv("SMTP_HOST")
 	portStr := os.Getenv("SMTP_PORT")
@@ -75,6 +169,7 @@ func (e *EmailService) SendEmailConfirmation(email, username string, userID uuid
 	token := &model.EmailVerificationToken{
 		UserID:    userID,
 		Token:     tokenStr,
+		Purpose:   model.EmailTokenPurposeVerifyEmail,
 		ExpiresAt: time.Now().Add(24 * time.Hour), // 24 hours expiry
 		IsUsed:    false,
 	}
@@ -105,7 +200,7 @@ func (e *EmailService) ResendEmailConfirmation(email, username string, userID uu
 	tokenDao := dao.NewEmailVerificationDao()
 
 	// Invalidate any existing tokens for this user
-	if err := tokenDao.DeleteTokensForUser(userID); err != nil {
+	if err := tokenDao.DeleteTokensForUser(userID, model.EmailTokenPurposeVerifyEmail); err != nil {
 		e.logError("Failed to delete existing tokens", err, email)
 		return fmt.Errorf("failed to cleanup existing tokens: %w", err)
 	}
@@ -117,6 +212,7 @@ func (e *EmailService) ResendEmailConfirmation(email, username string, userID uu
 	token := &model.EmailVerificationToken{
 		UserID:    userID,
 		Token:     tokenStr,
+		Purpose:   model.EmailTokenPurposeVerifyEmail,
 		ExpiresAt: time.Now().Add(24 * time.Hour), // 24 hours expiry
 		IsUsed:    false,
 	}
@@ -143,12 +239,237 @@ func (e *EmailService) ResendEmailConfirmation(email, username string, userID uu
 	return e.sendEmail(email, subject, body)
 }
 
-func (e *EmailService) sendEmail(to, subject, body string) error {
+func (e *EmailService) SendEmailChangeConfirmation(newEmail, username string, userID uuid.UUID) error {
+	tokenDao := dao.NewEmailVerificationDao()
+
+	// Only the most recent change request can be confirmed
+	if err := tokenDao.DeleteTokensForUser(userID, model.EmailTokenPurposeChangeEmail); err != nil {
+		e.logError("Failed to delete existing email change tokens", err, newEmail)
+		return fmt.Errorf("failed to cleanup existing tokens: %w", err)
+	}
+
+	tokenStr := uuid.New().String()
+
+	token := &model.EmailVerificationToken{
+		UserID:    userID,
+		Token:     tokenStr,
+		Purpose:   model.EmailTokenPurposeChangeEmail,
+		NewEmail:  &newEmail,
+		ExpiresAt: time.Now().Add(24 * time.Hour), // 24 hours expiry
+		IsUsed:    false,
+	}
+
+	if err := tokenDao.Create(token); err != nil {
+		e.logError("Failed to create email change token", err, newEmail)
+		return fmt.Errorf("failed to create email change token: %w", err)
+	}
+
+	subject := "TestLake - Confirm Your New Email Address"
+
+	data := EmailChangeTemplateData{
+		Username: username,
+		Token:    tokenStr,
+		BaseURL:  e.getBaseURL(),
+		NewEmail: newEmail,
+	}
+
+	body, err := e.loadTemplate("email_change_confirmation.html", data)
+	if err != nil {
+		e.logError("Failed to load email change confirmation template", err, newEmail)
+		return fmt.Errorf("failed to load email template: %w", err)
+	}
+
+	return e.sendEmail(newEmail, subject, body)
+}
+
+func (e *EmailService) SendEmailChangeNotice(oldEmail, username, newEmail string) error {
+	subject := "TestLake - Email Change Requested on Your Account"
+
+	data := EmailChangeTemplateData{
+		Username: username,
+		BaseURL:  e.getBaseURL(),
+		NewEmail: newEmail,
+	}
+
+	body, err := e.loadTemplate("email_change_notice.html", data)
+	if err != nil {
+		e.logError("Failed to load email change notice template", err, oldEmail)
+		return fmt.Errorf("failed to load email template: %w", err)
+	}
+
+	return e.sendEmail(oldEmail, subject, body)
+}
+
+func (e *EmailService) SendAccountLocked(email, username, unlockToken string, lockedUntil time.Time) error {
+	subject := "TestLake - Your Account Has Been Temporarily Locked"
+
+	data := AccountLockedTemplateData{
+		Username:    username,
+		Token:       unlockToken,
+		BaseURL:     e.getBaseURL(),
+		LockedUntil: lockedUntil.UTC().Format("January 2, 2006 15:04 MST"),
+	}
+
+	body, err := e.loadTemplate("account_locked.html", data)
+	if err != nil {
+		e.logError("Failed to load account locked template", err, email)
+		return fmt.Errorf("failed to load email template: %w", err)
+	}
+
+	return e.sendEmail(email, subject, body)
+}
+
+func (e *EmailService) SendBillingNotice(organizationID uuid.UUID, notice BillingNotice) error {
+	org, err := dao.NewOrganizationDao().GetByID(organizationID)
+	if err != nil {
+		return fmt.Errorf("failed to load organization: %w", err)
+	}
+
+	recipients, err := billingRecipients(org)
+	if err != nil {
+		return err
+	}
+	subject := "TestLake - " + notice.Subject
+
+	data := BillingNoticeTemplateData{
+		OrganizationName: org.Name,
+		Heading:          notice.Heading,
+		Message:          notice.Message,
+		InvoiceNumber:    notice.InvoiceNumber,
+		AmountDue:        notice.AmountDue,
+		BaseURL:          e.getBaseURL(),
+	}
+
+	body, err := e.loadTemplate("billing_notice.html", data)
+	if err != nil {
+		e.logError("Failed to load billing notice template", err, strings.Join(recipients, ", "))
+		err = fmt.Errorf("failed to load email template: %w", err)
+		for _, recipient := range recipients {
+			recordInvoiceDelivery(org.ID, notice, subject, recipient, err)
+		}
+		return err
+	}
+
+	var attachments []EmailAttachment
+	if notice.InvoiceID != nil {
+		attachment, err := invoiceAttachment(*notice.InvoiceID)
+		if err != nil {
+			err = fmt.Errorf("failed to render invoice: %w", err)
+			for _, recipient := range recipients {
+				recordInvoiceDelivery(org.ID, notice, subject, recipient, err)
+			}
+			return err
+		}
+		attachments = append(attachments, *attachment)
+	}
+
+	// One email per recipient, so that a rejected address does not hold back the others
+	var failures []error
+	for _, recipient := range recipients {
+		err := e.sendEmail(recipient, subject, body, attachments...)
+		recordInvoiceDelivery(org.ID, notice, subject, recipient, err)
+		if err != nil {
+			failures = append(failures, err)
+		}
+	}
+	return errors.Join(failures...)
+}
+
+// billingRecipients are the billing email of the organization, or its creator
+// when it has none, followed by its billing contacts, each address once.
+func billingRecipients(org *model.Organization) ([]string, error) {
+	primary := ""
+	if org.BillingEmail != nil {
+		primary = strings.TrimSpace(*org.BillingEmail)
+	}
+	if primary == "" {
+		creator, err := dao.NewUserDao().GetByID(org.CreatedBy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load organization creator: %w", err)
+		}
+		primary = creator.Email
+	}
+
+	contacts, err := dao.NewBillingContactDao().GetByOrganizationID(org.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load billing contacts: %w", err)
+	}
+	recipients := []string{primary}
+	seen := map[string]bool{strings.ToLower(primary): true}
+	for _, contact := range contacts {
+		if address := strings.ToLower(contact.Email); !seen[address] {
+			seen[address] = true
+			recipients = append(recipients, contact.Email)
+		}
+	}
+	return recipients, nil
+}
+
+func invoiceAttachment(invoiceID uuid.UUID) (*EmailAttachment, error) {
+	invoice, err := dao.NewInvoiceDao().GetByID(invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	document, err := InvoiceDocument(invoice)
+	if err != nil {
+		return nil, err
+	}
+	return &EmailAttachment{Filename: document.Filename, ContentType: "application/pdf", Content: document.Content}, nil
+}
+
+// recordInvoiceDelivery logs the attempt at emailing a notice about an invoice
+// to a recipient. Failing to log it never fails the email.
+func recordInvoiceDelivery(organizationID uuid.UUID, notice BillingNotice, subject, recipient string, sendErr error) {
+	if notice.InvoiceID == nil {
+		return
+	}
+	delivery := &model.InvoiceDelivery{
+		InvoiceID:      *notice.InvoiceID,
+		OrganizationID: organizationID,
+		Kind:           notice.Kind,
+		Recipient:      recipient,
+		Subject:        subject,
+		Status:         model.InvoiceDeliveryStatusSent,
+		AttemptedAt:    time.Now(),
+	}
+	if sendErr != nil {
+		reason := sendErr.Error()
+		delivery.Status = model.InvoiceDeliveryStatusFailed
+		delivery.Error = &reason
+	}
+	if err := dao.NewInvoiceDao().RecordDelivery(delivery); err != nil {
+		log.Printf("Failed to log delivery of invoice %s to %s: %v", *notice.InvoiceID, recipient, err)
+	}
+}
+
+func (e *EmailService) SendUsageAlert(email string, alert UsageAlertTemplateData) error {
+	alert.BaseURL = e.getBaseURL()
+
+	body, err := e.loadTemplate("usage_alert.html", alert)
+	if err != nil {
+		e.logError("Failed to load usage alert template", err, email)
+		return fmt.Errorf("failed to load email template: %w", err)
+	}
+
+	subject := fmt.Sprintf("TestLake - %s has used %d%% of its %s limit", alert.OrganizationName, alert.Threshold, alert.Resource)
+	return e.sendEmail(email, subject, body)
+}
+
+func (e *EmailService) sendEmail(to, subject, body string, attachments ...EmailAttachment) error {
 	message := gomail.NewMessage()
 	message.SetHeader("From", message.FormatAddress(e.from, e.name))
 	message.SetHeader("To", to)
 	message.SetHeader("Subject", subject)
 	message.SetBody("text/html", body)
+	for _, attachment := range attachments {
+		content := attachment.Content
+		message.Attach(attachment.Filename,
+			gomail.SetHeader(map[string][]string{"Content-Type": {attachment.ContentType}}),
+			gomail.SetCopyFunc(func(w io.Writer) error {
+				_, err := w.Write(content)
+				return err
+			}))
+	}
 
 	if err := e.dialer.DialAndSend(message); err != nil {
 		e.logError("Failed to send email", err, to)
@@ -158,7 +479,7 @@ func (e *EmailService) sendEmail(to, subject, body string) error {
 	return nil
 }
 
-func (e *EmailService) loadTemplate(templateName string, data EmailTemplateData) (string, error) {
+func (e *EmailService) loadTemplate(templateName string, data interface{}) (string, error) {
 	templatePath := filepath.Join("templates", templateName)
 
 	tmpl, err := template.ParseFiles(templatePath)
@@ -214,6 +535,24 @@ func RenderEmailVerifiedSuccess(baseURL string) (string, error) {
 	return body, nil
 }
 
+func RenderActionSuccess(title, heading, message string) (string, error) {
+	emailService := NewEmailService()
+
+	data := SuccessTemplateData{
+		Title:   title,
+		Heading: heading,
+		Message: message,
+		BaseURL: GetBaseURL(),
+	}
+
+	body, err := emailService.loadTemplate("action_success.html", data)
+	if err != nil {
+		return "", fmt.Errorf("failed to load success template: %w", err)
+	}
+
+	return body, nil
+}
+
 func RenderEmailVerificationError(title, heading, message string) (string, error) {
 	templatePath := filepath.Join("templates", "email_verification_error.html")
 
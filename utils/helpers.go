@@ -5,6 +5,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"os"
+	"unicode/utf8"
 )
 
 func GetBaseURL() string {
@@ -34,3 +35,14 @@ func GenerateSecureToken(length int) (string, error) {
 	}
 	return hex.EncodeToString(bytes), nil
 }
+
+// TruncateString shortens s to at most max bytes without splitting a UTF-8 character
+func TruncateString(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
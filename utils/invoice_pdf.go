@@ -0,0 +1,428 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"testlake/dao"
+	"testlake/model"
+
+	"github.com/jung-kurt/gofpdf"
+	"gorm.io/gorm"
+)
+
+// InvoiceBrand is what invoice PDFs show of the company issuing them. It is
+// read from INVOICE_TEMPLATE_PATH, templates/invoice.json by default.
+type InvoiceBrand struct {
+	CompanyName  string   `json:"company_name"`
+	AddressLines []string `json:"address_lines"`
+	Email        string   `json:"email"`
+	Website      string   `json:"website"`
+	TaxID        string   `json:"tax_id"`
+	// LogoPath is a PNG or JPEG drawn instead of the company name, relative to the working directory
+	LogoPath    string `json:"logo_path"`
+	AccentColor string `json:"accent_color"` // hex, e.g. #2c3e50
+	Footer      string `json:"footer"`
+}
+
+var defaultInvoiceBrand = InvoiceBrand{
+	CompanyName: "TestLake",
+	AccentColor: "#2c3e50",
+	Footer:      "Thank you for your business.",
+}
+
+// LoadInvoiceBrand reads the invoice template, falling back to a plain
+// TestLake brand when there is none.
+func LoadInvoiceBrand() (InvoiceBrand, error) {
+	path := os.Getenv("INVOICE_TEMPLATE_PATH")
+	if path == "" {
+		path = filepath.Join("templates", "invoice.json")
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return defaultInvoiceBrand, nil
+	}
+	if err != nil {
+		return InvoiceBrand{}, fmt.Errorf("failed to read invoice template: %w", err)
+	}
+
+	brand := defaultInvoiceBrand
+	if err := json.Unmarshal(content, &brand); err != nil {
+		return InvoiceBrand{}, fmt.Errorf("failed to parse invoice template %s: %w", path, err)
+	}
+	return brand, nil
+}
+
+// InvoiceDocument returns the PDF of the invoice, which must be loaded with its
+// line items and organization. The stored PDF is served while neither changed;
+// once the invoice is paid its PDF is final and served as is.
+func InvoiceDocument(invoice *model.Invoice) (*model.InvoiceDocument, error) {
+	invoiceDao := dao.NewInvoiceDao()
+	version := invoice.UpdatedAt
+	if invoice.Organization.UpdatedAt.After(version) {
+		version = invoice.Organization.UpdatedAt
+	}
+
+	cached, err := invoiceDao.GetDocument(invoice.ID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if cached != nil && (cached.Final || cached.InvoiceUpdatedAt.Equal(version)) {
+		return cached, nil
+	}
+
+	brand, err := LoadInvoiceBrand()
+	if err != nil {
+		return nil, err
+	}
+	content, err := RenderInvoicePDF(invoice, brand)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha256.Sum256(content)
+	document := &model.InvoiceDocument{
+		InvoiceID:        invoice.ID,
+		Filename:         invoice.PDFFilename(),
+		Content:          content,
+		Checksum:         hex.EncodeToString(checksum[:]),
+		Final:            invoice.IsPaid(),
+		InvoiceUpdatedAt: version,
+	}
+	if err := invoiceDao.SaveDocument(document); err != nil {
+		return nil, err
+	}
+
+	// Another request may have stored a final document first; that one wins
+	return invoiceDao.GetDocument(invoice.ID)
+}
+
+// CreditNoteDocument returns the PDF of an issued credit note, which must be
+// loaded with its organization and invoice. It is rendered once and served as
+// is from then on.
+func CreditNoteDocument(creditNote *model.CreditNote) ([]byte, string, error) {
+	if creditNote.PDFChecksum != nil {
+		return creditNote.PDFContent, *creditNote.PDFChecksum, nil
+	}
+
+	brand, err := LoadInvoiceBrand()
+	if err != nil {
+		return nil, "", err
+	}
+	content, err := RenderCreditNotePDF(creditNote, brand)
+	if err != nil {
+		return nil, "", err
+	}
+	sum := sha256.Sum256(content)
+	checksum := hex.EncodeToString(sum[:])
+
+	creditNoteDao := dao.NewCreditNoteDao()
+	if err := creditNoteDao.SaveDocument(creditNote.ID, content, checksum); err != nil {
+		return nil, "", err
+	}
+	// Another request may have stored its PDF first; that one wins
+	stored, err := creditNoteDao.GetByID(creditNote.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	return stored.PDFContent, *stored.PDFChecksum, nil
+}
+
+// billingDocument is what an invoice or credit note PDF shows.
+type billingDocument struct {
+	Title        string
+	Number       string
+	Status       string
+	Organization model.Organization
+	Dates        [][2]string
+	Lines        []documentLine
+	Totals       [][2]string
+	Total        int64
+	Notes        []string
+	Currency     string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type documentLine struct {
+	Description string
+	Quantity    int
+	UnitPrice   int64
+	Amount      int64
+}
+
+// RenderInvoicePDF renders the invoice, with its line items and organization,
+// as a PDF in the given brand.
+func RenderInvoicePDF(invoice *model.Invoice, brand InvoiceBrand) ([]byte, error) {
+	document := billingDocument{
+		Title:        "Invoice",
+		Number:       invoice.InvoiceNumber,
+		Status:       string(invoice.Status),
+		Organization: invoice.Organization,
+		Dates:        [][2]string{{"Issue date", formatDate(&invoice.CreatedAt)}},
+		Totals: [][2]string{
+			{"Subtotal", formatMoney(invoice.Amount, invoice.Currency)},
+			{taxLabel(invoice), formatMoney(invoice.TaxAmount, invoice.Currency)},
+		},
+		Total:     invoice.TotalAmount,
+		Currency:  invoice.Currency,
+		CreatedAt: invoice.CreatedAt,
+		UpdatedAt: invoice.UpdatedAt,
+	}
+	if invoice.DueDate != nil {
+		document.Dates = append(document.Dates, [2]string{"Due date", formatDate(invoice.DueDate)})
+	}
+	if invoice.BillingPeriodStart != nil && invoice.BillingPeriodEnd != nil {
+		document.Dates = append(document.Dates, [2]string{"Billing period", formatDate(invoice.BillingPeriodStart) + " - " + formatDate(invoice.BillingPeriodEnd)})
+	}
+	if invoice.PaidAt != nil {
+		document.Dates = append(document.Dates, [2]string{"Paid on", formatDate(invoice.PaidAt)})
+	}
+	for _, item := range invoice.LineItems {
+		document.Lines = append(document.Lines, documentLine{Description: item.Description, Quantity: item.Quantity, UnitPrice: item.UnitPrice, Amount: item.TotalPrice})
+	}
+	if invoice.ReverseCharge {
+		document.Notes = append(document.Notes, "Reverse charge: VAT to be accounted for by the recipient.")
+	}
+	return renderBillingDocument(document, brand)
+}
+
+// RenderCreditNotePDF renders the credit note, with its organization and
+// invoice, as a PDF in the given brand.
+func RenderCreditNotePDF(creditNote *model.CreditNote, brand InvoiceBrand) ([]byte, error) {
+	description := "Refund of invoice " + creditNote.Invoice.InvoiceNumber
+	if creditNote.Reason != nil && *creditNote.Reason != "" {
+		description += ": " + *creditNote.Reason
+	}
+	document := billingDocument{
+		Title:        "Credit note",
+		Number:       creditNote.CreditNoteNumber,
+		Status:       string(creditNote.Status),
+		Organization: creditNote.Organization,
+		Dates: [][2]string{
+			{"Issue date", formatDate(&creditNote.CreatedAt)},
+			{"Original invoice", creditNote.Invoice.InvoiceNumber},
+		},
+		Lines:     []documentLine{{Description: description, Quantity: 1, UnitPrice: -creditNote.Amount, Amount: -creditNote.Amount}},
+		Total:     -creditNote.Amount,
+		Currency:  creditNote.Currency,
+		CreatedAt: creditNote.CreatedAt,
+		UpdatedAt: creditNote.UpdatedAt,
+	}
+	if creditNote.IssuedAt != nil {
+		document.Dates = append(document.Dates, [2]string{"Refunded on", formatDate(creditNote.IssuedAt)})
+	}
+	return renderBillingDocument(document, brand)
+}
+
+func renderBillingDocument(document billingDocument, brand InvoiceBrand) ([]byte, error) {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTitle(document.Title+" "+document.Number, true)
+	pdf.SetAuthor(brand.CompanyName, true)
+	// The same document renders the same bytes
+	pdf.SetCreationDate(document.CreatedAt)
+	pdf.SetModificationDate(document.UpdatedAt)
+	pdf.SetCatalogSort(true)
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 25)
+	text := pdf.UnicodeTranslatorFromDescriptor("")
+	red, green, blue := hexColor(brand.AccentColor)
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-18)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 4, text(brand.Footer), "", 1, "C", false, 0, "")
+		pdf.CellFormat(0, 4, fmt.Sprintf("%s - page %d", document.Number, pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	// Header: brand on the left, document number and status on the right
+	if brand.LogoPath != "" {
+		pdf.ImageOptions(brand.LogoPath, 20, 18, 0, 14, false, gofpdf.ImageOptions{ReadDpi: true}, 0, "")
+	} else {
+		pdf.SetFont("Helvetica", "B", 20)
+		pdf.SetTextColor(red, green, blue)
+		pdf.Text(20, 28, text(brand.CompanyName))
+	}
+	pdf.SetXY(110, 18)
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.SetTextColor(red, green, blue)
+	pdf.CellFormat(80, 8, strings.ToUpper(document.Title), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.SetTextColor(60, 60, 60)
+	pdf.CellFormat(80, 5, text(document.Number), "", 2, "R", false, 0, "")
+	pdf.CellFormat(80, 5, strings.ToUpper(strings.ReplaceAll(document.Status, "_", " ")), "", 2, "R", false, 0, "")
+
+	// Issuer and customer
+	pdf.SetY(45)
+	top := pdf.GetY()
+	writeParty(pdf, text, 20, top, "From", brand.CompanyName, append(append([]string{}, brand.AddressLines...),
+		nonEmpty(brand.Email, brand.Website, taxIDLine(brand.TaxID))...))
+	bottom := pdf.GetY()
+
+	org := document.Organization
+	customerName := org.Name
+	if org.BillingName != nil {
+		customerName = *org.BillingName
+	}
+	customer := billingAddressLines(&org)
+	if org.BillingEmail != nil && *org.BillingEmail != "" {
+		customer = append(customer, *org.BillingEmail)
+	}
+	if org.TaxID != nil {
+		customer = append(customer, taxIDLine(*org.TaxID))
+	}
+	writeParty(pdf, text, 110, top, "Bill to", customerName, customer)
+	if pdf.GetY() > bottom {
+		bottom = pdf.GetY()
+	}
+
+	// Dates
+	pdf.SetY(bottom + 8)
+	for _, date := range document.Dates {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(35, 5, date[0], "", 0, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(40, 40, 40)
+		pdf.CellFormat(0, 5, text(date[1]), "", 1, "L", false, 0, "")
+	}
+
+	// Lines
+	pdf.Ln(8)
+	widths := []float64{95, 15, 30, 30}
+	pdf.SetFont("Helvetica", "B", 9)
+	pdf.SetFillColor(red, green, blue)
+	pdf.SetTextColor(255, 255, 255)
+	for i, heading := range []string{"Description", "Qty", "Unit price", "Amount"} {
+		align := "R"
+		if i == 0 {
+			align = "L"
+		}
+		pdf.CellFormat(widths[i], 8, heading, "", 0, align, true, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.SetTextColor(40, 40, 40)
+	pdf.SetDrawColor(225, 225, 225)
+	for _, line := range document.Lines {
+		pdf.CellFormat(widths[0], 8, text(line.Description), "B", 0, "L", false, 0, "")
+		pdf.CellFormat(widths[1], 8, strconv.Itoa(line.Quantity), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[2], 8, formatMoney(line.UnitPrice, document.Currency), "B", 0, "R", false, 0, "")
+		pdf.CellFormat(widths[3], 8, formatMoney(line.Amount, document.Currency), "B", 1, "R", false, 0, "")
+	}
+
+	// Totals
+	pdf.Ln(4)
+	for _, total := range document.Totals {
+		pdf.SetX(110)
+		pdf.CellFormat(50, 6, total[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(30, 6, total[1], "", 1, "R", false, 0, "")
+	}
+	pdf.SetX(110)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.SetTextColor(red, green, blue)
+	pdf.CellFormat(50, 9, "Total ("+document.Currency+")", "T", 0, "R", false, 0, "")
+	pdf.CellFormat(30, 9, formatMoney(document.Total, document.Currency), "T", 1, "R", false, 0, "")
+
+	if len(document.Notes) > 0 {
+		pdf.Ln(6)
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.SetTextColor(60, 60, 60)
+		for _, note := range document.Notes {
+			pdf.MultiCell(0, 5, text(note), "", "L", false)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := pdf.Output(&out); err != nil {
+		return nil, fmt.Errorf("failed to render %s %s: %w", strings.ToLower(document.Title), document.Number, err)
+	}
+	return out.Bytes(), nil
+}
+
+// writeParty writes a titled block of name and detail lines at x, y.
+func writeParty(pdf *gofpdf.Fpdf, text func(string) string, x, y float64, title, name string, lines []string) {
+	pdf.SetXY(x, y)
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.SetTextColor(120, 120, 120)
+	pdf.CellFormat(80, 5, strings.ToUpper(title), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.SetTextColor(40, 40, 40)
+	pdf.CellFormat(80, 5, text(name), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range lines {
+		pdf.CellFormat(80, 4.5, text(line), "", 2, "L", false, 0, "")
+	}
+}
+
+// billingAddressLines is the organization's billing address, one line each for
+// the street, the city and the state and country.
+func billingAddressLines(org *model.Organization) []string {
+	value := func(field *string) string {
+		if field == nil {
+			return ""
+		}
+		return *field
+	}
+	return nonEmpty(
+		value(org.BillingAddressLine1),
+		value(org.BillingAddressLine2),
+		strings.TrimSpace(value(org.BillingPostalCode)+" "+value(org.BillingCity)),
+		strings.Trim(value(org.BillingState)+", "+value(org.BillingCountry), ", "),
+	)
+}
+
+// taxLabel names the invoice's tax, e.g. "VAT 19% (DE)".
+func taxLabel(invoice *model.Invoice) string {
+	if invoice.TaxDescription == "" {
+		return "Tax"
+	}
+	return invoice.TaxDescription
+}
+
+func taxIDLine(taxID string) string {
+	if taxID == "" {
+		return ""
+	}
+	return "Tax ID: " + taxID
+}
+
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, value := range values {
+		if value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func formatDate(t *time.Time) string {
+	return t.Format("January 2, 2006")
+}
+
+func formatMoney(amount int64, currency string) string {
+	return model.FormatMinorUnits(amount, currency) + " " + currency
+}
+
+// hexColor parses #rrggbb, falling back to a dark grey.
+func hexColor(value string) (int, int, int) {
+	rgb, err := strconv.ParseUint(strings.TrimPrefix(value, "#"), 16, 32)
+	if err != nil || len(strings.TrimPrefix(value, "#")) != 6 {
+		return 44, 62, 80
+	}
+	return int(rgb >> 16 & 0xff), int(rgb >> 8 & 0xff), int(rgb & 0xff)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"testlake/dao"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,32 +14,51 @@ import (
 )
 
 type Claims struct {
-	UserID   uuid.UUID `json:"user_id"`
-	Email    string    `json:"email"`
-	Username string    `json:"username"`
+	UserID    uuid.UUID `json:"user_id"`
+	Email     string    `json:"email"`
+	Username  string    `json:"username"`
+	SessionID uuid.UUID `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID uuid.UUID, email, username string) (string, error) {
+// TokenTTL returns the lifetime of issued tokens from TOKEN_TTL (minutes)
+func TokenTTL() time.Duration {
 	tokenTTLStr := os.Getenv("TOKEN_TTL")
 	tokenTTL, err := strconv.Atoi(tokenTTLStr)
 	if err != nil {
 		tokenTTL = 2000 // default 2000 minutes
 	}
+	return time.Duration(tokenTTL) * time.Minute
+}
 
+// GenerateJWT issues a token for the user. sessionID links the token to a
+// UserSession so it can be revoked; uuid.Nil issues a session-less token.
+func GenerateJWT(userID uuid.UUID, email, username string, sessionID uuid.UUID) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Email:    email,
-		Username: username,
+		UserID:    userID,
+		Email:     email,
+		Username:  username,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Duration(tokenTTL) * time.Minute)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TokenTTL())),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	secretKey := os.Getenv("JWT_PRIVATE_KEY")
-	return token.SignedString([]byte(secretKey))
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		claims.Issuer = issuer
+	}
+
+	keySet, err := currentJWTKeys()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(keySet.active.Method, claims)
+	if keySet.active.ID != "" {
+		token.Header["kid"] = keySet.active.ID
+	}
+	return token.SignedString(keySet.active.signingKey)
 }
 
 func ValidateJWT(c *gin.Context) error {
@@ -47,10 +67,17 @@ func ValidateJWT(c *gin.Context) error {
 		return errors.New("authorization token required")
 	}
 
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		secretKey := os.Getenv("JWT_PRIVATE_KEY")
-		return []byte(secretKey), nil
-	})
+	keySet, err := currentJWTKeys()
+	if err != nil {
+		return errors.New("token verification unavailable")
+	}
+
+	var options []jwt.ParserOption
+	if issuer := os.Getenv("JWT_ISSUER"); issuer != "" {
+		options = append(options, jwt.WithIssuer(issuer))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keySet.lookupVerificationKey, options...)
 
 	if err != nil {
 		return errors.New("invalid token")
@@ -66,6 +93,22 @@ func ValidateJWT(c *gin.Context) error {
 		return errors.New("token has expired")
 	}
 
+	// Tokens bound to a session stop working as soon as the session is revoked
+	if claims.SessionID != uuid.Nil {
+		sessionDao := dao.NewUserSessionDao()
+		session, err := sessionDao.GetByID(claims.SessionID)
+		if err != nil || session.UserID != claims.UserID || !session.IsActive(time.Now()) {
+			return errors.New("session has been revoked")
+		}
+
+		// Only record activity once a minute to avoid a write on every request
+		if time.Since(session.LastSeenAt) > time.Minute {
+			sessionDao.Touch(session.ID, nil)
+		}
+
+		c.Set("session_id", claims.SessionID)
+	}
+
 	c.Set("user_id", claims.UserID)
 	c.Set("email", claims.Email)
 	c.Set("username", claims.Username)
@@ -87,6 +130,21 @@ func ExtractToken(c *gin.Context) string {
 	return ""
 }
 
+// ExtractSessionID returns the session of the current token, or uuid.Nil for session-less tokens
+func ExtractSessionID(c *gin.Context) uuid.UUID {
+	sessionID, exists := c.Get("session_id")
+	if !exists {
+		return uuid.Nil
+	}
+
+	sid, ok := sessionID.(uuid.UUID)
+	if !ok {
+		return uuid.Nil
+	}
+
+	return sid
+}
+
 func ExtractUserID(c *gin.Context) (uuid.UUID, error) {
 	userID, exists := c.Get("user_id")
 	if !exists {
@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWTKey is a signing or verification key identified by its kid.
+type JWTKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	signingKey interface{}
+	verifyKey  interface{}
+}
+
+// CanSign reports whether the key holds private material.
+func (k *JWTKey) CanSign() bool {
+	return k.signingKey != nil
+}
+
+// JSONWebKey is the public representation of a verification key (RFC 7517).
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"`
+	X         string `json:"x,omitempty"`
+	N         string `json:"n,omitempty"`
+	E         string `json:"e,omitempty"`
+}
+
+type jwtKeySet struct {
+	active *JWTKey
+	keys   map[string]*JWTKey
+	legacy *JWTKey
+}
+
+var (
+	jwtKeysMutex sync.RWMutex
+	jwtKeys      *jwtKeySet
+)
+
+// InitJWTKeys loads the key set from the environment. It is called at start-up
+// so that a misconfigured key fails fast instead of on the first sign-in.
+func InitJWTKeys() error {
+	return ReloadJWTKeys()
+}
+
+// ReloadJWTKeys re-reads the key configuration, which allows keys to be rotated
+// without restarting the server. The previous set stays in place on error.
+func ReloadJWTKeys() error {
+	keySet, err := loadJWTKeySet()
+	if err != nil {
+		return err
+	}
+
+	jwtKeysMutex.Lock()
+	jwtKeys = keySet
+	jwtKeysMutex.Unlock()
+	return nil
+}
+
+func currentJWTKeys() (*jwtKeySet, error) {
+	jwtKeysMutex.RLock()
+	keySet := jwtKeys
+	jwtKeysMutex.RUnlock()
+	if keySet != nil {
+		return keySet, nil
+	}
+
+	if err := ReloadJWTKeys(); err != nil {
+		return nil, err
+	}
+
+	jwtKeysMutex.RLock()
+	defer jwtKeysMutex.RUnlock()
+	return jwtKeys, nil
+}
+
+// loadJWTKeySet builds the key set:
+//   - JWT_SIGNING_KEY_ID / JWT_SIGNING_KEY_FILE: the active RSA or Ed25519 private key (PEM)
+//   - JWT_VERIFICATION_KEYS: comma separated kid=path pairs of public keys still accepted
+//   - JWT_PRIVATE_KEY: shared HS256 secret, used for signing only when no private key
+//     file is configured, and for verifying tokens without a kid while
+//     JWT_LEGACY_HS256_VERIFY is true
+func loadJWTKeySet() (*jwtKeySet, error) {
+	keySet := &jwtKeySet{keys: map[string]*JWTKey{}}
+
+	legacySecret := os.Getenv("JWT_PRIVATE_KEY")
+	signingKeyFile := os.Getenv("JWT_SIGNING_KEY_FILE")
+
+	if signingKeyFile != "" {
+		kid := os.Getenv("JWT_SIGNING_KEY_ID")
+		if kid == "" {
+			return nil, errors.New("JWT_SIGNING_KEY_ID is required when JWT_SIGNING_KEY_FILE is set")
+		}
+
+		key, err := loadPrivateJWTKey(kid, signingKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keySet.active = key
+		keySet.keys[kid] = key
+
+		if legacySecret != "" && os.Getenv("JWT_LEGACY_HS256_VERIFY") == "true" {
+			keySet.legacy = &JWTKey{Method: jwt.SigningMethodHS256, verifyKey: []byte(legacySecret)}
+		}
+	} else {
+		if legacySecret == "" {
+			return nil, errors.New("either JWT_SIGNING_KEY_FILE or JWT_PRIVATE_KEY must be set")
+		}
+		keySet.legacy = &JWTKey{
+			Method:     jwt.SigningMethodHS256,
+			signingKey: []byte(legacySecret),
+			verifyKey:  []byte(legacySecret),
+		}
+		keySet.active = keySet.legacy
+	}
+
+	for _, entry := range strings.Split(os.Getenv("JWT_VERIFICATION_KEYS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kid, path, found := strings.Cut(entry, "=")
+		if !found || kid == "" || path == "" {
+			return nil, fmt.Errorf("invalid JWT_VERIFICATION_KEYS entry %q, expected kid=path", entry)
+		}
+		if _, exists := keySet.keys[kid]; exists {
+			return nil, fmt.Errorf("duplicate JWT key id %q", kid)
+		}
+
+		key, err := loadPublicJWTKey(kid, path)
+		if err != nil {
+			return nil, err
+		}
+		keySet.keys[kid] = key
+	}
+
+	return keySet, nil
+}
+
+func loadPrivateJWTKey(kid, path string) (*JWTKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT signing key %q: %w", kid, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &JWTKey{ID: kid, Method: jwt.SigningMethodRS256, signingKey: key, verifyKey: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &JWTKey{ID: kid, Method: jwt.SigningMethodEdDSA, signingKey: key, verifyKey: key.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT signing key type for %q, use RSA or Ed25519", kid)
+	}
+}
+
+func loadPublicJWTKey(kid, path string) (*JWTKey, error) {
+	block, err := readPEMBlock(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT verification key %q: %w", kid, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PublicKey:
+		return &JWTKey{ID: kid, Method: jwt.SigningMethodRS256, verifyKey: key}, nil
+	case ed25519.PublicKey:
+		return &JWTKey{ID: kid, Method: jwt.SigningMethodEdDSA, verifyKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT verification key type for %q, use RSA or Ed25519", kid)
+	}
+}
+
+func readPEMBlock(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key file %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found in JWT key file %s", path)
+	}
+	return block, nil
+}
+
+// lookupVerificationKey resolves the key for a token, refusing any algorithm
+// other than the one the key was configured for.
+func (ks *jwtKeySet) lookupVerificationKey(token *jwt.Token) (interface{}, error) {
+	var key *JWTKey
+
+	kid, hasKid := token.Header["kid"].(string)
+	if hasKid && kid != "" {
+		key = ks.keys[kid]
+	} else {
+		key = ks.legacy
+	}
+
+	if key == nil {
+		return nil, errors.New("unknown signing key")
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, errors.New("unexpected signing method")
+	}
+
+	return key.verifyKey, nil
+}
+
+// PublicJWKs returns the asymmetric verification keys, ordered by kid, for
+// publication at /.well-known/jwks.json. Shared secrets are never exposed.
+func PublicJWKs() ([]JSONWebKey, error) {
+	keySet, err := currentJWTKeys()
+	if err != nil {
+		return nil, err
+	}
+
+	kids := make([]string, 0, len(keySet.keys))
+	for kid := range keySet.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+
+	jwks := make([]JSONWebKey, 0, len(kids))
+	for _, kid := range kids {
+		key := keySet.keys[kid]
+		jwk := JSONWebKey{KeyID: kid, Use: "sig", Algorithm: key.Method.Alg()}
+
+		switch publicKey := key.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		jwks = append(jwks, jwk)
+	}
+
+	return jwks, nil
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LinkPurposeAccountUnlock identifies signed links that lift a sign-in lockout.
+const LinkPurposeAccountUnlock = "account_unlock"
+
+// LoginThrottleConfig controls sign-in brute-force protection. Every value can
+// be overridden through the environment, see .env.example.
+type LoginThrottleConfig struct {
+	AccountThreshold int
+	IPThreshold      int
+	Window           time.Duration
+	LockoutDuration  time.Duration
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+}
+
+func LoadLoginThrottleConfig() LoginThrottleConfig {
+	return LoginThrottleConfig{
+		AccountThreshold: envInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+		IPThreshold:      envInt("LOGIN_IP_LOCKOUT_THRESHOLD", 20),
+		Window:           time.Duration(envInt("LOGIN_LOCKOUT_WINDOW_MINUTES", 15)) * time.Minute,
+		LockoutDuration:  time.Duration(envInt("LOGIN_LOCKOUT_DURATION_MINUTES", 30)) * time.Minute,
+		BaseDelay:        time.Duration(envInt("LOGIN_BACKOFF_BASE_SECONDS", 1)) * time.Second,
+		MaxDelay:         time.Duration(envInt("LOGIN_BACKOFF_MAX_SECONDS", 60)) * time.Second,
+	}
+}
+
+// NormalizeLoginKey lower-cases and trims an email so that throttling cannot
+// be bypassed by changing its case.
+func NormalizeLoginKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
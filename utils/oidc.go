@@ -0,0 +1,415 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrOIDCDiscovery     = errors.New("oidc discovery failed")
+	ErrOIDCCodeExchange  = errors.New("oidc code exchange failed")
+	ErrOIDCInvalidToken  = errors.New("oidc id token is invalid")
+	ErrOIDCNonceMismatch = errors.New("oidc id token nonce mismatch")
+)
+
+const (
+	oidcMetadataTTL = time.Hour
+	oidcJWKSTTL     = time.Hour
+	// oidcJWKSMinRefresh limits how often an unknown kid may trigger a JWKS refetch
+	oidcJWKSMinRefresh = time.Minute
+)
+
+// oidcSigningAlgorithms are the ID token algorithms accepted from providers.
+// Symmetric algorithms are excluded on purpose: the client secret must never
+// be usable to forge an ID token.
+var oidcSigningAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// OIDCProviderMetadata is the part of the discovery document used for sign-in.
+type OIDCProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// OIDCTokenResponse is the token endpoint response of the authorization code grant.
+type OIDCTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// OIDCIDTokenClaims are the ID token claims TestLake relies on.
+type OIDCIDTokenClaims struct {
+	Email           string      `json:"email"`
+	EmailVerified   interface{} `json:"email_verified"`
+	Name            string      `json:"name"`
+	GivenName       string      `json:"given_name"`
+	FamilyName      string      `json:"family_name"`
+	Nonce           string      `json:"nonce"`
+	AuthorizedParty string      `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// IsEmailUnverified reports whether the provider explicitly marked the email as
+// unverified. Several enterprise providers omit the claim altogether, in which
+// case the allowed domain list is what vouches for the address.
+func (c *OIDCIDTokenClaims) IsEmailUnverified() bool {
+	switch verified := c.EmailVerified.(type) {
+	case bool:
+		return !verified
+	case string:
+		return strings.EqualFold(verified, "false")
+	default:
+		return false
+	}
+}
+
+// OIDCClient runs the authorization code flow against a single provider.
+type OIDCClient struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	HTTPClient   *http.Client
+}
+
+func NewOIDCClient(issuer, clientID, clientSecret, redirectURL string) *OIDCClient {
+	return &OIDCClient{
+		Issuer:       strings.TrimRight(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SSORedirectURL is the callback registered with identity providers. It can be
+// overridden with SSO_REDIRECT_URL when the API sits behind a proxy.
+func SSORedirectURL() string {
+	if redirectURL := os.Getenv("SSO_REDIRECT_URL"); redirectURL != "" {
+		return redirectURL
+	}
+	return GetBaseURL() + "/api/v1/auth/sso/callback"
+}
+
+// PKCEChallenge derives the S256 code challenge for a code verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+type cachedOIDCMetadata struct {
+	metadata  *OIDCProviderMetadata
+	fetchedAt time.Time
+}
+
+type cachedOIDCKeys struct {
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+var (
+	oidcCacheMutex    sync.Mutex
+	oidcMetadataCache = map[string]cachedOIDCMetadata{}
+	oidcKeysCache     = map[string]cachedOIDCKeys{}
+)
+
+// Discover loads the provider's discovery document and checks that it belongs
+// to the configured issuer.
+func (c *OIDCClient) Discover() (*OIDCProviderMetadata, error) {
+	oidcCacheMutex.Lock()
+	cached, found := oidcMetadataCache[c.Issuer]
+	oidcCacheMutex.Unlock()
+	if found && time.Since(cached.fetchedAt) < oidcMetadataTTL {
+		return cached.metadata, nil
+	}
+
+	var metadata OIDCProviderMetadata
+	if err := c.getJSON(c.Issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCDiscovery, err)
+	}
+	if strings.TrimRight(metadata.Issuer, "/") != c.Issuer {
+		return nil, fmt.Errorf("%w: issuer %q does not match %q", ErrOIDCDiscovery, metadata.Issuer, c.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, fmt.Errorf("%w: discovery document is missing required endpoints", ErrOIDCDiscovery)
+	}
+
+	oidcCacheMutex.Lock()
+	oidcMetadataCache[c.Issuer] = cachedOIDCMetadata{metadata: &metadata, fetchedAt: time.Now()}
+	oidcCacheMutex.Unlock()
+	return &metadata, nil
+}
+
+// AuthorizationURL builds the URL the browser is sent to in order to sign in.
+func (c *OIDCClient) AuthorizationURL(metadata *OIDCProviderMetadata, state, nonce, codeVerifier, loginHint string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", c.ClientID)
+	params.Set("redirect_uri", c.RedirectURL)
+	params.Set("scope", "openid email profile")
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", PKCEChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+	if loginHint != "" {
+		params.Set("login_hint", loginHint)
+	}
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// ExchangeCode redeems an authorization code at the token endpoint.
+func (c *OIDCClient) ExchangeCode(metadata *OIDCProviderMetadata, code, codeVerifier string) (*OIDCTokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", c.RedirectURL)
+	form.Set("code_verifier", codeVerifier)
+	if c.ClientSecret == "" {
+		// Public clients identify themselves in the body
+		form.Set("client_id", c.ClientID)
+	}
+
+	request, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCCodeExchange, err)
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if c.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(c.ClientID), url.QueryEscape(c.ClientSecret))
+	}
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCCodeExchange, err)
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCCodeExchange, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: token endpoint returned %d: %s", ErrOIDCCodeExchange, response.StatusCode, TruncateString(string(body), 200))
+	}
+
+	var tokens OIDCTokenResponse
+	if err := json.Unmarshal(body, &tokens); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCCodeExchange, err)
+	}
+	if tokens.IDToken == "" {
+		return nil, fmt.Errorf("%w: no id_token in response", ErrOIDCCodeExchange)
+	}
+	return &tokens, nil
+}
+
+// VerifyIDToken checks the ID token signature against the provider's JWKS and
+// validates issuer, audience, expiry and the nonce sent with the request.
+func (c *OIDCClient) VerifyIDToken(metadata *OIDCProviderMetadata, rawIDToken, nonce string) (*OIDCIDTokenClaims, error) {
+	claims := &OIDCIDTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return c.providerKey(metadata.JWKSURI, kid)
+	},
+		jwt.WithValidMethods(oidcSigningAlgorithms),
+		jwt.WithIssuer(metadata.Issuer),
+		jwt.WithAudience(c.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrOIDCInvalidToken, err)
+	}
+
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != c.ClientID {
+		return nil, fmt.Errorf("%w: azp %q does not match the client", ErrOIDCInvalidToken, claims.AuthorizedParty)
+	}
+	if claims.Nonce != nonce {
+		return nil, ErrOIDCNonceMismatch
+	}
+	return claims, nil
+}
+
+// providerKey returns the provider key for a kid, refetching the JWKS when the
+// kid is unknown so that provider-side key rotation is picked up.
+func (c *OIDCClient) providerKey(jwksURI, kid string) (interface{}, error) {
+	oidcCacheMutex.Lock()
+	cached, found := oidcKeysCache[jwksURI]
+	oidcCacheMutex.Unlock()
+
+	stale := !found || time.Since(cached.fetchedAt) > oidcJWKSTTL
+	if !stale {
+		if key := selectOIDCKey(cached.keys, kid); key != nil {
+			return key, nil
+		}
+		if time.Since(cached.fetchedAt) < oidcJWKSMinRefresh {
+			return nil, errors.New("unknown signing key")
+		}
+	}
+
+	keys, err := c.fetchKeys(jwksURI)
+	if err != nil {
+		return nil, err
+	}
+
+	oidcCacheMutex.Lock()
+	oidcKeysCache[jwksURI] = cachedOIDCKeys{keys: keys, fetchedAt: time.Now()}
+	oidcCacheMutex.Unlock()
+
+	if key := selectOIDCKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+func selectOIDCKey(keys map[string]interface{}, kid string) interface{} {
+	if kid != "" {
+		return keys[kid]
+	}
+	// Providers with a single key may omit the kid header
+	if len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+type providerJSONWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (c *OIDCClient) fetchKeys(jwksURI string) (map[string]interface{}, error) {
+	var document struct {
+		Keys []providerJSONWebKey `json:"keys"`
+	}
+	if err := c.getJSON(jwksURI, &document); err != nil {
+		return nil, fmt.Errorf("failed to fetch provider keys: %w", err)
+	}
+
+	keys := map[string]interface{}{}
+	for _, jwk := range document.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip key types we do not understand instead of failing the whole set
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+	return keys, nil
+}
+
+func (jwk providerJSONWebKey) publicKey() (interface{}, error) {
+	decode := base64.RawURLEncoding.DecodeString
+
+	switch jwk.KeyType {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > int64(^uint32(0)>>1) {
+			return nil, errors.New("rsa exponent out of range")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("ec point is not on the curve")
+		}
+		return key, nil
+	case "OKP":
+		if jwk.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", jwk.Curve)
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", jwk.KeyType)
+	}
+}
+
+func (c *OIDCClient) getJSON(target string, out interface{}) error {
+	request, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", target, response.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(response.Body, 1<<20)).Decode(out)
+}
@@ -0,0 +1,299 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"testlake/model"
+)
+
+// ErrPaymentGatewayNotConfigured is returned when the gateway lacks credentials.
+var ErrPaymentGatewayNotConfigured = errors.New("payment gateway is not configured")
+
+// ErrWebhookSignatureInvalid is returned when a webhook was not sent by the provider.
+var ErrWebhookSignatureInvalid = errors.New("webhook signature is invalid")
+
+// GatewayInterval is the billing frequency of a gateway plan.
+type GatewayInterval string
+
+const (
+	GatewayIntervalMonth GatewayInterval = "MONTH"
+	GatewayIntervalYear  GatewayInterval = "YEAR"
+)
+
+type GatewayProductRequest struct {
+	Name        string
+	Description string
+}
+
+type GatewayProduct struct {
+	ID   string
+	Name string
+}
+
+type GatewayPlanRequest struct {
+	ProductID   string
+	Name        string
+	Description string
+	Interval    GatewayInterval
+	Price       int64 // minor units of Currency, like every gateway amount
+	Currency    string
+}
+
+type GatewayPlan struct {
+	ID        string
+	ProductID string
+	Status    string
+}
+
+type GatewaySubscriptionRequest struct {
+	PlanID          string
+	CustomID        string // echoed back in webhooks, set to the organization ID
+	SubscriberEmail string
+	ReturnURL       string
+	CancelURL       string
+	StartTime       *time.Time
+	IdempotencyKey  string
+	// Price overrides the plan price of every cycle when set, e.g. for a discount
+	Price    *int64
+	Currency string
+	// TaxPercentage is added on top of the price of every cycle when set
+	TaxPercentage *float64
+}
+
+// GatewaySubscription is a subscription on the gateway side. ApprovalURL is set
+// while the payer still has to approve it.
+type GatewaySubscription struct {
+	ID          string
+	Status      string
+	PlanID      string
+	ApprovalURL string
+}
+
+type GatewayOrderRequest struct {
+	ReferenceID    string
+	CustomID       string
+	InvoiceNumber  string
+	Description    string
+	Amount         int64
+	Currency       string
+	ReturnURL      string
+	CancelURL      string
+	IdempotencyKey string
+}
+
+type GatewayOrder struct {
+	ID          string
+	Status      string
+	ApprovalURL string
+}
+
+// GatewayChargeRequest charges a payment method saved at the provider, without
+// the payer having to approve the payment.
+type GatewayChargeRequest struct {
+	VaultID        string
+	ReferenceID    string
+	CustomID       string
+	InvoiceNumber  string
+	Description    string
+	Amount         int64
+	Currency       string
+	IdempotencyKey string
+}
+
+// GatewaySetupTokenRequest starts saving a payment method at the provider, to
+// be charged later without the payer.
+type GatewaySetupTokenRequest struct {
+	ReturnURL      string
+	CancelURL      string
+	IdempotencyKey string
+}
+
+// GatewaySetupToken is a payment method waiting for the payer to approve saving
+// it at ApprovalURL.
+type GatewaySetupToken struct {
+	ID          string
+	Status      string
+	ApprovalURL string
+}
+
+// GatewayPaymentToken is a payment method saved at the provider. Its ID is the
+// vault ID charges are made with.
+type GatewayPaymentToken struct {
+	ID         string
+	CustomerID string
+	PayerID    string
+	PayerEmail string
+}
+
+type GatewayCapture struct {
+	ID         string
+	OrderID    string
+	Status     string
+	Amount     int64
+	Currency   string
+	CustomID   string
+	PayerID    string
+	PayerEmail string
+}
+
+// GatewayRefundRequest refunds a capture in full when Amount is nil.
+type GatewayRefundRequest struct {
+	CaptureID      string
+	Amount         *int64
+	Currency       string
+	InvoiceNumber  string
+	NoteToPayer    string
+	IdempotencyKey string
+}
+
+type GatewayRefund struct {
+	ID       string
+	Status   string
+	Amount   int64
+	Currency string
+}
+
+// GatewayWebhook is a webhook delivery as received, with the transmission
+// headers the provider signs it with.
+type GatewayWebhook struct {
+	TransmissionID   string
+	TransmissionTime string
+	TransmissionSig  string
+	CertURL          string
+	AuthAlgo         string
+	Body             []byte
+}
+
+// GatewayError is a request rejected by the payment provider.
+type GatewayError struct {
+	StatusCode int
+	Name       string
+	Message    string
+	DebugID    string
+	// Issue is the first detailed reason given, e.g. INSTRUMENT_DECLINED
+	Issue string
+}
+
+func (e *GatewayError) Error() string {
+	return fmt.Sprintf("payment gateway error %d %s: %s (debug id %s)", e.StatusCode, e.Name, e.Message, e.DebugID)
+}
+
+// paymentMethodFailureIssues are the provider issues meaning a saved payment
+// method cannot be charged anymore, unlike a decline a later retry may pass.
+var paymentMethodFailureIssues = map[string]bool{
+	"PAYER_ACCOUNT_LOCKED_OR_CLOSED":         true,
+	"PAYER_ACCOUNT_RESTRICTED":               true,
+	"PAYMENT_SOURCE_CANNOT_BE_USED":          true,
+	"PAYMENT_SOURCE_INFO_CANNOT_BE_VERIFIED": true,
+	"INVALID_RESOURCE_ID":                    true,
+}
+
+// IsPaymentMethodFailure reports whether a charge failed because the saved
+// payment method no longer works, e.g. the payer closed the account or revoked
+// it, rather than because this payment was declined.
+func IsPaymentMethodFailure(err error) bool {
+	var gatewayErr *GatewayError
+	if !errors.As(err, &gatewayErr) {
+		return false
+	}
+	return paymentMethodFailureIssues[gatewayErr.Issue]
+}
+
+// PaymentGateway is the payment provider used for billing. Controllers and jobs
+// only talk to the provider through this interface.
+type PaymentGateway interface {
+	Name() string
+
+	CreateProduct(ctx context.Context, request GatewayProductRequest) (*GatewayProduct, error)
+	CreatePlan(ctx context.Context, request GatewayPlanRequest) (*GatewayPlan, error)
+	// DeactivatePlan stops new subscriptions to a plan; existing ones keep being billed.
+	DeactivatePlan(ctx context.Context, planID string) error
+
+	CreateSubscription(ctx context.Context, request GatewaySubscriptionRequest) (*GatewaySubscription, error)
+	ReviseSubscription(ctx context.Context, subscriptionID, planID string) (*GatewaySubscription, error)
+	// UpdateSubscriptionPrice changes what the subscription is charged from its next cycle on.
+	UpdateSubscriptionPrice(ctx context.Context, subscriptionID string, price int64, currency string) error
+	// UpdateSubscriptionTax changes the tax percentage added to the subscription's price.
+	UpdateSubscriptionTax(ctx context.Context, subscriptionID string, percentage float64) error
+	ActivateSubscription(ctx context.Context, subscriptionID, reason string) error
+	SuspendSubscription(ctx context.Context, subscriptionID, reason string) error
+	CancelSubscription(ctx context.Context, subscriptionID, reason string) error
+
+	CreateOrder(ctx context.Context, request GatewayOrderRequest) (*GatewayOrder, error)
+	CaptureOrder(ctx context.Context, orderID, idempotencyKey string) (*GatewayCapture, error)
+	ChargePaymentMethod(ctx context.Context, request GatewayChargeRequest) (*GatewayCapture, error)
+	RefundCapture(ctx context.Context, request GatewayRefundRequest) (*GatewayRefund, error)
+
+	CreateSetupToken(ctx context.Context, request GatewaySetupTokenRequest) (*GatewaySetupToken, error)
+	// CreatePaymentToken saves the payment method of a setup token the payer approved.
+	CreatePaymentToken(ctx context.Context, setupTokenID, idempotencyKey string) (*GatewayPaymentToken, error)
+	DeletePaymentToken(ctx context.Context, paymentTokenID string) error
+
+	// VerifyWebhook returns ErrWebhookSignatureInvalid unless the provider sent the webhook.
+	VerifyWebhook(ctx context.Context, webhook GatewayWebhook) error
+}
+
+var (
+	paymentGatewayMutex sync.RWMutex
+	paymentGateway      PaymentGateway
+)
+
+// GetPaymentGateway returns the configured gateway. PAYMENT_GATEWAY selects
+// "paypal" (default) or "fake", the in-memory gateway for local development.
+func GetPaymentGateway() PaymentGateway {
+	paymentGatewayMutex.RLock()
+	gateway := paymentGateway
+	paymentGatewayMutex.RUnlock()
+	if gateway != nil {
+		return gateway
+	}
+
+	paymentGatewayMutex.Lock()
+	defer paymentGatewayMutex.Unlock()
+	if paymentGateway == nil {
+		switch strings.ToLower(os.Getenv("PAYMENT_GATEWAY")) {
+		case "fake":
+			paymentGateway = NewFakePaymentGateway()
+		default:
+			paymentGateway = NewPayPalClientFromEnv()
+		}
+	}
+	return paymentGateway
+}
+
+// SetPaymentGateway replaces the gateway, mainly so tests can inject a fake.
+// Passing nil makes the next GetPaymentGateway call read the environment again.
+func SetPaymentGateway(gateway PaymentGateway) {
+	paymentGatewayMutex.Lock()
+	paymentGateway = gateway
+	paymentGatewayMutex.Unlock()
+}
+
+// PaymentReturnURLs are the pages the payer is sent back to after approving or
+// cancelling at the provider. They default to the API base URL.
+func PaymentReturnURLs() (string, string) {
+	returnURL := os.Getenv("PAYMENT_RETURN_URL")
+	if returnURL == "" {
+		returnURL = GetBaseURL() + "/billing/return"
+	}
+	cancelURL := os.Getenv("PAYMENT_CANCEL_URL")
+	if cancelURL == "" {
+		cancelURL = GetBaseURL() + "/billing/cancel"
+	}
+	return returnURL, cancelURL
+}
+
+func formatGatewayAmount(amount int64, currency string) string {
+	return model.FormatMinorUnits(amount, currency)
+}
+
+func parseGatewayAmount(value, currency string) int64 {
+	amount, _ := model.ParseMinorUnits(value, currency)
+	return amount
+}
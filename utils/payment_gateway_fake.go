@@ -0,0 +1,461 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// FakeWebhookSignature is the transmission signature the fake gateway accepts.
+const FakeWebhookSignature = "fake-signature"
+
+// FakePaymentGateway is an in-memory PaymentGateway for tests and local
+// development. Orders are approved as soon as they are created, subscriptions
+// stay APPROVAL_PENDING until ApproveSubscription is called.
+type FakePaymentGateway struct {
+	mutex         sync.Mutex
+	sequence      int
+	Products      map[string]*GatewayProduct
+	Plans         map[string]*GatewayPlan
+	Subscriptions map[string]*GatewaySubscription
+	Orders        map[string]*GatewayOrderRequest
+	Captures      map[string]*GatewayCapture
+	Refunds       map[string][]*GatewayRefund
+	Calls         []string
+	// Prices are the subscription prices overriding the plan price
+	Prices map[string]int64
+	// Taxes are the tax percentages added to subscription prices
+	Taxes         map[string]float64
+	SetupTokens   map[string]*GatewaySetupToken
+	PaymentTokens map[string]*GatewayPaymentToken
+
+	orderStatus map[string]string
+	idempotent  map[string]interface{}
+	failures    map[string]error
+}
+
+func NewFakePaymentGateway() *FakePaymentGateway {
+	return &FakePaymentGateway{
+		Products:      map[string]*GatewayProduct{},
+		Plans:         map[string]*GatewayPlan{},
+		Subscriptions: map[string]*GatewaySubscription{},
+		Orders:        map[string]*GatewayOrderRequest{},
+		Captures:      map[string]*GatewayCapture{},
+		Refunds:       map[string][]*GatewayRefund{},
+		Prices:        map[string]int64{},
+		Taxes:         map[string]float64{},
+		SetupTokens:   map[string]*GatewaySetupToken{},
+		PaymentTokens: map[string]*GatewayPaymentToken{},
+		orderStatus:   map[string]string{},
+		idempotent:    map[string]interface{}{},
+		failures:      map[string]error{},
+	}
+}
+
+func (f *FakePaymentGateway) Name() string {
+	return "fake"
+}
+
+// FailNext makes the next call of the named method (e.g. "CaptureOrder") return err.
+func (f *FakePaymentGateway) FailNext(method string, err error) {
+	f.mutex.Lock()
+	f.failures[method] = err
+	f.mutex.Unlock()
+}
+
+// ApproveSubscription simulates the payer approving a subscription at the provider.
+func (f *FakePaymentGateway) ApproveSubscription(subscriptionID string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if subscription, found := f.Subscriptions[subscriptionID]; found {
+		subscription.Status = "ACTIVE"
+		subscription.ApprovalURL = ""
+	}
+}
+
+// ApproveSetupToken simulates the payer approving to save a payment method.
+func (f *FakePaymentGateway) ApproveSetupToken(setupTokenID string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if token, found := f.SetupTokens[setupTokenID]; found {
+		token.Status = "APPROVED"
+		token.ApprovalURL = ""
+	}
+}
+
+// begin records the call and returns a queued failure, if any. Must hold the mutex.
+func (f *FakePaymentGateway) begin(method string) error {
+	f.Calls = append(f.Calls, method)
+	if err, found := f.failures[method]; found {
+		delete(f.failures, method)
+		return err
+	}
+	return nil
+}
+
+func (f *FakePaymentGateway) nextID(prefix string) string {
+	f.sequence++
+	return fmt.Sprintf("FAKE-%s-%d", prefix, f.sequence)
+}
+
+func (f *FakePaymentGateway) notFound(kind, id string) error {
+	return &GatewayError{StatusCode: http.StatusNotFound, Name: "RESOURCE_NOT_FOUND", Message: kind + " " + id + " does not exist"}
+}
+
+func (f *FakePaymentGateway) CreateProduct(ctx context.Context, request GatewayProductRequest) (*GatewayProduct, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreateProduct"); err != nil {
+		return nil, err
+	}
+
+	product := &GatewayProduct{ID: f.nextID("PROD"), Name: request.Name}
+	f.Products[product.ID] = product
+	return product, nil
+}
+
+func (f *FakePaymentGateway) CreatePlan(ctx context.Context, request GatewayPlanRequest) (*GatewayPlan, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreatePlan"); err != nil {
+		return nil, err
+	}
+	if _, found := f.Products[request.ProductID]; !found {
+		return nil, f.notFound("product", request.ProductID)
+	}
+
+	plan := &GatewayPlan{ID: f.nextID("PLAN"), ProductID: request.ProductID, Status: "ACTIVE"}
+	f.Plans[plan.ID] = plan
+	return plan, nil
+}
+
+func (f *FakePaymentGateway) DeactivatePlan(ctx context.Context, planID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("DeactivatePlan"); err != nil {
+		return err
+	}
+	plan, found := f.Plans[planID]
+	if !found {
+		return f.notFound("plan", planID)
+	}
+	plan.Status = "INACTIVE"
+	return nil
+}
+
+func (f *FakePaymentGateway) CreateSubscription(ctx context.Context, request GatewaySubscriptionRequest) (*GatewaySubscription, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreateSubscription"); err != nil {
+		return nil, err
+	}
+	if previous, found := f.idempotent[request.IdempotencyKey]; found && request.IdempotencyKey != "" {
+		result := *previous.(*GatewaySubscription)
+		return &result, nil
+	}
+	if plan, found := f.Plans[request.PlanID]; !found {
+		return nil, f.notFound("plan", request.PlanID)
+	} else if plan.Status != "ACTIVE" {
+		return nil, &GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "UNPROCESSABLE_ENTITY", Message: "PLAN_STATUS_INVALID"}
+	}
+
+	id := f.nextID("SUB")
+	subscription := &GatewaySubscription{ID: id, Status: "APPROVAL_PENDING", PlanID: request.PlanID, ApprovalURL: "https://fake.gateway/approve/" + id}
+	f.Subscriptions[id] = subscription
+	if request.Price != nil {
+		f.Prices[id] = *request.Price
+	}
+	if request.TaxPercentage != nil {
+		f.Taxes[id] = *request.TaxPercentage
+	}
+	if request.IdempotencyKey != "" {
+		f.idempotent[request.IdempotencyKey] = subscription
+	}
+	result := *subscription
+	return &result, nil
+}
+
+func (f *FakePaymentGateway) ReviseSubscription(ctx context.Context, subscriptionID, planID string) (*GatewaySubscription, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ReviseSubscription"); err != nil {
+		return nil, err
+	}
+	subscription, found := f.Subscriptions[subscriptionID]
+	if !found {
+		return nil, f.notFound("subscription", subscriptionID)
+	}
+	if _, found := f.Plans[planID]; !found {
+		return nil, f.notFound("plan", planID)
+	}
+
+	// The subscription is charged the price of its new plan
+	subscription.PlanID = planID
+	delete(f.Prices, subscriptionID)
+	result := *subscription
+	return &result, nil
+}
+
+func (f *FakePaymentGateway) UpdateSubscriptionPrice(ctx context.Context, subscriptionID string, price int64, currency string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("UpdateSubscriptionPrice"); err != nil {
+		return err
+	}
+	if _, found := f.Subscriptions[subscriptionID]; !found {
+		return f.notFound("subscription", subscriptionID)
+	}
+
+	f.Prices[subscriptionID] = price
+	return nil
+}
+
+func (f *FakePaymentGateway) UpdateSubscriptionTax(ctx context.Context, subscriptionID string, percentage float64) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("UpdateSubscriptionTax"); err != nil {
+		return err
+	}
+	if _, found := f.Subscriptions[subscriptionID]; !found {
+		return f.notFound("subscription", subscriptionID)
+	}
+
+	f.Taxes[subscriptionID] = percentage
+	return nil
+}
+
+func (f *FakePaymentGateway) ActivateSubscription(ctx context.Context, subscriptionID, reason string) error {
+	return f.setSubscriptionStatus("ActivateSubscription", subscriptionID, "ACTIVE")
+}
+
+func (f *FakePaymentGateway) SuspendSubscription(ctx context.Context, subscriptionID, reason string) error {
+	return f.setSubscriptionStatus("SuspendSubscription", subscriptionID, "SUSPENDED")
+}
+
+func (f *FakePaymentGateway) CancelSubscription(ctx context.Context, subscriptionID, reason string) error {
+	return f.setSubscriptionStatus("CancelSubscription", subscriptionID, "CANCELLED")
+}
+
+func (f *FakePaymentGateway) setSubscriptionStatus(method, subscriptionID, status string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin(method); err != nil {
+		return err
+	}
+	subscription, found := f.Subscriptions[subscriptionID]
+	if !found {
+		return f.notFound("subscription", subscriptionID)
+	}
+	if subscription.Status == "CANCELLED" {
+		return &GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "UNPROCESSABLE_ENTITY", Message: "subscription is cancelled"}
+	}
+
+	subscription.Status = status
+	return nil
+}
+
+func (f *FakePaymentGateway) CreateOrder(ctx context.Context, request GatewayOrderRequest) (*GatewayOrder, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreateOrder"); err != nil {
+		return nil, err
+	}
+
+	id := f.nextID("ORDER")
+	stored := request
+	f.Orders[id] = &stored
+	f.orderStatus[id] = "APPROVED"
+	return &GatewayOrder{ID: id, Status: "CREATED", ApprovalURL: "https://fake.gateway/checkout/" + id}, nil
+}
+
+func (f *FakePaymentGateway) CaptureOrder(ctx context.Context, orderID, idempotencyKey string) (*GatewayCapture, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CaptureOrder"); err != nil {
+		return nil, err
+	}
+	if previous, found := f.idempotent[idempotencyKey]; found && idempotencyKey != "" {
+		result := *previous.(*GatewayCapture)
+		return &result, nil
+	}
+	order, found := f.Orders[orderID]
+	if !found {
+		return nil, f.notFound("order", orderID)
+	}
+	if f.orderStatus[orderID] != "APPROVED" {
+		return nil, &GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "ORDER_ALREADY_CAPTURED", Message: "order " + orderID + " cannot be captured"}
+	}
+
+	capture := &GatewayCapture{
+		ID:         f.nextID("CAPTURE"),
+		OrderID:    orderID,
+		Status:     "COMPLETED",
+		Amount:     order.Amount,
+		Currency:   order.Currency,
+		CustomID:   order.CustomID,
+		PayerID:    "FAKEPAYER",
+		PayerEmail: "payer@example.com",
+	}
+	f.Captures[capture.ID] = capture
+	f.orderStatus[orderID] = "COMPLETED"
+	if idempotencyKey != "" {
+		f.idempotent[idempotencyKey] = capture
+	}
+	result := *capture
+	return &result, nil
+}
+
+// ChargePaymentMethod captures at once; vault IDs starting with "declined" are
+// declined and the ones starting with "closed" belong to a closed account.
+func (f *FakePaymentGateway) ChargePaymentMethod(ctx context.Context, request GatewayChargeRequest) (*GatewayCapture, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("ChargePaymentMethod"); err != nil {
+		return nil, err
+	}
+	if previous, found := f.idempotent[request.IdempotencyKey]; found && request.IdempotencyKey != "" {
+		result := *previous.(*GatewayCapture)
+		return &result, nil
+	}
+	if strings.HasPrefix(request.VaultID, "declined") {
+		return nil, &GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "UNPROCESSABLE_ENTITY", Message: "INSTRUMENT_DECLINED", Issue: "INSTRUMENT_DECLINED"}
+	}
+	if strings.HasPrefix(request.VaultID, "closed") {
+		return nil, &GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "UNPROCESSABLE_ENTITY", Message: "PAYER_ACCOUNT_LOCKED_OR_CLOSED", Issue: "PAYER_ACCOUNT_LOCKED_OR_CLOSED"}
+	}
+
+	orderID := f.nextID("ORDER")
+	stored := GatewayOrderRequest{ReferenceID: request.ReferenceID, CustomID: request.CustomID, InvoiceNumber: request.InvoiceNumber, Amount: request.Amount, Currency: request.Currency}
+	f.Orders[orderID] = &stored
+	f.orderStatus[orderID] = "COMPLETED"
+
+	capture := &GatewayCapture{
+		ID:         f.nextID("CAPTURE"),
+		OrderID:    orderID,
+		Status:     "COMPLETED",
+		Amount:     request.Amount,
+		Currency:   request.Currency,
+		CustomID:   request.CustomID,
+		PayerID:    "FAKEPAYER",
+		PayerEmail: "payer@example.com",
+	}
+	f.Captures[capture.ID] = capture
+	if request.IdempotencyKey != "" {
+		f.idempotent[request.IdempotencyKey] = capture
+	}
+	result := *capture
+	return &result, nil
+}
+
+func (f *FakePaymentGateway) RefundCapture(ctx context.Context, request GatewayRefundRequest) (*GatewayRefund, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("RefundCapture"); err != nil {
+		return nil, err
+	}
+	if previous, found := f.idempotent[request.IdempotencyKey]; found && request.IdempotencyKey != "" {
+		result := *previous.(*GatewayRefund)
+		return &result, nil
+	}
+	capture, found := f.Captures[request.CaptureID]
+	if !found {
+		return nil, f.notFound("capture", request.CaptureID)
+	}
+
+	var refunded int64
+	for _, refund := range f.Refunds[capture.ID] {
+		refunded += refund.Amount
+	}
+	amount := capture.Amount - refunded
+	if request.Amount != nil {
+		amount = *request.Amount
+	}
+	if amount <= 0 || refunded+amount > capture.Amount {
+		return nil, &GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "REFUND_AMOUNT_EXCEEDED", Message: "refund exceeds the captured amount"}
+	}
+
+	refund := &GatewayRefund{ID: f.nextID("REFUND"), Status: "COMPLETED", Amount: amount, Currency: capture.Currency}
+	f.Refunds[capture.ID] = append(f.Refunds[capture.ID], refund)
+	if request.IdempotencyKey != "" {
+		f.idempotent[request.IdempotencyKey] = refund
+	}
+	result := *refund
+	return &result, nil
+}
+
+func (f *FakePaymentGateway) CreateSetupToken(ctx context.Context, request GatewaySetupTokenRequest) (*GatewaySetupToken, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreateSetupToken"); err != nil {
+		return nil, err
+	}
+	if previous, found := f.idempotent[request.IdempotencyKey]; found && request.IdempotencyKey != "" {
+		result := *previous.(*GatewaySetupToken)
+		return &result, nil
+	}
+
+	id := f.nextID("SETUP")
+	token := &GatewaySetupToken{ID: id, Status: "PAYER_ACTION_REQUIRED", ApprovalURL: "https://fake.gateway/vault/" + id}
+	f.SetupTokens[id] = token
+	if request.IdempotencyKey != "" {
+		f.idempotent[request.IdempotencyKey] = token
+	}
+	result := *token
+	return &result, nil
+}
+
+// CreatePaymentToken vaults an approved setup token, once.
+func (f *FakePaymentGateway) CreatePaymentToken(ctx context.Context, setupTokenID, idempotencyKey string) (*GatewayPaymentToken, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("CreatePaymentToken"); err != nil {
+		return nil, err
+	}
+	if previous, found := f.idempotent[idempotencyKey]; found && idempotencyKey != "" {
+		result := *previous.(*GatewayPaymentToken)
+		return &result, nil
+	}
+	setupToken, found := f.SetupTokens[setupTokenID]
+	if !found {
+		return nil, f.notFound("setup token", setupTokenID)
+	}
+	if setupToken.Status != "APPROVED" {
+		return nil, &GatewayError{StatusCode: http.StatusUnprocessableEntity, Name: "UNPROCESSABLE_ENTITY", Message: "setup token " + setupTokenID + " is " + setupToken.Status, Issue: "SETUP_TOKEN_NOT_APPROVED"}
+	}
+
+	token := &GatewayPaymentToken{ID: f.nextID("VAULT"), CustomerID: "FAKE-CUSTOMER", PayerID: "FAKEPAYER", PayerEmail: "payer@example.com"}
+	f.PaymentTokens[token.ID] = token
+	setupToken.Status = "VAULTED"
+	if idempotencyKey != "" {
+		f.idempotent[idempotencyKey] = token
+	}
+	result := *token
+	return &result, nil
+}
+
+func (f *FakePaymentGateway) DeletePaymentToken(ctx context.Context, paymentTokenID string) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("DeletePaymentToken"); err != nil {
+		return err
+	}
+	if _, found := f.PaymentTokens[paymentTokenID]; !found {
+		return f.notFound("payment token", paymentTokenID)
+	}
+	delete(f.PaymentTokens, paymentTokenID)
+	return nil
+}
+
+func (f *FakePaymentGateway) VerifyWebhook(ctx context.Context, webhook GatewayWebhook) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if err := f.begin("VerifyWebhook"); err != nil {
+		return err
+	}
+	if webhook.TransmissionSig != FakeWebhookSignature {
+		return ErrWebhookSignatureInvalid
+	}
+	return nil
+}
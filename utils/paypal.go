@@ -0,0 +1,707 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	PayPalSandboxBaseURL = "https://api-m.sandbox.paypal.com"
+	PayPalLiveBaseURL    = "https://api-m.paypal.com"
+)
+
+// PayPalClient implements PaymentGateway on top of the PayPal REST API. It
+// authenticates with the OAuth2 client credentials grant and caches the token.
+type PayPalClient struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	WebhookID    string
+	BrandName    string
+	HTTPClient   *http.Client
+
+	tokenMutex     sync.Mutex
+	accessToken    string
+	tokenExpiresAt time.Time
+}
+
+func NewPayPalClient(baseURL, clientID, clientSecret string) *PayPalClient {
+	return &PayPalClient{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		BrandName:    "TestLake",
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// NewPayPalClientFromEnv reads PAYPAL_CLIENT_ID, PAYPAL_CLIENT_SECRET,
+// PAYPAL_WEBHOOK_ID and PAYPAL_MODE (sandbox or live). PAYPAL_BASE_URL
+// overrides the API host.
+func NewPayPalClientFromEnv() *PayPalClient {
+	baseURL := os.Getenv("PAYPAL_BASE_URL")
+	if baseURL == "" {
+		baseURL = PayPalSandboxBaseURL
+		if strings.ToLower(os.Getenv("PAYPAL_MODE")) == "live" {
+			baseURL = PayPalLiveBaseURL
+		}
+	}
+	client := NewPayPalClient(baseURL, os.Getenv("PAYPAL_CLIENT_ID"), os.Getenv("PAYPAL_CLIENT_SECRET"))
+	client.WebhookID = os.Getenv("PAYPAL_WEBHOOK_ID")
+	return client
+}
+
+func (c *PayPalClient) Name() string {
+	return "paypal"
+}
+
+type payPalLink struct {
+	Href string `json:"href"`
+	Rel  string `json:"rel"`
+}
+
+type payPalMoney struct {
+	CurrencyCode string `json:"currency_code"`
+	Value        string `json:"value"`
+}
+
+func payPalApprovalURL(links []payPalLink) string {
+	for _, link := range links {
+		if link.Rel == "approve" || link.Rel == "payer-action" {
+			return link.Href
+		}
+	}
+	return ""
+}
+
+func (c *PayPalClient) CreateProduct(ctx context.Context, request GatewayProductRequest) (*GatewayProduct, error) {
+	body := map[string]interface{}{
+		"name":     request.Name,
+		"type":     "SERVICE",
+		"category": "SOFTWARE",
+	}
+	if request.Description != "" {
+		body["description"] = request.Description
+	}
+
+	var response struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/catalogs/products", body, "", &response); err != nil {
+		return nil, err
+	}
+	return &GatewayProduct{ID: response.ID, Name: response.Name}, nil
+}
+
+func (c *PayPalClient) CreatePlan(ctx context.Context, request GatewayPlanRequest) (*GatewayPlan, error) {
+	body := map[string]interface{}{
+		"product_id":  request.ProductID,
+		"name":        request.Name,
+		"description": request.Description,
+		"status":      "ACTIVE",
+		"billing_cycles": []map[string]interface{}{{
+			"frequency":    map[string]interface{}{"interval_unit": string(request.Interval), "interval_count": 1},
+			"tenure_type":  "REGULAR",
+			"sequence":     1,
+			"total_cycles": 0,
+			"pricing_scheme": map[string]interface{}{
+				"fixed_price": payPalMoney{CurrencyCode: request.Currency, Value: formatGatewayAmount(request.Price, request.Currency)},
+			},
+		}},
+		"payment_preferences": map[string]interface{}{
+			"auto_bill_outstanding":     true,
+			"payment_failure_threshold": 3,
+		},
+	}
+
+	var response struct {
+		ID        string `json:"id"`
+		ProductID string `json:"product_id"`
+		Status    string `json:"status"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/billing/plans", body, "", &response); err != nil {
+		return nil, err
+	}
+	return &GatewayPlan{ID: response.ID, ProductID: response.ProductID, Status: response.Status}, nil
+}
+
+func (c *PayPalClient) DeactivatePlan(ctx context.Context, planID string) error {
+	return c.do(ctx, http.MethodPost, "/v1/billing/plans/"+url.PathEscape(planID)+"/deactivate", nil, "", nil)
+}
+
+type payPalSubscriptionResponse struct {
+	ID     string       `json:"id"`
+	Status string       `json:"status"`
+	PlanID string       `json:"plan_id"`
+	Links  []payPalLink `json:"links"`
+}
+
+func (r payPalSubscriptionResponse) toGateway() *GatewaySubscription {
+	return &GatewaySubscription{ID: r.ID, Status: r.Status, PlanID: r.PlanID, ApprovalURL: payPalApprovalURL(r.Links)}
+}
+
+func (c *PayPalClient) CreateSubscription(ctx context.Context, request GatewaySubscriptionRequest) (*GatewaySubscription, error) {
+	body := map[string]interface{}{
+		"plan_id":   request.PlanID,
+		"custom_id": request.CustomID,
+		"application_context": map[string]interface{}{
+			"brand_name":          c.BrandName,
+			"user_action":         "SUBSCRIBE_NOW",
+			"shipping_preference": "NO_SHIPPING",
+			"return_url":          request.ReturnURL,
+			"cancel_url":          request.CancelURL,
+		},
+	}
+	if request.SubscriberEmail != "" {
+		body["subscriber"] = map[string]interface{}{"email_address": request.SubscriberEmail}
+	}
+	if request.StartTime != nil {
+		body["start_time"] = request.StartTime.UTC().Format(time.RFC3339)
+	}
+	plan := map[string]interface{}{}
+	if request.Price != nil {
+		plan["billing_cycles"] = []map[string]interface{}{{
+			"sequence":       1,
+			"pricing_scheme": payPalPricingScheme(*request.Price, request.Currency),
+		}}
+	}
+	if request.TaxPercentage != nil {
+		plan["taxes"] = payPalTaxes(*request.TaxPercentage)
+	}
+	if len(plan) > 0 {
+		body["plan"] = plan
+	}
+
+	var response payPalSubscriptionResponse
+	if err := c.do(ctx, http.MethodPost, "/v1/billing/subscriptions", body, request.IdempotencyKey, &response); err != nil {
+		return nil, err
+	}
+	return response.toGateway(), nil
+}
+
+func (c *PayPalClient) ReviseSubscription(ctx context.Context, subscriptionID, planID string) (*GatewaySubscription, error) {
+	var response payPalSubscriptionResponse
+	path := "/v1/billing/subscriptions/" + url.PathEscape(subscriptionID) + "/revise"
+	if err := c.do(ctx, http.MethodPost, path, map[string]interface{}{"plan_id": planID}, "", &response); err != nil {
+		return nil, err
+	}
+	if response.ID == "" {
+		response.ID = subscriptionID
+	}
+	if response.PlanID == "" {
+		response.PlanID = planID
+	}
+	return response.toGateway(), nil
+}
+
+// UpdateSubscriptionPrice overrides the price of the single regular billing
+// cycle plans are created with.
+func (c *PayPalClient) UpdateSubscriptionPrice(ctx context.Context, subscriptionID string, price int64, currency string) error {
+	path := "/v1/billing/subscriptions/" + url.PathEscape(subscriptionID)
+	body := []map[string]interface{}{{
+		"op":    "replace",
+		"path":  "/plan/billing_cycles/@sequence==1/pricing_scheme/fixed_price",
+		"value": payPalMoney{CurrencyCode: currency, Value: formatGatewayAmount(price, currency)},
+	}}
+	return c.do(ctx, http.MethodPatch, path, body, "", nil)
+}
+
+// UpdateSubscriptionTax replaces the tax percentage PayPal adds to the price.
+func (c *PayPalClient) UpdateSubscriptionTax(ctx context.Context, subscriptionID string, percentage float64) error {
+	path := "/v1/billing/subscriptions/" + url.PathEscape(subscriptionID)
+	body := []map[string]interface{}{{
+		"op":    "replace",
+		"path":  "/plan/taxes/percentage",
+		"value": strconv.FormatFloat(percentage, 'f', -1, 64),
+	}}
+	return c.do(ctx, http.MethodPatch, path, body, "", nil)
+}
+
+// payPalTaxes is charged on top of the price, which excludes tax.
+func payPalTaxes(percentage float64) map[string]interface{} {
+	return map[string]interface{}{
+		"percentage": strconv.FormatFloat(percentage, 'f', -1, 64),
+		"inclusive":  false,
+	}
+}
+
+func payPalPricingScheme(price int64, currency string) map[string]interface{} {
+	return map[string]interface{}{
+		"fixed_price": payPalMoney{CurrencyCode: currency, Value: formatGatewayAmount(price, currency)},
+	}
+}
+
+func (c *PayPalClient) ActivateSubscription(ctx context.Context, subscriptionID, reason string) error {
+	return c.subscriptionAction(ctx, subscriptionID, "activate", reason)
+}
+
+func (c *PayPalClient) SuspendSubscription(ctx context.Context, subscriptionID, reason string) error {
+	return c.subscriptionAction(ctx, subscriptionID, "suspend", reason)
+}
+
+func (c *PayPalClient) CancelSubscription(ctx context.Context, subscriptionID, reason string) error {
+	return c.subscriptionAction(ctx, subscriptionID, "cancel", reason)
+}
+
+func (c *PayPalClient) subscriptionAction(ctx context.Context, subscriptionID, action, reason string) error {
+	path := "/v1/billing/subscriptions/" + url.PathEscape(subscriptionID) + "/" + action
+	return c.do(ctx, http.MethodPost, path, map[string]interface{}{"reason": reason}, "", nil)
+}
+
+func payPalPurchaseUnit(referenceID, customID, invoiceNumber, description string, amount int64, currency string) map[string]interface{} {
+	purchaseUnit := map[string]interface{}{
+		"reference_id": referenceID,
+		"custom_id":    customID,
+		"description":  description,
+		"amount":       payPalMoney{CurrencyCode: currency, Value: formatGatewayAmount(amount, currency)},
+	}
+	if invoiceNumber != "" {
+		purchaseUnit["invoice_id"] = invoiceNumber
+	}
+	return purchaseUnit
+}
+
+func (c *PayPalClient) CreateOrder(ctx context.Context, request GatewayOrderRequest) (*GatewayOrder, error) {
+	purchaseUnit := payPalPurchaseUnit(request.ReferenceID, request.CustomID, request.InvoiceNumber, request.Description, request.Amount, request.Currency)
+
+	body := map[string]interface{}{
+		"intent":         "CAPTURE",
+		"purchase_units": []map[string]interface{}{purchaseUnit},
+		"application_context": map[string]interface{}{
+			"brand_name":          c.BrandName,
+			"user_action":         "PAY_NOW",
+			"shipping_preference": "NO_SHIPPING",
+			"return_url":          request.ReturnURL,
+			"cancel_url":          request.CancelURL,
+		},
+	}
+
+	var response struct {
+		ID     string       `json:"id"`
+		Status string       `json:"status"`
+		Links  []payPalLink `json:"links"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v2/checkout/orders", body, request.IdempotencyKey, &response); err != nil {
+		return nil, err
+	}
+	return &GatewayOrder{ID: response.ID, Status: response.Status, ApprovalURL: payPalApprovalURL(response.Links)}, nil
+}
+
+// payPalOrderResponse is an order as returned once it has been captured.
+type payPalOrderResponse struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Payer  struct {
+		PayerID      string `json:"payer_id"`
+		EmailAddress string `json:"email_address"`
+	} `json:"payer"`
+	PurchaseUnits []struct {
+		Payments struct {
+			Captures []struct {
+				ID       string      `json:"id"`
+				Status   string      `json:"status"`
+				Amount   payPalMoney `json:"amount"`
+				CustomID string      `json:"custom_id"`
+			} `json:"captures"`
+		} `json:"payments"`
+	} `json:"purchase_units"`
+}
+
+func (r payPalOrderResponse) capture() *GatewayCapture {
+	if len(r.PurchaseUnits) == 0 || len(r.PurchaseUnits[0].Payments.Captures) == 0 {
+		return nil
+	}
+	capture := r.PurchaseUnits[0].Payments.Captures[0]
+	return &GatewayCapture{
+		ID:         capture.ID,
+		OrderID:    r.ID,
+		Status:     capture.Status,
+		Amount:     parseGatewayAmount(capture.Amount.Value, capture.Amount.CurrencyCode),
+		Currency:   capture.Amount.CurrencyCode,
+		CustomID:   capture.CustomID,
+		PayerID:    r.Payer.PayerID,
+		PayerEmail: r.Payer.EmailAddress,
+	}
+}
+
+func (c *PayPalClient) CaptureOrder(ctx context.Context, orderID, idempotencyKey string) (*GatewayCapture, error) {
+	var response payPalOrderResponse
+	path := "/v2/checkout/orders/" + url.PathEscape(orderID) + "/capture"
+	if err := c.do(ctx, http.MethodPost, path, map[string]interface{}{}, idempotencyKey, &response); err != nil {
+		return nil, err
+	}
+
+	capture := response.capture()
+	if capture == nil {
+		return nil, fmt.Errorf("paypal order %s returned no capture", orderID)
+	}
+	return capture, nil
+}
+
+// ChargePaymentMethod creates an order paid from a vaulted PayPal account. PayPal
+// usually captures such orders on creation; otherwise the order is captured here.
+func (c *PayPalClient) ChargePaymentMethod(ctx context.Context, request GatewayChargeRequest) (*GatewayCapture, error) {
+	body := map[string]interface{}{
+		"intent":         "CAPTURE",
+		"purchase_units": []map[string]interface{}{payPalPurchaseUnit(request.ReferenceID, request.CustomID, request.InvoiceNumber, request.Description, request.Amount, request.Currency)},
+		"payment_source": map[string]interface{}{
+			"paypal": map[string]interface{}{"vault_id": request.VaultID},
+		},
+	}
+
+	var response payPalOrderResponse
+	if err := c.do(ctx, http.MethodPost, "/v2/checkout/orders", body, request.IdempotencyKey, &response); err != nil {
+		return nil, err
+	}
+	if capture := response.capture(); capture != nil {
+		return capture, nil
+	}
+
+	captureKey := ""
+	if request.IdempotencyKey != "" {
+		captureKey = request.IdempotencyKey + "-capture"
+	}
+	return c.CaptureOrder(ctx, response.ID, captureKey)
+}
+
+func (c *PayPalClient) RefundCapture(ctx context.Context, request GatewayRefundRequest) (*GatewayRefund, error) {
+	body := map[string]interface{}{}
+	if request.Amount != nil {
+		body["amount"] = payPalMoney{CurrencyCode: request.Currency, Value: formatGatewayAmount(*request.Amount, request.Currency)}
+	}
+	if request.InvoiceNumber != "" {
+		body["invoice_id"] = request.InvoiceNumber
+	}
+	if request.NoteToPayer != "" {
+		body["note_to_payer"] = request.NoteToPayer
+	}
+
+	var response struct {
+		ID     string      `json:"id"`
+		Status string      `json:"status"`
+		Amount payPalMoney `json:"amount"`
+	}
+	path := "/v2/payments/captures/" + url.PathEscape(request.CaptureID) + "/refund"
+	if err := c.do(ctx, http.MethodPost, path, body, request.IdempotencyKey, &response); err != nil {
+		return nil, err
+	}
+	return &GatewayRefund{
+		ID:       response.ID,
+		Status:   response.Status,
+		Amount:   parseGatewayAmount(response.Amount.Value, response.Amount.CurrencyCode),
+		Currency: response.Amount.CurrencyCode,
+	}, nil
+}
+
+// CreateSetupToken asks PayPal to save the payer's PayPal account in its vault,
+// for charges made without the payer (merchant-initiated).
+func (c *PayPalClient) CreateSetupToken(ctx context.Context, request GatewaySetupTokenRequest) (*GatewaySetupToken, error) {
+	body := map[string]interface{}{
+		"payment_source": map[string]interface{}{
+			"paypal": map[string]interface{}{
+				"usage_type":                     "MERCHANT",
+				"permit_multiple_payment_tokens": true,
+				"experience_context": map[string]interface{}{
+					"brand_name":          c.BrandName,
+					"shipping_preference": "NO_SHIPPING",
+					"return_url":          request.ReturnURL,
+					"cancel_url":          request.CancelURL,
+				},
+			},
+		},
+	}
+
+	var response struct {
+		ID     string       `json:"id"`
+		Status string       `json:"status"`
+		Links  []payPalLink `json:"links"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v3/vault/setup-tokens", body, request.IdempotencyKey, &response); err != nil {
+		return nil, err
+	}
+	return &GatewaySetupToken{ID: response.ID, Status: response.Status, ApprovalURL: payPalApprovalURL(response.Links)}, nil
+}
+
+func (c *PayPalClient) CreatePaymentToken(ctx context.Context, setupTokenID, idempotencyKey string) (*GatewayPaymentToken, error) {
+	body := map[string]interface{}{
+		"payment_source": map[string]interface{}{
+			"token": map[string]interface{}{"id": setupTokenID, "type": "SETUP_TOKEN"},
+		},
+	}
+
+	var response struct {
+		ID       string `json:"id"`
+		Customer struct {
+			ID string `json:"id"`
+		} `json:"customer"`
+		PaymentSource struct {
+			PayPal struct {
+				EmailAddress string `json:"email_address"`
+				AccountID    string `json:"account_id"`
+			} `json:"paypal"`
+		} `json:"payment_source"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v3/vault/payment-tokens", body, idempotencyKey, &response); err != nil {
+		return nil, err
+	}
+	return &GatewayPaymentToken{
+		ID:         response.ID,
+		CustomerID: response.Customer.ID,
+		PayerID:    response.PaymentSource.PayPal.AccountID,
+		PayerEmail: response.PaymentSource.PayPal.EmailAddress,
+	}, nil
+}
+
+func (c *PayPalClient) DeletePaymentToken(ctx context.Context, paymentTokenID string) error {
+	return c.do(ctx, http.MethodDelete, "/v3/vault/payment-tokens/"+url.PathEscape(paymentTokenID), nil, "", nil)
+}
+
+// VerifyWebhook asks PayPal to check the transmission signature against the
+// webhook registered as WebhookID, so a forged or replayed-to-another-app
+// delivery is rejected.
+func (c *PayPalClient) VerifyWebhook(ctx context.Context, webhook GatewayWebhook) error {
+	if c.WebhookID == "" {
+		return ErrPaymentGatewayNotConfigured
+	}
+	if webhook.TransmissionID == "" || webhook.TransmissionSig == "" || webhook.CertURL == "" || !json.Valid(webhook.Body) {
+		return ErrWebhookSignatureInvalid
+	}
+
+	body := map[string]interface{}{
+		"transmission_id":   webhook.TransmissionID,
+		"transmission_time": webhook.TransmissionTime,
+		"transmission_sig":  webhook.TransmissionSig,
+		"cert_url":          webhook.CertURL,
+		"auth_algo":         webhook.AuthAlgo,
+		"webhook_id":        c.WebhookID,
+		"webhook_event":     json.RawMessage(webhook.Body),
+	}
+
+	var response struct {
+		VerificationStatus string `json:"verification_status"`
+	}
+	if err := c.do(ctx, http.MethodPost, "/v1/notifications/verify-webhook-signature", body, "", &response); err != nil {
+		return err
+	}
+	if response.VerificationStatus != "SUCCESS" {
+		return ErrWebhookSignatureInvalid
+	}
+	return nil
+}
+
+// PayPalWebhookEvent is the envelope of every PayPal webhook.
+type PayPalWebhookEvent struct {
+	ID           string          `json:"id"`
+	EventType    string          `json:"event_type"`
+	ResourceType string          `json:"resource_type"`
+	Summary      string          `json:"summary"`
+	CreateTime   time.Time       `json:"create_time"`
+	Resource     json.RawMessage `json:"resource"`
+}
+
+// PayPalWebhookResource holds the resource fields used by the billing events.
+// Which of them are set depends on the resource type.
+type PayPalWebhookResource struct {
+	ID                 string `json:"id"`
+	Status             string `json:"status"`
+	State              string `json:"state"`
+	CustomID           string `json:"custom_id"`
+	Custom             string `json:"custom"`
+	PlanID             string `json:"plan_id"`
+	BillingAgreementID string `json:"billing_agreement_id"`
+	Amount             struct {
+		Total    string `json:"total"`
+		Currency string `json:"currency"`
+	} `json:"amount"`
+	BillingInfo struct {
+		NextBillingTime   *time.Time `json:"next_billing_time"`
+		LastFailedPayment *struct {
+			Amount     payPalMoney `json:"amount"`
+			ReasonCode string      `json:"reason_code"`
+		} `json:"last_failed_payment"`
+	} `json:"billing_info"`
+	Invoice *struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	} `json:"invoice"`
+}
+
+// SaleAmount is the amount of a PAYMENT.SALE resource, in minor units.
+func (r PayPalWebhookResource) SaleAmount() (int64, string) {
+	return parseGatewayAmount(r.Amount.Total, r.Amount.Currency), r.Amount.Currency
+}
+
+// FailedPayment returns the amount, in minor units, and reason of the last
+// failed subscription payment.
+func (r PayPalWebhookResource) FailedPayment() (int64, string, string) {
+	if r.BillingInfo.LastFailedPayment == nil {
+		return 0, "", ""
+	}
+	failed := r.BillingInfo.LastFailedPayment
+	return parseGatewayAmount(failed.Amount.Value, failed.Amount.CurrencyCode), failed.Amount.CurrencyCode, failed.ReasonCode
+}
+
+// CustomValue is the custom id set when the resource was created.
+func (r PayPalWebhookResource) CustomValue() string {
+	if r.CustomID != "" {
+		return r.CustomID
+	}
+	return r.Custom
+}
+
+// InvoiceID is the PayPal invoice the resource refers to.
+func (r PayPalWebhookResource) InvoiceID() string {
+	if r.Invoice != nil && r.Invoice.ID != "" {
+		return r.Invoice.ID
+	}
+	return r.ID
+}
+
+// do sends an authenticated JSON request. A token rejected by the API is
+// refreshed once; a rejected client secret is not retried.
+func (c *PayPalClient) do(ctx context.Context, method, path string, body interface{}, idempotencyKey string, out interface{}) error {
+	token, err := c.token(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.send(ctx, token, method, path, body, idempotencyKey, out)
+	if gatewayErr, ok := err.(*GatewayError); ok && gatewayErr.StatusCode == http.StatusUnauthorized {
+		c.invalidateToken()
+		if token, err = c.token(ctx); err != nil {
+			return err
+		}
+		err = c.send(ctx, token, method, path, body, idempotencyKey, out)
+	}
+	return err
+}
+
+func (c *PayPalClient) send(ctx context.Context, token, method, path string, body interface{}, idempotencyKey string, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(encoded)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, payload)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+token)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("Prefer", "return=representation")
+	if idempotencyKey != "" {
+		// PayPal replays the original response for a repeated request id
+		request.Header.Set("PayPal-Request-Id", idempotencyKey)
+	}
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	return decodePayPalResponse(response, out)
+}
+
+func (c *PayPalClient) token(ctx context.Context) (string, error) {
+	c.tokenMutex.Lock()
+	defer c.tokenMutex.Unlock()
+
+	if c.accessToken != "" && time.Now().Before(c.tokenExpiresAt) {
+		return c.accessToken, nil
+	}
+	if c.ClientID == "" || c.ClientSecret == "" {
+		return "", ErrPaymentGatewayNotConfigured
+	}
+
+	form := url.Values{"grant_type": {"client_credentials"}}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/v1/oauth2/token", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.SetBasicAuth(c.ClientID, c.ClientSecret)
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	var tokenResponse struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := decodePayPalResponse(response, &tokenResponse); err != nil {
+		return "", err
+	}
+
+	// Renew a minute early so that a token never expires mid-request
+	c.accessToken = tokenResponse.AccessToken
+	c.tokenExpiresAt = time.Now().Add(time.Duration(tokenResponse.ExpiresIn)*time.Second - time.Minute)
+	return c.accessToken, nil
+}
+
+func (c *PayPalClient) invalidateToken() {
+	c.tokenMutex.Lock()
+	c.accessToken = ""
+	c.tokenMutex.Unlock()
+}
+
+func decodePayPalResponse(response *http.Response, out interface{}) error {
+	data, err := io.ReadAll(io.LimitReader(response.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		var apiError struct {
+			Name    string `json:"name"`
+			Message string `json:"message"`
+			DebugID string `json:"debug_id"`
+			Details []struct {
+				Issue string `json:"issue"`
+			} `json:"details"`
+			Error            string `json:"error"`
+			ErrorDescription string `json:"error_description"`
+		}
+		json.Unmarshal(data, &apiError)
+
+		gatewayErr := &GatewayError{
+			StatusCode: response.StatusCode,
+			Name:       apiError.Name,
+			Message:    apiError.Message,
+			DebugID:    apiError.DebugID,
+		}
+		if len(apiError.Details) > 0 {
+			gatewayErr.Issue = apiError.Details[0].Issue
+		}
+		// The OAuth2 endpoint reports errors in the RFC 6749 format
+		if gatewayErr.Name == "" {
+			gatewayErr.Name = apiError.Error
+			gatewayErr.Message = apiError.ErrorDescription
+		}
+		return gatewayErr
+	}
+
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
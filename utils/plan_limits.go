@@ -0,0 +1,214 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"testlake/dao"
+	"testlake/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LimitCheck tells whether an organization can add a number of resources
+// within the limits of its plan.
+type LimitCheck struct {
+	Resource  string `json:"resource"`
+	Used      int    `json:"used"`
+	Limit     int    `json:"limit"`     // negative when unlimited
+	Remaining int    `json:"remaining"` // negative when unlimited
+	Requested int    `json:"requested"`
+	Allowed   bool   `json:"allowed"`
+}
+
+// UpgradeOption is a plan whose limit would allow what a LimitCheck refused.
+type UpgradeOption struct {
+	PlanID       uuid.UUID `json:"plan_id"`
+	Name         string    `json:"name"`
+	Slug         string    `json:"slug"`
+	Limit        int       `json:"limit"` // negative when unlimited
+	PriceMonthly float64   `json:"price_monthly"`
+	PriceYearly  float64   `json:"price_yearly"`
+	Currency     string    `json:"currency"`
+}
+
+type LimitExceeded struct {
+	LimitCheck
+	PlanID         *uuid.UUID      `json:"plan_id"`
+	PlanName       string          `json:"plan_name"`
+	UpgradeOptions []UpgradeOption `json:"upgrade_options"`
+}
+
+// LimitExceededError is the 402 response sent when a plan limit blocks a change.
+type LimitExceededError struct {
+	ErrorCode        int           `json:"error_code"`
+	ErrorDescription string        `json:"error_description"`
+	Data             LimitExceeded `json:"data"`
+}
+
+// OrganizationLimits is the plan of an organization along with its live usage,
+// the one place quota is checked before anything consuming it is created.
+type OrganizationLimits struct {
+	OrganizationID uuid.UUID
+	// Plan is the organization's plan. Organizations created before plans have a
+	// plan built from their own MaxUsers and MaxProjects, with a nil ID.
+	Plan  *model.Plan
+	Usage *model.UsageCounts
+	// Currency and BillingCycle are what the organization pays in, to price
+	// the upgrade options.
+	Currency     string
+	BillingCycle model.BillingCycle
+}
+
+// LoadOrganizationLimits counts the live usage of the organization and loads its plan.
+func LoadOrganizationLimits(organizationID uuid.UUID) (*OrganizationLimits, error) {
+	org, err := dao.NewOrganizationDao().GetByID(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &model.Plan{
+		Name:                    string(org.PlanType),
+		MaxUsers:                org.MaxUsers,
+		MaxProjects:             org.MaxProjects,
+		MaxEnvironments:         -1,
+		MaxSchemas:              -1,
+		MaxTestRecordsPerSchema: -1,
+	}
+	if org.PlanID != nil {
+		plan, err = dao.NewPlanDao().GetByID(*org.PlanID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	usage, err := dao.NewOrganizationUsageDao().CountLiveUsage(organizationID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrganizationLimits{OrganizationID: organizationID, Plan: plan, Usage: usage, Currency: org.Currency, BillingCycle: org.BillingCycle}, nil
+}
+
+// Check tells whether requested more of the resource fit in the plan.
+func (l *OrganizationLimits) Check(resource string, requested int) LimitCheck {
+	check := LimitCheck{
+		Resource:  resource,
+		Used:      l.Usage.Used(resource),
+		Limit:     l.Plan.Limit(resource),
+		Remaining: -1,
+		Requested: requested,
+		Allowed:   true,
+	}
+	if check.Limit >= 0 {
+		check.Remaining = check.Limit - check.Used
+		if check.Remaining < 0 {
+			check.Remaining = 0
+		}
+		check.Allowed = check.Used+requested <= check.Limit
+	}
+	return check
+}
+
+// CheckAll checks every limited resource for one more of it.
+func (l *OrganizationLimits) CheckAll() []LimitCheck {
+	checks := make([]LimitCheck, 0, len(model.LimitResources))
+	for _, resource := range model.LimitResources {
+		checks = append(checks, l.Check(resource, 1))
+	}
+	return checks
+}
+
+// UpgradeOptions returns the active paid plans whose limit fits the usage the
+// check asked for, priced in the organization's currency and cheapest first in
+// its billing cycle. Plans not priced in the currency are left out.
+func (l *OrganizationLimits) UpgradeOptions(check LimitCheck) ([]UpgradeOption, error) {
+	plans, err := dao.NewPlanDao().GetAll()
+	if err != nil {
+		return nil, err
+	}
+	currency := l.Currency
+	if currency == "" {
+		currency = model.DefaultCurrency
+	}
+	cycle := l.BillingCycle
+	if cycle == "" {
+		cycle = model.BillingCycleMonthly
+	}
+
+	options := []UpgradeOption{}
+	prices := map[uuid.UUID]int64{}
+	for i := range plans {
+		plan := &plans[i]
+		limit := plan.Limit(check.Resource)
+		price, found := plan.PriceIn(currency, cycle)
+		if plan.ID == l.Plan.ID || !found || price <= 0 || (limit >= 0 && limit < check.Used+check.Requested) {
+			continue
+		}
+		monthly, _ := plan.PriceIn(currency, model.BillingCycleMonthly)
+		yearly, _ := plan.PriceIn(currency, model.BillingCycleYearly)
+		prices[plan.ID] = price
+		options = append(options, UpgradeOption{
+			PlanID:       plan.ID,
+			Name:         plan.Name,
+			Slug:         plan.Slug,
+			Limit:        limit,
+			PriceMonthly: model.FromMinorUnits(monthly, currency),
+			PriceYearly:  model.FromMinorUnits(yearly, currency),
+			Currency:     currency,
+		})
+	}
+	sort.SliceStable(options, func(i, j int) bool { return prices[options[i].PlanID] < prices[options[j].PlanID] })
+	return options, nil
+}
+
+// EnforceLimit checks that the organization can add requested more of the
+// resource. When it cannot, or the check fails, the error response is sent and
+// false is returned: the handler must stop.
+func EnforceLimit(c *gin.Context, organizationID uuid.UUID, resource string, requested int) bool {
+	limits, err := LoadOrganizationLimits(organizationID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ReportNotFound(c, "Organization not found")
+		} else {
+			ReportInternalServerError(c, "Failed to check plan limits")
+		}
+		return false
+	}
+
+	check := limits.Check(resource, requested)
+	if check.Allowed {
+		return true
+	}
+	ReportLimitExceeded(c, limits, check)
+	return false
+}
+
+// ReportLimitExceeded sends the 402 response naming the limit and the plans
+// that would allow the change.
+func ReportLimitExceeded(c *gin.Context, limits *OrganizationLimits, check LimitCheck) {
+	options, err := limits.UpgradeOptions(check)
+	if err != nil {
+		ReportInternalServerError(c, "Failed to load upgrade options")
+		return
+	}
+
+	var planID *uuid.UUID
+	if limits.Plan.ID != uuid.Nil {
+		planID = &limits.Plan.ID
+	}
+	c.JSON(http.StatusPaymentRequired, LimitExceededError{
+		ErrorCode:        http.StatusPaymentRequired,
+		ErrorDescription: fmt.Sprintf("Plan limit reached: %s (%d of %d used)", check.Resource, check.Used, check.Limit),
+		Data: LimitExceeded{
+			LimitCheck:     check,
+			PlanID:         planID,
+			PlanName:       limits.Plan.Name,
+			UpgradeOptions: options,
+		},
+	})
+}
@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"math"
+	"time"
+)
+
+// Proration is the adjustment billed when a subscription changes plan mid-period.
+type Proration struct {
+	// RemainingFraction is the share of the current period left at the change, 0 to 1.
+	RemainingFraction float64
+	// Credit is the unused part of the current price, refunded as a negative line item.
+	Credit int64
+	// Charge is what the new plan costs until the period ends, or a full period
+	// when the change restarts the period.
+	Charge int64
+}
+
+// Net is the amount due for the change; negative when the credit exceeds the charge.
+func (p Proration) Net() int64 {
+	return p.Charge - p.Credit
+}
+
+// CalculateProration prorates a change from currentPrice to newPrice made at the
+// given time within the period [periodStart, periodEnd). Both prices are for a
+// full period, in minor units. With restartPeriod, used when the billing cycle changes, the new
+// plan starts a fresh period so its full price is charged.
+func CalculateProration(currentPrice, newPrice int64, periodStart, periodEnd, at time.Time, restartPeriod bool) Proration {
+	remaining := 0.0
+	if total := periodEnd.Sub(periodStart); total > 0 {
+		remaining = float64(periodEnd.Sub(at)) / float64(total)
+	}
+	remaining = math.Max(0, math.Min(1, remaining))
+
+	charge := RoundMoney(float64(newPrice) * remaining)
+	if restartPeriod {
+		charge = newPrice
+	}
+
+	return Proration{
+		RemainingFraction: remaining,
+		Credit:            RoundMoney(float64(currentPrice) * remaining),
+		Charge:            charge,
+	}
+}
+
+// RoundMoney rounds a fraction of minor units, e.g. a share of a price, to the
+// nearest whole unit.
+func RoundMoney(amount float64) int64 {
+	return int64(math.Round(amount))
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testlake/model"
+)
+
+// LookupTXT resolves the TXT records of a DNS name. Tests replace it.
+var LookupTXT = net.LookupTXT
+
+// HasDomainVerificationRecord reports whether the domain publishes the TXT
+// record that proves the organization controls it.
+func HasDomainVerificationRecord(domain *model.OrganizationSSODomain) (bool, error) {
+	records, err := LookupTXT(domain.TXTRecordName())
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, record := range records {
+		if strings.TrimSpace(record) == domain.TXTRecordValue() {
+			return true, nil
+		}
+	}
+	return false, nil
+}
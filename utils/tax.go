@@ -0,0 +1,149 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TaxRequest is what is taxed: the amount of an invoice, after discounts, and
+// who it is billed to.
+type TaxRequest struct {
+	Amount   int64 // minor units of Currency
+	Currency string
+	Country  string // ISO 3166-1 alpha-2 billing country, empty when unknown
+	TaxID    string // business tax ID of the customer, empty for consumers
+}
+
+// TaxResult is the tax due on an amount. Description labels the tax on
+// invoices, e.g. "VAT 19% (DE)"; it is empty when no tax applies.
+type TaxResult struct {
+	Rate          float64 // percent
+	Amount        int64   // minor units of the request currency
+	ReverseCharge bool    // the customer accounts for the tax, nothing is charged
+	Description   string
+}
+
+// TaxCalculator computes the tax of invoices. TableTaxCalculator is the
+// default; SetTaxCalculator replaces it, e.g. with a tax service.
+type TaxCalculator interface {
+	Calculate(request TaxRequest) (TaxResult, error)
+}
+
+// TaxRate is the tax of a country and the format of its business tax IDs.
+type TaxRate struct {
+	Name  string
+	Rate  float64 // percent
+	TaxID *regexp.Regexp
+}
+
+// DefaultTaxRates are the standard VAT rates of the EU member states, the UK,
+// Norway and Switzerland. Tax IDs are matched without spaces, dots or dashes.
+var DefaultTaxRates = map[string]TaxRate{
+	"AT": {Name: "VAT", Rate: 20, TaxID: regexp.MustCompile(`^ATU\d{8}$`)},
+	"BE": {Name: "VAT", Rate: 21, TaxID: regexp.MustCompile(`^BE[01]\d{9}$`)},
+	"BG": {Name: "VAT", Rate: 20, TaxID: regexp.MustCompile(`^BG\d{9,10}$`)},
+	"CY": {Name: "VAT", Rate: 19, TaxID: regexp.MustCompile(`^CY\d{8}[A-Z]$`)},
+	"CZ": {Name: "VAT", Rate: 21, TaxID: regexp.MustCompile(`^CZ\d{8,10}$`)},
+	"DE": {Name: "VAT", Rate: 19, TaxID: regexp.MustCompile(`^DE\d{9}$`)},
+	"DK": {Name: "VAT", Rate: 25, TaxID: regexp.MustCompile(`^DK\d{8}$`)},
+	"EE": {Name: "VAT", Rate: 24, TaxID: regexp.MustCompile(`^EE\d{9}$`)},
+	"ES": {Name: "VAT", Rate: 21, TaxID: regexp.MustCompile(`^ES[0-9A-Z]\d{7}[0-9A-Z]$`)},
+	"FI": {Name: "VAT", Rate: 25.5, TaxID: regexp.MustCompile(`^FI\d{8}$`)},
+	"FR": {Name: "VAT", Rate: 20, TaxID: regexp.MustCompile(`^FR[0-9A-Z]{2}\d{9}$`)},
+	"GR": {Name: "VAT", Rate: 24, TaxID: regexp.MustCompile(`^EL\d{9}$`)},
+	"HR": {Name: "VAT", Rate: 25, TaxID: regexp.MustCompile(`^HR\d{11}$`)},
+	"HU": {Name: "VAT", Rate: 27, TaxID: regexp.MustCompile(`^HU\d{8}$`)},
+	"IE": {Name: "VAT", Rate: 23, TaxID: regexp.MustCompile(`^IE(\d{7}[A-Z]{1,2}|\d[A-Z+*]\d{5}[A-Z])$`)},
+	"IT": {Name: "VAT", Rate: 22, TaxID: regexp.MustCompile(`^IT\d{11}$`)},
+	"LT": {Name: "VAT", Rate: 21, TaxID: regexp.MustCompile(`^LT(\d{9}|\d{12})$`)},
+	"LU": {Name: "VAT", Rate: 17, TaxID: regexp.MustCompile(`^LU\d{8}$`)},
+	"LV": {Name: "VAT", Rate: 21, TaxID: regexp.MustCompile(`^LV\d{11}$`)},
+	"MT": {Name: "VAT", Rate: 18, TaxID: regexp.MustCompile(`^MT\d{8}$`)},
+	"NL": {Name: "VAT", Rate: 21, TaxID: regexp.MustCompile(`^NL\d{9}B\d{2}$`)},
+	"PL": {Name: "VAT", Rate: 23, TaxID: regexp.MustCompile(`^PL\d{10}$`)},
+	"PT": {Name: "VAT", Rate: 23, TaxID: regexp.MustCompile(`^PT\d{9}$`)},
+	"RO": {Name: "VAT", Rate: 21, TaxID: regexp.MustCompile(`^RO\d{2,10}$`)},
+	"SE": {Name: "VAT", Rate: 25, TaxID: regexp.MustCompile(`^SE\d{12}$`)},
+	"SI": {Name: "VAT", Rate: 22, TaxID: regexp.MustCompile(`^SI\d{8}$`)},
+	"SK": {Name: "VAT", Rate: 23, TaxID: regexp.MustCompile(`^SK\d{10}$`)},
+	"GB": {Name: "VAT", Rate: 20, TaxID: regexp.MustCompile(`^GB(\d{9}|\d{12}|GD\d{3}|HA\d{3})$`)},
+	"NO": {Name: "VAT", Rate: 25, TaxID: regexp.MustCompile(`^NO\d{9}(MVA)?$`)},
+	"CH": {Name: "VAT", Rate: 8.1, TaxID: regexp.MustCompile(`^CHE\d{9}(MWST|TVA|IVA)?$`)},
+}
+
+// TableTaxCalculator taxes customers at the rate of their billing country.
+// Businesses with a valid tax ID outside OriginCountry, the country the
+// invoices are issued from, are reverse charged. Countries not in Rates are
+// not taxed.
+type TableTaxCalculator struct {
+	Rates         map[string]TaxRate
+	OriginCountry string
+}
+
+// NewTableTaxCalculator uses DefaultTaxRates, issuing invoices from
+// TAX_ORIGIN_COUNTRY.
+func NewTableTaxCalculator() *TableTaxCalculator {
+	return &TableTaxCalculator{Rates: DefaultTaxRates, OriginCountry: strings.ToUpper(os.Getenv("TAX_ORIGIN_COUNTRY"))}
+}
+
+func (c *TableTaxCalculator) Calculate(request TaxRequest) (TaxResult, error) {
+	country := strings.ToUpper(strings.TrimSpace(request.Country))
+	rate, found := c.Rates[country]
+	if !found {
+		return TaxResult{}, nil
+	}
+
+	if request.TaxID != "" && country != c.OriginCountry && rate.TaxID != nil && rate.TaxID.MatchString(NormalizeTaxID(request.TaxID)) {
+		return TaxResult{ReverseCharge: true, Description: "Reverse charge"}, nil
+	}
+
+	var amount int64
+	if request.Amount > 0 {
+		amount = RoundMoney(float64(request.Amount) * rate.Rate / 100)
+	}
+	return TaxResult{
+		Rate:        rate.Rate,
+		Amount:      amount,
+		Description: fmt.Sprintf("%s %s%% (%s)", rate.Name, strconv.FormatFloat(rate.Rate, 'f', -1, 64), country),
+	}, nil
+}
+
+// NormalizeTaxID upper-cases a tax ID and drops the separators people type in it.
+func NormalizeTaxID(taxID string) string {
+	return strings.ToUpper(strings.NewReplacer(" ", "", ".", "", "-", "").Replace(taxID))
+}
+
+var (
+	taxCalculatorMutex sync.RWMutex
+	taxCalculator      TaxCalculator
+)
+
+// GetTaxCalculator returns the configured tax calculator, the table of
+// DefaultTaxRates unless SetTaxCalculator replaced it.
+func GetTaxCalculator() TaxCalculator {
+	taxCalculatorMutex.RLock()
+	calculator := taxCalculator
+	taxCalculatorMutex.RUnlock()
+	if calculator != nil {
+		return calculator
+	}
+
+	taxCalculatorMutex.Lock()
+	defer taxCalculatorMutex.Unlock()
+	if taxCalculator == nil {
+		taxCalculator = NewTableTaxCalculator()
+	}
+	return taxCalculator
+}
+
+// SetTaxCalculator replaces the tax calculator. Passing nil makes the next
+// GetTaxCalculator call read the environment again.
+func SetTaxCalculator(calculator TaxCalculator) {
+	taxCalculatorMutex.Lock()
+	taxCalculator = calculator
+	taxCalculatorMutex.Unlock()
+}
@@ -0,0 +1,18 @@
+package utils
+
+import "time"
+
+// TrialConfig controls the free trials of paid plans. Every value can be
+// overridden through the environment, see .env.example.
+type TrialConfig struct {
+	Length time.Duration
+	// ReminderBefore is how long before the end of a trial the organization is reminded
+	ReminderBefore time.Duration
+}
+
+func LoadTrialConfig() TrialConfig {
+	return TrialConfig{
+		Length:         time.Duration(envInt("TRIAL_DAYS", 14)) * 24 * time.Hour,
+		ReminderBefore: time.Duration(envInt("TRIAL_REMINDER_DAYS", 3)) * 24 * time.Hour,
+	}
+}
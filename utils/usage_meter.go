@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"testlake/dao"
+
+	"github.com/google/uuid"
+)
+
+// UsageMeter counts API requests per organization in memory and adds them to
+// the organizations' usage records when flushed, so that metering costs a
+// write per organization and flush rather than one per request.
+type UsageMeter struct {
+	mu       sync.Mutex
+	requests map[uuid.UUID]int
+}
+
+func NewUsageMeter() *UsageMeter {
+	return &UsageMeter{requests: map[uuid.UUID]int{}}
+}
+
+// RecordRequest counts one API request of the organization.
+func (m *UsageMeter) RecordRequest(organizationID uuid.UUID) {
+	m.mu.Lock()
+	m.requests[organizationID]++
+	m.mu.Unlock()
+}
+
+// Pending returns the requests of the organization not flushed yet.
+func (m *UsageMeter) Pending(organizationID uuid.UUID) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.requests[organizationID]
+}
+
+// Flush adds the counted requests to the usage of the period of now. Counts
+// that fail to be written are kept for the next flush.
+func (m *UsageMeter) Flush(now time.Time) error {
+	m.mu.Lock()
+	requests := m.requests
+	m.requests = map[uuid.UUID]int{}
+	m.mu.Unlock()
+
+	usageDao := dao.NewOrganizationUsageDao()
+	var firstErr error
+	for organizationID, count := range requests {
+		if err := usageDao.AddAPIRequests(organizationID, count, now); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			m.mu.Lock()
+			m.requests[organizationID] += count
+			m.mu.Unlock()
+		}
+	}
+	return firstErr
+}
+
+// Run flushes the meter every interval until ctx is done, then one last time.
+func (m *UsageMeter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.Flush(time.Now()); err != nil {
+				log.Printf("Failed to flush API usage: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := m.Flush(time.Now()); err != nil {
+				log.Printf("Failed to flush API usage: %v", err)
+			}
+		}
+	}
+}
+
+var apiUsageMeter = NewUsageMeter()
+
+// APIUsageMeter returns the meter of the API requests served by this instance.
+func APIUsageMeter() *UsageMeter {
+	return apiUsageMeter
+}
+
+// LoadUsageFlushInterval reads USAGE_FLUSH_INTERVAL_SECONDS, 60 by default.
+func LoadUsageFlushInterval() time.Duration {
+	seconds := envInt("USAGE_FLUSH_INTERVAL_SECONDS", 60)
+	if seconds <= 0 {
+		seconds = 60
+	}
+	return time.Duration(seconds) * time.Second
+}
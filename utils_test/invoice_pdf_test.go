@@ -0,0 +1,137 @@
+package utils_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createInvoice issues an open invoice with one line item to the organization.
+func createInvoice(t *testing.T, org *model.Organization) *model.Invoice {
+	require.NoError(t, dao.Database.AutoMigrate(&model.Invoice{}, &model.InvoiceLineItem{}, &model.InvoiceDocument{}))
+
+	billingEmail := "billing@acme.test"
+	require.NoError(t, dao.Database.Model(org).Update("billing_email", billingEmail).Error)
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	invoice := &model.Invoice{
+		OrganizationID:     org.ID,
+		InvoiceNumber:      "INV-2025-000042",
+		Amount:             2900,
+		TaxAmount:          609,
+		TotalAmount:        3509,
+		Currency:           "EUR",
+		Status:             model.InvoiceStatusSent,
+		BillingPeriodStart: &start,
+		BillingPeriodEnd:   &end,
+		DueDate:            &start,
+	}
+	require.NoError(t, dao.NewInvoiceDao().CreateWithLineItems(invoice, []model.InvoiceLineItem{
+		{Description: "Starter plan (monthly), Apr 1 - May 1 2025", Quantity: 1, UnitPrice: 2900, TotalPrice: 2900},
+	}))
+
+	loaded, err := dao.NewInvoiceDao().GetByID(invoice.ID)
+	require.NoError(t, err)
+	return loaded
+}
+
+func TestRenderInvoicePDFIsStable(t *testing.T) {
+	org := setupLimits(t)
+	invoice := createInvoice(t, org)
+
+	first, err := utils.RenderInvoicePDF(invoice, utils.InvoiceBrand{CompanyName: "TestLake", AccentColor: "#0055aa"})
+	require.NoError(t, err)
+	second, err := utils.RenderInvoicePDF(invoice, utils.InvoiceBrand{CompanyName: "TestLake", AccentColor: "#0055aa"})
+	require.NoError(t, err)
+
+	assert.True(t, bytes.HasPrefix(first, []byte("%PDF-")))
+	assert.Equal(t, first, second)
+}
+
+func TestInvoiceDocumentIsFinalOncePaid(t *testing.T) {
+	org := setupLimits(t)
+	invoice := createInvoice(t, org)
+	invoiceDao := dao.NewInvoiceDao()
+
+	open, err := utils.InvoiceDocument(invoice)
+	require.NoError(t, err)
+	assert.Equal(t, "INV-2025-000042.pdf", open.Filename)
+	assert.False(t, open.Final)
+
+	cached, err := utils.InvoiceDocument(invoice)
+	require.NoError(t, err)
+	assert.Equal(t, open.Checksum, cached.Checksum)
+
+	invoice.MarkPaid(time.Date(2025, 4, 3, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, invoiceDao.Update(invoice))
+	invoice, err = invoiceDao.GetByID(invoice.ID)
+	require.NoError(t, err)
+	paid, err := utils.InvoiceDocument(invoice)
+	require.NoError(t, err)
+	assert.True(t, paid.Final)
+	assert.NotEqual(t, open.Checksum, paid.Checksum, "the paid invoice shows its payment date")
+
+	invoice.TaxAmount = 0
+	require.NoError(t, invoiceDao.Update(invoice))
+	invoice, err = invoiceDao.GetByID(invoice.ID)
+	require.NoError(t, err)
+	after, err := utils.InvoiceDocument(invoice)
+	require.NoError(t, err)
+	assert.Equal(t, paid.Checksum, after.Checksum)
+}
+
+func TestCreditNoteDocumentIsRenderedOnce(t *testing.T) {
+	org := setupLimits(t)
+	invoice := createInvoice(t, org)
+	require.NoError(t, dao.Database.AutoMigrate(&model.Payment{}, &model.CreditNote{}))
+
+	payment := &model.Payment{OrganizationID: org.ID, InvoiceID: &invoice.ID, Amount: 3509, Currency: "EUR", Status: model.PaymentStatusPartiallyRefunded, RefundedAmount: 1000}
+	require.NoError(t, dao.Database.Create(payment).Error)
+	reason := "Service outage"
+	issuedAt := time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC)
+	creditNote := &model.CreditNote{OrganizationID: org.ID, InvoiceID: invoice.ID, PaymentID: payment.ID, CreditNoteNumber: "CN-2025-000007",
+		Amount: 1000, Currency: "EUR", Reason: &reason, Status: model.CreditNoteStatusIssued, IssuedAt: &issuedAt}
+	require.NoError(t, dao.Database.Create(creditNote).Error)
+
+	creditNote, err := dao.NewCreditNoteDao().GetByID(creditNote.ID)
+	require.NoError(t, err)
+	content, checksum, err := utils.CreditNoteDocument(creditNote)
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(content, []byte("%PDF-")))
+	assert.Equal(t, "CN-2025-000007.pdf", creditNote.PDFFilename())
+
+	// A new brand does not change a credit note already rendered
+	path := filepath.Join(t.TempDir(), "invoice.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"company_name": "Acme Billing"}`), 0o644))
+	t.Setenv("INVOICE_TEMPLATE_PATH", path)
+	creditNote, err = dao.NewCreditNoteDao().GetByID(creditNote.ID)
+	require.NoError(t, err)
+	_, again, err := utils.CreditNoteDocument(creditNote)
+	require.NoError(t, err)
+	assert.Equal(t, checksum, again)
+}
+
+func TestLoadInvoiceBrand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "invoice.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"company_name": "Acme Billing", "accent_color": "#112233"}`), 0o644))
+	t.Setenv("INVOICE_TEMPLATE_PATH", path)
+
+	brand, err := utils.LoadInvoiceBrand()
+	require.NoError(t, err)
+	assert.Equal(t, "Acme Billing", brand.CompanyName)
+	assert.Equal(t, "#112233", brand.AccentColor)
+	assert.NotEmpty(t, brand.Footer, "unset fields keep the default brand")
+
+	t.Setenv("INVOICE_TEMPLATE_PATH", filepath.Join(t.TempDir(), "missing.json"))
+	brand, err = utils.LoadInvoiceBrand()
+	require.NoError(t, err)
+	assert.Equal(t, "TestLake", brand.CompanyName)
+}
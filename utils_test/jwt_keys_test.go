@@ -0,0 +1,145 @@
+package utils_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testlake/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeKeyPair(t *testing.T, dir, name string, privateKey interface{}, publicKey interface{}) (string, string) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+
+	privatePath := filepath.Join(dir, name+".pem")
+	publicPath := filepath.Join(dir, name+".pub.pem")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+	return privatePath, publicPath
+}
+
+func validateToken(token string) error {
+	gin.SetMode(gin.TestMode)
+	context, _ := gin.CreateTestContext(httptest.NewRecorder())
+	context.Request = httptest.NewRequest("GET", "/", nil)
+	context.Request.Header.Set("Authorization", "Bearer "+token)
+	return utils.ValidateJWT(context)
+}
+
+func setJWTEnv(t *testing.T, values map[string]string) {
+	for _, name := range []string{"JWT_SIGNING_KEY_ID", "JWT_SIGNING_KEY_FILE", "JWT_VERIFICATION_KEYS", "JWT_LEGACY_HS256_VERIFY", "JWT_PRIVATE_KEY", "JWT_ISSUER"} {
+		previous, existed := os.LookupEnv(name)
+		t.Cleanup(func() {
+			if existed {
+				os.Setenv(name, previous)
+			} else {
+				os.Unsetenv(name)
+			}
+			utils.ReloadJWTKeys()
+		})
+		os.Unsetenv(name)
+	}
+	for name, value := range values {
+		os.Setenv(name, value)
+	}
+	require.NoError(t, utils.ReloadJWTKeys())
+}
+
+func TestJWT_RotationKeepsOldTokensValid(t *testing.T) {
+	dir := t.TempDir()
+	oldRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	oldPrivate, oldPublic := writeKeyPair(t, dir, "old", oldRSA, &oldRSA.PublicKey)
+
+	newPublicEd, newPrivateEd, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	newPrivate, _ := writeKeyPair(t, dir, "new", newPrivateEd, newPublicEd)
+
+	// Sign with the old RSA key
+	setJWTEnv(t, map[string]string{"JWT_SIGNING_KEY_ID": "2024-01", "JWT_SIGNING_KEY_FILE": oldPrivate})
+	oldToken, err := utils.GenerateJWT(uuid.New(), "user@example.com", "user", uuid.Nil)
+	require.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &utils.Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", parsed.Header["kid"])
+	assert.Equal(t, "RS256", parsed.Header["alg"])
+	assert.NoError(t, validateToken(oldToken))
+
+	// Rotate to the Ed25519 key while keeping the old public key for verification
+	setJWTEnv(t, map[string]string{
+		"JWT_SIGNING_KEY_ID":    "2024-02",
+		"JWT_SIGNING_KEY_FILE":  newPrivate,
+		"JWT_VERIFICATION_KEYS": "2024-01=" + oldPublic,
+	})
+	newToken, err := utils.GenerateJWT(uuid.New(), "user@example.com", "user", uuid.Nil)
+	require.NoError(t, err)
+
+	assert.NoError(t, validateToken(newToken))
+	assert.NoError(t, validateToken(oldToken))
+
+	jwks, err := utils.PublicJWKs()
+	require.NoError(t, err)
+	require.Len(t, jwks, 2)
+	assert.Equal(t, "2024-01", jwks[0].KeyID)
+	assert.Equal(t, "RSA", jwks[0].KeyType)
+	assert.Equal(t, "2024-02", jwks[1].KeyID)
+	assert.Equal(t, "OKP", jwks[1].KeyType)
+	assert.Equal(t, "Ed25519", jwks[1].Curve)
+
+	// Retiring the old key invalidates its tokens
+	setJWTEnv(t, map[string]string{"JWT_SIGNING_KEY_ID": "2024-02", "JWT_SIGNING_KEY_FILE": newPrivate})
+	assert.Error(t, validateToken(oldToken))
+	assert.NoError(t, validateToken(newToken))
+}
+
+func TestJWT_RejectsAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privatePath, publicPath := writeKeyPair(t, dir, "rsa", rsaKey, &rsaKey.PublicKey)
+
+	setJWTEnv(t, map[string]string{"JWT_SIGNING_KEY_ID": "rsa", "JWT_SIGNING_KEY_FILE": privatePath})
+
+	// An HS256 token signed with the public key bytes must not be accepted
+	publicPEM, err := os.ReadFile(publicPath)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{UserID: uuid.New()})
+	forged.Header["kid"] = "rsa"
+	forgedToken, err := forged.SignedString(publicPEM)
+	require.NoError(t, err)
+	assert.Error(t, validateToken(forgedToken))
+
+	// Tokens without a kid are rejected unless legacy verification is enabled
+	legacy := jwt.NewWithClaims(jwt.SigningMethodHS256, utils.Claims{UserID: uuid.New()})
+	legacyToken, err := legacy.SignedString([]byte("legacy-secret"))
+	require.NoError(t, err)
+	assert.Error(t, validateToken(legacyToken))
+
+	setJWTEnv(t, map[string]string{
+		"JWT_SIGNING_KEY_ID":      "rsa",
+		"JWT_SIGNING_KEY_FILE":    privatePath,
+		"JWT_PRIVATE_KEY":         "legacy-secret",
+		"JWT_LEGACY_HS256_VERIFY": "true",
+	})
+	assert.NoError(t, validateToken(legacyToken))
+
+	// The shared secret is never published
+	jwks, err := utils.PublicJWKs()
+	require.NoError(t, err)
+	assert.Len(t, jwks, 1)
+}
@@ -0,0 +1,219 @@
+package utils_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"testlake/utils"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubIdP is a minimal OIDC provider: discovery, JWKS and a token endpoint
+// that enforces client authentication and PKCE.
+type stubIdP struct {
+	t            *testing.T
+	server       *httptest.Server
+	issuer       string
+	clientID     string
+	clientSecret string
+	key          *rsa.PrivateKey
+	kid          string
+	audience     string
+	subject      string
+	email        string
+	codes        map[string]stubAuthorization
+}
+
+type stubAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	idp := &stubIdP{
+		t:            t,
+		clientID:     "testlake",
+		clientSecret: "s3cret",
+		key:          key,
+		kid:          "idp-1",
+		subject:      "user-123",
+		email:        "jane@acme.test",
+		codes:        map[string]stubAuthorization{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.issuer,
+			"authorization_endpoint": idp.issuer + "/authorize",
+			"token_endpoint":         idp.issuer + "/token",
+			"jwks_uri":               idp.issuer + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": idp.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(idp.key.PublicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.PublicKey.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if !ok || clientID != idp.clientID || clientSecret != idp.clientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.NoError(t, r.ParseForm())
+
+		authorization, found := idp.codes[r.PostForm.Get("code")]
+		if !found || utils.PKCEChallenge(r.PostForm.Get("code_verifier")) != authorization.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(idp.codes, r.PostForm.Get("code"))
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   3600,
+			"id_token":     idp.idToken(authorization.nonce),
+		})
+	})
+
+	idp.server = httptest.NewServer(mux)
+	idp.issuer = idp.server.URL
+	idp.audience = idp.clientID
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize plays the user signing in at the provider and returns the code
+func (idp *stubIdP) authorize(authorizationURL string) string {
+	parsed, err := url.Parse(authorizationURL)
+	require.NoError(idp.t, err)
+	query := parsed.Query()
+	require.Equal(idp.t, "S256", query.Get("code_challenge_method"))
+
+	code := "code-" + query.Get("state")
+	idp.codes[code] = stubAuthorization{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	return code
+}
+
+func (idp *stubIdP) idToken(nonce string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            idp.issuer,
+		"sub":            idp.subject,
+		"aud":            idp.audience,
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          nonce,
+		"email":          idp.email,
+		"email_verified": true,
+		"given_name":     "Jane",
+	})
+	token.Header["kid"] = idp.kid
+	signed, err := token.SignedString(idp.key)
+	require.NoError(idp.t, err)
+	return signed
+}
+
+func TestOIDC_AuthorizationCodeFlow(t *testing.T) {
+	idp := newStubIdP(t)
+	client := utils.NewOIDCClient(idp.issuer, idp.clientID, idp.clientSecret, "http://localhost:8000/api/v1/auth/sso/callback")
+
+	metadata, err := client.Discover()
+	require.NoError(t, err)
+	assert.Equal(t, idp.issuer+"/token", metadata.TokenEndpoint)
+
+	authorizationURL := client.AuthorizationURL(metadata, "state-1", "nonce-1", "verifier-0123456789012345678901234567890123", "jane@acme.test")
+	assert.Contains(t, authorizationURL, idp.issuer+"/authorize?")
+	assert.Contains(t, authorizationURL, "login_hint=jane%40acme.test")
+
+	code := idp.authorize(authorizationURL)
+
+	// A wrong PKCE verifier is refused by the provider
+	_, err = client.ExchangeCode(metadata, code, "another-verifier")
+	assert.ErrorIs(t, err, utils.ErrOIDCCodeExchange)
+
+	tokens, err := client.ExchangeCode(metadata, code, "verifier-0123456789012345678901234567890123")
+	require.NoError(t, err)
+
+	claims, err := client.VerifyIDToken(metadata, tokens.IDToken, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, "jane@acme.test", claims.Email)
+	assert.Equal(t, "user-123", claims.Subject)
+	assert.Equal(t, "Jane", claims.GivenName)
+	assert.False(t, claims.IsEmailUnverified())
+}
+
+func TestOIDC_VerifyIDTokenRejects(t *testing.T) {
+	idp := newStubIdP(t)
+	client := utils.NewOIDCClient(idp.issuer, idp.clientID, idp.clientSecret, "http://localhost/callback")
+	metadata, err := client.Discover()
+	require.NoError(t, err)
+
+	// Replayed token from another login request
+	_, err = client.VerifyIDToken(metadata, idp.idToken("nonce-a"), "nonce-b")
+	assert.ErrorIs(t, err, utils.ErrOIDCNonceMismatch)
+
+	// Token issued to another client
+	idp.audience = "someone-else"
+	_, err = client.VerifyIDToken(metadata, idp.idToken("nonce-a"), "nonce-a")
+	assert.ErrorIs(t, err, utils.ErrOIDCInvalidToken)
+	idp.audience = idp.clientID
+
+	// Token signed with a key that is not published by the provider
+	forger := *idp
+	forger.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, err = client.VerifyIDToken(metadata, forger.idToken("nonce-a"), "nonce-a")
+	assert.ErrorIs(t, err, utils.ErrOIDCInvalidToken)
+
+	// HS256 signed with the client secret is never accepted
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"iss": idp.issuer, "aud": idp.clientID, "exp": time.Now().Add(time.Minute).Unix(), "nonce": "nonce-a",
+	})
+	hmacToken.Header["kid"] = idp.kid
+	signed, err := hmacToken.SignedString([]byte(idp.clientSecret))
+	require.NoError(t, err)
+	_, err = client.VerifyIDToken(metadata, signed, "nonce-a")
+	assert.ErrorIs(t, err, utils.ErrOIDCInvalidToken)
+}
+
+func TestOIDC_DiscoverRejectsIssuerMismatch(t *testing.T) {
+	idp := newStubIdP(t)
+	client := utils.NewOIDCClient(idp.issuer+"/tenant", idp.clientID, idp.clientSecret, "http://localhost/callback")
+
+	// The stub serves no discovery document below /tenant
+	_, err := client.Discover()
+	assert.ErrorIs(t, err, utils.ErrOIDCDiscovery)
+
+	idp.server.Config.Handler.(*http.ServeMux).HandleFunc("/tenant/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 "https://evil.example",
+			"authorization_endpoint": idp.issuer + "/authorize",
+			"token_endpoint":         idp.issuer + "/token",
+			"jwks_uri":               idp.issuer + "/jwks",
+		})
+	})
+	_, err = client.Discover()
+	assert.ErrorIs(t, err, utils.ErrOIDCDiscovery)
+}
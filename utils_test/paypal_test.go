@@ -0,0 +1,456 @@
+package utils_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testlake/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// payPalInteraction is one recorded request/response pair of a cassette in
+// testdata/paypal. Request fields left empty are not checked.
+type payPalInteraction struct {
+	Request struct {
+		Method        string `json:"method"`
+		Path          string `json:"path"`
+		Authorization string `json:"authorization"`
+		RequestID     string `json:"request_id"`
+		BodyContains  string `json:"body_contains"`
+	} `json:"request"`
+	Response struct {
+		Status int             `json:"status"`
+		Body   json.RawMessage `json:"body"`
+	} `json:"response"`
+}
+
+// payPalRecording replays a cassette and fails the test when the client sends
+// requests out of order or differing from the recording.
+type payPalRecording struct {
+	t            *testing.T
+	server       *httptest.Server
+	mutex        sync.Mutex
+	interactions []payPalInteraction
+	position     int
+}
+
+func replayPayPal(t *testing.T, cassette string) (*payPalRecording, *utils.PayPalClient) {
+	data, err := os.ReadFile(filepath.Join("testdata", "paypal", cassette+".json"))
+	require.NoError(t, err)
+
+	recording := &payPalRecording{t: t}
+	require.NoError(t, json.Unmarshal(data, &recording.interactions))
+	recording.server = httptest.NewServer(http.HandlerFunc(recording.serve))
+	t.Cleanup(recording.server.Close)
+
+	return recording, utils.NewPayPalClient(recording.server.URL, "client-id", "client-secret")
+}
+
+func (r *payPalRecording) serve(w http.ResponseWriter, request *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.position >= len(r.interactions) {
+		r.t.Errorf("unexpected request %s %s", request.Method, request.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	interaction := r.interactions[r.position]
+	r.position++
+
+	body, _ := io.ReadAll(request.Body)
+	expected := interaction.Request
+	assert.Equal(r.t, expected.Method, request.Method)
+	assert.Equal(r.t, expected.Path, request.URL.Path)
+	if request.URL.Path == "/v1/oauth2/token" {
+		clientID, clientSecret, ok := request.BasicAuth()
+		assert.True(r.t, ok, "token request must use basic auth")
+		assert.Equal(r.t, "client-id", clientID)
+		assert.Equal(r.t, "client-secret", clientSecret)
+	}
+	if expected.Authorization != "" {
+		assert.Equal(r.t, expected.Authorization, request.Header.Get("Authorization"))
+	}
+	if expected.RequestID != "" {
+		assert.Equal(r.t, expected.RequestID, request.Header.Get("PayPal-Request-Id"))
+	}
+	if expected.BodyContains != "" {
+		assert.Contains(r.t, string(body), expected.BodyContains)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(interaction.Response.Status)
+	if len(interaction.Response.Body) > 0 {
+		w.Write(interaction.Response.Body)
+	}
+}
+
+func (r *payPalRecording) assertDone() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	assert.Equal(r.t, len(r.interactions), r.position, "not every recorded request was sent")
+}
+
+func TestPayPalSubscriptionLifecycle(t *testing.T) {
+	recording, client := replayPayPal(t, "subscription_lifecycle")
+	ctx := context.Background()
+
+	subscription, err := client.CreateSubscription(ctx, utils.GatewaySubscriptionRequest{
+		PlanID:         "P-5ML4271244454362WXNWU5NQ",
+		CustomID:       "org-1",
+		ReturnURL:      "https://app.example.com/billing/return",
+		CancelURL:      "https://app.example.com/billing/cancel",
+		IdempotencyKey: "sub-create-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "I-BW452GLLEP1G", subscription.ID)
+	assert.Equal(t, "APPROVAL_PENDING", subscription.Status)
+	assert.Equal(t, "https://www.sandbox.paypal.com/webapps/billing/subscriptions?ba_token=BA-2M539689T3856352J", subscription.ApprovalURL)
+
+	revised, err := client.ReviseSubscription(ctx, subscription.ID, "P-8ML4271244454362XXNWU5NQ")
+	require.NoError(t, err)
+	assert.Equal(t, subscription.ID, revised.ID)
+	assert.Equal(t, "P-8ML4271244454362XXNWU5NQ", revised.PlanID)
+	assert.Contains(t, revised.ApprovalURL, "ba_token=BA-03P9862361329373P")
+
+	require.NoError(t, client.SuspendSubscription(ctx, subscription.ID, "Cancelled at period end"))
+	require.NoError(t, client.ActivateSubscription(ctx, subscription.ID, "Reactivated"))
+
+	err = client.CancelSubscription(ctx, subscription.ID, "Closed")
+	var gatewayErr *utils.GatewayError
+	require.True(t, errors.As(err, &gatewayErr))
+	assert.Equal(t, http.StatusUnprocessableEntity, gatewayErr.StatusCode)
+	assert.Equal(t, "UNPROCESSABLE_ENTITY", gatewayErr.Name)
+	assert.Equal(t, "f2f4c9b1d1e0a", gatewayErr.DebugID)
+
+	// One token serves every call while it is valid
+	recording.assertDone()
+}
+
+func TestPayPalPlanCatalogue(t *testing.T) {
+	recording, client := replayPayPal(t, "plan_catalogue")
+	ctx := context.Background()
+
+	product, err := client.CreateProduct(ctx, utils.GatewayProductRequest{Name: "Team"})
+	require.NoError(t, err)
+	assert.Equal(t, "PROD-5FD60555F23244316", product.ID)
+
+	plan, err := client.CreatePlan(ctx, utils.GatewayPlanRequest{
+		ProductID: product.ID,
+		Name:      "Team v1 monthly (EUR)",
+		Interval:  utils.GatewayIntervalMonth,
+		Price:     4500,
+		Currency:  "EUR",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "P-2UF78835G6983425GLSM44MA", plan.ID)
+	assert.Equal(t, "ACTIVE", plan.Status)
+
+	require.NoError(t, client.DeactivatePlan(ctx, plan.ID))
+	recording.assertDone()
+}
+
+func TestPayPalOrderCaptureAndRefund(t *testing.T) {
+	recording, client := replayPayPal(t, "order_capture_refund")
+	ctx := context.Background()
+
+	order, err := client.CreateOrder(ctx, utils.GatewayOrderRequest{
+		ReferenceID:   "inv-1",
+		CustomID:      "inv-1",
+		InvoiceNumber: "INV-000001",
+		Amount:        2990,
+		Currency:      "USD",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "5O190127TN364715T", order.ID)
+	assert.Equal(t, "https://www.sandbox.paypal.com/checkoutnow?token=5O190127TN364715T", order.ApprovalURL)
+
+	capture, err := client.CaptureOrder(ctx, order.ID, "capture-inv-1")
+	require.NoError(t, err)
+	assert.Equal(t, "3C679366HH908993F", capture.ID)
+	assert.Equal(t, order.ID, capture.OrderID)
+	assert.Equal(t, "COMPLETED", capture.Status)
+	assert.Equal(t, int64(2990), capture.Amount)
+	assert.Equal(t, "USD", capture.Currency)
+	assert.Equal(t, "inv-1", capture.CustomID)
+	assert.Equal(t, "QYR5Z8XDVJNXQ", capture.PayerID)
+	assert.Equal(t, "buyer@example.com", capture.PayerEmail)
+
+	amount := int64(1000)
+	refund, err := client.RefundCapture(ctx, utils.GatewayRefundRequest{
+		CaptureID:      capture.ID,
+		Amount:         &amount,
+		Currency:       "USD",
+		IdempotencyKey: "refund-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1JU08902781691411", refund.ID)
+	assert.Equal(t, int64(1000), refund.Amount)
+
+	recording.assertDone()
+}
+
+func TestPayPalChargePaymentMethod(t *testing.T) {
+	recording, client := replayPayPal(t, "charge_payment_method")
+	ctx := context.Background()
+
+	// Captured when the order is created
+	capture, err := client.ChargePaymentMethod(ctx, utils.GatewayChargeRequest{
+		VaultID:        "8kk845170a1463523",
+		ReferenceID:    "inv-2",
+		CustomID:       "inv-2",
+		Amount:         7900,
+		Currency:       "USD",
+		IdempotencyKey: "charge-inv-2",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "7TK53561YB803214S", capture.ID)
+	assert.Equal(t, "9RU08902781691443", capture.OrderID)
+	assert.Equal(t, "COMPLETED", capture.Status)
+	assert.Equal(t, int64(7900), capture.Amount)
+	assert.Equal(t, "QYR5Z8XDVJNXQ", capture.PayerID)
+
+	// Created but not captured yet
+	capture, err = client.ChargePaymentMethod(ctx, utils.GatewayChargeRequest{
+		VaultID:        "8kk845170a1463523",
+		ReferenceID:    "inv-3",
+		CustomID:       "inv-3",
+		Amount:         2900,
+		Currency:       "USD",
+		IdempotencyKey: "charge-inv-3",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2GG279541U471931P", capture.ID)
+	assert.Equal(t, int64(2900), capture.Amount)
+
+	_, err = client.ChargePaymentMethod(ctx, utils.GatewayChargeRequest{
+		VaultID:        "8kk845170a1463523",
+		Amount:         2900,
+		Currency:       "USD",
+		IdempotencyKey: "charge-inv-4",
+	})
+	var gatewayErr *utils.GatewayError
+	require.True(t, errors.As(err, &gatewayErr))
+	assert.Equal(t, http.StatusUnprocessableEntity, gatewayErr.StatusCode)
+	assert.Equal(t, "INSTRUMENT_DECLINED", gatewayErr.Issue)
+	assert.False(t, utils.IsPaymentMethodFailure(err), "a decline may pass on a later retry")
+
+	recording.assertDone()
+}
+
+func TestPayPalVaultPaymentToken(t *testing.T) {
+	recording, client := replayPayPal(t, "vault_payment_token")
+	ctx := context.Background()
+
+	setupToken, err := client.CreateSetupToken(ctx, utils.GatewaySetupTokenRequest{
+		ReturnURL:      "https://app.example.com/billing/return",
+		CancelURL:      "https://app.example.com/billing/cancel",
+		IdempotencyKey: "pm-1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "5C991763VB2781612", setupToken.ID)
+	assert.Equal(t, "PAYER_ACTION_REQUIRED", setupToken.Status)
+	assert.Equal(t, "https://www.sandbox.paypal.com/agreements/approve?approval_session_id=5C991763VB2781612", setupToken.ApprovalURL)
+
+	// Before the payer approved
+	_, err = client.CreatePaymentToken(ctx, setupToken.ID, setupToken.ID)
+	var gatewayErr *utils.GatewayError
+	require.True(t, errors.As(err, &gatewayErr))
+	assert.Equal(t, "SETUP_TOKEN_NOT_APPROVED", gatewayErr.Issue)
+
+	paymentToken, err := client.CreatePaymentToken(ctx, setupToken.ID, setupToken.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "8kk845170a1463523", paymentToken.ID)
+	assert.Equal(t, "customer_4029352050", paymentToken.CustomerID)
+	assert.Equal(t, "QYR5Z8XDVJNXQ", paymentToken.PayerID)
+	assert.Equal(t, "buyer@example.com", paymentToken.PayerEmail)
+
+	require.NoError(t, client.DeletePaymentToken(ctx, paymentToken.ID))
+
+	recording.assertDone()
+}
+
+func TestPayPalRefreshesRejectedToken(t *testing.T) {
+	recording, client := replayPayPal(t, "token_refresh")
+	ctx := context.Background()
+
+	product, err := client.CreateProduct(ctx, utils.GatewayProductRequest{Name: "TestLake Pro"})
+	require.NoError(t, err)
+	assert.Equal(t, "PROD-XXCD1234QWER65782", product.ID)
+
+	plan, err := client.CreatePlan(ctx, utils.GatewayPlanRequest{
+		ProductID: product.ID,
+		Name:      "Pro monthly",
+		Interval:  utils.GatewayIntervalMonth,
+		Price:     2990,
+		Currency:  "USD",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "P-5ML4271244454362WXNWU5NQ", plan.ID)
+
+	recording.assertDone()
+}
+
+func TestPayPalInvalidClient(t *testing.T) {
+	recording, client := replayPayPal(t, "invalid_client")
+
+	_, err := client.CreateOrder(context.Background(), utils.GatewayOrderRequest{Amount: 100, Currency: "USD"})
+	var gatewayErr *utils.GatewayError
+	require.True(t, errors.As(err, &gatewayErr))
+	assert.Equal(t, http.StatusUnauthorized, gatewayErr.StatusCode)
+	assert.Equal(t, "invalid_client", gatewayErr.Name)
+	assert.Equal(t, "Client Authentication failed", gatewayErr.Message)
+
+	recording.assertDone()
+}
+
+func TestPayPalRequiresCredentials(t *testing.T) {
+	client := utils.NewPayPalClient("http://127.0.0.1:1", "", "")
+
+	_, err := client.CreateProduct(context.Background(), utils.GatewayProductRequest{Name: "TestLake"})
+	assert.ErrorIs(t, err, utils.ErrPaymentGatewayNotConfigured)
+}
+
+func TestPayPalClientFromEnv(t *testing.T) {
+	t.Setenv("PAYPAL_BASE_URL", "")
+	t.Setenv("PAYPAL_MODE", "live")
+	assert.Equal(t, utils.PayPalLiveBaseURL, utils.NewPayPalClientFromEnv().BaseURL)
+
+	t.Setenv("PAYPAL_MODE", "sandbox")
+	assert.Equal(t, utils.PayPalSandboxBaseURL, utils.NewPayPalClientFromEnv().BaseURL)
+
+	t.Setenv("PAYPAL_BASE_URL", "http://localhost:9000/")
+	assert.True(t, strings.HasPrefix(utils.NewPayPalClientFromEnv().BaseURL, "http://localhost:9000"))
+}
+
+func TestFakePaymentGateway(t *testing.T) {
+	gateway := utils.NewFakePaymentGateway()
+	ctx := context.Background()
+
+	product, err := gateway.CreateProduct(ctx, utils.GatewayProductRequest{Name: "Pro"})
+	require.NoError(t, err)
+	plan, err := gateway.CreatePlan(ctx, utils.GatewayPlanRequest{ProductID: product.ID, Interval: utils.GatewayIntervalMonth, Price: 1000, Currency: "USD"})
+	require.NoError(t, err)
+
+	subscription, err := gateway.CreateSubscription(ctx, utils.GatewaySubscriptionRequest{PlanID: plan.ID, IdempotencyKey: "key"})
+	require.NoError(t, err)
+	again, err := gateway.CreateSubscription(ctx, utils.GatewaySubscriptionRequest{PlanID: plan.ID, IdempotencyKey: "key"})
+	require.NoError(t, err)
+	assert.Equal(t, subscription.ID, again.ID)
+	assert.Equal(t, "APPROVAL_PENDING", subscription.Status)
+	gateway.ApproveSubscription(subscription.ID)
+	assert.Equal(t, "ACTIVE", gateway.Subscriptions[subscription.ID].Status)
+
+	order, err := gateway.CreateOrder(ctx, utils.GatewayOrderRequest{CustomID: "inv", Amount: 2500, Currency: "USD"})
+	require.NoError(t, err)
+	capture, err := gateway.CaptureOrder(ctx, order.ID, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2500), capture.Amount)
+	assert.Equal(t, "inv", capture.CustomID)
+	_, err = gateway.CaptureOrder(ctx, order.ID, "")
+	assert.Error(t, err, "an order can only be captured once")
+
+	partial := int64(2000)
+	_, err = gateway.RefundCapture(ctx, utils.GatewayRefundRequest{CaptureID: capture.ID, Amount: &partial})
+	require.NoError(t, err)
+	_, err = gateway.RefundCapture(ctx, utils.GatewayRefundRequest{CaptureID: capture.ID, Amount: &partial})
+	assert.Error(t, err, "refunds cannot exceed the captured amount")
+
+	charged, err := gateway.ChargePaymentMethod(ctx, utils.GatewayChargeRequest{VaultID: "vault-1", CustomID: "inv-2", Amount: 2900, Currency: "USD", IdempotencyKey: "charge"})
+	require.NoError(t, err)
+	chargedAgain, err := gateway.ChargePaymentMethod(ctx, utils.GatewayChargeRequest{VaultID: "vault-1", CustomID: "inv-2", Amount: 2900, Currency: "USD", IdempotencyKey: "charge"})
+	require.NoError(t, err)
+	assert.Equal(t, charged.ID, chargedAgain.ID, "a repeated charge is not billed twice")
+	_, err = gateway.ChargePaymentMethod(ctx, utils.GatewayChargeRequest{VaultID: "declined-1", Amount: 2900, Currency: "USD"})
+	assert.Error(t, err)
+
+	gateway.FailNext("CreateOrder", errors.New("provider down"))
+	_, err = gateway.CreateOrder(ctx, utils.GatewayOrderRequest{Amount: 100, Currency: "USD"})
+	assert.EqualError(t, err, "provider down")
+}
+
+func TestPayPalVerifyWebhook(t *testing.T) {
+	recording, client := replayPayPal(t, "verify_webhook")
+	client.WebhookID = "8PT597110X687430LKGECATA"
+	ctx := context.Background()
+
+	webhook := utils.GatewayWebhook{
+		TransmissionID:   "69cd13f0-d67a-11e5-baa3-778b53f4ae55",
+		TransmissionTime: "2026-10-18T10:00:00Z",
+		TransmissionSig:  "lmI95Jx3Y9nhR9SJWlHsCZMc0FH6Z6QzU5nTHN4SgKW7XwPJxc5A8xCaPsDSRuXQUbOsOB5Vs7zCWTJWnF3H0Q==",
+		CertURL:          "https://api.sandbox.paypal.com/v1/notifications/certs/CERT-360caa42-fca2a594-a5cafa77",
+		AuthAlgo:         "SHA256withRSA",
+		Body:             []byte(`{"id":"WH-2WR32451HC0233532-67976317FL4543714","event_type":"BILLING.SUBSCRIPTION.ACTIVATED"}`),
+	}
+	require.NoError(t, client.VerifyWebhook(ctx, webhook))
+
+	webhook.TransmissionSig = "forged"
+	assert.ErrorIs(t, client.VerifyWebhook(ctx, webhook), utils.ErrWebhookSignatureInvalid)
+
+	// Deliveries without signature headers are rejected without asking PayPal
+	webhook.TransmissionSig = ""
+	assert.ErrorIs(t, client.VerifyWebhook(ctx, webhook), utils.ErrWebhookSignatureInvalid)
+
+	recording.assertDone()
+}
+
+func TestPayPalVerifyWebhookRequiresWebhookID(t *testing.T) {
+	client := utils.NewPayPalClient("http://127.0.0.1:1", "client-id", "client-secret")
+
+	err := client.VerifyWebhook(context.Background(), utils.GatewayWebhook{Body: []byte(`{}`)})
+	assert.ErrorIs(t, err, utils.ErrPaymentGatewayNotConfigured)
+}
+
+func TestPayPalWebhookResource(t *testing.T) {
+	body := []byte(`{
+		"id": "WH-7Y7254563A4550640-11V2185806837105M",
+		"event_type": "BILLING.SUBSCRIPTION.PAYMENT.FAILED",
+		"resource_type": "subscription",
+		"create_time": "2026-10-18T10:00:00Z",
+		"resource": {
+			"id": "I-BW452GLLEP1G",
+			"status": "ACTIVE",
+			"custom_id": "0e0b1f4e-52a5-4ec8-9d35-0d2f4f0b6a11",
+			"billing_info": {
+				"next_billing_time": "2026-11-18T10:00:00Z",
+				"last_failed_payment": {"amount": {"currency_code": "USD", "value": "79.00"}, "reason_code": "PAYER_ACCOUNT_LOCKED_OR_CLOSED"}
+			}
+		}
+	}`)
+
+	var event utils.PayPalWebhookEvent
+	require.NoError(t, json.Unmarshal(body, &event))
+	assert.Equal(t, "WH-7Y7254563A4550640-11V2185806837105M", event.ID)
+	assert.Equal(t, "BILLING.SUBSCRIPTION.PAYMENT.FAILED", event.EventType)
+
+	var resource utils.PayPalWebhookResource
+	require.NoError(t, json.Unmarshal(event.Resource, &resource))
+	assert.Equal(t, "I-BW452GLLEP1G", resource.ID)
+	assert.Equal(t, "0e0b1f4e-52a5-4ec8-9d35-0d2f4f0b6a11", resource.CustomValue())
+	require.NotNil(t, resource.BillingInfo.NextBillingTime)
+	amount, currency, reason := resource.FailedPayment()
+	assert.Equal(t, int64(7900), amount)
+	assert.Equal(t, "USD", currency)
+	assert.Equal(t, "PAYER_ACCOUNT_LOCKED_OR_CLOSED", reason)
+
+	var sale utils.PayPalWebhookResource
+	require.NoError(t, json.Unmarshal([]byte(`{"id":"80021663DE681814L","state":"completed","amount":{"total":"79.00","currency":"USD"},"billing_agreement_id":"I-BW452GLLEP1G","custom":"org"}`), &sale))
+	amount, currency = sale.SaleAmount()
+	assert.Equal(t, int64(7900), amount)
+	assert.Equal(t, "USD", currency)
+	assert.Equal(t, "org", sale.CustomValue())
+
+	var invoice utils.PayPalWebhookResource
+	require.NoError(t, json.Unmarshal([]byte(`{"invoice":{"id":"INV2-Z56S-5LLA-Q52L-CPZ5","status":"PAID"}}`), &invoice))
+	assert.Equal(t, "INV2-Z56S-5LLA-Q52L-CPZ5", invoice.InvoiceID())
+}
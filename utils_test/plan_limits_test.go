@@ -0,0 +1,115 @@
+package utils_test
+
+import (
+	"testing"
+	"testlake/dao"
+	"testlake/model"
+	"testlake/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupLimits creates an organization on a plan with two seats, on an
+// in-memory database, along with a bigger paid plan and an unlimited one.
+func setupLimits(t *testing.T) *model.Organization {
+	db, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.Plan{}, &model.PlanPrice{}, &model.Organization{}, &model.Project{},
+		&model.Environment{}, &model.DataSchema{}, &model.TestData{}, &model.OrganizationUsage{}))
+	// Raw tables: their now() defaults do not exist in sqlite
+	require.NoError(t, db.Exec("CREATE TABLE organization_members (id text, organization_id text, user_id text, status text)").Error)
+	require.NoError(t, db.Exec("CREATE TABLE organization_invitations (id text, organization_id text, status text, expires_at datetime)").Error)
+	previous := dao.Database
+	dao.Database = db
+	t.Cleanup(func() { dao.Database = previous })
+
+	ownerID := uuid.New()
+	require.NoError(t, db.Create(&model.User{ID: ownerID, Email: "owner@example.com", Username: "owner"}).Error)
+
+	small := &model.Plan{Name: "Starter", Slug: "starter", PriceMonthly: 2900, MaxUsers: 2, MaxProjects: 10, MaxEnvironments: 5, MaxSchemas: 25, MaxTestRecordsPerSchema: 1000, Features: "[]", IsActive: true}
+	team := &model.Plan{Name: "Team", Slug: "team", PriceMonthly: 7900, MaxUsers: 10, MaxProjects: 50, MaxEnvironments: 20, MaxSchemas: 100, MaxTestRecordsPerSchema: 10000, Features: "[]", IsActive: true}
+	enterprise := &model.Plan{Name: "Enterprise", Slug: "enterprise", PriceMonthly: 29900, MaxUsers: -1, MaxProjects: -1, MaxEnvironments: -1, MaxSchemas: -1, MaxTestRecordsPerSchema: -1, Features: "[]", IsActive: true}
+	for _, plan := range []*model.Plan{enterprise, small, team} {
+		require.NoError(t, db.Create(plan).Error)
+	}
+
+	org := &model.Organization{Name: "Acme", Slug: "acme", CreatedBy: ownerID, PlanID: &small.ID}
+	require.NoError(t, db.Create(org).Error)
+	// The creator's own membership takes no extra seat
+	require.NoError(t, db.Exec("INSERT INTO organization_members VALUES (?, ?, ?, 'joined')", uuid.NewString(), org.ID, ownerID).Error)
+	return org
+}
+
+func TestOrganizationLimitsCountsMembersAndPendingInvitations(t *testing.T) {
+	org := setupLimits(t)
+
+	limits, err := utils.LoadOrganizationLimits(org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, limits.Usage.Users)
+	assert.True(t, limits.Check(model.LimitResourceUsers, 1).Allowed)
+
+	require.NoError(t, dao.Database.Exec("INSERT INTO organization_invitations VALUES (?, ?, 'pending', ?)", uuid.NewString(), org.ID, time.Now().Add(time.Hour)).Error)
+	require.NoError(t, dao.Database.Exec("INSERT INTO organization_invitations VALUES (?, ?, 'pending', ?)", uuid.NewString(), org.ID, time.Now().Add(-time.Hour)).Error)
+
+	limits, err = utils.LoadOrganizationLimits(org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, limits.Usage.Users, "expired invitations free their seat")
+
+	check := limits.Check(model.LimitResourceUsers, 1)
+	assert.False(t, check.Allowed)
+	assert.Equal(t, 2, check.Limit)
+	assert.Equal(t, 0, check.Remaining)
+}
+
+func TestOrganizationLimitsUpgradeOptionsFitTheRequest(t *testing.T) {
+	org := setupLimits(t)
+
+	limits, err := utils.LoadOrganizationLimits(org.ID)
+	require.NoError(t, err)
+
+	options, err := limits.UpgradeOptions(limits.Check(model.LimitResourceUsers, 5))
+	require.NoError(t, err)
+	require.Len(t, options, 2)
+	assert.Equal(t, "team", options[0].Slug, "cheapest first")
+	assert.Equal(t, "enterprise", options[1].Slug)
+	assert.Equal(t, -1, options[1].Limit)
+
+	options, err = limits.UpgradeOptions(limits.Check(model.LimitResourceUsers, 20))
+	require.NoError(t, err)
+	require.Len(t, options, 1)
+	assert.Equal(t, "enterprise", options[0].Slug)
+}
+
+func TestOrganizationLimitsUpgradeOptionsInOrganizationCurrency(t *testing.T) {
+	org := setupLimits(t)
+	require.NoError(t, dao.Database.Model(org).Updates(map[string]interface{}{"currency": "EUR", "billing_cycle": model.BillingCycleYearly}).Error)
+	var team model.Plan
+	require.NoError(t, dao.Database.First(&team, "slug = ?", "team").Error)
+	require.NoError(t, dao.Database.Create(&model.PlanPrice{PlanID: team.ID, Currency: "EUR", BillingCycle: model.BillingCycleMonthly, Amount: 7500}).Error)
+	require.NoError(t, dao.Database.Create(&model.PlanPrice{PlanID: team.ID, Currency: "EUR", BillingCycle: model.BillingCycleYearly, Amount: 75000}).Error)
+
+	limits, err := utils.LoadOrganizationLimits(org.ID)
+	require.NoError(t, err)
+	options, err := limits.UpgradeOptions(limits.Check(model.LimitResourceUsers, 5))
+	require.NoError(t, err)
+	require.Len(t, options, 1, "plans without a EUR price are left out")
+	assert.Equal(t, "team", options[0].Slug)
+	assert.Equal(t, "EUR", options[0].Currency)
+	assert.Equal(t, 75.0, options[0].PriceMonthly)
+	assert.Equal(t, 750.0, options[0].PriceYearly)
+}
+
+func TestOrganizationLimitsWithoutPlanUseOrganizationLimits(t *testing.T) {
+	org := setupLimits(t)
+	require.NoError(t, dao.Database.Model(org).Updates(map[string]interface{}{"plan_id": nil, "max_users": 1}).Error)
+
+	limits, err := utils.LoadOrganizationLimits(org.ID)
+	require.NoError(t, err)
+	assert.False(t, limits.Check(model.LimitResourceUsers, 1).Allowed)
+	assert.True(t, limits.Check(model.LimitResourceSchemas, 100).Allowed)
+}
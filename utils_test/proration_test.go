@@ -0,0 +1,62 @@
+package utils_test
+
+import (
+	"testing"
+	"testlake/utils"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateProrationHalfwayUpgrade(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	at := start.AddDate(0, 0, 15)
+
+	proration := utils.CalculateProration(2900, 7900, start, end, at, false)
+
+	assert.InDelta(t, 0.5, proration.RemainingFraction, 1e-9)
+	assert.Equal(t, int64(1450), proration.Credit)
+	assert.Equal(t, int64(3950), proration.Charge)
+	assert.Equal(t, int64(2500), proration.Net())
+}
+
+func TestCalculateProrationRoundsToMinorUnits(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	at := start.AddDate(0, 0, 10)
+
+	proration := utils.CalculateProration(2900, 7900, start, end, at, false)
+
+	assert.Equal(t, int64(1933), proration.Credit)
+	assert.Equal(t, int64(5267), proration.Charge)
+	assert.Equal(t, int64(3334), proration.Net())
+}
+
+func TestCalculateProrationRestartChargesFullPrice(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 30)
+	at := start.AddDate(0, 0, 24)
+
+	proration := utils.CalculateProration(2900, 29000, start, end, at, true)
+
+	assert.Equal(t, int64(580), proration.Credit)
+	assert.Equal(t, int64(29000), proration.Charge)
+	assert.Equal(t, int64(28420), proration.Net())
+}
+
+func TestCalculateProrationOutsidePeriod(t *testing.T) {
+	start := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	after := utils.CalculateProration(2900, 7900, start, end, end.Add(time.Hour), false)
+	assert.Zero(t, after.RemainingFraction)
+	assert.Zero(t, after.Net())
+
+	before := utils.CalculateProration(2900, 7900, start, end, start.Add(-time.Hour), false)
+	assert.Equal(t, 1.0, before.RemainingFraction)
+	assert.Equal(t, int64(5000), before.Net())
+
+	empty := utils.CalculateProration(2900, 7900, start, start, start, false)
+	assert.Zero(t, empty.RemainingFraction)
+}
@@ -0,0 +1,68 @@
+package utils_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"testlake/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignLinkToken_RoundTrip(t *testing.T) {
+	os.Setenv("LINK_SIGNING_SECRET", "test-link-secret")
+	defer os.Unsetenv("LINK_SIGNING_SECRET")
+
+	subject := uuid.New()
+	token := utils.SignLinkToken(utils.LinkPurposeAccountUnlock, subject, "1700000000", time.Hour)
+
+	gotSubject, nonce, err := utils.VerifyLinkToken(utils.LinkPurposeAccountUnlock, token)
+	require.NoError(t, err)
+	assert.Equal(t, subject, gotSubject)
+	assert.Equal(t, "1700000000", nonce)
+}
+
+func TestVerifyLinkToken_Rejects(t *testing.T) {
+	os.Setenv("LINK_SIGNING_SECRET", "test-link-secret")
+	defer os.Unsetenv("LINK_SIGNING_SECRET")
+
+	token := utils.SignLinkToken(utils.LinkPurposeAccountUnlock, uuid.New(), "", time.Hour)
+
+	// Wrong purpose
+	_, _, err := utils.VerifyLinkToken("other_purpose", token)
+	assert.ErrorIs(t, err, utils.ErrInvalidSignedLink)
+
+	// Tampered signature
+	parts := strings.Split(token, ".")
+	_, _, err = utils.VerifyLinkToken(utils.LinkPurposeAccountUnlock, parts[0]+".AAAA")
+	assert.ErrorIs(t, err, utils.ErrInvalidSignedLink)
+
+	// Expired
+	expired := utils.SignLinkToken(utils.LinkPurposeAccountUnlock, uuid.New(), "", -time.Minute)
+	_, _, err = utils.VerifyLinkToken(utils.LinkPurposeAccountUnlock, expired)
+	assert.ErrorIs(t, err, utils.ErrInvalidSignedLink)
+
+	// Signed with a different secret
+	os.Setenv("LINK_SIGNING_SECRET", "rotated-secret")
+	_, _, err = utils.VerifyLinkToken(utils.LinkPurposeAccountUnlock, token)
+	assert.ErrorIs(t, err, utils.ErrInvalidSignedLink)
+}
+
+func TestVerifyLinkToken_RequiresSecret(t *testing.T) {
+	os.Setenv("LINK_SIGNING_SECRET", "test-link-secret")
+	token := utils.SignLinkToken(utils.LinkPurposeAccountUnlock, uuid.New(), "", time.Hour)
+	require.NoError(t, utils.CheckLinkSigningSecret())
+
+	os.Unsetenv("LINK_SIGNING_SECRET")
+	assert.Error(t, utils.CheckLinkSigningSecret())
+
+	// Links signed with an empty secret must not verify either
+	forged := utils.SignLinkToken(utils.LinkPurposeAccountUnlock, uuid.New(), "", time.Hour)
+	for _, candidate := range []string{token, forged} {
+		_, _, err := utils.VerifyLinkToken(utils.LinkPurposeAccountUnlock, candidate)
+		assert.ErrorIs(t, err, utils.ErrInvalidSignedLink)
+	}
+}
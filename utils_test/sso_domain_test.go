@@ -0,0 +1,46 @@
+package utils_test
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"testlake/model"
+	"testlake/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHasDomainVerificationRecord(t *testing.T) {
+	defer func(lookup func(string) ([]string, error)) { utils.LookupTXT = lookup }(utils.LookupTXT)
+
+	domain := &model.OrganizationSSODomain{Domain: "acme.test", VerificationToken: "abc123"}
+	records := map[string][]string{}
+	utils.LookupTXT = func(name string) ([]string, error) {
+		if txt, ok := records[name]; ok {
+			return txt, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+	}
+
+	found, err := utils.HasDomainVerificationRecord(domain)
+	require.NoError(t, err)
+	assert.False(t, found, "no record published yet")
+
+	records["_testlake-verification.acme.test"] = []string{"v=spf1 -all", "testlake-verification=other"}
+	found, err = utils.HasDomainVerificationRecord(domain)
+	require.NoError(t, err)
+	assert.False(t, found, "another organization's token")
+
+	records["_testlake-verification.acme.test"] = append(records["_testlake-verification.acme.test"], "testlake-verification=abc123")
+	found, err = utils.HasDomainVerificationRecord(domain)
+	require.NoError(t, err)
+	assert.True(t, found)
+
+	// Resolver failures are not mistaken for a missing record
+	utils.LookupTXT = func(name string) ([]string, error) {
+		return nil, errors.New("i/o timeout")
+	}
+	_, err = utils.HasDomainVerificationRecord(domain)
+	assert.Error(t, err)
+}
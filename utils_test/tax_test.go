@@ -0,0 +1,65 @@
+package utils_test
+
+import (
+	"testing"
+	"testlake/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTableTaxCalculator(t *testing.T) {
+	calculator := &utils.TableTaxCalculator{Rates: utils.DefaultTaxRates, OriginCountry: "DE"}
+
+	for _, test := range []struct {
+		name    string
+		request utils.TaxRequest
+		want    utils.TaxResult
+	}{
+		{
+			name:    "consumer taxed at the rate of their country",
+			request: utils.TaxRequest{Amount: 2900, Country: "FR"},
+			want:    utils.TaxResult{Rate: 20, Amount: 580, Description: "VAT 20% (FR)"},
+		},
+		{
+			name:    "rounded to cents",
+			request: utils.TaxRequest{Amount: 2900, Country: "fi"},
+			want:    utils.TaxResult{Rate: 25.5, Amount: 740, Description: "VAT 25.5% (FI)"},
+		},
+		{
+			name:    "business in another country reverse charged",
+			request: utils.TaxRequest{Amount: 2900, Country: "NL", TaxID: "nl 123456789 B01"},
+			want:    utils.TaxResult{ReverseCharge: true, Description: "Reverse charge"},
+		},
+		{
+			name:    "business in the origin country taxed",
+			request: utils.TaxRequest{Amount: 2900, Country: "DE", TaxID: "DE123456789"},
+			want:    utils.TaxResult{Rate: 19, Amount: 551, Description: "VAT 19% (DE)"},
+		},
+		{
+			name:    "malformed tax ID taxed",
+			request: utils.TaxRequest{Amount: 2900, Country: "AT", TaxID: "12345"},
+			want:    utils.TaxResult{Rate: 20, Amount: 580, Description: "VAT 20% (AT)"},
+		},
+		{
+			name:    "country outside the table not taxed",
+			request: utils.TaxRequest{Amount: 2900, Country: "US"},
+			want:    utils.TaxResult{},
+		},
+		{
+			name:    "credit not taxed",
+			request: utils.TaxRequest{Amount: -1000, Country: "FR"},
+			want:    utils.TaxResult{Rate: 20, Description: "VAT 20% (FR)"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := calculator.Calculate(test.request)
+			require.NoError(t, err)
+			assert.Equal(t, test.want, result)
+		})
+	}
+}
+
+func TestNormalizeTaxID(t *testing.T) {
+	assert.Equal(t, "ATU12345678", utils.NormalizeTaxID("atu 1234-56.78"))
+}
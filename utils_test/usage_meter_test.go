@@ -0,0 +1,42 @@
+package utils_test
+
+import (
+	"testing"
+	"testlake/dao"
+	"testlake/utils"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageMeterFlushAddsToCurrentPeriod(t *testing.T) {
+	org := setupLimits(t)
+	meter := utils.NewUsageMeter()
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		meter.RecordRequest(org.ID)
+	}
+	assert.Equal(t, 3, meter.Pending(org.ID))
+	require.NoError(t, meter.Flush(now))
+	assert.Zero(t, meter.Pending(org.ID))
+
+	meter.RecordRequest(org.ID)
+	meter.RecordRequest(org.ID)
+	require.NoError(t, meter.Flush(now))
+
+	usage, err := dao.NewOrganizationUsageDao().GetCurrentUsage(org.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 5, usage.APIRequestsCount)
+}
+
+func TestUsageMeterKeepsCountsThatFailedToFlush(t *testing.T) {
+	org := setupLimits(t)
+	meter := utils.NewUsageMeter()
+	meter.RecordRequest(org.ID)
+
+	require.NoError(t, dao.Database.Exec("DROP TABLE organization_usages").Error)
+	assert.Error(t, meter.Flush(time.Now()))
+	assert.Equal(t, 1, meter.Pending(org.ID))
+}